@@ -0,0 +1,160 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AWSServiceAccountAuth configures authentication using IAM roles for service
+// accounts, where a token for a service account in the namespace of the
+// AWSSecret is exchanged for temporary credentials of an IAM role.
+type AWSServiceAccountAuth struct {
+	// ARN of the IAM role to assume. The trust policy of the role must permit
+	// the service account to assume it.
+	// +kubebuilder:validation:Pattern=`^arn:aws[a-z-]*:iam::`
+	RoleARN string `json:"roleARN"`
+
+	// Name of the service account a token is requested for. Defaults to
+	// default.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// Audience of the service account token. Defaults to sts.amazonaws.com.
+	// +optional
+	Audience string `json:"audience,omitempty"`
+}
+
+// AWSAccessKeyAuth configures authentication using an access key held in a
+// secret in the namespace of the AWSSecret. The secret must have the keys
+// accessKeyID and secretAccessKey, and may have sessionToken.
+type AWSAccessKeyAuth struct {
+	// Name of the secret holding the access key.
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
+}
+
+// AWSAuth configures how the manager authenticates with AWS. Exactly one
+// method must be given.
+// +kubebuilder:validation:MinProperties=1
+// +kubebuilder:validation:MaxProperties=1
+type AWSAuth struct {
+	// Authenticate by assuming an IAM role for a service account.
+	// +optional
+	ServiceAccount *AWSServiceAccountAuth `json:"serviceAccount,omitempty"`
+
+	// Authenticate using an access key held in a secret.
+	// +optional
+	AccessKey *AWSAccessKeyAuth `json:"accessKey,omitempty"`
+}
+
+// AWSSecretSpec defines the desired state of AWSSecret
+type AWSSecretSpec struct {
+	// AWS region holding the secret, such as us-east-1.
+	// +kubebuilder:validation:MinLength=1
+	Region string `json:"region"`
+
+	// Name or ARN of the secret in AWS Secrets Manager.
+	// +kubebuilder:validation:MinLength=1
+	SecretID string `json:"secretID"`
+
+	// Staging label of the version of the secret to read. Defaults to
+	// AWSCURRENT.
+	// +optional
+	VersionStage string `json:"versionStage,omitempty"`
+
+	// URL of the Secrets Manager endpoint, such as for a VPC endpoint. If not
+	// specified, the regional endpoint is used.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// How to authenticate with AWS.
+	Auth AWSAuth `json:"auth"`
+
+	// Name of the secret to create in the namespace of the AWSSecret. If not
+	// specified, the name of the AWSSecret is used.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// Interval at which the secret is read again from AWS. Defaults to 5
+	// minutes.
+	// +optional
+	RefreshInterval *metav1.Duration `json:"refreshInterval,omitempty"`
+}
+
+// AWSSecretStatus defines the observed state of AWSSecret
+type AWSSecretStatus struct {
+	// The generation of the AWSSecret last processed by the controller.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Name of the secret created for the AWSSecret.
+	SecretName string `json:"secretName,omitempty"`
+
+	// Identifier of the version of the secret last read from AWS.
+	VersionID string `json:"versionID,omitempty"`
+
+	// Time the secret was last read from AWS successfully.
+	// +optional
+	LastRefreshTime *metav1.Time `json:"lastRefreshTime,omitempty"`
+
+	// Conditions describing the state of the AWSSecret.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Secret ID",type=string,JSONPath=`.spec.secretID`
+// +kubebuilder:printcolumn:name="Secret",type=string,JSONPath=`.status.secretName`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// AWSSecret is the Schema for the awssecrets API. It keeps a secret in its
+// namespace in sync with a secret held in AWS Secrets Manager, so it can be
+// used as the source secret of SecretCopier rules.
+type AWSSecret struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AWSSecretSpec   `json:"spec,omitempty"`
+	Status AWSSecretStatus `json:"status,omitempty"`
+}
+
+// Name of the secret created for the AWSSecret.
+func (a *AWSSecret) TargetSecretName() string {
+	if a.Spec.SecretName != "" {
+		return a.Spec.SecretName
+	}
+
+	return a.Name
+}
+
+// +kubebuilder:object:root=true
+
+// AWSSecretList contains a list of AWSSecret
+type AWSSecretList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AWSSecret `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AWSSecret{}, &AWSSecretList{})
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SourceConfigMap is a reference to a config map to copy from.
+type SourceConfigMap struct {
+	// Name of the config map to copy from.
+	Name string `json:"name"`
+
+	// Namespace of the config map to copy from.
+	Namespace string `json:"namespace"`
+}
+
+// TargetConfigMap is a reference to a config map to copy to.
+type TargetConfigMap struct {
+	// Name of the config map to copy to.
+	Name string `json:"name"`
+
+	// Labels to apply to the config map.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// ConfigMapCopierRule is a rule for copying a config map.
+type ConfigMapCopierRule struct {
+	// Whether the rule is enabled. A disabled rule doesn't copy any target
+	// config maps, but target config maps it copied previously are left in
+	// place.
+	// +kubebuilder:default=true
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Reference to the config map to copy from.
+	SourceConfigMap SourceConfigMap `json:"sourceConfigMap"`
+
+	// Target namespaces to copy to.
+	TargetNamespaces selectors.TargetNamespaces `json:"targetNamespaces,omitempty"`
+
+	// Target config map to copy to.
+	TargetConfigMap TargetConfigMap `json:"targetConfigMap,omitempty"`
+
+	// Reclaim policy for copied config map.
+	// +kubebuilder:default=Delete
+	ReclaimPolicy ReclaimPolicy `json:"reclaimPolicy,omitempty"`
+
+	// Overrides of the reclaim policy for target namespaces matching a
+	// selector. The first override matching a target namespace applies, with
+	// the reclaim policy of the rule used for namespaces matched by none.
+	// +optional
+	ReclaimPolicyOverrides []ReclaimPolicyOverride `json:"reclaimPolicyOverrides,omitempty"`
+
+	// Whether terminating namespaces are matched as target namespaces. By
+	// default terminating namespaces are skipped.
+	// +optional
+	IncludeTerminatingNamespaces bool `json:"includeTerminatingNamespaces,omitempty"`
+
+	// Whether namespaces labelled as being decommissioned are matched as
+	// target namespaces. By default they are skipped.
+	// +optional
+	IncludeDecommissioningNamespaces bool `json:"includeDecommissioningNamespaces,omitempty"`
+}
+
+// ConfigMapCopierSpec defines the desired state of ConfigMapCopier
+type ConfigMapCopierSpec struct {
+	// A list of rules for copying config maps.
+	Rules []ConfigMapCopierRule `json:"rules,omitempty"`
+
+	// The interval at which to run the controller. If not specified, the
+	// default sync period of the manager is used.
+	// +optional
+	SyncPeriod *metav1.Duration `json:"syncPeriod,omitempty"`
+}
+
+// ConfigMapCopierRuleStatus is the observed state of a single rule.
+type ConfigMapCopierRuleStatus struct {
+	// Reference to the config map the rule copies from.
+	SourceConfigMap SourceConfigMap `json:"sourceConfigMap"`
+
+	// Whether the rule has been disabled.
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Number of target namespaces matched by the rule.
+	MatchedNamespaces int32 `json:"matchedNamespaces"`
+
+	// Number of matched namespaces where the target config map is in sync.
+	SyncedNamespaces int32 `json:"syncedNamespaces"`
+
+	// Number of matched namespaces where the target config map could not be
+	// written.
+	// +optional
+	FailedNamespaces int32 `json:"failedNamespaces,omitempty"`
+
+	// Human readable explanation of why the rule isn't copying to any target
+	// namespace, such as the source config map not existing.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ConfigMapCopierStatus defines the observed state of ConfigMapCopier
+type ConfigMapCopierStatus struct {
+	// The generation of the ConfigMapCopier last processed by the controller.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// The observed state of each rule, in the same order as the rules.
+	Rules []ConfigMapCopierRuleStatus `json:"rules,omitempty"`
+
+	// Conditions describing the state of the ConfigMapCopier.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ConfigMapCopier is the Schema for the configmapcopiers API
+type ConfigMapCopier struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ConfigMapCopierSpec   `json:"spec,omitempty"`
+	Status ConfigMapCopierStatus `json:"status,omitempty"`
+}
+
+// SkipsNamespace reports whether the rule skips the namespace as a target
+// namespace because it is terminating or being decommissioned.
+func (r *ConfigMapCopierRule) SkipsNamespace(namespace *corev1.Namespace) bool {
+	if namespace.Status.Phase == corev1.NamespaceTerminating && !r.IncludeTerminatingNamespaces {
+		return true
+	}
+
+	return namespace.Labels[NamespaceDecommissioningLabel] == "true" && !r.IncludeDecommissioningNamespaces
+}
+
+// ReclaimPolicyFor returns the reclaim policy for config maps copied by the
+// rule to the target namespace, taking into account any overrides.
+func (r *ConfigMapCopierRule) ReclaimPolicyFor(namespace *corev1.Namespace) ReclaimPolicy {
+	for _, override := range r.ReclaimPolicyOverrides {
+		if override.TargetNamespaces.Matches(namespace) {
+			return override.ReclaimPolicy
+		}
+	}
+
+	return r.ReclaimPolicy
+}
+
+// IsEnabled reports whether the rule is enabled. Rules are enabled unless
+// explicitly disabled.
+func (r *ConfigMapCopierRule) IsEnabled() bool {
+	return r.Enabled == nil || *r.Enabled
+}
+
+// +kubebuilder:object:root=true
+
+// ConfigMapCopierList contains a list of ConfigMapCopier
+type ConfigMapCopierList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ConfigMapCopier `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ConfigMapCopier{}, &ConfigMapCopierList{})
+}
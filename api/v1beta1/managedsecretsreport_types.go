@@ -0,0 +1,136 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ManagedSecretReference identifies a secret listed in a ManagedSecretsReport.
+type ManagedSecretReference struct {
+	// Namespace of the secret.
+	Namespace string `json:"namespace"`
+
+	// Name of the secret.
+	Name string `json:"name"`
+
+	// Name of the SecretCopier the secret relates to.
+	SecretCopier string `json:"secretCopier,omitempty"`
+}
+
+// ManagedSecretsCopierSummary summarizes the secrets managed by a single
+// SecretCopier.
+type ManagedSecretsCopierSummary struct {
+	// Name of the SecretCopier.
+	Name string `json:"name"`
+
+	// Number of target secrets managed by the SecretCopier.
+	ManagedSecrets int32 `json:"managedSecrets"`
+
+	// Number of target secrets which exist but are not managed by the
+	// SecretCopier, so cannot be copied to.
+	Conflicts int32 `json:"conflicts"`
+
+	// Number of target secrets whose content differs from the source secret,
+	// or whose source secret no longer exists.
+	StaleTargets int32 `json:"staleTargets"`
+}
+
+// ManagerBuildInfo identifies the build of the secrets manager.
+type ManagerBuildInfo struct {
+	// Release version of the secrets manager.
+	Version string `json:"version,omitempty"`
+
+	// Git commit the secrets manager was built from.
+	GitCommit string `json:"gitCommit,omitempty"`
+
+	// Time at which the secrets manager was built.
+	BuildDate string `json:"buildDate,omitempty"`
+}
+
+// ManagedSecretsReportStatus defines the observed state of ManagedSecretsReport
+type ManagedSecretsReportStatus struct {
+	// Time at which the report was last generated.
+	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
+
+	// Build of the secrets manager which generated the report.
+	Manager ManagerBuildInfo `json:"manager,omitempty"`
+
+	// Total number of secrets managed by the secrets manager, including
+	// secrets created for SecretClaims.
+	TotalManagedSecrets int32 `json:"totalManagedSecrets"`
+
+	// Number of secrets created for SecretClaims.
+	ClaimedSecrets int32 `json:"claimedSecrets"`
+
+	// Number of secrets created by a SecretCopier which no longer exists, or
+	// which no longer has a rule for the secret.
+	OrphanedSecrets int32 `json:"orphanedSecrets"`
+
+	// Number of target secrets which exist but are not managed by the
+	// SecretCopier with a rule for them.
+	ConflictingSecrets int32 `json:"conflictingSecrets"`
+
+	// Number of target secrets whose content differs from the source secret.
+	StaleSecrets int32 `json:"staleSecrets"`
+
+	// Summary of the secrets managed by each SecretCopier.
+	SecretCopiers []ManagedSecretsCopierSummary `json:"secretCopiers,omitempty"`
+
+	// Orphaned secrets. The list is truncated if there are too many.
+	Orphans []ManagedSecretReference `json:"orphans,omitempty"`
+
+	// Conflicting target secrets. The list is truncated if there are too
+	// many.
+	Conflicts []ManagedSecretReference `json:"conflicts,omitempty"`
+
+	// Stale target secrets. The list is truncated if there are too many.
+	Stale []ManagedSecretReference `json:"stale,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Managed",type=integer,JSONPath=`.status.totalManagedSecrets`
+// +kubebuilder:printcolumn:name="Orphaned",type=integer,JSONPath=`.status.orphanedSecrets`
+// +kubebuilder:printcolumn:name="Conflicts",type=integer,JSONPath=`.status.conflictingSecrets`
+// +kubebuilder:printcolumn:name="Stale",type=integer,JSONPath=`.status.staleSecrets`
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.manager.version`,priority=1
+// +kubebuilder:printcolumn:name="Updated",type=date,JSONPath=`.status.lastUpdateTime`
+
+// ManagedSecretsReport is the Schema for the managedsecretsreports API. It is
+// maintained by the controller and summarizes all secrets managed across the
+// cluster.
+type ManagedSecretsReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status ManagedSecretsReportStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ManagedSecretsReportList contains a list of ManagedSecretsReport
+type ManagedSecretsReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ManagedSecretsReport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ManagedSecretsReport{}, &ManagedSecretsReportList{})
+}
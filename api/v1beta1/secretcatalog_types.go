@@ -0,0 +1,82 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SecretCatalogEntry is a secret which can be claimed by a SecretClaim.
+type SecretCatalogEntry struct {
+	// Key used by a SecretClaim to request the entry.
+	Key string `json:"key"`
+
+	// Reference to the secret which is copied to the namespace of a claim.
+	SourceSecret SourceSecret `json:"sourceSecret"`
+
+	// Namespaces from which the entry may be claimed. If not specified, the
+	// entry may be claimed from all but Kubernetes system namespaces.
+	AllowedNamespaces selectors.TargetNamespaces `json:"allowedNamespaces,omitempty"`
+}
+
+// SecretCatalogSpec defines the desired state of SecretCatalog
+type SecretCatalogSpec struct {
+	// A list of secrets which can be claimed.
+	Entries []SecretCatalogEntry `json:"entries,omitempty"`
+}
+
+// SecretCatalogStatus defines the observed state of SecretCatalog
+type SecretCatalogStatus struct {
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+
+// SecretCatalog is the Schema for the secretcatalogs API
+type SecretCatalog struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SecretCatalogSpec   `json:"spec,omitempty"`
+	Status SecretCatalogStatus `json:"status,omitempty"`
+}
+
+// Find the entry in the catalog with the given key.
+func (c *SecretCatalog) FindEntry(key string) (*SecretCatalogEntry, bool) {
+	for i := range c.Spec.Entries {
+		if c.Spec.Entries[i].Key == key {
+			return &c.Spec.Entries[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// +kubebuilder:object:root=true
+
+// SecretCatalogList contains a list of SecretCatalog
+type SecretCatalogList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SecretCatalog `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SecretCatalog{}, &SecretCatalogList{})
+}
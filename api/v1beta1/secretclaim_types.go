@@ -0,0 +1,123 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SecretClaimSpec defines the desired state of SecretClaim
+type SecretClaimSpec struct {
+	// Name of the SecretCatalog holding the entry to claim.
+	Catalog string `json:"catalog"`
+
+	// Key of the entry in the SecretCatalog to claim.
+	Key string `json:"key"`
+
+	// Name of the secret to create in the namespace of the claim. If not
+	// specified, the name of the SecretClaim is used.
+	SecretName string `json:"secretName,omitempty"`
+
+	// Duration of the lease on the claim. When set, the secret created for the
+	// claim is removed once the lease expires unless the claim is renewed by
+	// changing the value of the "secrets-manager.advok8s.io/renew-lease"
+	// annotation on the claim. The lease starts when the claim is first
+	// processed.
+	// +optional
+	LeaseDuration *metav1.Duration `json:"leaseDuration,omitempty"`
+}
+
+// Annotation on a SecretClaim which when changed renews the lease on the claim.
+const SecretClaimRenewLeaseAnnotation = "secrets-manager.advok8s.io/renew-lease"
+
+// Phase of a SecretClaim.
+// +kubebuilder:validation:Enum=Pending;Bound;Denied;Expired
+type SecretClaimPhase string
+
+const (
+	// The claim has been accepted but the secret has not yet been created.
+	SecretClaimPending SecretClaimPhase = "Pending"
+
+	// The secret for the claim has been created in the namespace of the
+	// claim.
+	SecretClaimBound SecretClaimPhase = "Bound"
+
+	// The claim was not permitted.
+	SecretClaimDenied SecretClaimPhase = "Denied"
+
+	// The claim was previously bound but the entry it claimed is no longer
+	// available or the lease on the claim has expired, so the secret for the
+	// claim has been removed.
+	SecretClaimExpired SecretClaimPhase = "Expired"
+)
+
+// SecretClaimStatus defines the observed state of SecretClaim
+type SecretClaimStatus struct {
+	// The generation of the SecretClaim last processed by the controller.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Phase of the claim.
+	Phase SecretClaimPhase `json:"phase,omitempty"`
+
+	// Human readable explanation of the phase of the claim.
+	Message string `json:"message,omitempty"`
+
+	// Name of the secret created for the claim.
+	SecretName string `json:"secretName,omitempty"`
+
+	// Time at which the lease on the claim was last started or renewed.
+	// +optional
+	LeaseRenewTime *metav1.Time `json:"leaseRenewTime,omitempty"`
+
+	// Time at which the lease on the claim expires.
+	// +optional
+	LeaseExpiryTime *metav1.Time `json:"leaseExpiryTime,omitempty"`
+
+	// Value of the renew lease annotation when the lease was last renewed.
+	// +optional
+	ObservedRenewal string `json:"observedRenewal,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Catalog",type=string,JSONPath=`.spec.catalog`
+// +kubebuilder:printcolumn:name="Key",type=string,JSONPath=`.spec.key`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Expires",type=date,JSONPath=`.status.leaseExpiryTime`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// SecretClaim is the Schema for the secretclaims API
+type SecretClaim struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SecretClaimSpec   `json:"spec,omitempty"`
+	Status SecretClaimStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SecretClaimList contains a list of SecretClaim
+type SecretClaimList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SecretClaim `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SecretClaim{}, &SecretClaimList{})
+}
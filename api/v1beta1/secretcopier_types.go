@@ -17,7 +17,10 @@ limitations under the License.
 package v1beta1
 
 import (
+	"fmt"
+
 	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -40,6 +43,122 @@ type TargetSecret struct {
 
 	// Labels to apply to the secret.
 	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations to apply to the secret. These are overlaid on any other
+	// annotations of the secret. Annotations with the prefix
+	// secrets-manager.advok8s.io/ are reserved for use by the manager.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Glob patterns for the data keys of the source secret to copy. If not
+	// specified, all keys are copied.
+	// +optional
+	IncludeKeys []string `json:"includeKeys,omitempty"`
+
+	// Glob patterns for the data keys of the source secret not to copy. Keys
+	// matching both includeKeys and excludeKeys are not copied.
+	// +optional
+	ExcludeKeys []string `json:"excludeKeys,omitempty"`
+
+	// Write the target secret as an immutable secret with a suffix derived
+	// from a hash of its content appended to the name. A new secret is
+	// created each time the source secret changes. If not specified, the
+	// target secret is updated in place.
+	// +optional
+	HashSuffix *TargetSecretHashSuffix `json:"hashSuffix,omitempty"`
+
+	// Go templates rendered over the data of the source secret to produce the
+	// data of the target secret. If not specified, the data of the source
+	// secret is copied as is.
+	// +optional
+	Template *TargetSecretTemplate `json:"template,omitempty"`
+
+	// Mirrors which credentials for registries held in the .dockerconfigjson
+	// and .dockercfg data keys of the target secret are rewritten to be for,
+	// so image pull secrets point at the mirrors rather than the registries.
+	// +listType=map
+	// +listMapKey=registry
+	// +optional
+	RegistryMirrors []RegistryMirror `json:"registryMirrors,omitempty"`
+
+	// Create the target secret as an immutable secret. As an immutable secret
+	// can't be updated, the target secret is deleted and created again when
+	// the source secret changes.
+	// +optional
+	Immutable bool `json:"immutable,omitempty"`
+}
+
+// RegistryMirror maps an image registry to a mirror of it.
+type RegistryMirror struct {
+	// Hostname of the registry, optionally with a port and path, such as
+	// docker.io. Credentials given for aliases of Docker Hub are matched by
+	// docker.io.
+	// +kubebuilder:validation:MinLength=1
+	Registry string `json:"registry"`
+
+	// Hostname of the mirror, optionally with a port and path, such as
+	// mirror.example.com/dockerhub.
+	// +kubebuilder:validation:MinLength=1
+	Mirror string `json:"mirror"`
+}
+
+// TargetSecretTemplate configures the rendering of the data of the target
+// secret from the data of the source secret.
+type TargetSecretTemplate struct {
+	// Templates for the data keys of the target secret, keyed by the name of
+	// the data key. Templates are rendered with the data of the source secret
+	// selected by includeKeys and excludeKeys available as strings through
+	// .Data, and the name and namespace of the source secret through .Name
+	// and .Namespace.
+	// +kubebuilder:validation:MinProperties=1
+	Data map[string]string `json:"data"`
+
+	// Whether the data keys of the source secret are copied to the target
+	// secret as well as those rendered from templates. Rendered keys replace
+	// keys of the source secret with the same name. If not specified, only
+	// rendered keys are copied.
+	// +optional
+	IncludeSourceData bool `json:"includeSourceData,omitempty"`
+}
+
+// CopiesKey reports whether a data key of the source secret is copied to the
+// target secret.
+func (t *TargetSecret) CopiesKey(key string) bool {
+	if len(t.IncludeKeys) != 0 && !selectors.MatchesAnyPattern(key, t.IncludeKeys) {
+		return false
+	}
+
+	return !selectors.MatchesAnyPattern(key, t.ExcludeKeys)
+}
+
+// FiltersKeys reports whether only some data keys of the source secret may be
+// copied to the target secret.
+func (t *TargetSecret) FiltersKeys() bool {
+	return len(t.IncludeKeys) != 0 || len(t.ExcludeKeys) != 0
+}
+
+// BundledConfigMap is a config map copied together with the source secret of a
+// rule, such as the CA bundle matching a TLS secret.
+type BundledConfigMap struct {
+	// Name of the config map to copy. It must be in the same namespace as the
+	// source secret.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Name of the config map to copy to. If not specified, the name of the
+	// source config map is used.
+	// +optional
+	TargetName string `json:"targetName,omitempty"`
+}
+
+// TargetSecretHashSuffix configures target secrets named with a content hash
+// suffix.
+type TargetSecretHashSuffix struct {
+	// Number of generations of the target secret to keep in each target
+	// namespace, including the current one. Older generations are deleted.
+	// +kubebuilder:default=3
+	// +kubebuilder:validation:Minimum=1
+	RetainedGenerations int32 `json:"retainedGenerations,omitempty"`
 }
 
 // Reclaim policy for copied secret.
@@ -51,9 +170,124 @@ const (
 	ReclaimRetain ReclaimPolicy = "Retain"
 )
 
+// ReclaimPolicyOverride overrides the reclaim policy of a rule for the target
+// namespaces matching a selector.
+type ReclaimPolicyOverride struct {
+	// Target namespaces the override applies to. Only target namespaces
+	// matched by the rule are considered.
+	TargetNamespaces selectors.TargetNamespaces `json:"targetNamespaces"`
+
+	// Reclaim policy for secrets copied to the matching target namespaces.
+	ReclaimPolicy ReclaimPolicy `json:"reclaimPolicy"`
+}
+
+// NamespaceReadiness describes the state a target namespace must be in before
+// it is considered ready to have secrets copied to it. All of the conditions
+// given must be satisfied.
+type NamespaceReadiness struct {
+	// Labels which must be present on the target namespace with the given
+	// values.
+	// +optional
+	MatchLabels map[string]string `json:"matchLabels,omitempty"`
+
+	// Annotations which must be present on the target namespace with the
+	// given values.
+	// +optional
+	MatchAnnotations map[string]string `json:"matchAnnotations,omitempty"`
+
+	// Name of a service account which must exist in the target namespace.
+	// +optional
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+
+	// Name of a resource quota which must exist in the target namespace.
+	// +optional
+	ResourceQuota string `json:"resourceQuota,omitempty"`
+}
+
+// RequiredResource identifies a resource which must exist in a target
+// namespace for a secret to be copied to it.
+type RequiredResource struct {
+	// API version of the resource, such as apps/v1.
+	// +kubebuilder:validation:MinLength=1
+	APIVersion string `json:"apiVersion"`
+
+	// Kind of the resource, such as Deployment.
+	// +kubebuilder:validation:MinLength=1
+	Kind string `json:"kind"`
+
+	// Name of the resource. If not specified, any resource of the kind
+	// matching the selector satisfies the requirement.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Labels the resource must match. If not specified, resources of the
+	// kind match regardless of their labels.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// SecretCopierRollout configures a canary rollout of changes to the source
+// secret of a rule across the target namespaces.
+type SecretCopierRollout struct {
+	// Target namespaces which are updated first when the source secret
+	// changes. Only target namespaces matched by the rule are considered.
+	CanaryNamespaces selectors.TargetNamespaces `json:"canaryNamespaces"`
+
+	// Time to wait after the canary namespaces have been updated before
+	// updating the remaining target namespaces.
+	// +kubebuilder:default="5m"
+	VerificationDelay metav1.Duration `json:"verificationDelay,omitempty"`
+
+	// URL of a verification hook called once the verification delay has
+	// elapsed. The rollout proceeds to the remaining target namespaces only
+	// if the hook responds with a 2xx status. If not specified, the rollout
+	// proceeds once the verification delay has elapsed.
+	// +optional
+	VerificationURL string `json:"verificationURL,omitempty"`
+}
+
+// FirstCopyHook configures actions taken once when a target namespace first
+// receives the target secret of a rule, so provisioning workflows can tell
+// when seeding of secrets into the namespace has completed.
+type FirstCopyHook struct {
+	// Labels added to the target namespace.
+	// +optional
+	NamespaceLabels map[string]string `json:"namespaceLabels,omitempty"`
+
+	// Annotations added to the target namespace.
+	// +optional
+	NamespaceAnnotations map[string]string `json:"namespaceAnnotations,omitempty"`
+
+	// URL of a hook notified with a POST request describing the target
+	// secret. The hook is called again on a later reconciliation if it does
+	// not respond with a 2xx status.
+	// +optional
+	WebhookURL string `json:"webhookURL,omitempty"`
+}
+
 // SecretCopierRule is a rule for copying a secret.
 type SecretCopierRule struct {
-	// Reference to the secret to copy to.
+	// Name of the rule, by which other rules can refer to it as a
+	// dependency. Must be unique within the SecretCopier.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Names of rules which must have synced their target secret to a target
+	// namespace before this rule copies to the same namespace. Rules which
+	// don't target the namespace impose no constraint.
+	// +optional
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	// Whether the rule is enabled. A disabled rule doesn't copy or delete
+	// any target secrets, but target secrets it copied previously are left
+	// in place and are not treated as orphaned.
+	// +kubebuilder:default=true
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Reference to the secret to copy to. The name of the secret can be a
+	// glob pattern, in which case every secret in the source namespace with
+	// a matching name is copied, each to a target secret of the same name.
 	SourceSecret SourceSecret `json:"sourceSecret"`
 
 	// Target namespaces to copy to.
@@ -62,9 +296,165 @@ type SecretCopierRule struct {
 	// Target secret to copy to.
 	TargetSecret TargetSecret `json:"targetSecret,omitempty"`
 
+	// A config map in the namespace of the source secret to copy together
+	// with it. The secret is only copied while the config map exists, and the
+	// copy of the config map is owned by the target secret, so the two are
+	// propagated and pruned as a unit.
+	// +optional
+	BundledConfigMap *BundledConfigMap `json:"bundledConfigMap,omitempty"`
+
 	// Reclaim policy for copied secret.
 	// +kubebuilder:default=Delete
 	ReclaimPolicy ReclaimPolicy `json:"reclaimPolicy,omitempty"`
+
+	// Overrides of the reclaim policy for target namespaces matching a
+	// selector. The first override matching a target namespace applies, with
+	// the reclaim policy of the rule used for namespaces matched by none.
+	// +optional
+	ReclaimPolicyOverrides []ReclaimPolicyOverride `json:"reclaimPolicyOverrides,omitempty"`
+
+	// Names of other SecretCopiers whose target secrets the rule takes over.
+	// An existing target secret managed by one of these SecretCopiers is
+	// adopted if it was copied from the same source secret, so copiers can be
+	// reorganized without deleting and recreating target secrets.
+	// +optional
+	AdoptFrom []string `json:"adoptFrom,omitempty"`
+
+	// Acknowledge that the rule is intended to match more target namespaces
+	// than the fan-out guard threshold of the manager. Without this, a rule
+	// matching more namespaces than the threshold is not processed.
+	// +optional
+	AllowLargeFanOut bool `json:"allowLargeFanOut,omitempty"`
+
+	// Whether terminating namespaces are matched as target namespaces. Target
+	// secrets can't be created in a terminating namespace, but existing ones
+	// can still be updated. By default terminating namespaces are skipped.
+	// +optional
+	IncludeTerminatingNamespaces bool `json:"includeTerminatingNamespaces,omitempty"`
+
+	// Whether namespaces labelled as being decommissioned are matched as
+	// target namespaces. By default they are skipped.
+	// +optional
+	IncludeDecommissioningNamespaces bool `json:"includeDecommissioningNamespaces,omitempty"`
+
+	// Readiness signal required of a target namespace before the secret is
+	// copied to it. This allows copying to be deferred until any pipeline
+	// provisioning the namespace has finished. If not specified, target
+	// namespaces are always considered ready.
+	// +optional
+	TargetNamespaceReadiness *NamespaceReadiness `json:"targetNamespaceReadiness,omitempty"`
+
+	// A resource which must exist in a target namespace for the secret to be
+	// copied to it, such as the Deployment which consumes the secret. The
+	// target secret is removed from target namespaces where no matching
+	// resource exists any more. If not specified, copying doesn't depend on
+	// the resources in the target namespace.
+	// +optional
+	RequiredResource *RequiredResource `json:"requiredResource,omitempty"`
+
+	// Canary rollout of changes to the source secret. If not specified,
+	// changes are copied to all target namespaces at once.
+	// +optional
+	Rollout *SecretCopierRollout `json:"rollout,omitempty"`
+
+	// Minimum time between propagating successive changes to the source
+	// secret to target namespaces. A change made before the interval has
+	// elapsed since the last change was propagated is held back until it
+	// has, with only the latest change then being copied. If not specified,
+	// changes are propagated as soon as they are seen.
+	// +optional
+	MinInterval *metav1.Duration `json:"minInterval,omitempty"`
+
+	// Actions taken once when a target namespace first receives the target
+	// secret of the rule. If not specified, no actions are taken.
+	// +optional
+	OnFirstCopy *FirstCopyHook `json:"onFirstCopy,omitempty"`
+
+	// Remote cluster to copy the secret to. The target namespaces are then
+	// those of the remote cluster. If not specified, the secret is copied to
+	// namespaces of the cluster the manager runs in.
+	// +optional
+	TargetCluster *TargetCluster `json:"targetCluster,omitempty"`
+
+	// How namespaces created by vcluster to hold the namespaces of a virtual
+	// cluster are treated when matched by the rule. If not specified, they
+	// are targeted like any other namespace.
+	// +optional
+	VirtualClusterNamespaces *VirtualClusterNamespaces `json:"virtualClusterNamespaces,omitempty"`
+
+	// Whether target namespaces must consent to receiving copies by being
+	// annotated with secrets-manager.advok8s.io/accept-copies set to "true".
+	// Matching namespaces without the annotation are skipped. Consent can
+	// also be required of all rules by the manager.
+	// +optional
+	RequireNamespaceConsent bool `json:"requireNamespaceConsent,omitempty"`
+}
+
+// VirtualClusterNamespacePolicy decides how a rule treats namespaces created
+// by vcluster for a virtual cluster.
+// +kubebuilder:validation:Enum=Target;Skip;CopyIntoVirtualCluster
+type VirtualClusterNamespacePolicy string
+
+const (
+	// Copy to the namespace like any other namespace.
+	VirtualClusterNamespaceTarget VirtualClusterNamespacePolicy = "Target"
+
+	// Don't copy to the namespace.
+	VirtualClusterNamespaceSkip VirtualClusterNamespacePolicy = "Skip"
+
+	// Don't copy to the namespace, but instead to the namespace of the
+	// virtual cluster it holds, accessed using the kubeconfig of the virtual
+	// cluster.
+	VirtualClusterNamespaceCopyInto VirtualClusterNamespacePolicy = "CopyIntoVirtualCluster"
+)
+
+// VirtualClusterNamespaces configures how a rule treats namespaces created
+// by vcluster to hold the namespaces of a virtual cluster.
+// +kubebuilder:validation:XValidation:rule="self.policy != 'CopyIntoVirtualCluster' || has(self.kubeconfigSecretNamespace)",message="kubeconfigSecretNamespace must be specified to copy into virtual clusters"
+type VirtualClusterNamespaces struct {
+	// Policy for namespaces created by vcluster.
+	Policy VirtualClusterNamespacePolicy `json:"policy"`
+
+	// Namespace holding the kubeconfig secrets of the virtual clusters,
+	// which vcluster names vc-<name>. This is the namespace vcluster is
+	// installed in. Required when copying into virtual clusters.
+	// +optional
+	KubeconfigSecretNamespace string `json:"kubeconfigSecretNamespace,omitempty"`
+}
+
+// TargetCluster identifies a remote cluster to copy secrets to.
+type TargetCluster struct {
+	// Secret holding a kubeconfig used to access the remote cluster. The
+	// secret is read with the same permissions as source secrets.
+	KubeconfigSecret KubeconfigSecretReference `json:"kubeconfigSecret"`
+}
+
+// KubeconfigSecretReference is a reference to a kubeconfig held in a secret.
+type KubeconfigSecretReference struct {
+	// Name of the secret.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Namespace of the secret.
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+
+	// Data key of the secret holding the kubeconfig.
+	// +kubebuilder:default=kubeconfig
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// SecretCopierServiceAccount identifies a service account the controller
+// impersonates when acting for a SecretCopier.
+type SecretCopierServiceAccount struct {
+	// The name of the service account.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// The namespace of the service account.
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
 }
 
 // SecretCopierSpec defines the desired state of SecretCopier
@@ -75,20 +465,417 @@ type SecretCopierSpec struct {
 	// A list of rules for copying secrets.
 	Rules []SecretCopierRule `json:"rules,omitempty"`
 
-	// The interval at which to run the controller.
-	// +kubebuilder:default="1m"
-	SyncPeriod metav1.Duration `json:"syncPeriod,omitempty"`
+	// A service account to impersonate when reading source secrets and
+	// writing target secrets, so the SecretCopier can only access the
+	// secrets the service account is permitted to by RBAC. If not specified,
+	// the permissions of the controller are used.
+	// +optional
+	ServiceAccount *SecretCopierServiceAccount `json:"serviceAccount,omitempty"`
+
+	// The interval at which to run the controller. If not specified, the
+	// default sync period of the manager is used.
+	// +optional
+	SyncPeriod *metav1.Duration `json:"syncPeriod,omitempty"`
+
+	// How much detail is reported in the status. With Summary, the lists of
+	// individual target namespaces are left out, with only counts being
+	// reported. If not specified, the full status is reported.
+	// +kubebuilder:default=Full
+	// +optional
+	StatusDetail StatusDetail `json:"statusDetail,omitempty"`
+
+	// What is done with target secrets left behind when a rule is removed or
+	// its target namespaces no longer match. With Delete, target secrets
+	// copied by rules with a reclaim policy of Delete are deleted. If not
+	// specified, target secrets are left in place.
+	// +kubebuilder:default=None
+	// +optional
+	PrunePolicy PrunePolicy `json:"prunePolicy,omitempty"`
+}
+
+// Amount of detail reported in the status of a SecretCopier.
+// +kubebuilder:validation:Enum=Full;Summary
+type StatusDetail string
+
+const (
+	StatusDetailFull    StatusDetail = "Full"
+	StatusDetailSummary StatusDetail = "Summary"
+)
+
+// Policy for target secrets no longer wanted by any rule of a SecretCopier.
+// +kubebuilder:validation:Enum=None;Delete
+type PrunePolicy string
+
+const (
+	PruneNone   PrunePolicy = "None"
+	PruneDelete PrunePolicy = "Delete"
+)
+
+// SecretCopierDeniedTarget is a target namespace where the controller was
+// denied permission to manage the target secret.
+type SecretCopierDeniedTarget struct {
+	// Name of the target namespace.
+	Namespace string `json:"namespace"`
+
+	// The verb which was denied.
+	Verb string `json:"verb"`
+
+	// The resource for which the verb was denied.
+	Resource string `json:"resource"`
+}
+
+// SecretCopierFailedTarget records a target namespace where copying the
+// source secret of a rule is failing.
+type SecretCopierFailedTarget struct {
+	// Name of the target namespace.
+	Namespace string `json:"namespace"`
+
+	// Index of the rule in the spec the target namespace was matched by. For
+	// rules expanded from a source secret name pattern, this is the index of
+	// the rule holding the pattern.
+	Rule int32 `json:"rule"`
+
+	// Machine readable reason for the failure, being one of the values of
+	// CopyFailureReason.
+	Reason string `json:"reason"`
+
+	// Description of the most recent failure.
+	Message string `json:"message,omitempty"`
+
+	// Time at which copying to the target namespace started failing.
+	FirstFailureTime metav1.Time `json:"firstFailureTime"`
+
+	// Time of the most recent failure. While the failure is unchanged this
+	// is updated at most once a minute.
+	LastFailureTime metav1.Time `json:"lastFailureTime"`
+}
+
+// CopyFailureReason classifies why copying a secret to a target namespace
+// failed. The set of reasons is stable, so they can be relied on by alerts and
+// dashboards in place of error messages.
+type CopyFailureReason string
+
+const (
+	// The manager is not permitted to write the target secret.
+	CopyFailureForbidden CopyFailureReason = "Forbidden"
+
+	// The target namespace, or a resource needed to write the target secret,
+	// does not exist.
+	CopyFailureNotFound CopyFailureReason = "NotFound"
+
+	// The target secret was changed while being written, already exists
+	// when it was to be created, or has a different type which prevents it
+	// being updated.
+	CopyFailureConflict CopyFailureReason = "Conflict"
+
+	// Writing the target secret would exceed a resource quota of the target
+	// namespace.
+	CopyFailureQuotaExceeded CopyFailureReason = "QuotaExceeded"
+
+	// The target secret exceeds the maximum size of a secret.
+	CopyFailureOversize CopyFailureReason = "Oversize"
+
+	// An admission webhook rejected the write of the target secret.
+	CopyFailureWebhookDenied CopyFailureReason = "WebhookDenied"
+
+	// The API server holding the target secret could not be reached, or
+	// timed out.
+	CopyFailureRemoteUnreachable CopyFailureReason = "RemoteUnreachable"
+
+	// The failure does not fall into any of the other reasons.
+	CopyFailureUnknown CopyFailureReason = "Unknown"
+)
+
+// Phase of a canary rollout.
+// +kubebuilder:validation:Enum=Canary;Verifying;Complete;Failed
+type RolloutPhase string
+
+const (
+	// The source secret is being copied to the canary namespaces.
+	RolloutCanary RolloutPhase = "Canary"
+
+	// The canary namespaces have been updated and the rollout is waiting for
+	// the verification delay to elapse and the verification hook to pass.
+	RolloutVerifying RolloutPhase = "Verifying"
+
+	// The source secret is being copied to all target namespaces.
+	RolloutComplete RolloutPhase = "Complete"
+
+	// Verification failed and the rollout has been halted. A new rollout is
+	// started when the source secret next changes.
+	RolloutFailed RolloutPhase = "Failed"
+)
+
+// SecretCopierRolloutStatus is the progress of a canary rollout.
+type SecretCopierRolloutStatus struct {
+	// Hash of the content of the source secret being rolled out.
+	Revision string `json:"revision"`
+
+	// Phase of the rollout.
+	Phase RolloutPhase `json:"phase"`
+
+	// Number of target namespaces selected as canaries.
+	CanaryNamespaces int32 `json:"canaryNamespaces"`
+
+	// Time at which all canary namespaces had been updated.
+	// +optional
+	CanaryCompletedTime *metav1.Time `json:"canaryCompletedTime,omitempty"`
+
+	// Human readable explanation of the phase of the rollout.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// SecretCopierRuleStatus is the observed state of a single rule.
+type SecretCopierRuleStatus struct {
+	// Name of the rule, if it has one.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Reference to the secret the rule copies from.
+	SourceSecret SourceSecret `json:"sourceSecret"`
+
+	// Whether the rule has been disabled.
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Whether the rule was not processed because it matches more target
+	// namespaces than the fan-out guard threshold without acknowledging it.
+	// +optional
+	FanOutBlocked bool `json:"fanOutBlocked,omitempty"`
+
+	// Number of target namespaces matched by the rule.
+	MatchedNamespaces int32 `json:"matchedNamespaces"`
+
+	// Number of matched namespaces where the target secret is in sync.
+	SyncedNamespaces int32 `json:"syncedNamespaces"`
+
+	// Number of matched namespaces where a write was deferred because of
+	// the secret write rate limit, skipped because the manager is running in
+	// report-only mode, or held back by a canary rollout.
+	PendingNamespaces int32 `json:"pendingNamespaces"`
+
+	// Number of namespaces matching the selectors of the rule which were
+	// skipped because they are terminating or being decommissioned, or were
+	// created by vcluster for a virtual cluster. These are not counted in
+	// matchedNamespaces.
+	// +optional
+	SkippedNamespaces int32 `json:"skippedNamespaces,omitempty"`
+
+	// Number of matched namespaces which are not yet ready to have the
+	// secret copied to them.
+	// +optional
+	NotReadyNamespaces int32 `json:"notReadyNamespaces,omitempty"`
+
+	// Number of namespaces matching the selectors of the rule to which the
+	// secret is not copied as they are outside the target namespaces
+	// delegated for the source namespace by SecretCopierDelegations. These
+	// are not counted in matchedNamespaces.
+	// +optional
+	UndelegatedNamespaces int32 `json:"undelegatedNamespaces,omitempty"`
+
+	// Number of namespaces matching the selectors of the rule which were
+	// created by vcluster, where the secret is copied into the namespace of
+	// the virtual cluster instead. These are not counted in
+	// matchedNamespaces.
+	// +optional
+	VirtualClusterNamespaces int32 `json:"virtualClusterNamespaces,omitempty"`
+
+	// Number of namespaces matching the selectors of the rule to which the
+	// secret is not copied as they haven't consented to receiving copies.
+	// These are not counted in matchedNamespaces.
+	// +optional
+	UnconsentedNamespaces int32 `json:"unconsentedNamespaces,omitempty"`
+
+	// Number of matched namespaces where copying is waiting on rules this
+	// rule depends on to sync their target secret first.
+	// +optional
+	WaitingOnDependencies int32 `json:"waitingOnDependencies,omitempty"`
+
+	// Number of matched namespaces where the target secret is labelled with
+	// secrets-manager.advok8s.io/ignore set to "true", so is no longer being
+	// updated.
+	// +optional
+	IgnoredNamespaces int32 `json:"ignoredNamespaces,omitempty"`
+
+	// Human readable explanation of why the rule isn't copying to any target
+	// namespace, such as the source secret not existing or the selectors not
+	// matching any namespace.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Explanation of why the dependencies of the rule could not be resolved.
+	// +optional
+	DependencyError string `json:"dependencyError,omitempty"`
+
+	// Target namespaces where the controller was denied permission to manage
+	// the target secret, ordered by namespace. The list is capped, with any
+	// further denied target namespaces counted by deniedNamespacesOverflow.
+	DeniedNamespaces []SecretCopierDeniedTarget `json:"deniedNamespaces,omitempty"`
+
+	// Number of denied target namespaces not listed in deniedNamespaces.
+	// +optional
+	DeniedNamespacesOverflow int32 `json:"deniedNamespacesOverflow,omitempty"`
+
+	// Name of the current target secret, if the target secret is named with
+	// a content hash suffix.
+	// +optional
+	TargetSecretName string `json:"targetSecretName,omitempty"`
+
+	// Progress of the canary rollout of the source secret, if the rule has a
+	// rollout configured.
+	// +optional
+	Rollout *SecretCopierRolloutStatus `json:"rollout,omitempty"`
+
+	// Number of target secrets the rule has created.
+	// +optional
+	CreatedSecrets int64 `json:"createdSecrets,omitempty"`
+
+	// Number of times the rule has updated a target secret.
+	// +optional
+	UpdatedSecrets int64 `json:"updatedSecrets,omitempty"`
+
+	// The most recent error encountered copying the secret to a target
+	// namespace. It is kept after the error is resolved, with the Synced
+	// condition giving the current state of the rule.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+
+	// When the most recent error was first encountered.
+	// +optional
+	LastErrorTime *metav1.Time `json:"lastErrorTime,omitempty"`
+
+	// Interval after which the rule is next due to be synced. This is the
+	// sync period of the SecretCopier, unless the rule needs to be checked
+	// again sooner, such as while backing off waiting for its source secret
+	// to be created.
+	// +optional
+	SyncInterval *metav1.Duration `json:"syncInterval,omitempty"`
+
+	// Why the rule is next due to be synced after the sync interval. One of
+	// SyncPeriod, WaitingForSource, Cooldown, Rollout or TargetClusterRetry.
+	// +optional
+	SyncReason string `json:"syncReason,omitempty"`
+
+	// Conditions describing the state of the rule, ordered by type.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// SecretCopierTargetClusterStatus describes whether a remote cluster which
+// rules copy secrets to can be reached.
+type SecretCopierTargetClusterStatus struct {
+	// The secret holding the kubeconfig for the target cluster, given as
+	// namespace/name.
+	KubeconfigSecret string `json:"kubeconfigSecret"`
+
+	// Time at which the controller will next try to reach the target
+	// cluster. Only set while the target cluster is unreachable.
+	// +optional
+	NextRetryTime *metav1.Time `json:"nextRetryTime,omitempty"`
+
+	// Conditions describing the state of the target cluster, ordered by
+	// type. The Ready condition has a reason of Reachable or Unreachable.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // SecretCopierStatus defines the observed state of SecretCopier
 type SecretCopierStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
+
+	// The generation of the SecretCopier last processed by the controller.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// The observed state of each rule, in the same order as the rules.
+	Rules []SecretCopierRuleStatus `json:"rules,omitempty"`
+
+	// Target namespaces where copying is failing, ordered by rule and then
+	// namespace. The list is capped, with any further failing targets
+	// counted by failedTargetsOverflow. Failing targets are only counted
+	// when statusDetail is Summary.
+	FailedTargets []SecretCopierFailedTarget `json:"failedTargets,omitempty"`
+
+	// Number of failing target namespaces not listed in failedTargets.
+	FailedTargetsOverflow int32 `json:"failedTargetsOverflow,omitempty"`
+
+	// The remote clusters rules copy secrets to, ordered by kubeconfig
+	// secret.
+	// +listType=map
+	// +listMapKey=kubeconfigSecret
+	// +optional
+	TargetClusters []SecretCopierTargetClusterStatus `json:"targetClusters,omitempty"`
+
+	// The value of the secrets-manager.advok8s.io/resync annotation for
+	// which a resync was last performed.
+	LastHandledResync string `json:"lastHandledResync,omitempty"`
+
+	// The sync period in effect for the SecretCopier, after the default sync
+	// period of the manager has been applied.
+	// +optional
+	SyncPeriod *metav1.Duration `json:"syncPeriod,omitempty"`
+
+	// Time at which the SecretCopier is next scheduled to be reconciled. Not
+	// set if no reconcile is scheduled.
+	// +optional
+	NextSyncTime *metav1.Time `json:"nextSyncTime,omitempty"`
+
+	// Conditions describing the state of the SecretCopier, ordered by type.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+// Condition types reported in the status of a SecretCopier.
+const (
+	// The target secrets of all enabled rules are in sync with their source
+	// secrets.
+	ConditionReady = "Ready"
+
+	// The target secret of a rule is in sync with the source secret in all
+	// target namespaces. Reported in the status of each rule.
+	ConditionSynced = "Synced"
+
+	// The controller was denied permission to manage the target secret in
+	// one or more target namespaces.
+	ConditionPermissionDenied = "PermissionDenied"
+
+	// The manager is running in report-only mode, so target secrets are not
+	// being written.
+	ConditionReportOnly = "ReportOnly"
+
+	// One or more rules match more target namespaces than the fan-out guard
+	// threshold without acknowledging it, and have not been processed.
+	ConditionLargeFanOut = "LargeFanOut"
+
+	// The target secret would exceed the size limit of the API server in one
+	// or more target namespaces, so could not be written.
+	ConditionSecretTooLarge = "SecretTooLarge"
+
+	// The source secret of one or more rules doesn't exist yet, so the
+	// SecretCopier is being requeued to check for it with backoff.
+	ConditionWaitingForSource = "WaitingForSource"
+
+	// The target secret in one or more target namespaces is labelled to be
+	// ignored, so is no longer being updated.
+	ConditionIgnored = "Ignored"
+
+	// One or more remote clusters which rules copy secrets to can't be
+	// reached, so copying to them is being retried with backoff.
+	ConditionTargetClusterUnreachable = "TargetClusterUnreachable"
+)
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // SecretCopier is the Schema for the secretcopiers API
 type SecretCopier struct {
@@ -99,6 +886,172 @@ type SecretCopier struct {
 	Status SecretCopierStatus `json:"status,omitempty"`
 }
 
+// Label marking a namespace as being decommissioned. Namespaces with the label
+// set to "true" are skipped as target namespaces unless a rule sets
+// includeDecommissioningNamespaces.
+const NamespaceDecommissioningLabel = "secrets-manager.advok8s.io/decommissioning"
+
+// Annotation by which a namespace consents to receiving copies of secrets.
+// Where consent is required, namespaces are only used as target namespaces
+// when the annotation is set to "true".
+const NamespaceAcceptCopiesAnnotation = "secrets-manager.advok8s.io/accept-copies"
+
+// NamespaceAcceptsCopies reports whether the namespace has consented to
+// receiving copies of secrets.
+func NamespaceAcceptsCopies(namespace *corev1.Namespace) bool {
+	return namespace.Annotations[NamespaceAcceptCopiesAnnotation] == "true"
+}
+
+// SkipsNamespace reports whether the rule skips the namespace as a target
+// namespace because it is terminating or being decommissioned, or because it
+// was created by vcluster for a virtual cluster and the rule doesn't target
+// such namespaces directly.
+func (r *SecretCopierRule) SkipsNamespace(namespace *corev1.Namespace) bool {
+	if r.VirtualClusterNamespaces != nil && r.VirtualClusterNamespaces.Policy != VirtualClusterNamespaceTarget && VirtualClusterOf(namespace) != "" {
+		return true
+	}
+
+	return r.SkipsInactiveNamespace(namespace)
+}
+
+// SkipsInactiveNamespace reports whether the rule skips the namespace as a
+// target namespace because it is terminating or being decommissioned.
+func (r *SecretCopierRule) SkipsInactiveNamespace(namespace *corev1.Namespace) bool {
+	if namespace.Status.Phase == corev1.NamespaceTerminating && !r.IncludeTerminatingNamespaces {
+		return true
+	}
+
+	return namespace.Labels[NamespaceDecommissioningLabel] == "true" && !r.IncludeDecommissioningNamespaces
+}
+
+// CopiesIntoVirtualCluster reports whether the rule copies to the namespace
+// of the virtual cluster held by the namespace, rather than the namespace
+// itself.
+func (r *SecretCopierRule) CopiesIntoVirtualCluster(namespace *corev1.Namespace) bool {
+	return r.VirtualClusterNamespaces != nil && r.VirtualClusterNamespaces.Policy == VirtualClusterNamespaceCopyInto && VirtualClusterOf(namespace) != ""
+}
+
+// Label vcluster sets on objects it creates in the host cluster, including
+// namespaces, giving the name of the virtual cluster.
+const VirtualClusterManagedByLabel = "vcluster.loft.sh/managed-by"
+
+// Annotation vcluster sets on objects it creates in the host cluster, giving
+// the name of the object in the virtual cluster.
+const VirtualClusterObjectNameAnnotation = "vcluster.loft.sh/object-name"
+
+// VirtualClusterOf returns the name of the virtual cluster a namespace was
+// created by vcluster for, or an empty string if it wasn't.
+func VirtualClusterOf(namespace *corev1.Namespace) string {
+	return namespace.Labels[VirtualClusterManagedByLabel]
+}
+
+// VirtualNamespaceOf returns the name of the namespace in the virtual cluster
+// held by a namespace vcluster created. If vcluster didn't record the name,
+// the namespace is assumed to have the same name in the virtual cluster.
+func VirtualNamespaceOf(namespace *corev1.Namespace) string {
+	if name := namespace.Annotations[VirtualClusterObjectNameAnnotation]; name != "" {
+		return name
+	}
+
+	return namespace.Name
+}
+
+// ReclaimPolicyFor returns the reclaim policy for secrets copied by the rule
+// to the target namespace, taking into account any overrides.
+func (r *SecretCopierRule) ReclaimPolicyFor(namespace *corev1.Namespace) ReclaimPolicy {
+	for _, override := range r.ReclaimPolicyOverrides {
+		if override.TargetNamespaces.Matches(namespace) {
+			return override.ReclaimPolicy
+		}
+	}
+
+	return r.ReclaimPolicy
+}
+
+// IsEnabled reports whether the rule is enabled. Rules are enabled unless
+// explicitly disabled.
+func (r *SecretCopierRule) IsEnabled() bool {
+	return r.Enabled == nil || *r.Enabled
+}
+
+// CopiesSourceSecretPattern reports whether the name of the source secret is a
+// glob pattern matching any number of secrets in the source namespace.
+func (r *SecretCopierRule) CopiesSourceSecretPattern() bool {
+	return selectors.IsPattern(r.SourceSecret.Name)
+}
+
+// Determine the order in which the rules should be processed so that each rule
+// comes after the rules it depends on. Rules are otherwise kept in the order
+// given. Rules whose dependencies cannot be resolved, because they refer to a
+// rule which doesn't exist or form a cycle, are returned in a separate map
+// with the reason.
+func (s *SecretCopierSpec) RuleOrder() ([]int, map[int]string) {
+	indexes := make(map[string]int, len(s.Rules))
+	unresolved := make(map[int]string)
+
+	for i, rule := range s.Rules {
+		if rule.Name == "" {
+			continue
+		}
+
+		if _, found := indexes[rule.Name]; found {
+			unresolved[i] = fmt.Sprintf("duplicate rule name %q", rule.Name)
+			continue
+		}
+
+		indexes[rule.Name] = i
+	}
+
+	for i, rule := range s.Rules {
+		for _, name := range rule.DependsOn {
+			if _, found := indexes[name]; !found {
+				unresolved[i] = fmt.Sprintf("depends on unknown rule %q", name)
+			}
+		}
+	}
+
+	// Repeatedly take the first rule all of whose dependencies have already
+	// been placed. Anything left over must be part of, or depend on, a cycle.
+
+	order := make([]int, 0, len(s.Rules))
+	placed := make(map[int]bool, len(s.Rules))
+
+	for progress := true; progress; {
+		progress = false
+
+		for i, rule := range s.Rules {
+			if placed[i] || unresolved[i] != "" {
+				continue
+			}
+
+			ready := true
+
+			for _, name := range rule.DependsOn {
+				if dependency := indexes[name]; !placed[dependency] {
+					ready = false
+					break
+				}
+			}
+
+			if ready {
+				order = append(order, i)
+				placed[i] = true
+				progress = true
+
+				break
+			}
+		}
+	}
+
+	for i := range s.Rules {
+		if !placed[i] && unresolved[i] == "" {
+			unresolved[i] = "dependencies form a cycle or depend on an unresolved rule"
+		}
+	}
+
+	return order, unresolved
+}
+
 // +kubebuilder:object:root=true
 
 // SecretCopierList contains a list of SecretCopier
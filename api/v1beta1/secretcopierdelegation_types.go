@@ -0,0 +1,75 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SecretCopierDelegationSpec defines the desired state of
+// SecretCopierDelegation
+type SecretCopierDelegationSpec struct {
+	// Namespace holding the source secrets whose copying is delegated.
+	// +kubebuilder:validation:MinLength=1
+	SourceNamespace string `json:"sourceNamespace"`
+
+	// Namespaces to which secrets from the source namespace may be copied.
+	// Rules of a SecretCopier with a source secret in the source namespace
+	// only copy to matched target namespaces which are also in this scope.
+	// If not specified, secrets may be copied to all but Kubernetes system
+	// namespaces.
+	// +optional
+	TargetNamespaces selectors.TargetNamespaces `json:"targetNamespaces,omitempty"`
+}
+
+// SecretCopierDelegationStatus defines the observed state of
+// SecretCopierDelegation
+type SecretCopierDelegationStatus struct {
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Source Namespace",type=string,JSONPath=`.spec.sourceNamespace`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// SecretCopierDelegation is the Schema for the secretcopierdelegations API.
+// It is created by a cluster admin to delegate the copying of secrets from a
+// source namespace to a bounded set of target namespaces. Once any delegation
+// exists for a source namespace, SecretCopier rules copying secrets from it
+// are restricted to the target namespaces delegated.
+type SecretCopierDelegation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SecretCopierDelegationSpec   `json:"spec,omitempty"`
+	Status SecretCopierDelegationStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SecretCopierDelegationList contains a list of SecretCopierDelegation
+type SecretCopierDelegationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SecretCopierDelegation `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SecretCopierDelegation{}, &SecretCopierDelegationList{})
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SecretExportSpec defines the desired state of SecretExport
+type SecretExportSpec struct {
+	// Name of the secret in the namespace of the export which is offered for
+	// import. If not specified, the name of the SecretExport is used.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// Namespaces into which the secret may be imported. If not specified, the
+	// secret may be imported into all but Kubernetes system namespaces.
+	// +optional
+	ToNamespaces selectors.TargetNamespaces `json:"toNamespaces,omitempty"`
+}
+
+// SecretExportStatus defines the observed state of SecretExport
+type SecretExportStatus struct {
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Secret",type=string,JSONPath=`.spec.secretName`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// SecretExport is the Schema for the secretexports API
+type SecretExport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SecretExportSpec   `json:"spec,omitempty"`
+	Status SecretExportStatus `json:"status,omitempty"`
+}
+
+// Name of the secret offered for import by the export.
+func (e *SecretExport) ExportedSecretName() string {
+	if e.Spec.SecretName != "" {
+		return e.Spec.SecretName
+	}
+
+	return e.Name
+}
+
+// +kubebuilder:object:root=true
+
+// SecretExportList contains a list of SecretExport
+type SecretExportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SecretExport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SecretExport{}, &SecretExportList{})
+}
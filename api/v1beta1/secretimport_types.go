@@ -0,0 +1,120 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SecretImportSpec defines the desired state of SecretImport
+type SecretImportSpec struct {
+	// Namespace holding the SecretExport to import.
+	// +kubebuilder:validation:MinLength=1
+	FromNamespace string `json:"fromNamespace"`
+
+	// Name of the SecretExport to import. If not specified, the name of the
+	// SecretImport is used.
+	// +optional
+	ExportName string `json:"exportName,omitempty"`
+
+	// Name of the secret to create in the namespace of the import. If not
+	// specified, the name of the SecretImport is used.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// Phase of a SecretImport.
+// +kubebuilder:validation:Enum=Pending;Imported;Denied;Revoked
+type SecretImportPhase string
+
+const (
+	// The import has been accepted but the secret has not yet been created.
+	SecretImportPending SecretImportPhase = "Pending"
+
+	// The secret has been copied into the namespace of the import.
+	SecretImportImported SecretImportPhase = "Imported"
+
+	// The import was not permitted by the SecretExport.
+	SecretImportDenied SecretImportPhase = "Denied"
+
+	// The secret was previously imported but the SecretExport has since been
+	// removed or no longer permits the import, so the imported secret has
+	// been removed.
+	SecretImportRevoked SecretImportPhase = "Revoked"
+)
+
+// SecretImportStatus defines the observed state of SecretImport
+type SecretImportStatus struct {
+	// The generation of the SecretImport last processed by the controller.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Phase of the import.
+	Phase SecretImportPhase `json:"phase,omitempty"`
+
+	// Human readable explanation of the phase of the import.
+	Message string `json:"message,omitempty"`
+
+	// Name of the secret created for the import.
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="From",type=string,JSONPath=`.spec.fromNamespace`
+// +kubebuilder:printcolumn:name="Export",type=string,JSONPath=`.spec.exportName`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// SecretImport is the Schema for the secretimports API
+type SecretImport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SecretImportSpec   `json:"spec,omitempty"`
+	Status SecretImportStatus `json:"status,omitempty"`
+}
+
+// Name of the SecretExport imported by the import.
+func (i *SecretImport) ImportedExportName() string {
+	if i.Spec.ExportName != "" {
+		return i.Spec.ExportName
+	}
+
+	return i.Name
+}
+
+// Name of the secret created for the import.
+func (i *SecretImport) ImportedSecretName() string {
+	if i.Spec.SecretName != "" {
+		return i.Spec.SecretName
+	}
+
+	return i.Name
+}
+
+// +kubebuilder:object:root=true
+
+// SecretImportList contains a list of SecretImport
+type SecretImportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SecretImport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SecretImport{}, &SecretImportList{})
+}
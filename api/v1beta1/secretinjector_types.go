@@ -0,0 +1,108 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// InjectedSecretEnv describes how keys of a secret are injected into
+// containers as environment variables.
+type InjectedSecretEnv struct {
+	// Prefix added to the key to give the name of the environment variable
+	// for each key.
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// InjectedSecret is a secret injected into the containers of matching pods.
+// +kubebuilder:validation:XValidation:rule="has(self.env) || has(self.mountPath)",message="one of env or mountPath must be specified"
+type InjectedSecret struct {
+	// Name of the secret, which must be in the same namespace as the
+	// SecretInjector.
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
+
+	// Keys of the secret to inject. If not specified, all keys are injected.
+	// +optional
+	Keys []string `json:"keys,omitempty"`
+
+	// If specified, the keys of the secret are injected as environment
+	// variables.
+	// +optional
+	Env *InjectedSecretEnv `json:"env,omitempty"`
+
+	// If specified, the secret is mounted as a read only volume at this path.
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+
+	// Whether pods can still start if the secret or any of the keys do not
+	// exist.
+	// +optional
+	Optional bool `json:"optional,omitempty"`
+}
+
+// SecretInjectorSpec defines the desired state of SecretInjector
+type SecretInjectorSpec struct {
+	// Selects the pods in the namespace of the SecretInjector which secrets
+	// are injected into when they are created. An empty selector selects all
+	// pods in the namespace.
+	Selector metav1.LabelSelector `json:"selector"`
+
+	// Names of the containers of a pod which secrets are injected into. If
+	// not specified, secrets are injected into all containers of the pod,
+	// but not into init containers.
+	// +optional
+	Containers []string `json:"containers,omitempty"`
+
+	// Secrets to inject.
+	// +kubebuilder:validation:MinItems=1
+	Secrets []InjectedSecret `json:"secrets"`
+}
+
+// SecretInjectorStatus defines the observed state of SecretInjector
+type SecretInjectorStatus struct {
+}
+
+// Annotation added to pods listing the SecretInjectors which injected secrets
+// into the pod.
+const SecretInjectorInjectedByAnnotation = "secrets-manager.advok8s.io/injected-by"
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+
+// SecretInjector is the Schema for the secretinjectors API
+type SecretInjector struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SecretInjectorSpec   `json:"spec,omitempty"`
+	Status SecretInjectorStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SecretInjectorList contains a list of SecretInjector
+type SecretInjectorList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SecretInjector `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SecretInjector{}, &SecretInjectorList{})
+}
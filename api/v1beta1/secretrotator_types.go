@@ -0,0 +1,80 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SecretRotatorSpec defines the desired state of SecretRotator
+type SecretRotatorSpec struct {
+	// Namespaces in which workloads are restarted. If not specified,
+	// workloads in all but Kubernetes system namespaces are restarted.
+	// +optional
+	TargetNamespaces selectors.TargetNamespaces `json:"targetNamespaces,omitempty"`
+}
+
+// SecretRotatorStatus defines the observed state of SecretRotator
+type SecretRotatorStatus struct {
+	// The generation of the SecretRotator last processed by the controller.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Number of workloads which depend on copied secrets watched by the
+	// SecretRotator.
+	Workloads int32 `json:"workloads,omitempty"`
+
+	// Number of workloads restarted by the SecretRotator since it was
+	// created.
+	Restarts int64 `json:"restarts,omitempty"`
+
+	// Time the SecretRotator last restarted a workload.
+	// +optional
+	LastRestartTime *metav1.Time `json:"lastRestartTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Workloads",type=integer,JSONPath=`.status.workloads`
+// +kubebuilder:printcolumn:name="Restarts",type=integer,JSONPath=`.status.restarts`
+// +kubebuilder:printcolumn:name="Last Restart",type=date,JSONPath=`.status.lastRestartTime`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// SecretRotator is the Schema for the secretrotators API. It restarts
+// Deployments and StatefulSets annotated as depending on copied secrets when
+// the data of those secrets changes, so rotated credentials are picked up.
+type SecretRotator struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SecretRotatorSpec   `json:"spec,omitempty"`
+	Status SecretRotatorStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SecretRotatorList contains a list of SecretRotator
+type SecretRotatorList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SecretRotator `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SecretRotator{}, &SecretRotatorList{})
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SecretsManagerConfigSpec defines controller wide settings for the secrets
+// manager. Settings which are not specified fall back to the values given by
+// the command line flags of the manager.
+type SecretsManagerConfigSpec struct {
+	// Namespaces which are never used as target namespaces, nor may claim
+	// secrets. Names may be glob patterns.
+	// +optional
+	DeniedNamespaces []string `json:"deniedNamespaces,omitempty"`
+
+	// Sync period for SecretCopier objects which don't specify one.
+	// +optional
+	DefaultSyncPeriod *metav1.Duration `json:"defaultSyncPeriod,omitempty"`
+
+	// Labels applied to all target secrets. Labels given in the target secret
+	// of a rule take precedence.
+	// +optional
+	TargetSecretLabels map[string]string `json:"targetSecretLabels,omitempty"`
+
+	// Maximum rate at which target secrets are created or updated across all
+	// SecretCopiers. Use 0 to disable the limit.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxSecretWritesPerSecond *int32 `json:"maxSecretWritesPerSecond,omitempty"`
+
+	// Number of target namespaces a rule may match before it must set
+	// allowLargeFanOut to be processed. Use 0 to disable the guard.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	FanOutGuardThreshold *int32 `json:"fanOutGuardThreshold,omitempty"`
+
+	// Whether target namespaces must consent to receiving copies for all
+	// rules, by being annotated with secrets-manager.advok8s.io/accept-copies
+	// set to "true". Rules can require consent even when this is not set.
+	// +optional
+	RequireNamespaceConsent *bool `json:"requireNamespaceConsent,omitempty"`
+
+	// Emergency freeze halting all writes to secrets until the given time.
+	// While frozen, writes which would have been made are reported as they
+	// are in report-only mode, and writing resumes automatically once the
+	// freeze expires.
+	// +optional
+	WriteFreeze *WriteFreeze `json:"writeFreeze,omitempty"`
+}
+
+// WriteFreeze halts all writes to secrets for a bounded period, such as while
+// an incident is investigated.
+type WriteFreeze struct {
+	// Time at which the freeze expires and writes resume.
+	Until metav1.Time `json:"until"`
+
+	// Reason for the freeze, included in events and conditions.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+}
+
+// SecretsManagerConfigStatus defines the observed state of SecretsManagerConfig
+type SecretsManagerConfigStatus struct {
+	// The generation of the SecretsManagerConfig last applied by the manager.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions describing the state of the SecretsManagerConfig.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// Condition types reported in the status of a SecretsManagerConfig.
+const (
+	// The settings have been applied by the manager.
+	ConditionApplied = "Applied"
+
+	// Writes to secrets are frozen until the freeze expires.
+	ConditionWritesFrozen = "WritesFrozen"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+
+// SecretsManagerConfig is the Schema for the secretsmanagerconfigs API. The
+// manager watches the SecretsManagerConfig it is configured to use and applies
+// changes to it without needing to be restarted.
+type SecretsManagerConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SecretsManagerConfigSpec   `json:"spec,omitempty"`
+	Status SecretsManagerConfigStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SecretsManagerConfigList contains a list of SecretsManagerConfig
+type SecretsManagerConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SecretsManagerConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SecretsManagerConfig{}, &SecretsManagerConfigList{})
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VaultSecretKeyReference is a reference to a key of a secret in the
+// namespace of the VaultSecret.
+type VaultSecretKeyReference struct {
+	// Name of the secret.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Key of the secret holding the value.
+	// +kubebuilder:validation:MinLength=1
+	Key string `json:"key"`
+}
+
+// VaultKubernetesAuth configures login using the Vault Kubernetes auth
+// method, presenting a token for a service account in the namespace of the
+// VaultSecret.
+type VaultKubernetesAuth struct {
+	// Path the Kubernetes auth method is mounted at. Defaults to kubernetes.
+	// +optional
+	Mount string `json:"mount,omitempty"`
+
+	// Vault role to log in as.
+	// +kubebuilder:validation:MinLength=1
+	Role string `json:"role"`
+
+	// Name of the service account a token is requested for. Defaults to
+	// default.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// Audiences of the service account token. If not specified, the default
+	// audiences of the API server are used.
+	// +optional
+	Audiences []string `json:"audiences,omitempty"`
+}
+
+// VaultAuth configures how the manager authenticates with Vault. Exactly one
+// method must be given.
+// +kubebuilder:validation:MinProperties=1
+// +kubebuilder:validation:MaxProperties=1
+type VaultAuth struct {
+	// Authenticate using a Vault token held in a secret.
+	// +optional
+	Token *VaultSecretKeyReference `json:"token,omitempty"`
+
+	// Authenticate using the Kubernetes auth method.
+	// +optional
+	Kubernetes *VaultKubernetesAuth `json:"kubernetes,omitempty"`
+}
+
+// VaultSecretSpec defines the desired state of VaultSecret
+type VaultSecretSpec struct {
+	// Address of the Vault server, such as https://vault.example.com:8200.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	Address string `json:"address"`
+
+	// Vault Enterprise namespace to read the secret from. If not specified,
+	// the root namespace is used.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Reference to a secret holding the PEM encoded CA certificates used to
+	// verify the Vault server. If not specified, the system roots are used.
+	// +optional
+	CACertificates *VaultSecretKeyReference `json:"caCertificates,omitempty"`
+
+	// Path the KV secrets engine is mounted at. Defaults to secret.
+	// +optional
+	Mount string `json:"mount,omitempty"`
+
+	// Path of the secret within the KV secrets engine.
+	// +kubebuilder:validation:MinLength=1
+	Path string `json:"path"`
+
+	// Version of the KV secrets engine, either 1 or 2. Defaults to 2.
+	// +kubebuilder:validation:Enum=1;2
+	// +optional
+	KVVersion int32 `json:"kvVersion,omitempty"`
+
+	// How to authenticate with Vault.
+	Auth VaultAuth `json:"auth"`
+
+	// Name of the secret to create in the namespace of the VaultSecret. If
+	// not specified, the name of the VaultSecret is used.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// Interval at which the secret is read again from Vault. Defaults to 5
+	// minutes.
+	// +optional
+	RefreshInterval *metav1.Duration `json:"refreshInterval,omitempty"`
+}
+
+// VaultSecretStatus defines the observed state of VaultSecret
+type VaultSecretStatus struct {
+	// The generation of the VaultSecret last processed by the controller.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Name of the secret created for the VaultSecret.
+	SecretName string `json:"secretName,omitempty"`
+
+	// Version of the secret last read from Vault. Only set for version 2 of
+	// the KV secrets engine.
+	SecretVersion int64 `json:"secretVersion,omitempty"`
+
+	// Time the secret was last read from Vault successfully.
+	// +optional
+	LastRefreshTime *metav1.Time `json:"lastRefreshTime,omitempty"`
+
+	// Conditions describing the state of the VaultSecret.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Path",type=string,JSONPath=`.spec.path`
+// +kubebuilder:printcolumn:name="Secret",type=string,JSONPath=`.status.secretName`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// VaultSecret is the Schema for the vaultsecrets API. It keeps a secret in its
+// namespace in sync with a secret held in a KV secrets engine of HashiCorp
+// Vault, so it can be used as the source secret of SecretCopier rules.
+type VaultSecret struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VaultSecretSpec   `json:"spec,omitempty"`
+	Status VaultSecretStatus `json:"status,omitempty"`
+}
+
+// Name of the secret created for the VaultSecret.
+func (v *VaultSecret) TargetSecretName() string {
+	if v.Spec.SecretName != "" {
+		return v.Spec.SecretName
+	}
+
+	return v.Name
+}
+
+// +kubebuilder:object:root=true
+
+// VaultSecretList contains a list of VaultSecret
+type VaultSecretList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VaultSecret `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VaultSecret{}, &VaultSecretList{})
+}
@@ -21,30 +21,1724 @@ limitations under the License.
 package v1beta1
 
 import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSAccessKeyAuth) DeepCopyInto(out *AWSAccessKeyAuth) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSAccessKeyAuth.
+func (in *AWSAccessKeyAuth) DeepCopy() *AWSAccessKeyAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSAccessKeyAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSAuth) DeepCopyInto(out *AWSAuth) {
+	*out = *in
+	if in.ServiceAccount != nil {
+		in, out := &in.ServiceAccount, &out.ServiceAccount
+		*out = new(AWSServiceAccountAuth)
+		**out = **in
+	}
+	if in.AccessKey != nil {
+		in, out := &in.AccessKey, &out.AccessKey
+		*out = new(AWSAccessKeyAuth)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSAuth.
+func (in *AWSAuth) DeepCopy() *AWSAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSSecret) DeepCopyInto(out *AWSSecret) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSSecret.
+func (in *AWSSecret) DeepCopy() *AWSSecret {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSSecret)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AWSSecret) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSSecretList) DeepCopyInto(out *AWSSecretList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AWSSecret, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSSecretList.
+func (in *AWSSecretList) DeepCopy() *AWSSecretList {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSSecretList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AWSSecretList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSSecretSpec) DeepCopyInto(out *AWSSecretSpec) {
+	*out = *in
+	in.Auth.DeepCopyInto(&out.Auth)
+	if in.RefreshInterval != nil {
+		in, out := &in.RefreshInterval, &out.RefreshInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSSecretSpec.
+func (in *AWSSecretSpec) DeepCopy() *AWSSecretSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSSecretSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSSecretStatus) DeepCopyInto(out *AWSSecretStatus) {
+	*out = *in
+	if in.LastRefreshTime != nil {
+		in, out := &in.LastRefreshTime, &out.LastRefreshTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSSecretStatus.
+func (in *AWSSecretStatus) DeepCopy() *AWSSecretStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSSecretStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AWSServiceAccountAuth) DeepCopyInto(out *AWSServiceAccountAuth) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AWSServiceAccountAuth.
+func (in *AWSServiceAccountAuth) DeepCopy() *AWSServiceAccountAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(AWSServiceAccountAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BundledConfigMap) DeepCopyInto(out *BundledConfigMap) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BundledConfigMap.
+func (in *BundledConfigMap) DeepCopy() *BundledConfigMap {
+	if in == nil {
+		return nil
+	}
+	out := new(BundledConfigMap)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapCopier) DeepCopyInto(out *ConfigMapCopier) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapCopier.
+func (in *ConfigMapCopier) DeepCopy() *ConfigMapCopier {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapCopier)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ConfigMapCopier) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapCopierList) DeepCopyInto(out *ConfigMapCopierList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ConfigMapCopier, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapCopierList.
+func (in *ConfigMapCopierList) DeepCopy() *ConfigMapCopierList {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapCopierList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ConfigMapCopierList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapCopierRule) DeepCopyInto(out *ConfigMapCopierRule) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	out.SourceConfigMap = in.SourceConfigMap
+	in.TargetNamespaces.DeepCopyInto(&out.TargetNamespaces)
+	in.TargetConfigMap.DeepCopyInto(&out.TargetConfigMap)
+	if in.ReclaimPolicyOverrides != nil {
+		in, out := &in.ReclaimPolicyOverrides, &out.ReclaimPolicyOverrides
+		*out = make([]ReclaimPolicyOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapCopierRule.
+func (in *ConfigMapCopierRule) DeepCopy() *ConfigMapCopierRule {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapCopierRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapCopierRuleStatus) DeepCopyInto(out *ConfigMapCopierRuleStatus) {
+	*out = *in
+	out.SourceConfigMap = in.SourceConfigMap
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapCopierRuleStatus.
+func (in *ConfigMapCopierRuleStatus) DeepCopy() *ConfigMapCopierRuleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapCopierRuleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapCopierSpec) DeepCopyInto(out *ConfigMapCopierSpec) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]ConfigMapCopierRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SyncPeriod != nil {
+		in, out := &in.SyncPeriod, &out.SyncPeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapCopierSpec.
+func (in *ConfigMapCopierSpec) DeepCopy() *ConfigMapCopierSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapCopierSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapCopierStatus) DeepCopyInto(out *ConfigMapCopierStatus) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]ConfigMapCopierRuleStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapCopierStatus.
+func (in *ConfigMapCopierStatus) DeepCopy() *ConfigMapCopierStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapCopierStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FirstCopyHook) DeepCopyInto(out *FirstCopyHook) {
+	*out = *in
+	if in.NamespaceLabels != nil {
+		in, out := &in.NamespaceLabels, &out.NamespaceLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.NamespaceAnnotations != nil {
+		in, out := &in.NamespaceAnnotations, &out.NamespaceAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FirstCopyHook.
+func (in *FirstCopyHook) DeepCopy() *FirstCopyHook {
+	if in == nil {
+		return nil
+	}
+	out := new(FirstCopyHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InjectedSecret) DeepCopyInto(out *InjectedSecret) {
+	*out = *in
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = new(InjectedSecretEnv)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InjectedSecret.
+func (in *InjectedSecret) DeepCopy() *InjectedSecret {
+	if in == nil {
+		return nil
+	}
+	out := new(InjectedSecret)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InjectedSecretEnv) DeepCopyInto(out *InjectedSecretEnv) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InjectedSecretEnv.
+func (in *InjectedSecretEnv) DeepCopy() *InjectedSecretEnv {
+	if in == nil {
+		return nil
+	}
+	out := new(InjectedSecretEnv)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeconfigSecretReference) DeepCopyInto(out *KubeconfigSecretReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeconfigSecretReference.
+func (in *KubeconfigSecretReference) DeepCopy() *KubeconfigSecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeconfigSecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedSecretReference) DeepCopyInto(out *ManagedSecretReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedSecretReference.
+func (in *ManagedSecretReference) DeepCopy() *ManagedSecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedSecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedSecretsCopierSummary) DeepCopyInto(out *ManagedSecretsCopierSummary) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedSecretsCopierSummary.
+func (in *ManagedSecretsCopierSummary) DeepCopy() *ManagedSecretsCopierSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedSecretsCopierSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedSecretsReport) DeepCopyInto(out *ManagedSecretsReport) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedSecretsReport.
+func (in *ManagedSecretsReport) DeepCopy() *ManagedSecretsReport {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedSecretsReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ManagedSecretsReport) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedSecretsReportList) DeepCopyInto(out *ManagedSecretsReportList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ManagedSecretsReport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedSecretsReportList.
+func (in *ManagedSecretsReportList) DeepCopy() *ManagedSecretsReportList {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedSecretsReportList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ManagedSecretsReportList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedSecretsReportStatus) DeepCopyInto(out *ManagedSecretsReportStatus) {
+	*out = *in
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	out.Manager = in.Manager
+	if in.SecretCopiers != nil {
+		in, out := &in.SecretCopiers, &out.SecretCopiers
+		*out = make([]ManagedSecretsCopierSummary, len(*in))
+		copy(*out, *in)
+	}
+	if in.Orphans != nil {
+		in, out := &in.Orphans, &out.Orphans
+		*out = make([]ManagedSecretReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conflicts != nil {
+		in, out := &in.Conflicts, &out.Conflicts
+		*out = make([]ManagedSecretReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Stale != nil {
+		in, out := &in.Stale, &out.Stale
+		*out = make([]ManagedSecretReference, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedSecretsReportStatus.
+func (in *ManagedSecretsReportStatus) DeepCopy() *ManagedSecretsReportStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedSecretsReportStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagerBuildInfo) DeepCopyInto(out *ManagerBuildInfo) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagerBuildInfo.
+func (in *ManagerBuildInfo) DeepCopy() *ManagerBuildInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagerBuildInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceReadiness) DeepCopyInto(out *NamespaceReadiness) {
+	*out = *in
+	if in.MatchLabels != nil {
+		in, out := &in.MatchLabels, &out.MatchLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.MatchAnnotations != nil {
+		in, out := &in.MatchAnnotations, &out.MatchAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceReadiness.
+func (in *NamespaceReadiness) DeepCopy() *NamespaceReadiness {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceReadiness)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReclaimPolicyOverride) DeepCopyInto(out *ReclaimPolicyOverride) {
+	*out = *in
+	in.TargetNamespaces.DeepCopyInto(&out.TargetNamespaces)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReclaimPolicyOverride.
+func (in *ReclaimPolicyOverride) DeepCopy() *ReclaimPolicyOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(ReclaimPolicyOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryMirror) DeepCopyInto(out *RegistryMirror) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryMirror.
+func (in *RegistryMirror) DeepCopy() *RegistryMirror {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryMirror)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RequiredResource) DeepCopyInto(out *RequiredResource) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RequiredResource.
+func (in *RequiredResource) DeepCopy() *RequiredResource {
+	if in == nil {
+		return nil
+	}
+	out := new(RequiredResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretCatalog) DeepCopyInto(out *SecretCatalog) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCatalog.
+func (in *SecretCatalog) DeepCopy() *SecretCatalog {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretCatalog)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SecretCatalog) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretCatalogEntry) DeepCopyInto(out *SecretCatalogEntry) {
+	*out = *in
+	out.SourceSecret = in.SourceSecret
+	in.AllowedNamespaces.DeepCopyInto(&out.AllowedNamespaces)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCatalogEntry.
+func (in *SecretCatalogEntry) DeepCopy() *SecretCatalogEntry {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretCatalogEntry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretCatalogList) DeepCopyInto(out *SecretCatalogList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SecretCatalog, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCatalogList.
+func (in *SecretCatalogList) DeepCopy() *SecretCatalogList {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretCatalogList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SecretCatalogList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretCatalogSpec) DeepCopyInto(out *SecretCatalogSpec) {
+	*out = *in
+	if in.Entries != nil {
+		in, out := &in.Entries, &out.Entries
+		*out = make([]SecretCatalogEntry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCatalogSpec.
+func (in *SecretCatalogSpec) DeepCopy() *SecretCatalogSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretCatalogSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretCatalogStatus) DeepCopyInto(out *SecretCatalogStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCatalogStatus.
+func (in *SecretCatalogStatus) DeepCopy() *SecretCatalogStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretCatalogStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretClaim) DeepCopyInto(out *SecretClaim) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretClaim.
+func (in *SecretClaim) DeepCopy() *SecretClaim {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretClaim)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SecretClaim) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretClaimList) DeepCopyInto(out *SecretClaimList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SecretClaim, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretClaimList.
+func (in *SecretClaimList) DeepCopy() *SecretClaimList {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretClaimList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SecretClaimList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretClaimSpec) DeepCopyInto(out *SecretClaimSpec) {
+	*out = *in
+	if in.LeaseDuration != nil {
+		in, out := &in.LeaseDuration, &out.LeaseDuration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretClaimSpec.
+func (in *SecretClaimSpec) DeepCopy() *SecretClaimSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretClaimSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretClaimStatus) DeepCopyInto(out *SecretClaimStatus) {
+	*out = *in
+	if in.LeaseRenewTime != nil {
+		in, out := &in.LeaseRenewTime, &out.LeaseRenewTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LeaseExpiryTime != nil {
+		in, out := &in.LeaseExpiryTime, &out.LeaseExpiryTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretClaimStatus.
+func (in *SecretClaimStatus) DeepCopy() *SecretClaimStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretClaimStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretCopier) DeepCopyInto(out *SecretCopier) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCopier.
+func (in *SecretCopier) DeepCopy() *SecretCopier {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretCopier)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SecretCopier) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretCopierDelegation) DeepCopyInto(out *SecretCopierDelegation) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCopierDelegation.
+func (in *SecretCopierDelegation) DeepCopy() *SecretCopierDelegation {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretCopierDelegation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SecretCopierDelegation) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretCopierDelegationList) DeepCopyInto(out *SecretCopierDelegationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SecretCopierDelegation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCopierDelegationList.
+func (in *SecretCopierDelegationList) DeepCopy() *SecretCopierDelegationList {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretCopierDelegationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SecretCopierDelegationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretCopierDelegationSpec) DeepCopyInto(out *SecretCopierDelegationSpec) {
+	*out = *in
+	in.TargetNamespaces.DeepCopyInto(&out.TargetNamespaces)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCopierDelegationSpec.
+func (in *SecretCopierDelegationSpec) DeepCopy() *SecretCopierDelegationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretCopierDelegationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretCopierDelegationStatus) DeepCopyInto(out *SecretCopierDelegationStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCopierDelegationStatus.
+func (in *SecretCopierDelegationStatus) DeepCopy() *SecretCopierDelegationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretCopierDelegationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretCopierDeniedTarget) DeepCopyInto(out *SecretCopierDeniedTarget) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCopierDeniedTarget.
+func (in *SecretCopierDeniedTarget) DeepCopy() *SecretCopierDeniedTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretCopierDeniedTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretCopierFailedTarget) DeepCopyInto(out *SecretCopierFailedTarget) {
+	*out = *in
+	in.FirstFailureTime.DeepCopyInto(&out.FirstFailureTime)
+	in.LastFailureTime.DeepCopyInto(&out.LastFailureTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCopierFailedTarget.
+func (in *SecretCopierFailedTarget) DeepCopy() *SecretCopierFailedTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretCopierFailedTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretCopierList) DeepCopyInto(out *SecretCopierList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SecretCopier, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCopierList.
+func (in *SecretCopierList) DeepCopy() *SecretCopierList {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretCopierList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SecretCopierList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretCopierRollout) DeepCopyInto(out *SecretCopierRollout) {
+	*out = *in
+	in.CanaryNamespaces.DeepCopyInto(&out.CanaryNamespaces)
+	out.VerificationDelay = in.VerificationDelay
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCopierRollout.
+func (in *SecretCopierRollout) DeepCopy() *SecretCopierRollout {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretCopierRollout)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretCopierRolloutStatus) DeepCopyInto(out *SecretCopierRolloutStatus) {
+	*out = *in
+	if in.CanaryCompletedTime != nil {
+		in, out := &in.CanaryCompletedTime, &out.CanaryCompletedTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCopierRolloutStatus.
+func (in *SecretCopierRolloutStatus) DeepCopy() *SecretCopierRolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretCopierRolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretCopierRule) DeepCopyInto(out *SecretCopierRule) {
+	*out = *in
+	if in.DependsOn != nil {
+		in, out := &in.DependsOn, &out.DependsOn
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	out.SourceSecret = in.SourceSecret
+	in.TargetNamespaces.DeepCopyInto(&out.TargetNamespaces)
+	in.TargetSecret.DeepCopyInto(&out.TargetSecret)
+	if in.BundledConfigMap != nil {
+		in, out := &in.BundledConfigMap, &out.BundledConfigMap
+		*out = new(BundledConfigMap)
+		**out = **in
+	}
+	if in.ReclaimPolicyOverrides != nil {
+		in, out := &in.ReclaimPolicyOverrides, &out.ReclaimPolicyOverrides
+		*out = make([]ReclaimPolicyOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AdoptFrom != nil {
+		in, out := &in.AdoptFrom, &out.AdoptFrom
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TargetNamespaceReadiness != nil {
+		in, out := &in.TargetNamespaceReadiness, &out.TargetNamespaceReadiness
+		*out = new(NamespaceReadiness)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RequiredResource != nil {
+		in, out := &in.RequiredResource, &out.RequiredResource
+		*out = new(RequiredResource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(SecretCopierRollout)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MinInterval != nil {
+		in, out := &in.MinInterval, &out.MinInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.OnFirstCopy != nil {
+		in, out := &in.OnFirstCopy, &out.OnFirstCopy
+		*out = new(FirstCopyHook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TargetCluster != nil {
+		in, out := &in.TargetCluster, &out.TargetCluster
+		*out = new(TargetCluster)
+		**out = **in
+	}
+	if in.VirtualClusterNamespaces != nil {
+		in, out := &in.VirtualClusterNamespaces, &out.VirtualClusterNamespaces
+		*out = new(VirtualClusterNamespaces)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCopierRule.
+func (in *SecretCopierRule) DeepCopy() *SecretCopierRule {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretCopierRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretCopierRuleStatus) DeepCopyInto(out *SecretCopierRuleStatus) {
+	*out = *in
+	out.SourceSecret = in.SourceSecret
+	if in.DeniedNamespaces != nil {
+		in, out := &in.DeniedNamespaces, &out.DeniedNamespaces
+		*out = make([]SecretCopierDeniedTarget, len(*in))
+		copy(*out, *in)
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(SecretCopierRolloutStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastErrorTime != nil {
+		in, out := &in.LastErrorTime, &out.LastErrorTime
+		*out = (*in).DeepCopy()
+	}
+	if in.SyncInterval != nil {
+		in, out := &in.SyncInterval, &out.SyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCopierRuleStatus.
+func (in *SecretCopierRuleStatus) DeepCopy() *SecretCopierRuleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretCopierRuleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretCopierServiceAccount) DeepCopyInto(out *SecretCopierServiceAccount) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCopierServiceAccount.
+func (in *SecretCopierServiceAccount) DeepCopy() *SecretCopierServiceAccount {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretCopierServiceAccount)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretCopierSpec) DeepCopyInto(out *SecretCopierSpec) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]SecretCopierRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ServiceAccount != nil {
+		in, out := &in.ServiceAccount, &out.ServiceAccount
+		*out = new(SecretCopierServiceAccount)
+		**out = **in
+	}
+	if in.SyncPeriod != nil {
+		in, out := &in.SyncPeriod, &out.SyncPeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCopierSpec.
+func (in *SecretCopierSpec) DeepCopy() *SecretCopierSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretCopierSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretCopierStatus) DeepCopyInto(out *SecretCopierStatus) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]SecretCopierRuleStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.FailedTargets != nil {
+		in, out := &in.FailedTargets, &out.FailedTargets
+		*out = make([]SecretCopierFailedTarget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TargetClusters != nil {
+		in, out := &in.TargetClusters, &out.TargetClusters
+		*out = make([]SecretCopierTargetClusterStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SyncPeriod != nil {
+		in, out := &in.SyncPeriod, &out.SyncPeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.NextSyncTime != nil {
+		in, out := &in.NextSyncTime, &out.NextSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCopierStatus.
+func (in *SecretCopierStatus) DeepCopy() *SecretCopierStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretCopierStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretCopierTargetClusterStatus) DeepCopyInto(out *SecretCopierTargetClusterStatus) {
+	*out = *in
+	if in.NextRetryTime != nil {
+		in, out := &in.NextRetryTime, &out.NextRetryTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCopierTargetClusterStatus.
+func (in *SecretCopierTargetClusterStatus) DeepCopy() *SecretCopierTargetClusterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretCopierTargetClusterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretExport) DeepCopyInto(out *SecretExport) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretExport.
+func (in *SecretExport) DeepCopy() *SecretExport {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretExport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SecretExport) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretExportList) DeepCopyInto(out *SecretExportList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SecretExport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretExportList.
+func (in *SecretExportList) DeepCopy() *SecretExportList {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretExportList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SecretExportList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretExportSpec) DeepCopyInto(out *SecretExportSpec) {
+	*out = *in
+	in.ToNamespaces.DeepCopyInto(&out.ToNamespaces)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretExportSpec.
+func (in *SecretExportSpec) DeepCopy() *SecretExportSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretExportSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretExportStatus) DeepCopyInto(out *SecretExportStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretExportStatus.
+func (in *SecretExportStatus) DeepCopy() *SecretExportStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretExportStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretImport) DeepCopyInto(out *SecretImport) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretImport.
+func (in *SecretImport) DeepCopy() *SecretImport {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretImport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SecretImport) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretImportList) DeepCopyInto(out *SecretImportList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SecretImport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretImportList.
+func (in *SecretImportList) DeepCopy() *SecretImportList {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretImportList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SecretImportList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretImportSpec) DeepCopyInto(out *SecretImportSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretImportSpec.
+func (in *SecretImportSpec) DeepCopy() *SecretImportSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretImportSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretImportStatus) DeepCopyInto(out *SecretImportStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretImportStatus.
+func (in *SecretImportStatus) DeepCopy() *SecretImportStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretImportStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretInjector) DeepCopyInto(out *SecretInjector) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretInjector.
+func (in *SecretInjector) DeepCopy() *SecretInjector {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretInjector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SecretInjector) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretInjectorList) DeepCopyInto(out *SecretInjectorList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SecretInjector, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretInjectorList.
+func (in *SecretInjectorList) DeepCopy() *SecretInjectorList {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretInjectorList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SecretInjectorList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretInjectorSpec) DeepCopyInto(out *SecretInjectorSpec) {
+	*out = *in
+	in.Selector.DeepCopyInto(&out.Selector)
+	if in.Containers != nil {
+		in, out := &in.Containers, &out.Containers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Secrets != nil {
+		in, out := &in.Secrets, &out.Secrets
+		*out = make([]InjectedSecret, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretInjectorSpec.
+func (in *SecretInjectorSpec) DeepCopy() *SecretInjectorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretInjectorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretInjectorStatus) DeepCopyInto(out *SecretInjectorStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretInjectorStatus.
+func (in *SecretInjectorStatus) DeepCopy() *SecretInjectorStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretInjectorStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretRotator) DeepCopyInto(out *SecretRotator) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretRotator.
+func (in *SecretRotator) DeepCopy() *SecretRotator {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretRotator)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SecretRotator) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretRotatorList) DeepCopyInto(out *SecretRotatorList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SecretRotator, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretRotatorList.
+func (in *SecretRotatorList) DeepCopy() *SecretRotatorList {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretRotatorList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SecretRotatorList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretRotatorSpec) DeepCopyInto(out *SecretRotatorSpec) {
+	*out = *in
+	in.TargetNamespaces.DeepCopyInto(&out.TargetNamespaces)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretRotatorSpec.
+func (in *SecretRotatorSpec) DeepCopy() *SecretRotatorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretRotatorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretRotatorStatus) DeepCopyInto(out *SecretRotatorStatus) {
+	*out = *in
+	if in.LastRestartTime != nil {
+		in, out := &in.LastRestartTime, &out.LastRestartTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretRotatorStatus.
+func (in *SecretRotatorStatus) DeepCopy() *SecretRotatorStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretRotatorStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretsManagerConfig) DeepCopyInto(out *SecretsManagerConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCopier.
-func (in *SecretCopier) DeepCopy() *SecretCopier {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretsManagerConfig.
+func (in *SecretsManagerConfig) DeepCopy() *SecretsManagerConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(SecretCopier)
+	out := new(SecretsManagerConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *SecretCopier) DeepCopyObject() runtime.Object {
+func (in *SecretsManagerConfig) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -52,31 +1746,31 @@ func (in *SecretCopier) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SecretCopierList) DeepCopyInto(out *SecretCopierList) {
+func (in *SecretsManagerConfigList) DeepCopyInto(out *SecretsManagerConfigList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]SecretCopier, len(*in))
+		*out = make([]SecretsManagerConfig, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCopierList.
-func (in *SecretCopierList) DeepCopy() *SecretCopierList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretsManagerConfigList.
+func (in *SecretsManagerConfigList) DeepCopy() *SecretsManagerConfigList {
 	if in == nil {
 		return nil
 	}
-	out := new(SecretCopierList)
+	out := new(SecretsManagerConfigList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *SecretCopierList) DeepCopyObject() runtime.Object {
+func (in *SecretsManagerConfigList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -84,57 +1778,90 @@ func (in *SecretCopierList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SecretCopierRule) DeepCopyInto(out *SecretCopierRule) {
+func (in *SecretsManagerConfigSpec) DeepCopyInto(out *SecretsManagerConfigSpec) {
 	*out = *in
-	out.SourceSecret = in.SourceSecret
-	in.TargetNamespaces.DeepCopyInto(&out.TargetNamespaces)
-	in.TargetSecret.DeepCopyInto(&out.TargetSecret)
+	if in.DeniedNamespaces != nil {
+		in, out := &in.DeniedNamespaces, &out.DeniedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DefaultSyncPeriod != nil {
+		in, out := &in.DefaultSyncPeriod, &out.DefaultSyncPeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.TargetSecretLabels != nil {
+		in, out := &in.TargetSecretLabels, &out.TargetSecretLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.MaxSecretWritesPerSecond != nil {
+		in, out := &in.MaxSecretWritesPerSecond, &out.MaxSecretWritesPerSecond
+		*out = new(int32)
+		**out = **in
+	}
+	if in.FanOutGuardThreshold != nil {
+		in, out := &in.FanOutGuardThreshold, &out.FanOutGuardThreshold
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RequireNamespaceConsent != nil {
+		in, out := &in.RequireNamespaceConsent, &out.RequireNamespaceConsent
+		*out = new(bool)
+		**out = **in
+	}
+	if in.WriteFreeze != nil {
+		in, out := &in.WriteFreeze, &out.WriteFreeze
+		*out = new(WriteFreeze)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCopierRule.
-func (in *SecretCopierRule) DeepCopy() *SecretCopierRule {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretsManagerConfigSpec.
+func (in *SecretsManagerConfigSpec) DeepCopy() *SecretsManagerConfigSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(SecretCopierRule)
+	out := new(SecretsManagerConfigSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SecretCopierSpec) DeepCopyInto(out *SecretCopierSpec) {
+func (in *SecretsManagerConfigStatus) DeepCopyInto(out *SecretsManagerConfigStatus) {
 	*out = *in
-	if in.Rules != nil {
-		in, out := &in.Rules, &out.Rules
-		*out = make([]SecretCopierRule, len(*in))
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	out.SyncPeriod = in.SyncPeriod
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCopierSpec.
-func (in *SecretCopierSpec) DeepCopy() *SecretCopierSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretsManagerConfigStatus.
+func (in *SecretsManagerConfigStatus) DeepCopy() *SecretsManagerConfigStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(SecretCopierSpec)
+	out := new(SecretsManagerConfigStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *SecretCopierStatus) DeepCopyInto(out *SecretCopierStatus) {
+func (in *SourceConfigMap) DeepCopyInto(out *SourceConfigMap) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretCopierStatus.
-func (in *SecretCopierStatus) DeepCopy() *SecretCopierStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SourceConfigMap.
+func (in *SourceConfigMap) DeepCopy() *SourceConfigMap {
 	if in == nil {
 		return nil
 	}
-	out := new(SecretCopierStatus)
+	out := new(SourceConfigMap)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -154,6 +1881,44 @@ func (in *SourceSecret) DeepCopy() *SourceSecret {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetCluster) DeepCopyInto(out *TargetCluster) {
+	*out = *in
+	out.KubeconfigSecret = in.KubeconfigSecret
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetCluster.
+func (in *TargetCluster) DeepCopy() *TargetCluster {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetCluster)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetConfigMap) DeepCopyInto(out *TargetConfigMap) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetConfigMap.
+func (in *TargetConfigMap) DeepCopy() *TargetConfigMap {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetConfigMap)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TargetSecret) DeepCopyInto(out *TargetSecret) {
 	*out = *in
@@ -164,6 +1929,38 @@ func (in *TargetSecret) DeepCopyInto(out *TargetSecret) {
 			(*out)[key] = val
 		}
 	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.IncludeKeys != nil {
+		in, out := &in.IncludeKeys, &out.IncludeKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeKeys != nil {
+		in, out := &in.ExcludeKeys, &out.ExcludeKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.HashSuffix != nil {
+		in, out := &in.HashSuffix, &out.HashSuffix
+		*out = new(TargetSecretHashSuffix)
+		**out = **in
+	}
+	if in.Template != nil {
+		in, out := &in.Template, &out.Template
+		*out = new(TargetSecretTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RegistryMirrors != nil {
+		in, out := &in.RegistryMirrors, &out.RegistryMirrors
+		*out = make([]RegistryMirror, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetSecret.
@@ -175,3 +1972,242 @@ func (in *TargetSecret) DeepCopy() *TargetSecret {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetSecretHashSuffix) DeepCopyInto(out *TargetSecretHashSuffix) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetSecretHashSuffix.
+func (in *TargetSecretHashSuffix) DeepCopy() *TargetSecretHashSuffix {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetSecretHashSuffix)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetSecretTemplate) DeepCopyInto(out *TargetSecretTemplate) {
+	*out = *in
+	if in.Data != nil {
+		in, out := &in.Data, &out.Data
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetSecretTemplate.
+func (in *TargetSecretTemplate) DeepCopy() *TargetSecretTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetSecretTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultAuth) DeepCopyInto(out *VaultAuth) {
+	*out = *in
+	if in.Token != nil {
+		in, out := &in.Token, &out.Token
+		*out = new(VaultSecretKeyReference)
+		**out = **in
+	}
+	if in.Kubernetes != nil {
+		in, out := &in.Kubernetes, &out.Kubernetes
+		*out = new(VaultKubernetesAuth)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultAuth.
+func (in *VaultAuth) DeepCopy() *VaultAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultKubernetesAuth) DeepCopyInto(out *VaultKubernetesAuth) {
+	*out = *in
+	if in.Audiences != nil {
+		in, out := &in.Audiences, &out.Audiences
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultKubernetesAuth.
+func (in *VaultKubernetesAuth) DeepCopy() *VaultKubernetesAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultKubernetesAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultSecret) DeepCopyInto(out *VaultSecret) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultSecret.
+func (in *VaultSecret) DeepCopy() *VaultSecret {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultSecret)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VaultSecret) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultSecretKeyReference) DeepCopyInto(out *VaultSecretKeyReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultSecretKeyReference.
+func (in *VaultSecretKeyReference) DeepCopy() *VaultSecretKeyReference {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultSecretKeyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultSecretList) DeepCopyInto(out *VaultSecretList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VaultSecret, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultSecretList.
+func (in *VaultSecretList) DeepCopy() *VaultSecretList {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultSecretList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VaultSecretList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultSecretSpec) DeepCopyInto(out *VaultSecretSpec) {
+	*out = *in
+	if in.CACertificates != nil {
+		in, out := &in.CACertificates, &out.CACertificates
+		*out = new(VaultSecretKeyReference)
+		**out = **in
+	}
+	in.Auth.DeepCopyInto(&out.Auth)
+	if in.RefreshInterval != nil {
+		in, out := &in.RefreshInterval, &out.RefreshInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultSecretSpec.
+func (in *VaultSecretSpec) DeepCopy() *VaultSecretSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultSecretSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultSecretStatus) DeepCopyInto(out *VaultSecretStatus) {
+	*out = *in
+	if in.LastRefreshTime != nil {
+		in, out := &in.LastRefreshTime, &out.LastRefreshTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultSecretStatus.
+func (in *VaultSecretStatus) DeepCopy() *VaultSecretStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultSecretStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualClusterNamespaces) DeepCopyInto(out *VirtualClusterNamespaces) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualClusterNamespaces.
+func (in *VirtualClusterNamespaces) DeepCopy() *VirtualClusterNamespaces {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualClusterNamespaces)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WriteFreeze) DeepCopyInto(out *WriteFreeze) {
+	*out = *in
+	in.Until.DeepCopyInto(&out.Until)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WriteFreeze.
+func (in *WriteFreeze) DeepCopy() *WriteFreeze {
+	if in == nil {
+		return nil
+	}
+	out := new(WriteFreeze)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/yaml"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/internal/controller"
+)
+
+// Run the apply subcommand, which performs the copies described by the
+// SecretCopier manifests in the given files once, prints a summary and exits.
+// Returns the exit status for the process.
+func runApply(args []string) int {
+	var output string
+	var timeout time.Duration
+	var reportOnly bool
+
+	flags := flag.NewFlagSet("apply", flag.ExitOnError)
+
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s apply [flags] FILE...\n\n", os.Args[0])
+		fmt.Fprintf(flags.Output(), "Perform the copies described by SecretCopier manifests once. Use - to read from stdin.\n\n")
+		flags.PrintDefaults()
+	}
+
+	flags.StringVar(&output, "output", "json", "Format of the summary, either json or yaml.")
+	flags.BoolVar(&reportOnly, "report-only", false, "If set, report the writes which would be made without writing any secrets.")
+	flags.DurationVar(&timeout, "timeout", 5*time.Minute, "Maximum time to spend applying the manifests.")
+
+	opts := zap.Options{}
+	opts.BindFlags(flags)
+
+	_ = flags.Parse(args)
+
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+
+	if flags.NArg() == 0 || (output != "json" && output != "yaml") {
+		flags.Usage()
+		return 2
+	}
+
+	var secretCopiers []secretsv1beta1.SecretCopier
+
+	for _, path := range flags.Args() {
+		loaded, err := loadSecretCopiers(path)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to load %s: %v\n", path, err)
+			return 2
+		}
+
+		secretCopiers = append(secretCopiers, loaded...)
+	}
+
+	config, err := ctrl.GetConfig()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to load Kubernetes configuration: %v\n", err)
+		return 2
+	}
+
+	c, err := client.New(config, client.Options{Scheme: scheme})
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to create Kubernetes client: %v\n", err)
+		return 2
+	}
+
+	reconciler := &controller.SecretCopierReconciler{
+		Client:     c,
+		Scheme:     scheme,
+		Recorder:   &record.FakeRecorder{},
+		ReportOnly: reportOnly,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	summaries := make([]controller.ApplySummary, 0, len(secretCopiers))
+
+	status := 0
+
+	for i := range secretCopiers {
+		summary, err := reconciler.ApplyOnce(ctx, &secretCopiers[i])
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to apply SecretCopier %s: %v\n", secretCopiers[i].Name, err)
+			return 2
+		}
+
+		if summary.Failed() {
+			status = 1
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	var data []byte
+
+	if output == "yaml" {
+		data, err = yaml.Marshal(summaries)
+	} else {
+		data, err = json.MarshalIndent(summaries, "", "  ")
+		data = append(data, '\n')
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to format summary: %v\n", err)
+		return 2
+	}
+
+	_, _ = os.Stdout.Write(data)
+
+	return status
+}
+
+// Load the SecretCopier manifests from a file containing one or more YAML or
+// JSON documents, ignoring documents for other kinds of resources.
+func loadSecretCopiers(path string) ([]secretsv1beta1.SecretCopier, error) {
+	var reader io.Reader = os.Stdin
+
+	if path != "-" {
+		file, err := os.Open(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		defer file.Close()
+
+		reader = file
+	}
+
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bufio.NewReader(reader), 4096)
+
+	var secretCopiers []secretsv1beta1.SecretCopier
+
+	for {
+		var secretCopier secretsv1beta1.SecretCopier
+
+		if err := decoder.Decode(&secretCopier); err != nil {
+			if errors.Is(err, io.EOF) {
+				return secretCopiers, nil
+			}
+
+			return nil, err
+		}
+
+		if secretCopier.Kind != "SecretCopier" || secretCopier.APIVersion != secretsv1beta1.GroupVersion.String() {
+			continue
+		}
+
+		if secretCopier.Name == "" {
+			return nil, fmt.Errorf("SecretCopier is missing a name")
+		}
+
+		secretCopiers = append(secretCopiers, secretCopier)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/advok8s/advok8s-secrets-manager/internal/controller"
+	"github.com/advok8s/advok8s-secrets-manager/internal/csiprovider"
+	"github.com/advok8s/advok8s-secrets-manager/internal/version"
+)
+
+// Run the csi-provider subcommand, which serves the provider API of the
+// Secrets Store CSI driver so pods can mount SecretCatalog entries as files.
+// Returns the exit status for the process.
+func runCSIProvider(args []string) int {
+	var socketPath string
+	var authorizeMounts bool
+	var neverMountSecretTypes string
+
+	flags := flag.NewFlagSet("csi-provider", flag.ExitOnError)
+
+	flags.StringVar(&socketPath, "socket-path", csiprovider.DefaultSocketPath,
+		"Path of the unix socket the provider listens on.")
+	flags.BoolVar(&authorizeMounts, "authorize-mounts", false,
+		"If set, the service account of a pod must be granted create on the secretcatalogentries/claim "+
+			"subresource to mount a catalog entry.")
+	flags.StringVar(&neverMountSecretTypes, "never-mount-secret-types", joinSecretTypes(controller.DefaultNeverCopySecretTypes),
+		"Comma separated list of secret types which are never mounted.")
+
+	opts := zap.Options{}
+	opts.BindFlags(flags)
+
+	_ = flags.Parse(args)
+
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+
+	config, err := ctrl.GetConfig()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to load Kubernetes configuration: %v\n", err)
+		return 2
+	}
+
+	c, err := client.New(config, client.Options{Scheme: scheme})
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to create Kubernetes client: %v\n", err)
+		return 2
+	}
+
+	server := &csiprovider.Server{
+		Client:                c,
+		AuthorizeMounts:       authorizeMounts,
+		NeverMountSecretTypes: splitSecretTypes(neverMountSecretTypes),
+		RuntimeVersion:        version.Get().Version,
+	}
+
+	ctx := ctrl.LoggerInto(ctrl.SetupSignalHandler(), ctrl.Log.WithName("csi-provider"))
+
+	if err := server.Serve(ctx, socketPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to serve CSI provider: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/advok8s/advok8s-secrets-manager/internal/doctor"
+)
+
+// File from which the namespace of the manager is read when running in a pod.
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// Run the doctor subcommand, which checks that the secrets manager has been
+// deployed correctly. Returns the exit status for the process.
+func runDoctor(args []string) int {
+	var options doctor.Options
+	var enableLeaderElection bool
+
+	namespace := "advok8s-secrets-manager-system"
+
+	if data, err := os.ReadFile(serviceAccountNamespaceFile); err == nil {
+		namespace = strings.TrimSpace(string(data))
+	}
+
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+
+	flags.StringVar(&options.Namespace, "namespace", namespace,
+		"Namespace the manager is deployed in.")
+	flags.StringVar(&options.ServiceAccount, "service-account", "",
+		"Service account the manager runs as, in the form namespace:name. If empty, the permissions of the caller are checked.")
+	flags.BoolVar(&enableLeaderElection, "leader-elect", true,
+		"Whether the manager is running with leader election enabled.")
+	flags.BoolVar(&options.Webhooks, "webhooks", true,
+		"Whether the validating webhook is expected to be enabled.")
+	flags.BoolVar(&options.ReportOnly, "report-only", false,
+		"Value of the same flag passed to the manager.")
+	flags.StringVar(&options.TargetNamespaceStatusConfigMap, "target-namespace-status-configmap", "",
+		"Value of the same flag passed to the manager.")
+	flags.BoolVar(&options.AuthorizeSecretClaims, "authorize-secret-claims", false,
+		"Value of the same flag passed to the manager.")
+	flags.StringVar(&options.ManagedSecretsReport, "managed-secrets-report", "cluster",
+		"Value of the same flag passed to the manager.")
+	flags.StringVar(&options.ManagerConfig, "manager-config", "cluster",
+		"Value of the same flag passed to the manager.")
+	flags.BoolVar(&options.SecretsReadyAnnotation, "secrets-ready-annotation", false,
+		"Value of the same flag passed to the manager.")
+
+	_ = flags.Parse(args)
+
+	if enableLeaderElection {
+		options.LeaderElectionID = leaderElectionID
+	}
+
+	if err := apiextensionsv1.AddToScheme(scheme); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to register scheme: %v\n", err)
+		return 2
+	}
+
+	config, err := ctrl.GetConfig()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to load Kubernetes configuration: %v\n", err)
+		return 2
+	}
+
+	c, err := client.New(config, client.Options{Scheme: scheme})
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to create Kubernetes client: %v\n", err)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if !doctor.Run(ctx, c, options, os.Stdout) {
+		return 1
+	}
+
+	return 0
+}
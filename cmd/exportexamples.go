@@ -0,0 +1,115 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/advok8s/advok8s-secrets-manager/internal/controller"
+)
+
+// Run the export-examples subcommand, which finds secrets shared by hand
+// between namespaces and writes SecretCopier manifests which would do the
+// same, to help bootstrap rules from what is already in the cluster. Returns
+// the exit status for the process.
+func runExportExamples(args []string) int {
+	var excludeNamespaces string
+	var minCopies int
+	var output string
+	var timeout time.Duration
+
+	flags := flag.NewFlagSet("export-examples", flag.ExitOnError)
+
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s export-examples [flags]\n\n", os.Args[0])
+		fmt.Fprintf(flags.Output(), "Suggest SecretCopier manifests for secrets with identical data in several namespaces.\n\n")
+		flags.PrintDefaults()
+	}
+
+	flags.StringVar(&excludeNamespaces, "exclude-namespaces", "kube-*",
+		"Comma separated list of namespaces, which may be glob patterns, whose secrets are ignored.")
+	flags.IntVar(&minCopies, "min-copies", 1, "Minimum number of copies in other namespaces for a secret to be reported.")
+	flags.StringVar(&output, "output", "", "File to write the manifests to. If empty, they are written to standard output.")
+	flags.DurationVar(&timeout, "timeout", time.Minute, "Maximum time to spend looking for shared secrets.")
+
+	_ = flags.Parse(args)
+
+	options := controller.SharedSecretOptions{MinCopies: minCopies}
+
+	for _, name := range strings.Split(excludeNamespaces, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			options.ExcludedNamespaces = append(options.ExcludedNamespaces, name)
+		}
+	}
+
+	config, err := ctrl.GetConfig()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to load Kubernetes configuration: %v\n", err)
+		return 2
+	}
+
+	c, err := client.New(config, client.Options{Scheme: scheme})
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to create Kubernetes client: %v\n", err)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	shared, err := controller.CollectSharedSecrets(ctx, c, options)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to collect shared secrets: %v\n", err)
+		return 1
+	}
+
+	out := io.Writer(os.Stdout)
+
+	if output != "" {
+		file, err := os.Create(output)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to create output file: %v\n", err)
+			return 2
+		}
+
+		defer file.Close()
+
+		out = file
+	}
+
+	if err := controller.WriteExamples(out, shared); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to write manifests: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "Suggested %d SecretCopiers for secrets shared between namespaces\n", len(shared))
+
+	return 0
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/advok8s/advok8s-secrets-manager/internal/controller"
+)
+
+// Run the inventory subcommand, which exports the target secrets managed by
+// SecretCopiers as CSV or JSON for compliance reporting and offline analysis.
+// Returns the exit status for the process.
+func runInventory(args []string) int {
+	var format string
+	var output string
+	var timeout time.Duration
+
+	flags := flag.NewFlagSet("inventory", flag.ExitOnError)
+
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s inventory [flags]\n\n", os.Args[0])
+		fmt.Fprintf(flags.Output(), "Export the inventory of secrets managed by SecretCopiers.\n\n")
+		flags.PrintDefaults()
+	}
+
+	flags.StringVar(&format, "format", "csv", "Format of the inventory, either csv or json.")
+	flags.StringVar(&output, "output", "", "File to write the inventory to. If empty, it is written to standard output.")
+	flags.DurationVar(&timeout, "timeout", time.Minute, "Maximum time to spend collecting the inventory.")
+
+	_ = flags.Parse(args)
+
+	var write func(io.Writer, []controller.InventoryEntry) error
+
+	switch format {
+	case "csv":
+		write = controller.WriteInventoryCSV
+	case "json":
+		write = controller.WriteInventoryJSON
+	default:
+		fmt.Fprintf(os.Stderr, "Invalid format %q, must be csv or json\n\n", format)
+		flags.Usage()
+		return 2
+	}
+
+	config, err := ctrl.GetConfig()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to load Kubernetes configuration: %v\n", err)
+		return 2
+	}
+
+	c, err := client.New(config, client.Options{Scheme: scheme})
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to create Kubernetes client: %v\n", err)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	entries, err := controller.CollectInventory(ctx, c)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to collect inventory: %v\n", err)
+		return 1
+	}
+
+	out := io.Writer(os.Stdout)
+
+	if output != "" {
+		file, err := os.Create(output)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to create output file: %v\n", err)
+			return 2
+		}
+
+		defer file.Close()
+
+		out = file
+	}
+
+	if err := write(out, entries); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to write inventory: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
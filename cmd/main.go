@@ -18,17 +18,24 @@ package main
 
 import (
 	"crypto/tls"
+	"encoding/json"
 	"flag"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
@@ -37,9 +44,17 @@ import (
 
 	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
 	"github.com/advok8s/advok8s-secrets-manager/internal/controller"
+	"github.com/advok8s/advok8s-secrets-manager/internal/features"
+	"github.com/advok8s/advok8s-secrets-manager/internal/version"
+	webhookcorev1 "github.com/advok8s/advok8s-secrets-manager/internal/webhook/v1"
+	webhooksecretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/internal/webhook/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/mutation"
 	// +kubebuilder:scaffold:imports
 )
 
+// Name of the lease used for leader election.
+const leaderElectionID = "dc911fa3.advok8s.io"
+
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
@@ -53,11 +68,100 @@ func init() {
 }
 
 func main() {
+	// The version subcommand prints the build information of the binary.
+
+	if len(os.Args) > 1 && os.Args[1] == "version" {
+		_ = json.NewEncoder(os.Stdout).Encode(version.Get())
+		os.Exit(0)
+	}
+
+	// The doctor subcommand checks the deployment instead of running the
+	// manager.
+
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		os.Exit(runDoctor(os.Args[2:]))
+	}
+
+	// The apply subcommand performs the copies described by SecretCopier
+	// manifests once and exits, for use in pipelines.
+
+	if len(os.Args) > 1 && os.Args[1] == "apply" {
+		os.Exit(runApply(os.Args[2:]))
+	}
+
+	// The csi-provider subcommand serves SecretCatalog entries to the
+	// Secrets Store CSI driver instead of running the manager.
+
+	if len(os.Args) > 1 && os.Args[1] == "csi-provider" {
+		os.Exit(runCSIProvider(os.Args[2:]))
+	}
+
+	// The scale-test subcommand generates synthetic resources and reports
+	// how quickly the manager copies secrets, for capacity planning.
+
+	if len(os.Args) > 1 && os.Args[1] == "scale-test" {
+		os.Exit(runScaleTest(os.Args[2:]))
+	}
+
+	// The inventory subcommand exports the secrets managed by SecretCopiers
+	// as CSV or JSON.
+
+	if len(os.Args) > 1 && os.Args[1] == "inventory" {
+		os.Exit(runInventory(os.Args[2:]))
+	}
+
+	// The plan subcommand snapshots the target secrets SecretCopiers should
+	// produce, or compares them against an earlier snapshot.
+
+	if len(os.Args) > 1 && os.Args[1] == "plan" {
+		os.Exit(runPlan(os.Args[2:]))
+	}
+
+	// The export-examples subcommand suggests SecretCopier manifests for
+	// secrets which have been shared between namespaces by hand.
+
+	if len(os.Args) > 1 && os.Args[1] == "export-examples" {
+		os.Exit(runExportExamples(os.Args[2:]))
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var maxSecretWritesPerSecond float64
+	var fanOutGuardThreshold int
+	var requireNamespaceConsent bool
+	var reconcileCoalesceWindow time.Duration
+	var warmUpWindow time.Duration
+	var ruleProcessingBudget time.Duration
+	var copierQueueRate float64
+	var maxConcurrentReconciles int
+	var copyWorkers int
+	var newNamespaceWorkers int
+	var copierQueueBurst int
+	var sourceWaitRequeue time.Duration
+	var sourceWaitMaxRequeue time.Duration
+	var targetClusterHealthInterval time.Duration
+	var secretCopierLimits webhooksecretsv1beta1.SecretCopierLimits
+	var targetNamespaceEvents bool
+	var sourceSecretEvents bool
+	var copyEvents bool
+	var recreateOnTypeChange bool
+	var secretsReadyAnnotation bool
+	var eventAggregationWindow time.Duration
+	var targetNamespaceStatusConfigMap string
+	var authorizeSecretClaims bool
+	var managedSecretsReportName string
+	var managedSecretsReportInterval time.Duration
+	var reapOrphanedSecrets bool
+	var migrateOnStartup bool
+	var managerConfigName string
+	var reportOnly bool
+	var targetMutatorType string
+	var targetMutatorOptions string
+	var neverCopySecretTypes string
+	var orphanedSecretGracePeriod time.Duration
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -69,6 +173,100 @@ func main() {
 		"If set, the metrics endpoint is served securely via HTTPS. Use --metrics-secure=false to use HTTP instead.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.Float64Var(&maxSecretWritesPerSecond, "max-secret-writes-per-second", 0,
+		"Maximum rate at which target secrets are created or updated across all SecretCopiers. "+
+			"Writes beyond the limit are deferred and resumed on a later reconcile. Use 0 to disable.")
+	flag.IntVar(&fanOutGuardThreshold, "fan-out-guard-threshold", 0,
+		"Number of target namespaces a SecretCopier rule may match before it must set allowLargeFanOut to be processed. "+
+			"Use 0 to disable the guard.")
+	flag.BoolVar(&requireNamespaceConsent, "require-namespace-consent", false,
+		"If set, secrets are only copied into target namespaces annotated with "+
+			"secrets-manager.advok8s.io/accept-copies set to \"true\", for all SecretCopier rules.")
+	flag.DurationVar(&reconcileCoalesceWindow, "reconcile-coalesce-window", 500*time.Millisecond,
+		"Window over which reconcile requests for a SecretCopier triggered by changes to secrets and "+
+			"namespaces are coalesced into a single reconcile. Use 0 to disable.")
+	flag.DurationVar(&warmUpWindow, "warm-up-window", 0,
+		"Window over which the reconciles of existing SecretCopiers are spread when the manager starts, "+
+			"to avoid load spikes on the API server. Use 0 to reconcile them all immediately.")
+	flag.DurationVar(&ruleProcessingBudget, "rule-processing-budget", 30*time.Second,
+		"Maximum time spent copying the secret of a single SecretCopier rule in one reconcile before the remaining "+
+			"target namespaces are deferred to the next, so large rules don't hold up other rules. Use 0 to disable.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"Number of SecretCopiers which can be reconciled concurrently.")
+	flag.IntVar(&copyWorkers, "copy-workers", 1,
+		"Number of target namespaces of a SecretCopier rule the source secret is copied to concurrently.")
+	flag.IntVar(&newNamespaceWorkers, "new-namespace-workers", 1,
+		"Number of newly created namespaces secrets are copied into concurrently, using a queue separate from "+
+			"that of SecretCopiers so new namespaces aren't held up by large routine syncs. Use 0 to disable.")
+	flag.Float64Var(&copierQueueRate, "copier-queue-rate", 0,
+		"If set, each SecretCopier gets its own queue budget allowing this many reconciles per second, with each "+
+			"second spent reconciling it counted as a further reconcile, so one SecretCopier can't delay the others. "+
+			"Use 0 to have all SecretCopiers share one queue.")
+	flag.IntVar(&copierQueueBurst, "copier-queue-burst", 10,
+		"Number of reconciles of a SecretCopier which can be made in a burst when it has its own queue budget.")
+	flag.DurationVar(&sourceWaitRequeue, "source-wait-requeue", 5*time.Second,
+		"Delay before a SecretCopier with a rule whose source secret doesn't exist is reconciled again to check for it. "+
+			"The delay doubles while the source secret is still missing. Use 0 to wait for the sync period instead.")
+	flag.DurationVar(&sourceWaitMaxRequeue, "source-wait-max-requeue", 2*time.Minute,
+		"Maximum delay between checks for a missing source secret of a SecretCopier rule.")
+	flag.DurationVar(&targetClusterHealthInterval, "target-cluster-health-interval", 30*time.Second,
+		"Interval between checks of whether the remote clusters SecretCopier rules copy secrets to can be reached. "+
+			"Use 0 to only check them when the SecretCopiers copying to them are reconciled.")
+	flag.BoolVar(&targetNamespaceEvents, "target-namespace-events", false,
+		"If set, events are recorded against target secrets in the target namespace when they are created or updated.")
+	flag.BoolVar(&sourceSecretEvents, "source-secret-events", false,
+		"If set, events are recorded against source secrets when they are copied to target namespaces or copying fails.")
+	flag.BoolVar(&copyEvents, "copy-events", false,
+		"If set, events are recorded against SecretCopier objects when target secrets are created, updated, skipped or fail to copy.")
+	flag.BoolVar(&recreateOnTypeChange, "recreate-on-type-change", false,
+		"If set, target secrets are deleted and recreated when the type of the source secret changes, "+
+			"as the type of a secret can't be updated. Otherwise copying to the target fails.")
+	flag.BoolVar(&secretsReadyAnnotation, "secrets-ready-annotation", false,
+		"If set, namespaces matched by SecretCopier rules are annotated with secrets-manager.advok8s.io/secrets-ready "+
+			"once all secrets to be copied to them have been copied. Not supported in report-only mode.")
+	flag.DurationVar(&eventAggregationWindow, "event-aggregation-window", time.Minute,
+		"Window over which repeated events with the same object, type and reason are aggregated into a single "+
+			"counted event. The window doubles while the events keep repeating. Use 0 to disable.")
+	flag.StringVar(&targetNamespaceStatusConfigMap, "target-namespace-status-configmap", "",
+		"Name of a ConfigMap to maintain in each target namespace listing the secrets copied into it. "+
+			"Leave empty to disable.")
+	flag.BoolVar(&authorizeSecretClaims, "authorize-secret-claims", false,
+		"If set, SecretClaims are only bound when a SubjectAccessReview confirms the namespace of the claim "+
+			"has been granted access to the catalog entry through RBAC.")
+	flag.StringVar(&managedSecretsReportName, "managed-secrets-report", "cluster",
+		"Name of the cluster scoped ManagedSecretsReport summarizing all managed secrets. Leave empty to disable.")
+	flag.DurationVar(&managedSecretsReportInterval, "managed-secrets-report-interval", 5*time.Minute,
+		"Interval at which the ManagedSecretsReport is regenerated.")
+	flag.BoolVar(&reapOrphanedSecrets, "reap-orphaned-secrets", false,
+		"If set, target secrets with a reclaim policy of Delete which are no longer wanted by a rule, "+
+			"or whose source secret has been deleted, are deleted after the grace period.")
+	flag.BoolVar(&migrateOnStartup, "migrate-on-startup", true,
+		"If set, SecretCopier objects and target secrets written by earlier versions of the manager "+
+			"are rewritten to the current schema and marker format on startup.")
+	flag.DurationVar(&orphanedSecretGracePeriod, "orphaned-secret-grace-period", time.Hour,
+		"Time a target secret must remain orphaned before it is deleted by the orphan reaper.")
+	flag.StringVar(&managerConfigName, "manager-config", "cluster",
+		"Name of the cluster scoped SecretsManagerConfig holding controller wide settings, which are applied "+
+			"without a restart when changed. Settings it doesn't specify default to those given by flags. "+
+			"Leave empty to disable.")
+	flag.IntVar(&secretCopierLimits.MaxRules, "max-rules-per-copier", 0,
+		"Maximum number of rules permitted in a SecretCopier at admission. Use 0 for no limit.")
+	flag.IntVar(&secretCopierLimits.MaxTargetNamespacesPerRule, "max-target-namespaces-per-rule", 0,
+		"Maximum number of target namespaces a SecretCopier rule may match at admission. Use 0 for no limit.")
+	flag.IntVar(&secretCopierLimits.MaxTargetsPerCopier, "max-targets-per-copier", 0,
+		"Maximum number of target secrets a SecretCopier may manage at admission. Use 0 for no limit.")
+	flag.BoolVar(&reportOnly, "report-only", false,
+		"If set, all controllers evaluate and report through status, events and metrics, but never write secrets.")
+	flag.StringVar(&targetMutatorType, "target-mutator", "",
+		"Type of mutator applied to target secrets copied by SecretCopiers before they are written, "+
+			"such as exec. Leave empty to disable.")
+	flag.StringVar(&targetMutatorOptions, "target-mutator-options", "",
+		"Comma separated list of key=value options for the target mutator, such as command=/usr/local/bin/mutate.")
+	flag.StringVar(&neverCopySecretTypes, "never-copy-secret-types", joinSecretTypes(controller.DefaultNeverCopySecretTypes),
+		"Comma separated list of secret types which are never copied, regardless of rule configuration.")
+	flag.Var(features.DefaultGate, "feature-gates",
+		"A set of key=value pairs that describe feature gates for alpha/experimental features. Options are:\n"+
+			strings.Join(features.DefaultGate.KnownFeatures(), "\n"))
 	opts := zap.Options{
 		Development: true,
 	}
@@ -77,6 +275,8 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	setupLog.Info("feature gates", "featureGates", features.DefaultGate.String())
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -110,6 +310,11 @@ func main() {
 		// unauthorized access to sensitive metrics data. Consider replacing with CertDir, CertName, and KeyName
 		// to provide certificates, ensuring the server communicates using trusted and secure certificates.
 		TLSOpts: tlsOpts,
+		// Build information is served alongside the metrics so the version of
+		// the manager running in each cluster can be checked.
+		ExtraHandlers: map[string]http.Handler{
+			"/version": version.Handler(),
+		},
 	}
 
 	if secureMetrics {
@@ -126,7 +331,7 @@ func main() {
 		WebhookServer:          webhookServer,
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "dc911fa3.advok8s.io",
+		LeaderElectionID:       leaderElectionID,
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -144,13 +349,207 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Controller wide settings default to those given by the command line
+	// flags and can be overridden while running using a SecretsManagerConfig.
+
+	managerConfig := controller.NewManagerConfig(controller.ManagerSettings{
+		MaxSecretWritesPerSecond: maxSecretWritesPerSecond,
+		FanOutGuardThreshold:     fanOutGuardThreshold,
+		RequireNamespaceConsent:  requireNamespaceConsent,
+		NeverCopySecretTypes:     splitSecretTypes(neverCopySecretTypes),
+	})
+
+	if managerConfigName != "" {
+		if err = (&controller.SecretsManagerConfigReconciler{
+			Client:     mgr.GetClient(),
+			Scheme:     mgr.GetScheme(),
+			ConfigName: managerConfigName,
+			Config:     managerConfig,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "SecretsManagerConfig")
+			os.Exit(1)
+		}
+	}
+
+	var targetMutator mutation.Mutator
+
+	if targetMutatorType != "" {
+		options, err := mutation.ParseOptions(targetMutatorOptions)
+
+		if err == nil {
+			targetMutator, err = mutation.New(targetMutatorType, options)
+		}
+
+		if err != nil {
+			setupLog.Error(err, "unable to create target mutator", "mutator", targetMutatorType)
+			os.Exit(1)
+		}
+	}
+
+	impersonatingClients := controller.NewImpersonatingClients(mgr.GetConfig(),
+		client.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()})
+
 	if err = (&controller.SecretCopierReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:                         mgr.GetClient(),
+		APIReader:                      mgr.GetAPIReader(),
+		ImpersonatingClient:            impersonatingClients.Client,
+		Scheme:                         mgr.GetScheme(),
+		SecretWriteLimiter:             managerConfig.SecretWriteLimiter(),
+		CoalesceWindow:                 reconcileCoalesceWindow,
+		WarmUpWindow:                   warmUpWindow,
+		RuleProcessingBudget:           ruleProcessingBudget,
+		MaxConcurrentReconciles:        maxConcurrentReconciles,
+		CopyWorkers:                    copyWorkers,
+		NewNamespaceWorkers:            newNamespaceWorkers,
+		CopierQueueRate:                rate.Limit(copierQueueRate),
+		CopierQueueBurst:               copierQueueBurst,
+		SourceWaitRequeue:              sourceWaitRequeue,
+		SourceWaitMaxRequeue:           sourceWaitMaxRequeue,
+		TargetClusterHealthInterval:    targetClusterHealthInterval,
+		Mutator:                        targetMutator,
+		Recorder:                       controller.NewAggregatingEventRecorder(mgr.GetEventRecorderFor("secretcopier-controller"), eventAggregationWindow),
+		TargetNamespaceEvents:          targetNamespaceEvents,
+		SourceSecretEvents:             sourceSecretEvents,
+		CopyEvents:                     copyEvents,
+		RecreateOnTypeChange:           recreateOnTypeChange,
+		TargetNamespaceStatusConfigMap: targetNamespaceStatusConfigMap,
+		Config:                         managerConfig,
+		ReportOnly:                     reportOnly,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "SecretCopier")
 		os.Exit(1)
 	}
+	if features.DefaultGate.Enabled(features.SecretClaims) {
+		if err = (&controller.SecretClaimReconciler{
+			Client:          mgr.GetClient(),
+			Scheme:          mgr.GetScheme(),
+			Recorder:        controller.NewAggregatingEventRecorder(mgr.GetEventRecorderFor("secretclaim-controller"), eventAggregationWindow),
+			AuthorizeClaims: authorizeSecretClaims,
+			Config:          managerConfig,
+			ReportOnly:      reportOnly,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "SecretClaim")
+			os.Exit(1)
+		}
+	}
+	if features.DefaultGate.Enabled(features.SecretExports) {
+		if err = (&controller.SecretImportReconciler{
+			Client:     mgr.GetClient(),
+			Scheme:     mgr.GetScheme(),
+			Recorder:   controller.NewAggregatingEventRecorder(mgr.GetEventRecorderFor("secretimport-controller"), eventAggregationWindow),
+			Config:     managerConfig,
+			ReportOnly: reportOnly,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "SecretImport")
+			os.Exit(1)
+		}
+	}
+	if features.DefaultGate.Enabled(features.SecretRotators) {
+		if err = (&controller.SecretRotatorReconciler{
+			Client:     mgr.GetClient(),
+			Scheme:     mgr.GetScheme(),
+			Recorder:   controller.NewAggregatingEventRecorder(mgr.GetEventRecorderFor("secretrotator-controller"), eventAggregationWindow),
+			Config:     managerConfig,
+			ReportOnly: reportOnly,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "SecretRotator")
+			os.Exit(1)
+		}
+	}
+	if features.DefaultGate.Enabled(features.VaultSecrets) {
+		if err = (&controller.VaultSecretReconciler{
+			Client:     mgr.GetClient(),
+			Scheme:     mgr.GetScheme(),
+			Recorder:   controller.NewAggregatingEventRecorder(mgr.GetEventRecorderFor("vaultsecret-controller"), eventAggregationWindow),
+			Config:     managerConfig,
+			ReportOnly: reportOnly,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "VaultSecret")
+			os.Exit(1)
+		}
+	}
+	if features.DefaultGate.Enabled(features.AWSSecrets) {
+		if err = (&controller.AWSSecretReconciler{
+			Client:     mgr.GetClient(),
+			Scheme:     mgr.GetScheme(),
+			Recorder:   controller.NewAggregatingEventRecorder(mgr.GetEventRecorderFor("awssecret-controller"), eventAggregationWindow),
+			Config:     managerConfig,
+			ReportOnly: reportOnly,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "AWSSecret")
+			os.Exit(1)
+		}
+	}
+	if features.DefaultGate.Enabled(features.ConfigMapCopiers) {
+		if err = (&controller.ConfigMapCopierReconciler{
+			Client:     mgr.GetClient(),
+			Scheme:     mgr.GetScheme(),
+			Recorder:   mgr.GetEventRecorderFor("configmapcopier-controller"),
+			Config:     managerConfig,
+			ReportOnly: reportOnly,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ConfigMapCopier")
+			os.Exit(1)
+		}
+	}
+	if managedSecretsReportName != "" && features.DefaultGate.Enabled(features.ManagedSecretsReport) {
+		if err = (&controller.ManagedSecretsReportReconciler{
+			Client:         mgr.GetClient(),
+			Scheme:         mgr.GetScheme(),
+			ReportName:     managedSecretsReportName,
+			Interval:       managedSecretsReportInterval,
+			CoalesceWindow: reconcileCoalesceWindow,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ManagedSecretsReport")
+			os.Exit(1)
+		}
+	}
+	if secretsReadyAnnotation && !reportOnly {
+		if err = (&controller.SecretsReadyReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+			Config: managerConfig,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "SecretsReady")
+			os.Exit(1)
+		}
+	}
+	if reapOrphanedSecrets {
+		if err = mgr.Add(&controller.OrphanReaper{
+			Client:      mgr.GetClient(),
+			Interval:    time.Minute,
+			GracePeriod: orphanedSecretGracePeriod,
+			ReportOnly:  reportOnly,
+			Config:      managerConfig,
+		}); err != nil {
+			setupLog.Error(err, "unable to create orphan reaper")
+			os.Exit(1)
+		}
+	}
+	if migrateOnStartup {
+		if err = mgr.Add(&controller.SchemaMigrator{
+			Client:        mgr.GetClient(),
+			RetryInterval: time.Minute,
+			ReportOnly:    reportOnly,
+		}); err != nil {
+			setupLog.Error(err, "unable to create schema migrator")
+			os.Exit(1)
+		}
+	}
+	// nolint:goconst
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" && features.DefaultGate.Enabled(features.AdmissionWebhooks) {
+		if err = webhooksecretsv1beta1.SetupSecretCopierWebhookWithManager(mgr, secretCopierLimits); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "SecretCopier")
+			os.Exit(1)
+		}
+	}
+	// nolint:goconst
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" && features.DefaultGate.Enabled(features.SecretInjection) {
+		if err = webhookcorev1.SetupPodWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Pod")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -162,9 +561,36 @@ func main() {
 		os.Exit(1)
 	}
 
-	setupLog.Info("starting manager")
+	info := version.Get()
+
+	setupLog.Info("starting manager", "version", info.Version, "gitCommit", info.GitCommit, "buildDate", info.BuildDate)
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+// Join a list of secret types into a comma separated string.
+func joinSecretTypes(secretTypes []corev1.SecretType) string {
+	names := make([]string, 0, len(secretTypes))
+
+	for _, secretType := range secretTypes {
+		names = append(names, string(secretType))
+	}
+
+	return strings.Join(names, ",")
+}
+
+// Split a comma separated string into a list of secret types. An empty string
+// results in an empty, but not nil, list.
+func splitSecretTypes(value string) []corev1.SecretType {
+	secretTypes := make([]corev1.SecretType, 0)
+
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			secretTypes = append(secretTypes, corev1.SecretType(name))
+		}
+	}
+
+	return secretTypes
+}
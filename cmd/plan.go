@@ -0,0 +1,183 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/advok8s/advok8s-secrets-manager/internal/controller"
+)
+
+// Run the plan subcommand, which snapshots the target secrets SecretCopiers
+// should produce, or compares them against an earlier snapshot, so changes in
+// behavior from upgrading the manager can be caught. Returns the exit status
+// for the process.
+func runPlan(args []string) int {
+	var snapshot string
+	var output string
+	var timeout time.Duration
+	var managerConfigName string
+	var fanOutGuardThreshold int
+	var requireNamespaceConsent bool
+
+	flags := flag.NewFlagSet("plan", flag.ExitOnError)
+
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s plan snapshot|compare [flags]\n\n", os.Args[0])
+		fmt.Fprintf(flags.Output(), "Snapshot the target secrets SecretCopiers should produce, or compare them\n")
+		fmt.Fprintf(flags.Output(), "against an earlier snapshot.\n\n")
+		flags.PrintDefaults()
+	}
+
+	flags.StringVar(&snapshot, "snapshot", "", "File holding the snapshot to compare against. Required for compare.")
+	flags.StringVar(&output, "output", "", "File to write the snapshot to. If empty, it is written to standard output.")
+	flags.DurationVar(&timeout, "timeout", time.Minute, "Maximum time to spend working out the plan.")
+	flags.StringVar(&managerConfigName, "manager-config", "cluster",
+		"Name of the SecretsManagerConfig whose settings are applied, as used by the manager. Leave empty to only "+
+			"apply the settings given by flags.")
+	flags.IntVar(&fanOutGuardThreshold, "fan-out-guard-threshold", 0,
+		"Fan-out guard threshold the manager was started with. Use 0 if the guard is disabled.")
+	flags.BoolVar(&requireNamespaceConsent, "require-namespace-consent", false,
+		"Set if the manager was started requiring namespaces to consent to copies.")
+
+	if len(args) == 0 || (args[0] != "snapshot" && args[0] != "compare") {
+		flags.Usage()
+		return 2
+	}
+
+	action := args[0]
+
+	_ = flags.Parse(args[1:])
+
+	// Read the earlier snapshot before doing anything else, so a bad path
+	// is reported straight away.
+
+	var before controller.CopyPlan
+
+	if action == "compare" {
+		if snapshot == "" {
+			fmt.Fprintf(os.Stderr, "The --snapshot flag is required for compare\n\n")
+			flags.Usage()
+			return 2
+		}
+
+		file, err := os.Open(snapshot)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to open snapshot: %v\n", err)
+			return 2
+		}
+
+		before, err = controller.ReadCopyPlan(file)
+
+		file.Close()
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to read snapshot: %v\n", err)
+			return 2
+		}
+	}
+
+	config, err := ctrl.GetConfig()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to load Kubernetes configuration: %v\n", err)
+		return 2
+	}
+
+	c, err := client.New(config, client.Options{Scheme: scheme})
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to create Kubernetes client: %v\n", err)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// Apply the settings of the manager the same way it does, with those in
+	// the SecretsManagerConfig overriding those given by flags.
+
+	settings, err := controller.LoadManagerSettings(ctx, c, controller.ManagerSettings{
+		FanOutGuardThreshold:    fanOutGuardThreshold,
+		RequireNamespaceConsent: requireNamespaceConsent,
+	}, managerConfigName)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to load SecretsManagerConfig: %v\n", err)
+		return 1
+	}
+
+	plan, err := controller.CollectCopyPlan(ctx, c, settings)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to work out copy plan: %v\n", err)
+		return 1
+	}
+
+	if action == "snapshot" {
+		out := io.Writer(os.Stdout)
+
+		if output != "" {
+			file, err := os.Create(output)
+
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Unable to create output file: %v\n", err)
+				return 2
+			}
+
+			defer file.Close()
+
+			out = file
+		}
+
+		if err := controller.WriteCopyPlan(out, plan); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to write snapshot: %v\n", err)
+			return 1
+		}
+
+		fmt.Fprintf(os.Stderr, "Snapshot of %d target secrets taken with version %s\n", len(plan.Targets), plan.Version)
+
+		return 0
+	}
+
+	// Report each difference on its own line, exiting with a non zero status
+	// if there are any so the check can gate an upgrade pipeline.
+
+	differences := controller.CompareCopyPlans(before, plan)
+
+	for _, difference := range differences {
+		fmt.Println(difference)
+	}
+
+	fmt.Fprintf(os.Stderr, "Compared %d target secrets from version %s against %d from version %s, %d differences\n",
+		len(before.Targets), before.Version, len(plan.Targets), plan.Version, len(differences))
+
+	if len(differences) != 0 {
+		return 1
+	}
+
+	return 0
+}
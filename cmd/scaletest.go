@@ -0,0 +1,110 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/advok8s/advok8s-secrets-manager/internal/scaletest"
+)
+
+// Run the scale-test subcommand, which generates synthetic namespaces,
+// secrets and SecretCopier resources in the cluster and reports how quickly
+// the manager copies the secrets. Returns the exit status for the process.
+func runScaleTest(args []string) int {
+	var options scaletest.Options
+	var timeout time.Duration
+	var cleanup bool
+
+	flags := flag.NewFlagSet("scale-test", flag.ExitOnError)
+
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s scale-test [flags]\n\n", os.Args[0])
+		fmt.Fprintf(flags.Output(), "Generate synthetic secrets to copy and report the throughput of the manager.\n\n")
+		flags.PrintDefaults()
+	}
+
+	flags.StringVar(&options.Prefix, "prefix", "scale-test", "Prefix for the names of the generated resources.")
+	flags.IntVar(&options.Namespaces, "namespaces", 100, "Number of target namespaces to create.")
+	flags.IntVar(&options.Secrets, "secrets", 10, "Number of source secrets to create, each copied to every target namespace.")
+	flags.IntVar(&options.SecretCopiers, "secret-copiers", 1, "Number of SecretCopiers the source secrets are spread across.")
+	flags.IntVar(&options.SecretSize, "secret-size", 1024, "Size in bytes of the data of each source secret.")
+	flags.DurationVar(&options.PollInterval, "poll-interval", time.Second, "How often to check how many secrets have been copied.")
+	flags.DurationVar(&timeout, "timeout", 10*time.Minute, "Maximum time to wait for the secrets to be copied.")
+	flags.BoolVar(&cleanup, "cleanup", true, "If set, delete the generated resources when done.")
+
+	_ = flags.Parse(args)
+
+	if err := options.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid options: %v\n\n", err)
+		flags.Usage()
+		return 2
+	}
+
+	config, err := ctrl.GetConfig()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to load Kubernetes configuration: %v\n", err)
+		return 2
+	}
+
+	c, err := client.New(config, client.Options{Scheme: scheme})
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to create Kubernetes client: %v\n", err)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	report, err := scaletest.Run(ctx, c, options, os.Stderr)
+
+	status := 0
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to run scale test: %v\n", err)
+		status = 2
+	} else if !report.Complete {
+		status = 1
+	}
+
+	if cleanup {
+		cleanupCtx, cleanupCancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cleanupCancel()
+
+		if err := scaletest.Cleanup(cleanupCtx, c, options.Prefix); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to delete generated resources: %v\n", err)
+			status = 2
+		}
+	}
+
+	if err == nil {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		_, _ = os.Stdout.Write(append(data, '\n'))
+	}
+
+	return status
+}
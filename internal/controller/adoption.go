@@ -0,0 +1,104 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/internal/redact"
+)
+
+// Determine if the target secret is managed by another SecretCopier which the
+// rule takes over from. The target secret must have been copied from the same
+// source secret as the rule copies, so a secret which merely has the same name
+// is never taken over.
+func (r *SecretCopierReconciler) targetSecretAdoptable(secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, targetSecret *corev1.Secret) bool {
+	previousOwner := targetSecret.Annotations["secrets-manager.advok8s.io/secret-copier"]
+
+	if previousOwner == "" || previousOwner == secretCopier.Name || !slices.Contains(rule.AdoptFrom, previousOwner) {
+		return false
+	}
+
+	return targetSecretSourceMatches(targetSecret, rule.SourceSecret)
+}
+
+// Take over a target secret managed by another SecretCopier. The annotation
+// recording the managing SecretCopier is changed and any owner reference to
+// the previous SecretCopier replaced according to the reclaim policy of the
+// rule, so deleting the previous SecretCopier no longer deletes the target
+// secret. The content of the target secret is brought up to date in the same
+// write where possible.
+func (r *SecretCopierReconciler) adoptTargetSecret(ctx context.Context, secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, sourceSecret *corev1.Secret, targetSecret *corev1.Secret, backoffKey string) (copyResult, error) {
+	log := log.FromContext(ctx)
+
+	previousOwner := targetSecret.Annotations["secrets-manager.advok8s.io/secret-copier"]
+
+	log.Info("Adopting target secret from SecretCopier", "targetSecret", targetSecret.Name, "targetNamespace", targetSecret.Namespace, "previousOwner", previousOwner)
+
+	currentSecret := targetSecret.DeepCopy()
+
+	setTargetSecretMarkers(targetSecret, secretCopier.Name, rule.SourceSecret)
+
+	ownerReferences := make([]metav1.OwnerReference, 0, len(targetSecret.OwnerReferences))
+
+	for _, ownerReference := range targetSecret.OwnerReferences {
+		if ownerReference.Kind == "SecretCopier" && ownerReference.Name == previousOwner &&
+			strings.HasPrefix(ownerReference.APIVersion, secretsv1beta1.GroupVersion.Group+"/") {
+			continue
+		}
+
+		ownerReferences = append(ownerReferences, ownerReference)
+	}
+
+	if rule.ReclaimPolicy == secretsv1beta1.ReclaimDelete {
+		ownerReferences = append(ownerReferences, secretCopierOwnerReference(secretCopier))
+	}
+
+	targetSecret.OwnerReferences = ownerReferences
+
+	// The type of a secret can't be changed, nor the content of an immutable
+	// secret, so in those cases only the metadata is updated, with the content
+	// dealt with on the next reconciliation.
+
+	if targetSecret.Type == sourceSecret.Type && (targetSecret.Immutable == nil || !*targetSecret.Immutable) {
+		targetSecret.Data = sourceSecret.Data
+	}
+
+	targetSecret.Labels = r.targetSecretLabels(rule, sourceSecret)
+
+	applyTargetSecretAnnotations(rule, targetSecret)
+
+	if err := r.mutateTargetSecret(ctx, secretCopier, rule, sourceSecret, targetSecret); err != nil {
+		return copyFailed, err
+	}
+
+	result, err := r.writeTargetSecret(ctx, secretCopier, rule, targetSecret, "update", redact.DiffSecrets(currentSecret, targetSecret), backoffKey)
+
+	if result == copyUpdated {
+		r.Recorder.Eventf(secretCopier, corev1.EventTypeNormal, "SecretAdopted",
+			"Took over secret %s in namespace %s from SecretCopier %s", targetSecret.Name, targetSecret.Namespace, previousOwner)
+	}
+
+	return result, err
+}
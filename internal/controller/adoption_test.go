@@ -0,0 +1,113 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Target Secret Adoption", func() {
+	ctx := context.Background()
+
+	secretCopier := &secretsv1beta1.SecretCopier{
+		TypeMeta:   metav1.TypeMeta{APIVersion: secretsv1beta1.GroupVersion.String(), Kind: "SecretCopier"},
+		ObjectMeta: metav1.ObjectMeta{Name: "new-copier", UID: "new-uid"},
+	}
+
+	rule := &secretsv1beta1.SecretCopierRule{
+		SourceSecret:  secretsv1beta1.SourceSecret{Name: "registry", Namespace: "source"},
+		ReclaimPolicy: secretsv1beta1.ReclaimDelete,
+		AdoptFrom:     []string{"old-copier"},
+	}
+
+	sourceSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "source"},
+		Type:       corev1.SecretTypeOpaque,
+		Data:       map[string][]byte{"password": []byte("rotated")},
+	}
+
+	targetSecret := func(copier string, source string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "registry",
+				Namespace: "tenant-a",
+				Annotations: map[string]string{
+					"secrets-manager.advok8s.io/secret-copier": copier,
+					"secrets-manager.advok8s.io/secret-name":   source,
+				},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: secretsv1beta1.GroupVersion.String(),
+					Kind:       "SecretCopier",
+					Name:       copier,
+					UID:        "old-uid",
+					Controller: ptr.To(true),
+				}},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{"password": []byte("secret")},
+		}
+	}
+
+	It("should only adopt target secrets of listed SecretCopiers copied from the same source secret", func() {
+		reconciler := &SecretCopierReconciler{}
+
+		Expect(reconciler.targetSecretAdoptable(secretCopier, rule, targetSecret("old-copier", "source/registry"))).To(BeTrue())
+		Expect(reconciler.targetSecretAdoptable(secretCopier, rule, targetSecret("other-copier", "source/registry"))).To(BeFalse())
+		Expect(reconciler.targetSecretAdoptable(secretCopier, rule, targetSecret("old-copier", "source/other"))).To(BeFalse())
+		Expect(reconciler.targetSecretAdoptable(secretCopier, rule, targetSecret("new-copier", "source/registry"))).To(BeFalse())
+	})
+
+	It("should switch the managing SecretCopier and owner of an adopted target secret", func() {
+		existing := targetSecret("old-copier", "source/registry")
+
+		fakeClient := fake.NewClientBuilder().WithObjects(existing).Build()
+		recorder := record.NewFakeRecorder(10)
+
+		reconciler := &SecretCopierReconciler{Client: fakeClient, Recorder: recorder}
+
+		Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(existing), existing)).To(Succeed())
+
+		result, err := reconciler.adoptTargetSecret(ctx, secretCopier, rule, sourceSecret, existing, "key")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyUpdated))
+
+		Expect(recorder.Events).To(Receive(Equal(
+			"Normal SecretAdopted Took over secret registry in namespace tenant-a from SecretCopier old-copier")))
+
+		adopted := &corev1.Secret{}
+		Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(existing), adopted)).To(Succeed())
+
+		Expect(adopted.Annotations).To(HaveKeyWithValue("secrets-manager.advok8s.io/secret-copier", "new-copier"))
+		Expect(adopted.OwnerReferences).To(HaveLen(1))
+		Expect(adopted.OwnerReferences[0].UID).To(BeEquivalentTo("new-uid"))
+		Expect(adopted.Data).To(Equal(sourceSecret.Data))
+		Expect(reconciler.targetSecretManagedBySecretCopier(secretCopier, rule, adopted)).To(BeTrue())
+	})
+})
@@ -0,0 +1,114 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Annotation recording on a target secret the keys of the annotations applied
+// from the rule, so that annotations removed from the rule can be removed from
+// the target secret without touching annotations added by anything else.
+const targetAnnotationsMarkerAnnotation = "secrets-manager.advok8s.io/target-annotations"
+
+// Prefix of annotations reserved for use by the manager. Annotations with this
+// prefix given for a target secret are ignored, so they can't clobber the
+// markers the manager uses to track target secrets.
+const managerAnnotationPrefix = "secrets-manager.advok8s.io/"
+
+// Calculate the annotations from the rule to apply to the target secret,
+// excluding any reserved for use by the manager.
+func ruleTargetSecretAnnotations(rule *secretsv1beta1.SecretCopierRule) map[string]string {
+	annotations := make(map[string]string, len(rule.TargetSecret.Annotations))
+
+	for key, value := range rule.TargetSecret.Annotations {
+		if !strings.HasPrefix(key, managerAnnotationPrefix) {
+			annotations[key] = value
+		}
+	}
+
+	return annotations
+}
+
+// Format the keys of the annotations applied from the rule for recording in
+// the marker annotation.
+func targetAnnotationsMarker(annotations map[string]string) string {
+	keys := make([]string, 0, len(annotations))
+
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return strings.Join(keys, ",")
+}
+
+// Overlay the annotations from the rule on the target secret. Annotations
+// previously applied from the rule which the rule no longer gives are removed,
+// while annotations added by anything else are left as they are.
+func applyTargetSecretAnnotations(rule *secretsv1beta1.SecretCopierRule, targetSecret *corev1.Secret) {
+	annotations := ruleTargetSecretAnnotations(rule)
+
+	if previous := targetSecret.Annotations[targetAnnotationsMarkerAnnotation]; previous != "" {
+		for _, key := range strings.Split(previous, ",") {
+			if _, found := annotations[key]; !found && !strings.HasPrefix(key, managerAnnotationPrefix) {
+				delete(targetSecret.Annotations, key)
+			}
+		}
+	}
+
+	if len(annotations) == 0 {
+		delete(targetSecret.Annotations, targetAnnotationsMarkerAnnotation)
+		return
+	}
+
+	if targetSecret.Annotations == nil {
+		targetSecret.Annotations = make(map[string]string)
+	}
+
+	for key, value := range annotations {
+		targetSecret.Annotations[key] = value
+	}
+
+	targetSecret.Annotations[targetAnnotationsMarkerAnnotation] = targetAnnotationsMarker(annotations)
+}
+
+// Determine if the annotations from the rule are applied to the target secret,
+// with none left behind which the rule no longer gives.
+func targetSecretAnnotationsMatch(rule *secretsv1beta1.SecretCopierRule, targetSecret *corev1.Secret) bool {
+	annotations := ruleTargetSecretAnnotations(rule)
+
+	for key, value := range annotations {
+		if current, found := targetSecret.Annotations[key]; !found || current != value {
+			return false
+		}
+	}
+
+	current, found := targetSecret.Annotations[targetAnnotationsMarkerAnnotation]
+
+	if len(annotations) == 0 {
+		return !found
+	}
+
+	return current == targetAnnotationsMarker(annotations)
+}
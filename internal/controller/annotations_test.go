@@ -0,0 +1,131 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Target Secret Annotations", func() {
+	ctx := context.Background()
+
+	secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "annotating-copier"}}
+
+	newRule := func(annotations map[string]string) *secretsv1beta1.SecretCopierRule {
+		return &secretsv1beta1.SecretCopierRule{
+			SourceSecret:  secretsv1beta1.SourceSecret{Name: "registry", Namespace: "source"},
+			TargetSecret:  secretsv1beta1.TargetSecret{Annotations: annotations},
+			ReclaimPolicy: secretsv1beta1.ReclaimRetain,
+		}
+	}
+
+	It("should overlay annotations without clobbering the markers", func() {
+		rule := newRule(map[string]string{
+			"reloader.stakater.com/match":              "true",
+			"secrets-manager.advok8s.io/secret-copier": "other-copier",
+		})
+
+		targetSecret := (&SecretCopierReconciler{}).newTargetSecret(secretCopier, rule, &corev1.Secret{}, "registry", "tenant-a")
+
+		Expect(targetSecret.Annotations).To(HaveKeyWithValue("reloader.stakater.com/match", "true"))
+		Expect(targetSecret.Annotations).To(HaveKeyWithValue("secrets-manager.advok8s.io/secret-copier", "annotating-copier"))
+		Expect(targetSecret.Annotations).To(HaveKeyWithValue("secrets-manager.advok8s.io/target-annotations", "reloader.stakater.com/match"))
+		Expect(targetSecretAnnotationsMatch(rule, &targetSecret)).To(BeTrue())
+	})
+
+	It("should only remove annotations previously applied from the rule", func() {
+		targetSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					"reloader.stakater.com/match":                   "true",
+					"team.example.com/owner":                        "platform",
+					"kubectl.kubernetes.io/last-applied":            "{}",
+					"secrets-manager.advok8s.io/target-annotations": "reloader.stakater.com/match,team.example.com/owner",
+				},
+			},
+		}
+
+		rule := newRule(map[string]string{"team.example.com/owner": "security"})
+
+		Expect(targetSecretAnnotationsMatch(rule, targetSecret)).To(BeFalse())
+
+		applyTargetSecretAnnotations(rule, targetSecret)
+
+		Expect(targetSecret.Annotations).To(Equal(map[string]string{
+			"team.example.com/owner":                        "security",
+			"kubectl.kubernetes.io/last-applied":            "{}",
+			"secrets-manager.advok8s.io/target-annotations": "team.example.com/owner",
+		}))
+
+		Expect(targetSecretAnnotationsMatch(rule, targetSecret)).To(BeTrue())
+
+		rule = newRule(nil)
+
+		Expect(targetSecretAnnotationsMatch(rule, targetSecret)).To(BeFalse())
+
+		applyTargetSecretAnnotations(rule, targetSecret)
+
+		Expect(targetSecret.Annotations).To(Equal(map[string]string{
+			"kubectl.kubernetes.io/last-applied": "{}",
+		}))
+
+		Expect(targetSecretAnnotationsMatch(rule, targetSecret)).To(BeTrue())
+	})
+
+	It("should update the target secret when the annotations of the rule change", func() {
+		k8sClient := fake.NewClientBuilder().WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "source"},
+				Data:       map[string][]byte{"password": []byte("secret")},
+			},
+		).Build()
+
+		reconciler := &SecretCopierReconciler{Client: k8sClient, Recorder: record.NewFakeRecorder(10)}
+
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, newRule(nil), "tenant-a")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyCreated))
+
+		rule := newRule(map[string]string{"reloader.stakater.com/match": "true"})
+
+		result, err = reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant-a")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyUpdated))
+
+		targetSecret := &corev1.Secret{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "tenant-a", Name: "registry"}, targetSecret)).To(Succeed())
+		Expect(targetSecret.Annotations).To(HaveKeyWithValue("reloader.stakater.com/match", "true"))
+
+		result, err = reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant-a")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyUnchanged))
+	})
+})
@@ -0,0 +1,233 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// ApplySummary is the outcome of applying a SecretCopier once.
+type ApplySummary struct {
+	// Name of the SecretCopier.
+	SecretCopier string `json:"secretCopier"`
+
+	// Outcome of each rule, in the same order as the rules.
+	Rules []ApplyRuleSummary `json:"rules"`
+}
+
+// ApplyRuleSummary is the outcome of applying a single rule of a SecretCopier.
+type ApplyRuleSummary struct {
+	// Reference to the secret the rule copies from.
+	SourceSecret secretsv1beta1.SourceSecret `json:"sourceSecret"`
+
+	// Outcome of copying to each target namespace matched by the rule.
+	Targets []ApplyTargetResult `json:"targets"`
+
+	// Error when the rule as a whole could not be applied.
+	Error string `json:"error,omitempty"`
+}
+
+// ApplyTargetResult is the outcome of copying the source secret of a rule to a
+// single target namespace.
+type ApplyTargetResult struct {
+	// Name of the target namespace.
+	Namespace string `json:"namespace"`
+
+	// Name of the target secret.
+	Secret string `json:"secret"`
+
+	// One of created, updated, unchanged, skipped, not-ready, waiting,
+	// forbidden or failed.
+	Result string `json:"result"`
+
+	// Error when the copy failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Failed reports whether copying to any target failed.
+func (s *ApplySummary) Failed() bool {
+	for _, rule := range s.Rules {
+		if rule.Error != "" {
+			return true
+		}
+
+		for _, target := range rule.Targets {
+			if target.Result == "failed" || target.Result == "forbidden" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Names of the outcomes of copying to a target namespace as reported in the
+// summary.
+var copyResultNames = map[copyResult]string{
+	copyFailed:     "failed",
+	copySkipped:    "skipped",
+	copyCreated:    "created",
+	copyUpdated:    "updated",
+	copyUnchanged:  "unchanged",
+	copyThrottled:  "throttled",
+	copyForbidden:  "forbidden",
+	copyReportOnly: "report-only",
+	copyNotReady:   "not-ready",
+	copyWaiting:    "waiting",
+	copyIgnored:    "ignored",
+}
+
+// ApplyOnce performs the copies described by a SecretCopier a single time
+// without the SecretCopier needing to exist in the cluster, returning a
+// summary of the outcome. As the SecretCopier cannot act as the owner of the
+// target secrets, all rules are applied with a reclaim policy of Retain.
+// Namespaces created by vcluster which a rule copies into the virtual cluster
+// of are left out, as are rules copying to a remote cluster. The status of the
+// SecretCopier is not updated.
+func (r *SecretCopierReconciler) ApplyOnce(ctx context.Context, secretCopier *secretsv1beta1.SecretCopier) (ApplySummary, error) {
+	log := log.FromContext(ctx)
+
+	// Rules with a source secret name which is a glob pattern are applied as
+	// a rule for each matching source secret.
+
+	rules, _, err := expandSourceSecretPatterns(ctx, r.Client, secretCopier.Spec.Rules)
+
+	if err != nil {
+		log.Error(err, "Unable to list source secrets matching rules of SecretCopier", "name", secretCopier.Name)
+		return ApplySummary{SecretCopier: secretCopier.Name}, err
+	}
+
+	secretCopier = secretCopier.DeepCopy()
+	secretCopier.Spec.Rules = rules
+
+	summary := ApplySummary{
+		SecretCopier: secretCopier.Name,
+		Rules:        make([]ApplyRuleSummary, len(secretCopier.Spec.Rules)),
+	}
+
+	namespaces := &corev1.NamespaceList{}
+
+	if err := r.List(ctx, namespaces); err != nil {
+		log.Error(err, "Unable to list namespaces")
+		return summary, err
+	}
+
+	delegations, err := loadDelegationScopes(ctx, r.Client)
+
+	if err != nil {
+		log.Error(err, "Unable to list SecretCopierDelegation objects")
+		return summary, err
+	}
+
+	settings := r.Config.Settings()
+
+	// Apply the rules so that each comes after the rules it depends on. Rules
+	// with unresolvable dependencies are applied last and never copy to any
+	// target namespace.
+
+	ruleOrder, unresolvedRules := secretCopier.Spec.RuleOrder()
+
+	for i := range secretCopier.Spec.Rules {
+		if _, found := unresolvedRules[i]; found {
+			ruleOrder = append(ruleOrder, i)
+		}
+	}
+
+	dependencies := newRuleDependencies()
+
+	for _, i := range ruleOrder {
+		rule := secretCopier.Spec.Rules[i]
+
+		rule.ReclaimPolicy = secretsv1beta1.ReclaimRetain
+		rule.ReclaimPolicyOverrides = nil
+
+		ruleSummary := ApplyRuleSummary{
+			SourceSecret: rule.SourceSecret,
+			Targets:      make([]ApplyTargetResult, 0),
+		}
+
+		if !rule.IsEnabled() {
+			summary.Rules[i] = ruleSummary
+			continue
+		}
+
+		if rule.TargetCluster != nil {
+			ruleSummary.Error = "rules copying to a remote cluster can't be applied once"
+
+			summary.Rules[i] = ruleSummary
+			continue
+		}
+
+		// Target namespaces are selected the same way as when the
+		// SecretCopier is reconciled.
+
+		selection := r.selectTargetNamespaces(ctx, settings, delegations, &rule, rule.TargetNamespaces.Compile(), namespaces.Items)
+
+		if selection.fanOutBlocked {
+			ruleSummary.Error = fmt.Sprintf("rule matches %d target namespaces, more than %d, without setting allowLargeFanOut",
+				len(selection.matched), settings.FanOutGuardThreshold)
+
+			summary.Rules[i] = ruleSummary
+			continue
+		}
+
+		for _, matched := range selection.matched {
+			namespace := matched.namespace
+
+			dependencies.matchedTarget(&rule, namespace.Name)
+
+			result := copyNotReady
+
+			err := matched.err
+
+			if matched.ready() && (unresolvedRules[i] != "" || dependencies.waiting(&rule, namespace.Name)) {
+				result = copyWaiting
+			} else if matched.ready() {
+				result, err = r.copySecretToNamespace(ctx, secretCopier, &rule, namespace.Name)
+			}
+
+			switch result {
+			case copyCreated, copyUpdated, copyUnchanged:
+				dependencies.syncedTarget(&rule, namespace.Name)
+			}
+
+			target := ApplyTargetResult{
+				Namespace: namespace.Name,
+				Secret:    r.currentTargetSecretName(ctx, &rule),
+				Result:    copyResultNames[result],
+			}
+
+			if err != nil {
+				target.Error = err.Error()
+			} else if result == copyWaiting && unresolvedRules[i] != "" {
+				target.Error = unresolvedRules[i]
+			}
+
+			ruleSummary.Targets = append(ruleSummary.Targets, target)
+		}
+
+		summary.Rules[i] = ruleSummary
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,293 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+var _ = Describe("SecretCopier ApplyOnce", func() {
+	ctx := context.Background()
+
+	// Test applying a SecretCopier which doesn't exist in the cluster once.
+
+	Context("Apply secret copier once #1", func() {
+		It("should copy secret and report the outcome", func() {
+			sourceNamespaceName := "apply-source-namespace-1"
+			targetNamespaceName := "apply-target-namespace-1"
+			sourceSecretName := "apply-source-secret-1"
+
+			for _, name := range []string{sourceNamespaceName, targetNamespaceName} {
+				namespace := &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: name,
+					},
+				}
+				Expect(k8sClient.Create(ctx, namespace)).To(Succeed())
+			}
+
+			sourceSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      sourceSecretName,
+					Namespace: sourceNamespaceName,
+				},
+				Type: corev1.SecretTypeOpaque,
+				StringData: map[string]string{
+					"key1": "value1",
+				},
+			}
+			Expect(k8sClient.Create(ctx, sourceSecret)).To(Succeed())
+
+			secretCopier := &secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "apply-secret-copier-1",
+				},
+				Spec: secretsv1beta1.SecretCopierSpec{
+					Rules: []secretsv1beta1.SecretCopierRule{
+						{
+							SourceSecret: secretsv1beta1.SourceSecret{
+								Namespace: sourceNamespaceName,
+								Name:      sourceSecretName,
+							},
+							TargetNamespaces: selectors.TargetNamespaces{
+								NameSelector: selectors.NameSelector{
+									MatchNames: []string{targetNamespaceName},
+								},
+							},
+							ReclaimPolicy: secretsv1beta1.ReclaimDelete,
+						},
+					},
+				},
+			}
+
+			reconciler := &SecretCopierReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: &record.FakeRecorder{},
+			}
+
+			summary, err := reconciler.ApplyOnce(ctx, secretCopier)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(summary.Failed()).To(BeFalse())
+			Expect(summary.Rules).To(HaveLen(1))
+			Expect(summary.Rules[0].Targets).To(ConsistOf(ApplyTargetResult{
+				Namespace: targetNamespaceName,
+				Secret:    sourceSecretName,
+				Result:    "created",
+			}))
+
+			// The target secret should have been created without an owner
+			// as the SecretCopier doesn't exist in the cluster.
+
+			targetSecret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{
+				Namespace: targetNamespaceName,
+				Name:      sourceSecretName,
+			}, targetSecret)).To(Succeed())
+			Expect(targetSecret.OwnerReferences).To(BeEmpty())
+
+			// Applying again should leave the target secret unchanged.
+
+			summary, err = reconciler.ApplyOnce(ctx, secretCopier)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(summary.Rules[0].Targets[0].Result).To(Equal("unchanged"))
+		})
+	})
+
+	// Test applying a SecretCopier once where the target secret has been
+	// labelled to be ignored.
+
+	Context("Apply secret copier once #2", func() {
+		It("should report ignored target secret without changing it", func() {
+			sourceNamespaceName := "apply-source-namespace-2"
+			targetNamespaceName := "apply-target-namespace-2"
+			sourceSecretName := "apply-source-secret-2"
+
+			for _, name := range []string{sourceNamespaceName, targetNamespaceName} {
+				namespace := &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: name,
+					},
+				}
+				Expect(k8sClient.Create(ctx, namespace)).To(Succeed())
+			}
+
+			sourceSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      sourceSecretName,
+					Namespace: sourceNamespaceName,
+				},
+				Type: corev1.SecretTypeOpaque,
+				StringData: map[string]string{
+					"key1": "value1",
+				},
+			}
+			Expect(k8sClient.Create(ctx, sourceSecret)).To(Succeed())
+
+			secretCopier := &secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "apply-secret-copier-2",
+				},
+				Spec: secretsv1beta1.SecretCopierSpec{
+					Rules: []secretsv1beta1.SecretCopierRule{
+						{
+							SourceSecret: secretsv1beta1.SourceSecret{
+								Namespace: sourceNamespaceName,
+								Name:      sourceSecretName,
+							},
+							TargetNamespaces: selectors.TargetNamespaces{
+								NameSelector: selectors.NameSelector{
+									MatchNames: []string{targetNamespaceName},
+								},
+							},
+						},
+					},
+				},
+			}
+
+			reconciler := &SecretCopierReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: &record.FakeRecorder{},
+			}
+
+			summary, err := reconciler.ApplyOnce(ctx, secretCopier)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(summary.Rules[0].Targets[0].Result).To(Equal("created"))
+
+			// Label the copied target secret to be ignored and change it, as
+			// a namespace admin taking over the copy would.
+
+			targetSecret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{
+				Namespace: targetNamespaceName,
+				Name:      sourceSecretName,
+			}, targetSecret)).To(Succeed())
+
+			targetSecret.Labels = map[string]string{
+				ignoreLabel: "true",
+			}
+			targetSecret.Data = map[string][]byte{
+				"key1": []byte("local"),
+			}
+			Expect(k8sClient.Update(ctx, targetSecret)).To(Succeed())
+
+			summary, err = reconciler.ApplyOnce(ctx, secretCopier)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(summary.Failed()).To(BeFalse())
+			Expect(summary.Rules[0].Targets).To(ConsistOf(ApplyTargetResult{
+				Namespace: targetNamespaceName,
+				Secret:    sourceSecretName,
+				Result:    "ignored",
+			}))
+
+			// The target secret should still hold the value set locally.
+
+			Expect(k8sClient.Get(ctx, client.ObjectKey{
+				Namespace: targetNamespaceName,
+				Name:      sourceSecretName,
+			}, targetSecret)).To(Succeed())
+			Expect(targetSecret.Data).To(HaveKeyWithValue("key1", []byte("local")))
+		})
+	})
+
+	// Test applying a SecretCopier once in report-only mode.
+
+	Context("Apply secret copier in report-only mode #1", func() {
+		It("should report write without copying secret", func() {
+			sourceNamespaceName := "report-only-source-namespace-1"
+			targetNamespaceName := "report-only-target-namespace-1"
+			sourceSecretName := "report-only-source-secret-1"
+
+			for _, name := range []string{sourceNamespaceName, targetNamespaceName} {
+				namespace := &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: name,
+					},
+				}
+				Expect(k8sClient.Create(ctx, namespace)).To(Succeed())
+			}
+
+			sourceSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      sourceSecretName,
+					Namespace: sourceNamespaceName,
+				},
+				Type: corev1.SecretTypeOpaque,
+				StringData: map[string]string{
+					"key1": "value1",
+				},
+			}
+			Expect(k8sClient.Create(ctx, sourceSecret)).To(Succeed())
+
+			secretCopier := &secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "report-only-secret-copier-1",
+				},
+				Spec: secretsv1beta1.SecretCopierSpec{
+					Rules: []secretsv1beta1.SecretCopierRule{
+						{
+							SourceSecret: secretsv1beta1.SourceSecret{
+								Namespace: sourceNamespaceName,
+								Name:      sourceSecretName,
+							},
+							TargetNamespaces: selectors.TargetNamespaces{
+								NameSelector: selectors.NameSelector{
+									MatchNames: []string{targetNamespaceName},
+								},
+							},
+						},
+					},
+				},
+			}
+
+			reconciler := &SecretCopierReconciler{
+				Client:     k8sClient,
+				Scheme:     k8sClient.Scheme(),
+				Recorder:   &record.FakeRecorder{},
+				ReportOnly: true,
+			}
+
+			summary, err := reconciler.ApplyOnce(ctx, secretCopier)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(summary.Rules[0].Targets).To(ConsistOf(ApplyTargetResult{
+				Namespace: targetNamespaceName,
+				Secret:    sourceSecretName,
+				Result:    "report-only",
+			}))
+
+			targetSecret := &corev1.Secret{}
+			err = k8sClient.Get(ctx, client.ObjectKey{
+				Namespace: targetNamespaceName,
+				Name:      sourceSecretName,
+			}, targetSecret)
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,98 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sort"
+	"strconv"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Key of the extra information added to the user impersonated for a
+// SecretCopier, so the audit log records which SecretCopier a request was made
+// for. The key is recorded under impersonatedUser.extra in audit events.
+const secretCopierImpersonationExtra = "secrets-manager.advok8s.io/secret-copier"
+
+// Maximum length of a field manager name accepted by the API server.
+const maxFieldManagerLength = 128
+
+// Name of the field manager used when writing target secrets for a rule of a
+// SecretCopier. The field manager is recorded in the managed fields of the
+// target secret and appears in the request URI of audit events, so writes can
+// be attributed to the rule responsible rather than only the service account
+// of the controller. Rules are identified by name if they have one, otherwise
+// by their source secret, as the index of a rule changes as rules are added
+// and removed.
+func targetSecretFieldManager(secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule) string {
+	fieldManager := "secrets-manager:" + secretCopier.Name + ":" + ruleReference(rule)
+
+	if len(fieldManager) > maxFieldManagerLength {
+		fieldManager = fieldManager[:maxFieldManagerLength]
+	}
+
+	return fieldManager
+}
+
+// Identify a rule by its name where it has one, and otherwise by the namespace
+// and name of its source secret.
+func ruleReference(rule *secretsv1beta1.SecretCopierRule) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+
+	return rule.SourceSecret.Namespace + "/" + rule.SourceSecret.Name
+}
+
+// Options applied to writes of target secrets for a rule of a SecretCopier,
+// identifying the SecretCopier and rule in the audit log.
+func targetSecretWriteOwner(secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule) client.FieldOwner {
+	return client.FieldOwner(targetSecretFieldManager(secretCopier, rule))
+}
+
+// Extra information added to the user impersonated for a SecretCopier.
+func secretCopierImpersonationExtras(secretCopier *secretsv1beta1.SecretCopier) map[string][]string {
+	return map[string][]string{
+		secretCopierImpersonationExtra: {secretCopier.Name},
+	}
+}
+
+// Key under which a client impersonating a user with extra information is
+// cached.
+func impersonationCacheKey(username string, extra map[string][]string) string {
+	names := make([]string, 0, len(extra))
+
+	for name := range extra {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	key := username
+
+	for _, name := range names {
+		key += "\x00" + name
+
+		for _, value := range extra[name] {
+			key += "\x00" + strconv.Quote(value)
+		}
+	}
+
+	return key
+}
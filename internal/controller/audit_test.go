@@ -0,0 +1,76 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Audit Attribution", func() {
+	secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "registry-copier"}}
+
+	It("should identify a named rule by its name", func() {
+		rule := &secretsv1beta1.SecretCopierRule{
+			Name:         "registry",
+			SourceSecret: secretsv1beta1.SourceSecret{Name: "registry-credentials", Namespace: "source"},
+		}
+
+		Expect(targetSecretFieldManager(secretCopier, rule)).To(Equal("secrets-manager:registry-copier:registry"))
+	})
+
+	It("should identify an unnamed rule by its source secret", func() {
+		rule := &secretsv1beta1.SecretCopierRule{
+			SourceSecret: secretsv1beta1.SourceSecret{Name: "registry-credentials", Namespace: "source"},
+		}
+
+		Expect(targetSecretFieldManager(secretCopier, rule)).To(Equal("secrets-manager:registry-copier:source/registry-credentials"))
+	})
+
+	It("should truncate field managers longer than the API server accepts", func() {
+		longCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("a", 200)}}
+
+		rule := &secretsv1beta1.SecretCopierRule{Name: "registry"}
+
+		Expect(targetSecretFieldManager(longCopier, rule)).To(HaveLen(maxFieldManagerLength))
+	})
+
+	It("should cache impersonating clients by user and extra information", func() {
+		first := impersonationCacheKey("system:serviceaccount:team-a:copier", map[string][]string{
+			secretCopierImpersonationExtra: {"copier-1"},
+			"other":                        {"value"},
+		})
+
+		second := impersonationCacheKey("system:serviceaccount:team-a:copier", map[string][]string{
+			"other":                        {"value"},
+			secretCopierImpersonationExtra: {"copier-1"},
+		})
+
+		third := impersonationCacheKey("system:serviceaccount:team-a:copier", map[string][]string{
+			secretCopierImpersonationExtra: {"copier-2"},
+		})
+
+		Expect(first).To(Equal(second))
+		Expect(first).NotTo(Equal(third))
+	})
+})
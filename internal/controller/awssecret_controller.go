@@ -0,0 +1,287 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/awssecrets"
+)
+
+const (
+	// Annotation on a secret naming the AWSSecret it was created for.
+	awsSecretAnnotation = "secrets-manager.advok8s.io/aws-secret"
+
+	// Annotation on a secret recording the name or ARN of the secret in AWS
+	// Secrets Manager it was read from.
+	awsSecretIDAnnotation = "secrets-manager.advok8s.io/aws-secret-id"
+
+	// Annotation on a secret recording the version of the secret in AWS
+	// Secrets Manager it holds.
+	awsVersionIDAnnotation = "secrets-manager.advok8s.io/aws-version-id"
+
+	// Audience of service account tokens used to assume IAM roles if not
+	// specified.
+	defaultAWSAudience = "sts.amazonaws.com"
+)
+
+// Characters not permitted in the session name when assuming an IAM role.
+var awsSessionNameInvalid = regexp.MustCompile(`[^\w+=,.@-]`)
+
+// AWSSecretReconciler reconciles an AWSSecret object
+type AWSSecretReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Recorder for events about AWSSecret objects.
+	Recorder record.EventRecorder
+
+	// Controller wide settings which can be changed while running. If nil,
+	// the built in defaults are used.
+	Config *ManagerConfig
+
+	// If set, secrets for AWSSecret objects are never written.
+	ReportOnly bool
+
+	// HTTP client used for requests against AWS. If nil, http.DefaultClient
+	// is used.
+	HTTPClient *http.Client
+
+	// URL of the STS endpoint used when assuming IAM roles. If empty, the
+	// regional endpoint is used.
+	STSEndpoint string
+}
+
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=awssecrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=awssecrets/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=awssecrets/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts/token,verbs=create
+
+// Reconcile an AWSSecret by reading the secret from AWS Secrets Manager and
+// writing its value to a secret in the namespace of the AWSSecret. The secret
+// is read again each refresh interval, so changes made in AWS, including
+// rotation by Secrets Manager, are picked up, and from there propagated by
+// any SecretCopier using the secret as a source.
+func (r *AWSSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	// Fetch the named AWSSecret object.
+
+	var awsSecret secretsv1beta1.AWSSecret
+
+	if err := r.Get(ctx, req.NamespacedName, &awsSecret); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			// Custom resource has been deleted. The secret created for it
+			// will be deleted by the garbage collector as the AWSSecret is
+			// set as its owner.
+
+			log.V(1).Info("AWSSecret has been deleted", "name", req.NamespacedName)
+
+			return ctrl.Result{}, nil
+		}
+
+		log.Error(err, "Unable to fetch AWSSecret", "name", req.NamespacedName)
+
+		return ctrl.Result{}, err
+	}
+
+	refreshInterval := defaultExternalRefreshInterval
+
+	if awsSecret.Spec.RefreshInterval != nil && awsSecret.Spec.RefreshInterval.Duration > 0 {
+		refreshInterval = awsSecret.Spec.RefreshInterval.Duration
+	}
+
+	secretName := awsSecret.TargetSecretName()
+
+	status := *awsSecret.Status.DeepCopy()
+
+	status.ObservedGeneration = awsSecret.Generation
+
+	condition := metav1.Condition{ObservedGeneration: awsSecret.Generation}
+
+	secret, err := r.readAWSSecret(ctx, &awsSecret)
+
+	if err != nil {
+		condition.Reason = "ReadFailed"
+		condition.Message = err.Error()
+
+		var authErr *externalAuthError
+
+		if errors.As(err, &authErr) {
+			condition.Reason = "AuthenticationFailed"
+		}
+	} else {
+		synced := fmt.Sprintf("Secret %s synced from %s at version %s", secretName, awsSecret.Spec.SecretID, secret.VersionID)
+
+		annotations := map[string]string{
+			awsSecretAnnotation:    awsSecret.Name,
+			awsSecretIDAnnotation:  awsSecret.Spec.SecretID,
+			awsVersionIDAnnotation: secret.VersionID,
+		}
+
+		writer := &externalSecretWriter{Client: r.Client, Scheme: r.Scheme, Config: r.Config, ReportOnly: r.ReportOnly, Controller: "awssecret"}
+
+		condition.Reason, condition.Message, err = writer.write(ctx, &awsSecret, "AWSSecret", secretName, secret.Data, annotations, synced)
+
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if condition.Reason == "Synced" {
+			now := metav1.Now()
+
+			status.SecretName = secretName
+			status.VersionID = secret.VersionID
+			status.LastRefreshTime = &now
+		}
+	}
+
+	requeueAfter := setExternalSecretReadyCondition(r.Recorder, &awsSecret, &status.Conditions, condition, refreshInterval, r.Config)
+
+	if err := r.updateAWSSecretStatus(ctx, &awsSecret, status); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// Read the secret for the AWSSecret from AWS Secrets Manager, authenticating
+// as configured. Errors are reported in the status of the AWSSecret rather
+// than retried immediately, as they are usually due to configuration of the
+// AWSSecret or of IAM.
+func (r *AWSSecretReconciler) readAWSSecret(ctx context.Context, awsSecret *secretsv1beta1.AWSSecret) (*awssecrets.Secret, error) {
+	spec := &awsSecret.Spec
+
+	awsClient := &awssecrets.Client{
+		Region:      spec.Region,
+		Endpoint:    spec.Endpoint,
+		STSEndpoint: r.STSEndpoint,
+		HTTPClient:  r.HTTPClient,
+	}
+
+	switch {
+	case spec.Auth.ServiceAccount != nil:
+		auth := spec.Auth.ServiceAccount
+
+		serviceAccountName := auth.ServiceAccountName
+
+		if serviceAccountName == "" {
+			serviceAccountName = "default"
+		}
+
+		audience := auth.Audience
+
+		if audience == "" {
+			audience = defaultAWSAudience
+		}
+
+		token, err := requestServiceAccountToken(ctx, r.Client, awsSecret.Namespace, serviceAccountName, []string{audience})
+
+		if err != nil {
+			return nil, &externalAuthError{"AWS", err}
+		}
+
+		if _, err := awsClient.AssumeRoleWithWebIdentity(ctx, auth.RoleARN, awsSessionName(awsSecret), token); err != nil {
+			return nil, &externalAuthError{"AWS", fmt.Errorf("unable to assume role %s: %w", auth.RoleARN, err)}
+		}
+
+	case spec.Auth.AccessKey != nil:
+		var accessKey corev1.Secret
+
+		if err := r.Get(ctx, client.ObjectKey{Namespace: awsSecret.Namespace, Name: spec.Auth.AccessKey.SecretName}, &accessKey); err != nil {
+			return nil, &externalAuthError{"AWS", fmt.Errorf("unable to fetch secret %s: %w", spec.Auth.AccessKey.SecretName, err)}
+		}
+
+		awsClient.Credentials = awssecrets.Credentials{
+			AccessKeyID:     string(accessKey.Data["accessKeyID"]),
+			SecretAccessKey: string(accessKey.Data["secretAccessKey"]),
+			SessionToken:    string(accessKey.Data["sessionToken"]),
+		}
+
+		if awsClient.Credentials.AccessKeyID == "" || awsClient.Credentials.SecretAccessKey == "" {
+			return nil, &externalAuthError{"AWS", fmt.Errorf("secret %s must have keys accessKeyID and secretAccessKey", spec.Auth.AccessKey.SecretName)}
+		}
+
+	default:
+		return nil, &externalAuthError{"AWS", errors.New("no authentication method configured")}
+	}
+
+	secret, err := awsClient.GetSecretValue(ctx, spec.SecretID, spec.VersionStage)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", spec.SecretID, err)
+	}
+
+	return secret, nil
+}
+
+// Session name used when assuming an IAM role for an AWSSecret, so requests
+// made for it can be identified in CloudTrail.
+func awsSessionName(awsSecret *secretsv1beta1.AWSSecret) string {
+	name := awsSessionNameInvalid.ReplaceAllString("secrets-manager@"+awsSecret.Namespace+"."+awsSecret.Name, "-")
+
+	if len(name) > 64 {
+		name = name[:64]
+	}
+
+	return name
+}
+
+// Update the status of the AWSSecret if it has changed.
+func (r *AWSSecretReconciler) updateAWSSecretStatus(ctx context.Context, awsSecret *secretsv1beta1.AWSSecret, status secretsv1beta1.AWSSecretStatus) error {
+	log := log.FromContext(ctx)
+
+	if equality.Semantic.DeepEqual(awsSecret.Status, status) {
+		return nil
+	}
+
+	awsSecret.Status = status
+
+	if err := r.Status().Update(ctx, awsSecret); err != nil {
+		log.Error(err, "Unable to update AWSSecret status", "name", awsSecret.Name, "namespace", awsSecret.Namespace)
+		return err
+	}
+
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager. Only changes to
+// the generation of an AWSSecret trigger a reconciliation, so the status
+// updated on each refresh doesn't cause AWS to be read again immediately.
+func (r *AWSSecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&secretsv1beta1.AWSSecret{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Owns(&corev1.Secret{}).
+		Complete(r)
+}
@@ -0,0 +1,275 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("AWSSecret Controller", func() {
+	ctx := context.Background()
+
+	request := ctrl.Request{NamespacedName: client.ObjectKey{Namespace: "team-1", Name: "database"}}
+
+	roleARN := "arn:aws:iam::123456789012:role/team-1"
+
+	// Fake AWS server serving both STS and Secrets Manager. Web identity
+	// tokens for the secrets-reader service account are accepted for the
+	// team-1 role, and Secrets Manager requests must be signed with either
+	// the static access key or the credentials issued by assuming the role.
+
+	var mutex sync.Mutex
+	var secretString string
+	var versionID string
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		secretString = `{"username":"admin","password":"secret-1"}`
+		versionID = "v1"
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if r.Header.Get("X-Amz-Target") == "" {
+				_ = r.ParseForm()
+
+				if r.Form.Get("RoleArn") != roleARN || r.Form.Get("WebIdentityToken") != "secrets-reader-token" {
+					w.WriteHeader(http.StatusForbidden)
+					_, _ = w.Write([]byte(`<ErrorResponse><Error><Code>AccessDenied</Code><Message>Not authorized</Message></Error></ErrorResponse>`))
+					return
+				}
+
+				_, _ = w.Write([]byte(`<AssumeRoleWithWebIdentityResponse><AssumeRoleWithWebIdentityResult><Credentials>` +
+					`<AccessKeyId>ASIATEMPORARY</AccessKeyId><SecretAccessKey>temporary</SecretAccessKey><SessionToken>session</SessionToken>` +
+					`</Credentials></AssumeRoleWithWebIdentityResult></AssumeRoleWithWebIdentityResponse>`))
+				return
+			}
+
+			authorization := r.Header.Get("Authorization")
+
+			if !strings.Contains(authorization, "Credential=AKIDEXAMPLE/") && !strings.Contains(authorization, "Credential=ASIATEMPORARY/") {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"__type":"UnrecognizedClientException","message":"The security token included in the request is invalid."}`))
+				return
+			}
+
+			var input map[string]string
+
+			_ = json.NewDecoder(r.Body).Decode(&input)
+
+			if input["SecretId"] != "team-1/database" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"__type":"ResourceNotFoundException","message":"Secrets Manager can't find the specified secret."}`))
+				return
+			}
+
+			encoded, _ := json.Marshal(map[string]string{"VersionId": versionID, "SecretString": secretString})
+
+			_, _ = w.Write(encoded)
+		}))
+
+		DeferCleanup(server.Close)
+	})
+
+	newAWSSecret := func(secretID string, auth secretsv1beta1.AWSAuth) *secretsv1beta1.AWSSecret {
+		return &secretsv1beta1.AWSSecret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-1", Name: "database", Generation: 1},
+			Spec: secretsv1beta1.AWSSecretSpec{
+				Region:   "us-east-1",
+				SecretID: secretID,
+				Endpoint: server.URL,
+				Auth:     auth,
+			},
+		}
+	}
+
+	accessKeyAuth := func() secretsv1beta1.AWSAuth {
+		return secretsv1beta1.AWSAuth{AccessKey: &secretsv1beta1.AWSAccessKeyAuth{SecretName: "aws-access-key"}}
+	}
+
+	serviceAccountAuth := func(serviceAccountName string) secretsv1beta1.AWSAuth {
+		return secretsv1beta1.AWSAuth{ServiceAccount: &secretsv1beta1.AWSServiceAccountAuth{RoleARN: roleARN, ServiceAccountName: serviceAccountName}}
+	}
+
+	accessKeySecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-1", Name: "aws-access-key"},
+		Data: map[string][]byte{
+			"accessKeyID":     []byte("AKIDEXAMPLE"),
+			"secretAccessKey": []byte("example"),
+		},
+	}
+
+	newReconciler := func(awsSecret *secretsv1beta1.AWSSecret, objects ...client.Object) (*AWSSecretReconciler, *record.FakeRecorder) {
+		recorder := record.NewFakeRecorder(10)
+
+		c := fake.NewClientBuilder().
+			WithObjects(append(objects, awsSecret)...).
+			WithStatusSubresource(awsSecret).
+			WithInterceptorFuncs(interceptor.Funcs{
+				SubResourceCreate: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, subResource client.Object, opts ...client.SubResourceCreateOption) error {
+					tokenRequest := subResource.(*authenticationv1.TokenRequest)
+					Expect(tokenRequest.Spec.Audiences).To(Equal([]string{"sts.amazonaws.com"}))
+					tokenRequest.Status.Token = obj.GetName() + "-token"
+					return nil
+				},
+			}).
+			Build()
+
+		return &AWSSecretReconciler{Client: c, Scheme: scheme.Scheme, Recorder: recorder, STSEndpoint: server.URL}, recorder
+	}
+
+	fetch := func(reconciler *AWSSecretReconciler) (*secretsv1beta1.AWSSecret, *corev1.Secret) {
+		var awsSecret secretsv1beta1.AWSSecret
+
+		Expect(reconciler.Get(ctx, request.NamespacedName, &awsSecret)).To(Succeed())
+
+		var secret corev1.Secret
+
+		if err := reconciler.Get(ctx, request.NamespacedName, &secret); err != nil {
+			Expect(client.IgnoreNotFound(err)).To(Succeed())
+			return &awsSecret, nil
+		}
+
+		return &awsSecret, &secret
+	}
+
+	It("should derive a valid session name for assuming a role", func() {
+		awsSecret := &secretsv1beta1.AWSSecret{ObjectMeta: metav1.ObjectMeta{Namespace: "team-1", Name: "database"}}
+
+		Expect(awsSessionName(awsSecret)).To(Equal("secrets-manager@team-1.database"))
+
+		awsSecret.Name = strings.Repeat("a", 80)
+
+		Expect(awsSessionName(awsSecret)).To(HaveLen(64))
+	})
+
+	It("should sync the secret from AWS using an access key", func() {
+		reconciler, recorder := newReconciler(newAWSSecret("team-1/database", accessKeyAuth()), accessKeySecret.DeepCopy())
+
+		result, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(5 * time.Minute))
+
+		awsSecret, secret := fetch(reconciler)
+		Expect(secret).NotTo(BeNil())
+		Expect(secret.Type).To(Equal(corev1.SecretTypeOpaque))
+		Expect(secret.Data).To(Equal(map[string][]byte{"username": []byte("admin"), "password": []byte("secret-1")}))
+		Expect(secret.Annotations).To(HaveKeyWithValue(awsSecretAnnotation, "database"))
+		Expect(secret.Annotations).To(HaveKeyWithValue(awsSecretIDAnnotation, "team-1/database"))
+		Expect(secret.Annotations).To(HaveKeyWithValue(awsVersionIDAnnotation, "v1"))
+		Expect(metav1.IsControlledBy(secret, awsSecret)).To(BeTrue())
+
+		Expect(awsSecret.Status.SecretName).To(Equal("database"))
+		Expect(awsSecret.Status.VersionID).To(Equal("v1"))
+		Expect(awsSecret.Status.LastRefreshTime).NotTo(BeNil())
+		Expect(meta.IsStatusConditionTrue(awsSecret.Status.Conditions, secretsv1beta1.ConditionReady)).To(BeTrue())
+		Expect(recorder.Events).To(Receive(ContainSubstring("Synced Secret database synced from team-1/database at version v1")))
+
+		// Rotation of the secret in AWS is picked up on refresh.
+
+		mutex.Lock()
+		secretString = "plain-text"
+		versionID = "v2"
+		mutex.Unlock()
+
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		awsSecret, secret = fetch(reconciler)
+		Expect(secret.Data).To(Equal(map[string][]byte{"value": []byte("plain-text")}))
+		Expect(secret.Annotations).To(HaveKeyWithValue(awsVersionIDAnnotation, "v2"))
+		Expect(awsSecret.Status.VersionID).To(Equal("v2"))
+	})
+
+	It("should assume an IAM role for a service account", func() {
+		reconciler, _ := newReconciler(newAWSSecret("team-1/database", serviceAccountAuth("secrets-reader")))
+
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, secret := fetch(reconciler)
+		Expect(secret).NotTo(BeNil())
+		Expect(secret.Data).To(HaveKeyWithValue("password", []byte("secret-1")))
+	})
+
+	It("should report failures to read from AWS and retry sooner", func() {
+		tests := []struct {
+			name      string
+			awsSecret *secretsv1beta1.AWSSecret
+			objects   []client.Object
+			reason    string
+		}{
+			{
+				name:      "missing access key secret",
+				awsSecret: newAWSSecret("team-1/database", accessKeyAuth()),
+				reason:    "AuthenticationFailed",
+			},
+			{
+				name:      "role not permitted for service account",
+				awsSecret: newAWSSecret("team-1/database", serviceAccountAuth("default")),
+				reason:    "AuthenticationFailed",
+			},
+			{
+				name:      "missing secret",
+				awsSecret: newAWSSecret("team-1/missing", accessKeyAuth()),
+				objects:   []client.Object{accessKeySecret.DeepCopy()},
+				reason:    "ReadFailed",
+			},
+		}
+
+		for _, test := range tests {
+			By(test.name)
+
+			reconciler, recorder := newReconciler(test.awsSecret, test.objects...)
+
+			result, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Minute))
+
+			awsSecret, secret := fetch(reconciler)
+			Expect(secret).To(BeNil())
+
+			condition := meta.FindStatusCondition(awsSecret.Status.Conditions, secretsv1beta1.ConditionReady)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(test.reason))
+			Expect(recorder.Events).To(Receive(HavePrefix("Warning " + test.reason)))
+		}
+	})
+})
@@ -0,0 +1,119 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sync"
+	"time"
+)
+
+// Exponential backoff tracked per key, used to avoid repeatedly retrying an
+// operation against a target which is known to be failing. The zero value is
+// ready to use.
+type targetBackoff struct {
+	mutex   sync.Mutex
+	entries map[string]*targetBackoffEntry
+}
+
+type targetBackoffEntry struct {
+	delay   time.Duration
+	retryAt time.Time
+	err     error
+}
+
+// Check whether an operation against the target can be attempted. If the
+// target is still backing off, the error from the last failure is returned.
+func (b *targetBackoff) ready(key string, now time.Time) (bool, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	entry, ok := b.entries[key]
+
+	if !ok || !now.Before(entry.retryAt) {
+		return true, nil
+	}
+
+	return false, entry.err
+}
+
+// Record that an operation against the target failed, extending the time
+// until the operation can be attempted again. The delay starts at the initial
+// delay and doubles on each successive failure, up to the maximum delay. The
+// delay until the next attempt is returned.
+func (b *targetBackoff) failed(key string, now time.Time, initialDelay, maxDelay time.Duration, err error) time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.entries == nil {
+		b.entries = make(map[string]*targetBackoffEntry)
+	}
+
+	entry, ok := b.entries[key]
+
+	if !ok {
+		entry = &targetBackoffEntry{delay: initialDelay}
+		b.entries[key] = entry
+	} else {
+		entry.delay *= 2
+
+		if entry.delay > maxDelay {
+			entry.delay = maxDelay
+		}
+	}
+
+	entry.retryAt = now.Add(entry.delay)
+	entry.err = err
+
+	return entry.delay
+}
+
+// Return the time at which an operation against the target can next be
+// attempted, and whether the target is backing off at all. The target remains
+// backing off after the time has passed until the operation succeeds.
+func (b *targetBackoff) retryTime(key string) (time.Time, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	entry, ok := b.entries[key]
+
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return entry.retryAt, true
+}
+
+// Record that an operation against the target succeeded, clearing any
+// backoff for it.
+func (b *targetBackoff) succeeded(key string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.entries, key)
+}
+
+// Clear any backoff for the keys accepted by the filter.
+func (b *targetBackoff) forget(filter func(key string) bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for key := range b.entries {
+		if filter(key) {
+			delete(b.entries, key)
+		}
+	}
+}
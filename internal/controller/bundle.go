@@ -0,0 +1,269 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Annotation recording on a bundled config map the name of the target secret
+// it was copied with.
+const bundledSecretAnnotation = "secrets-manager.advok8s.io/bundled-secret"
+
+// Name of the config map bundled with the target secret of a rule. This
+// defaults to the name of the source config map.
+func bundledConfigMapName(rule *secretsv1beta1.SecretCopierRule) string {
+	if rule.BundledConfigMap.TargetName != "" {
+		return rule.BundledConfigMap.TargetName
+	}
+
+	return rule.BundledConfigMap.Name
+}
+
+// Copy the source secret of a rule and the config map bundled with it to the
+// target namespace. The secret is only copied while the source config map
+// exists, so a target namespace never receives one without the other. The
+// config map is written straight after the secret, and is owned by the target
+// secret so it is deleted whenever the target secret is, whether by the
+// garbage collector when the SecretCopier is deleted or by the orphan reaper.
+func (r *SecretCopierReconciler) copyBundleToNamespace(ctx context.Context, secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, targetNamespace string) (copyResult, error) {
+	log := log.FromContext(ctx)
+
+	if rule.SourceSecret.Namespace == targetNamespace {
+		return copySkipped, nil
+	}
+
+	secretsClient, err := r.secretsClient(secretCopier)
+
+	if err != nil {
+		return copyFailed, err
+	}
+
+	var sourceConfigMap corev1.ConfigMap
+
+	sourceConfigMapKey := client.ObjectKey{Namespace: rule.SourceSecret.Namespace, Name: rule.BundledConfigMap.Name}
+
+	if err := secretsClient.Get(ctx, sourceConfigMapKey, &sourceConfigMap); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.V(1).Info("Skipping copy of secret as bundled config map does not exist", "sourceSecret", rule.SourceSecret, "configMap", sourceConfigMapKey)
+			return copySkipped, nil
+		}
+
+		log.Error(err, "Unable to fetch bundled config map", "configMap", sourceConfigMapKey)
+
+		return copyFailed, err
+	}
+
+	result, err := r.copyTargetSecretToNamespace(ctx, secretCopier, rule, targetNamespace)
+
+	if result != copyCreated && result != copyUpdated && result != copyUnchanged {
+		return result, err
+	}
+
+	// The target secret must be read to find its UID for the owner reference
+	// of the config map. A target secret which was only just created may not
+	// yet be in the informer cache, in which case it is read from the API
+	// server instead.
+
+	var targetSecret corev1.Secret
+
+	targetSecretKey := client.ObjectKey{Namespace: targetNamespace, Name: targetSecretName(rule)}
+
+	err = secretsClient.Get(ctx, targetSecretKey, &targetSecret)
+
+	if apierrors.IsNotFound(err) && r.APIReader != nil && secretCopier.Spec.ServiceAccount == nil {
+		err = r.APIReader.Get(ctx, targetSecretKey, &targetSecret)
+	}
+
+	if err != nil {
+		log.Error(err, "Unable to fetch target secret for bundled config map", "targetSecret", targetSecretKey)
+		return copyFailed, err
+	}
+
+	bundleResult, err := r.writeBundledConfigMap(ctx, secretsClient, secretCopier, rule, &sourceConfigMap, &targetSecret)
+
+	if bundleResult != copyUnchanged {
+		return bundleResult, err
+	}
+
+	return result, nil
+}
+
+// Create or update the copy of the bundled config map in the namespace of the
+// target secret. An existing config map which wasn't copied by the
+// SecretCopier from the same source config map is left alone, and the bundle
+// reported as skipped.
+func (r *SecretCopierReconciler) writeBundledConfigMap(ctx context.Context, secretsClient client.Client, secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, sourceConfigMap *corev1.ConfigMap, targetSecret *corev1.Secret) (copyResult, error) {
+	log := log.FromContext(ctx)
+
+	desired := corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      bundledConfigMapName(rule),
+			Namespace: targetSecret.Namespace,
+			Labels:    maps.Clone(sourceConfigMap.Labels),
+			Annotations: map[string]string{
+				secretCopierMarkerAnnotation:    secretCopier.Name,
+				sourceConfigMapMarkerAnnotation: sourceConfigMap.Namespace + "/" + sourceConfigMap.Name,
+				bundledSecretAnnotation:         targetSecret.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion:         "v1",
+					Kind:               "Secret",
+					Name:               targetSecret.Name,
+					UID:                targetSecret.UID,
+					Controller:         ptr.To(true),
+					BlockOwnerDeletion: ptr.To(false),
+				},
+			},
+		},
+		Data:       sourceConfigMap.Data,
+		BinaryData: sourceConfigMap.BinaryData,
+	}
+
+	var targetConfigMap corev1.ConfigMap
+
+	if err := secretsClient.Get(ctx, client.ObjectKeyFromObject(&desired), &targetConfigMap); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return copyFailed, err
+		}
+
+		if r.writesSuspended() {
+			log.V(1).Info("Report-only mode, not creating bundled config map", "configMap", desired.Name, "targetNamespace", desired.Namespace)
+			return copyReportOnly, nil
+		}
+
+		if err := secretsClient.Create(ctx, &desired, targetSecretWriteOwner(secretCopier, rule)); err != nil {
+			log.Error(err, "Unable to create bundled config map", "configMap", desired.Name, "targetNamespace", desired.Namespace)
+			return copyFailed, err
+		}
+
+		log.V(1).Info("Created bundled config map", "configMap", desired.Name, "targetNamespace", desired.Namespace)
+
+		return copyCreated, nil
+	}
+
+	if targetConfigMap.Annotations[secretCopierMarkerAnnotation] != secretCopier.Name ||
+		targetConfigMap.Annotations[sourceConfigMapMarkerAnnotation] != desired.Annotations[sourceConfigMapMarkerAnnotation] {
+		log.V(1).Info("Bundled config map not managed by SecretCopier, skipping", "configMap", targetConfigMap.Name, "targetNamespace", targetConfigMap.Namespace)
+		return copySkipped, nil
+	}
+
+	if equality.Semantic.DeepEqual(targetConfigMap.Data, desired.Data) &&
+		equality.Semantic.DeepEqual(targetConfigMap.BinaryData, desired.BinaryData) &&
+		maps.Equal(targetConfigMap.Labels, desired.Labels) &&
+		maps.Equal(targetConfigMap.Annotations, desired.Annotations) &&
+		equality.Semantic.DeepEqual(targetConfigMap.OwnerReferences, desired.OwnerReferences) {
+		return copyUnchanged, nil
+	}
+
+	if r.writesSuspended() {
+		log.V(1).Info("Report-only mode, not updating bundled config map", "configMap", desired.Name, "targetNamespace", desired.Namespace)
+		return copyReportOnly, nil
+	}
+
+	targetConfigMap.Data = desired.Data
+	targetConfigMap.BinaryData = desired.BinaryData
+	targetConfigMap.Labels = desired.Labels
+	targetConfigMap.Annotations = desired.Annotations
+	targetConfigMap.OwnerReferences = desired.OwnerReferences
+
+	if err := secretsClient.Update(ctx, &targetConfigMap, targetSecretWriteOwner(secretCopier, rule)); err != nil {
+		log.Error(err, "Unable to update bundled config map", "configMap", desired.Name, "targetNamespace", desired.Namespace)
+		return copyFailed, err
+	}
+
+	log.V(1).Info("Updated bundled config map", "configMap", desired.Name, "targetNamespace", desired.Namespace)
+
+	return copyUpdated, nil
+}
+
+// Name of the field index on SecretCopier objects holding the config maps
+// bundled by their rules, so config map events only need to look up the
+// SecretCopier objects affected.
+const bundledConfigMapIndexField = "spec.rules.bundledConfigMap"
+
+// Extract the index keys for the config maps bundled by the rules of a
+// SecretCopier. The config map is read from the namespace of the source secret.
+func bundledConfigMapIndexValues(object client.Object) []string {
+	secretCopier, ok := object.(*secretsv1beta1.SecretCopier)
+
+	if !ok {
+		return nil
+	}
+
+	var values []string
+
+	for _, rule := range secretCopier.Spec.Rules {
+		if rule.BundledConfigMap == nil {
+			continue
+		}
+
+		if value := rule.SourceSecret.Namespace + "/" + rule.BundledConfigMap.Name; !slices.Contains(values, value) {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// Register the field index used to look up the SecretCopier objects bundling
+// a config map.
+func indexSecretCopierBundledConfigMaps(ctx context.Context, mgr ctrl.Manager) error {
+	return mgr.GetFieldIndexer().IndexField(ctx, &secretsv1beta1.SecretCopier{}, bundledConfigMapIndexField, bundledConfigMapIndexValues)
+}
+
+// Handler function to find SecretCopier objects with a rule bundling a config
+// map, so the config map is copied again when it changes. Only the metadata
+// of config maps is watched, which is all that is needed to look up the
+// SecretCopier objects in the index.
+func (r *SecretCopierReconciler) findSecretCopiersBundlingConfigMap(ctx context.Context, configMap client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	var secretCopiers secretsv1beta1.SecretCopierList
+
+	if err := r.List(ctx, &secretCopiers, client.MatchingFields{bundledConfigMapIndexField: configMap.GetNamespace() + "/" + configMap.GetName()}); err != nil {
+		log.Error(err, "Unable to list SecretCopier objects")
+		return nil
+	}
+
+	var requests []reconcile.Request
+
+	for i := range secretCopiers.Items {
+		secretCopier := &secretCopiers.Items[i]
+
+		log.V(1).Info("Queue reconcile for bundled ConfigMap against SecretCopier", "name", secretCopier.Name, "configMap", configMap.GetName(), "namespace", configMap.GetNamespace())
+
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secretCopier)})
+	}
+
+	return requests
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Bundled ConfigMaps", func() {
+	ctx := context.Background()
+
+	secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "copier"}}
+
+	rule := &secretsv1beta1.SecretCopierRule{
+		SourceSecret:     secretsv1beta1.SourceSecret{Name: "tls", Namespace: "source"},
+		BundledConfigMap: &secretsv1beta1.BundledConfigMap{Name: "ca-bundle", TargetName: "tls-ca"},
+	}
+
+	var fakeClient client.Client
+	var reconciler *SecretCopierReconciler
+
+	BeforeEach(func() {
+		fakeClient = fake.NewClientBuilder().WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "tls", Namespace: "source"},
+			Data:       map[string][]byte{"tls.crt": []byte("certificate")},
+		}).Build()
+
+		reconciler = &SecretCopierReconciler{
+			Client:   fakeClient,
+			Recorder: record.NewFakeRecorder(10),
+		}
+	})
+
+	It("should not copy the secret without the bundled config map", func() {
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copySkipped))
+
+		var targetSecret corev1.Secret
+		Expect(fakeClient.Get(ctx, client.ObjectKey{Namespace: "tenant", Name: "tls"}, &targetSecret)).NotTo(Succeed())
+	})
+
+	It("should copy the config map owned by the target secret", func() {
+		Expect(fakeClient.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "ca-bundle", Namespace: "source"},
+			Data:       map[string]string{"ca.crt": "authority"},
+		})).To(Succeed())
+
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyCreated))
+
+		var targetSecret corev1.Secret
+		Expect(fakeClient.Get(ctx, client.ObjectKey{Namespace: "tenant", Name: "tls"}, &targetSecret)).To(Succeed())
+
+		var targetConfigMap corev1.ConfigMap
+		Expect(fakeClient.Get(ctx, client.ObjectKey{Namespace: "tenant", Name: "tls-ca"}, &targetConfigMap)).To(Succeed())
+
+		Expect(targetConfigMap.Data).To(Equal(map[string]string{"ca.crt": "authority"}))
+		Expect(targetConfigMap.Annotations).To(HaveKeyWithValue(bundledSecretAnnotation, "tls"))
+		Expect(targetConfigMap.OwnerReferences).To(HaveLen(1))
+		Expect(targetConfigMap.OwnerReferences[0].Kind).To(Equal("Secret"))
+		Expect(targetConfigMap.OwnerReferences[0].UID).To(Equal(targetSecret.UID))
+
+		// A change to only the config map is reported as an update.
+
+		var sourceConfigMap corev1.ConfigMap
+		Expect(fakeClient.Get(ctx, client.ObjectKey{Namespace: "source", Name: "ca-bundle"}, &sourceConfigMap)).To(Succeed())
+
+		sourceConfigMap.Data["ca.crt"] = "rotated"
+		Expect(fakeClient.Update(ctx, &sourceConfigMap)).To(Succeed())
+
+		result, err = reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyUpdated))
+
+		Expect(fakeClient.Get(ctx, client.ObjectKey{Namespace: "tenant", Name: "tls-ca"}, &targetConfigMap)).To(Succeed())
+		Expect(targetConfigMap.Data).To(HaveKeyWithValue("ca.crt", "rotated"))
+
+		result, err = reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyUnchanged))
+	})
+
+	It("should not overwrite a config map it doesn't manage", func() {
+		Expect(fakeClient.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "ca-bundle", Namespace: "source"},
+			Data:       map[string]string{"ca.crt": "authority"},
+		})).To(Succeed())
+
+		Expect(fakeClient.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "tls-ca", Namespace: "tenant"},
+			Data:       map[string]string{"ca.crt": "local"},
+		})).To(Succeed())
+
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copySkipped))
+
+		var targetConfigMap corev1.ConfigMap
+		Expect(fakeClient.Get(ctx, client.ObjectKey{Namespace: "tenant", Name: "tls-ca"}, &targetConfigMap)).To(Succeed())
+		Expect(targetConfigMap.Data).To(HaveKeyWithValue("ca.crt", "local"))
+	})
+
+	It("should only queue SecretCopier objects bundling the config map", func() {
+		bundling := func(name string, namespace string, configMap string) *secretsv1beta1.SecretCopier {
+			return &secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Spec: secretsv1beta1.SecretCopierSpec{Rules: []secretsv1beta1.SecretCopierRule{{
+					SourceSecret:     secretsv1beta1.SourceSecret{Name: "tls", Namespace: namespace},
+					BundledConfigMap: &secretsv1beta1.BundledConfigMap{Name: configMap},
+				}}},
+			}
+		}
+
+		reconciler := &SecretCopierReconciler{Client: fake.NewClientBuilder().
+			WithIndex(&secretsv1beta1.SecretCopier{}, bundledConfigMapIndexField, bundledConfigMapIndexValues).
+			WithObjects(
+				bundling("bundling", "source", "ca-bundle"),
+				bundling("other-namespace", "other", "ca-bundle"),
+				bundling("other-name", "source", "other-bundle"),
+				&secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "not-bundling"}},
+			).Build()}
+
+		requested := func(namespace string, name string) []string {
+			configMap := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+
+			var names []string
+
+			for _, request := range reconciler.findSecretCopiersBundlingConfigMap(ctx, configMap) {
+				names = append(names, request.Name)
+			}
+
+			return names
+		}
+
+		Expect(requested("source", "ca-bundle")).To(ConsistOf("bundling"))
+		Expect(requested("other", "ca-bundle")).To(ConsistOf("other-namespace"))
+		Expect(requested("tenant", "secrets-status")).To(BeEmpty())
+	})
+})
@@ -0,0 +1,81 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Virtual resource against which access to catalog entries is checked. There
+// is no such resource in the API server, but RBAC rules can still reference it
+// and the authorizer will evaluate them when a SubjectAccessReview is made.
+const (
+	catalogEntryResource    = "secretcatalogentries"
+	catalogEntrySubresource = "claim"
+	catalogEntryVerb        = "create"
+)
+
+// Name of the catalog entry used as the resource name in access reviews. This
+// allows RBAC rules to restrict access to specific entries using resourceNames.
+func catalogEntryResourceName(catalog string, key string) string {
+	return catalog + ":" + key
+}
+
+// Check using a SubjectAccessReview whether the namespace of a claim is
+// permitted to claim the entry from the catalog. The subject of the review is
+// the default service account of the namespace and the service accounts group
+// of the namespace only, so access is granted by creating a RoleBinding in the
+// namespace of the tenant binding a role with access to the entry to the group
+// "system:serviceaccounts:<namespace>". Groups spanning all namespaces, such as
+// "system:authenticated", are left out so a binding to them can't grant every
+// namespace the right to claim the entry.
+func (r *SecretClaimReconciler) claimAllowed(ctx context.Context, secretClaim *secretsv1beta1.SecretClaim) (bool, string, error) {
+	log := log.FromContext(ctx)
+
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User: "system:serviceaccount:" + secretClaim.Namespace + ":default",
+			Groups: []string{
+				"system:serviceaccounts:" + secretClaim.Namespace,
+			},
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   secretClaim.Namespace,
+				Verb:        catalogEntryVerb,
+				Group:       secretsv1beta1.GroupVersion.Group,
+				Resource:    catalogEntryResource,
+				Subresource: catalogEntrySubresource,
+				Name:        catalogEntryResourceName(secretClaim.Spec.Catalog, secretClaim.Spec.Key),
+			},
+		},
+	}
+
+	if err := r.Create(ctx, review); err != nil {
+		log.Error(err, "Unable to perform SubjectAccessReview for SecretClaim", "name", secretClaim.Name, "namespace", secretClaim.Namespace)
+
+		return false, "", err
+	}
+
+	log.V(1).Info("SubjectAccessReview for SecretClaim", "name", secretClaim.Name, "namespace", secretClaim.Namespace,
+		"allowed", review.Status.Allowed, "reason", review.Status.Reason)
+
+	return review.Status.Allowed, review.Status.Reason, nil
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Claim Authorization", func() {
+	ctx := context.Background()
+
+	It("should only review the identity of the namespace of the claim", func() {
+		var reviewed *authorizationv1.SubjectAccessReviewSpec
+
+		k8sClient := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, object client.Object, opts ...client.CreateOption) error {
+				review := object.(*authorizationv1.SubjectAccessReview)
+
+				reviewed = review.Spec.DeepCopy()
+
+				review.Status.Allowed = true
+
+				return nil
+			},
+		}).Build()
+
+		reconciler := &SecretClaimReconciler{Client: k8sClient}
+
+		secretClaim := &secretsv1beta1.SecretClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "tenant-a"},
+			Spec:       secretsv1beta1.SecretClaimSpec{Catalog: "shared-secrets", Key: "database-credentials"},
+		}
+
+		allowed, _, err := reconciler.claimAllowed(ctx, secretClaim)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(allowed).To(BeTrue())
+
+		Expect(reviewed.User).To(Equal("system:serviceaccount:tenant-a:default"))
+		Expect(reviewed.Groups).To(Equal([]string{"system:serviceaccounts:tenant-a"}))
+		Expect(reviewed.ResourceAttributes.Name).To(Equal("shared-secrets:database-credentials"))
+	})
+})
@@ -0,0 +1,83 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Create an event handler which maps events to reconcile requests using the
+// supplied map function, the same as handler.EnqueueRequestsFromMapFunc. When
+// the coalescing window is non zero, requests are added to the work queue only
+// after the window has elapsed. The delaying work queue holds a single entry
+// for each distinct request, so any further events mapping to the same request
+// within the window are coalesced into the one reconciliation. This avoids
+// storms of updates to secrets or namespaces, such as a mass renewal of
+// certificates, resulting in repeated reconciliation of the same SecretCopier.
+// Create events for objects which existed before the controller started are
+// instead scheduled according to the warm-up, if one is given.
+func enqueueCoalescedRequestsFromMapFunc(fn handler.MapFunc, window time.Duration, w *warmUp) handler.EventHandler {
+	enqueue := func(ctx context.Context, q workqueue.TypedRateLimitingInterface[reconcile.Request], objects ...client.Object) {
+		requests := make(map[reconcile.Request]struct{})
+
+		for _, object := range objects {
+			for _, request := range fn(ctx, object) {
+				if _, ok := requests[request]; ok {
+					continue
+				}
+
+				requests[request] = struct{}{}
+
+				if window > 0 {
+					q.AddAfter(request, window)
+				} else {
+					q.Add(request)
+				}
+			}
+		}
+	}
+
+	return handler.Funcs{
+		CreateFunc: func(ctx context.Context, e event.CreateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			if w.initial(e.Object) {
+				for _, request := range fn(ctx, e.Object) {
+					w.enqueue(q, request)
+				}
+
+				return
+			}
+
+			enqueue(ctx, q, e.Object)
+		},
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(ctx, q, e.ObjectOld, e.ObjectNew)
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(ctx, q, e.Object)
+		},
+		GenericFunc: func(ctx context.Context, e event.GenericEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(ctx, q, e.Object)
+		},
+	}
+}
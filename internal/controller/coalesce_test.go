@@ -0,0 +1,96 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Request Coalescing", func() {
+	ctx := context.Background()
+
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: "copier"}}
+
+	mapFunc := func(context.Context, client.Object) []reconcile.Request {
+		return []reconcile.Request{request}
+	}
+
+	secret := func(name string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "source"}}
+	}
+
+	newQueue := func() workqueue.TypedRateLimitingInterface[reconcile.Request] {
+		return workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+	}
+
+	It("should queue requests immediately without a window", func() {
+		handler := enqueueCoalescedRequestsFromMapFunc(mapFunc, 0, nil)
+
+		queue := newQueue()
+		defer queue.ShutDown()
+
+		handler.Update(ctx, event.UpdateEvent{ObjectOld: secret("a"), ObjectNew: secret("a")}, queue)
+
+		Expect(queue.Len()).To(Equal(1))
+	})
+
+	It("should collapse a burst of events into one request after the window", func() {
+		window := 200 * time.Millisecond
+
+		handler := enqueueCoalescedRequestsFromMapFunc(mapFunc, window, nil)
+
+		queue := newQueue()
+		defer queue.ShutDown()
+
+		start := time.Now()
+
+		for _, name := range []string{"a", "b", "c", "d", "e"} {
+			handler.Create(ctx, event.CreateEvent{Object: secret(name)}, queue)
+			handler.Update(ctx, event.UpdateEvent{ObjectOld: secret(name), ObjectNew: secret(name)}, queue)
+			handler.Delete(ctx, event.DeleteEvent{Object: secret(name)}, queue)
+		}
+
+		Expect(queue.Len()).To(BeZero())
+
+		Eventually(queue.Len).WithTimeout(5 * window).WithPolling(10 * time.Millisecond).Should(Equal(1))
+
+		Expect(time.Since(start)).To(BeNumerically(">=", window))
+
+		Consistently(queue.Len).WithTimeout(window).WithPolling(10 * time.Millisecond).Should(Equal(1))
+
+		item, shutdown := queue.Get()
+
+		Expect(shutdown).To(BeFalse())
+		Expect(item).To(Equal(request))
+
+		queue.Done(item)
+
+		Expect(queue.Len()).To(BeZero())
+	})
+})
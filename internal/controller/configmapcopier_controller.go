@@ -0,0 +1,452 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Annotations recording on a target config map the ConfigMapCopier managing
+// it and the source config map it was copied from.
+const (
+	configMapCopierMarkerAnnotation = "secrets-manager.advok8s.io/configmap-copier"
+	sourceConfigMapMarkerAnnotation = "secrets-manager.advok8s.io/configmap-name"
+)
+
+// Message recorded in the status of a rule which matches no target namespaces.
+// This is not treated as the rule being out of sync.
+const noTargetConfigMapNamespacesMessage = "No target namespaces match the selectors of the rule"
+
+// ConfigMapCopierReconciler reconciles a ConfigMapCopier object
+type ConfigMapCopierReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Recorder for events about ConfigMapCopier objects.
+	Recorder record.EventRecorder
+
+	// Controller wide settings which can be changed while running. If nil,
+	// the built in defaults are used.
+	Config *ManagerConfig
+
+	// If set, target config maps are never written.
+	ReportOnly bool
+}
+
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=configmapcopiers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=configmapcopiers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=configmapcopiers/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+
+// Reconcile copies the source config map of each rule of a ConfigMapCopier to
+// the target namespaces matched by the rule, in the same way as secrets are
+// copied by a SecretCopier.
+func (r *ConfigMapCopierReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	// Fetch the named ConfigMapCopier object.
+
+	var configMapCopier secretsv1beta1.ConfigMapCopier
+
+	if err := r.Get(ctx, req.NamespacedName, &configMapCopier); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			// Custom resource has been deleted. Target config maps with a
+			// reclaim policy of Delete are removed by the garbage collector
+			// through their owner reference.
+
+			log.V(1).Info("ConfigMapCopier has been deleted", "name", req.NamespacedName)
+
+			return ctrl.Result{}, nil
+		}
+
+		log.Error(err, "Unable to fetch ConfigMapCopier", "name", req.NamespacedName)
+
+		return ctrl.Result{}, err
+	}
+
+	log.V(1).Info("Fetched ConfigMapCopier", "configMapCopier", &configMapCopier)
+
+	settings := r.Config.Settings()
+
+	syncPeriod := settings.DefaultSyncPeriod
+
+	if configMapCopier.Spec.SyncPeriod != nil {
+		syncPeriod = configMapCopier.Spec.SyncPeriod.Duration
+	}
+
+	// Query the set of namespaces in the Kubernetes cluster, filtering out
+	// those which the manager has been configured to never copy to, and
+	// process them in order of name.
+
+	var namespaces corev1.NamespaceList
+
+	if err := r.List(ctx, &namespaces); err != nil {
+		log.Error(err, "Unable to list namespaces")
+		return ctrl.Result{}, err
+	}
+
+	activeNamespaces := make([]corev1.Namespace, 0)
+
+	for _, namespace := range namespaces.Items {
+		if !settings.NamespaceDenied(namespace.Name) {
+			activeNamespaces = append(activeNamespaces, namespace)
+		}
+	}
+
+	slices.SortFunc(activeNamespaces, func(a, b corev1.Namespace) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	ruleStatuses := make([]secretsv1beta1.ConfigMapCopierRuleStatus, len(configMapCopier.Spec.Rules))
+
+	for ruleIndex := range configMapCopier.Spec.Rules {
+		rule := &configMapCopier.Spec.Rules[ruleIndex]
+
+		ruleStatuses[ruleIndex] = r.processRule(ctx, &configMapCopier, rule, activeNamespaces)
+	}
+
+	configMapCopier.Status.ObservedGeneration = configMapCopier.Generation
+	configMapCopier.Status.Rules = ruleStatuses
+
+	meta.SetStatusCondition(&configMapCopier.Status.Conditions, configMapCopierReadyCondition(ruleStatuses, configMapCopier.Generation))
+
+	if err := r.updateStatus(ctx, &configMapCopier); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: syncPeriod}, nil
+}
+
+// Copy the source config map of a rule to the matching target namespaces,
+// returning the status of the rule.
+func (r *ConfigMapCopierReconciler) processRule(ctx context.Context, configMapCopier *secretsv1beta1.ConfigMapCopier, rule *secretsv1beta1.ConfigMapCopierRule, activeNamespaces []corev1.Namespace) secretsv1beta1.ConfigMapCopierRuleStatus {
+	log := log.FromContext(ctx)
+
+	ruleStatus := secretsv1beta1.ConfigMapCopierRuleStatus{
+		SourceConfigMap: rule.SourceConfigMap,
+	}
+
+	if !rule.IsEnabled() {
+		ruleStatus.Disabled = true
+		return ruleStatus
+	}
+
+	matcher := rule.TargetNamespaces.Compile()
+
+	targetNamespaces := make([]*corev1.Namespace, 0)
+
+	for i := range activeNamespaces {
+		namespace := &activeNamespaces[i]
+
+		if namespace.Name == rule.SourceConfigMap.Namespace || rule.SkipsNamespace(namespace) {
+			continue
+		}
+
+		if matcher.Matches(namespace) {
+			targetNamespaces = append(targetNamespaces, namespace)
+		}
+	}
+
+	ruleStatus.MatchedNamespaces = int32(len(targetNamespaces))
+
+	var sourceConfigMap corev1.ConfigMap
+
+	if err := r.Get(ctx, client.ObjectKey{Namespace: rule.SourceConfigMap.Namespace, Name: rule.SourceConfigMap.Name}, &sourceConfigMap); err != nil {
+		if apierrors.IsNotFound(err) {
+			ruleStatus.Message = fmt.Sprintf("Source config map %s/%s does not exist", rule.SourceConfigMap.Namespace, rule.SourceConfigMap.Name)
+		} else {
+			ruleStatus.Message = fmt.Sprintf("Unable to read source config map %s/%s: %v", rule.SourceConfigMap.Namespace, rule.SourceConfigMap.Name, err)
+		}
+
+		return ruleStatus
+	}
+
+	if len(targetNamespaces) == 0 {
+		ruleStatus.Message = noTargetConfigMapNamespacesMessage
+		return ruleStatus
+	}
+
+	for _, namespace := range targetNamespaces {
+		result, err := r.copyConfigMapToNamespace(ctx, configMapCopier, rule, &sourceConfigMap, namespace)
+
+		switch result {
+		case copyCreated, copyUpdated, copyUnchanged:
+			ruleStatus.SyncedNamespaces++
+		case copyFailed:
+			log.Error(err, "Unable to copy ConfigMap to target namespace", "name", configMapCopier.Name, "configMap", rule.SourceConfigMap, "namespace", namespace.Name)
+
+			r.Recorder.Eventf(configMapCopier, corev1.EventTypeWarning, "CopyFailed",
+				"Unable to copy config map %s/%s to namespace %s: %v", rule.SourceConfigMap.Namespace, rule.SourceConfigMap.Name, namespace.Name, err)
+
+			ruleStatus.FailedNamespaces++
+		}
+	}
+
+	return ruleStatus
+}
+
+// Copy the source config map to the target namespace. An existing config map
+// in the target namespace is only updated if it was created by the same
+// ConfigMapCopier from the same source config map.
+func (r *ConfigMapCopierReconciler) copyConfigMapToNamespace(ctx context.Context, configMapCopier *secretsv1beta1.ConfigMapCopier, rule *secretsv1beta1.ConfigMapCopierRule, sourceConfigMap *corev1.ConfigMap, namespace *corev1.Namespace) (copyResult, error) {
+	log := log.FromContext(ctx)
+
+	desired := newTargetConfigMap(configMapCopier, rule, sourceConfigMap, namespace)
+
+	var targetConfigMap corev1.ConfigMap
+
+	if err := r.Get(ctx, client.ObjectKeyFromObject(&desired), &targetConfigMap); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return copyFailed, err
+		}
+
+		if r.ReportOnly {
+			log.V(1).Info("Report-only mode, not creating target ConfigMap", "name", desired.Name, "namespace", desired.Namespace)
+			return copyReportOnly, nil
+		}
+
+		if err := r.Create(ctx, &desired); err != nil {
+			return copyFailed, err
+		}
+
+		log.V(1).Info("Created target ConfigMap", "name", desired.Name, "namespace", desired.Namespace)
+
+		return copyCreated, nil
+	}
+
+	if !targetConfigMapManagedByConfigMapCopier(configMapCopier, rule, &targetConfigMap) {
+		log.V(1).Info("Target ConfigMap not managed by ConfigMapCopier, skipping", "name", targetConfigMap.Name, "namespace", targetConfigMap.Namespace)
+		return copySkipped, nil
+	}
+
+	if equality.Semantic.DeepEqual(targetConfigMap.Data, desired.Data) &&
+		equality.Semantic.DeepEqual(targetConfigMap.BinaryData, desired.BinaryData) &&
+		maps.Equal(targetConfigMap.Labels, desired.Labels) &&
+		equality.Semantic.DeepEqual(targetConfigMap.OwnerReferences, desired.OwnerReferences) {
+		return copyUnchanged, nil
+	}
+
+	if r.ReportOnly {
+		log.V(1).Info("Report-only mode, not updating target ConfigMap", "name", targetConfigMap.Name, "namespace", targetConfigMap.Namespace)
+		return copyReportOnly, nil
+	}
+
+	targetConfigMap.Data = desired.Data
+	targetConfigMap.BinaryData = desired.BinaryData
+	targetConfigMap.Labels = desired.Labels
+	targetConfigMap.OwnerReferences = desired.OwnerReferences
+
+	if err := r.Update(ctx, &targetConfigMap); err != nil {
+		return copyFailed, err
+	}
+
+	log.V(1).Info("Updated target ConfigMap", "name", targetConfigMap.Name, "namespace", targetConfigMap.Namespace)
+
+	return copyUpdated, nil
+}
+
+// Construct the target config map for a rule. The ConfigMapCopier is made the
+// owner of the target config map if it is to be deleted with it.
+func newTargetConfigMap(configMapCopier *secretsv1beta1.ConfigMapCopier, rule *secretsv1beta1.ConfigMapCopierRule, sourceConfigMap *corev1.ConfigMap, namespace *corev1.Namespace) corev1.ConfigMap {
+	name := rule.TargetConfigMap.Name
+
+	if name == "" {
+		name = rule.SourceConfigMap.Name
+	}
+
+	labels := make(map[string]string)
+
+	maps.Copy(labels, sourceConfigMap.Labels)
+	maps.Copy(labels, rule.TargetConfigMap.Labels)
+
+	var ownerReferences []metav1.OwnerReference
+
+	if rule.ReclaimPolicyFor(namespace) == secretsv1beta1.ReclaimDelete {
+		ownerReferences = append(ownerReferences, metav1.OwnerReference{
+			APIVersion:         secretsv1beta1.GroupVersion.String(),
+			Kind:               "ConfigMapCopier",
+			Name:               configMapCopier.Name,
+			UID:                configMapCopier.UID,
+			Controller:         ptr.To(true),
+			BlockOwnerDeletion: ptr.To(true),
+		})
+	}
+
+	return corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace.Name,
+			Labels:    labels,
+			Annotations: map[string]string{
+				configMapCopierMarkerAnnotation: configMapCopier.Name,
+				sourceConfigMapMarkerAnnotation: rule.SourceConfigMap.Namespace + "/" + rule.SourceConfigMap.Name,
+			},
+			OwnerReferences: ownerReferences,
+		},
+		Data:       sourceConfigMap.Data,
+		BinaryData: sourceConfigMap.BinaryData,
+	}
+}
+
+// Verify that an existing target config map was created from the source
+// config map by the same ConfigMapCopier, by checking its annotations.
+func targetConfigMapManagedByConfigMapCopier(configMapCopier *secretsv1beta1.ConfigMapCopier, rule *secretsv1beta1.ConfigMapCopierRule, targetConfigMap *corev1.ConfigMap) bool {
+	if targetConfigMap.Annotations[configMapCopierMarkerAnnotation] != configMapCopier.Name {
+		return false
+	}
+
+	return targetConfigMap.Annotations[sourceConfigMapMarkerAnnotation] == rule.SourceConfigMap.Namespace+"/"+rule.SourceConfigMap.Name
+}
+
+// Calculate the Ready condition of a ConfigMapCopier from the status of its
+// rules. The ConfigMapCopier is ready when every enabled rule has copied its
+// source config map to all of its target namespaces.
+func configMapCopierReadyCondition(ruleStatuses []secretsv1beta1.ConfigMapCopierRuleStatus, generation int64) metav1.Condition {
+	notSynced := make([]string, 0)
+
+	for ruleIndex, ruleStatus := range ruleStatuses {
+		if ruleStatus.Disabled {
+			continue
+		}
+
+		if ruleStatus.SyncedNamespaces != ruleStatus.MatchedNamespaces || ruleStatus.Message != "" && ruleStatus.Message != noTargetConfigMapNamespacesMessage {
+			notSynced = append(notSynced, fmt.Sprintf("rule %d", ruleIndex))
+		}
+	}
+
+	if len(notSynced) != 0 {
+		return metav1.Condition{
+			Type:               secretsv1beta1.ConditionReady,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: generation,
+			Reason:             "RulesNotSynced",
+			Message:            "Rules not in sync: " + strings.Join(notSynced, ", "),
+		}
+	}
+
+	return metav1.Condition{
+		Type:               secretsv1beta1.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "Synced",
+		Message:            "Target config maps of all rules are in sync",
+	}
+}
+
+// Update the status of the ConfigMapCopier if it has changed.
+func (r *ConfigMapCopierReconciler) updateStatus(ctx context.Context, configMapCopier *secretsv1beta1.ConfigMapCopier) error {
+	log := log.FromContext(ctx)
+
+	var current secretsv1beta1.ConfigMapCopier
+
+	if err := r.Get(ctx, client.ObjectKeyFromObject(configMapCopier), &current); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	if equality.Semantic.DeepEqual(current.Status, configMapCopier.Status) {
+		return nil
+	}
+
+	if err := r.Status().Update(ctx, configMapCopier); err != nil {
+		log.Error(err, "Unable to update ConfigMapCopier status", "name", configMapCopier.Name)
+		return err
+	}
+
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ConfigMapCopierReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&secretsv1beta1.ConfigMapCopier{}).
+		Named("configmapcopier").
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.findConfigMapCopiersMatchingSourceConfigMap)).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.findConfigMapCopiersMatchingTargetNamespace)).
+		Complete(r)
+}
+
+// Handler function to find ConfigMapCopier objects which copy a config map,
+// so the config map is copied again when it changes.
+func (r *ConfigMapCopierReconciler) findConfigMapCopiersMatchingSourceConfigMap(ctx context.Context, configMap client.Object) []reconcile.Request {
+	return r.findConfigMapCopiers(ctx, func(rule *secretsv1beta1.ConfigMapCopierRule) bool {
+		return rule.SourceConfigMap.Name == configMap.GetName() && rule.SourceConfigMap.Namespace == configMap.GetNamespace()
+	})
+}
+
+// Handler function to find ConfigMapCopier objects with a rule matching a
+// namespace, so config maps are copied to the namespace when it is created
+// or its labels change.
+func (r *ConfigMapCopierReconciler) findConfigMapCopiersMatchingTargetNamespace(ctx context.Context, object client.Object) []reconcile.Request {
+	namespace, ok := object.(*corev1.Namespace)
+
+	if !ok {
+		return nil
+	}
+
+	return r.findConfigMapCopiers(ctx, func(rule *secretsv1beta1.ConfigMapCopierRule) bool {
+		return rule.SourceConfigMap.Namespace != namespace.Name && rule.TargetNamespaces.Matches(namespace)
+	})
+}
+
+// Return requests for the ConfigMapCopier objects with any rule for which the
+// function returns true.
+func (r *ConfigMapCopierReconciler) findConfigMapCopiers(ctx context.Context, matches func(rule *secretsv1beta1.ConfigMapCopierRule) bool) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	var configMapCopiers secretsv1beta1.ConfigMapCopierList
+
+	if err := r.List(ctx, &configMapCopiers); err != nil {
+		log.Error(err, "Unable to list ConfigMapCopier objects")
+		return nil
+	}
+
+	var requests []reconcile.Request
+
+	for i := range configMapCopiers.Items {
+		configMapCopier := &configMapCopiers.Items[i]
+
+		for ruleIndex := range configMapCopier.Spec.Rules {
+			if matches(&configMapCopier.Spec.Rules[ruleIndex]) {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(configMapCopier)})
+				break
+			}
+		}
+	}
+
+	return requests
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+var _ = Describe("ConfigMapCopier Controller", func() {
+	ctx := context.Background()
+
+	var fakeClient client.Client
+	var reconciler *ConfigMapCopierReconciler
+
+	newConfigMapCopier := func(rule secretsv1beta1.ConfigMapCopierRule) *secretsv1beta1.ConfigMapCopier {
+		return &secretsv1beta1.ConfigMapCopier{
+			ObjectMeta: metav1.ObjectMeta{Name: "ca-bundle", UID: "uid-1"},
+			Spec: secretsv1beta1.ConfigMapCopierSpec{
+				Rules: []secretsv1beta1.ConfigMapCopierRule{rule},
+			},
+		}
+	}
+
+	reconcileConfigMapCopier := func(configMapCopier *secretsv1beta1.ConfigMapCopier) *secretsv1beta1.ConfigMapCopier {
+		Expect(fakeClient.Create(ctx, configMapCopier)).To(Succeed())
+
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(configMapCopier)})
+		Expect(err).NotTo(HaveOccurred())
+
+		var updated secretsv1beta1.ConfigMapCopier
+		Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(configMapCopier), &updated)).To(Succeed())
+
+		return &updated
+	}
+
+	BeforeEach(func() {
+		fakeClient = fake.NewClientBuilder().
+			WithStatusSubresource(&secretsv1beta1.ConfigMapCopier{}).
+			WithObjects(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "source"}},
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"tenant": "true"}}},
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b", Labels: map[string]string{"tenant": "true"}}},
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "ca-bundle", Namespace: "source", Labels: map[string]string{"app": "ca"}},
+					Data:       map[string]string{"ca.crt": "certificate"},
+				},
+			).
+			Build()
+
+		reconciler = &ConfigMapCopierReconciler{
+			Client:   fakeClient,
+			Recorder: record.NewFakeRecorder(10),
+		}
+	})
+
+	It("should copy the config map to matching target namespaces", func() {
+		configMapCopier := reconcileConfigMapCopier(newConfigMapCopier(secretsv1beta1.ConfigMapCopierRule{
+			SourceConfigMap: secretsv1beta1.SourceConfigMap{Name: "ca-bundle", Namespace: "source"},
+			TargetNamespaces: selectors.TargetNamespaces{
+				LabelSelector: selectors.LabelSelector{MatchLabels: map[string]string{"tenant": "true"}},
+			},
+			TargetConfigMap: secretsv1beta1.TargetConfigMap{Labels: map[string]string{"copied": "true"}},
+			ReclaimPolicy:   secretsv1beta1.ReclaimDelete,
+		}))
+
+		for _, namespace := range []string{"team-a", "team-b"} {
+			var target corev1.ConfigMap
+			Expect(fakeClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "ca-bundle"}, &target)).To(Succeed())
+
+			Expect(target.Data).To(Equal(map[string]string{"ca.crt": "certificate"}))
+			Expect(target.Labels).To(Equal(map[string]string{"app": "ca", "copied": "true"}))
+			Expect(target.Annotations).To(HaveKeyWithValue(configMapCopierMarkerAnnotation, "ca-bundle"))
+			Expect(target.OwnerReferences).To(HaveLen(1))
+			Expect(target.OwnerReferences[0].Kind).To(Equal("ConfigMapCopier"))
+		}
+
+		var other corev1.ConfigMap
+		Expect(fakeClient.Get(ctx, client.ObjectKey{Namespace: "other", Name: "ca-bundle"}, &other)).NotTo(Succeed())
+
+		Expect(configMapCopier.Status.Rules).To(HaveLen(1))
+		Expect(configMapCopier.Status.Rules[0].MatchedNamespaces).To(Equal(int32(2)))
+		Expect(configMapCopier.Status.Rules[0].SyncedNamespaces).To(Equal(int32(2)))
+		Expect(meta.IsStatusConditionTrue(configMapCopier.Status.Conditions, secretsv1beta1.ConditionReady)).To(BeTrue())
+	})
+
+	It("should apply reclaim policy overrides", func() {
+		reconcileConfigMapCopier(newConfigMapCopier(secretsv1beta1.ConfigMapCopierRule{
+			SourceConfigMap: secretsv1beta1.SourceConfigMap{Name: "ca-bundle", Namespace: "source"},
+			TargetNamespaces: selectors.TargetNamespaces{
+				NameSelector: selectors.NameSelector{MatchNames: []string{"team-*"}},
+			},
+			ReclaimPolicy: secretsv1beta1.ReclaimDelete,
+			ReclaimPolicyOverrides: []secretsv1beta1.ReclaimPolicyOverride{
+				{
+					TargetNamespaces: selectors.TargetNamespaces{NameSelector: selectors.NameSelector{MatchNames: []string{"team-b"}}},
+					ReclaimPolicy:    secretsv1beta1.ReclaimRetain,
+				},
+			},
+		}))
+
+		var retained corev1.ConfigMap
+		Expect(fakeClient.Get(ctx, client.ObjectKey{Namespace: "team-b", Name: "ca-bundle"}, &retained)).To(Succeed())
+		Expect(retained.OwnerReferences).To(BeEmpty())
+
+		var deleted corev1.ConfigMap
+		Expect(fakeClient.Get(ctx, client.ObjectKey{Namespace: "team-a", Name: "ca-bundle"}, &deleted)).To(Succeed())
+		Expect(deleted.OwnerReferences).To(HaveLen(1))
+	})
+
+	It("should update target config maps when the source changes", func() {
+		configMapCopier := reconcileConfigMapCopier(newConfigMapCopier(secretsv1beta1.ConfigMapCopierRule{
+			SourceConfigMap: secretsv1beta1.SourceConfigMap{Name: "ca-bundle", Namespace: "source"},
+			TargetNamespaces: selectors.TargetNamespaces{
+				NameSelector: selectors.NameSelector{MatchNames: []string{"team-a"}},
+			},
+		}))
+
+		var source corev1.ConfigMap
+		Expect(fakeClient.Get(ctx, client.ObjectKey{Namespace: "source", Name: "ca-bundle"}, &source)).To(Succeed())
+
+		source.Data["ca.crt"] = "rotated"
+		Expect(fakeClient.Update(ctx, &source)).To(Succeed())
+
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(configMapCopier)})
+		Expect(err).NotTo(HaveOccurred())
+
+		var target corev1.ConfigMap
+		Expect(fakeClient.Get(ctx, client.ObjectKey{Namespace: "team-a", Name: "ca-bundle"}, &target)).To(Succeed())
+		Expect(target.Data).To(HaveKeyWithValue("ca.crt", "rotated"))
+	})
+
+	It("should not overwrite config maps it doesn't manage", func() {
+		Expect(fakeClient.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "ca-bundle", Namespace: "team-a"},
+			Data:       map[string]string{"ca.crt": "local"},
+		})).To(Succeed())
+
+		configMapCopier := reconcileConfigMapCopier(newConfigMapCopier(secretsv1beta1.ConfigMapCopierRule{
+			SourceConfigMap: secretsv1beta1.SourceConfigMap{Name: "ca-bundle", Namespace: "source"},
+			TargetNamespaces: selectors.TargetNamespaces{
+				NameSelector: selectors.NameSelector{MatchNames: []string{"team-a"}},
+			},
+		}))
+
+		var target corev1.ConfigMap
+		Expect(fakeClient.Get(ctx, client.ObjectKey{Namespace: "team-a", Name: "ca-bundle"}, &target)).To(Succeed())
+		Expect(target.Data).To(HaveKeyWithValue("ca.crt", "local"))
+
+		Expect(configMapCopier.Status.Rules[0].SyncedNamespaces).To(Equal(int32(0)))
+		Expect(meta.IsStatusConditionTrue(configMapCopier.Status.Conditions, secretsv1beta1.ConditionReady)).To(BeFalse())
+	})
+
+	It("should report a missing source config map", func() {
+		configMapCopier := reconcileConfigMapCopier(newConfigMapCopier(secretsv1beta1.ConfigMapCopierRule{
+			SourceConfigMap: secretsv1beta1.SourceConfigMap{Name: "missing", Namespace: "source"},
+			TargetNamespaces: selectors.TargetNamespaces{
+				NameSelector: selectors.NameSelector{MatchNames: []string{"team-a"}},
+			},
+		}))
+
+		Expect(configMapCopier.Status.Rules[0].Message).To(ContainSubstring("does not exist"))
+		Expect(meta.IsStatusConditionTrue(configMapCopier.Status.Conditions, secretsv1beta1.ConditionReady)).To(BeFalse())
+	})
+})
@@ -0,0 +1,143 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+var _ = Describe("Namespace Consent", func() {
+	ctx := context.Background()
+
+	newNamespace := func(name string, acceptCopies string) *corev1.Namespace {
+		namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+
+		if acceptCopies != "" {
+			namespace.Annotations = map[string]string{secretsv1beta1.NamespaceAcceptCopiesAnnotation: acceptCopies}
+		}
+
+		return namespace
+	}
+
+	It("should only require consent when the manager or rule asks for it", func() {
+		tests := []struct {
+			name         string
+			managerWide  bool
+			ruleRequired bool
+			acceptCopies string
+			unconsented  bool
+		}{
+			{name: "not required", acceptCopies: "", unconsented: false},
+			{name: "required by rule", ruleRequired: true, acceptCopies: "", unconsented: true},
+			{name: "required by manager", managerWide: true, acceptCopies: "", unconsented: true},
+			{name: "consent given", managerWide: true, acceptCopies: "true", unconsented: false},
+			{name: "consent refused", ruleRequired: true, acceptCopies: "false", unconsented: true},
+		}
+
+		for _, test := range tests {
+			config := NewManagerConfig(ManagerSettings{})
+
+			config.apply(config.merge(&secretsv1beta1.SecretsManagerConfigSpec{
+				RequireNamespaceConsent: ptr.To(test.managerWide),
+			}))
+
+			rule := &secretsv1beta1.SecretCopierRule{RequireNamespaceConsent: test.ruleRequired}
+
+			Expect(config.Settings().NamespaceUnconsented(rule, newNamespace("tenant-a", test.acceptCopies))).To(Equal(test.unconsented), test.name)
+		}
+	})
+
+	It("should only copy to namespaces which have consented", func() {
+		secretCopier := &secretsv1beta1.SecretCopier{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry-copier", Generation: 1},
+			Spec: secretsv1beta1.SecretCopierSpec{
+				Rules: []secretsv1beta1.SecretCopierRule{
+					{
+						SourceSecret: secretsv1beta1.SourceSecret{Name: "registry", Namespace: "registries"},
+						TargetNamespaces: selectors.TargetNamespaces{
+							NameSelector: selectors.NameSelector{MatchNames: []string{"tenant-*"}},
+						},
+						RequireNamespaceConsent: true,
+					},
+				},
+			},
+		}
+
+		k8sClient := fake.NewClientBuilder().WithObjects(
+			secretCopier,
+			newNamespace("registries", ""),
+			newNamespace("tenant-a", "true"),
+			newNamespace("tenant-b", ""),
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "registries"},
+				Data:       map[string][]byte{"token": []byte("registry-token")},
+			},
+		).WithStatusSubresource(secretCopier).Build()
+
+		reconciler := &SecretCopierReconciler{Client: k8sClient, Recorder: record.NewFakeRecorder(100)}
+
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "registry-copier"}})
+
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "tenant-a", Name: "registry"}, &corev1.Secret{})).To(Succeed())
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "tenant-b", Name: "registry"}, &corev1.Secret{})).NotTo(Succeed())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(secretCopier), secretCopier)).To(Succeed())
+
+		Expect(secretCopier.Status.Rules).To(HaveLen(1))
+		Expect(secretCopier.Status.Rules[0].MatchedNamespaces).To(Equal(int32(1)))
+		Expect(secretCopier.Status.Rules[0].UnconsentedNamespaces).To(Equal(int32(1)))
+	})
+
+	It("should explain when no matched namespace has consented", func() {
+		reconciler := &SecretCopierReconciler{}
+
+		rule := &secretsv1beta1.SecretCopierRule{
+			SourceSecret: secretsv1beta1.SourceSecret{Namespace: "registries", Name: "registry"},
+		}
+
+		message := reconciler.explainNoTargetNamespaces(rule, rule.TargetNamespaces.Compile(), nil, 0, 0, 0, 3)
+
+		Expect(message).To(Equal(`All 3 matched namespaces have not consented to copies by setting annotation secrets-manager.advok8s.io/accept-copies to "true"`))
+	})
+
+	It("should reconcile when a namespace gives or withdraws consent", func() {
+		reconciler := &SecretCopierReconciler{Client: fake.NewClientBuilder().Build()}
+
+		predicate := reconciler.namespaceSelectorFieldsChanged()
+
+		Expect(predicate.Update(event.UpdateEvent{ObjectOld: newNamespace("tenant-a", ""), ObjectNew: newNamespace("tenant-a", "true")})).To(BeTrue())
+		Expect(predicate.Update(event.UpdateEvent{ObjectOld: newNamespace("tenant-a", "true"), ObjectNew: newNamespace("tenant-a", "false")})).To(BeTrue())
+		Expect(predicate.Update(event.UpdateEvent{ObjectOld: newNamespace("tenant-a", "false"), ObjectNew: newNamespace("tenant-a", "")})).To(BeFalse())
+	})
+})
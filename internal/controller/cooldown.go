@@ -0,0 +1,117 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Revision of the source secret of a rule last propagated to target
+// namespaces, and when the rule started propagating it.
+type updateCooldown struct {
+	revision   string
+	propagated time.Time
+
+	// Revision last held back, so each revision held back is only counted
+	// once in the metrics however many reconciliations it is held for.
+	suppressed string
+}
+
+// Cooldown windows of rules which limit how often changes to their source
+// secret are propagated. The zero value is ready to use.
+type updateCooldowns struct {
+	mutex   sync.Mutex
+	entries map[string]*updateCooldown
+}
+
+// Key under which the cooldown of a rule is tracked. The source secret is
+// included so that changing the source secret of a rule isn't held back.
+func updateCooldownKey(secretCopier *secretsv1beta1.SecretCopier, ruleIndex int, rule *secretsv1beta1.SecretCopierRule) string {
+	return secretCopier.Name + "/" + ruleSourceKey(ruleIndex, rule)
+}
+
+// Determine whether a change to the source secret of a rule must be held back
+// because the previous change was propagated less than the minimum interval
+// of the rule ago. Returns how long until the change can be propagated, or
+// zero if it can be propagated now. The first revision seen is never held
+// back, as there is no earlier propagation to measure from.
+func (r *SecretCopierReconciler) ruleCooldown(secretCopier *secretsv1beta1.SecretCopier, ruleIndex int, rule *secretsv1beta1.SecretCopierRule, sourceSecret *corev1.Secret, now time.Time) time.Duration {
+	if rule.MinInterval == nil || rule.MinInterval.Duration <= 0 || sourceSecret.Name == "" {
+		return 0
+	}
+
+	revision := sourceSecretRevision(filterSourceSecretKeys(rule, sourceSecret))
+
+	key := updateCooldownKey(secretCopier, ruleIndex, rule)
+
+	c := &r.updateCooldowns
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]*updateCooldown)
+	}
+
+	entry, found := c.entries[key]
+
+	if !found {
+		c.entries[key] = &updateCooldown{revision: revision, propagated: now}
+		return 0
+	}
+
+	if entry.revision == revision {
+		return 0
+	}
+
+	remaining := entry.propagated.Add(rule.MinInterval.Duration).Sub(now)
+
+	if remaining <= 0 {
+		entry.revision = revision
+		entry.propagated = now
+		entry.suppressed = ""
+
+		return 0
+	}
+
+	if entry.suppressed != revision {
+		entry.suppressed = revision
+
+		suppressedUpdatesTotal.WithLabelValues(secretCopier.Name, strconv.Itoa(ruleIndex)).Inc()
+	}
+
+	return remaining
+}
+
+// Discard the cooldowns of all rules of a SecretCopier.
+func (c *updateCooldowns) forget(secretCopierName string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for key := range c.entries {
+		if strings.HasPrefix(key, secretCopierName+"/") {
+			delete(c.entries, key)
+		}
+	}
+}
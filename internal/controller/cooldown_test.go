@@ -0,0 +1,106 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Rule Update Cooldown", func() {
+	newSourceSecret := func(value string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "token", Namespace: "source"},
+			Data:       map[string][]byte{"token": []byte(value)},
+		}
+	}
+
+	rule := &secretsv1beta1.SecretCopierRule{
+		SourceSecret: secretsv1beta1.SourceSecret{Name: "token", Namespace: "source"},
+		MinInterval:  &metav1.Duration{Duration: time.Minute},
+	}
+
+	It("should hold back changes made within the minimum interval", func() {
+		secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "cooldown-copier-a"}}
+
+		reconciler := &SecretCopierReconciler{}
+
+		start := time.Now()
+
+		suppressed := suppressedUpdatesTotal.WithLabelValues("cooldown-copier-a", "0")
+
+		// The first revision seen is propagated immediately.
+
+		Expect(reconciler.ruleCooldown(secretCopier, 0, rule, newSourceSecret("one"), start)).To(BeZero())
+
+		// A change inside the interval is held back until it has elapsed,
+		// and only counted once however often it is seen.
+
+		Expect(reconciler.ruleCooldown(secretCopier, 0, rule, newSourceSecret("two"), start.Add(10*time.Second))).To(Equal(50 * time.Second))
+		Expect(reconciler.ruleCooldown(secretCopier, 0, rule, newSourceSecret("two"), start.Add(20*time.Second))).To(Equal(40 * time.Second))
+
+		Expect(testutil.ToFloat64(suppressed)).To(Equal(1.0))
+
+		Expect(reconciler.ruleCooldown(secretCopier, 0, rule, newSourceSecret("three"), start.Add(30*time.Second))).To(Equal(30 * time.Second))
+
+		Expect(testutil.ToFloat64(suppressed)).To(Equal(2.0))
+
+		// Once the interval has elapsed the latest change is propagated and
+		// starts a new interval.
+
+		Expect(reconciler.ruleCooldown(secretCopier, 0, rule, newSourceSecret("three"), start.Add(time.Minute))).To(BeZero())
+		Expect(reconciler.ruleCooldown(secretCopier, 0, rule, newSourceSecret("three"), start.Add(70*time.Second))).To(BeZero())
+		Expect(reconciler.ruleCooldown(secretCopier, 0, rule, newSourceSecret("four"), start.Add(70*time.Second))).To(Equal(50 * time.Second))
+	})
+
+	It("should not hold back rules without a minimum interval", func() {
+		secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "cooldown-copier-b"}}
+
+		reconciler := &SecretCopierReconciler{}
+
+		unlimited := rule.DeepCopy()
+		unlimited.MinInterval = nil
+
+		now := time.Now()
+
+		Expect(reconciler.ruleCooldown(secretCopier, 0, unlimited, newSourceSecret("one"), now)).To(BeZero())
+		Expect(reconciler.ruleCooldown(secretCopier, 0, unlimited, newSourceSecret("two"), now)).To(BeZero())
+	})
+
+	It("should start again once the SecretCopier is forgotten", func() {
+		secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "cooldown-copier-c"}}
+
+		reconciler := &SecretCopierReconciler{}
+
+		now := time.Now()
+
+		Expect(reconciler.ruleCooldown(secretCopier, 0, rule, newSourceSecret("one"), now)).To(BeZero())
+		Expect(reconciler.ruleCooldown(secretCopier, 0, rule, newSourceSecret("two"), now)).NotTo(BeZero())
+
+		reconciler.updateCooldowns.forget("cooldown-copier-c")
+
+		Expect(reconciler.ruleCooldown(secretCopier, 0, rule, newSourceSecret("two"), now)).To(BeZero())
+	})
+})
@@ -0,0 +1,218 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Time spent reconciling a SecretCopier which is charged against its queue
+// budget as one further reconciliation, so a SecretCopier whose
+// reconciliations are slow is reconciled less often.
+const copierQueueWorkUnit = time.Second
+
+// Per SecretCopier rate limiting of the reconcile queue. Each SecretCopier
+// gets its own token bucket, so a SecretCopier whose rules fan out to many
+// target namespaces or keep failing uses up its own budget rather than delaying
+// the reconciliation of every other SecretCopier sharing the controller.
+type copierQueues struct {
+	mutex sync.Mutex
+
+	// Rate and burst of the token bucket of each SecretCopier.
+	limit rate.Limit
+	burst int
+
+	// Token bucket of each SecretCopier.
+	limiters map[string]*rate.Limiter
+
+	// Time at which each SecretCopier delayed by its budget will be added to
+	// the queue.
+	scheduled map[string]time.Time
+
+	// Time at which the reconciliation of each SecretCopier being processed
+	// was started.
+	started map[string]time.Time
+}
+
+// Create per SecretCopier rate limiting with the given rate and burst.
+func newCopierQueues(limit rate.Limit, burst int) *copierQueues {
+	return &copierQueues{
+		limit:     limit,
+		burst:     max(burst, 1),
+		limiters:  make(map[string]*rate.Limiter),
+		scheduled: make(map[string]time.Time),
+		started:   make(map[string]time.Time),
+	}
+}
+
+// Return the token bucket of a SecretCopier. The mutex must be held.
+func (q *copierQueues) limiter(name string) *rate.Limiter {
+	limiter, ok := q.limiters[name]
+
+	if !ok {
+		limiter = rate.NewLimiter(q.limit, q.burst)
+		q.limiters[name] = limiter
+	}
+
+	return limiter
+}
+
+// Determine the delay before a SecretCopier can be added to the queue, which
+// is at least the requested delay. Requests which are to be added immediately
+// or are retries are charged against the budget of the SecretCopier, while
+// requests for a later reconciliation are not. If the SecretCopier is already
+// waiting to be added because it had used up its budget, false is returned
+// for charged requests and for requests which would be added no earlier, as
+// it doesn't need to be added again.
+func (q *copierQueues) admit(name string, delay time.Duration, charge bool, now time.Time) (time.Duration, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if at, ok := q.scheduled[name]; ok && now.Before(at) {
+		if charge || !at.After(now.Add(delay)) {
+			return 0, false
+		}
+
+		return delay, true
+	}
+
+	delete(q.scheduled, name)
+
+	if !charge {
+		return delay, true
+	}
+
+	budgetDelay := q.limiter(name).ReserveN(now, 1).DelayFrom(now)
+
+	if budgetDelay > delay {
+		q.scheduled[name] = now.Add(budgetDelay)
+
+		return budgetDelay, true
+	}
+
+	return delay, true
+}
+
+// Record that the reconciliation of a SecretCopier has started.
+func (q *copierQueues) start(name string, now time.Time) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.started[name] = now
+}
+
+// Record that the reconciliation of a SecretCopier has finished, charging the
+// time it took against the budget of the SecretCopier.
+func (q *copierQueues) finish(name string, now time.Time) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	started, ok := q.started[name]
+
+	if !ok {
+		return
+	}
+
+	delete(q.started, name)
+
+	units := min(int(now.Sub(started)/copierQueueWorkUnit), q.burst)
+
+	if units > 0 {
+		q.limiter(name).ReserveN(now, units)
+	}
+}
+
+// Discard the budget of a deleted SecretCopier.
+func (q *copierQueues) forget(name string) {
+	if q == nil {
+		return
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	delete(q.limiters, name)
+	delete(q.scheduled, name)
+}
+
+// Wrap the reconcile queue of the controller so that requests are admitted
+// against the budget of the SecretCopier they are for.
+func (q *copierQueues) wrap(queue workqueue.TypedRateLimitingInterface[reconcile.Request], rateLimiter workqueue.TypedRateLimiter[reconcile.Request]) workqueue.TypedRateLimitingInterface[reconcile.Request] {
+	return &isolatedCopierQueue{
+		TypedRateLimitingInterface: queue,
+		copierQueues:               q,
+		rateLimiter:                rateLimiter,
+	}
+}
+
+// Reconcile queue admitting requests against the budget of the SecretCopier
+// they are for.
+type isolatedCopierQueue struct {
+	workqueue.TypedRateLimitingInterface[reconcile.Request]
+
+	copierQueues *copierQueues
+	rateLimiter  workqueue.TypedRateLimiter[reconcile.Request]
+}
+
+func (q *isolatedCopierQueue) Add(request reconcile.Request) {
+	q.add(request, 0, true)
+}
+
+func (q *isolatedCopierQueue) AddAfter(request reconcile.Request, duration time.Duration) {
+	q.add(request, duration, false)
+}
+
+func (q *isolatedCopierQueue) AddRateLimited(request reconcile.Request) {
+	q.add(request, q.rateLimiter.When(request), true)
+}
+
+// Add the request to the queue once it has been admitted against the budget
+// of the SecretCopier.
+func (q *isolatedCopierQueue) add(request reconcile.Request, duration time.Duration, charge bool) {
+	delay, ok := q.copierQueues.admit(request.Name, duration, charge, time.Now())
+
+	if !ok {
+		return
+	}
+
+	if delay > 0 {
+		q.TypedRateLimitingInterface.AddAfter(request, delay)
+	} else {
+		q.TypedRateLimitingInterface.Add(request)
+	}
+}
+
+func (q *isolatedCopierQueue) Get() (reconcile.Request, bool) {
+	request, shutdown := q.TypedRateLimitingInterface.Get()
+
+	if !shutdown {
+		q.copierQueues.start(request.Name, time.Now())
+	}
+
+	return request, shutdown
+}
+
+func (q *isolatedCopierQueue) Done(request reconcile.Request) {
+	q.copierQueues.finish(request.Name, time.Now())
+
+	q.TypedRateLimitingInterface.Done(request)
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Copier Queue Isolation", func() {
+	admitted := func(queues *copierQueues, name string, delay time.Duration, charge bool, now time.Time) time.Duration {
+		delay, ok := queues.admit(name, delay, charge, now)
+		Expect(ok).To(BeTrue())
+
+		return delay
+	}
+
+	It("should delay requests for a SecretCopier once it has used its budget", func() {
+		queues := newCopierQueues(1, 2)
+
+		now := time.Now()
+
+		// The burst is admitted immediately.
+
+		Expect(admitted(queues, "busy-copier", 0, true, now)).To(Equal(time.Duration(0)))
+		Expect(admitted(queues, "busy-copier", 0, true, now)).To(Equal(time.Duration(0)))
+
+		// Further requests are delayed until there is budget again, and while
+		// waiting further requests aren't added again.
+
+		Expect(admitted(queues, "busy-copier", 0, true, now)).To(BeNumerically("~", time.Second, 10*time.Millisecond))
+
+		_, ok := queues.admit("busy-copier", 0, true, now)
+		Expect(ok).To(BeFalse())
+
+		// Other SecretCopier objects aren't affected.
+
+		Expect(admitted(queues, "quiet-copier", 0, true, now)).To(Equal(time.Duration(0)))
+	})
+
+	It("should not charge requests for a later reconciliation", func() {
+		queues := newCopierQueues(1, 1)
+
+		now := time.Now()
+
+		for range 3 {
+			Expect(admitted(queues, "periodic-copier", time.Minute, false, now)).To(Equal(time.Minute))
+		}
+
+		Expect(admitted(queues, "periodic-copier", 0, true, now)).To(Equal(time.Duration(0)))
+	})
+
+	It("should charge the time spent reconciling against the budget", func() {
+		queues := newCopierQueues(1, 5)
+
+		now := time.Now()
+
+		queues.start("slow-copier", now)
+		queues.finish("slow-copier", now.Add(5*time.Second))
+
+		Expect(admitted(queues, "slow-copier", 0, true, now.Add(5*time.Second))).To(BeNumerically(">", 0))
+
+		queues.forget("slow-copier")
+
+		Expect(admitted(queues, "slow-copier", 0, true, now.Add(5*time.Second))).To(Equal(time.Duration(0)))
+	})
+})
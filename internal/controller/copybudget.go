@@ -0,0 +1,89 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Bytes of secret data held in the target secrets of each SecretCopier, used
+// to report how much duplicate secret data the controller is adding to etcd,
+// both for each SecretCopier and across the cluster. The zero value is ready
+// to use.
+type copyBudget struct {
+	mutex sync.Mutex
+	bytes map[string]int
+}
+
+// Record the bytes of secret data held in the target secrets of a
+// SecretCopier, updating the cluster wide total.
+func (b *copyBudget) set(secretCopierName string, bytes int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.bytes == nil {
+		b.bytes = make(map[string]int)
+	}
+
+	b.bytes[secretCopierName] = bytes
+
+	targetSecretDataBytes.WithLabelValues(secretCopierName).Set(float64(bytes))
+	clusterTargetSecretDataBytes.Set(float64(b.total()))
+}
+
+// Discard the bytes recorded for a SecretCopier which has been deleted.
+func (b *copyBudget) forget(secretCopierName string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.bytes, secretCopierName)
+
+	clusterTargetSecretDataBytes.Set(float64(b.total()))
+}
+
+// Total bytes across all SecretCopiers. Must be called with the mutex held.
+func (b *copyBudget) total() int {
+	total := 0
+
+	for _, bytes := range b.bytes {
+		total += bytes
+	}
+
+	return total
+}
+
+// Calculate the bytes of secret data a rule holds in each target secret, being
+// the size of the data of the source secret selected or rendered by the rule.
+// Zero is returned if the source secret doesn't exist or its templates fail to
+// render.
+func ruleTargetSecretSize(rule *secretsv1beta1.SecretCopierRule, sourceSecret *corev1.Secret) int {
+	if sourceSecret.Name == "" {
+		return 0
+	}
+
+	content, err := targetSecretContent(rule, sourceSecret)
+
+	if err != nil {
+		return 0
+	}
+
+	return secretDataSize(content)
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Copy Budget Metrics", func() {
+	It("should size target secrets by the keys the rule copies", func() {
+		sourceSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "source"},
+			Data: map[string][]byte{
+				"username": []byte("admin"),
+				"password": []byte("0123456789"),
+			},
+		}
+
+		rule := &secretsv1beta1.SecretCopierRule{}
+
+		Expect(ruleTargetSecretSize(rule, sourceSecret)).To(Equal(15))
+
+		rule.TargetSecret.IncludeKeys = []string{"password"}
+
+		Expect(ruleTargetSecretSize(rule, sourceSecret)).To(Equal(10))
+
+		Expect(ruleTargetSecretSize(rule, &corev1.Secret{})).To(BeZero())
+	})
+
+	It("should track bytes held for each SecretCopier and across the cluster", func() {
+		var budget copyBudget
+
+		budget.set("budget-copier-a", 100)
+		budget.set("budget-copier-b", 50)
+
+		Expect(testutil.ToFloat64(targetSecretDataBytes.WithLabelValues("budget-copier-a"))).To(Equal(100.0))
+		Expect(testutil.ToFloat64(clusterTargetSecretDataBytes)).To(Equal(150.0))
+
+		budget.set("budget-copier-a", 40)
+
+		Expect(testutil.ToFloat64(clusterTargetSecretDataBytes)).To(Equal(90.0))
+
+		budget.forget("budget-copier-b")
+
+		Expect(testutil.ToFloat64(clusterTargetSecretDataBytes)).To(Equal(40.0))
+
+		budget.forget("budget-copier-a")
+
+		Expect(testutil.ToFloat64(clusterTargetSecretDataBytes)).To(BeZero())
+	})
+})
@@ -0,0 +1,308 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/internal/version"
+)
+
+// CopyPlan is the set of target secrets the SecretCopiers in a cluster should
+// produce, as worked out by a particular version of the manager. Comparing
+// plans worked out before and after an upgrade of the manager shows whether
+// the upgrade changes which secrets are copied where.
+type CopyPlan struct {
+	// Version of the manager which worked out the plan.
+	Version string `json:"version"`
+
+	// When the plan was worked out.
+	Created time.Time `json:"created"`
+
+	// Target secrets the SecretCopiers should produce, ordered by
+	// SecretCopier and then target secret.
+	Targets []CopyPlanTarget `json:"targets"`
+}
+
+// CopyPlanTarget is a single target secret which a rule of a SecretCopier
+// should produce.
+type CopyPlanTarget struct {
+	// Name of the SecretCopier.
+	SecretCopier string `json:"secretCopier"`
+
+	// Index of the rule of the SecretCopier producing the target secret.
+	Rule int `json:"rule"`
+
+	// Name of the rule, if it has one.
+	RuleName string `json:"ruleName,omitempty"`
+
+	// Source secret the target secret is copied from.
+	SourceNamespace string `json:"sourceNamespace"`
+	SourceName      string `json:"sourceName"`
+
+	// Hash of the type and data of the source secret, or empty if the source
+	// secret doesn't exist.
+	SourceHash string `json:"sourceHash,omitempty"`
+
+	// The target secret.
+	TargetNamespace string `json:"targetNamespace"`
+	TargetName      string `json:"targetName"`
+
+	// Hash of the type and data the target secret should hold, or empty if
+	// the source secret doesn't exist or its content can't be rendered.
+	Hash string `json:"hash,omitempty"`
+}
+
+// Key identifying the target secret within a plan.
+func (t *CopyPlanTarget) key() string {
+	return t.SecretCopier + "/" + t.TargetNamespace + "/" + t.TargetName
+}
+
+// CopyPlanDifference describes a target secret which differs between two
+// copy plans.
+type CopyPlanDifference struct {
+	// Name of the SecretCopier.
+	SecretCopier string `json:"secretCopier"`
+
+	// The target secret.
+	TargetNamespace string `json:"targetNamespace"`
+	TargetName      string `json:"targetName"`
+
+	// One of added, removed or changed.
+	Change string `json:"change"`
+
+	// Explanation of what changed.
+	Detail string `json:"detail"`
+}
+
+// String formats the difference for display.
+func (d CopyPlanDifference) String() string {
+	return fmt.Sprintf("%s: secret %s in namespace %s of SecretCopier %s, %s", d.Change, d.TargetName, d.TargetNamespace, d.SecretCopier, d.Detail)
+}
+
+// CollectCopyPlan evaluates the rules of all SecretCopiers in the cluster
+// against the namespaces and source secrets in the cluster, returning the
+// target secrets they should produce. Target namespaces are selected the same
+// way as when SecretCopiers are reconciled, using the given settings of the
+// manager. Rules which are disabled, copy to a remote cluster or are blocked
+// by the fan-out guard are left out, as are rules naming the target secret
+// with a content hash suffix whose source secret doesn't exist, as the name
+// of the target secret can't be known.
+func CollectCopyPlan(ctx context.Context, c client.Client, settings *ManagerSettings) (CopyPlan, error) {
+	plan := CopyPlan{
+		Version: version.Get().Version,
+		Created: time.Now().UTC().Truncate(time.Second),
+		Targets: make([]CopyPlanTarget, 0),
+	}
+
+	var secretCopiers secretsv1beta1.SecretCopierList
+
+	if err := c.List(ctx, &secretCopiers); err != nil {
+		return plan, err
+	}
+
+	var namespaces corev1.NamespaceList
+
+	if err := c.List(ctx, &namespaces); err != nil {
+		return plan, err
+	}
+
+	delegations, err := loadDelegationScopes(ctx, c)
+
+	if err != nil {
+		return plan, err
+	}
+
+	// Readiness signals and required resources of target namespaces are
+	// checked through a reconciler reading directly from the cluster.
+
+	r := &SecretCopierReconciler{Client: c}
+
+	for i := range secretCopiers.Items {
+		secretCopier := &secretCopiers.Items[i]
+
+		rules, ruleOrigins, err := expandSourceSecretPatterns(ctx, c, secretCopier.Spec.Rules)
+
+		if err != nil {
+			return plan, err
+		}
+
+		for ruleIndex := range rules {
+			rule := &rules[ruleIndex]
+
+			if !rule.IsEnabled() || rule.TargetCluster != nil {
+				continue
+			}
+
+			target := CopyPlanTarget{
+				SecretCopier:    secretCopier.Name,
+				Rule:            ruleOrigins[ruleIndex],
+				RuleName:        rule.Name,
+				SourceNamespace: rule.SourceSecret.Namespace,
+				SourceName:      rule.SourceSecret.Name,
+				TargetName:      targetSecretName(rule),
+			}
+
+			var sourceSecret corev1.Secret
+
+			if err := c.Get(ctx, client.ObjectKey{Namespace: rule.SourceSecret.Namespace, Name: rule.SourceSecret.Name}, &sourceSecret); err != nil {
+				if client.IgnoreNotFound(err) != nil {
+					return plan, err
+				}
+
+				if rule.TargetSecret.HashSuffix != nil {
+					continue
+				}
+			} else {
+				target.SourceHash = sourceSecretRevision(&sourceSecret)
+
+				if content, err := targetSecretContent(rule, &sourceSecret); err == nil {
+					target.Hash = sourceSecretRevision(content)
+				}
+
+				if rule.TargetSecret.HashSuffix != nil {
+					target.TargetName = hashedTargetSecretName(rule, &sourceSecret)
+				}
+			}
+
+			selection := r.selectTargetNamespaces(ctx, settings, delegations, rule, rule.TargetNamespaces.Compile(), namespaces.Items)
+
+			if selection.fanOutBlocked {
+				continue
+			}
+
+			for _, matched := range selection.matched {
+				if !matched.ready() {
+					continue
+				}
+
+				target.TargetNamespace = matched.namespace.Name
+
+				plan.Targets = append(plan.Targets, target)
+			}
+		}
+	}
+
+	sort.SliceStable(plan.Targets, func(i, j int) bool {
+		return plan.Targets[i].key() < plan.Targets[j].key()
+	})
+
+	return plan, nil
+}
+
+// CompareCopyPlans returns the target secrets which differ between a plan
+// worked out before a change, such as an upgrade of the manager, and a plan
+// worked out after it. A change in the content of a target secret is only
+// reported if its source secret is unchanged, as the content is otherwise
+// expected to differ.
+func CompareCopyPlans(before CopyPlan, after CopyPlan) []CopyPlanDifference {
+	differences := make([]CopyPlanDifference, 0)
+
+	previous := make(map[string]*CopyPlanTarget, len(before.Targets))
+
+	for i := range before.Targets {
+		previous[before.Targets[i].key()] = &before.Targets[i]
+	}
+
+	current := make(map[string]*CopyPlanTarget, len(after.Targets))
+
+	for i := range after.Targets {
+		current[after.Targets[i].key()] = &after.Targets[i]
+	}
+
+	difference := func(target *CopyPlanTarget, change string, detail string) CopyPlanDifference {
+		return CopyPlanDifference{
+			SecretCopier:    target.SecretCopier,
+			TargetNamespace: target.TargetNamespace,
+			TargetName:      target.TargetName,
+			Change:          change,
+			Detail:          detail,
+		}
+	}
+
+	for i := range before.Targets {
+		old := &before.Targets[i]
+
+		target, found := current[old.key()]
+
+		switch {
+		case !found:
+			differences = append(differences, difference(old, "removed", "no longer copied from "+old.SourceNamespace+"/"+old.SourceName))
+
+		case target.SourceNamespace != old.SourceNamespace || target.SourceName != old.SourceName:
+			differences = append(differences, difference(target, "changed",
+				fmt.Sprintf("now copied from %s/%s instead of %s/%s", target.SourceNamespace, target.SourceName, old.SourceNamespace, old.SourceName)))
+
+		case target.SourceHash == old.SourceHash && target.Hash != old.Hash:
+			differences = append(differences, difference(target, "changed",
+				fmt.Sprintf("content hash is %q instead of %q", target.Hash, old.Hash)))
+		}
+	}
+
+	for i := range after.Targets {
+		target := &after.Targets[i]
+
+		if _, found := previous[target.key()]; !found {
+			differences = append(differences, difference(target, "added", "now copied from "+target.SourceNamespace+"/"+target.SourceName))
+		}
+	}
+
+	sort.SliceStable(differences, func(i, j int) bool {
+		a, b := differences[i], differences[j]
+
+		if a.SecretCopier != b.SecretCopier {
+			return a.SecretCopier < b.SecretCopier
+		}
+
+		if a.TargetNamespace != b.TargetNamespace {
+			return a.TargetNamespace < b.TargetNamespace
+		}
+
+		return a.TargetName < b.TargetName
+	})
+
+	return differences
+}
+
+// WriteCopyPlan writes a copy plan as JSON.
+func WriteCopyPlan(w io.Writer, plan CopyPlan) error {
+	encoder := json.NewEncoder(w)
+
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(plan)
+}
+
+// ReadCopyPlan reads a copy plan written by WriteCopyPlan.
+func ReadCopyPlan(r io.Reader) (CopyPlan, error) {
+	var plan CopyPlan
+
+	if err := json.NewDecoder(r).Decode(&plan); err != nil {
+		return CopyPlan{}, err
+	}
+
+	return plan, nil
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+var _ = Describe("Copy Plan", func() {
+	ctx := context.Background()
+
+	newNamespace := func(name string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+
+	newSecret := func(password string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "plan-source", Name: "registry"},
+			Data:       map[string][]byte{"password": []byte(password)},
+		}
+	}
+
+	newSecretCopier := func(matchNames ...string) *secretsv1beta1.SecretCopier {
+		return &secretsv1beta1.SecretCopier{
+			ObjectMeta: metav1.ObjectMeta{Name: "plan-copier"},
+			Spec: secretsv1beta1.SecretCopierSpec{
+				Rules: []secretsv1beta1.SecretCopierRule{
+					{
+						SourceSecret: secretsv1beta1.SourceSecret{Namespace: "plan-source", Name: "registry"},
+						TargetNamespaces: selectors.TargetNamespaces{
+							NameSelector: selectors.NameSelector{MatchNames: matchNames},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	collect := func(objects ...client.Object) CopyPlan {
+		c := fake.NewClientBuilder().WithObjects(objects...).Build()
+
+		plan, err := CollectCopyPlan(ctx, c, defaultManagerSettings)
+
+		Expect(err).NotTo(HaveOccurred())
+
+		return plan
+	}
+
+	namespaces := []client.Object{
+		newNamespace("plan-source"),
+		newNamespace("plan-a"),
+		newNamespace("plan-b"),
+		newNamespace("other"),
+	}
+
+	It("should plan a target secret for each matched namespace", func() {
+		plan := collect(append(namespaces, newSecret("one"), newSecretCopier("plan-*"))...)
+
+		Expect(plan.Targets).To(HaveLen(2))
+
+		Expect(plan.Targets[0].TargetNamespace).To(Equal("plan-a"))
+		Expect(plan.Targets[0].TargetName).To(Equal("registry"))
+		Expect(plan.Targets[0].Hash).To(Equal(sourceSecretRevision(newSecret("one"))))
+		Expect(plan.Targets[1].TargetNamespace).To(Equal("plan-b"))
+	})
+
+	It("should plan the hashed name of target secrets named with a content hash suffix", func() {
+		secretCopier := newSecretCopier("plan-*")
+
+		secretCopier.Spec.Rules[0].TargetSecret.HashSuffix = &secretsv1beta1.TargetSecretHashSuffix{}
+
+		plan := collect(append(namespaces, newSecret("one"), secretCopier)...)
+
+		Expect(plan.Targets).To(HaveLen(2))
+		Expect(plan.Targets[0].TargetName).To(Equal(hashedTargetSecretName(&secretCopier.Spec.Rules[0], newSecret("one"))))
+
+		// Without the source secret the name of the target secret isn't
+		// known, so nothing is planned for the rule.
+
+		plan = collect(append(namespaces, secretCopier)...)
+
+		Expect(plan.Targets).To(BeEmpty())
+	})
+
+	It("should leave out namespaces the rule wouldn't copy to yet", func() {
+		secretCopier := newSecretCopier("plan-*")
+
+		secretCopier.Spec.Rules[0].TargetNamespaceReadiness = &secretsv1beta1.NamespaceReadiness{
+			MatchLabels: map[string]string{"ready": "true"},
+		}
+
+		readyNamespace := newNamespace("plan-a")
+
+		readyNamespace.Labels = map[string]string{"ready": "true"}
+
+		plan := collect(readyNamespace, newNamespace("plan-source"), newNamespace("plan-b"), newSecret("one"), secretCopier)
+
+		Expect(plan.Targets).To(HaveLen(1))
+		Expect(plan.Targets[0].TargetNamespace).To(Equal("plan-a"))
+	})
+
+	It("should apply the settings of the manager", func() {
+		consentingNamespace := newNamespace("plan-a")
+
+		consentingNamespace.Annotations = map[string]string{secretsv1beta1.NamespaceAcceptCopiesAnnotation: "true"}
+
+		managerConfig := &secretsv1beta1.SecretsManagerConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+			Spec: secretsv1beta1.SecretsManagerConfigSpec{
+				DeniedNamespaces: []string{"other"},
+			},
+		}
+
+		c := fake.NewClientBuilder().WithObjects(consentingNamespace, newNamespace("plan-source"), newNamespace("plan-b"),
+			newNamespace("other"), newSecret("one"), newSecretCopier("plan-*", "other"), managerConfig).Build()
+
+		// Consent is required by the flags of the manager, and the namespace
+		// never copied to by its SecretsManagerConfig.
+
+		settings, err := LoadManagerSettings(ctx, c, ManagerSettings{RequireNamespaceConsent: true}, "cluster")
+
+		Expect(err).NotTo(HaveOccurred())
+
+		plan, err := CollectCopyPlan(ctx, c, settings)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.Targets).To(HaveLen(1))
+		Expect(plan.Targets[0].TargetNamespace).To(Equal("plan-a"))
+
+		// A fan-out guard the rule exceeds leaves the rule out entirely.
+
+		settings, err = LoadManagerSettings(ctx, c, ManagerSettings{FanOutGuardThreshold: 1}, "")
+
+		Expect(err).NotTo(HaveOccurred())
+
+		plan, err = CollectCopyPlan(ctx, c, settings)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.Targets).To(BeEmpty())
+	})
+
+	It("should round trip a plan through its JSON form", func() {
+		plan := collect(append(namespaces, newSecret("one"), newSecretCopier("plan-*"))...)
+
+		var buffer bytes.Buffer
+
+		Expect(WriteCopyPlan(&buffer, plan)).To(Succeed())
+
+		read, err := ReadCopyPlan(&buffer)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(read.Targets).To(Equal(plan.Targets))
+		Expect(CompareCopyPlans(plan, read)).To(BeEmpty())
+	})
+
+	It("should report target secrets which are added, removed or changed", func() {
+		before := collect(append(namespaces, newSecret("one"), newSecretCopier("plan-a", "plan-b"))...)
+		after := collect(append(namespaces, newSecret("one"), newSecretCopier("plan-b", "other"))...)
+
+		after.Targets[1].Hash = "changed"
+
+		var changes []string
+
+		for _, difference := range CompareCopyPlans(before, after) {
+			changes = append(changes, difference.String())
+		}
+
+		Expect(changes).To(Equal([]string{
+			`added: secret registry in namespace other of SecretCopier plan-copier, now copied from plan-source/registry`,
+			`removed: secret registry in namespace plan-a of SecretCopier plan-copier, no longer copied from plan-source/registry`,
+			`changed: secret registry in namespace plan-b of SecretCopier plan-copier, content hash is "changed" instead of "` + before.Targets[1].Hash + `"`,
+		}))
+	})
+
+	It("should not report content changes caused by the source secret changing", func() {
+		before := collect(append(namespaces, newSecret("one"), newSecretCopier("plan-*"))...)
+		after := collect(append(namespaces, newSecret("two"), newSecretCopier("plan-*"))...)
+
+		Expect(after.Targets[0].Hash).NotTo(Equal(before.Targets[0].Hash))
+		Expect(CompareCopyPlans(before, after)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,78 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sync"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Outcome of copying the source secret of a rule to a target namespace.
+type namespaceCopy struct {
+	namespace string
+	result    copyResult
+	err       error
+}
+
+// Copy the source secret of a rule to each of the target namespaces, using up
+// to the configured number of copy workers concurrently. The outcomes are
+// returned in the same order as the target namespaces.
+func (r *SecretCopierReconciler) copySecretToNamespaces(ctx context.Context, secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, targetNamespaces []string) []namespaceCopy {
+	copies := make([]namespaceCopy, len(targetNamespaces))
+
+	workers := min(max(r.CopyWorkers, 1), len(targetNamespaces))
+
+	if workers <= 1 {
+		for i, targetNamespace := range targetNamespaces {
+			result, err := r.copySecretToNamespace(ctx, secretCopier, rule, targetNamespace)
+
+			copies[i] = namespaceCopy{namespace: targetNamespace, result: result, err: err}
+		}
+
+		return copies
+	}
+
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for range workers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				result, err := r.copySecretToNamespace(ctx, secretCopier, rule, targetNamespaces[i])
+
+				copies[i] = namespaceCopy{namespace: targetNamespaces[i], result: result, err: err}
+			}
+		}()
+	}
+
+	for i := range targetNamespaces {
+		indexes <- i
+	}
+
+	close(indexes)
+
+	wg.Wait()
+
+	return copies
+}
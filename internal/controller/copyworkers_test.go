@@ -0,0 +1,82 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Copy Workers", func() {
+	ctx := context.Background()
+
+	secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "workers-copier"}}
+
+	rule := &secretsv1beta1.SecretCopierRule{
+		SourceSecret: secretsv1beta1.SourceSecret{Name: "token", Namespace: "workers-source"},
+	}
+
+	targetNamespaces := make([]string, 0, 25)
+
+	for i := range 25 {
+		targetNamespaces = append(targetNamespaces, fmt.Sprintf("workers-target-%02d", i))
+	}
+
+	for _, copyWorkers := range []int{0, 1, 4} {
+		It(fmt.Sprintf("should copy to all target namespaces in order with %d workers", copyWorkers), func() {
+			k8sClient := fake.NewClientBuilder().WithObjects(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "token", Namespace: "workers-source"},
+				Data:       map[string][]byte{"token": []byte("value")},
+			}).Build()
+
+			reconciler := &SecretCopierReconciler{
+				Client:      k8sClient,
+				Recorder:    record.NewFakeRecorder(100),
+				CopyWorkers: copyWorkers,
+			}
+
+			copies := reconciler.copySecretToNamespaces(ctx, secretCopier, rule, targetNamespaces)
+
+			Expect(copies).To(HaveLen(len(targetNamespaces)))
+
+			for i, namespaceCopy := range copies {
+				Expect(namespaceCopy.namespace).To(Equal(targetNamespaces[i]))
+				Expect(namespaceCopy.err).NotTo(HaveOccurred())
+				Expect(namespaceCopy.result).To(Equal(copyCreated))
+
+				Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: targetNamespaces[i], Name: "token"}, &corev1.Secret{})).To(Succeed())
+			}
+		})
+	}
+
+	It("should return no outcomes when there are no target namespaces", func() {
+		reconciler := &SecretCopierReconciler{CopyWorkers: 4}
+
+		Expect(reconciler.copySecretToNamespaces(ctx, secretCopier, rule, nil)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,119 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+// Target namespace scopes delegated by SecretCopierDelegations, keyed by the
+// source namespace they were delegated for. Source namespaces without any
+// delegation have no entry and are not restricted.
+type delegationScopes map[string][]*selectors.TargetNamespacesMatcher
+
+// Load the target namespace scopes delegated for each source namespace.
+func loadDelegationScopes(ctx context.Context, reader client.Reader) (delegationScopes, error) {
+	var delegations secretsv1beta1.SecretCopierDelegationList
+
+	if err := reader.List(ctx, &delegations); err != nil {
+		return nil, err
+	}
+
+	scopes := make(delegationScopes)
+
+	for _, delegation := range delegations.Items {
+		sourceNamespace := delegation.Spec.SourceNamespace
+
+		scopes[sourceNamespace] = append(scopes[sourceNamespace], delegation.Spec.TargetNamespaces.Compile())
+	}
+
+	return scopes, nil
+}
+
+// Determine whether copying from the source namespace has been delegated,
+// restricting the target namespaces secrets from it may be copied to.
+func (s delegationScopes) delegated(sourceNamespace string) bool {
+	_, ok := s[sourceNamespace]
+
+	return ok
+}
+
+// Determine whether secrets from the source namespace may be copied to the
+// target namespace. This is the case if copying from the source namespace
+// hasn't been delegated, or the target namespace is within the scope of any
+// of the delegations for it.
+func (s delegationScopes) permits(sourceNamespace string, namespace *corev1.Namespace) bool {
+	matchers, ok := s[sourceNamespace]
+
+	if !ok {
+		return true
+	}
+
+	for _, matcher := range matchers {
+		if matcher.Matches(namespace) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Handler function to find SecretCopier objects with a rule copying a secret
+// from the source namespace of a SecretCopierDelegation. This is used to
+// trigger a reconciliation of the SecretCopier when a delegation is created,
+// changed or deleted, so the delegated scope is applied.
+func (r *SecretCopierReconciler) findSecretCopiersForDelegation(ctx context.Context, object client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	delegation, ok := object.(*secretsv1beta1.SecretCopierDelegation)
+
+	if !ok {
+		log.Error(nil, "Object is not a SecretCopierDelegation", "object", object)
+		return nil
+	}
+
+	var secretCopiers secretsv1beta1.SecretCopierList
+
+	if err := r.List(ctx, &secretCopiers); err != nil {
+		log.Error(err, "Unable to list SecretCopier objects")
+		return nil
+	}
+
+	var requests []reconcile.Request
+
+	for _, secretCopier := range secretCopiers.Items {
+		for _, rule := range secretCopier.Spec.Rules {
+			if rule.SourceSecret.Namespace == delegation.Spec.SourceNamespace {
+				log.V(1).Info("Queue reconcile for SecretCopierDelegation against SecretCopier", "name", secretCopier.Name, "delegation", delegation.Name, "sourceNamespace", delegation.Spec.SourceNamespace)
+
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&secretCopier)})
+
+				break
+			}
+		}
+	}
+
+	return requests
+}
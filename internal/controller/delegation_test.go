@@ -0,0 +1,124 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+var _ = Describe("Source Namespace Delegation", func() {
+	ctx := context.Background()
+
+	newDelegation := func(name string, sourceNamespace string, matchNames ...string) *secretsv1beta1.SecretCopierDelegation {
+		return &secretsv1beta1.SecretCopierDelegation{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: secretsv1beta1.SecretCopierDelegationSpec{
+				SourceNamespace: sourceNamespace,
+				TargetNamespaces: selectors.TargetNamespaces{
+					NameSelector: selectors.NameSelector{MatchNames: matchNames},
+				},
+			},
+		}
+	}
+
+	newNamespace := func(name string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+
+	newSecretCopier := func(name string, sourceNamespace string) *secretsv1beta1.SecretCopier {
+		return &secretsv1beta1.SecretCopier{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: secretsv1beta1.SecretCopierSpec{
+				Rules: []secretsv1beta1.SecretCopierRule{
+					{SourceSecret: secretsv1beta1.SourceSecret{Namespace: sourceNamespace, Name: "registry"}},
+				},
+			},
+		}
+	}
+
+	It("should restrict delegated source namespaces to the union of their scopes", func() {
+		reconciler := &SecretCopierReconciler{Client: fake.NewClientBuilder().WithObjects(
+			newDelegation("team-a-dev", "team-a", "team-a-dev-*"),
+			newDelegation("team-a-prod", "team-a", "team-a-prod"),
+		).Build()}
+
+		scopes, err := loadDelegationScopes(ctx, reconciler.Client)
+
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(scopes.delegated("team-a")).To(BeTrue())
+		Expect(scopes.delegated("team-b")).To(BeFalse())
+
+		checks := []struct {
+			sourceNamespace string
+			targetNamespace string
+			permitted       bool
+		}{
+			{"team-a", "team-a-dev-1", true},
+			{"team-a", "team-a-prod", true},
+			{"team-a", "team-a-staging", false},
+			{"team-a", "team-b-dev-1", false},
+			{"team-b", "team-a-staging", true},
+		}
+
+		for _, check := range checks {
+			Expect(scopes.permits(check.sourceNamespace, newNamespace(check.targetNamespace))).To(Equal(check.permitted), check.targetNamespace)
+		}
+	})
+
+	It("should not restrict any source namespace when there are no delegations", func() {
+		reconciler := &SecretCopierReconciler{Client: fake.NewClientBuilder().Build()}
+
+		scopes, err := loadDelegationScopes(ctx, reconciler.Client)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(scopes.permits("team-a", newNamespace("anywhere"))).To(BeTrue())
+	})
+
+	It("should queue SecretCopier objects copying from the delegated source namespace", func() {
+		reconciler := &SecretCopierReconciler{Client: fake.NewClientBuilder().WithObjects(
+			newSecretCopier("team-a-copier", "team-a"),
+			newSecretCopier("team-b-copier", "team-b"),
+		).Build()}
+
+		requests := reconciler.findSecretCopiersForDelegation(ctx, newDelegation("team-a", "team-a", "team-a-*"))
+
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Name).To(Equal("team-a-copier"))
+	})
+
+	It("should explain when all matched namespaces are outside the delegated scope", func() {
+		reconciler := &SecretCopierReconciler{}
+
+		rule := &secretsv1beta1.SecretCopierRule{
+			SourceSecret: secretsv1beta1.SourceSecret{Namespace: "team-a", Name: "registry"},
+		}
+
+		message := reconciler.explainNoTargetNamespaces(rule, rule.TargetNamespaces.Compile(), nil, 0, 0, 2, 0)
+
+		Expect(message).To(Equal("All 2 matched namespaces are outside the scope delegated for source namespace team-a"))
+	})
+})
@@ -0,0 +1,71 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Tracks which target namespaces named rules of a SecretCopier have matched
+// and synced during a single reconciliation, so that rules which depend on
+// them can hold off copying to a namespace until their dependencies have
+// synced to it.
+type ruleDependencies struct {
+	matched map[string]map[string]bool
+	synced  map[string]map[string]bool
+}
+
+func newRuleDependencies() *ruleDependencies {
+	return &ruleDependencies{
+		matched: make(map[string]map[string]bool),
+		synced:  make(map[string]map[string]bool),
+	}
+}
+
+// Record that the rule matched the target namespace.
+func (d *ruleDependencies) matchedTarget(rule *secretsv1beta1.SecretCopierRule, namespace string) {
+	recordRuleTarget(d.matched, rule.Name, namespace)
+}
+
+// Record that the rule synced its target secret to the target namespace.
+func (d *ruleDependencies) syncedTarget(rule *secretsv1beta1.SecretCopierRule, namespace string) {
+	recordRuleTarget(d.synced, rule.Name, namespace)
+}
+
+// Determine whether copying to the target namespace must wait for a rule the
+// rule depends on, which also targets the namespace, to sync to it first.
+func (d *ruleDependencies) waiting(rule *secretsv1beta1.SecretCopierRule, namespace string) bool {
+	for _, name := range rule.DependsOn {
+		if d.matched[name][namespace] && !d.synced[name][namespace] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func recordRuleTarget(targets map[string]map[string]bool, name string, namespace string) {
+	if name == "" {
+		return
+	}
+
+	if targets[name] == nil {
+		targets[name] = make(map[string]bool)
+	}
+
+	targets[name][namespace] = true
+}
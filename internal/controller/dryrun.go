@@ -0,0 +1,53 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Check using a server side dry run whether updating the target secret would
+// change it. The comparison of the source and target secret made by the
+// controller can report a difference where the API server would normalize or
+// default the target secret back to what is already stored, such as an empty
+// data map versus no data, resulting in updates which change nothing but still
+// consume the write rate limit and trigger watchers of the secret. If the dry
+// run fails, the update is assumed to be required so that the real write
+// reports the error. The dry run is made using the same client as the real
+// write would be.
+func (r *SecretCopierReconciler) updateChangesSecret(ctx context.Context, writer client.Writer, current *corev1.Secret, updated *corev1.Secret) bool {
+	log := log.FromContext(ctx)
+
+	dryRun := updated.DeepCopy()
+
+	if err := writer.Update(ctx, dryRun, client.DryRunAll); err != nil {
+		log.V(1).Info("Unable to dry run update of target secret", "targetSecret", updated.Name, "targetNamespace", updated.Namespace, "error", err.Error())
+		return true
+	}
+
+	return current.Type != dryRun.Type ||
+		!equality.Semantic.DeepEqual(current.Data, dryRun.Data) ||
+		!equality.Semantic.DeepEqual(current.Immutable, dryRun.Immutable) ||
+		!equality.Semantic.DeepEqual(current.Labels, dryRun.Labels) ||
+		!equality.Semantic.DeepEqual(current.Annotations, dryRun.Annotations) ||
+		!equality.Semantic.DeepEqual(current.OwnerReferences, dryRun.OwnerReferences)
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Dry Run Updates", func() {
+	ctx := context.Background()
+
+	var reconciler *SecretCopierReconciler
+	var current *corev1.Secret
+
+	BeforeEach(func() {
+		stored := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "dry-run-secret",
+				Namespace: "dry-run-namespace",
+				Labels:    map[string]string{"app": "registry"},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{},
+		}
+
+		fakeClient := fake.NewClientBuilder().WithObjects(stored).Build()
+
+		reconciler = &SecretCopierReconciler{Client: fakeClient}
+
+		current = &corev1.Secret{}
+		Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(stored), current)).To(Succeed())
+	})
+
+	It("should report a change when the data differs", func() {
+		updated := current.DeepCopy()
+		updated.Data = map[string][]byte{"password": []byte("changed")}
+
+		Expect(reconciler.updateChangesSecret(ctx, reconciler.Client, current, updated)).To(BeTrue())
+	})
+
+	It("should report a change when the labels differ", func() {
+		updated := current.DeepCopy()
+		updated.Labels = map[string]string{"app": "database"}
+
+		Expect(reconciler.updateChangesSecret(ctx, reconciler.Client, current, updated)).To(BeTrue())
+	})
+
+	It("should report no change when the update only differs by normalization", func() {
+		dryRuns := 0
+		writes := 0
+
+		// Stand in for the API server defaulting the type of the secret,
+		// which the fake client doesn't do, so the update can only be found
+		// to change nothing by making the dry run.
+
+		reconciler.Client = interceptor.NewClient(reconciler.Client.(client.WithWatch), interceptor.Funcs{
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				options := &client.UpdateOptions{}
+				options.ApplyOptions(opts)
+
+				if len(options.DryRun) == 0 {
+					writes++
+					return c.Update(ctx, obj, opts...)
+				}
+
+				dryRuns++
+
+				secret := obj.(*corev1.Secret)
+
+				if secret.Type == "" {
+					secret.Type = corev1.SecretTypeOpaque
+				}
+
+				return nil
+			},
+		})
+
+		updated := current.DeepCopy()
+		updated.Type = ""
+
+		Expect(reconciler.updateChangesSecret(ctx, reconciler.Client, current, updated)).To(BeFalse())
+
+		Expect(dryRuns).To(Equal(1))
+		Expect(writes).To(BeZero())
+	})
+
+	It("should skip the real write when the dry run reports no change", func() {
+		secretCopier := &secretsv1beta1.SecretCopier{
+			ObjectMeta: metav1.ObjectMeta{Name: "dry-run-copier"},
+		}
+
+		rule := &secretsv1beta1.SecretCopierRule{
+			SourceSecret: secretsv1beta1.SourceSecret{Namespace: "dry-run-source", Name: "registry"},
+		}
+
+		sourceSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "dry-run-source", Name: "registry"},
+			Type:       corev1.SecretTypeOpaque,
+			Data:       map[string][]byte{"token": []byte("one")},
+		}
+
+		fakeClient := fake.NewClientBuilder().WithObjects(sourceSecret,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "dry-run-namespace"}}).Build()
+
+		reconciler.Client = fakeClient
+
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "dry-run-namespace")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyCreated))
+
+		sourceSecret.Data = map[string][]byte{"token": []byte("two")}
+		Expect(fakeClient.Update(ctx, sourceSecret)).To(Succeed())
+
+		// Have the dry run answer with the target secret as stored, as the
+		// API server would for an update which normalizes away.
+
+		dryRuns := 0
+		writes := 0
+
+		reconciler.Client = interceptor.NewClient(fakeClient, interceptor.Funcs{
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				options := &client.UpdateOptions{}
+				options.ApplyOptions(opts)
+
+				if len(options.DryRun) == 0 {
+					writes++
+					return c.Update(ctx, obj, opts...)
+				}
+
+				dryRuns++
+
+				return c.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+			},
+		})
+
+		result, err = reconciler.copySecretToNamespace(ctx, secretCopier, rule, "dry-run-namespace")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyUnchanged))
+		Expect(dryRuns).To(Equal(1))
+		Expect(writes).To(BeZero())
+
+		targetSecret := &corev1.Secret{}
+		Expect(fakeClient.Get(ctx, client.ObjectKey{Namespace: "dry-run-namespace", Name: "registry"}, targetSecret)).To(Succeed())
+		Expect(targetSecret.Data).To(HaveKeyWithValue("token", []byte("one")))
+	})
+
+	It("should report a change when the dry run fails", func() {
+		reconciler.Client = interceptor.NewClient(reconciler.Client.(client.WithWatch), interceptor.Funcs{
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				return errors.New("dry run failed")
+			},
+		})
+
+		updated := current.DeepCopy()
+		updated.Data = nil
+
+		Expect(reconciler.updateChangesSecret(ctx, reconciler.Client, current, updated)).To(BeTrue())
+	})
+})
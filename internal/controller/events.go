@@ -0,0 +1,172 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// Maximum window over which repeated events are aggregated. The window
+// doubles each time it ends with repeats still occurring, up to this limit.
+const eventAggregationMaxWindow = 30 * time.Minute
+
+// Event recorder which aggregates repeated events. The first event for a
+// given object, type and reason is recorded immediately. Further events with
+// the same object, type and reason within the aggregation window are only
+// counted, with a single event giving the count and the most recent message
+// being recorded when the window ends. While the events keep repeating the
+// window doubles, so a persistent failure, such as the same permission error
+// for every target namespace on each sync, doesn't flood the events list.
+type aggregatingEventRecorder struct {
+	recorder record.EventRecorder
+	window   time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]*aggregatedEvent
+}
+
+type aggregatedEvent struct {
+	object      runtime.Object
+	annotations map[string]string
+	eventType   string
+	reason      string
+	message     string
+	count       int
+	window      time.Duration
+	timer       *time.Timer
+}
+
+// NewAggregatingEventRecorder wraps an event recorder so that repeated events
+// are aggregated over the given window. If the window is zero, the recorder
+// is returned unchanged.
+func NewAggregatingEventRecorder(recorder record.EventRecorder, window time.Duration) record.EventRecorder {
+	if window <= 0 {
+		return recorder
+	}
+
+	return &aggregatingEventRecorder{
+		recorder: recorder,
+		window:   window,
+		entries:  make(map[string]*aggregatedEvent),
+	}
+}
+
+func (r *aggregatingEventRecorder) Event(object runtime.Object, eventType, reason, message string) {
+	r.record(object, nil, eventType, reason, message)
+}
+
+func (r *aggregatingEventRecorder) Eventf(object runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	r.record(object, nil, eventType, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func (r *aggregatingEventRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventType, reason, messageFmt string, args ...interface{}) {
+	r.record(object, annotations, eventType, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// Key identifying events which are aggregated together.
+func aggregatedEventKey(object runtime.Object, eventType, reason string) string {
+	key := fmt.Sprintf("%T", object)
+
+	if accessor, err := meta.Accessor(object); err == nil {
+		key += "/" + accessor.GetNamespace() + "/" + accessor.GetName()
+	}
+
+	return key + "/" + eventType + "/" + reason
+}
+
+// Record the event if it is the first in the window, otherwise count it.
+func (r *aggregatingEventRecorder) record(object runtime.Object, annotations map[string]string, eventType, reason, message string) {
+	key := aggregatedEventKey(object, eventType, reason)
+
+	r.mutex.Lock()
+
+	if entry, ok := r.entries[key]; ok {
+		entry.object = object
+		entry.annotations = annotations
+		entry.message = message
+		entry.count++
+
+		r.mutex.Unlock()
+
+		return
+	}
+
+	r.entries[key] = &aggregatedEvent{
+		object:    object,
+		eventType: eventType,
+		reason:    reason,
+		window:    r.window,
+		timer:     time.AfterFunc(r.window, func() { r.flush(key) }),
+	}
+
+	r.mutex.Unlock()
+
+	r.emit(object, annotations, eventType, reason, message)
+}
+
+// Called when the window for an event ends. If the event was repeated within
+// the window, a single event giving the count is recorded and a longer window
+// started, otherwise the next occurrence of the event is recorded immediately.
+func (r *aggregatingEventRecorder) flush(key string) {
+	r.mutex.Lock()
+
+	entry, ok := r.entries[key]
+
+	if !ok {
+		r.mutex.Unlock()
+		return
+	}
+
+	if entry.count == 0 {
+		delete(r.entries, key)
+		r.mutex.Unlock()
+		return
+	}
+
+	object, annotations, message := entry.object, entry.annotations, entry.message
+
+	message = fmt.Sprintf("%s (repeated %d times in the last %s)", message, entry.count, entry.window)
+
+	entry.count = 0
+	entry.window *= 2
+
+	if entry.window > eventAggregationMaxWindow {
+		entry.window = max(eventAggregationMaxWindow, r.window)
+	}
+
+	entry.timer.Stop()
+	entry.timer = time.AfterFunc(entry.window, func() { r.flush(key) })
+
+	r.mutex.Unlock()
+
+	r.emit(object, annotations, entry.eventType, entry.reason, message)
+}
+
+func (r *aggregatingEventRecorder) emit(object runtime.Object, annotations map[string]string, eventType, reason, message string) {
+	if annotations != nil {
+		r.recorder.AnnotatedEventf(object, annotations, eventType, reason, "%s", message)
+		return
+	}
+
+	r.recorder.Event(object, eventType, reason, message)
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Events", func() {
+	// Drain the events recorded so far by a fake recorder.
+	drain := func(recorder *record.FakeRecorder) []string {
+		var events []string
+
+		for {
+			select {
+			case event := <-recorder.Events:
+				events = append(events, event)
+			default:
+				return events
+			}
+		}
+	}
+
+	It("should return the recorder unchanged when aggregation is disabled", func() {
+		recorder := record.NewFakeRecorder(10)
+
+		Expect(NewAggregatingEventRecorder(recorder, 0)).To(BeIdenticalTo(recorder))
+	})
+
+	It("should count repeated events and record them when the window ends", func() {
+		recorder := record.NewFakeRecorder(100)
+
+		// Use a long window so that the window is ended by the test calling
+		// flush rather than the timer.
+
+		aggregator := NewAggregatingEventRecorder(recorder, 20*time.Minute).(*aggregatingEventRecorder)
+
+		secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "events-copier"}}
+		otherCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "events-other-copier"}}
+
+		for i := 0; i < 200; i++ {
+			aggregator.Eventf(secretCopier, corev1.EventTypeWarning, "PermissionDenied", "Permission denied in namespace ns-%d", i)
+		}
+
+		aggregator.Eventf(secretCopier, corev1.EventTypeNormal, "ReportOnly", "Would copy secret")
+		aggregator.Eventf(otherCopier, corev1.EventTypeWarning, "PermissionDenied", "Permission denied in namespace ns-0")
+
+		Expect(drain(recorder)).To(Equal([]string{
+			"Warning PermissionDenied Permission denied in namespace ns-0",
+			"Normal ReportOnly Would copy secret",
+			"Warning PermissionDenied Permission denied in namespace ns-0",
+		}))
+
+		key := aggregatedEventKey(secretCopier, corev1.EventTypeWarning, "PermissionDenied")
+
+		aggregator.flush(key)
+
+		Expect(drain(recorder)).To(Equal([]string{
+			"Warning PermissionDenied Permission denied in namespace ns-199 (repeated 199 times in the last 20m0s)",
+		}))
+
+		// The window backs off while the events keep repeating.
+
+		Expect(aggregator.entries[key].window).To(Equal(eventAggregationMaxWindow))
+
+		// Once a window ends without repeats, the next event is recorded
+		// immediately again.
+
+		aggregator.flush(key)
+
+		Expect(drain(recorder)).To(BeEmpty())
+		Expect(aggregator.entries).NotTo(HaveKey(key))
+
+		aggregator.Eventf(secretCopier, corev1.EventTypeWarning, "PermissionDenied", "Permission denied in namespace ns-0")
+
+		Expect(drain(recorder)).To(HaveLen(1))
+	})
+
+	It("should record distribution events against the source secret when enabled", func() {
+		recorder := record.NewFakeRecorder(10)
+
+		secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "events-copier"}}
+		sourceSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "source"}}
+
+		reconciler := &SecretCopierReconciler{Recorder: recorder}
+
+		reconciler.recordSourceSecretEvent(secretCopier, sourceSecret, 0, 2, 1, 37)
+
+		Expect(drain(recorder)).To(BeEmpty())
+
+		reconciler.SourceSecretEvents = true
+
+		reconciler.recordSourceSecretEvent(secretCopier, sourceSecret, 0, 0, 0, 37)
+
+		Expect(drain(recorder)).To(BeEmpty())
+
+		reconciler.recordSourceSecretEvent(secretCopier, sourceSecret, 0, 2, 1, 37)
+
+		Expect(drain(recorder)).To(Equal([]string{
+			"Normal SecretDistributed Secret copied to 2 namespaces by rule 0 of SecretCopier events-copier, now synced to 37 namespaces",
+			"Warning SecretDistributionFailed Secret could not be copied to 1 namespaces by rule 0 of SecretCopier events-copier",
+		}))
+	})
+
+	It("should record the outcome of copies against the SecretCopier when enabled", func() {
+		recorder := record.NewFakeRecorder(10)
+
+		secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "events-copier"}}
+
+		rule := &secretsv1beta1.SecretCopierRule{
+			SourceSecret: secretsv1beta1.SourceSecret{Name: "registry", Namespace: "source"},
+		}
+
+		reconciler := &SecretCopierReconciler{Recorder: recorder}
+
+		reconciler.recordCopyEvent(secretCopier, rule, "tenant-a", "registry", copyCreated, nil)
+
+		Expect(drain(recorder)).To(BeEmpty())
+
+		reconciler.CopyEvents = true
+
+		for _, result := range []copyResult{copyCreated, copyUpdated, copyUnchanged, copySkipped, copyIgnored, copyThrottled, copyForbidden} {
+			reconciler.recordCopyEvent(secretCopier, rule, "tenant-a", "registry", result, nil)
+		}
+
+		reconciler.recordCopyEvent(secretCopier, rule, "tenant-a", "registry", copyFailed, errors.New("connection refused"))
+
+		Expect(drain(recorder)).To(Equal([]string{
+			"Normal SecretCreated Secret tenant-a/registry created from source/registry",
+			"Normal SecretUpdated Secret tenant-a/registry updated from source/registry",
+			"Normal SecretSkipped Secret tenant-a/registry not copied from source/registry as it is not managed by the SecretCopier or the source can't be copied",
+			"Normal SecretIgnored Secret tenant-a/registry not updated from source/registry as it is labelled to be ignored",
+			"Warning SecretCopyFailed Unable to copy secret source/registry to tenant-a/registry (reason Unknown): connection refused",
+		}))
+	})
+})
@@ -0,0 +1,320 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+// SharedSecret describes a secret found with identical type and data in
+// several namespaces, as left behind by sharing the secret by hand, which
+// could instead be copied by a SecretCopier.
+type SharedSecret struct {
+	// The secret treated as the source, being the oldest of the copies.
+	Source client.ObjectKey
+
+	// Type of the secret.
+	Type corev1.SecretType
+
+	// Names of the keys of the secret.
+	Keys []string
+
+	// The other copies of the secret, which are in namespaces other than
+	// that of the source, ordered by name and then namespace.
+	Copies []client.ObjectKey
+}
+
+// SharedSecretOptions control which secrets are considered when looking for
+// secrets shared by hand.
+type SharedSecretOptions struct {
+	// Namespaces whose secrets are ignored. Names may be glob patterns.
+	ExcludedNamespaces []string
+
+	// Minimum number of copies of a secret in other namespaces for it to be
+	// reported.
+	MinCopies int
+}
+
+// CollectSharedSecrets returns the secrets in the cluster which have identical
+// type and data in more than one namespace, ordered by source secret. Secrets
+// written by the manager, secrets owned by other resources, secrets without
+// data, and secrets of types which are never copied are ignored, as they
+// aren't candidates for being copied by a SecretCopier.
+func CollectSharedSecrets(ctx context.Context, c client.Reader, options SharedSecretOptions) ([]SharedSecret, error) {
+	var secrets corev1.SecretList
+
+	if err := c.List(ctx, &secrets); err != nil {
+		return nil, err
+	}
+
+	excluded := selectors.NameSelector{MatchNames: options.ExcludedNamespaces}.Compile()
+
+	settings := defaultManagerSettings
+
+	groups := make(map[string][]*corev1.Secret)
+
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+
+		if len(options.ExcludedNamespaces) != 0 && excluded.Matches(secret.Namespace) {
+			continue
+		}
+
+		if len(secret.Data) == 0 || len(secret.OwnerReferences) != 0 || settings.SecretTypeDenied(secret.Type) {
+			continue
+		}
+
+		if secret.Annotations[secretCopierMarkerAnnotation] != "" ||
+			secret.Annotations[vaultSecretAnnotation] != "" ||
+			secret.Annotations[awsSecretAnnotation] != "" {
+			continue
+		}
+
+		revision := sourceSecretRevision(secret)
+
+		groups[revision] = append(groups[revision], secret)
+	}
+
+	shared := make([]SharedSecret, 0)
+
+	for _, group := range groups {
+		// Treat the oldest secret as the source, as it is most likely the
+		// one the others were copied from.
+
+		sort.Slice(group, func(i, j int) bool {
+			if !group[i].CreationTimestamp.Equal(&group[j].CreationTimestamp) {
+				return group[i].CreationTimestamp.Before(&group[j].CreationTimestamp)
+			}
+
+			if group[i].Namespace != group[j].Namespace {
+				return group[i].Namespace < group[j].Namespace
+			}
+
+			return group[i].Name < group[j].Name
+		})
+
+		source := group[0]
+
+		copies := make([]client.ObjectKey, 0, len(group)-1)
+
+		for _, secret := range group[1:] {
+			if secret.Namespace != source.Namespace {
+				copies = append(copies, client.ObjectKeyFromObject(secret))
+			}
+		}
+
+		if len(copies) == 0 || len(copies) < options.MinCopies {
+			continue
+		}
+
+		sort.Slice(copies, func(i, j int) bool {
+			if copies[i].Name != copies[j].Name {
+				return copies[i].Name < copies[j].Name
+			}
+
+			return copies[i].Namespace < copies[j].Namespace
+		})
+
+		keys := make([]string, 0, len(source.Data))
+
+		for key := range source.Data {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		shared = append(shared, SharedSecret{
+			Source: client.ObjectKeyFromObject(source),
+			Type:   source.Type,
+			Keys:   keys,
+			Copies: copies,
+		})
+	}
+
+	sort.Slice(shared, func(i, j int) bool {
+		if shared[i].Source.Namespace != shared[j].Source.Namespace {
+			return shared[i].Source.Namespace < shared[j].Source.Namespace
+		}
+
+		return shared[i].Source.Name < shared[j].Source.Name
+	})
+
+	return shared, nil
+}
+
+// SuggestSecretCopier returns a SecretCopier which would copy a shared secret
+// from its source to the namespaces holding the other copies. A rule is added
+// for each distinct name the copies are held under. The reclaim policy of the
+// rules is Retain, so deleting the SecretCopier doesn't remove secrets which
+// existed before it was created.
+func SuggestSecretCopier(shared SharedSecret) *secretsv1beta1.SecretCopier {
+	secretCopier := &secretsv1beta1.SecretCopier{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: secretsv1beta1.GroupVersion.String(),
+			Kind:       "SecretCopier",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: suggestedSecretCopierName(shared.Source),
+		},
+	}
+
+	for start := 0; start < len(shared.Copies); {
+		end := start
+
+		namespaces := make([]string, 0)
+
+		for end < len(shared.Copies) && shared.Copies[end].Name == shared.Copies[start].Name {
+			namespaces = append(namespaces, shared.Copies[end].Namespace)
+			end++
+		}
+
+		rule := secretsv1beta1.SecretCopierRule{
+			SourceSecret: secretsv1beta1.SourceSecret{
+				Namespace: shared.Source.Namespace,
+				Name:      shared.Source.Name,
+			},
+			TargetNamespaces: selectors.TargetNamespaces{
+				NameSelector: selectors.NameSelector{MatchNames: namespaces},
+			},
+			ReclaimPolicy: secretsv1beta1.ReclaimRetain,
+		}
+
+		if shared.Copies[start].Name != shared.Source.Name {
+			rule.TargetSecret.Name = shared.Copies[start].Name
+		}
+
+		secretCopier.Spec.Rules = append(secretCopier.Spec.Rules, rule)
+
+		start = end
+	}
+
+	return secretCopier
+}
+
+// Name a suggested SecretCopier after its source secret, keeping within the
+// limit on the length of a name.
+func suggestedSecretCopierName(source client.ObjectKey) string {
+	name := source.Namespace + "-" + source.Name
+
+	if len(name) > 253 {
+		name = strings.TrimRight(name[:253], "-.")
+	}
+
+	return name
+}
+
+// WriteExamples writes a suggested SecretCopier for each shared secret as a
+// stream of YAML documents, each preceded by comments describing the secret
+// it was derived from. Fields left at their zero value are omitted so the
+// manifests only hold what is needed to reproduce the sharing.
+func WriteExamples(w io.Writer, shared []SharedSecret) error {
+	for _, secret := range shared {
+		secretCopier := SuggestSecretCopier(secret)
+
+		data, err := json.Marshal(secretCopier)
+
+		if err != nil {
+			return err
+		}
+
+		var manifest map[string]any
+
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return err
+		}
+
+		content, err := yaml.Marshal(pruneEmptyFields(manifest))
+
+		if err != nil {
+			return err
+		}
+
+		namespaces := make([]string, 0, len(secret.Copies))
+
+		for _, target := range secret.Copies {
+			namespaces = append(namespaces, target.Namespace)
+		}
+
+		fmt.Fprintf(w, "---\n")
+		fmt.Fprintf(w, "# Secret %s of type %s with keys %s has identical\n", secret.Source, secret.Type, strings.Join(secret.Keys, ", "))
+		fmt.Fprintf(w, "# data in %d other namespaces: %s.\n", len(namespaces), strings.Join(namespaces, ", "))
+
+		if _, err := w.Write(content); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Remove nulls, empty strings, and maps and lists left empty once their own
+// contents have been pruned, from a decoded JSON value.
+func pruneEmptyFields(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, item := range value {
+			if pruned := pruneEmptyFields(item); pruned != nil {
+				value[key] = pruned
+			} else {
+				delete(value, key)
+			}
+		}
+
+		if len(value) == 0 {
+			return nil
+		}
+
+		return value
+
+	case []any:
+		items := make([]any, 0, len(value))
+
+		for _, item := range value {
+			if pruned := pruneEmptyFields(item); pruned != nil {
+				items = append(items, pruned)
+			}
+		}
+
+		if len(items) == 0 {
+			return nil
+		}
+
+		return items
+
+	case string:
+		if value == "" {
+			return nil
+		}
+
+		return value
+	}
+
+	return value
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Example Export", func() {
+	ctx := context.Background()
+
+	newSecret := func(namespace string, name string, age time.Duration, password string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         namespace,
+				CreationTimestamp: metav1.NewTime(time.Date(2024, 9, 1, 10, 0, 0, 0, time.UTC).Add(-age)),
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{"password": []byte(password)},
+		}
+	}
+
+	It("should find secrets with identical data in several namespaces", func() {
+		managed := newSecret("team-c", "registry", 0, "registry-password")
+		managed.Annotations = map[string]string{secretCopierMarkerAnnotation: "registry-copier"}
+
+		owned := newSecret("team-d", "registry", 0, "registry-password")
+		owned.OwnerReferences = []metav1.OwnerReference{{APIVersion: "v1", Kind: "ConfigMap", Name: "owner", UID: "owner-uid"}}
+
+		token := newSecret("team-e", "registry", 0, "registry-password")
+		token.Type = corev1.SecretTypeServiceAccountToken
+
+		c := fake.NewClientBuilder().WithObjects(
+			newSecret("registries", "registry", time.Hour, "registry-password"),
+			newSecret("team-a", "registry", 0, "registry-password"),
+			newSecret("team-b", "pull-secret", 0, "registry-password"),
+			newSecret("kube-system", "registry", 2*time.Hour, "registry-password"),
+			newSecret("team-a", "database", 0, "database-password"),
+			managed,
+			owned,
+			token,
+		).Build()
+
+		shared, err := CollectSharedSecrets(ctx, c, SharedSecretOptions{ExcludedNamespaces: []string{"kube-*"}})
+
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(shared).To(Equal([]SharedSecret{
+			{
+				Source: client.ObjectKey{Namespace: "registries", Name: "registry"},
+				Type:   corev1.SecretTypeOpaque,
+				Keys:   []string{"password"},
+				Copies: []client.ObjectKey{
+					{Namespace: "team-b", Name: "pull-secret"},
+					{Namespace: "team-a", Name: "registry"},
+				},
+			},
+		}))
+
+		shared, err = CollectSharedSecrets(ctx, c, SharedSecretOptions{ExcludedNamespaces: []string{"kube-*"}, MinCopies: 3})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(shared).To(BeEmpty())
+	})
+
+	It("should suggest a rule for each name the copies are held under", func() {
+		secretCopier := SuggestSecretCopier(SharedSecret{
+			Source: client.ObjectKey{Namespace: "registries", Name: "registry"},
+			Copies: []client.ObjectKey{
+				{Namespace: "team-b", Name: "pull-secret"},
+				{Namespace: "team-a", Name: "registry"},
+				{Namespace: "team-c", Name: "registry"},
+			},
+		})
+
+		Expect(secretCopier.Name).To(Equal("registries-registry"))
+		Expect(secretCopier.Spec.Rules).To(HaveLen(2))
+
+		tests := []struct {
+			targetName string
+			namespaces []string
+		}{
+			{targetName: "pull-secret", namespaces: []string{"team-b"}},
+			{targetName: "", namespaces: []string{"team-a", "team-c"}},
+		}
+
+		for i, test := range tests {
+			rule := secretCopier.Spec.Rules[i]
+
+			Expect(rule.SourceSecret).To(Equal(secretsv1beta1.SourceSecret{Namespace: "registries", Name: "registry"}))
+			Expect(rule.TargetSecret.Name).To(Equal(test.targetName))
+			Expect(rule.TargetNamespaces.NameSelector.MatchNames).To(Equal(test.namespaces))
+			Expect(rule.ReclaimPolicy).To(Equal(secretsv1beta1.ReclaimRetain))
+		}
+	})
+
+	It("should write manifests without empty fields which can be applied", func() {
+		shared := []SharedSecret{
+			{
+				Source: client.ObjectKey{Namespace: "registries", Name: "registry"},
+				Type:   corev1.SecretTypeOpaque,
+				Keys:   []string{"password", "username"},
+				Copies: []client.ObjectKey{{Namespace: "team-a", Name: "registry"}},
+			},
+		}
+
+		var out bytes.Buffer
+
+		Expect(WriteExamples(&out, shared)).To(Succeed())
+
+		Expect(out.String()).To(HavePrefix("---\n# Secret registries/registry of type Opaque with keys password, username has identical\n# data in 1 other namespaces: team-a.\n"))
+		Expect(out.String()).NotTo(ContainSubstring("{}"))
+		Expect(out.String()).NotTo(ContainSubstring("null"))
+
+		documents := strings.Split(out.String(), "---\n")
+
+		var secretCopier secretsv1beta1.SecretCopier
+
+		Expect(yaml.UnmarshalStrict([]byte(documents[1]), &secretCopier)).To(Succeed())
+
+		Expect(secretCopier.Kind).To(Equal("SecretCopier"))
+		Expect(secretCopier.APIVersion).To(Equal("secrets-manager.advok8s.io/v1beta1"))
+		Expect(secretCopier.Spec).To(Equal(SuggestSecretCopier(shared[0]).Spec))
+	})
+})
@@ -0,0 +1,278 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+const (
+	// Interval at which a secret held in an external secrets store is read
+	// again if not specified.
+	defaultExternalRefreshInterval = 5 * time.Minute
+
+	// Longest interval after which reading a secret from an external secrets
+	// store is retried following a failure.
+	externalRetryInterval = time.Minute
+)
+
+// Error returned when authenticating with an external secrets store fails.
+type externalAuthError struct {
+	store string
+	err   error
+}
+
+func (e *externalAuthError) Error() string {
+	return fmt.Sprintf("unable to authenticate with %s: %v", e.store, e.err)
+}
+
+func (e *externalAuthError) Unwrap() error {
+	return e.err
+}
+
+// Set the Ready condition in the conditions of an object synced from an
+// external secrets store, with the status following from whether the secret
+// was synced, and record an event if the outcome has changed. Returns the
+// interval after which the secret should be read again. A failure is retried
+// sooner than the refresh interval, as is a write held back by a freeze on
+// writes to secrets.
+func setExternalSecretReadyCondition(recorder record.EventRecorder, object runtime.Object, conditions *[]metav1.Condition, condition metav1.Condition, refreshInterval time.Duration, config *ManagerConfig) time.Duration {
+	requeueAfter := refreshInterval
+
+	condition.Type = secretsv1beta1.ConditionReady
+
+	if condition.Reason == "Synced" {
+		condition.Status = metav1.ConditionTrue
+	} else {
+		condition.Status = metav1.ConditionFalse
+
+		requeueAfter = min(refreshInterval, externalRetryInterval)
+
+		if remaining := config.Settings().WriteFreezeRemaining(time.Now()); remaining > 0 {
+			requeueAfter = min(requeueAfter, remaining)
+		}
+	}
+
+	previous := meta.FindStatusCondition(*conditions, secretsv1beta1.ConditionReady)
+
+	if previous == nil || previous.Reason != condition.Reason || previous.Message != condition.Message {
+		eventType := corev1.EventTypeNormal
+
+		if condition.Status == metav1.ConditionFalse {
+			eventType = corev1.EventTypeWarning
+		}
+
+		recorder.Event(object, eventType, condition.Reason, condition.Message)
+	}
+
+	meta.SetStatusCondition(conditions, condition)
+
+	return requeueAfter
+}
+
+// Read the value of a key of a secret in the given namespace.
+func readSecretKey(ctx context.Context, reader client.Reader, namespace string, name string, key string) ([]byte, error) {
+	var secret corev1.Secret
+
+	if err := reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &secret); err != nil {
+		return nil, fmt.Errorf("unable to fetch secret %s: %w", name, err)
+	}
+
+	value, ok := secret.Data[key]
+
+	if !ok {
+		return nil, fmt.Errorf("secret %s has no key %s", name, key)
+	}
+
+	return value, nil
+}
+
+// Request a short lived token for a service account, which is presented to
+// an external secrets store to authenticate. This fails if the service
+// account doesn't exist.
+func requestServiceAccountToken(ctx context.Context, c client.Client, namespace string, name string, audiences []string) (string, error) {
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+
+	expirationSeconds := int64(600)
+
+	tokenRequest := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			Audiences:         audiences,
+			ExpirationSeconds: &expirationSeconds,
+		},
+	}
+
+	if err := c.SubResource("token").Create(ctx, serviceAccount, tokenRequest); err != nil {
+		return "", fmt.Errorf("unable to request token for service account %s: %w", name, err)
+	}
+
+	return tokenRequest.Status.Token, nil
+}
+
+// Writes the secret holding the data read from an external secrets store
+// into the namespace of the object it is read for.
+type externalSecretWriter struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Controller wide settings, used to check whether writes are frozen.
+	Config *ManagerConfig
+
+	// If set, the secret is never written.
+	ReportOnly bool
+
+	// Name of the controller, used when counting skipped writes.
+	Controller string
+}
+
+// Create or update the secret owned by the object with the data and
+// annotations given, returning the reason and message for the Ready
+// condition of the object. An annotation with an empty value is removed. The
+// synced message is returned where the secret is up to date. An error is only
+// returned where the operation should be retried.
+func (w *externalSecretWriter) write(ctx context.Context, owner client.Object, kind string, secretName string, data map[string][]byte, annotations map[string]string, synced string) (string, string, error) {
+	log := log.FromContext(ctx)
+
+	namespace := owner.GetNamespace()
+
+	// An existing secret which isn't managed by the object is never
+	// overwritten.
+
+	var targetSecret corev1.Secret
+
+	err := w.Get(ctx, client.ObjectKey{Namespace: namespace, Name: secretName}, &targetSecret)
+
+	if err != nil && client.IgnoreNotFound(err) != nil {
+		log.Error(err, "Unable to fetch target secret", "targetSecret", secretName, "targetNamespace", namespace)
+
+		return "", "", err
+	}
+
+	exists := err == nil
+
+	if exists && !metav1.IsControlledBy(&targetSecret, owner) {
+		return "SecretConflict", fmt.Sprintf("Secret %s already exists and is not managed by the %s", secretName, kind), nil
+	}
+
+	if exists && targetSecret.Type == corev1.SecretTypeOpaque && equality.Semantic.DeepEqual(targetSecret.Data, data) {
+		current := true
+
+		for name, value := range annotations {
+			if targetSecret.Annotations[name] != value {
+				current = false
+			}
+		}
+
+		if current {
+			return "Synced", synced, nil
+		}
+	}
+
+	// When running in report-only mode, or writes to secrets are frozen,
+	// the secret is not written.
+
+	if w.ReportOnly || w.Config.WritesFrozen() {
+		verb := "create"
+
+		if exists {
+			verb = "update"
+		}
+
+		log.Info("Writes suspended, skipping write of secret for "+kind, "verb", verb, "name", owner.GetName(), "namespace", namespace, "targetSecret", secretName)
+
+		countSkippedWrite(w.Controller, verb, w.ReportOnly)
+
+		return "WritesSuspended", fmt.Sprintf("%s, secret %s would be %sd", skippedWriteReason(w.ReportOnly, w.Config), secretName, verb), nil
+	}
+
+	// The type of a secret is immutable so if it differs the secret needs to
+	// be recreated.
+
+	if exists && targetSecret.Type != corev1.SecretTypeOpaque {
+		if err := w.Delete(ctx, &targetSecret); client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Unable to delete target secret", "targetSecret", secretName, "targetNamespace", namespace)
+
+			return "", "", err
+		}
+
+		exists = false
+	}
+
+	if !exists {
+		targetSecret = corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretName,
+				Namespace: namespace,
+			},
+		}
+
+		if err := controllerutil.SetControllerReference(owner, &targetSecret, w.Scheme); err != nil {
+			return "", "", err
+		}
+	}
+
+	if targetSecret.Annotations == nil {
+		targetSecret.Annotations = make(map[string]string)
+	}
+
+	for name, value := range annotations {
+		if value != "" {
+			targetSecret.Annotations[name] = value
+		} else {
+			delete(targetSecret.Annotations, name)
+		}
+	}
+
+	targetSecret.Type = corev1.SecretTypeOpaque
+	targetSecret.Data = data
+
+	if exists {
+		err = w.Update(ctx, &targetSecret)
+	} else {
+		err = w.Create(ctx, &targetSecret)
+	}
+
+	if err != nil {
+		log.Error(err, "Unable to write target secret", "targetSecret", secretName, "targetNamespace", namespace)
+
+		return "", "", err
+	}
+
+	log.V(1).Info("Wrote secret for "+kind, "name", owner.GetName(), "namespace", namespace, "targetSecret", secretName)
+
+	return "Synced", synced, nil
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Maximum number of failing target namespaces listed in the status of a
+// SecretCopier, so the status doesn't grow beyond the size limit for an
+// object when a failure affects every target namespace.
+const maxFailedTargets = 50
+
+// Minimum interval between updates of the last failure time of a target
+// namespace whose failure is unchanged. Each status update triggers another
+// reconcile, so updating it on every reconcile would never settle.
+const failedTargetRefreshInterval = time.Minute
+
+// Describe the failure to copy to a target namespace.
+func newFailedTarget(ruleIndex int, targetNamespace string, result copyResult, err error) secretsv1beta1.SecretCopierFailedTarget {
+	failedTarget := secretsv1beta1.SecretCopierFailedTarget{
+		Namespace: targetNamespace,
+		Rule:      int32(ruleIndex),
+		Reason:    string(classifyCopyFailure(result, err)),
+	}
+
+	if err != nil {
+		failedTarget.Message = err.Error()
+	}
+
+	return failedTarget
+}
+
+// Merge the failures from the current reconcile with those previously
+// recorded in the status. The first failure time is carried over for targets
+// which were already failing, and the last failure time only refreshed if the
+// failure changed or the refresh interval has passed. Returns the capped list
+// and the number of failing targets which were left out.
+func mergeFailedTargets(previous []secretsv1beta1.SecretCopierFailedTarget, current []secretsv1beta1.SecretCopierFailedTarget, now metav1.Time) ([]secretsv1beta1.SecretCopierFailedTarget, int32) {
+	type targetKey struct {
+		rule      int32
+		namespace string
+	}
+
+	existing := make(map[targetKey]secretsv1beta1.SecretCopierFailedTarget, len(previous))
+
+	for _, failedTarget := range previous {
+		existing[targetKey{failedTarget.Rule, failedTarget.Namespace}] = failedTarget
+	}
+
+	merged := make([]secretsv1beta1.SecretCopierFailedTarget, 0, len(current))
+
+	for _, failedTarget := range current {
+		failedTarget.FirstFailureTime = now
+		failedTarget.LastFailureTime = now
+
+		if old, ok := existing[targetKey{failedTarget.Rule, failedTarget.Namespace}]; ok {
+			failedTarget.FirstFailureTime = old.FirstFailureTime
+
+			if old.Reason == failedTarget.Reason && old.Message == failedTarget.Message &&
+				now.Sub(old.LastFailureTime.Time) < failedTargetRefreshInterval {
+				failedTarget.LastFailureTime = old.LastFailureTime
+			}
+		}
+
+		merged = append(merged, failedTarget)
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		if merged[i].Rule != merged[j].Rule {
+			return merged[i].Rule < merged[j].Rule
+		}
+
+		return merged[i].Namespace < merged[j].Namespace
+	})
+
+	if len(merged) == 0 {
+		return nil, 0
+	}
+
+	if len(merged) > maxFailedTargets {
+		return merged[:maxFailedTargets], int32(len(merged) - maxFailedTargets)
+	}
+
+	return merged, 0
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Failed Targets", func() {
+	secretsResource := schema.GroupResource{Resource: "secrets"}
+
+	It("should classify the reason for a failure", func() {
+		forbidden := newPermissionDeniedError("create", "tenant-a", apierrors.NewForbidden(secretsResource, "registry", errors.New("denied")))
+
+		Expect(newFailedTarget(0, "tenant-a", copyForbidden, forbidden).Reason).To(Equal("Forbidden"))
+
+		invalid := apierrors.NewInvalid(schema.GroupKind{Kind: "Secret"}, "registry", nil)
+
+		failedTarget := newFailedTarget(1, "tenant-b", copyFailed, invalid)
+
+		Expect(failedTarget.Rule).To(Equal(int32(1)))
+		Expect(failedTarget.Namespace).To(Equal("tenant-b"))
+		Expect(failedTarget.Reason).To(Equal("Unknown"))
+		Expect(failedTarget.Message).To(Equal(invalid.Error()))
+
+		Expect(newFailedTarget(0, "tenant-c", copyFailed, errors.New("connection refused")).Reason).To(Equal("Unknown"))
+	})
+
+	It("should carry over failure times for targets which were already failing", func() {
+		first := metav1.NewTime(time.Date(2024, 9, 1, 10, 0, 0, 0, time.UTC))
+		recent := metav1.NewTime(first.Add(30 * time.Second))
+		now := metav1.NewTime(first.Add(45 * time.Second))
+		later := metav1.NewTime(first.Add(2 * time.Minute))
+
+		previous := []secretsv1beta1.SecretCopierFailedTarget{
+			{Namespace: "tenant-a", Rule: 0, Reason: "Forbidden", Message: "denied", FirstFailureTime: first, LastFailureTime: recent},
+			{Namespace: "tenant-b", Rule: 0, Reason: "Invalid", Message: "invalid", FirstFailureTime: first, LastFailureTime: recent},
+			{Namespace: "tenant-c", Rule: 0, Reason: "Forbidden", Message: "denied", FirstFailureTime: first, LastFailureTime: recent},
+		}
+
+		current := []secretsv1beta1.SecretCopierFailedTarget{
+			{Namespace: "tenant-d", Rule: 0, Reason: "Forbidden", Message: "denied"},
+			{Namespace: "tenant-b", Rule: 0, Reason: "Invalid", Message: "still invalid"},
+			{Namespace: "tenant-a", Rule: 0, Reason: "Forbidden", Message: "denied"},
+		}
+
+		merged, overflow := mergeFailedTargets(previous, current, now)
+
+		Expect(overflow).To(BeZero())
+		Expect(merged).To(Equal([]secretsv1beta1.SecretCopierFailedTarget{
+			{Namespace: "tenant-a", Rule: 0, Reason: "Forbidden", Message: "denied", FirstFailureTime: first, LastFailureTime: recent},
+			{Namespace: "tenant-b", Rule: 0, Reason: "Invalid", Message: "still invalid", FirstFailureTime: first, LastFailureTime: now},
+			{Namespace: "tenant-d", Rule: 0, Reason: "Forbidden", Message: "denied", FirstFailureTime: now, LastFailureTime: now},
+		}))
+
+		// An unchanged failure has its last failure time refreshed once the
+		// refresh interval has passed.
+
+		merged, _ = mergeFailedTargets(merged, current[2:], later)
+
+		Expect(merged).To(Equal([]secretsv1beta1.SecretCopierFailedTarget{
+			{Namespace: "tenant-a", Rule: 0, Reason: "Forbidden", Message: "denied", FirstFailureTime: first, LastFailureTime: later},
+		}))
+
+		merged, overflow = mergeFailedTargets(merged, nil, later)
+
+		Expect(merged).To(BeNil())
+		Expect(overflow).To(BeZero())
+	})
+
+	It("should cap the number of failed targets listed", func() {
+		var current []secretsv1beta1.SecretCopierFailedTarget
+
+		for i := 0; i < maxFailedTargets+25; i++ {
+			current = append(current, secretsv1beta1.SecretCopierFailedTarget{
+				Namespace: fmt.Sprintf("tenant-%03d", i),
+				Reason:    "Forbidden",
+			})
+		}
+
+		merged, overflow := mergeFailedTargets(nil, current, metav1.Now())
+
+		Expect(merged).To(HaveLen(maxFailedTargets))
+		Expect(merged[0].Namespace).To(Equal("tenant-000"))
+		Expect(overflow).To(Equal(int32(25)))
+	})
+})
@@ -0,0 +1,94 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"syscall"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Classify the failure to copy a secret to a target namespace into one of the
+// stable set of reasons used in the status, conditions, events and metrics.
+// Errors from the API server are classified by their status, with quota and
+// admission webhook rejections, which the API server also reports as
+// forbidden, being distinguished by their message.
+func classifyCopyFailure(result copyResult, err error) secretsv1beta1.CopyFailureReason {
+	var tooLarge *secretTooLargeError
+	var typeChanged *secretTypeChangedError
+
+	switch {
+	case errors.As(err, &tooLarge) || apierrors.IsRequestEntityTooLargeError(err):
+		return secretsv1beta1.CopyFailureOversize
+	case errors.As(err, &typeChanged):
+		return secretsv1beta1.CopyFailureConflict
+	case apiErrorMessageContains(err, "admission webhook"):
+		return secretsv1beta1.CopyFailureWebhookDenied
+	case apiErrorMessageContains(err, "exceeded quota"):
+		return secretsv1beta1.CopyFailureQuotaExceeded
+	case result == copyForbidden || apierrors.IsForbidden(err) || apierrors.IsUnauthorized(err):
+		return secretsv1beta1.CopyFailureForbidden
+	case apierrors.IsNotFound(err):
+		return secretsv1beta1.CopyFailureNotFound
+	case apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err):
+		return secretsv1beta1.CopyFailureConflict
+	case remoteUnreachable(err):
+		return secretsv1beta1.CopyFailureRemoteUnreachable
+	}
+
+	return secretsv1beta1.CopyFailureUnknown
+}
+
+// Determine whether the message of an error returned by the API server
+// contains the given text.
+func apiErrorMessageContains(err error, text string) bool {
+	var status apierrors.APIStatus
+
+	if !errors.As(err, &status) {
+		return false
+	}
+
+	return strings.Contains(status.Status().Message, text)
+}
+
+// Determine whether an error indicates that the API server could not be
+// reached, or didn't respond in time.
+func remoteUnreachable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var netErr net.Error
+
+	if errors.As(err, &netErr) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	return apierrors.IsServiceUnavailable(err) || apierrors.IsTimeout(err) || apierrors.IsServerTimeout(err)
+}
+
+// Count a failure to copy a secret to a target namespace against the reason
+// for the failure.
+func countCopyFailure(secretCopier *secretsv1beta1.SecretCopier, reason string) {
+	copyFailuresTotal.WithLabelValues(secretCopier.Name, reason).Inc()
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"syscall"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Copy Failure Reasons", func() {
+	secretsResource := schema.GroupResource{Resource: "secrets"}
+
+	It("should classify copy failures into stable reasons", func() {
+		failures := []struct {
+			result   copyResult
+			err      error
+			expected secretsv1beta1.CopyFailureReason
+		}{
+			{copyForbidden, newPermissionDeniedError("create", "tenant-a", apierrors.NewForbidden(secretsResource, "registry", errors.New("denied"))), secretsv1beta1.CopyFailureForbidden},
+			{copyFailed, apierrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, "tenant-a"), secretsv1beta1.CopyFailureNotFound},
+			{copyFailed, apierrors.NewConflict(secretsResource, "registry", errors.New("object was modified")), secretsv1beta1.CopyFailureConflict},
+			{copyFailed, fmt.Errorf("update failed: %w", &secretTypeChangedError{Namespace: "tenant-a", Name: "registry"}), secretsv1beta1.CopyFailureConflict},
+			{copyFailed, apierrors.NewForbidden(secretsResource, "registry", errors.New("exceeded quota: default-quota, requested: count/secrets=1")), secretsv1beta1.CopyFailureQuotaExceeded},
+			{copyFailed, &secretTooLargeError{Namespace: "tenant-a", Name: "registry", Size: 2 << 20}, secretsv1beta1.CopyFailureOversize},
+			{copyFailed, apierrors.NewForbidden(secretsResource, "registry", errors.New(`admission webhook "policy.example.com" denied the request`)), secretsv1beta1.CopyFailureWebhookDenied},
+			{copyFailed, &url.Error{Op: "Put", URL: "https://remote:6443", Err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}}, secretsv1beta1.CopyFailureRemoteUnreachable},
+			{copyFailed, fmt.Errorf("request failed: %w", context.DeadlineExceeded), secretsv1beta1.CopyFailureRemoteUnreachable},
+			{copyFailed, apierrors.NewServiceUnavailable("etcd unavailable"), secretsv1beta1.CopyFailureRemoteUnreachable},
+			{copyFailed, apierrors.NewInvalid(schema.GroupKind{Kind: "Secret"}, "registry", nil), secretsv1beta1.CopyFailureUnknown},
+		}
+
+		for _, failure := range failures {
+			Expect(classifyCopyFailure(failure.result, failure.err)).To(Equal(failure.expected), failure.err.Error())
+		}
+	})
+})
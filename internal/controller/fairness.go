@@ -0,0 +1,97 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Delay before reconciling a SecretCopier again when processing of a rule was
+// cut short because it used up its processing budget.
+const deferredRuleRequeueDelay = time.Second
+
+// Position reached by each rule when its processing was last cut short by the
+// processing budget, so the next reconciliation can resume from the following
+// target namespace rather than starting from the first again. The zero value
+// is ready to use.
+type ruleCursors struct {
+	mutex   sync.Mutex
+	entries map[string]string
+}
+
+// Key identifying a rule of a SecretCopier.
+func ruleCursorKey(secretCopierName string, ruleIndex int, rule *secretsv1beta1.SecretCopierRule) string {
+	return secretCopierName + "/" + ruleSourceKey(ruleIndex, rule)
+}
+
+// Rotate the sorted list of target namespaces so it starts from the first
+// namespace after the one the rule last reached, wrapping around to the start.
+func (c *ruleCursors) rotate(key string, namespaces []string) []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	cursor, ok := c.entries[key]
+
+	if !ok {
+		return namespaces
+	}
+
+	for i, namespace := range namespaces {
+		if namespace > cursor {
+			return append(namespaces[i:len(namespaces):len(namespaces)], namespaces[:i]...)
+		}
+	}
+
+	return namespaces
+}
+
+// Record the last target namespace processed by the rule before its
+// processing was cut short.
+func (c *ruleCursors) set(key string, namespace string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]string)
+	}
+
+	c.entries[key] = namespace
+}
+
+// Clear the position of the rule once it has processed all target namespaces.
+func (c *ruleCursors) reset(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.entries, key)
+}
+
+// Clear the positions of all rules of a SecretCopier.
+func (c *ruleCursors) forget(secretCopierName string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for key := range c.entries {
+		if strings.HasPrefix(key, secretCopierName+"/") {
+			delete(c.entries, key)
+		}
+	}
+}
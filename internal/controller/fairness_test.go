@@ -0,0 +1,99 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Rule Fairness", func() {
+	namespaces := []string{"alpha", "bravo", "charlie", "delta"}
+
+	rule := &secretsv1beta1.SecretCopierRule{SourceSecret: secretsv1beta1.SourceSecret{Name: "registry", Namespace: "source"}}
+
+	Context("When rotating target namespaces", func() {
+		It("should leave namespaces unchanged without a cursor", func() {
+			var cursors ruleCursors
+
+			Expect(cursors.rotate(ruleCursorKey("copier", 0, rule), namespaces)).To(Equal(namespaces))
+		})
+
+		It("should resume after the namespace last reached", func() {
+			var cursors ruleCursors
+
+			key := ruleCursorKey("copier", 0, rule)
+
+			cursors.set(key, "bravo")
+
+			Expect(cursors.rotate(key, namespaces)).To(Equal([]string{"charlie", "delta", "alpha", "bravo"}))
+			Expect(namespaces).To(Equal([]string{"alpha", "bravo", "charlie", "delta"}))
+		})
+
+		It("should resume correctly when the namespace last reached was deleted", func() {
+			var cursors ruleCursors
+
+			key := ruleCursorKey("copier", 0, rule)
+
+			cursors.set(key, "bravo-deleted")
+
+			Expect(cursors.rotate(key, namespaces)).To(Equal([]string{"charlie", "delta", "alpha", "bravo"}))
+
+			cursors.set(key, "echo")
+
+			Expect(cursors.rotate(key, namespaces)).To(Equal(namespaces))
+		})
+
+		It("should start from the first namespace once reset", func() {
+			var cursors ruleCursors
+
+			key := ruleCursorKey("copier", 1, rule)
+
+			cursors.set(key, "alpha")
+			cursors.reset(key)
+
+			Expect(cursors.rotate(key, namespaces)).To(Equal(namespaces))
+		})
+
+		It("should keep the cursor of a rule expanded from a pattern when it moves", func() {
+			var cursors ruleCursors
+
+			other := &secretsv1beta1.SecretCopierRule{SourceSecret: secretsv1beta1.SourceSecret{Name: "registry-other", Namespace: "source"}}
+
+			cursors.set(ruleCursorKey("copier", 0, rule), "bravo")
+
+			Expect(cursors.rotate(ruleCursorKey("copier", 0, other), namespaces)).To(Equal(namespaces))
+			Expect(cursors.rotate(ruleCursorKey("copier", 0, rule), namespaces)).To(Equal([]string{"charlie", "delta", "alpha", "bravo"}))
+		})
+
+		It("should forget only the rules of the named SecretCopier", func() {
+			var cursors ruleCursors
+
+			cursors.set(ruleCursorKey("copier", 0, rule), "alpha")
+			cursors.set(ruleCursorKey("copier", 1, rule), "alpha")
+			cursors.set(ruleCursorKey("copier-other", 0, rule), "alpha")
+
+			cursors.forget("copier")
+
+			Expect(cursors.rotate(ruleCursorKey("copier", 0, rule), namespaces)).To(Equal(namespaces))
+			Expect(cursors.rotate(ruleCursorKey("copier", 1, rule), namespaces)).To(Equal(namespaces))
+			Expect(cursors.rotate(ruleCursorKey("copier-other", 0, rule), namespaces)).To(Equal([]string{"bravo", "charlie", "delta", "alpha"}))
+		})
+	})
+})
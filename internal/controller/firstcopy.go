@@ -0,0 +1,183 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Prefix of the annotation added to a target namespace once the first copy
+// hook of a rule has run for the namespace. The remainder of the annotation
+// name is derived from the SecretCopier and rule, so each rule copying into
+// the namespace is tracked separately.
+const firstCopyAnnotationPrefix = "secrets-manager.advok8s.io/first-copy-"
+
+// HTTP client used to call first copy webhooks.
+var firstCopyHookClient = &http.Client{Timeout: 30 * time.Second}
+
+// Payload posted to a first copy webhook.
+type firstCopyHookRequest struct {
+	SecretCopier    string                      `json:"secretCopier"`
+	Rule            string                      `json:"rule"`
+	SourceSecret    secretsv1beta1.SourceSecret `json:"sourceSecret"`
+	TargetNamespace string                      `json:"targetNamespace"`
+	TargetSecret    string                      `json:"targetSecret"`
+}
+
+// Name of the annotation recording on a target namespace that the first copy
+// hook of the rule has run.
+func firstCopyAnnotation(secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule) string {
+	hash := sha256.Sum256([]byte(secretCopier.Name + "\x00" + ruleReference(rule)))
+
+	return firstCopyAnnotationPrefix + hex.EncodeToString(hash[:])[:16]
+}
+
+// Run the first copy hook of the rule for the target namespace if it hasn't
+// already run. The hook is run once the target secret is in sync, and whether
+// it has run is recorded by an annotation on the namespace, so it runs once
+// per namespace even across restarts of the manager. If calling the webhook or
+// updating the namespace fails, the hook is run again on a later
+// reconciliation. Failures are reported through events and don't fail the
+// copy of the target secret.
+func (r *SecretCopierReconciler) runFirstCopyHook(ctx context.Context, secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, targetNamespace string, targetSecretName string) {
+	if rule.OnFirstCopy == nil || r.writesSuspended() {
+		return
+	}
+
+	log := log.FromContext(ctx)
+
+	var namespace corev1.Namespace
+
+	if err := r.Get(ctx, client.ObjectKey{Name: targetNamespace}, &namespace); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Unable to fetch target namespace for first copy hook", "namespace", targetNamespace)
+		}
+
+		return
+	}
+
+	marker := firstCopyAnnotation(secretCopier, rule)
+
+	if _, found := namespace.Annotations[marker]; found {
+		return
+	}
+
+	hook := rule.OnFirstCopy
+
+	if err := callFirstCopyWebhook(ctx, hook.WebhookURL, firstCopyHookRequest{
+		SecretCopier:    secretCopier.Name,
+		Rule:            ruleReference(rule),
+		SourceSecret:    rule.SourceSecret,
+		TargetNamespace: targetNamespace,
+		TargetSecret:    targetSecretName,
+	}); err != nil {
+		log.Error(err, "First copy webhook failed", "namespace", targetNamespace)
+
+		r.Recorder.Eventf(secretCopier, corev1.EventTypeWarning, "FirstCopyHookFailed",
+			"First copy hook for rule %s failed for namespace %s: %s", ruleReference(rule), targetNamespace, err.Error())
+
+		return
+	}
+
+	// Labels and annotations of the hook are applied in the same patch as
+	// the marker annotation, so they are retried along with the webhook if
+	// the patch fails.
+
+	patch := client.MergeFrom(namespace.DeepCopy())
+
+	if len(hook.NamespaceLabels) != 0 && namespace.Labels == nil {
+		namespace.Labels = make(map[string]string, len(hook.NamespaceLabels))
+	}
+
+	for key, value := range hook.NamespaceLabels {
+		namespace.Labels[key] = value
+	}
+
+	if namespace.Annotations == nil {
+		namespace.Annotations = make(map[string]string, len(hook.NamespaceAnnotations)+1)
+	}
+
+	for key, value := range hook.NamespaceAnnotations {
+		namespace.Annotations[key] = value
+	}
+
+	namespace.Annotations[marker] = fmt.Sprintf("%s/%s", secretCopier.Name, ruleReference(rule))
+
+	if err := r.Patch(ctx, &namespace, patch); err != nil {
+		log.Error(err, "Unable to update target namespace for first copy hook", "namespace", targetNamespace)
+
+		r.Recorder.Eventf(secretCopier, corev1.EventTypeWarning, "FirstCopyHookFailed",
+			"First copy hook for rule %s failed for namespace %s: %s", ruleReference(rule), targetNamespace, err.Error())
+
+		return
+	}
+
+	log.V(1).Info("Ran first copy hook for target namespace", "namespace", targetNamespace, "rule", ruleReference(rule))
+
+	r.Recorder.Eventf(secretCopier, corev1.EventTypeNormal, "FirstCopyHookCompleted",
+		"First copy hook for rule %s completed for namespace %s", ruleReference(rule), targetNamespace)
+}
+
+// Post the request to the first copy webhook if one is configured. Returns an
+// error if the webhook could not be called or did not respond with a 2xx
+// status.
+func callFirstCopyWebhook(ctx context.Context, url string, hookRequest firstCopyHookRequest) error {
+	if url == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(hookRequest)
+
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := firstCopyHookClient.Do(request)
+
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("first copy webhook returned status %d", response.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("First Copy Hooks", func() {
+	ctx := context.Background()
+
+	secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "seeding-copier"}}
+
+	var k8sClient client.Client
+
+	var requests []firstCopyHookRequest
+
+	var status int
+
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		requests = nil
+		status = http.StatusOK
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var request firstCopyHookRequest
+
+			Expect(json.NewDecoder(r.Body).Decode(&request)).To(Succeed())
+
+			requests = append(requests, request)
+
+			w.WriteHeader(status)
+		}))
+
+		k8sClient = fake.NewClientBuilder().WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-a"}},
+		).Build()
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	newRule := func() *secretsv1beta1.SecretCopierRule {
+		return &secretsv1beta1.SecretCopierRule{
+			SourceSecret: secretsv1beta1.SourceSecret{Name: "registry", Namespace: "source"},
+			OnFirstCopy: &secretsv1beta1.FirstCopyHook{
+				NamespaceLabels:      map[string]string{"example.com/secrets-seeded": "true"},
+				NamespaceAnnotations: map[string]string{"example.com/seeded-by": "secrets-manager"},
+				WebhookURL:           server.URL,
+			},
+		}
+	}
+
+	It("should run the hook once for a target namespace", func() {
+		reconciler := &SecretCopierReconciler{Client: k8sClient, Recorder: record.NewFakeRecorder(10)}
+
+		rule := newRule()
+
+		reconciler.runFirstCopyHook(ctx, secretCopier, rule, "tenant-a", "registry")
+
+		Expect(requests).To(Equal([]firstCopyHookRequest{{
+			SecretCopier:    "seeding-copier",
+			Rule:            "source/registry",
+			SourceSecret:    rule.SourceSecret,
+			TargetNamespace: "tenant-a",
+			TargetSecret:    "registry",
+		}}))
+
+		namespace := &corev1.Namespace{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Name: "tenant-a"}, namespace)).To(Succeed())
+
+		Expect(namespace.Labels).To(HaveKeyWithValue("example.com/secrets-seeded", "true"))
+		Expect(namespace.Annotations).To(HaveKeyWithValue("example.com/seeded-by", "secrets-manager"))
+		Expect(namespace.Annotations).To(HaveKeyWithValue(firstCopyAnnotation(secretCopier, rule), "seeding-copier/source/registry"))
+
+		reconciler.runFirstCopyHook(ctx, secretCopier, rule, "tenant-a", "registry")
+
+		Expect(requests).To(HaveLen(1))
+	})
+
+	It("should run the hook again when the webhook fails", func() {
+		recorder := record.NewFakeRecorder(10)
+
+		reconciler := &SecretCopierReconciler{Client: k8sClient, Recorder: recorder}
+
+		rule := newRule()
+
+		status = http.StatusServiceUnavailable
+
+		reconciler.runFirstCopyHook(ctx, secretCopier, rule, "tenant-a", "registry")
+
+		Expect(recorder.Events).To(Receive(ContainSubstring("FirstCopyHookFailed")))
+
+		namespace := &corev1.Namespace{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Name: "tenant-a"}, namespace)).To(Succeed())
+
+		Expect(namespace.Labels).NotTo(HaveKey("example.com/secrets-seeded"))
+		Expect(namespace.Annotations).NotTo(HaveKey(firstCopyAnnotation(secretCopier, rule)))
+
+		status = http.StatusOK
+
+		reconciler.runFirstCopyHook(ctx, secretCopier, rule, "tenant-a", "registry")
+
+		Expect(requests).To(HaveLen(2))
+
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Name: "tenant-a"}, namespace)).To(Succeed())
+
+		Expect(namespace.Annotations).To(HaveKey(firstCopyAnnotation(secretCopier, rule)))
+	})
+
+	It("should track each rule separately", func() {
+		reconciler := &SecretCopierReconciler{Client: k8sClient, Recorder: record.NewFakeRecorder(10)}
+
+		rule := newRule()
+
+		other := newRule()
+		other.Name = "other"
+
+		Expect(firstCopyAnnotation(secretCopier, rule)).NotTo(Equal(firstCopyAnnotation(secretCopier, other)))
+
+		reconciler.runFirstCopyHook(ctx, secretCopier, rule, "tenant-a", "registry")
+		reconciler.runFirstCopyHook(ctx, secretCopier, other, "tenant-a", "registry")
+
+		Expect(requests).To(HaveLen(2))
+	})
+
+	It("should not run the hook in report only mode", func() {
+		reconciler := &SecretCopierReconciler{Client: k8sClient, Recorder: record.NewFakeRecorder(10), ReportOnly: true}
+
+		reconciler.runFirstCopyHook(ctx, secretCopier, newRule(), "tenant-a", "registry")
+
+		Expect(requests).To(BeEmpty())
+	})
+})
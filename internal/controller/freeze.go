@@ -0,0 +1,75 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Determine whether writes to target secrets are to be skipped, either
+// because the manager is running in report-only mode, or because writes to
+// secrets are frozen.
+func (r *SecretCopierReconciler) writesSuspended() bool {
+	return r.ReportOnly || r.Config.WritesFrozen()
+}
+
+// Count a write to a secret which was skipped, against report-only mode if
+// the manager is running in it, and otherwise against the write freeze.
+func countSkippedWrite(controllerName string, verb string, reportOnly bool) {
+	if reportOnly {
+		reportOnlySkippedWritesTotal.WithLabelValues(controllerName, verb).Inc()
+	} else {
+		frozenSkippedWritesTotal.WithLabelValues(controllerName, verb).Inc()
+	}
+}
+
+// Describe why writes to secrets are being skipped, for use in status
+// messages.
+func skippedWriteReason(reportOnly bool, config *ManagerConfig) string {
+	if reportOnly {
+		return "Manager is running in report-only mode"
+	}
+
+	return describeWriteFreeze(config.Settings())
+}
+
+// Describe the write freeze given by a set of settings.
+func describeWriteFreeze(settings *ManagerSettings) string {
+	message := "Writes to secrets are frozen until " + settings.WriteFreezeUntil.UTC().Format(time.RFC3339)
+
+	if settings.WriteFreezeReason != "" {
+		message += fmt.Sprintf(" (%s)", settings.WriteFreezeReason)
+	}
+
+	return message
+}
+
+// Construct the condition reporting that writes to secrets are frozen.
+func writesFrozenCondition(settings *ManagerSettings, generation int64) metav1.Condition {
+	return metav1.Condition{
+		Type:               secretsv1beta1.ConditionWritesFrozen,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "WriteFreeze",
+		Message:            describeWriteFreeze(settings),
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Write Freeze", func() {
+	ctx := context.Background()
+
+	secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "freeze-copier"}}
+
+	rule := &secretsv1beta1.SecretCopierRule{
+		SourceSecret:  secretsv1beta1.SourceSecret{Name: "registry", Namespace: "freeze-source"},
+		ReclaimPolicy: secretsv1beta1.ReclaimRetain,
+	}
+
+	newClient := func() client.Client {
+		return fake.NewClientBuilder().WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "freeze-source"},
+				Data:       map[string][]byte{"password": []byte("secret")},
+			},
+		).Build()
+	}
+
+	frozenConfig := func(until time.Time) *ManagerConfig {
+		config := NewManagerConfig(ManagerSettings{})
+
+		config.apply(config.merge(&secretsv1beta1.SecretsManagerConfigSpec{
+			WriteFreeze: &secretsv1beta1.WriteFreeze{
+				Until:  metav1.Time{Time: until},
+				Reason: "incident 42",
+			},
+		}))
+
+		return config
+	}
+
+	It("should only freeze writes until the freeze expires", func() {
+		now := time.Now()
+
+		settings := frozenConfig(now.Add(time.Hour)).Settings()
+
+		Expect(settings.WritesFrozen(now)).To(BeTrue())
+		Expect(settings.WriteFreezeRemaining(now)).To(Equal(time.Hour))
+		Expect(settings.WritesFrozen(now.Add(time.Hour))).To(BeFalse())
+		Expect(settings.WriteFreezeRemaining(now.Add(2 * time.Hour))).To(BeZero())
+
+		var config *ManagerConfig
+
+		Expect(config.WritesFrozen()).To(BeFalse())
+	})
+
+	It("should report target secrets which would be written while frozen", func() {
+		c := newClient()
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &SecretCopierReconciler{Client: c, Recorder: recorder, Config: frozenConfig(time.Now().Add(time.Hour))}
+
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "freeze-target")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyReportOnly))
+
+		var event string
+		Expect(recorder.Events).To(Receive(&event))
+		Expect(event).To(Equal("Normal WriteFrozen Would create secret registry in namespace freeze-target (added keys password)"))
+
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: "freeze-target", Name: "registry"}, &corev1.Secret{})).NotTo(Succeed())
+	})
+
+	It("should resume writes once the freeze has expired", func() {
+		c := newClient()
+		reconciler := &SecretCopierReconciler{Client: c, Recorder: record.NewFakeRecorder(10), Config: frozenConfig(time.Now().Add(-time.Second))}
+
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "freeze-target")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyCreated))
+
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: "freeze-target", Name: "registry"}, &corev1.Secret{})).To(Succeed())
+	})
+
+	It("should report the freeze in the status of the SecretsManagerConfig", func() {
+		until := metav1.Time{Time: time.Now().Add(time.Hour).Truncate(time.Second)}
+
+		config := &secretsv1beta1.SecretsManagerConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "freeze-config"},
+			Spec: secretsv1beta1.SecretsManagerConfigSpec{
+				WriteFreeze: &secretsv1beta1.WriteFreeze{Until: until, Reason: "incident 42"},
+			},
+		}
+
+		c := fake.NewClientBuilder().WithObjects(config).WithStatusSubresource(config).Build()
+
+		reconciler := &SecretsManagerConfigReconciler{Client: c, ConfigName: "freeze-config", Config: NewManagerConfig(ManagerSettings{})}
+
+		result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "freeze-config"}})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeNumerically(">", 59*time.Minute))
+		Expect(result.RequeueAfter).To(BeNumerically("<=", time.Hour))
+
+		Expect(reconciler.Config.WritesFrozen()).To(BeTrue())
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(config), config)).To(Succeed())
+
+		condition := meta.FindStatusCondition(config.Status.Conditions, secretsv1beta1.ConditionWritesFrozen)
+
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Message).To(Equal("Writes to secrets are frozen until " + until.UTC().Format(time.RFC3339) + " (incident 42)"))
+	})
+})
@@ -0,0 +1,305 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/internal/redact"
+)
+
+// Length of the content hash suffix appended to the name of target secrets.
+const hashSuffixLength = 10
+
+// Calculate the name of the target secret for a rule where the target secret
+// is named with a suffix derived from a hash of the content of the source
+// secret.
+func hashedTargetSecretName(rule *secretsv1beta1.SecretCopierRule, sourceSecret *corev1.Secret) string {
+	content, err := targetSecretContent(rule, sourceSecret)
+
+	if err != nil {
+		// The target secret can't be written while its templates fail to
+		// render, so any name will do for looking it up.
+
+		content = filterSourceSecretKeys(rule, sourceSecret)
+	}
+
+	return contentHashedTargetSecretName(rule, content)
+}
+
+// Calculate the name of a target secret named with a content hash suffix from
+// the content of the target secret, as given by targetSecretContent().
+func contentHashedTargetSecretName(rule *secretsv1beta1.SecretCopierRule, content *corev1.Secret) string {
+	return targetSecretName(rule) + "-" + sourceSecretRevision(content)[:hashSuffixLength]
+}
+
+// Determine whether an existing secret has the name a rule would give to the
+// target secret. Where the target secret is named with a content hash suffix,
+// all generations of the target secret are considered to match.
+func targetSecretNameMatches(rule *secretsv1beta1.SecretCopierRule, secret *corev1.Secret) bool {
+	if rule.TargetSecret.HashSuffix != nil {
+		return secret.Annotations["secrets-manager.advok8s.io/target-secret-name"] == targetSecretName(rule)
+	}
+
+	return secret.Name == targetSecretName(rule)
+}
+
+// Return the name of the current target secret for a rule. This only differs
+// from the name given by the rule when the target secret is named with a
+// content hash suffix, in which case the source secret must be read to
+// calculate it.
+func (r *SecretCopierReconciler) currentTargetSecretName(ctx context.Context, rule *secretsv1beta1.SecretCopierRule) string {
+	if rule.TargetSecret.HashSuffix == nil {
+		return targetSecretName(rule)
+	}
+
+	var sourceSecret corev1.Secret
+
+	if err := r.Get(ctx, client.ObjectKey{Namespace: rule.SourceSecret.Namespace, Name: rule.SourceSecret.Name}, &sourceSecret); err != nil {
+		return ""
+	}
+
+	return hashedTargetSecretName(rule, &sourceSecret)
+}
+
+// Copy the source secret to the target namespace as an immutable secret named
+// with a content hash suffix. An existing target secret with the same name
+// already holds the same content, so only its labels may need updating. Older
+// generations of the target secret beyond those to be retained are deleted.
+func (r *SecretCopierReconciler) copyHashedSecretToNamespace(ctx context.Context, reader client.Reader, secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, sourceSecret *corev1.Secret, targetNamespace string, backoffKey string) (copyResult, error) {
+	log := log.FromContext(ctx)
+
+	hashedName := contentHashedTargetSecretName(rule, sourceSecret)
+
+	var targetSecret corev1.Secret
+
+	err := reader.Get(ctx, client.ObjectKey{Namespace: targetNamespace, Name: hashedName}, &targetSecret)
+
+	if err != nil && client.IgnoreNotFound(err) != nil {
+		log.Error(err, "Unable to fetch target secret", "targetSecret", hashedName, "targetNamespace", targetNamespace)
+
+		if apierrors.IsForbidden(err) {
+			err = newPermissionDeniedError("get", targetNamespace, err)
+			r.recordPermissionDenied(secretCopier, hashedName, err)
+			return copyForbidden, err
+		}
+
+		return copyFailed, err
+	}
+
+	var result copyResult
+
+	switch {
+	case err != nil:
+		// The target secret does not exist, so create it.
+
+		targetSecret = r.newTargetSecret(secretCopier, rule, sourceSecret, hashedName, targetNamespace)
+
+		targetSecret.Annotations["secrets-manager.advok8s.io/target-secret-name"] = targetSecretName(rule)
+		targetSecret.Immutable = ptr.To(true)
+
+		if err := r.mutateTargetSecret(ctx, secretCopier, rule, sourceSecret, &targetSecret); err != nil {
+			log.Error(err, "Unable to mutate target secret", "targetSecret", hashedName, "targetNamespace", targetNamespace)
+			return copyFailed, err
+		}
+
+		log.V(1).Info("Creating hashed target secret", "targetSecret", hashedName, "targetNamespace", targetNamespace)
+
+		result, err = r.writeTargetSecret(ctx, secretCopier, rule, &targetSecret, "create", redact.DiffSecrets(nil, &targetSecret), backoffKey)
+
+	case r.targetSecretIgnoredBySecretCopier(secretCopier, rule, &targetSecret):
+		log.V(1).Info("Skipping update of target secret as labelled to be ignored", "targetSecret", hashedName, "targetNamespace", targetNamespace)
+		return copyIgnored, nil
+
+	case r.targetSecretAdoptable(secretCopier, rule, &targetSecret):
+		result, err = r.adoptTargetSecret(ctx, secretCopier, rule, sourceSecret, &targetSecret, backoffKey)
+
+	case !r.targetSecretManagedBySecretCopier(secretCopier, rule, &targetSecret):
+		log.V(1).Info("Skipping update of target secret as not managed by SecretCopier", "targetSecret", hashedName, "targetNamespace", targetNamespace)
+		return copySkipped, nil
+
+	default:
+		// The content of an immutable secret cannot change, but the labels
+		// and annotations can still be updated.
+
+		currentSecret := targetSecret.DeepCopy()
+
+		targetSecret.Labels = r.targetSecretLabels(rule, sourceSecret)
+
+		applyTargetSecretAnnotations(rule, &targetSecret)
+
+		if err := r.mutateTargetSecret(ctx, secretCopier, rule, sourceSecret, &targetSecret); err != nil {
+			log.Error(err, "Unable to mutate target secret", "targetSecret", hashedName, "targetNamespace", targetNamespace)
+			return copyFailed, err
+		}
+
+		if targetSecretContentEqual(currentSecret, &targetSecret) {
+			result = copyUnchanged
+			break
+		}
+
+		log.V(1).Info("Updating metadata of hashed target secret", "targetSecret", hashedName, "targetNamespace", targetNamespace)
+
+		result, err = r.writeTargetSecret(ctx, secretCopier, rule, &targetSecret, "update", redact.DiffSecrets(currentSecret, &targetSecret), backoffKey)
+	}
+
+	if err != nil || (result != copyCreated && result != copyUpdated && result != copyUnchanged) {
+		return result, err
+	}
+
+	if err := r.pruneHashedTargetSecrets(ctx, secretCopier, rule, targetNamespace, hashedName); err != nil {
+		log.Error(err, "Unable to delete old generations of target secret", "targetSecret", hashedName, "targetNamespace", targetNamespace)
+	}
+
+	return result, nil
+}
+
+// Create or update a target secret, subject to report-only mode and the secret
+// write rate limit. The changes being made are described in events and logs.
+func (r *SecretCopierReconciler) writeTargetSecret(ctx context.Context, secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, targetSecret *corev1.Secret, verb string, changes redact.ContentDiff, backoffKey string) (copyResult, error) {
+	log := log.FromContext(ctx)
+
+	if err := r.checkTargetSecretSize(secretCopier, targetSecret); err != nil {
+		log.Info("Unable to write target secret as it is too large", "verb", verb, "targetSecret", targetSecret.Name, "targetNamespace", targetSecret.Namespace, "size", secretDataSize(targetSecret))
+		return copyFailed, err
+	}
+
+	if r.writesSuspended() {
+		r.recordReportOnlyWrite(ctx, secretCopier, verb, targetSecret, changes)
+		return copyReportOnly, nil
+	}
+
+	if !r.allowSecretWrite() {
+		log.V(1).Info("Deferring write of target secret as write rate limit reached", "verb", verb, "targetSecret", targetSecret.Name, "targetNamespace", targetSecret.Namespace)
+		return copyThrottled, nil
+	}
+
+	secretsClient, err := r.secretsClient(secretCopier)
+
+	if err != nil {
+		return copyFailed, err
+	}
+
+	if verb == "create" {
+		err = secretsClient.Create(ctx, targetSecret, targetSecretWriteOwner(secretCopier, rule))
+	} else {
+		err = secretsClient.Update(ctx, targetSecret, targetSecretWriteOwner(secretCopier, rule))
+	}
+
+	if err != nil {
+		log.Error(err, "Unable to write target secret", "verb", verb, "targetSecret", targetSecret.Name, "targetNamespace", targetSecret.Namespace)
+
+		if apierrors.IsForbidden(err) {
+			err = newPermissionDeniedError(verb, targetSecret.Namespace, err)
+			r.recordPermissionDenied(secretCopier, targetSecret.Name, err)
+			return copyForbidden, err
+		}
+
+		return copyFailed, err
+	}
+
+	log.V(1).Info("Wrote target secret", "verb", verb, "targetSecret", targetSecret.Name, "targetNamespace", targetSecret.Namespace, "changes", changes.String())
+
+	r.deniedBackoff.succeeded(backoffKey)
+
+	if verb == "create" {
+		r.recordTargetNamespaceEvent(secretCopier, rule, targetSecret, "Created", "created", changes)
+		return copyCreated, nil
+	}
+
+	r.recordTargetNamespaceEvent(secretCopier, rule, targetSecret, "Updated", "updated", changes)
+
+	return copyUpdated, nil
+}
+
+// Delete generations of a hashed target secret in the target namespace beyond
+// the number to be retained. The current generation is always retained, with
+// other generations retained newest first. Generations labelled to be ignored
+// are never deleted.
+func (r *SecretCopierReconciler) pruneHashedTargetSecrets(ctx context.Context, secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, targetNamespace string, currentName string) error {
+	log := log.FromContext(ctx)
+
+	secretsClient, err := r.secretsClient(secretCopier)
+
+	if err != nil {
+		return err
+	}
+
+	var secrets corev1.SecretList
+
+	if err := secretsClient.List(ctx, &secrets, client.InNamespace(targetNamespace)); err != nil {
+		return err
+	}
+
+	generations := make([]*corev1.Secret, 0)
+
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+
+		if secret.Name != currentName && !targetSecretIgnored(secret) && r.targetSecretManagedBySecretCopier(secretCopier, rule, secret) && targetSecretNameMatches(rule, secret) {
+			generations = append(generations, secret)
+		}
+	}
+
+	retained := int(rule.TargetSecret.HashSuffix.RetainedGenerations) - 1
+
+	if retained < 0 {
+		retained = 0
+	}
+
+	if len(generations) <= retained {
+		return nil
+	}
+
+	sort.Slice(generations, func(i, j int) bool {
+		if !generations[i].CreationTimestamp.Equal(&generations[j].CreationTimestamp) {
+			return generations[j].CreationTimestamp.Before(&generations[i].CreationTimestamp)
+		}
+
+		return generations[i].Name > generations[j].Name
+	})
+
+	for _, secret := range generations[retained:] {
+		if r.writesSuspended() {
+			r.recordReportOnlyWrite(ctx, secretCopier, "delete", secret, redact.ContentDiff{})
+			continue
+		}
+
+		if !r.allowSecretWrite() {
+			log.V(1).Info("Deferring delete of old target secret as write rate limit reached", "targetSecret", secret.Name, "targetNamespace", targetNamespace)
+			return nil
+		}
+
+		log.V(1).Info("Deleting old generation of target secret", "targetSecret", secret.Name, "targetNamespace", targetNamespace)
+
+		if err := secretsClient.Delete(ctx, secret); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+
+		hashedSecretsPrunedTotal.WithLabelValues(secretCopier.Name).Inc()
+	}
+
+	return nil
+}
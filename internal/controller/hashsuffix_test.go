@@ -0,0 +1,142 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+var _ = Describe("SecretCopier Hash Suffix", func() {
+	ctx := context.Background()
+
+	// Test copying a secret as immutable secrets named with a content hash
+	// suffix, where older generations are deleted as the source changes.
+
+	Context("Copy secret with hash suffix #1", func() {
+		It("should create a new target secret for each change and prune old ones", func() {
+			sourceNamespaceName := "hash-source-namespace-1"
+			targetNamespaceName := "hash-target-namespace-1"
+			sourceSecretName := "hash-source-secret-1"
+
+			for _, name := range []string{sourceNamespaceName, targetNamespaceName} {
+				namespace := &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: name,
+					},
+				}
+				Expect(k8sClient.Create(ctx, namespace)).To(Succeed())
+			}
+
+			sourceSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      sourceSecretName,
+					Namespace: sourceNamespaceName,
+				},
+				Type: corev1.SecretTypeOpaque,
+				Data: map[string][]byte{
+					"key1": []byte("value1"),
+				},
+			}
+			Expect(k8sClient.Create(ctx, sourceSecret)).To(Succeed())
+
+			rule := secretsv1beta1.SecretCopierRule{
+				SourceSecret: secretsv1beta1.SourceSecret{
+					Namespace: sourceNamespaceName,
+					Name:      sourceSecretName,
+				},
+				TargetNamespaces: selectors.TargetNamespaces{
+					NameSelector: selectors.NameSelector{
+						MatchNames: []string{targetNamespaceName},
+					},
+				},
+				TargetSecret: secretsv1beta1.TargetSecret{
+					HashSuffix: &secretsv1beta1.TargetSecretHashSuffix{
+						RetainedGenerations: 2,
+					},
+				},
+				ReclaimPolicy: secretsv1beta1.ReclaimDelete,
+			}
+
+			secretCopier := &secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "hash-secret-copier-1",
+				},
+				Spec: secretsv1beta1.SecretCopierSpec{
+					Rules: []secretsv1beta1.SecretCopierRule{rule},
+				},
+			}
+			Expect(k8sClient.Create(ctx, secretCopier)).To(Succeed())
+
+			// Verify that an immutable target secret named with the hash of
+			// the content was created.
+
+			firstName := hashedTargetSecretName(&rule, sourceSecret)
+
+			Eventually(func() bool {
+				var targetSecret corev1.Secret
+				err := k8sClient.Get(ctx, client.ObjectKey{Namespace: targetNamespaceName, Name: firstName}, &targetSecret)
+				return err == nil && targetSecret.Immutable != nil && *targetSecret.Immutable
+			}).Should(BeTrue())
+
+			// Change the source secret twice and verify only the latest two
+			// generations of the target secret are kept.
+
+			for _, value := range []string{"value2", "value3"} {
+				Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(sourceSecret), sourceSecret)).To(Succeed())
+				sourceSecret.Data["key1"] = []byte(value)
+				Expect(k8sClient.Update(ctx, sourceSecret)).To(Succeed())
+
+				name := hashedTargetSecretName(&rule, sourceSecret)
+
+				Eventually(func() error {
+					var targetSecret corev1.Secret
+					return k8sClient.Get(ctx, client.ObjectKey{Namespace: targetNamespaceName, Name: name}, &targetSecret)
+				}).Should(Succeed())
+			}
+
+			Eventually(func() int {
+				var secrets corev1.SecretList
+				Expect(k8sClient.List(ctx, &secrets, client.InNamespace(targetNamespaceName))).To(Succeed())
+				count := 0
+				for i := range secrets.Items {
+					if targetSecretNameMatches(&rule, &secrets.Items[i]) {
+						count++
+					}
+				}
+				return count
+			}).Should(Equal(2))
+
+			Eventually(func() string {
+				Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(secretCopier), secretCopier)).To(Succeed())
+				if len(secretCopier.Status.Rules) == 0 {
+					return ""
+				}
+				return secretCopier.Status.Rules[0].TargetSecretName
+			}).Should(Equal(hashedTargetSecretName(&rule, sourceSecret)))
+		})
+	})
+})
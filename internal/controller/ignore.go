@@ -0,0 +1,83 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Label which when set to "true" on a target secret stops the controller from
+// updating, adopting or deleting it, so a namespace admin can take over the
+// copy in a single namespace.
+const ignoreLabel = "secrets-manager.advok8s.io/ignore"
+
+// Maximum number of ignored target secrets named in the Ignored condition.
+const maxIgnoredTargetsReported = 10
+
+// Determine whether a secret is labelled to be ignored.
+func targetSecretIgnored(secret *corev1.Secret) bool {
+	return secret.Labels[ignoreLabel] == "true"
+}
+
+// Determine whether an existing target secret which the SecretCopier would
+// otherwise update or adopt is labelled to be ignored. Secrets which aren't
+// managed by the SecretCopier are never touched anyway, so aren't reported as
+// ignored.
+func (r *SecretCopierReconciler) targetSecretIgnoredBySecretCopier(secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, targetSecret *corev1.Secret) bool {
+	if !targetSecretIgnored(targetSecret) {
+		return false
+	}
+
+	return r.targetSecretManagedBySecretCopier(secretCopier, rule, targetSecret) || r.targetSecretAdoptable(secretCopier, rule, targetSecret)
+}
+
+// Build the condition reporting the target secrets which are labelled to be
+// ignored, given as namespace/name.
+func ignoredCondition(ignoredTargets []string, generation int64) metav1.Condition {
+	// The targets are listed in order so the message doesn't change with the
+	// order in which target namespaces were processed.
+
+	names := slices.Clone(ignoredTargets)
+
+	slices.Sort(names)
+
+	if len(names) > maxIgnoredTargetsReported {
+		names = names[:maxIgnoredTargetsReported]
+	}
+
+	message := fmt.Sprintf("Target secrets labelled with %s are not being updated in %d target namespaces: %s",
+		ignoreLabel, len(ignoredTargets), strings.Join(names, ", "))
+
+	if len(ignoredTargets) > len(names) {
+		message += fmt.Sprintf(" and %d more", len(ignoredTargets)-len(names))
+	}
+
+	return metav1.Condition{
+		Type:               secretsv1beta1.ConditionIgnored,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "IgnoreLabel",
+		Message:            message,
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Ignored Target Secrets", func() {
+	ctx := context.Background()
+
+	secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "ignoring-copier"}}
+
+	rule := &secretsv1beta1.SecretCopierRule{
+		SourceSecret: secretsv1beta1.SourceSecret{Name: "registry", Namespace: "source"},
+	}
+
+	var k8sClient client.Client
+
+	var reconciler *SecretCopierReconciler
+
+	BeforeEach(func() {
+		k8sClient = fake.NewClientBuilder().WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "registry",
+					Namespace: "source",
+					Labels:    map[string]string{ignoreLabel: "true"},
+				},
+				Data: map[string][]byte{"password": []byte("original")},
+			},
+		).Build()
+
+		reconciler = &SecretCopierReconciler{Client: k8sClient, Recorder: record.NewFakeRecorder(10)}
+	})
+
+	updateSource := func(value string) {
+		sourceSecret := &corev1.Secret{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "source", Name: "registry"}, sourceSecret)).To(Succeed())
+
+		sourceSecret.Data["password"] = []byte(value)
+		Expect(k8sClient.Update(ctx, sourceSecret)).To(Succeed())
+	}
+
+	getTarget := func() *corev1.Secret {
+		targetSecret := &corev1.Secret{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "tenant-a", Name: "registry"}, targetSecret)).To(Succeed())
+
+		return targetSecret
+	}
+
+	It("should not copy the ignore label from the source secret", func() {
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant-a")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyCreated))
+		Expect(getTarget().Labels).NotTo(HaveKey(ignoreLabel))
+	})
+
+	It("should stop updating a target secret labelled to be ignored", func() {
+		_, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant-a")
+		Expect(err).NotTo(HaveOccurred())
+
+		targetSecret := getTarget()
+		targetSecret.Labels = map[string]string{ignoreLabel: "true"}
+		targetSecret.Data["password"] = []byte("local")
+		Expect(k8sClient.Update(ctx, targetSecret)).To(Succeed())
+
+		updateSource("rotated")
+
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant-a")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyIgnored))
+		Expect(getTarget().Data).To(HaveKeyWithValue("password", []byte("local")))
+
+		// Removing the label hands the target secret back to the controller.
+
+		targetSecret = getTarget()
+		delete(targetSecret.Labels, ignoreLabel)
+		Expect(k8sClient.Update(ctx, targetSecret)).To(Succeed())
+
+		result, err = reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant-a")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyUpdated))
+		Expect(getTarget().Data).To(HaveKeyWithValue("password", []byte("rotated")))
+	})
+
+	It("should not report unmanaged secrets as ignored", func() {
+		Expect(k8sClient.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "registry",
+				Namespace: "tenant-a",
+				Labels:    map[string]string{ignoreLabel: "true"},
+			},
+		})).To(Succeed())
+
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant-a")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copySkipped))
+	})
+
+	It("should name a limited number of ignored target secrets in the condition", func() {
+		targets := make([]string, 0)
+
+		for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l"} {
+			targets = append(targets, "tenant-"+name+"/registry")
+		}
+
+		condition := ignoredCondition(targets, 3)
+
+		Expect(condition.Type).To(Equal(secretsv1beta1.ConditionIgnored))
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Message).To(ContainSubstring("in 12 target namespaces: tenant-a/registry"))
+		Expect(condition.Message).To(HaveSuffix("tenant-j/registry and 2 more"))
+	})
+})
@@ -0,0 +1,63 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Determine whether a secret is immutable.
+func secretImmutable(secret *corev1.Secret) bool {
+	return secret.Immutable != nil && *secret.Immutable
+}
+
+// Replace an immutable target secret if its content no longer matches that of
+// the source secret, or the rule no longer requires it to be immutable. The
+// data of an immutable secret can't be changed, nor can the secret be made
+// mutable again, so the target secret is deleted and created again. Returns
+// false if the target secret didn't need replacing, in which case only its
+// metadata needs updating, which is still permitted.
+func (r *SecretCopierReconciler) replaceImmutableTargetSecret(ctx context.Context, secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, sourceSecret *corev1.Secret, targetSecret *corev1.Secret, backoffKey string) (copyResult, bool, error) {
+	log := log.FromContext(ctx)
+
+	replacement := r.newTargetSecret(secretCopier, rule, sourceSecret, targetSecret.Name, targetSecret.Namespace)
+
+	if err := r.mutateTargetSecret(ctx, secretCopier, rule, sourceSecret, &replacement); err != nil {
+		log.Error(err, "Unable to mutate target secret", "targetSecret", targetSecret.Name, "targetNamespace", targetSecret.Namespace)
+		return copyFailed, true, err
+	}
+
+	if secretImmutable(&replacement) && replacement.Type == targetSecret.Type && equality.Semantic.DeepEqual(replacement.Data, targetSecret.Data) {
+		return copyUnchanged, false, nil
+	}
+
+	reason := "it is immutable and the source secret changed"
+
+	if !secretImmutable(&replacement) {
+		reason = "it is immutable and the rule no longer requires an immutable secret"
+	}
+
+	result, err := r.replaceTargetSecret(ctx, secretCopier, rule, targetSecret, &replacement, backoffKey, reason)
+
+	return result, true, err
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Immutable Target Secrets", func() {
+	ctx := context.Background()
+
+	secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "immutable-copier"}}
+
+	var fakeClient client.Client
+	var recorder *record.FakeRecorder
+	var reconciler *SecretCopierReconciler
+	var rule *secretsv1beta1.SecretCopierRule
+
+	BeforeEach(func() {
+		fakeClient = fake.NewClientBuilder().WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "immutable-source"},
+			Data:       map[string][]byte{"password": []byte("first")},
+		}).Build()
+
+		recorder = record.NewFakeRecorder(10)
+
+		reconciler = &SecretCopierReconciler{Client: fakeClient, Recorder: recorder}
+
+		rule = &secretsv1beta1.SecretCopierRule{
+			SourceSecret:  secretsv1beta1.SourceSecret{Name: "registry", Namespace: "immutable-source"},
+			TargetSecret:  secretsv1beta1.TargetSecret{Immutable: true},
+			ReclaimPolicy: secretsv1beta1.ReclaimRetain,
+		}
+	})
+
+	copySecret := func() copyResult {
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "immutable-target")
+		Expect(err).NotTo(HaveOccurred())
+		return result
+	}
+
+	targetSecret := func() *corev1.Secret {
+		secret := &corev1.Secret{}
+		Expect(fakeClient.Get(ctx, client.ObjectKey{Namespace: "immutable-target", Name: "registry"}, secret)).To(Succeed())
+		return secret
+	}
+
+	changeSource := func(password string) {
+		source := &corev1.Secret{}
+		Expect(fakeClient.Get(ctx, client.ObjectKey{Namespace: "immutable-source", Name: "registry"}, source)).To(Succeed())
+
+		source.Data["password"] = []byte(password)
+
+		Expect(fakeClient.Update(ctx, source)).To(Succeed())
+	}
+
+	It("should create the target secret as immutable", func() {
+		Expect(copySecret()).To(Equal(copyCreated))
+
+		Expect(secretImmutable(targetSecret())).To(BeTrue())
+		Expect(copySecret()).To(Equal(copyUnchanged))
+	})
+
+	It("should recreate the target secret when the source secret changes", func() {
+		Expect(copySecret()).To(Equal(copyCreated))
+
+		changeSource("second")
+
+		Expect(copySecret()).To(Equal(copyUpdated))
+
+		secret := targetSecret()
+
+		Expect(secretImmutable(secret)).To(BeTrue())
+		Expect(secret.Data).To(HaveKeyWithValue("password", []byte("second")))
+
+		Expect(recorder.Events).To(Receive(Equal(
+			"Normal SecretRecreated Recreated secret registry in namespace immutable-target as it is immutable and the source secret changed")))
+	})
+
+	It("should update only the metadata in place when the content is unchanged", func() {
+		Expect(copySecret()).To(Equal(copyCreated))
+
+		rule.TargetSecret.Labels = map[string]string{"team": "payments"}
+
+		Expect(copySecret()).To(Equal(copyUpdated))
+
+		Expect(targetSecret().Labels).To(HaveKeyWithValue("team", "payments"))
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("should recreate the target secret as mutable when no longer required to be immutable", func() {
+		Expect(copySecret()).To(Equal(copyCreated))
+
+		rule.TargetSecret.Immutable = false
+
+		Expect(copySecret()).To(Equal(copyUpdated))
+
+		Expect(secretImmutable(targetSecret())).To(BeFalse())
+	})
+
+	It("should make an existing target secret immutable in place", func() {
+		rule.TargetSecret.Immutable = false
+
+		Expect(copySecret()).To(Equal(copyCreated))
+
+		rule.TargetSecret.Immutable = true
+
+		Expect(copySecret()).To(Equal(copyUpdated))
+
+		Expect(secretImmutable(targetSecret())).To(BeTrue())
+		Expect(recorder.Events).NotTo(Receive())
+	})
+})
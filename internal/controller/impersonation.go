@@ -0,0 +1,104 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"sync"
+
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Error returned when a SecretCopier names a service account to impersonate,
+// but the controller hasn't been configured with a means to impersonate it.
+var errImpersonationUnavailable = errors.New("impersonation of service accounts is not available")
+
+// Username a service account is authenticated as, which is the user to
+// impersonate to act as the service account.
+func serviceAccountUsername(serviceAccount *secretsv1beta1.SecretCopierServiceAccount) string {
+	return "system:serviceaccount:" + serviceAccount.Namespace + ":" + serviceAccount.Name
+}
+
+// ImpersonatingClients creates clients which impersonate a user, caching them
+// so each user only has a single client. The clients read directly from the
+// API server rather than through the informer cache of the manager, as the
+// cache is populated using the permissions of the controller.
+type ImpersonatingClients struct {
+	config  *rest.Config
+	options client.Options
+
+	mutex   sync.Mutex
+	clients map[string]client.Client
+}
+
+// NewImpersonatingClients returns a source of clients which impersonate a
+// user, created from the given REST config and client options.
+func NewImpersonatingClients(config *rest.Config, options client.Options) *ImpersonatingClients {
+	return &ImpersonatingClients{
+		config:  config,
+		options: options,
+		clients: make(map[string]client.Client),
+	}
+}
+
+// Client returns a client which impersonates the given user, with the extra
+// information added to the impersonated user.
+func (c *ImpersonatingClients) Client(username string, extra map[string][]string) (client.Client, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := impersonationCacheKey(username, extra)
+
+	if impersonating, found := c.clients[key]; found {
+		return impersonating, nil
+	}
+
+	config := rest.CopyConfig(c.config)
+
+	config.Impersonate = rest.ImpersonationConfig{UserName: username, Extra: extra}
+
+	impersonating, err := client.New(config, c.options)
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.clients[key] = impersonating
+
+	return impersonating, nil
+}
+
+// Return the client used to read source secrets and write target secrets for
+// the SecretCopier. If the SecretCopier names a service account, the client
+// impersonates the service account, so the SecretCopier can only access the
+// secrets the service account is permitted to by RBAC. The name of the
+// SecretCopier is added as extra information to the impersonated user, so
+// audit events for requests made for it identify the SecretCopier.
+func (r *SecretCopierReconciler) secretsClient(secretCopier *secretsv1beta1.SecretCopier) (client.Client, error) {
+	if secretCopier.Spec.ServiceAccount == nil {
+		return r.Client, nil
+	}
+
+	if r.ImpersonatingClient == nil {
+		return nil, errImpersonationUnavailable
+	}
+
+	return r.ImpersonatingClient(serviceAccountUsername(secretCopier.Spec.ServiceAccount), secretCopierImpersonationExtras(secretCopier))
+}
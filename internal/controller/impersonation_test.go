@@ -0,0 +1,144 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Service Account Impersonation", func() {
+	ctx := context.Background()
+
+	rule := &secretsv1beta1.SecretCopierRule{
+		SourceSecret: secretsv1beta1.SourceSecret{Name: "registry", Namespace: "source"},
+	}
+
+	var fakeClient client.Client
+	var recorder *record.FakeRecorder
+	var impersonatedUsers []string
+	var impersonatedExtras []map[string][]string
+	var reconciler *SecretCopierReconciler
+
+	BeforeEach(func() {
+		fakeClient = fake.NewClientBuilder().WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "source"},
+			Data:       map[string][]byte{"password": []byte("secret")},
+		}).Build()
+
+		recorder = record.NewFakeRecorder(10)
+
+		impersonatedUsers = nil
+		impersonatedExtras = nil
+
+		// The impersonating client is only permitted to create secrets in
+		// the namespace of the team owning the service account.
+
+		impersonatingClient := interceptor.NewClient(fakeClient.(client.WithWatch), interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				if obj.GetNamespace() != "team-a" {
+					return apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, obj.GetName(), errors.New("not permitted"))
+				}
+
+				return c.Create(ctx, obj, opts...)
+			},
+		})
+
+		reconciler = &SecretCopierReconciler{
+			Client:   fakeClient,
+			Recorder: recorder,
+			ImpersonatingClient: func(username string, extra map[string][]string) (client.Client, error) {
+				impersonatedUsers = append(impersonatedUsers, username)
+				impersonatedExtras = append(impersonatedExtras, extra)
+				return impersonatingClient, nil
+			},
+		}
+	})
+
+	It("should use the permissions of the controller without a service account", func() {
+		secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "copier"}}
+
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "team-b")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyCreated))
+		Expect(impersonatedUsers).To(BeEmpty())
+	})
+
+	It("should copy secrets where the service account is permitted", func() {
+		secretCopier := &secretsv1beta1.SecretCopier{
+			ObjectMeta: metav1.ObjectMeta{Name: "copier"},
+			Spec: secretsv1beta1.SecretCopierSpec{
+				ServiceAccount: &secretsv1beta1.SecretCopierServiceAccount{Namespace: "team-a", Name: "copier"},
+			},
+		}
+
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "team-a")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyCreated))
+		Expect(impersonatedUsers).To(ContainElement("system:serviceaccount:team-a:copier"))
+		Expect(impersonatedExtras).To(ContainElement(HaveKeyWithValue(secretCopierImpersonationExtra, []string{"copier"})))
+	})
+
+	It("should be denied copying secrets where the service account is not permitted", func() {
+		secretCopier := &secretsv1beta1.SecretCopier{
+			ObjectMeta: metav1.ObjectMeta{Name: "copier"},
+			Spec: secretsv1beta1.SecretCopierSpec{
+				ServiceAccount: &secretsv1beta1.SecretCopierServiceAccount{Namespace: "team-a", Name: "copier"},
+			},
+		}
+
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "team-b")
+
+		Expect(result).To(Equal(copyForbidden))
+		Expect(apierrors.IsForbidden(err)).To(BeTrue())
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning PermissionDenied")))
+
+		Expect(apierrors.IsNotFound(fakeClient.Get(ctx, client.ObjectKey{Namespace: "team-b", Name: "registry"}, &corev1.Secret{}))).To(BeTrue())
+	})
+
+	It("should fail when impersonation is not available", func() {
+		reconciler.ImpersonatingClient = nil
+
+		secretCopier := &secretsv1beta1.SecretCopier{
+			ObjectMeta: metav1.ObjectMeta{Name: "copier"},
+			Spec: secretsv1beta1.SecretCopierSpec{
+				ServiceAccount: &secretsv1beta1.SecretCopierServiceAccount{Namespace: "team-a", Name: "copier"},
+			},
+		}
+
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "team-a")
+
+		Expect(result).To(Equal(copyFailed))
+		Expect(err).To(MatchError(errImpersonationUnavailable))
+	})
+})
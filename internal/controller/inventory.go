@@ -0,0 +1,200 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// InventoryEntry describes a single target secret managed by a SecretCopier.
+type InventoryEntry struct {
+	// Name of the SecretCopier managing the target secret.
+	SecretCopier string `json:"secretCopier"`
+
+	// Index of the rule of the SecretCopier which produces the target
+	// secret, or -1 if no rule of the SecretCopier produces it any longer.
+	Rule int `json:"rule"`
+
+	// Name of the rule, if it has one.
+	RuleName string `json:"ruleName,omitempty"`
+
+	// Source secret the target secret was copied from.
+	SourceNamespace string `json:"sourceNamespace"`
+	SourceName      string `json:"sourceName"`
+
+	// The target secret.
+	TargetNamespace string `json:"targetNamespace"`
+	TargetName      string `json:"targetName"`
+
+	// Type of the target secret.
+	Type corev1.SecretType `json:"type"`
+
+	// When the target secret was last written.
+	LastSyncTime time.Time `json:"lastSyncTime"`
+
+	// Hash of the type and data of the target secret, which is the same as
+	// that of the source secret when the target secret is up to date.
+	Hash string `json:"hash"`
+}
+
+// Columns of the inventory when written as CSV.
+var inventoryCSVHeader = []string{
+	"secretCopier",
+	"rule",
+	"ruleName",
+	"sourceNamespace",
+	"sourceName",
+	"targetNamespace",
+	"targetName",
+	"type",
+	"lastSyncTime",
+	"hash",
+}
+
+// CollectInventory returns an entry for each target secret in the cluster
+// managed by a SecretCopier, ordered by SecretCopier and then target secret.
+// Target secrets are identified by the markers recorded on them, so target
+// secrets left behind by deleted SecretCopiers or rules are included.
+func CollectInventory(ctx context.Context, c client.Reader) ([]InventoryEntry, error) {
+	var secretCopiers secretsv1beta1.SecretCopierList
+
+	if err := c.List(ctx, &secretCopiers); err != nil {
+		return nil, err
+	}
+
+	copiers := make(map[string]*secretsv1beta1.SecretCopier, len(secretCopiers.Items))
+
+	for i := range secretCopiers.Items {
+		copiers[secretCopiers.Items[i].Name] = &secretCopiers.Items[i]
+	}
+
+	var secrets corev1.SecretList
+
+	if err := c.List(ctx, &secrets); err != nil {
+		return nil, err
+	}
+
+	entries := make([]InventoryEntry, 0)
+
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+
+		copierName := secret.Annotations[secretCopierMarkerAnnotation]
+
+		if copierName == "" {
+			continue
+		}
+
+		source, ok := targetSecretSource(secret)
+
+		if !ok {
+			continue
+		}
+
+		entry := InventoryEntry{
+			SecretCopier:    copierName,
+			Rule:            -1,
+			SourceNamespace: source.Namespace,
+			SourceName:      source.Name,
+			TargetNamespace: secret.Namespace,
+			TargetName:      secret.Name,
+			Type:            secret.Type,
+			LastSyncTime:    sourceSecretChangeTime(secret).UTC(),
+			Hash:            sourceSecretRevision(secret),
+		}
+
+		if secretCopier, found := copiers[copierName]; found {
+			for ruleIndex := range secretCopier.Spec.Rules {
+				rule := ruleForTargetSecret(&secretCopier.Spec.Rules[ruleIndex], secret)
+
+				if targetSecretSourceMatches(secret, rule.SourceSecret) && targetSecretNameMatches(rule, secret) {
+					entry.Rule = ruleIndex
+					entry.RuleName = secretCopier.Spec.Rules[ruleIndex].Name
+					break
+				}
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].SecretCopier != entries[j].SecretCopier {
+			return entries[i].SecretCopier < entries[j].SecretCopier
+		}
+
+		if entries[i].TargetNamespace != entries[j].TargetNamespace {
+			return entries[i].TargetNamespace < entries[j].TargetNamespace
+		}
+
+		return entries[i].TargetName < entries[j].TargetName
+	})
+
+	return entries, nil
+}
+
+// WriteInventoryCSV writes the inventory as CSV with a header row.
+func WriteInventoryCSV(w io.Writer, entries []InventoryEntry) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(inventoryCSVHeader); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		record := []string{
+			entry.SecretCopier,
+			strconv.Itoa(entry.Rule),
+			entry.RuleName,
+			entry.SourceNamespace,
+			entry.SourceName,
+			entry.TargetNamespace,
+			entry.TargetName,
+			string(entry.Type),
+			entry.LastSyncTime.Format(time.RFC3339),
+			entry.Hash,
+		}
+
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// WriteInventoryJSON writes the inventory as a JSON array.
+func WriteInventoryJSON(w io.Writer, entries []InventoryEntry) error {
+	encoder := json.NewEncoder(w)
+
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(entries)
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Managed Secret Inventory", func() {
+	ctx := context.Background()
+
+	created := metav1.NewTime(time.Date(2024, 9, 1, 10, 0, 0, 0, time.UTC))
+
+	targetSecret := func(namespace string, name string, copier string, source string) *corev1.Secret {
+		annotations := map[string]string{}
+
+		if copier != "" {
+			annotations["secrets-manager.advok8s.io/secret-copier"] = copier
+			annotations["secrets-manager.advok8s.io/secret-name"] = source
+		}
+
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         namespace,
+				Annotations:       annotations,
+				CreationTimestamp: created,
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{"password": []byte("secret")},
+		}
+	}
+
+	var fakeClient client.Client
+
+	BeforeEach(func() {
+		fakeClient = fake.NewClientBuilder().WithObjects(
+			&secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry-copier"},
+				Spec: secretsv1beta1.SecretCopierSpec{
+					Rules: []secretsv1beta1.SecretCopierRule{
+						{
+							Name:         "registry",
+							SourceSecret: secretsv1beta1.SourceSecret{Namespace: "secrets", Name: "registry"},
+						},
+					},
+				},
+			},
+			targetSecret("tenant-b", "registry", "registry-copier", "secrets/registry"),
+			targetSecret("tenant-a", "registry", "registry-copier", "secrets/registry"),
+			targetSecret("tenant-a", "database", "deleted-copier", "secrets/database"),
+			targetSecret("tenant-a", "unmanaged", "", ""),
+		).Build()
+	})
+
+	It("should list managed secrets ordered by SecretCopier and target", func() {
+		entries, err := CollectInventory(ctx, fakeClient)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(3))
+
+		Expect(entries[0].SecretCopier).To(Equal("deleted-copier"))
+		Expect(entries[0].Rule).To(Equal(-1))
+
+		Expect(entries[1]).To(Equal(InventoryEntry{
+			SecretCopier:    "registry-copier",
+			Rule:            0,
+			RuleName:        "registry",
+			SourceNamespace: "secrets",
+			SourceName:      "registry",
+			TargetNamespace: "tenant-a",
+			TargetName:      "registry",
+			Type:            corev1.SecretTypeOpaque,
+			LastSyncTime:    created.Time,
+			Hash:            sourceSecretRevision(targetSecret("", "", "", "")),
+		}))
+
+		Expect(entries[2].TargetNamespace).To(Equal("tenant-b"))
+	})
+
+	It("should write the inventory as CSV", func() {
+		entries, err := CollectInventory(ctx, fakeClient)
+		Expect(err).NotTo(HaveOccurred())
+
+		var out bytes.Buffer
+
+		Expect(WriteInventoryCSV(&out, entries)).To(Succeed())
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+
+		Expect(lines).To(HaveLen(4))
+		Expect(lines[0]).To(Equal("secretCopier,rule,ruleName,sourceNamespace,sourceName,targetNamespace,targetName,type,lastSyncTime,hash"))
+		Expect(lines[2]).To(HavePrefix("registry-copier,0,registry,secrets,registry,tenant-a,registry,Opaque,2024-09-01T10:00:00Z,"))
+	})
+
+	It("should write the inventory as JSON", func() {
+		entries, err := CollectInventory(ctx, fakeClient)
+		Expect(err).NotTo(HaveOccurred())
+
+		var out bytes.Buffer
+
+		Expect(WriteInventoryJSON(&out, entries)).To(Succeed())
+
+		var decoded []InventoryEntry
+
+		Expect(json.Unmarshal(out.Bytes(), &decoded)).To(Succeed())
+		Expect(decoded).To(Equal(entries))
+	})
+})
@@ -0,0 +1,46 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Return the source secret with only the data keys the rule copies to the
+// target secret. The source secret is returned unchanged if the rule doesn't
+// filter keys, otherwise a copy is returned. Everything derived from the
+// content of the source secret, such as the revision used to name target
+// secrets with a hash suffix, is calculated from the filtered secret, so a
+// change to a key which isn't copied doesn't result in the target secret
+// being written.
+func filterSourceSecretKeys(rule *secretsv1beta1.SecretCopierRule, sourceSecret *corev1.Secret) *corev1.Secret {
+	if !rule.TargetSecret.FiltersKeys() {
+		return sourceSecret
+	}
+
+	filtered := sourceSecret.DeepCopy()
+
+	for key := range filtered.Data {
+		if !rule.TargetSecret.CopiesKey(key) {
+			delete(filtered.Data, key)
+		}
+	}
+
+	return filtered
+}
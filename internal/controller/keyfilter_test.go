@@ -0,0 +1,112 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Key Filtering", func() {
+	ctx := context.Background()
+
+	newSourceSecret := func() *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: "source"},
+			Data: map[string][]byte{
+				"ca.crt":  []byte("certificate"),
+				"tls.crt": []byte("public"),
+				"tls.key": []byte("private"),
+			},
+		}
+	}
+
+	newRule := func(includeKeys []string, excludeKeys []string) *secretsv1beta1.SecretCopierRule {
+		return &secretsv1beta1.SecretCopierRule{
+			SourceSecret: secretsv1beta1.SourceSecret{Name: "credentials", Namespace: "source"},
+			TargetSecret: secretsv1beta1.TargetSecret{IncludeKeys: includeKeys, ExcludeKeys: excludeKeys},
+		}
+	}
+
+	It("should return the source secret unchanged without filters", func() {
+		sourceSecret := newSourceSecret()
+
+		Expect(filterSourceSecretKeys(newRule(nil, nil), sourceSecret)).To(BeIdenticalTo(sourceSecret))
+	})
+
+	It("should keep only included keys less excluded keys", func() {
+		sourceSecret := newSourceSecret()
+
+		filtered := filterSourceSecretKeys(newRule([]string{"*.crt", "tls.key"}, []string{"tls.*"}), sourceSecret)
+
+		Expect(filtered.Data).To(Equal(map[string][]byte{"ca.crt": []byte("certificate")}))
+		Expect(sourceSecret.Data).To(HaveLen(3))
+	})
+
+	It("should not rename hashed target secrets when an excluded key changes", func() {
+		rule := newRule(nil, []string{"*.key"})
+
+		sourceSecret := newSourceSecret()
+		name := hashedTargetSecretName(rule, sourceSecret)
+
+		sourceSecret.Data["tls.key"] = []byte("rotated")
+		Expect(hashedTargetSecretName(rule, sourceSecret)).To(Equal(name))
+
+		sourceSecret.Data["tls.crt"] = []byte("rotated")
+		Expect(hashedTargetSecretName(rule, sourceSecret)).NotTo(Equal(name))
+	})
+
+	It("should copy only the selected keys to the target namespace", func() {
+		fakeClient := fake.NewClientBuilder().WithObjects(newSourceSecret()).Build()
+
+		reconciler := &SecretCopierReconciler{
+			Client:   fakeClient,
+			Recorder: record.NewFakeRecorder(10),
+		}
+
+		secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "copier"}}
+
+		rule := newRule([]string{"ca.crt"}, nil)
+
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyCreated))
+
+		var targetSecret corev1.Secret
+		Expect(fakeClient.Get(ctx, client.ObjectKey{Namespace: "tenant", Name: "credentials"}, &targetSecret)).To(Succeed())
+		Expect(targetSecret.Data).To(Equal(map[string][]byte{"ca.crt": []byte("certificate")}))
+
+		// A later copy finds the target secret in sync even though it holds
+		// fewer keys than the source secret.
+
+		result, err = reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyUnchanged))
+	})
+})
@@ -0,0 +1,127 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Tracks, for each rule of each SecretCopier, the revision of the source
+// secret which is being propagated to target namespaces and when the source
+// secret was changed to it. The zero value is ready to use.
+type propagationTracker struct {
+	mutex   sync.Mutex
+	entries map[string]map[string]propagationEntry
+}
+
+type propagationEntry struct {
+	revision string
+	changed  time.Time
+}
+
+// Record that target secrets were written for the revision of the source
+// secret. Where the revision is already being tracked, the time it was
+// changed is left as is.
+func (t *propagationTracker) written(secretCopier string, rule string, revision string, changed time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.entries == nil {
+		t.entries = make(map[string]map[string]propagationEntry)
+	}
+
+	if t.entries[secretCopier] == nil {
+		t.entries[secretCopier] = make(map[string]propagationEntry)
+	}
+
+	if entry, ok := t.entries[secretCopier][rule]; ok && entry.revision == revision {
+		return
+	}
+
+	t.entries[secretCopier][rule] = propagationEntry{revision: revision, changed: changed}
+}
+
+// Record that all target secrets hold the revision of the source secret. If
+// target secrets were written for the revision, returns the time since the
+// source secret was changed to it and stops tracking it.
+func (t *propagationTracker) completed(secretCopier string, rule string, revision string, now time.Time) (time.Duration, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	entry, ok := t.entries[secretCopier][rule]
+
+	if !ok || entry.revision != revision {
+		return 0, false
+	}
+
+	delete(t.entries[secretCopier], rule)
+
+	return now.Sub(entry.changed), true
+}
+
+// Stop tracking all rules of a SecretCopier which has been deleted.
+func (t *propagationTracker) forget(secretCopier string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.entries, secretCopier)
+}
+
+// Determine when the source secret was last changed. The API server records
+// the time of each write in the managed fields of the secret, so the latest
+// of these is used, falling back to when the secret was created.
+func sourceSecretChangeTime(secret *corev1.Secret) time.Time {
+	changed := secret.CreationTimestamp.Time
+
+	for _, entry := range secret.ManagedFields {
+		if entry.Time != nil && entry.Time.After(changed) {
+			changed = entry.Time.Time
+		}
+	}
+
+	return changed
+}
+
+// Record the progress of propagating the source secret of a rule to its
+// target namespaces. Once all target namespaces hold the current revision of
+// the source secret, the time taken since the source secret was changed is
+// observed by the propagation latency metric. Only revisions for which target
+// secrets were written by the controller are measured, so target secrets
+// which were already up to date when the manager started are not counted.
+func (r *SecretCopierReconciler) recordPropagation(secretCopier *secretsv1beta1.SecretCopier, ruleIndex int, rule *secretsv1beta1.SecretCopierRule, sourceSecret *corev1.Secret, written int, complete bool) {
+	revision := sourceSecretRevision(sourceSecret)
+
+	key := ruleSourceKey(ruleIndex, rule)
+
+	if written > 0 {
+		r.propagation.written(secretCopier.Name, key, revision, sourceSecretChangeTime(sourceSecret))
+	}
+
+	if !complete {
+		return
+	}
+
+	if latency, ok := r.propagation.completed(secretCopier.Name, key, revision, time.Now()); ok {
+		propagationLatency.WithLabelValues(secretCopier.Name, strconv.Itoa(ruleIndex)).Observe(max(latency, 0).Seconds())
+	}
+}
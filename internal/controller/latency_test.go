@@ -0,0 +1,93 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Propagation Latency", func() {
+	changed := time.Date(2024, 9, 1, 10, 0, 0, 0, time.UTC)
+
+	It("should measure from when targets were first written for a revision", func() {
+		var tracker propagationTracker
+
+		tracker.written("copier", "0/source/registry", "rev1", changed)
+
+		// Further writes for the same revision keep the original change time.
+
+		tracker.written("copier", "0/source/registry", "rev1", changed.Add(time.Minute))
+
+		latency, ok := tracker.completed("copier", "0/source/registry", "rev1", changed.Add(90*time.Second))
+
+		Expect(ok).To(BeTrue())
+		Expect(latency).To(Equal(90 * time.Second))
+
+		// The revision is only measured once.
+
+		_, ok = tracker.completed("copier", "0/source/registry", "rev1", changed.Add(2*time.Minute))
+
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should not measure revisions for which no targets were written", func() {
+		var tracker propagationTracker
+
+		_, ok := tracker.completed("copier", "0/source/registry", "rev1", changed)
+
+		Expect(ok).To(BeFalse())
+
+		tracker.written("copier", "0/source/registry", "rev1", changed)
+
+		_, ok = tracker.completed("copier", "0/source/registry", "rev2", changed)
+
+		Expect(ok).To(BeFalse())
+
+		tracker.forget("copier")
+
+		_, ok = tracker.completed("copier", "0/source/registry", "rev1", changed)
+
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should use the latest write to the source secret as the change time", func() {
+		updated := metav1.NewTime(changed.Add(time.Hour))
+		earlier := metav1.NewTime(changed.Add(time.Minute))
+
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				CreationTimestamp: metav1.NewTime(changed),
+				ManagedFields: []metav1.ManagedFieldsEntry{
+					{Manager: "kubectl", Time: &updated},
+					{Manager: "rotator", Time: &earlier},
+				},
+			},
+		}
+
+		Expect(sourceSecretChangeTime(secret)).To(Equal(updated.Time))
+
+		secret.ManagedFields = nil
+
+		Expect(sourceSecretChangeTime(secret)).To(Equal(changed))
+	})
+})
@@ -0,0 +1,357 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/internal/version"
+)
+
+// Maximum number of individual secrets listed in each of the orphans,
+// conflicts and stale lists of the report, so the report doesn't grow beyond
+// the size limit for an object.
+const maxReportedSecrets = 100
+
+// ManagedSecretsReportReconciler maintains a cluster scoped ManagedSecretsReport
+// summarizing all secrets managed by the secrets manager.
+type ManagedSecretsReportReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Name of the ManagedSecretsReport to maintain.
+	ReportName string
+
+	// Interval at which the report is regenerated.
+	Interval time.Duration
+
+	// Window over which changes to SecretCopier objects are coalesced into a
+	// single regeneration of the report.
+	CoalesceWindow time.Duration
+}
+
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=managedsecretsreports,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=managedsecretsreports/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=secretcopiers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+
+// Reconcile the ManagedSecretsReport by creating it if it doesn't exist and
+// regenerating its status from the current set of secrets, namespaces and
+// SecretCopier objects.
+func (r *ManagedSecretsReportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	if req.Name != r.ReportName {
+		return ctrl.Result{}, nil
+	}
+
+	// Fetch the report, creating it if it doesn't exist.
+
+	var report secretsv1beta1.ManagedSecretsReport
+
+	if err := r.Get(ctx, req.NamespacedName, &report); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Unable to fetch ManagedSecretsReport", "name", req.Name)
+			return ctrl.Result{}, err
+		}
+
+		report = secretsv1beta1.ManagedSecretsReport{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: r.ReportName,
+			},
+		}
+
+		if err := r.Create(ctx, &report); err != nil {
+			log.Error(err, "Unable to create ManagedSecretsReport", "name", req.Name)
+			return ctrl.Result{}, err
+		}
+
+		log.V(1).Info("Created ManagedSecretsReport", "name", req.Name)
+	}
+
+	status, err := r.generateReport(ctx)
+
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Only update the report if the summary has changed, ignoring the time
+	// at which it was generated.
+
+	status.LastUpdateTime = report.Status.LastUpdateTime
+
+	if report.Status.LastUpdateTime.IsZero() || !equality.Semantic.DeepEqual(report.Status, status) {
+		status.LastUpdateTime = metav1.Now()
+
+		report.Status = status
+
+		if err := r.Status().Update(ctx, &report); err != nil {
+			log.Error(err, "Unable to update ManagedSecretsReport status", "name", req.Name)
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: r.Interval}, nil
+}
+
+// Generate the status of the report from the current state of the cluster.
+func (r *ManagedSecretsReportReconciler) generateReport(ctx context.Context) (secretsv1beta1.ManagedSecretsReportStatus, error) {
+	log := log.FromContext(ctx)
+
+	var status secretsv1beta1.ManagedSecretsReportStatus
+
+	info := version.Get()
+
+	status.Manager = secretsv1beta1.ManagerBuildInfo{
+		Version:   info.Version,
+		GitCommit: info.GitCommit,
+		BuildDate: info.BuildDate,
+	}
+
+	var secretCopiers secretsv1beta1.SecretCopierList
+
+	if err := r.List(ctx, &secretCopiers); err != nil {
+		log.Error(err, "Unable to list SecretCopier objects")
+		return status, err
+	}
+
+	var namespaces corev1.NamespaceList
+
+	if err := r.List(ctx, &namespaces); err != nil {
+		log.Error(err, "Unable to list namespaces")
+		return status, err
+	}
+
+	var secrets corev1.SecretList
+
+	if err := r.List(ctx, &secrets); err != nil {
+		log.Error(err, "Unable to list secrets")
+		return status, err
+	}
+
+	secretsByKey := make(map[client.ObjectKey]*corev1.Secret, len(secrets.Items))
+
+	for i := range secrets.Items {
+		secretsByKey[client.ObjectKeyFromObject(&secrets.Items[i])] = &secrets.Items[i]
+	}
+
+	summaries := make(map[string]*secretsv1beta1.ManagedSecretsCopierSummary, len(secretCopiers.Items))
+	copiers := make(map[string]*secretsv1beta1.SecretCopier, len(secretCopiers.Items))
+
+	for i := range secretCopiers.Items {
+		secretCopier := &secretCopiers.Items[i]
+
+		copiers[secretCopier.Name] = secretCopier
+		summaries[secretCopier.Name] = &secretsv1beta1.ManagedSecretsCopierSummary{Name: secretCopier.Name}
+	}
+
+	appendSecret := func(list []secretsv1beta1.ManagedSecretReference, secret *corev1.Secret, secretCopier string) []secretsv1beta1.ManagedSecretReference {
+		if len(list) >= maxReportedSecrets {
+			return list
+		}
+
+		return append(list, secretsv1beta1.ManagedSecretReference{
+			Namespace:    secret.Namespace,
+			Name:         secret.Name,
+			SecretCopier: secretCopier,
+		})
+	}
+
+	// Classify each secret carrying the annotations added by the secrets
+	// manager as being managed, orphaned or stale.
+
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+
+		if secret.Annotations["secrets-manager.advok8s.io/secret-claim"] != "" {
+			status.TotalManagedSecrets++
+			status.ClaimedSecrets++
+
+			continue
+		}
+
+		copierName := secret.Annotations["secrets-manager.advok8s.io/secret-copier"]
+
+		if copierName == "" {
+			continue
+		}
+
+		status.TotalManagedSecrets++
+
+		var rule *secretsv1beta1.SecretCopierRule
+
+		if secretCopier, found := copiers[copierName]; found {
+			rule = secretCopierRuleForTarget(secretCopier, secret)
+		}
+
+		if rule == nil {
+			status.OrphanedSecrets++
+			status.Orphans = appendSecret(status.Orphans, secret, copierName)
+
+			continue
+		}
+
+		summary := summaries[copierName]
+
+		summary.ManagedSecrets++
+
+		sourceSecret, found := secretsByKey[sourceSecretKey(secret)]
+
+		// Earlier generations of target secrets named with a content hash
+		// suffix are retained intentionally and are not considered stale.
+
+		hashed := secret.Annotations["secrets-manager.advok8s.io/target-secret-name"] != ""
+
+		if found {
+			content, err := targetSecretContent(rule, sourceSecret)
+
+			if err != nil {
+				found = false
+			} else {
+				sourceSecret = content
+			}
+		}
+
+		if !found || (!hashed && (sourceSecret.Type != secret.Type || !equality.Semantic.DeepEqual(sourceSecret.Data, secret.Data))) {
+			summary.StaleTargets++
+
+			status.StaleSecrets++
+			status.Stale = appendSecret(status.Stale, secret, copierName)
+		}
+	}
+
+	// Look for target secrets of each rule in the namespaces matched by the
+	// rule which exist but are not managed by the SecretCopier.
+
+	for i := range secretCopiers.Items {
+		secretCopier := &secretCopiers.Items[i]
+
+		for j := range secretCopier.Spec.Rules {
+			rule := &secretCopier.Spec.Rules[j]
+
+			if rule.TargetCluster != nil {
+				continue
+			}
+
+			matcher := rule.TargetNamespaces.Compile()
+
+			for k := range namespaces.Items {
+				namespace := &namespaces.Items[k]
+
+				if rule.SkipsNamespace(namespace) || namespace.Name == rule.SourceSecret.Namespace {
+					continue
+				}
+
+				if !matcher.Matches(namespace) {
+					continue
+				}
+
+				secret, found := secretsByKey[client.ObjectKey{Namespace: namespace.Name, Name: targetSecretName(rule)}]
+
+				if !found {
+					continue
+				}
+
+				if secret.Annotations[secretCopierMarkerAnnotation] == secretCopier.Name && targetSecretSourceMatches(secret, rule.SourceSecret) {
+					continue
+				}
+
+				summaries[secretCopier.Name].Conflicts++
+
+				status.ConflictingSecrets++
+				status.Conflicts = appendSecret(status.Conflicts, secret, secretCopier.Name)
+			}
+		}
+	}
+
+	for _, summary := range summaries {
+		status.SecretCopiers = append(status.SecretCopiers, *summary)
+	}
+
+	sort.Slice(status.SecretCopiers, func(i, j int) bool {
+		return status.SecretCopiers[i].Name < status.SecretCopiers[j].Name
+	})
+
+	return status, nil
+}
+
+// Key of the source secret recorded in the annotations of a target secret.
+func sourceSecretKey(secret *corev1.Secret) client.ObjectKey {
+	source, _ := targetSecretSource(secret)
+
+	return source
+}
+
+// Find the rule of a SecretCopier which would produce the target secret,
+// returning nil if the SecretCopier no longer has one.
+func secretCopierRuleForTarget(secretCopier *secretsv1beta1.SecretCopier, secret *corev1.Secret) *secretsv1beta1.SecretCopierRule {
+	for i := range secretCopier.Spec.Rules {
+		rule := ruleForTargetSecret(&secretCopier.Spec.Rules[i], secret)
+
+		if targetSecretSourceMatches(secret, rule.SourceSecret) && targetSecretNameMatches(rule, secret) {
+			return rule
+		}
+	}
+
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ManagedSecretsReportReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Trigger an initial reconcile so the report is created on startup even
+	// if there are no SecretCopier objects.
+
+	initial := make(chan event.GenericEvent, 1)
+
+	initial <- event.GenericEvent{
+		Object: &secretsv1beta1.ManagedSecretsReport{
+			ObjectMeta: metav1.ObjectMeta{Name: r.ReportName},
+		},
+	}
+
+	enqueueReport := func(ctx context.Context, object client.Object) []reconcile.Request {
+		return []reconcile.Request{{NamespacedName: client.ObjectKey{Name: r.ReportName}}}
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&secretsv1beta1.ManagedSecretsReport{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(
+			&secretsv1beta1.SecretCopier{},
+			enqueueCoalescedRequestsFromMapFunc(enqueueReport, r.CoalesceWindow, nil),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		WatchesRawSource(source.Channel(initial, &handler.EnqueueRequestForObject{})).
+		Complete(r)
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/internal/version"
+)
+
+var _ = Describe("ManagedSecretsReport Controller", func() {
+	ctx := context.Background()
+
+	// Test that the report lists a secret left behind by a SecretCopier which
+	// no longer exists as being orphaned.
+
+	Context("Report orphaned secret #1", func() {
+		It("should report secret of deleted SecretCopier as orphaned", func() {
+			namespaceName := "report-namespace-1"
+			secretName := "report-orphan-secret-1"
+
+			namespace := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: namespaceName,
+				},
+			}
+			Expect(k8sClient.Create(ctx, namespace)).To(Succeed())
+
+			// Create a secret with the annotations of a copied secret but
+			// where the SecretCopier doesn't exist.
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      secretName,
+					Namespace: namespaceName,
+					Annotations: map[string]string{
+						"secrets-manager.advok8s.io/secret-copier": "report-missing-copier-1",
+						"secrets-manager.advok8s.io/secret-name":   "report-source-namespace-1/" + secretName,
+					},
+				},
+				Type: corev1.SecretTypeOpaque,
+				StringData: map[string]string{
+					"key1": "value1",
+				},
+			}
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+
+			// Wait for the report to list the secret as orphaned.
+
+			Eventually(func() []secretsv1beta1.ManagedSecretReference {
+				report := &secretsv1beta1.ManagedSecretsReport{}
+				err := k8sClient.Get(ctx, client.ObjectKey{Name: "cluster"}, report)
+				if err != nil {
+					return nil
+				}
+				return report.Status.Orphans
+			}, 10*time.Second).Should(ContainElement(secretsv1beta1.ManagedSecretReference{
+				Namespace:    namespaceName,
+				Name:         secretName,
+				SecretCopier: "report-missing-copier-1",
+			}))
+
+			// The report identifies the build of the manager.
+
+			report := &secretsv1beta1.ManagedSecretsReport{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Name: "cluster"}, report)).To(Succeed())
+			Expect(report.Status.Manager.Version).To(Equal(version.Get().Version))
+		})
+	})
+})
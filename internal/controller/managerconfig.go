@@ -0,0 +1,431 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+// Sync period used for SecretCopier objects which don't specify one when no
+// other default has been configured.
+const defaultSyncPeriod = time.Minute
+
+// DefaultNeverCopySecretTypes are the types of secret which are never copied
+// when no other list has been configured. Service account tokens grant the
+// identity of a workload and Helm release secrets hold the state of a release,
+// so fanning either out to other namespaces is never intended.
+var DefaultNeverCopySecretTypes = []corev1.SecretType{
+	corev1.SecretTypeServiceAccountToken,
+	"helm.sh/release.v1",
+}
+
+// ManagerSettings are controller wide settings which can be changed while the
+// manager is running through a SecretsManagerConfig.
+type ManagerSettings struct {
+	// Namespaces which are never used as target namespaces, nor may claim
+	// secrets. Names may be glob patterns.
+	DeniedNamespaces []string
+
+	// Sync period for SecretCopier objects which don't specify one.
+	DefaultSyncPeriod time.Duration
+
+	// Labels applied to all target secrets.
+	TargetSecretLabels map[string]string
+
+	// Maximum rate at which target secrets are written. Use 0 for no limit.
+	MaxSecretWritesPerSecond float64
+
+	// Number of target namespaces a rule may match before it must
+	// acknowledge the fan-out to be processed. Use 0 to disable the guard.
+	FanOutGuardThreshold int
+
+	// Whether target namespaces must consent to receiving copies for all
+	// rules, whether or not a rule requires it.
+	RequireNamespaceConsent bool
+
+	// Types of secret which are never copied, regardless of the rules of a
+	// SecretCopier or entries of a SecretCatalog. This can only be set from
+	// the command line and is not overridden by a SecretsManagerConfig. If
+	// nil, DefaultNeverCopySecretTypes is used.
+	NeverCopySecretTypes []corev1.SecretType
+
+	// Time until which all writes to secrets are frozen. Writes are not
+	// frozen if zero or in the past.
+	WriteFreezeUntil time.Time
+
+	// Reason given for freezing writes to secrets.
+	WriteFreezeReason string
+
+	// Compiled matcher for the denied namespaces.
+	deniedNamespaces *selectors.NameMatcher
+}
+
+// Settings used when no ManagerConfig has been supplied.
+var defaultManagerSettings = newManagerSettings(ManagerSettings{})
+
+// Fill in defaults and compile the matchers for a set of settings.
+func newManagerSettings(settings ManagerSettings) *ManagerSettings {
+	if settings.DefaultSyncPeriod == 0 {
+		settings.DefaultSyncPeriod = defaultSyncPeriod
+	}
+
+	if settings.NeverCopySecretTypes == nil {
+		settings.NeverCopySecretTypes = DefaultNeverCopySecretTypes
+	}
+
+	settings.deniedNamespaces = selectors.NameSelector{MatchNames: settings.DeniedNamespaces}.Compile()
+
+	return &settings
+}
+
+// NamespaceDenied reports whether the namespace is in the list of namespaces
+// which are never used as target namespaces.
+func (s *ManagerSettings) NamespaceDenied(name string) bool {
+	return len(s.DeniedNamespaces) != 0 && s.deniedNamespaces.Matches(name)
+}
+
+// FanOutBlocked reports whether a rule matching the given number of target
+// namespaces must not be processed because it exceeds the fan-out guard
+// threshold without acknowledging it.
+func (s *ManagerSettings) FanOutBlocked(rule *secretsv1beta1.SecretCopierRule, matched int) bool {
+	return s.FanOutGuardThreshold > 0 && matched > s.FanOutGuardThreshold && !rule.AllowLargeFanOut
+}
+
+// NamespaceConsentRequired reports whether target namespaces must consent to
+// receiving copies of the secret of a rule.
+func (s *ManagerSettings) NamespaceConsentRequired(rule *secretsv1beta1.SecretCopierRule) bool {
+	return s.RequireNamespaceConsent || rule.RequireNamespaceConsent
+}
+
+// NamespaceUnconsented reports whether the namespace must be skipped as a
+// target namespace of a rule because consent is required and it hasn't been
+// given.
+func (s *ManagerSettings) NamespaceUnconsented(rule *secretsv1beta1.SecretCopierRule, namespace *corev1.Namespace) bool {
+	return s.NamespaceConsentRequired(rule) && !secretsv1beta1.NamespaceAcceptsCopies(namespace)
+}
+
+// SecretTypeDenied reports whether secrets of the given type must never be
+// copied.
+func (s *ManagerSettings) SecretTypeDenied(secretType corev1.SecretType) bool {
+	for _, denied := range s.NeverCopySecretTypes {
+		if secretType == denied {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WritesFrozen reports whether writes to secrets are frozen at the given time.
+func (s *ManagerSettings) WritesFrozen(now time.Time) bool {
+	return now.Before(s.WriteFreezeUntil)
+}
+
+// WriteFreezeRemaining returns how long after the given time writes to secrets
+// remain frozen, or 0 if they are not frozen.
+func (s *ManagerSettings) WriteFreezeRemaining(now time.Time) time.Duration {
+	if !s.WritesFrozen(now) {
+		return 0
+	}
+
+	return s.WriteFreezeUntil.Sub(now)
+}
+
+// SyncPeriod returns the sync period for a SecretCopier, falling back to the
+// default sync period if the SecretCopier doesn't specify one.
+func (s *ManagerSettings) SyncPeriod(secretCopier *secretsv1beta1.SecretCopier) time.Duration {
+	if secretCopier.Spec.SyncPeriod != nil {
+		return secretCopier.Spec.SyncPeriod.Duration
+	}
+
+	return s.DefaultSyncPeriod
+}
+
+// ManagerConfig holds the current controller wide settings. The settings
+// start out as the defaults given by the command line flags, and are replaced
+// whenever the SecretsManagerConfig changes. Reconcilers read the settings at
+// the start of each reconcile so changes take effect without a restart.
+type ManagerConfig struct {
+	defaults ManagerSettings
+	current  atomic.Pointer[ManagerSettings]
+	limiter  *rate.Limiter
+	changes  chan event.GenericEvent
+}
+
+// NewManagerConfig creates a ManagerConfig with the given default settings.
+func NewManagerConfig(defaults ManagerSettings) *ManagerConfig {
+	c := &ManagerConfig{
+		defaults: defaults,
+		limiter:  rate.NewLimiter(rate.Inf, 1),
+		changes:  make(chan event.GenericEvent, 1),
+	}
+
+	c.apply(defaults)
+
+	return c
+}
+
+// Settings returns the current settings. It is safe to call on a nil
+// ManagerConfig, in which case the built in defaults are returned.
+func (c *ManagerConfig) Settings() *ManagerSettings {
+	if c == nil {
+		return defaultManagerSettings
+	}
+
+	return c.current.Load()
+}
+
+// WritesFrozen reports whether writes to secrets are currently frozen. It is
+// safe to call on a nil ManagerConfig, in which case writes are never frozen.
+func (c *ManagerConfig) WritesFrozen() bool {
+	return c.Settings().WritesFrozen(time.Now())
+}
+
+// SecretWriteLimiter returns the rate limiter for writes to target secrets,
+// whose limit is adjusted whenever the settings change.
+func (c *ManagerConfig) SecretWriteLimiter() *rate.Limiter {
+	return c.limiter
+}
+
+// Replace the current settings and adjust the secret write rate limiter to
+// match.
+func (c *ManagerConfig) apply(settings ManagerSettings) {
+	c.current.Store(newManagerSettings(settings))
+
+	if settings.MaxSecretWritesPerSecond > 0 {
+		c.limiter.SetLimit(rate.Limit(settings.MaxSecretWritesPerSecond))
+		c.limiter.SetBurst(int(math.Ceil(settings.MaxSecretWritesPerSecond)))
+	} else {
+		c.limiter.SetLimit(rate.Inf)
+	}
+}
+
+// Notify watchers that the settings have changed. Notifications are dropped if
+// one is already pending as a single notification is enough for watchers to
+// pick up the latest settings.
+func (c *ManagerConfig) notify(object client.Object) {
+	select {
+	case c.changes <- event.GenericEvent{Object: object}:
+	default:
+	}
+}
+
+// Changes returns a channel on which an event is delivered whenever the
+// settings change. Only a single consumer of the channel is supported.
+func (c *ManagerConfig) Changes() <-chan event.GenericEvent {
+	return c.changes
+}
+
+// LoadManagerSettings returns the settings the manager would be using, given
+// the defaults set by its command line flags and the name of the
+// SecretsManagerConfig overriding them. This is for commands run outside of
+// the manager which need to behave as it does. If the name is empty or the
+// SecretsManagerConfig doesn't exist, the defaults are returned.
+func LoadManagerSettings(ctx context.Context, reader client.Reader, defaults ManagerSettings, configName string) (*ManagerSettings, error) {
+	config := NewManagerConfig(defaults)
+
+	if configName == "" {
+		return config.Settings(), nil
+	}
+
+	var managerConfig secretsv1beta1.SecretsManagerConfig
+
+	if err := reader.Get(ctx, client.ObjectKey{Name: configName}, &managerConfig); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return nil, err
+		}
+
+		return config.Settings(), nil
+	}
+
+	return newManagerSettings(config.merge(&managerConfig.Spec)), nil
+}
+
+// Merge the spec of a SecretsManagerConfig over the default settings.
+func (c *ManagerConfig) merge(spec *secretsv1beta1.SecretsManagerConfigSpec) ManagerSettings {
+	settings := c.defaults
+
+	if spec.DeniedNamespaces != nil {
+		settings.DeniedNamespaces = spec.DeniedNamespaces
+	}
+
+	if spec.DefaultSyncPeriod != nil {
+		settings.DefaultSyncPeriod = spec.DefaultSyncPeriod.Duration
+	}
+
+	if spec.TargetSecretLabels != nil {
+		settings.TargetSecretLabels = spec.TargetSecretLabels
+	}
+
+	if spec.MaxSecretWritesPerSecond != nil {
+		settings.MaxSecretWritesPerSecond = float64(*spec.MaxSecretWritesPerSecond)
+	}
+
+	if spec.FanOutGuardThreshold != nil {
+		settings.FanOutGuardThreshold = int(*spec.FanOutGuardThreshold)
+	}
+
+	if spec.RequireNamespaceConsent != nil {
+		settings.RequireNamespaceConsent = *spec.RequireNamespaceConsent
+	}
+
+	if spec.WriteFreeze != nil {
+		settings.WriteFreezeUntil = spec.WriteFreeze.Until.Time
+		settings.WriteFreezeReason = spec.WriteFreeze.Reason
+	}
+
+	return settings
+}
+
+// SecretsManagerConfigReconciler watches the SecretsManagerConfig used by the
+// manager and applies its settings to the ManagerConfig.
+type SecretsManagerConfigReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Name of the SecretsManagerConfig to watch.
+	ConfigName string
+
+	// Settings to update when the SecretsManagerConfig changes.
+	Config *ManagerConfig
+}
+
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=secretsmanagerconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=secretsmanagerconfigs/status,verbs=get;update;patch
+
+// Reconcile the SecretsManagerConfig by applying its settings, or reverting to
+// the default settings if it has been deleted.
+func (r *SecretsManagerConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	if req.Name != r.ConfigName {
+		return ctrl.Result{}, nil
+	}
+
+	var config secretsv1beta1.SecretsManagerConfig
+
+	if err := r.Get(ctx, req.NamespacedName, &config); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Unable to fetch SecretsManagerConfig", "name", req.Name)
+			return ctrl.Result{}, err
+		}
+
+		log.Info("SecretsManagerConfig not found, using default settings", "name", req.Name)
+
+		r.applySettings(r.Config.defaults, &secretsv1beta1.SecretsManagerConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: req.Name},
+		})
+
+		return ctrl.Result{}, nil
+	}
+
+	settings := r.Config.merge(&config.Spec)
+
+	r.applySettings(settings, &config)
+
+	log.Info("Applied SecretsManagerConfig", "name", req.Name, "generation", config.Generation)
+
+	// Record that the settings have been applied, and whether writes to
+	// secrets are frozen.
+
+	now := time.Now()
+
+	status := *config.Status.DeepCopy()
+
+	status.ObservedGeneration = config.Generation
+
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               secretsv1beta1.ConditionApplied,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: config.Generation,
+		Reason:             "Applied",
+		Message:            fmt.Sprintf("Settings applied to SecretsManagerConfig generation %d", config.Generation),
+	})
+
+	if settings.WritesFrozen(now) {
+		meta.SetStatusCondition(&status.Conditions, writesFrozenCondition(&settings, config.Generation))
+	} else {
+		meta.RemoveStatusCondition(&status.Conditions, secretsv1beta1.ConditionWritesFrozen)
+	}
+
+	// Requeue for when the freeze expires so the condition is cleared.
+
+	result := ctrl.Result{RequeueAfter: settings.WriteFreezeRemaining(now)}
+
+	if equality.Semantic.DeepEqual(config.Status, status) {
+		return result, nil
+	}
+
+	config.Status = status
+
+	if err := r.Status().Update(ctx, &config); err != nil {
+		log.Error(err, "Unable to update SecretsManagerConfig status", "name", req.Name)
+		return ctrl.Result{}, err
+	}
+
+	return result, nil
+}
+
+// Apply the settings if they differ from the current settings, notifying
+// watchers of the change.
+func (r *SecretsManagerConfigReconciler) applySettings(settings ManagerSettings, config *secretsv1beta1.SecretsManagerConfig) {
+	current := *r.Config.Settings()
+	next := *newManagerSettings(settings)
+
+	current.deniedNamespaces = nil
+	next.deniedNamespaces = nil
+
+	// Times can't be compared by reflection, so the end of any write freeze
+	// is compared separately.
+
+	freezeUnchanged := current.WriteFreezeUntil.Equal(next.WriteFreezeUntil)
+
+	current.WriteFreezeUntil = time.Time{}
+	next.WriteFreezeUntil = time.Time{}
+
+	if freezeUnchanged && equality.Semantic.DeepEqual(current, next) {
+		return
+	}
+
+	r.Config.apply(settings)
+	r.Config.notify(config)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SecretsManagerConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&secretsv1beta1.SecretsManagerConfig{}).
+		Complete(r)
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Manager Config", func() {
+	It("should use built in defaults when there is no config", func() {
+		var config *ManagerConfig
+
+		settings := config.Settings()
+
+		Expect(settings.DefaultSyncPeriod).To(Equal(time.Minute))
+		Expect(settings.NamespaceDenied("default")).To(BeFalse())
+	})
+
+	It("should merge SecretsManagerConfig over the defaults", func() {
+		config := NewManagerConfig(ManagerSettings{
+			MaxSecretWritesPerSecond: 5,
+		})
+
+		Expect(config.SecretWriteLimiter().Limit()).To(Equal(rate.Limit(5)))
+
+		config.apply(config.merge(&secretsv1beta1.SecretsManagerConfigSpec{
+			DeniedNamespaces:  []string{"kube-*"},
+			DefaultSyncPeriod: &metav1.Duration{Duration: 5 * time.Minute},
+		}))
+
+		settings := config.Settings()
+
+		Expect(settings.NamespaceDenied("kube-system")).To(BeTrue())
+		Expect(settings.NamespaceDenied("default")).To(BeFalse())
+		Expect(settings.MaxSecretWritesPerSecond).To(Equal(float64(5)))
+
+		Expect(settings.SyncPeriod(&secretsv1beta1.SecretCopier{})).To(Equal(5 * time.Minute))
+		Expect(settings.SyncPeriod(&secretsv1beta1.SecretCopier{
+			Spec: secretsv1beta1.SecretCopierSpec{
+				SyncPeriod: &metav1.Duration{Duration: time.Second},
+			},
+		})).To(Equal(time.Second))
+
+		config.apply(config.merge(&secretsv1beta1.SecretsManagerConfigSpec{
+			MaxSecretWritesPerSecond: ptr.To[int32](0),
+		}))
+
+		Expect(config.SecretWriteLimiter().Limit()).To(Equal(rate.Inf))
+	})
+
+	It("should block rules exceeding the fan-out guard unless acknowledged", func() {
+		config := NewManagerConfig(ManagerSettings{})
+
+		rule := &secretsv1beta1.SecretCopierRule{}
+
+		Expect(config.Settings().FanOutBlocked(rule, 1000)).To(BeFalse())
+
+		config.apply(config.merge(&secretsv1beta1.SecretsManagerConfigSpec{
+			FanOutGuardThreshold: ptr.To[int32](10),
+		}))
+
+		Expect(config.Settings().FanOutBlocked(rule, 10)).To(BeFalse())
+		Expect(config.Settings().FanOutBlocked(rule, 11)).To(BeTrue())
+
+		rule.AllowLargeFanOut = true
+
+		Expect(config.Settings().FanOutBlocked(rule, 11)).To(BeFalse())
+	})
+
+	It("should never copy secret types given on the command line", func() {
+		var defaults *ManagerConfig
+
+		Expect(defaults.Settings().SecretTypeDenied(corev1.SecretTypeServiceAccountToken)).To(BeTrue())
+		Expect(defaults.Settings().SecretTypeDenied("helm.sh/release.v1")).To(BeTrue())
+		Expect(defaults.Settings().SecretTypeDenied(corev1.SecretTypeOpaque)).To(BeFalse())
+
+		config := NewManagerConfig(ManagerSettings{
+			NeverCopySecretTypes: []corev1.SecretType{corev1.SecretTypeTLS},
+		})
+
+		config.apply(config.merge(&secretsv1beta1.SecretsManagerConfigSpec{
+			DeniedNamespaces: []string{"kube-*"},
+		}))
+
+		Expect(config.Settings().SecretTypeDenied(corev1.SecretTypeTLS)).To(BeTrue())
+		Expect(config.Settings().SecretTypeDenied(corev1.SecretTypeServiceAccountToken)).To(BeFalse())
+
+		config = NewManagerConfig(ManagerSettings{
+			NeverCopySecretTypes: []corev1.SecretType{},
+		})
+
+		Expect(config.Settings().SecretTypeDenied(corev1.SecretTypeServiceAccountToken)).To(BeFalse())
+	})
+})
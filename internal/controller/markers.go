@@ -0,0 +1,123 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Annotations recording on a target secret the SecretCopier managing it, the
+// source secret it was copied from, and the version of the format in which
+// these were recorded.
+const (
+	secretCopierMarkerAnnotation  = "secrets-manager.advok8s.io/secret-copier"
+	sourceSecretMarkerAnnotation  = "secrets-manager.advok8s.io/secret-name"
+	markerVersionMarkerAnnotation = "secrets-manager.advok8s.io/marker-version"
+)
+
+// Version of the format of the markers written to target secrets. Target
+// secrets written before the version was recorded use the format of version 1.
+const currentMarkerVersion = 1
+
+// Format of the reference to the source secret recorded in the markers of a
+// target secret.
+type sourceSecretMarkerFormat struct {
+	format func(namespace string, name string) string
+	parse  func(value string) (client.ObjectKey, bool)
+}
+
+// Formats of the reference to the source secret, indexed by marker version.
+// When the format changes, a new version is added here rather than the
+// existing format being changed, so target secrets written by earlier
+// versions of the controller are still recognised as managed.
+var sourceSecretMarkerFormats = map[int]sourceSecretMarkerFormat{
+	1: {
+		format: func(namespace string, name string) string {
+			return namespace + "/" + name
+		},
+		parse: func(value string) (client.ObjectKey, bool) {
+			namespace, name, found := strings.Cut(value, "/")
+
+			if !found || namespace == "" || name == "" {
+				return client.ObjectKey{}, false
+			}
+
+			return client.ObjectKey{Namespace: namespace, Name: name}, true
+		},
+	},
+}
+
+// Determine the version of the format of the markers of a target secret. A
+// version newer than any known, as written by a later version of the
+// controller, is read using the current format, so a downgrade doesn't result
+// in all target secrets being treated as unmanaged.
+func targetSecretMarkerVersion(secret *corev1.Secret) (int, bool) {
+	value, found := secret.Annotations[markerVersionMarkerAnnotation]
+
+	if !found {
+		return 1, true
+	}
+
+	version, err := strconv.Atoi(value)
+
+	if err != nil || version < 1 {
+		return 0, false
+	}
+
+	if version > currentMarkerVersion {
+		return currentMarkerVersion, true
+	}
+
+	return version, true
+}
+
+// Set the markers on a target secret recording the SecretCopier managing it
+// and the source secret it was copied from, using the current format.
+func setTargetSecretMarkers(secret *corev1.Secret, secretCopierName string, sourceSecret secretsv1beta1.SourceSecret) {
+	if secret.Annotations == nil {
+		secret.Annotations = make(map[string]string)
+	}
+
+	secret.Annotations[secretCopierMarkerAnnotation] = secretCopierName
+	secret.Annotations[sourceSecretMarkerAnnotation] = sourceSecretMarkerFormats[currentMarkerVersion].format(sourceSecret.Namespace, sourceSecret.Name)
+	secret.Annotations[markerVersionMarkerAnnotation] = strconv.Itoa(currentMarkerVersion)
+}
+
+// Return the source secret recorded in the markers of a target secret.
+func targetSecretSource(secret *corev1.Secret) (client.ObjectKey, bool) {
+	version, ok := targetSecretMarkerVersion(secret)
+
+	if !ok {
+		return client.ObjectKey{}, false
+	}
+
+	return sourceSecretMarkerFormats[version].parse(secret.Annotations[sourceSecretMarkerAnnotation])
+}
+
+// Determine whether the markers of a target secret record that it was copied
+// from the source secret.
+func targetSecretSourceMatches(secret *corev1.Secret, sourceSecret secretsv1beta1.SourceSecret) bool {
+	source, ok := targetSecretSource(secret)
+
+	return ok && source.Namespace == sourceSecret.Namespace && source.Name == sourceSecret.Name
+}
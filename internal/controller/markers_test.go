@@ -0,0 +1,93 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Target Secret Markers", func() {
+	sourceSecret := secretsv1beta1.SourceSecret{Namespace: "source", Name: "registry"}
+
+	targetSecret := func(annotations map[string]string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "tenant-a", Annotations: annotations}}
+	}
+
+	It("should record the current marker version", func() {
+		secret := targetSecret(nil)
+
+		setTargetSecretMarkers(secret, "copier", sourceSecret)
+
+		Expect(secret.Annotations).To(Equal(map[string]string{
+			"secrets-manager.advok8s.io/secret-copier":  "copier",
+			"secrets-manager.advok8s.io/secret-name":    "source/registry",
+			"secrets-manager.advok8s.io/marker-version": "1",
+		}))
+
+		Expect(targetSecretSourceMatches(secret, sourceSecret)).To(BeTrue())
+	})
+
+	It("should match markers written before the version was recorded", func() {
+		secret := targetSecret(map[string]string{
+			"secrets-manager.advok8s.io/secret-copier": "copier",
+			"secrets-manager.advok8s.io/secret-name":   "source/registry",
+		})
+
+		Expect(targetSecretSourceMatches(secret, sourceSecret)).To(BeTrue())
+		source, ok := targetSecretSource(secret)
+
+		Expect(ok).To(BeTrue())
+		Expect(source).To(Equal(client.ObjectKey{Namespace: "source", Name: "registry"}))
+	})
+
+	It("should match markers written by a later version", func() {
+		secret := targetSecret(map[string]string{
+			"secrets-manager.advok8s.io/secret-copier":  "copier",
+			"secrets-manager.advok8s.io/secret-name":    "source/registry",
+			"secrets-manager.advok8s.io/marker-version": "99",
+		})
+
+		Expect(targetSecretSourceMatches(secret, sourceSecret)).To(BeTrue())
+	})
+
+	It("should not match markers with an invalid version", func() {
+		secret := targetSecret(map[string]string{
+			"secrets-manager.advok8s.io/secret-copier":  "copier",
+			"secrets-manager.advok8s.io/secret-name":    "source/registry",
+			"secrets-manager.advok8s.io/marker-version": "latest",
+		})
+
+		Expect(targetSecretSourceMatches(secret, sourceSecret)).To(BeFalse())
+	})
+
+	It("should not match a different or malformed source secret", func() {
+		Expect(targetSecretSourceMatches(targetSecret(map[string]string{
+			"secrets-manager.advok8s.io/secret-name": "source/other",
+		}), sourceSecret)).To(BeFalse())
+
+		Expect(targetSecretSourceMatches(targetSecret(map[string]string{
+			"secrets-manager.advok8s.io/secret-name": "registry",
+		}), sourceSecret)).To(BeFalse())
+	})
+})
@@ -0,0 +1,80 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+// Cache of compiled target namespace matchers for the rules of each
+// SecretCopier. Entries are keyed on the name of the SecretCopier and are
+// discarded when the UID or generation of the SecretCopier changes, the latter
+// of which happens whenever the spec of the SecretCopier is modified.
+type matcherCache struct {
+	mutex   sync.Mutex
+	entries map[string]matcherCacheEntry
+}
+
+type matcherCacheEntry struct {
+	uid        types.UID
+	generation int64
+	matchers   []*selectors.TargetNamespacesMatcher
+}
+
+// Return the compiled target namespace matchers for the rules of the
+// SecretCopier, compiling them if there is no valid cache entry.
+func (c *matcherCache) get(secretCopier *secretsv1beta1.SecretCopier) []*selectors.TargetNamespacesMatcher {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if entry, ok := c.entries[secretCopier.Name]; ok {
+		if entry.uid == secretCopier.UID && entry.generation == secretCopier.Generation {
+			return entry.matchers
+		}
+	}
+
+	matchers := make([]*selectors.TargetNamespacesMatcher, 0, len(secretCopier.Spec.Rules))
+
+	for _, rule := range secretCopier.Spec.Rules {
+		matchers = append(matchers, rule.TargetNamespaces.Compile())
+	}
+
+	if c.entries == nil {
+		c.entries = make(map[string]matcherCacheEntry)
+	}
+
+	c.entries[secretCopier.Name] = matcherCacheEntry{
+		uid:        secretCopier.UID,
+		generation: secretCopier.Generation,
+		matchers:   matchers,
+	}
+
+	return matchers
+}
+
+// Discard any cache entry for the named SecretCopier.
+func (c *matcherCache) forget(name string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.entries, name)
+}
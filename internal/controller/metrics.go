@@ -0,0 +1,373 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/advok8s/advok8s-secrets-manager/internal/version"
+)
+
+var (
+	// Time taken to evaluate the target namespace selectors of a rule
+	// against the set of active namespaces in the cluster.
+	selectorEvaluationDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "secrets_manager_selector_evaluation_duration_seconds",
+			Help:    "Time taken to evaluate the target namespace selectors of a SecretCopier rule.",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 10),
+		},
+		[]string{"secretcopier", "rule"},
+	)
+
+	// Time taken to process a rule of a SecretCopier, from evaluating its
+	// target namespace selectors to copying the source secret to the target
+	// namespaces.
+	ruleProcessingDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "secrets_manager_rule_processing_duration_seconds",
+			Help:    "Time taken to process a SecretCopier rule in a reconciliation.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 4, 10),
+		},
+		[]string{"secretcopier", "rule"},
+	)
+
+	// Number of namespaces the target namespace selectors of a rule were
+	// evaluated against.
+	selectorEvaluationNamespaces = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "secrets_manager_selector_evaluation_namespaces",
+			Help: "Number of namespaces evaluated against the target namespace selectors of a SecretCopier rule.",
+		},
+		[]string{"secretcopier", "rule"},
+	)
+
+	// Number of times the controller was denied permission to perform an
+	// operation on a target secret.
+	permissionDeniedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "secrets_manager_permission_denied_total",
+			Help: "Number of operations on target secrets denied by the API server as forbidden.",
+		},
+		[]string{"secretcopier", "verb", "resource"},
+	)
+
+	// Number of target namespaces where the controller is currently denied
+	// permission to manage the target secret.
+	permissionDeniedTargets = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "secrets_manager_permission_denied_targets",
+			Help: "Number of target namespaces where the controller is denied permission to manage the target secret.",
+		},
+		[]string{"secretcopier"},
+	)
+
+	// Number of writes to secrets skipped because the manager is running in
+	// report-only mode.
+	reportOnlySkippedWritesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "secrets_manager_report_only_skipped_writes_total",
+			Help: "Number of writes to secrets skipped because the manager is running in report-only mode.",
+		},
+		[]string{"controller", "verb"},
+	)
+
+	// Number of writes to secrets skipped because writes to secrets are
+	// frozen.
+	frozenSkippedWritesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "secrets_manager_frozen_skipped_writes_total",
+			Help: "Number of writes to secrets skipped because writes to secrets are frozen.",
+		},
+		[]string{"controller", "verb"},
+	)
+
+	// Number of failures to copy a secret to a target namespace, by the
+	// reason for the failure.
+	copyFailuresTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "secrets_manager_copy_failures_total",
+			Help: "Number of failures to copy a secret to a target namespace, by reason.",
+		},
+		[]string{"secretcopier", "reason"},
+	)
+
+	// Number of orphaned target secrets found by the last scan of the orphan
+	// reaper, including those still within their grace period.
+	orphanedSecrets = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "secrets_manager_orphaned_secrets",
+			Help: "Number of orphaned target secrets found by the last scan of the orphan reaper.",
+		},
+	)
+
+	// Number of orphaned target secrets deleted by the orphan reaper.
+	orphanedSecretsRemovedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "secrets_manager_orphaned_secrets_removed_total",
+			Help: "Number of orphaned target secrets deleted by the orphan reaper.",
+		},
+		[]string{"reason"},
+	)
+
+	// Number of objects rewritten by the schema migrator.
+	migratedObjectsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "secrets_manager_migrated_objects_total",
+			Help: "Number of objects written by an earlier version of the manager which have been migrated.",
+		},
+		[]string{"kind"},
+	)
+
+	// Number of objects found by the last pass of the schema migrator which
+	// still need to be migrated.
+	migrationPendingObjects = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "secrets_manager_migration_pending_objects",
+			Help: "Number of objects written by an earlier version of the manager which still need to be migrated.",
+		},
+		[]string{"kind"},
+	)
+
+	// Number of old generations of hashed target secrets deleted.
+	hashedSecretsPrunedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "secrets_manager_hashed_secrets_pruned_total",
+			Help: "Number of old generations of hash suffixed target secrets deleted.",
+		},
+		[]string{"secretcopier"},
+	)
+
+	// Number of target secrets deleted because they were no longer wanted by
+	// any rule of a SecretCopier with a prune policy of Delete.
+	prunedSecretsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "secrets_manager_pruned_secrets_total",
+			Help: "Number of target secrets deleted because they were no longer wanted by any rule.",
+		},
+		[]string{"secretcopier", "reason"},
+	)
+
+	// Number of updates of target secrets not made because a server side
+	// dry run reported the update would not change the target secret.
+	dryRunSkippedUpdatesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "secrets_manager_dry_run_skipped_updates_total",
+			Help: "Number of updates of target secrets skipped because a server side dry run reported no change.",
+		},
+		[]string{"secretcopier"},
+	)
+
+	// Number of copies of a secret retried with the target secret read
+	// directly from the API server, because a write based on the copy of the
+	// target secret in the informer cache failed as the cache was stale.
+	staleCacheRetriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "secrets_manager_stale_cache_retries_total",
+			Help: "Number of copies of secrets retried because the cached target secret was stale.",
+		},
+		[]string{"secretcopier"},
+	)
+
+	// Time from a change to a source secret until all target namespaces of a
+	// rule hold the changed secret.
+	propagationLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "secrets_manager_propagation_latency_seconds",
+			Help:    "Time from a change to a source secret until it has been copied to all target namespaces of a SecretCopier rule.",
+			Buckets: prometheus.ExponentialBuckets(0.5, 2, 12),
+		},
+		[]string{"secretcopier", "rule"},
+	)
+
+	// Time from the creation of a namespace until secrets were copied into it
+	// by the fast path for new namespaces.
+	newNamespaceCopyLatency = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "secrets_manager_new_namespace_copy_latency_seconds",
+			Help:    "Time from the creation of a namespace until secrets were copied into it by the fast path for new namespaces.",
+			Buckets: prometheus.ExponentialBuckets(0.25, 2, 10),
+		},
+	)
+
+	// Number of revisions of source secrets held back because the rule
+	// copying them limits how often changes are propagated.
+	suppressedUpdatesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "secrets_manager_suppressed_updates_total",
+			Help: "Number of changes to source secrets held back by the minimum update interval of a SecretCopier rule.",
+		},
+		[]string{"secretcopier", "rule"},
+	)
+
+	// Bytes of secret data written to target secrets when they are created
+	// or updated.
+	secretDataWrittenBytesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "secrets_manager_secret_data_written_bytes_total",
+			Help: "Bytes of secret data written to target secrets created or updated by a SecretCopier.",
+		},
+		[]string{"secretcopier"},
+	)
+
+	// Bytes of secret data held in the target secrets of a SecretCopier, as
+	// of the last reconciliation which processed all of its rules.
+	targetSecretDataBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "secrets_manager_target_secret_data_bytes",
+			Help: "Bytes of secret data held in the target secrets of a SecretCopier.",
+		},
+		[]string{"secretcopier"},
+	)
+
+	// Bytes of secret data held in the target secrets of all SecretCopiers.
+	clusterTargetSecretDataBytes = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "secrets_manager_cluster_target_secret_data_bytes",
+			Help: "Bytes of secret data held in the target secrets of all SecretCopiers.",
+		},
+	)
+
+	// Number of target namespaces of a remote cluster where the target
+	// secrets of a SecretCopier are in sync, as of the last reconciliation
+	// which could reach the cluster.
+	targetClusterSecretsSynced = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "secrets_manager_target_cluster_secrets_synced",
+			Help: "Number of target namespaces of a remote cluster where the target secrets of a SecretCopier are in sync.",
+		},
+		[]string{"secretcopier", "cluster"},
+	)
+
+	// Time at which the target secrets of a SecretCopier were last synced to
+	// a remote cluster without any failures.
+	targetClusterLastSyncTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "secrets_manager_target_cluster_last_sync_timestamp_seconds",
+			Help: "Time at which the target secrets of a SecretCopier were last synced to a remote cluster without failures.",
+		},
+		[]string{"secretcopier", "cluster"},
+	)
+
+	// Number of failures syncing the target secrets of a SecretCopier to a
+	// remote cluster, counting each attempt to reach the cluster which
+	// failed and each target namespace where copying failed.
+	targetClusterSyncFailuresTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "secrets_manager_target_cluster_sync_failures_total",
+			Help: "Number of failures syncing the target secrets of a SecretCopier to a remote cluster.",
+		},
+		[]string{"secretcopier", "cluster"},
+	)
+
+	// Number of workloads restarted by a SecretRotator as copied secrets
+	// they depend on changed.
+	workloadRestartsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "secrets_manager_workload_restarts_total",
+			Help: "Number of workloads restarted by a SecretRotator as copied secrets they depend on changed.",
+		},
+		[]string{"secretrotator", "kind"},
+	)
+
+	// Build information for the running manager. Always has the value 1,
+	// with the details given by the labels.
+	buildInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "secrets_manager_build_info",
+			Help: "Build information for the secrets manager, with the value always being 1.",
+		},
+		[]string{"version", "git_commit", "build_date", "go_version"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		selectorEvaluationDuration,
+		selectorEvaluationNamespaces,
+		ruleProcessingDuration,
+		permissionDeniedTotal,
+		permissionDeniedTargets,
+		orphanedSecrets,
+		orphanedSecretsRemovedTotal,
+		migratedObjectsTotal,
+		migrationPendingObjects,
+		reportOnlySkippedWritesTotal,
+		frozenSkippedWritesTotal,
+		copyFailuresTotal,
+		hashedSecretsPrunedTotal,
+		prunedSecretsTotal,
+		dryRunSkippedUpdatesTotal,
+		staleCacheRetriesTotal,
+		propagationLatency,
+		newNamespaceCopyLatency,
+		suppressedUpdatesTotal,
+		secretDataWrittenBytesTotal,
+		targetSecretDataBytes,
+		clusterTargetSecretDataBytes,
+		targetClusterSecretsSynced,
+		targetClusterLastSyncTimestamp,
+		targetClusterSyncFailuresTotal,
+		workloadRestartsTotal,
+		buildInfo,
+	)
+
+	info := version.Get()
+
+	buildInfo.WithLabelValues(info.Version, info.GitCommit, info.BuildDate, info.GoVersion).Set(1)
+}
+
+// Remove all metric series recorded against a SecretCopier which has been
+// deleted so that they are not reported indefinitely.
+func deleteSecretCopierMetrics(name string) {
+	labels := prometheus.Labels{"secretcopier": name}
+
+	selectorEvaluationDuration.DeletePartialMatch(labels)
+	selectorEvaluationNamespaces.DeletePartialMatch(labels)
+	ruleProcessingDuration.DeletePartialMatch(labels)
+	permissionDeniedTotal.DeletePartialMatch(labels)
+	permissionDeniedTargets.DeletePartialMatch(labels)
+	copyFailuresTotal.DeletePartialMatch(labels)
+	hashedSecretsPrunedTotal.DeletePartialMatch(labels)
+	prunedSecretsTotal.DeletePartialMatch(labels)
+	dryRunSkippedUpdatesTotal.DeletePartialMatch(labels)
+	staleCacheRetriesTotal.DeletePartialMatch(labels)
+	propagationLatency.DeletePartialMatch(labels)
+	suppressedUpdatesTotal.DeletePartialMatch(labels)
+	secretDataWrittenBytesTotal.DeletePartialMatch(labels)
+	targetSecretDataBytes.DeletePartialMatch(labels)
+	targetClusterSecretsSynced.DeletePartialMatch(labels)
+	targetClusterLastSyncTimestamp.DeletePartialMatch(labels)
+	targetClusterSyncFailuresTotal.DeletePartialMatch(labels)
+}
+
+// Remove the metric series recorded against a SecretCopier for a remote
+// cluster none of its rules copy to any longer.
+func deleteTargetClusterMetrics(secretCopierName string, cluster string) {
+	labels := prometheus.Labels{"secretcopier": secretCopierName, "cluster": cluster}
+
+	targetClusterSecretsSynced.Delete(labels)
+	targetClusterLastSyncTimestamp.Delete(labels)
+	targetClusterSyncFailuresTotal.Delete(labels)
+}
+
+// Remove all metric series recorded against a SecretRotator which has been
+// deleted so that they are not reported indefinitely.
+func deleteSecretRotatorMetrics(name string) {
+	workloadRestartsTotal.DeletePartialMatch(prometheus.Labels{"secretrotator": name})
+}
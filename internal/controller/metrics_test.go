@@ -0,0 +1,129 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+var _ = Describe("SecretCopier Metrics", func() {
+	ctx := context.Background()
+
+	It("should record selector metrics against the index of the rule in the spec", func() {
+		targetNamespaces := selectors.TargetNamespaces{
+			NameSelector: selectors.NameSelector{MatchNames: []string{"tenant-*"}},
+		}
+
+		secretCopier := &secretsv1beta1.SecretCopier{
+			ObjectMeta: metav1.ObjectMeta{Name: "metrics-copier", Generation: 1},
+			Spec: secretsv1beta1.SecretCopierSpec{
+				Rules: []secretsv1beta1.SecretCopierRule{
+					{
+						SourceSecret:     secretsv1beta1.SourceSecret{Name: "token", Namespace: "registries"},
+						TargetNamespaces: targetNamespaces,
+					},
+					{
+						SourceSecret:     secretsv1beta1.SourceSecret{Name: "registry-*", Namespace: "registries"},
+						TargetNamespaces: targetNamespaces,
+					},
+				},
+			},
+		}
+
+		sourceSecret := func(name string) *corev1.Secret {
+			return &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "registries"},
+				Data:       map[string][]byte{"token": []byte(name)},
+			}
+		}
+
+		k8sClient := fake.NewClientBuilder().WithObjects(
+			secretCopier,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "registries"}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-a"}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-b"}},
+			sourceSecret("token"),
+			sourceSecret("registry-primary"),
+			sourceSecret("registry-mirror"),
+		).WithStatusSubresource(secretCopier).Build()
+
+		reconciler := &SecretCopierReconciler{Client: k8sClient, Recorder: record.NewFakeRecorder(100)}
+
+		evaluations := testutil.CollectAndCount(selectorEvaluationDuration)
+		namespaces := testutil.CollectAndCount(selectorEvaluationNamespaces)
+		processing := testutil.CollectAndCount(ruleProcessingDuration)
+
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "metrics-copier"}})
+
+		Expect(err).NotTo(HaveOccurred())
+
+		// The pattern expands to two rules, which are both recorded against
+		// the one rule of the spec, so there is a series for each of the two
+		// rules of the spec.
+
+		Expect(testutil.CollectAndCount(selectorEvaluationDuration)).To(Equal(evaluations + 2))
+		Expect(testutil.CollectAndCount(selectorEvaluationNamespaces)).To(Equal(namespaces + 2))
+		Expect(testutil.CollectAndCount(ruleProcessingDuration)).To(Equal(processing + 2))
+
+		Expect(testutil.ToFloat64(selectorEvaluationNamespaces.WithLabelValues("metrics-copier", "0"))).To(Equal(3.0))
+		Expect(testutil.ToFloat64(selectorEvaluationNamespaces.WithLabelValues("metrics-copier", "1"))).To(Equal(3.0))
+
+		deleteSecretCopierMetrics("metrics-copier")
+
+		Expect(testutil.CollectAndCount(selectorEvaluationDuration)).To(Equal(evaluations))
+		Expect(testutil.CollectAndCount(selectorEvaluationNamespaces)).To(Equal(namespaces))
+		Expect(testutil.CollectAndCount(ruleProcessingDuration)).To(Equal(processing))
+	})
+
+	It("should only delete the metrics of the named SecretCopier", func() {
+		for _, name := range []string{"deleted-copier", "kept-copier"} {
+			selectorEvaluationDuration.WithLabelValues(name, "0").Observe(0.01)
+			selectorEvaluationNamespaces.WithLabelValues(name, "0").Set(5)
+			ruleProcessingDuration.WithLabelValues(name, "0").Observe(0.1)
+			suppressedUpdatesTotal.WithLabelValues(name, "0").Inc()
+			secretDataWrittenBytesTotal.WithLabelValues(name).Add(100)
+		}
+
+		deleteSecretCopierMetrics("deleted-copier")
+
+		Expect(selectorEvaluationDuration.DeleteLabelValues("deleted-copier", "0")).To(BeFalse())
+		Expect(selectorEvaluationNamespaces.DeleteLabelValues("deleted-copier", "0")).To(BeFalse())
+		Expect(ruleProcessingDuration.DeleteLabelValues("deleted-copier", "0")).To(BeFalse())
+		Expect(suppressedUpdatesTotal.DeleteLabelValues("deleted-copier", "0")).To(BeFalse())
+		Expect(secretDataWrittenBytesTotal.DeleteLabelValues("deleted-copier")).To(BeFalse())
+
+		Expect(testutil.ToFloat64(selectorEvaluationNamespaces.WithLabelValues("kept-copier", "0"))).To(Equal(5.0))
+		Expect(testutil.ToFloat64(suppressedUpdatesTotal.WithLabelValues("kept-copier", "0"))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(secretDataWrittenBytesTotal.WithLabelValues("kept-copier"))).To(Equal(100.0))
+
+		deleteSecretCopierMetrics("kept-copier")
+	})
+})
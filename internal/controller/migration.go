@@ -0,0 +1,258 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Annotation recording on a SecretCopier the version of the schema it was last
+// stored with by the manager.
+const schemaVersionAnnotation = "secrets-manager.advok8s.io/schema-version"
+
+// Version of the schema SecretCopier objects are migrated to. This is
+// increased whenever a change to the API means objects written by earlier
+// versions of the manager need to be rewritten.
+const currentSchemaVersion = 1
+
+// Kinds of object reported in the migration metrics.
+const (
+	migrationKindSecretCopier = "SecretCopier"
+	migrationKindTargetSecret = "Secret"
+)
+
+// SchemaMigrator rewrites objects written by earlier versions of the manager
+// when the manager starts, so that version upgrades don't strand managed
+// secrets. SecretCopier objects are rewritten so that the API server stores
+// them with the current schema and its defaults, and target secrets whose
+// markers were written using an earlier format have them rewritten using the
+// current format. Passes are repeated until all objects have been migrated.
+type SchemaMigrator struct {
+	client.Client
+
+	// Interval between passes while objects remain to be migrated.
+	RetryInterval time.Duration
+
+	// If set, objects needing migration are reported but never rewritten.
+	ReportOnly bool
+}
+
+// Summary of a pass of the migration over objects of one kind.
+type migrationSummary struct {
+	pending  int
+	migrated int
+	failed   int
+}
+
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=secretcopiers,verbs=get;list;watch;update
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;patch
+
+// Start migrating objects. Implements manager.Runnable, and as it doesn't
+// implement LeaderElectionRunnable, only runs when elected leader. Returns
+// once all objects have been migrated.
+func (m *SchemaMigrator) Start(ctx context.Context) error {
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithName("schema-migrator"))
+
+	_ = wait.PollUntilContextCancel(ctx, m.RetryInterval, true, func(ctx context.Context) (bool, error) {
+		return m.migrate(ctx), nil
+	})
+
+	return nil
+}
+
+// Make a pass over all objects, migrating those which need it. Returns whether
+// the migration is complete, which in report-only mode is after the first
+// pass which could list all objects.
+func (m *SchemaMigrator) migrate(ctx context.Context) bool {
+	log := log.FromContext(ctx)
+
+	secretCopiers, err := m.migrateSecretCopiers(ctx)
+
+	if err != nil {
+		log.Error(err, "Unable to list SecretCopier objects")
+		return false
+	}
+
+	targetSecrets, err := m.migrateTargetSecrets(ctx)
+
+	if err != nil {
+		log.Error(err, "Unable to list secrets")
+		return false
+	}
+
+	migrationPendingObjects.WithLabelValues(migrationKindSecretCopier).Set(float64(secretCopiers.pending))
+	migrationPendingObjects.WithLabelValues(migrationKindTargetSecret).Set(float64(targetSecrets.pending))
+
+	log.Info("Migration pass completed",
+		"secretCopiersMigrated", secretCopiers.migrated, "secretCopiersFailed", secretCopiers.failed, "secretCopiersPending", secretCopiers.pending,
+		"targetSecretsMigrated", targetSecrets.migrated, "targetSecretsFailed", targetSecrets.failed, "targetSecretsPending", targetSecrets.pending,
+		"reportOnly", m.ReportOnly)
+
+	return m.ReportOnly || (secretCopiers.pending == 0 && targetSecrets.pending == 0)
+}
+
+// Rewrite SecretCopier objects last stored with an earlier schema version. An
+// update which doesn't otherwise change the object is enough for the API
+// server to store it with the current schema and any defaults added since.
+func (m *SchemaMigrator) migrateSecretCopiers(ctx context.Context) (migrationSummary, error) {
+	log := log.FromContext(ctx)
+
+	var summary migrationSummary
+
+	var secretCopiers secretsv1beta1.SecretCopierList
+
+	if err := m.List(ctx, &secretCopiers); err != nil {
+		return summary, err
+	}
+
+	for i := range secretCopiers.Items {
+		secretCopier := &secretCopiers.Items[i]
+
+		if !secretCopierNeedsMigration(secretCopier) {
+			continue
+		}
+
+		if m.ReportOnly {
+			log.Info("SecretCopier needs migration", "name", secretCopier.Name)
+
+			summary.pending++
+			continue
+		}
+
+		if secretCopier.Annotations == nil {
+			secretCopier.Annotations = make(map[string]string)
+		}
+
+		secretCopier.Annotations[schemaVersionAnnotation] = strconv.Itoa(currentSchemaVersion)
+
+		if err := m.Update(ctx, secretCopier); err != nil {
+			log.Error(err, "Unable to migrate SecretCopier", "name", secretCopier.Name)
+
+			summary.pending++
+			summary.failed++
+
+			continue
+		}
+
+		log.V(1).Info("Migrated SecretCopier", "name", secretCopier.Name)
+
+		migratedObjectsTotal.WithLabelValues(migrationKindSecretCopier).Inc()
+
+		summary.migrated++
+	}
+
+	return summary, nil
+}
+
+// Rewrite the markers of target secrets recorded using an earlier format.
+// Target secrets whose markers can't be read at all are left alone, as the
+// source secret they were copied from can't be determined.
+func (m *SchemaMigrator) migrateTargetSecrets(ctx context.Context) (migrationSummary, error) {
+	log := log.FromContext(ctx)
+
+	var summary migrationSummary
+
+	var secrets corev1.SecretList
+
+	if err := m.List(ctx, &secrets); err != nil {
+		return summary, err
+	}
+
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+
+		if !targetSecretNeedsMigration(secret) {
+			continue
+		}
+
+		source, ok := targetSecretSource(secret)
+
+		if !ok {
+			log.Info("Unable to migrate target secret with unreadable markers", "secret", secret.Name, "namespace", secret.Namespace)
+			continue
+		}
+
+		if m.ReportOnly {
+			log.Info("Target secret needs migration", "secret", secret.Name, "namespace", secret.Namespace)
+
+			summary.pending++
+			continue
+		}
+
+		patch := client.MergeFrom(secret.DeepCopy())
+
+		setTargetSecretMarkers(secret, secret.Annotations[secretCopierMarkerAnnotation], secretsv1beta1.SourceSecret{
+			Namespace: source.Namespace,
+			Name:      source.Name,
+		})
+
+		if err := m.Patch(ctx, secret, patch); err != nil {
+			if client.IgnoreNotFound(err) == nil {
+				continue
+			}
+
+			log.Error(err, "Unable to migrate target secret", "secret", secret.Name, "namespace", secret.Namespace)
+
+			summary.pending++
+			summary.failed++
+
+			continue
+		}
+
+		log.V(1).Info("Migrated target secret", "secret", secret.Name, "namespace", secret.Namespace)
+
+		migratedObjectsTotal.WithLabelValues(migrationKindTargetSecret).Inc()
+
+		summary.migrated++
+	}
+
+	return summary, nil
+}
+
+// Determine if a SecretCopier was last stored with an earlier schema version.
+func secretCopierNeedsMigration(secretCopier *secretsv1beta1.SecretCopier) bool {
+	version, err := strconv.Atoi(secretCopier.Annotations[schemaVersionAnnotation])
+
+	return err != nil || version < currentSchemaVersion
+}
+
+// Determine if a target secret has markers recorded using an earlier format,
+// including those written before the version of the format was recorded.
+func targetSecretNeedsMigration(secret *corev1.Secret) bool {
+	if secret.Annotations[secretCopierMarkerAnnotation] == "" {
+		return false
+	}
+
+	value, found := secret.Annotations[markerVersionMarkerAnnotation]
+
+	if !found {
+		return true
+	}
+
+	version, err := strconv.Atoi(value)
+
+	return err == nil && version >= 1 && version < currentMarkerVersion
+}
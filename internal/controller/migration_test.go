@@ -0,0 +1,114 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Schema Migrator", func() {
+	ctx := context.Background()
+
+	newClient := func() client.Client {
+		scheme := runtime.NewScheme()
+
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(secretsv1beta1.AddToScheme(scheme)).To(Succeed())
+
+		return fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{Name: "old-copier"},
+			},
+			&secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "current-copier",
+					Annotations: map[string]string{schemaVersionAnnotation: "1"},
+				},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "old-target",
+					Namespace: "migrate-target",
+					Annotations: map[string]string{
+						secretCopierMarkerAnnotation: "old-copier",
+						sourceSecretMarkerAnnotation: "migrate-source/registry",
+					},
+				},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "unreadable-target",
+					Namespace: "migrate-target",
+					Annotations: map[string]string{
+						secretCopierMarkerAnnotation: "old-copier",
+					},
+				},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "unmanaged", Namespace: "migrate-target"},
+			},
+		).Build()
+	}
+
+	It("should rewrite objects written by earlier versions", func() {
+		c := newClient()
+		migrator := &SchemaMigrator{Client: c}
+
+		Expect(migrator.migrate(ctx)).To(BeTrue())
+
+		secretCopier := &secretsv1beta1.SecretCopier{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "old-copier"}, secretCopier)).To(Succeed())
+		Expect(secretCopierNeedsMigration(secretCopier)).To(BeFalse())
+
+		secret := &corev1.Secret{}
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: "migrate-target", Name: "old-target"}, secret)).To(Succeed())
+		Expect(targetSecretNeedsMigration(secret)).To(BeFalse())
+		Expect(secret.Annotations[secretCopierMarkerAnnotation]).To(Equal("old-copier"))
+		Expect(secret.Annotations[sourceSecretMarkerAnnotation]).To(Equal("migrate-source/registry"))
+
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: "migrate-target", Name: "unreadable-target"}, secret)).To(Succeed())
+		Expect(secret.Annotations).NotTo(HaveKey(markerVersionMarkerAnnotation))
+	})
+
+	It("should only report objects needing migration in report-only mode", func() {
+		c := newClient()
+		migrator := &SchemaMigrator{Client: c, ReportOnly: true}
+
+		secretCopiers, err := migrator.migrateSecretCopiers(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secretCopiers).To(Equal(migrationSummary{pending: 1}))
+
+		targetSecrets, err := migrator.migrateTargetSecrets(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(targetSecrets).To(Equal(migrationSummary{pending: 1}))
+
+		secretCopier := &secretsv1beta1.SecretCopier{}
+		Expect(c.Get(ctx, client.ObjectKey{Name: "old-copier"}, secretCopier)).To(Succeed())
+		Expect(secretCopierNeedsMigration(secretCopier)).To(BeTrue())
+	})
+})
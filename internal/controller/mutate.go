@@ -0,0 +1,86 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/mutation"
+)
+
+// Apply the target mutator, if one is configured, to a target secret about to
+// be written. Only changes to the labels, annotations and data of the target
+// secret are kept. Changes to the annotations the manager uses to track target
+// secrets are discarded, and the data of an existing immutable target secret
+// is never changed.
+func (r *SecretCopierReconciler) mutateTargetSecret(ctx context.Context, secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, sourceSecret *corev1.Secret, targetSecret *corev1.Secret) error {
+	if r.Mutator == nil {
+		return nil
+	}
+
+	request := &mutation.Request{
+		SecretCopier: secretCopier.Name,
+		Rule:         ruleReference(rule),
+		Source:       sourceSecret.DeepCopy(),
+		Target:       targetSecret.DeepCopy(),
+	}
+
+	if err := r.Mutator.Mutate(ctx, request); err != nil {
+		return fmt.Errorf("mutator %s failed for target secret %s in namespace %s: %w",
+			r.Mutator.Name(), targetSecret.Name, targetSecret.Namespace, err)
+	}
+
+	mutated := request.Target
+
+	annotations := make(map[string]string, len(mutated.Annotations))
+
+	for key, value := range mutated.Annotations {
+		if !strings.HasPrefix(key, managerAnnotationPrefix) {
+			annotations[key] = value
+		}
+	}
+
+	for key, value := range targetSecret.Annotations {
+		if strings.HasPrefix(key, managerAnnotationPrefix) {
+			annotations[key] = value
+		}
+	}
+
+	targetSecret.Labels = mutated.Labels
+	targetSecret.Annotations = annotations
+
+	if targetSecret.ResourceVersion == "" || targetSecret.Immutable == nil || !*targetSecret.Immutable {
+		targetSecret.Data = mutated.Data
+	}
+
+	return nil
+}
+
+// Determine if the parts of a target secret which can be changed by a mutator
+// are the same for both secrets.
+func targetSecretContentEqual(a, b *corev1.Secret) bool {
+	return a.Type == b.Type && secretImmutable(a) == secretImmutable(b) &&
+		equality.Semantic.DeepEqual(a.Data, b.Data) &&
+		equality.Semantic.DeepEqual(a.Labels, b.Labels) &&
+		equality.Semantic.DeepEqual(a.Annotations, b.Annotations)
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/mutation"
+)
+
+var _ = Describe("Target Secret Mutation", func() {
+	ctx := context.Background()
+
+	secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "mutating-copier"}}
+
+	rule := &secretsv1beta1.SecretCopierRule{
+		SourceSecret:  secretsv1beta1.SourceSecret{Name: "registry", Namespace: "source"},
+		ReclaimPolicy: secretsv1beta1.ReclaimRetain,
+	}
+
+	// Mutator adding a label and a data key, and attempting to change the
+	// name and markers of the target secret.
+
+	calls := 0
+
+	mutator := mutation.Func(func(ctx context.Context, request *mutation.Request) error {
+		calls++
+
+		Expect(request.SecretCopier).To(Equal("mutating-copier"))
+		Expect(request.Rule).To(Equal("source/registry"))
+		Expect(request.Source.Data).To(HaveKey("password"))
+
+		target := request.Target
+
+		target.Name = "renamed"
+
+		if target.Labels == nil {
+			target.Labels = map[string]string{}
+		}
+
+		target.Labels["team.example.com/owner"] = "platform"
+		target.Annotations["secrets-manager.advok8s.io/secret-copier"] = "other-copier"
+		target.Annotations["team.example.com/contact"] = "platform@example.com"
+		target.Data["source"] = []byte(request.Source.Namespace)
+
+		return nil
+	})
+
+	var k8sClient client.Client
+
+	BeforeEach(func() {
+		calls = 0
+
+		k8sClient = fake.NewClientBuilder().WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "source"},
+				Data:       map[string][]byte{"password": []byte("s3cr3t-password")},
+			},
+		).Build()
+	})
+
+	It("should apply the mutator before the target secret is written", func() {
+		reconciler := &SecretCopierReconciler{Client: k8sClient, Recorder: record.NewFakeRecorder(10), Mutator: mutator}
+
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant-a")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyCreated))
+		Expect(calls).To(Equal(1))
+
+		targetSecret := &corev1.Secret{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "tenant-a", Name: "registry"}, targetSecret)).To(Succeed())
+
+		Expect(targetSecret.Labels).To(HaveKeyWithValue("team.example.com/owner", "platform"))
+		Expect(targetSecret.Annotations).To(HaveKeyWithValue("team.example.com/contact", "platform@example.com"))
+		Expect(targetSecret.Annotations).To(HaveKeyWithValue("secrets-manager.advok8s.io/secret-copier", "mutating-copier"))
+		Expect(targetSecret.Data).To(HaveKeyWithValue("source", []byte("source")))
+		Expect(targetSecret.Data).To(HaveKeyWithValue("password", []byte("s3cr3t-password")))
+
+		// The mutated target secret is in sync, so is not written again.
+
+		result, err = reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant-a")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyUnchanged))
+		Expect(calls).To(Equal(2))
+	})
+
+	It("should fail the copy when the mutator fails", func() {
+		failing := mutation.Func(func(ctx context.Context, request *mutation.Request) error {
+			return context.DeadlineExceeded
+		})
+
+		reconciler := &SecretCopierReconciler{Client: k8sClient, Recorder: record.NewFakeRecorder(10), Mutator: failing}
+
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant-a")
+
+		Expect(result).To(Equal(copyFailed))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("mutator func failed for target secret registry in namespace tenant-a"))
+
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "tenant-a", Name: "registry"}, &corev1.Secret{})).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,122 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Split a key used to track backoff for a target secret into the name of the
+// SecretCopier and the target namespace.
+func parseTargetBackoffKey(key string) (string, string, bool) {
+	parts := strings.SplitN(key, "/", 3)
+
+	if len(parts) != 3 {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+// Clear state held for target namespaces of a SecretCopier which have since
+// been deleted. Entries for the namespaces are removed from the failed targets
+// and denied namespaces in the status of the SecretCopier, and any backoff for
+// target secrets in them is forgotten so they are no longer retried. A single
+// event summarizing what was cleared is recorded against the SecretCopier.
+// Namespaces which are terminating still exist and are left alone.
+func (r *SecretCopierReconciler) forgetDeletedTargetNamespaces(ctx context.Context, secretCopier *secretsv1beta1.SecretCopier, namespaces []corev1.Namespace) {
+	log := log.FromContext(ctx)
+
+	existing := make(map[string]bool, len(namespaces))
+
+	for i := range namespaces {
+		existing[namespaces[i].Name] = true
+	}
+
+	deleted := make(map[string]bool)
+
+	var failedTargets []secretsv1beta1.SecretCopierFailedTarget
+
+	for _, failedTarget := range secretCopier.Status.FailedTargets {
+		if existing[failedTarget.Namespace] {
+			failedTargets = append(failedTargets, failedTarget)
+		} else {
+			deleted[failedTarget.Namespace] = true
+		}
+	}
+
+	clearedStatus := len(secretCopier.Status.FailedTargets) - len(failedTargets)
+
+	secretCopier.Status.FailedTargets = failedTargets
+
+	for i := range secretCopier.Status.Rules {
+		ruleStatus := &secretCopier.Status.Rules[i]
+
+		var deniedNamespaces []secretsv1beta1.SecretCopierDeniedTarget
+
+		for _, denied := range ruleStatus.DeniedNamespaces {
+			if existing[denied.Namespace] {
+				deniedNamespaces = append(deniedNamespaces, denied)
+			} else {
+				deleted[denied.Namespace] = true
+			}
+		}
+
+		clearedStatus += len(ruleStatus.DeniedNamespaces) - len(deniedNamespaces)
+
+		ruleStatus.DeniedNamespaces = deniedNamespaces
+	}
+
+	clearedBackoff := 0
+
+	r.deniedBackoff.forget(func(key string) bool {
+		name, namespace, ok := parseTargetBackoffKey(key)
+
+		if !ok || name != secretCopier.Name || existing[namespace] {
+			return false
+		}
+
+		deleted[namespace] = true
+		clearedBackoff++
+
+		return true
+	})
+
+	if len(deleted) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(deleted))
+
+	for namespace := range deleted {
+		names = append(names, namespace)
+	}
+
+	sort.Strings(names)
+
+	log.Info("Cleared state for deleted target namespaces of SecretCopier", "name", secretCopier.Name, "namespaces", names, "status", clearedStatus, "backoff", clearedBackoff)
+
+	r.Recorder.Eventf(secretCopier, corev1.EventTypeNormal, "TargetNamespacesDeleted",
+		"Cleared %d status entries and %d retry backoffs for deleted target namespaces %s", clearedStatus, clearedBackoff, strings.Join(names, ", "))
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Deleted Target Namespaces", func() {
+	ctx := context.Background()
+
+	newSecretCopier := func() *secretsv1beta1.SecretCopier {
+		return &secretsv1beta1.SecretCopier{
+			ObjectMeta: metav1.ObjectMeta{Name: "deletion-copier"},
+			Status: secretsv1beta1.SecretCopierStatus{
+				FailedTargets: []secretsv1beta1.SecretCopierFailedTarget{
+					{Namespace: "deletion-gone", Rule: 0, Reason: "Forbidden"},
+					{Namespace: "deletion-live", Rule: 0, Reason: "Forbidden"},
+				},
+				Rules: []secretsv1beta1.SecretCopierRuleStatus{
+					{
+						DeniedNamespaces: []secretsv1beta1.SecretCopierDeniedTarget{
+							{Namespace: "deletion-gone", Verb: "create", Resource: "secrets"},
+							{Namespace: "deletion-live", Verb: "create", Resource: "secrets"},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	namespaces := []corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "deletion-live"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "deletion-terminating"}, Status: corev1.NamespaceStatus{Phase: corev1.NamespaceTerminating}},
+	}
+
+	It("should split backoff keys into the SecretCopier and target namespace", func() {
+		secretCopier := newSecretCopier()
+
+		name, namespace, ok := parseTargetBackoffKey(targetBackoffKey(secretCopier, "deletion-gone", "registry"))
+
+		Expect(ok).To(BeTrue())
+		Expect(name).To(Equal("deletion-copier"))
+		Expect(namespace).To(Equal("deletion-gone"))
+
+		_, _, ok = parseTargetBackoffKey("deletion-copier/0")
+
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should clear status entries and backoff for deleted target namespaces", func() {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &SecretCopierReconciler{Recorder: recorder}
+
+		secretCopier := newSecretCopier()
+
+		now := time.Now()
+		denied := errors.New("denied")
+
+		reconciler.deniedBackoff.failed(targetBackoffKey(secretCopier, "deletion-gone", "registry"), now, time.Minute, time.Hour, denied)
+		reconciler.deniedBackoff.failed(targetBackoffKey(secretCopier, "deletion-terminating", "registry"), now, time.Minute, time.Hour, denied)
+		reconciler.deniedBackoff.failed("other-copier/deletion-gone/registry", now, time.Minute, time.Hour, denied)
+
+		reconciler.forgetDeletedTargetNamespaces(ctx, secretCopier, namespaces)
+
+		Expect(secretCopier.Status.FailedTargets).To(HaveLen(1))
+		Expect(secretCopier.Status.FailedTargets[0].Namespace).To(Equal("deletion-live"))
+
+		Expect(secretCopier.Status.Rules[0].DeniedNamespaces).To(HaveLen(1))
+		Expect(secretCopier.Status.Rules[0].DeniedNamespaces[0].Namespace).To(Equal("deletion-live"))
+
+		ready, _ := reconciler.deniedBackoff.ready(targetBackoffKey(secretCopier, "deletion-gone", "registry"), now)
+		Expect(ready).To(BeTrue())
+
+		ready, _ = reconciler.deniedBackoff.ready(targetBackoffKey(secretCopier, "deletion-terminating", "registry"), now)
+		Expect(ready).To(BeFalse())
+
+		ready, _ = reconciler.deniedBackoff.ready("other-copier/deletion-gone/registry", now)
+		Expect(ready).To(BeFalse())
+
+		var event string
+		Expect(recorder.Events).To(Receive(&event))
+		Expect(event).To(Equal("Normal TargetNamespacesDeleted Cleared 2 status entries and 1 retry backoffs for deleted target namespaces deletion-gone"))
+	})
+
+	It("should do nothing when no target namespace has been deleted", func() {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &SecretCopierReconciler{Recorder: recorder}
+
+		secretCopier := newSecretCopier()
+		secretCopier.Status.FailedTargets = secretCopier.Status.FailedTargets[1:]
+		secretCopier.Status.Rules[0].DeniedNamespaces = secretCopier.Status.Rules[0].DeniedNamespaces[1:]
+
+		reconciler.forgetDeletedTargetNamespaces(ctx, secretCopier, namespaces)
+
+		Expect(secretCopier.Status.FailedTargets).To(HaveLen(1))
+		Expect(secretCopier.Status.Rules[0].DeniedNamespaces).To(HaveLen(1))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+})
@@ -0,0 +1,188 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Set up the fast path for newly created namespaces. This is a separate
+// controller with its own work queue, keyed by namespace, so copying secrets
+// into a new namespace isn't held up behind the reconciliation of large
+// SecretCopiers by the main controller. Only namespaces created after the
+// controller started are handled, as existing namespaces are covered by the
+// initial reconciliation of each SecretCopier.
+func (r *SecretCopierReconciler) setupNewNamespaceFastPath(mgr ctrl.Manager, start time.Time) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("secretcopier-new-namespace").
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.NewNamespaceWorkers}).
+		For(&corev1.Namespace{}, builder.WithPredicates(namespaceCreatedSince(start))).
+		Complete(reconcile.Func(r.reconcileNewNamespace))
+}
+
+// Predicate which only lets through the creation of namespaces created at or
+// after the given time. Creation timestamps only have a resolution of
+// seconds, so the time is truncated to the second.
+func namespaceCreatedSince(start time.Time) predicate.Predicate {
+	start = start.Truncate(time.Second)
+
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return !e.Object.GetCreationTimestamp().Time.Before(start)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return false
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+// Determine whether a rule can be copied to a new namespace by the fast path.
+// Rules which depend on other rules, are being rolled out, limit how often
+// changes are propagated, wait on the target namespace, copy elsewhere than
+// the namespaces of this cluster, or may be blocked by the fan-out guard
+// depend on state only the full reconciliation of the SecretCopier works
+// out, so are left to it.
+func newNamespaceFastPathEligible(settings *ManagerSettings, rule *secretsv1beta1.SecretCopierRule) bool {
+	if !rule.IsEnabled() || rule.TargetCluster != nil || len(rule.DependsOn) != 0 {
+		return false
+	}
+
+	if rule.Rollout != nil || rule.MinInterval != nil {
+		return false
+	}
+
+	if rule.TargetNamespaceReadiness != nil || rule.RequiredResource != nil {
+		return false
+	}
+
+	return settings.FanOutGuardThreshold == 0 || rule.AllowLargeFanOut
+}
+
+// Copy the secrets of all rules matching a newly created namespace into it.
+// The SecretCopiers matching the namespace are still reconciled in full by
+// the main controller, which updates their status and takes care of rules
+// the fast path leaves alone, but finds the target secrets already copied.
+func (r *SecretCopierReconciler) reconcileNewNamespace(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	log := log.FromContext(ctx)
+
+	var namespace corev1.Namespace
+
+	if err := r.Get(ctx, req.NamespacedName, &namespace); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+
+	settings := r.Config.Settings()
+
+	if settings.NamespaceDenied(namespace.Name) {
+		return reconcile.Result{}, nil
+	}
+
+	var secretCopiers secretsv1beta1.SecretCopierList
+
+	if err := r.List(ctx, &secretCopiers); err != nil {
+		log.Error(err, "Unable to list SecretCopier objects")
+		return reconcile.Result{}, err
+	}
+
+	delegations, err := loadDelegationScopes(ctx, r.Client)
+
+	if err != nil {
+		log.Error(err, "Unable to list SecretCopierDelegation objects")
+		return reconcile.Result{}, err
+	}
+
+	copied := 0
+
+	for i := range secretCopiers.Items {
+		secretCopier := &secretCopiers.Items[i]
+
+		if !secretCopier.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		secretsClient, err := r.secretsClient(secretCopier)
+
+		if err != nil {
+			log.Error(err, "Unable to create client impersonating service account for SecretCopier", "name", secretCopier.Name)
+			continue
+		}
+
+		rules, ruleOrigins, err := expandSourceSecretPatterns(ctx, secretsClient, secretCopier.Spec.Rules)
+
+		if err != nil {
+			log.Error(err, "Unable to list source secrets matching rules of SecretCopier", "name", secretCopier.Name)
+			continue
+		}
+
+		matchers := r.matchers.get(secretCopier)
+
+		for ruleIndex := range rules {
+			rule := &rules[ruleIndex]
+
+			if !newNamespaceFastPathEligible(settings, rule) {
+				continue
+			}
+
+			if check, _ := r.checkTargetNamespace(ctx, settings, delegations, rule, matchers[ruleOrigins[ruleIndex]], &namespace); check != targetNamespaceEligible {
+				continue
+			}
+
+			result, err := r.copySecretToNamespace(ctx, secretCopier, rule, namespace.Name)
+
+			r.recordCopyEvent(secretCopier, rule, namespace.Name, r.currentTargetSecretName(ctx, rule), result, err)
+
+			if err != nil {
+				// Failures are left for the full reconciliation of the
+				// SecretCopier to retry and report in its status.
+
+				log.V(1).Info("Unable to copy secret to new namespace", "name", secretCopier.Name, "rule", ruleIndex, "namespace", namespace.Name, "error", err.Error())
+				continue
+			}
+
+			if result == copyCreated || result == copyUpdated {
+				log.V(1).Info("Copied secret to new namespace", "name", secretCopier.Name, "rule", ruleIndex, "namespace", namespace.Name)
+
+				copied++
+			}
+		}
+	}
+
+	if copied != 0 {
+		newNamespaceCopyLatency.Observe(time.Since(namespace.CreationTimestamp.Time).Seconds())
+	}
+
+	return reconcile.Result{}, nil
+}
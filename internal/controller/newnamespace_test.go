@@ -0,0 +1,186 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+var _ = Describe("New Namespace Fast Path", func() {
+	ctx := context.Background()
+
+	newRule := func(sourceName string) secretsv1beta1.SecretCopierRule {
+		return secretsv1beta1.SecretCopierRule{
+			SourceSecret: secretsv1beta1.SourceSecret{Name: sourceName, Namespace: "registries"},
+			TargetNamespaces: selectors.TargetNamespaces{
+				NameSelector: selectors.NameSelector{MatchNames: []string{"tenant-*"}},
+			},
+		}
+	}
+
+	It("should only handle namespaces created after the controller started", func() {
+		start := time.Date(2024, 9, 1, 10, 0, 0, 500, time.UTC)
+
+		created := func(at time.Time) *corev1.Namespace {
+			return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-a", CreationTimestamp: metav1.NewTime(at)}}
+		}
+
+		predicate := namespaceCreatedSince(start)
+
+		Expect(predicate.Create(event.CreateEvent{Object: created(start.Add(time.Minute))})).To(BeTrue())
+		Expect(predicate.Create(event.CreateEvent{Object: created(start.Truncate(time.Second))})).To(BeTrue())
+		Expect(predicate.Create(event.CreateEvent{Object: created(start.Add(-time.Hour))})).To(BeFalse())
+		Expect(predicate.Update(event.UpdateEvent{ObjectOld: created(start.Add(time.Minute)), ObjectNew: created(start.Add(time.Minute))})).To(BeFalse())
+	})
+
+	It("should leave rules depending on state of the full reconciliation to it", func() {
+		tests := []struct {
+			name     string
+			mutate   func(rule *secretsv1beta1.SecretCopierRule)
+			guarded  bool
+			eligible bool
+		}{
+			{name: "plain", mutate: func(rule *secretsv1beta1.SecretCopierRule) {}, eligible: true},
+			{name: "disabled", mutate: func(rule *secretsv1beta1.SecretCopierRule) { rule.Enabled = new(bool) }, eligible: false},
+			{name: "depends on", mutate: func(rule *secretsv1beta1.SecretCopierRule) { rule.DependsOn = []string{"ca"} }, eligible: false},
+			{name: "rollout", mutate: func(rule *secretsv1beta1.SecretCopierRule) { rule.Rollout = &secretsv1beta1.SecretCopierRollout{} }, eligible: false},
+			{name: "min interval", mutate: func(rule *secretsv1beta1.SecretCopierRule) {
+				rule.MinInterval = &metav1.Duration{Duration: time.Minute}
+			}, eligible: false},
+			{name: "readiness", mutate: func(rule *secretsv1beta1.SecretCopierRule) {
+				rule.TargetNamespaceReadiness = &secretsv1beta1.NamespaceReadiness{}
+			}, eligible: false},
+			{name: "fan-out guard", mutate: func(rule *secretsv1beta1.SecretCopierRule) {}, guarded: true, eligible: false},
+			{name: "large fan-out allowed", mutate: func(rule *secretsv1beta1.SecretCopierRule) { rule.AllowLargeFanOut = true }, guarded: true, eligible: true},
+		}
+
+		for _, test := range tests {
+			rule := newRule("registry")
+			test.mutate(&rule)
+
+			settings := newManagerSettings(ManagerSettings{})
+
+			if test.guarded {
+				settings.FanOutGuardThreshold = 10
+			}
+
+			Expect(newNamespaceFastPathEligible(settings, &rule)).To(Equal(test.eligible), test.name)
+		}
+	})
+
+	It("should copy secrets of matching rules into a new namespace", func() {
+		rolloutRule := newRule("tls")
+		rolloutRule.Rollout = &secretsv1beta1.SecretCopierRollout{}
+
+		consentRule := newRule("database")
+		consentRule.RequireNamespaceConsent = true
+
+		k8sClient := fake.NewClientBuilder().WithObjects(
+			&secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{Name: "tenant-copier"},
+				Spec: secretsv1beta1.SecretCopierSpec{
+					Rules: []secretsv1beta1.SecretCopierRule{newRule("registry"), rolloutRule, consentRule},
+				},
+			},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-a", CreationTimestamp: metav1.Now()}},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "registries"},
+				Data:       map[string][]byte{"token": []byte("registry-token")},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "tls", Namespace: "registries"},
+				Data:       map[string][]byte{"tls.crt": []byte("certificate")},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "registries"},
+				Data:       map[string][]byte{"password": []byte("database-password")},
+			},
+		).Build()
+
+		reconciler := &SecretCopierReconciler{Client: k8sClient, Recorder: record.NewFakeRecorder(10)}
+
+		_, err := reconciler.reconcileNewNamespace(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "tenant-a"}})
+
+		Expect(err).NotTo(HaveOccurred())
+
+		tests := []struct {
+			name   string
+			copied bool
+		}{
+			{name: "registry", copied: true},
+			{name: "tls", copied: false},
+			{name: "database", copied: false},
+		}
+
+		for _, test := range tests {
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "tenant-a", Name: test.name}, &corev1.Secret{})
+
+			if test.copied {
+				Expect(err).NotTo(HaveOccurred(), test.name)
+			} else {
+				Expect(err).To(HaveOccurred(), test.name)
+			}
+		}
+	})
+
+	It("should name target secrets with a content hash suffix in events", func() {
+		rule := newRule("registry")
+		rule.TargetSecret.HashSuffix = &secretsv1beta1.TargetSecretHashSuffix{}
+
+		sourceSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "registries"},
+			Data:       map[string][]byte{"token": []byte("registry-token")},
+		}
+
+		k8sClient := fake.NewClientBuilder().WithObjects(
+			&secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{Name: "tenant-copier"},
+				Spec:       secretsv1beta1.SecretCopierSpec{Rules: []secretsv1beta1.SecretCopierRule{rule}},
+			},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-a", CreationTimestamp: metav1.Now()}},
+			sourceSecret,
+		).Build()
+
+		recorder := record.NewFakeRecorder(10)
+
+		reconciler := &SecretCopierReconciler{Client: k8sClient, Recorder: recorder, CopyEvents: true}
+
+		_, err := reconciler.reconcileNewNamespace(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "tenant-a"}})
+
+		Expect(err).NotTo(HaveOccurred())
+
+		hashedName := hashedTargetSecretName(&rule, sourceSecret)
+
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "tenant-a", Name: hashedName}, &corev1.Secret{})).To(Succeed())
+		Expect(recorder.Events).To(Receive(Equal("Normal SecretCreated Secret tenant-a/" + hashedName + " created from registries/registry")))
+	})
+})
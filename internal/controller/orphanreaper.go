@@ -0,0 +1,262 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// OrphanReaper periodically looks for target secrets created by a SecretCopier
+// with a reclaim policy of Delete which are no longer wanted, and deletes them
+// once they have remained orphaned for the grace period. A target secret is
+// orphaned when its SecretCopier no longer exists, when the SecretCopier no
+// longer has a rule for it or its namespace, or when the source secret has
+// been deleted. Target secrets with a reclaim policy of Retain are never
+// deleted.
+type OrphanReaper struct {
+	client.Client
+
+	// Interval between scans for orphaned secrets.
+	Interval time.Duration
+
+	// Time a secret must remain orphaned before it is deleted.
+	GracePeriod time.Duration
+
+	// If set, orphaned secrets are reported but never deleted.
+	ReportOnly bool
+
+	// Controller wide settings, consulted for whether writes to secrets are
+	// frozen, in which case orphaned secrets are not deleted until the freeze
+	// expires.
+	Config *ManagerConfig
+
+	// Time at which each orphaned secret was first seen, keyed by the UID of
+	// the secret.
+	firstSeen map[string]time.Time
+}
+
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=secretcopiers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+
+// Start scanning for orphaned secrets. Implements manager.Runnable, and as it
+// doesn't implement LeaderElectionRunnable, only runs when elected leader.
+func (r *OrphanReaper) Start(ctx context.Context) error {
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithName("orphan-reaper"))
+
+	r.firstSeen = make(map[string]time.Time)
+
+	wait.UntilWithContext(ctx, r.reap, r.Interval)
+
+	return nil
+}
+
+// Scan for orphaned secrets and delete any which have been orphaned for
+// longer than the grace period.
+func (r *OrphanReaper) reap(ctx context.Context) {
+	log := log.FromContext(ctx)
+
+	var secretCopiers secretsv1beta1.SecretCopierList
+
+	if err := r.List(ctx, &secretCopiers); err != nil {
+		log.Error(err, "Unable to list SecretCopier objects")
+		return
+	}
+
+	var namespaces corev1.NamespaceList
+
+	if err := r.List(ctx, &namespaces); err != nil {
+		log.Error(err, "Unable to list namespaces")
+		return
+	}
+
+	var secrets corev1.SecretList
+
+	if err := r.List(ctx, &secrets); err != nil {
+		log.Error(err, "Unable to list secrets")
+		return
+	}
+
+	copiers := make(map[string]*secretsv1beta1.SecretCopier, len(secretCopiers.Items))
+
+	for i := range secretCopiers.Items {
+		copiers[secretCopiers.Items[i].Name] = &secretCopiers.Items[i]
+	}
+
+	namespacesByName := make(map[string]*corev1.Namespace, len(namespaces.Items))
+
+	for i := range namespaces.Items {
+		namespacesByName[namespaces.Items[i].Name] = &namespaces.Items[i]
+	}
+
+	secretsByKey := make(map[client.ObjectKey]*corev1.Secret, len(secrets.Items))
+
+	for i := range secrets.Items {
+		secretsByKey[client.ObjectKeyFromObject(&secrets.Items[i])] = &secrets.Items[i]
+	}
+
+	now := time.Now()
+
+	seen := make(map[string]time.Time)
+
+	var orphaned float64
+
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+
+		reason := orphanedSecretReason(secret, copiers, namespacesByName, secretsByKey)
+
+		if reason == "" || targetSecretIgnored(secret) {
+			continue
+		}
+
+		orphaned++
+
+		firstSeen, found := r.firstSeen[string(secret.UID)]
+
+		if !found {
+			firstSeen = now
+
+			log.V(1).Info("Found orphaned secret", "secret", secret.Name, "namespace", secret.Namespace, "reason", reason)
+		}
+
+		if now.Sub(firstSeen) < r.GracePeriod {
+			seen[string(secret.UID)] = firstSeen
+			continue
+		}
+
+		if r.ReportOnly || r.Config.WritesFrozen() {
+			log.Info("Writes suspended, skipping delete of orphaned secret", "secret", secret.Name, "namespace", secret.Namespace, "reason", reason)
+
+			countSkippedWrite("orphanreaper", "delete", r.ReportOnly)
+
+			seen[string(secret.UID)] = firstSeen
+
+			continue
+		}
+
+		// Only delete the secret if it hasn't changed since it was checked.
+
+		preconditions := client.Preconditions{
+			UID:             &secret.UID,
+			ResourceVersion: &secret.ResourceVersion,
+		}
+
+		if err := r.Delete(ctx, secret, preconditions); client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Unable to delete orphaned secret", "secret", secret.Name, "namespace", secret.Namespace)
+
+			seen[string(secret.UID)] = firstSeen
+
+			continue
+		}
+
+		log.Info("Deleted orphaned secret", "secret", secret.Name, "namespace", secret.Namespace, "reason", reason)
+
+		orphanedSecretsRemovedTotal.WithLabelValues(reason).Inc()
+	}
+
+	// Only remember secrets which are still orphaned so a secret which is
+	// adopted again starts a new grace period if later orphaned.
+
+	r.firstSeen = seen
+
+	orphanedSecrets.Set(orphaned)
+}
+
+// Reasons a target secret is considered to be orphaned.
+const (
+	orphanedCopierDeleted = "CopierDeleted"
+	orphanedRuleRemoved   = "RuleRemoved"
+	orphanedSourceDeleted = "SourceDeleted"
+	orphanedNotTargeted   = "NamespaceNotTargeted"
+)
+
+// Determine whether a secret is an orphaned target secret which should be
+// deleted, returning the reason if it is, or an empty string if not. Only
+// secrets owned by a SecretCopier, which is the case when the reclaim policy
+// of the rule is Delete, are considered.
+func orphanedSecretReason(secret *corev1.Secret, copiers map[string]*secretsv1beta1.SecretCopier, namespaces map[string]*corev1.Namespace, secrets map[client.ObjectKey]*corev1.Secret) string {
+	copierName := secret.Annotations["secrets-manager.advok8s.io/secret-copier"]
+
+	if copierName == "" {
+		return ""
+	}
+
+	owner := metav1.GetControllerOf(secret)
+
+	if owner == nil || owner.Kind != "SecretCopier" || owner.Name != copierName {
+		return ""
+	}
+
+	secretCopier, found := copiers[copierName]
+
+	if !found || secretCopier.UID != owner.UID {
+		return orphanedCopierDeleted
+	}
+
+	// Look for a rule of the SecretCopier which still targets the secret. As
+	// different rules could copy the same source secret to the same target
+	// name in different namespaces, all rules need to be checked.
+
+	reason := orphanedRuleRemoved
+
+	for i := range secretCopier.Spec.Rules {
+		rule := ruleForTargetSecret(&secretCopier.Spec.Rules[i], secret)
+
+		if rule.TargetCluster != nil {
+			continue
+		}
+
+		if !targetSecretSourceMatches(secret, rule.SourceSecret) || !targetSecretNameMatches(rule, secret) {
+			continue
+		}
+
+		namespace, found := namespaces[secret.Namespace]
+
+		reclaimPolicy := rule.ReclaimPolicy
+
+		if found {
+			reclaimPolicy = rule.ReclaimPolicyFor(namespace)
+		}
+
+		if reclaimPolicy != secretsv1beta1.ReclaimDelete || !rule.IsEnabled() {
+			return ""
+		}
+
+		if !found || !rule.TargetNamespaces.Matches(namespace) {
+			reason = orphanedNotTargeted
+			continue
+		}
+
+		if _, found := secrets[sourceSecretKey(secret)]; !found {
+			return orphanedSourceDeleted
+		}
+
+		return ""
+	}
+
+	return reason
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+var _ = Describe("Orphan Reaper", func() {
+	secretCopier := &secretsv1beta1.SecretCopier{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "reaper-copier",
+			UID:  "reaper-copier-uid",
+		},
+		Spec: secretsv1beta1.SecretCopierSpec{
+			Rules: []secretsv1beta1.SecretCopierRule{
+				{
+					SourceSecret: secretsv1beta1.SourceSecret{
+						Namespace: "reaper-source",
+						Name:      "reaper-secret",
+					},
+					TargetNamespaces: selectors.TargetNamespaces{
+						NameSelector: selectors.NameSelector{
+							MatchNames: []string{"reaper-target-*"},
+						},
+					},
+					ReclaimPolicy: secretsv1beta1.ReclaimDelete,
+				},
+			},
+		},
+	}
+
+	sourceSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "reaper-source",
+			Name:      "reaper-secret",
+		},
+	}
+
+	namespace := func(name string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+
+	targetSecret := func(namespace string, owned bool) *corev1.Secret {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      "reaper-secret",
+				Annotations: map[string]string{
+					"secrets-manager.advok8s.io/secret-copier": "reaper-copier",
+					"secrets-manager.advok8s.io/secret-name":   "reaper-source/reaper-secret",
+				},
+			},
+		}
+
+		if owned {
+			secret.OwnerReferences = []metav1.OwnerReference{
+				{
+					APIVersion: secretsv1beta1.GroupVersion.String(),
+					Kind:       "SecretCopier",
+					Name:       "reaper-copier",
+					UID:        "reaper-copier-uid",
+					Controller: ptr.To(true),
+				},
+			}
+		}
+
+		return secret
+	}
+
+	copiers := map[string]*secretsv1beta1.SecretCopier{
+		secretCopier.Name: secretCopier,
+	}
+
+	namespaces := map[string]*corev1.Namespace{
+		"reaper-source":   namespace("reaper-source"),
+		"reaper-target-1": namespace("reaper-target-1"),
+		"reaper-other-1":  namespace("reaper-other-1"),
+	}
+
+	secrets := map[client.ObjectKey]*corev1.Secret{
+		client.ObjectKeyFromObject(sourceSecret): sourceSecret,
+	}
+
+	It("should not treat a wanted target secret as orphaned", func() {
+		Expect(orphanedSecretReason(targetSecret("reaper-target-1", true), copiers, namespaces, secrets)).To(BeEmpty())
+	})
+
+	It("should not treat a retained target secret as orphaned", func() {
+		Expect(orphanedSecretReason(targetSecret("reaper-other-1", false), copiers, namespaces, secrets)).To(BeEmpty())
+	})
+
+	It("should treat a target secret of a deleted copier as orphaned", func() {
+		Expect(orphanedSecretReason(targetSecret("reaper-target-1", true), nil, namespaces, secrets)).To(Equal(orphanedCopierDeleted))
+	})
+
+	It("should treat a target secret in a namespace no longer matched as orphaned", func() {
+		Expect(orphanedSecretReason(targetSecret("reaper-other-1", true), copiers, namespaces, secrets)).To(Equal(orphanedNotTargeted))
+	})
+
+	It("should treat a target secret whose source was deleted as orphaned", func() {
+		Expect(orphanedSecretReason(targetSecret("reaper-target-1", true), copiers, namespaces, nil)).To(Equal(orphanedSourceDeleted))
+	})
+
+	It("should not treat a target secret of a disabled rule as orphaned", func() {
+		disabledCopier := secretCopier.DeepCopy()
+		disabledCopier.Spec.Rules[0].Enabled = ptr.To(false)
+
+		disabledCopiers := map[string]*secretsv1beta1.SecretCopier{
+			disabledCopier.Name: disabledCopier,
+		}
+
+		Expect(orphanedSecretReason(targetSecret("reaper-other-1", true), disabledCopiers, namespaces, nil)).To(BeEmpty())
+	})
+
+	It("should not treat a target secret in a namespace overridden to be retained as orphaned", func() {
+		overriddenCopier := secretCopier.DeepCopy()
+		overriddenCopier.Spec.Rules[0].ReclaimPolicyOverrides = []secretsv1beta1.ReclaimPolicyOverride{
+			{
+				TargetNamespaces: selectors.TargetNamespaces{
+					NameSelector: selectors.NameSelector{
+						MatchNames: []string{"reaper-other-*"},
+					},
+				},
+				ReclaimPolicy: secretsv1beta1.ReclaimRetain,
+			},
+		}
+
+		overriddenCopiers := map[string]*secretsv1beta1.SecretCopier{
+			overriddenCopier.Name: overriddenCopier,
+		}
+
+		Expect(orphanedSecretReason(targetSecret("reaper-other-1", true), overriddenCopiers, namespaces, secrets)).To(BeEmpty())
+		Expect(orphanedSecretReason(targetSecret("reaper-target-1", true), overriddenCopiers, namespaces, nil)).To(Equal(orphanedSourceDeleted))
+	})
+})
@@ -0,0 +1,79 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Maximum total size of the data of a secret accepted by the API server.
+const maxSecretSize = 1024 * 1024
+
+// Error returned when a target secret would be larger than the API server
+// accepts.
+type secretTooLargeError struct {
+	Namespace string
+	Name      string
+	Size      int
+}
+
+func (e *secretTooLargeError) Error() string {
+	return fmt.Sprintf("target secret %s in namespace %s would hold %d bytes of data, exceeding the limit of %d bytes",
+		e.Name, e.Namespace, e.Size, maxSecretSize)
+}
+
+// Calculate the total size of the data of a secret, the same as the API
+// server does when validating it.
+func secretDataSize(secret *corev1.Secret) int {
+	size := 0
+
+	for _, value := range secret.Data {
+		size += len(value)
+	}
+
+	for _, value := range secret.StringData {
+		size += len(value)
+	}
+
+	return size
+}
+
+// Check that a target secret about to be written is within the size limit of
+// the API server. If it isn't, a warning event giving the size is recorded
+// against the SecretCopier and an error returned, so the failure is reported
+// clearly rather than as a generic rejection by the API server.
+func (r *SecretCopierReconciler) checkTargetSecretSize(secretCopier *secretsv1beta1.SecretCopier, targetSecret *corev1.Secret) error {
+	size := secretDataSize(targetSecret)
+
+	if size <= maxSecretSize {
+		return nil
+	}
+
+	err := &secretTooLargeError{
+		Namespace: targetSecret.Namespace,
+		Name:      targetSecret.Name,
+		Size:      size,
+	}
+
+	r.Recorder.Eventf(secretCopier, corev1.EventTypeWarning, "SecretTooLarge", "Unable to write %s", err.Error())
+
+	return err
+}
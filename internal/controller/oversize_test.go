@@ -0,0 +1,73 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Oversized Target Secrets", func() {
+	secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "oversize-copier"}}
+
+	targetSecret := func(sizes ...int) *corev1.Secret {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "bundle", Namespace: "tenant-a"},
+			Data:       map[string][]byte{},
+		}
+
+		for i, size := range sizes {
+			secret.Data[strings.Repeat("k", i+1)] = make([]byte, size)
+		}
+
+		return secret
+	}
+
+	It("should allow target secrets within the size limit", func() {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &SecretCopierReconciler{Recorder: recorder}
+
+		Expect(reconciler.checkTargetSecretSize(secretCopier, targetSecret(maxSecretSize/2, maxSecretSize/2))).To(Succeed())
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("should reject target secrets over the size limit with an event giving the size", func() {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &SecretCopierReconciler{Recorder: recorder}
+
+		err := reconciler.checkTargetSecretSize(secretCopier, targetSecret(maxSecretSize/2, maxSecretSize/2+1))
+
+		var tooLarge *secretTooLargeError
+
+		Expect(errors.As(err, &tooLarge)).To(BeTrue())
+		Expect(tooLarge.Size).To(Equal(maxSecretSize + 1))
+
+		Expect(recorder.Events).To(Receive(Equal(
+			"Warning SecretTooLarge Unable to write target secret bundle in namespace tenant-a would hold 1048577 bytes of data, exceeding the limit of 1048576 bytes")))
+
+		Expect(newFailedTarget(0, "tenant-a", copyFailed, err).Reason).To(Equal("Oversize"))
+	})
+})
@@ -0,0 +1,87 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Delays used when backing off from targets where the controller has been
+// denied permission to manage the target secret.
+const (
+	permissionDeniedInitialBackoff = 5 * time.Second
+	permissionDeniedMaxBackoff     = 5 * time.Minute
+)
+
+// Error returned when the controller is forbidden from performing an operation
+// on a target secret.
+type permissionDeniedError struct {
+	Verb      string
+	Resource  string
+	Namespace string
+	Err       error
+}
+
+func (e *permissionDeniedError) Error() string {
+	return fmt.Sprintf("permission denied to %s %s in namespace %s: %v", e.Verb, e.Resource, e.Namespace, e.Err)
+}
+
+func (e *permissionDeniedError) Unwrap() error {
+	return e.Err
+}
+
+// Wrap a Forbidden error from the API server in an error recording the
+// operation on target secrets which was denied.
+func newPermissionDeniedError(verb string, namespace string, err error) error {
+	return &permissionDeniedError{
+		Verb:      verb,
+		Resource:  "secrets",
+		Namespace: namespace,
+		Err:       err,
+	}
+}
+
+// Key used to track backoff for a target secret of a SecretCopier.
+func targetBackoffKey(secretCopier *secretsv1beta1.SecretCopier, targetNamespace string, targetSecretName string) string {
+	return secretCopier.Name + "/" + targetNamespace + "/" + targetSecretName
+}
+
+// Record that the controller was denied permission to manage a target secret.
+// An event is recorded against the SecretCopier giving the verb and resource
+// which were denied, the metric for denials is incremented, and the target is
+// put into backoff so it isn't retried on every reconcile.
+func (r *SecretCopierReconciler) recordPermissionDenied(secretCopier *secretsv1beta1.SecretCopier, targetSecretName string, err error) {
+	var denied *permissionDeniedError
+
+	if !errors.As(err, &denied) {
+		return
+	}
+
+	r.Recorder.Eventf(secretCopier, corev1.EventTypeWarning, "PermissionDenied",
+		"Permission denied to %s %s in namespace %s for secret %s", denied.Verb, denied.Resource, denied.Namespace, targetSecretName)
+
+	permissionDeniedTotal.WithLabelValues(secretCopier.Name, denied.Verb, denied.Resource).Inc()
+
+	r.deniedBackoff.failed(targetBackoffKey(secretCopier, denied.Namespace, targetSecretName), time.Now(),
+		permissionDeniedInitialBackoff, permissionDeniedMaxBackoff, err)
+}
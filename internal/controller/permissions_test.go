@@ -0,0 +1,102 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Permission Denied", func() {
+	secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "denied-copier"}}
+
+	forbidden := apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "registry", errors.New("no access"))
+
+	AfterEach(func() {
+		deleteSecretCopierMetrics(secretCopier.Name)
+	})
+
+	It("should record an event and metric and back off the target", func() {
+		recorder := record.NewFakeRecorder(10)
+
+		reconciler := &SecretCopierReconciler{Recorder: recorder}
+
+		key := targetBackoffKey(secretCopier, "tenant-a", "registry")
+
+		start := time.Now()
+
+		reconciler.recordPermissionDenied(secretCopier, "registry", newPermissionDeniedError("update", "tenant-a", forbidden))
+
+		var event string
+		Expect(recorder.Events).To(Receive(&event))
+		Expect(event).To(Equal("Warning PermissionDenied Permission denied to update secrets in namespace tenant-a for secret registry"))
+
+		Expect(testutil.ToFloat64(permissionDeniedTotal.WithLabelValues("denied-copier", "update", "secrets"))).To(Equal(1.0))
+
+		ready, err := reconciler.deniedBackoff.ready(key, time.Now())
+
+		Expect(ready).To(BeFalse())
+		Expect(apierrors.IsForbidden(err)).To(BeTrue())
+
+		retryAt, backingOff := reconciler.deniedBackoff.retryTime(key)
+
+		Expect(backingOff).To(BeTrue())
+		Expect(retryAt).To(BeTemporally(">=", start.Add(permissionDeniedInitialBackoff)))
+		Expect(retryAt).To(BeTemporally("<=", time.Now().Add(permissionDeniedInitialBackoff)))
+
+		// A further denial doubles the delay before the target is retried.
+
+		reconciler.recordPermissionDenied(secretCopier, "registry", newPermissionDeniedError("update", "tenant-a", forbidden))
+
+		Expect(testutil.ToFloat64(permissionDeniedTotal.WithLabelValues("denied-copier", "update", "secrets"))).To(Equal(2.0))
+
+		retryAt, _ = reconciler.deniedBackoff.retryTime(key)
+
+		Expect(retryAt).To(BeTemporally(">=", start.Add(2*permissionDeniedInitialBackoff)))
+
+		ready, _ = reconciler.deniedBackoff.ready(key, time.Now().Add(permissionDeniedMaxBackoff))
+
+		Expect(ready).To(BeTrue())
+	})
+
+	It("should ignore errors other than permission being denied", func() {
+		recorder := record.NewFakeRecorder(10)
+
+		reconciler := &SecretCopierReconciler{Recorder: recorder}
+
+		reconciler.recordPermissionDenied(secretCopier, "registry", forbidden)
+
+		Expect(recorder.Events).NotTo(Receive())
+
+		Expect(permissionDeniedTotal.DeleteLabelValues("denied-copier", "update", "secrets")).To(BeFalse())
+
+		_, backingOff := reconciler.deniedBackoff.retryTime(targetBackoffKey(secretCopier, "tenant-a", "registry"))
+
+		Expect(backingOff).To(BeFalse())
+	})
+})
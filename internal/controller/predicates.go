@@ -0,0 +1,205 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Predicate which filters out updates to namespaces which cannot change the
+// outcome of matching the namespace against the rules of any SecretCopier.
+// Namespaces are updated frequently for reasons unrelated to selectors, such
+// as changes to status or to labels no selector refers to, so only changes to
+// owner references, to the phase, decommissioning label, vcluster label or
+// consent annotation which decide whether a namespace is skipped, and to
+// labels or annotations referenced by a selector, canary selector or
+// readiness signal of a current rule, or by the target namespace selector of
+// a delegation, are let through. Creation and deletion of namespaces always
+// pass.
+func (r *SecretCopierReconciler) namespaceSelectorFieldsChanged() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldNamespace, ok := e.ObjectOld.(*corev1.Namespace)
+
+			if !ok {
+				return true
+			}
+
+			newNamespace, ok := e.ObjectNew.(*corev1.Namespace)
+
+			if !ok {
+				return true
+			}
+
+			if !equality.Semantic.DeepEqual(oldNamespace.OwnerReferences, newNamespace.OwnerReferences) {
+				return true
+			}
+
+			if oldNamespace.Status.Phase != newNamespace.Status.Phase ||
+				oldNamespace.Labels[secretsv1beta1.NamespaceDecommissioningLabel] != newNamespace.Labels[secretsv1beta1.NamespaceDecommissioningLabel] ||
+				secretsv1beta1.VirtualClusterOf(oldNamespace) != secretsv1beta1.VirtualClusterOf(newNamespace) ||
+				secretsv1beta1.NamespaceAcceptsCopies(oldNamespace) != secretsv1beta1.NamespaceAcceptsCopies(newNamespace) {
+				return true
+			}
+
+			labelKeys, annotationKeys, err := r.namespaceKeys(context.Background())
+
+			if err != nil {
+				// Err on the side of reconciling if we can't tell what
+				// labels are referenced.
+
+				return true
+			}
+
+			for key := range labelKeys {
+				oldValue, oldOk := oldNamespace.Labels[key]
+				newValue, newOk := newNamespace.Labels[key]
+
+				if oldOk != newOk || oldValue != newValue {
+					return true
+				}
+			}
+
+			for key := range annotationKeys {
+				oldValue, oldOk := oldNamespace.Annotations[key]
+				newValue, newOk := newNamespace.Annotations[key]
+
+				if oldOk != newOk || oldValue != newValue {
+					return true
+				}
+			}
+
+			return false
+		},
+	}
+}
+
+// Predicate which filters out updates to secrets which do not change the type,
+// data or labels of the secret. These are the only parts of a source secret
+// which are copied to a target secret, so changes to anything else, such as
+// owner references or managed fields, cannot require the target secret to be
+// updated. Creation and deletion of secrets always pass.
+func secretContentChanged() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldSecret, ok := e.ObjectOld.(*corev1.Secret)
+
+			if !ok {
+				return true
+			}
+
+			newSecret, ok := e.ObjectNew.(*corev1.Secret)
+
+			if !ok {
+				return true
+			}
+
+			if oldSecret.Type != newSecret.Type {
+				return true
+			}
+
+			if !equality.Semantic.DeepEqual(oldSecret.Data, newSecret.Data) {
+				return true
+			}
+
+			if !equality.Semantic.DeepEqual(oldSecret.Labels, newSecret.Labels) {
+				return true
+			}
+
+			return false
+		},
+	}
+}
+
+// Calculate the sets of namespace label and annotation keys referenced by the
+// target namespace selectors, canary namespace selectors and readiness
+// signals of the rules of all SecretCopier objects, and by the target
+// namespace selectors of all SecretCopierDelegation objects, as a namespace
+// moving into or out of a delegated scope changes where secrets are copied.
+func (r *SecretCopierReconciler) namespaceKeys(ctx context.Context) (map[string]struct{}, map[string]struct{}, error) {
+	log := log.FromContext(ctx)
+
+	var secretCopiers secretsv1beta1.SecretCopierList
+
+	if err := r.List(ctx, &secretCopiers, &client.ListOptions{}); err != nil {
+		log.Error(err, "Unable to list SecretCopier objects")
+		return nil, nil, err
+	}
+
+	labelKeys := make(map[string]struct{})
+	annotationKeys := make(map[string]struct{})
+
+	for _, secretCopier := range secretCopiers.Items {
+		for _, rule := range secretCopier.Spec.Rules {
+			for _, key := range rule.TargetNamespaces.LabelKeys() {
+				labelKeys[key] = struct{}{}
+			}
+
+			for _, key := range rule.TargetNamespaces.AnnotationKeys() {
+				annotationKeys[key] = struct{}{}
+			}
+
+			if rule.Rollout != nil {
+				for _, key := range rule.Rollout.CanaryNamespaces.LabelKeys() {
+					labelKeys[key] = struct{}{}
+				}
+
+				for _, key := range rule.Rollout.CanaryNamespaces.AnnotationKeys() {
+					annotationKeys[key] = struct{}{}
+				}
+			}
+
+			if readiness := rule.TargetNamespaceReadiness; readiness != nil {
+				for key := range readiness.MatchLabels {
+					labelKeys[key] = struct{}{}
+				}
+
+				for key := range readiness.MatchAnnotations {
+					annotationKeys[key] = struct{}{}
+				}
+			}
+		}
+	}
+
+	var delegations secretsv1beta1.SecretCopierDelegationList
+
+	if err := r.List(ctx, &delegations); err != nil {
+		log.Error(err, "Unable to list SecretCopierDelegation objects")
+		return nil, nil, err
+	}
+
+	for _, delegation := range delegations.Items {
+		for _, key := range delegation.Spec.TargetNamespaces.LabelKeys() {
+			labelKeys[key] = struct{}{}
+		}
+
+		for _, key := range delegation.Spec.TargetNamespaces.AnnotationKeys() {
+			annotationKeys[key] = struct{}{}
+		}
+	}
+
+	return labelKeys, annotationKeys, nil
+}
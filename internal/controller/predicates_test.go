@@ -0,0 +1,213 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+var _ = Describe("Predicates", func() {
+	Context("When filtering namespace updates", func() {
+		newReconciler := func(objects ...client.Object) *SecretCopierReconciler {
+			objects = append(objects, &secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{Name: "predicate-copier"},
+				Spec: secretsv1beta1.SecretCopierSpec{
+					Rules: []secretsv1beta1.SecretCopierRule{
+						{
+							SourceSecret: secretsv1beta1.SourceSecret{Name: "registry", Namespace: "registries"},
+							TargetNamespaces: selectors.TargetNamespaces{
+								LabelSelector: selectors.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+							},
+							TargetNamespaceReadiness: &secretsv1beta1.NamespaceReadiness{
+								MatchAnnotations: map[string]string{"example.com/ready": "true"},
+							},
+						},
+					},
+				},
+			})
+
+			return &SecretCopierReconciler{Client: fake.NewClientBuilder().WithObjects(objects...).Build()}
+		}
+
+		newNamespace := func(labels map[string]string, annotations map[string]string) *corev1.Namespace {
+			return &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: "tenant-a", Labels: labels, Annotations: annotations},
+				Status:     corev1.NamespaceStatus{Phase: corev1.NamespaceActive},
+			}
+		}
+
+		It("should only pass updates which can change the outcome of matching", func() {
+			predicate := newReconciler().namespaceSelectorFieldsChanged()
+
+			terminating := newNamespace(nil, nil)
+			terminating.Status.Phase = corev1.NamespaceTerminating
+
+			owned := newNamespace(nil, nil)
+			owned.OwnerReferences = []metav1.OwnerReference{{APIVersion: "v1", Kind: "ConfigMap", Name: "owner", UID: "owner-uid"}}
+
+			tests := []struct {
+				name   string
+				old    *corev1.Namespace
+				new    *corev1.Namespace
+				passes bool
+			}{
+				{"unchanged", newNamespace(nil, nil), newNamespace(nil, nil), false},
+				{"selector label added", newNamespace(nil, nil), newNamespace(map[string]string{"team": "a"}, nil), true},
+				{"selector label changed", newNamespace(map[string]string{"team": "a"}, nil), newNamespace(map[string]string{"team": "b"}, nil), true},
+				{"selector label removed", newNamespace(map[string]string{"team": "a"}, nil), newNamespace(nil, nil), true},
+				{"unreferenced label added", newNamespace(nil, nil), newNamespace(map[string]string{"cost-centre": "1234"}, nil), false},
+				{"readiness annotation added", newNamespace(nil, nil), newNamespace(nil, map[string]string{"example.com/ready": "true"}), true},
+				{"unreferenced annotation added", newNamespace(nil, nil), newNamespace(nil, map[string]string{"example.com/owner": "ops"}), false},
+				{"decommissioning label added", newNamespace(nil, nil), newNamespace(map[string]string{secretsv1beta1.NamespaceDecommissioningLabel: "true"}, nil), true},
+				{"consent annotation added", newNamespace(nil, nil), newNamespace(nil, map[string]string{secretsv1beta1.NamespaceAcceptCopiesAnnotation: "true"}), true},
+				{"phase changed", newNamespace(nil, nil), terminating, true},
+				{"owner reference added", newNamespace(nil, nil), owned, true},
+			}
+
+			for _, test := range tests {
+				Expect(predicate.Update(event.UpdateEvent{ObjectOld: test.old, ObjectNew: test.new})).To(Equal(test.passes), test.name)
+			}
+		})
+
+		It("should pass updates to labels referenced by the canary selector of a rollout", func() {
+			reconciler := newReconciler(&secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{Name: "predicate-canary-copier"},
+				Spec: secretsv1beta1.SecretCopierSpec{
+					Rules: []secretsv1beta1.SecretCopierRule{
+						{
+							SourceSecret: secretsv1beta1.SourceSecret{Name: "registry", Namespace: "registries"},
+							Rollout: &secretsv1beta1.SecretCopierRollout{
+								CanaryNamespaces: selectors.TargetNamespaces{
+									LabelSelector: selectors.LabelSelector{MatchLabels: map[string]string{"ring": "canary"}},
+								},
+							},
+						},
+					},
+				},
+			})
+
+			predicate := reconciler.namespaceSelectorFieldsChanged()
+
+			Expect(predicate.Update(event.UpdateEvent{
+				ObjectOld: newNamespace(nil, nil),
+				ObjectNew: newNamespace(map[string]string{"ring": "canary"}, nil),
+			})).To(BeTrue())
+
+			Expect(predicate.Update(event.UpdateEvent{
+				ObjectOld: newNamespace(map[string]string{"ring": "canary"}, nil),
+				ObjectNew: newNamespace(nil, nil),
+			})).To(BeTrue())
+		})
+
+		It("should pass updates to labels referenced by the scope of a delegation", func() {
+			reconciler := newReconciler(&secretsv1beta1.SecretCopierDelegation{
+				ObjectMeta: metav1.ObjectMeta{Name: "predicate-delegation"},
+				Spec: secretsv1beta1.SecretCopierDelegationSpec{
+					SourceNamespace: "registries",
+					TargetNamespaces: selectors.TargetNamespaces{
+						LabelSelector: selectors.LabelSelector{MatchLabels: map[string]string{"tenancy": "shared"}},
+					},
+				},
+			})
+
+			predicate := reconciler.namespaceSelectorFieldsChanged()
+
+			Expect(predicate.Update(event.UpdateEvent{
+				ObjectOld: newNamespace(map[string]string{"tenancy": "shared"}, nil),
+				ObjectNew: newNamespace(map[string]string{"tenancy": "dedicated"}, nil),
+			})).To(BeTrue())
+
+			Expect(predicate.Update(event.UpdateEvent{
+				ObjectOld: newNamespace(map[string]string{"tenancy": "shared"}, nil),
+				ObjectNew: newNamespace(map[string]string{"tenancy": "shared", "cost-centre": "1234"}, nil),
+			})).To(BeFalse())
+		})
+
+		It("should always pass creation and deletion of namespaces", func() {
+			predicate := newReconciler().namespaceSelectorFieldsChanged()
+
+			Expect(predicate.Create(event.CreateEvent{Object: newNamespace(nil, nil)})).To(BeTrue())
+			Expect(predicate.Delete(event.DeleteEvent{Object: newNamespace(nil, nil)})).To(BeTrue())
+		})
+	})
+
+	Context("When filtering secret updates", func() {
+		newSecret := func(mutate func(secret *corev1.Secret)) *corev1.Secret {
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "registry",
+					Namespace:   "registries",
+					Labels:      map[string]string{"app": "registry"},
+					Annotations: map[string]string{"example.com/owner": "ops"},
+				},
+				Type: corev1.SecretTypeOpaque,
+				Data: map[string][]byte{"token": []byte("registry-token")},
+			}
+
+			if mutate != nil {
+				mutate(secret)
+			}
+
+			return secret
+		}
+
+		It("should only pass updates to the type, data or labels", func() {
+			predicate := secretContentChanged()
+
+			tests := []struct {
+				name   string
+				mutate func(secret *corev1.Secret)
+				passes bool
+			}{
+				{"unchanged", nil, false},
+				{"type changed", func(secret *corev1.Secret) { secret.Type = corev1.SecretTypeDockerConfigJson }, true},
+				{"data changed", func(secret *corev1.Secret) { secret.Data["token"] = []byte("rotated-token") }, true},
+				{"data key added", func(secret *corev1.Secret) { secret.Data["user"] = []byte("registry-user") }, true},
+				{"label changed", func(secret *corev1.Secret) { secret.Labels["app"] = "mirror" }, true},
+				{"label removed", func(secret *corev1.Secret) { secret.Labels = nil }, true},
+				{"annotation changed", func(secret *corev1.Secret) { secret.Annotations["example.com/owner"] = "dev" }, false},
+				{"resource version changed", func(secret *corev1.Secret) { secret.ResourceVersion = "2" }, false},
+				{"owner reference added", func(secret *corev1.Secret) {
+					secret.OwnerReferences = []metav1.OwnerReference{{APIVersion: "v1", Kind: "ConfigMap", Name: "owner", UID: "owner-uid"}}
+				}, false},
+				{"managed fields changed", func(secret *corev1.Secret) {
+					secret.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationUpdate}}
+				}, false},
+			}
+
+			for _, test := range tests {
+				Expect(predicate.Update(event.UpdateEvent{ObjectOld: newSecret(nil), ObjectNew: newSecret(test.mutate)})).To(Equal(test.passes), test.name)
+			}
+		})
+
+		It("should always pass creation and deletion of secrets", func() {
+			predicate := secretContentChanged()
+
+			Expect(predicate.Create(event.CreateEvent{Object: newSecret(nil)})).To(BeTrue())
+			Expect(predicate.Delete(event.DeleteEvent{Object: newSecret(nil)})).To(BeTrue())
+		})
+	})
+})
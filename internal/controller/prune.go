@@ -0,0 +1,109 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/internal/redact"
+)
+
+// Delete target secrets of a SecretCopier with a prune policy of Delete which
+// are no longer wanted by any of its rules, because the rule which copied them
+// was removed or their namespace is no longer matched. Only target secrets
+// owned by the SecretCopier, which is the case when the reclaim policy of the
+// rule was Delete, are deleted. Target secrets whose source secret has been
+// deleted are left to the orphan reaper. Returns the number of target secrets
+// deleted.
+func (r *SecretCopierReconciler) pruneTargetSecrets(ctx context.Context, secretsClient client.Client, secretCopier *secretsv1beta1.SecretCopier, namespaces []corev1.Namespace) (int, error) {
+	log := log.FromContext(ctx)
+
+	if secretCopier.Spec.PrunePolicy != secretsv1beta1.PruneDelete {
+		return 0, nil
+	}
+
+	var secrets corev1.SecretList
+
+	if err := r.List(ctx, &secrets); err != nil {
+		return 0, err
+	}
+
+	copiers := map[string]*secretsv1beta1.SecretCopier{
+		secretCopier.Name: secretCopier,
+	}
+
+	namespacesByName := make(map[string]*corev1.Namespace, len(namespaces))
+
+	for i := range namespaces {
+		namespacesByName[namespaces[i].Name] = &namespaces[i]
+	}
+
+	secretsByKey := make(map[client.ObjectKey]*corev1.Secret, len(secrets.Items))
+
+	for i := range secrets.Items {
+		secretsByKey[client.ObjectKeyFromObject(&secrets.Items[i])] = &secrets.Items[i]
+	}
+
+	pruned := 0
+
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+
+		if secret.Annotations[secretCopierMarkerAnnotation] != secretCopier.Name || targetSecretIgnored(secret) {
+			continue
+		}
+
+		reason := orphanedSecretReason(secret, copiers, namespacesByName, secretsByKey)
+
+		if reason != orphanedRuleRemoved && reason != orphanedNotTargeted {
+			continue
+		}
+
+		if r.writesSuspended() {
+			r.recordReportOnlyWrite(ctx, secretCopier, "delete", secret, redact.ContentDiff{})
+			continue
+		}
+
+		// Only delete the secret if it hasn't changed since it was checked.
+
+		preconditions := client.Preconditions{
+			UID:             &secret.UID,
+			ResourceVersion: &secret.ResourceVersion,
+		}
+
+		if err := secretsClient.Delete(ctx, secret, preconditions); client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Unable to prune target secret", "secret", secret.Name, "namespace", secret.Namespace)
+			continue
+		}
+
+		log.Info("Pruned target secret", "secretCopier", secretCopier.Name, "secret", secret.Name, "namespace", secret.Namespace, "reason", reason)
+
+		r.Recorder.Eventf(secretCopier, corev1.EventTypeNormal, "SecretPruned",
+			"Deleted secret %s in namespace %s as it is no longer wanted by any rule (%s)", secret.Name, secret.Namespace, reason)
+
+		prunedSecretsTotal.WithLabelValues(secretCopier.Name, reason).Inc()
+
+		pruned++
+	}
+
+	return pruned, nil
+}
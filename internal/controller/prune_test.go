@@ -0,0 +1,143 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+var _ = Describe("Pruning Target Secrets", func() {
+	ctx := context.Background()
+
+	newSecretCopier := func(prunePolicy secretsv1beta1.PrunePolicy) *secretsv1beta1.SecretCopier {
+		return &secretsv1beta1.SecretCopier{
+			ObjectMeta: metav1.ObjectMeta{Name: "prune-copier", UID: "prune-copier-uid"},
+			Spec: secretsv1beta1.SecretCopierSpec{
+				PrunePolicy: prunePolicy,
+				Rules: []secretsv1beta1.SecretCopierRule{
+					{
+						SourceSecret:  secretsv1beta1.SourceSecret{Namespace: "prune-source", Name: "registry"},
+						ReclaimPolicy: secretsv1beta1.ReclaimDelete,
+						TargetNamespaces: selectors.TargetNamespaces{
+							NameSelector: selectors.NameSelector{MatchNames: []string{"prune-kept"}},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	targetSecret := func(namespace string, owned bool) *corev1.Secret {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      "registry",
+				Annotations: map[string]string{
+					secretCopierMarkerAnnotation: "prune-copier",
+					sourceSecretMarkerAnnotation: "prune-source/registry",
+				},
+			},
+		}
+
+		if owned {
+			secret.OwnerReferences = []metav1.OwnerReference{
+				{
+					APIVersion: secretsv1beta1.GroupVersion.String(),
+					Kind:       "SecretCopier",
+					Name:       "prune-copier",
+					UID:        "prune-copier-uid",
+					Controller: ptr.To(true),
+				},
+			}
+		}
+
+		return secret
+	}
+
+	namespaces := []corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "prune-source"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "prune-kept"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "prune-dropped"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "prune-retained"}},
+	}
+
+	newClient := func() client.Client {
+		return fake.NewClientBuilder().WithObjects(
+			&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "prune-source", Name: "registry"}},
+			targetSecret("prune-kept", true),
+			targetSecret("prune-dropped", true),
+			targetSecret("prune-retained", false),
+		).Build()
+	}
+
+	exists := func(c client.Client, namespace string) bool {
+		return c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "registry"}, &corev1.Secret{}) == nil
+	}
+
+	It("should delete owned target secrets in namespaces no longer matched", func() {
+		c := newClient()
+		reconciler := &SecretCopierReconciler{Client: c, Recorder: record.NewFakeRecorder(10)}
+
+		Expect(reconciler.pruneTargetSecrets(ctx, c, newSecretCopier(secretsv1beta1.PruneDelete), namespaces)).To(Equal(1))
+
+		Expect(exists(c, "prune-kept")).To(BeTrue())
+		Expect(exists(c, "prune-dropped")).To(BeFalse())
+		Expect(exists(c, "prune-retained")).To(BeTrue())
+	})
+
+	It("should delete owned target secrets of a removed rule", func() {
+		c := newClient()
+		reconciler := &SecretCopierReconciler{Client: c, Recorder: record.NewFakeRecorder(10)}
+
+		secretCopier := newSecretCopier(secretsv1beta1.PruneDelete)
+		secretCopier.Spec.Rules = nil
+
+		Expect(reconciler.pruneTargetSecrets(ctx, c, secretCopier, namespaces)).To(Equal(2))
+
+		Expect(exists(c, "prune-retained")).To(BeTrue())
+	})
+
+	It("should leave target secrets in place without a prune policy", func() {
+		c := newClient()
+		reconciler := &SecretCopierReconciler{Client: c, Recorder: record.NewFakeRecorder(10)}
+
+		Expect(reconciler.pruneTargetSecrets(ctx, c, newSecretCopier(secretsv1beta1.PruneNone), namespaces)).To(Equal(0))
+
+		Expect(exists(c, "prune-dropped")).To(BeTrue())
+	})
+
+	It("should only report target secrets which would be deleted in report-only mode", func() {
+		c := newClient()
+		reconciler := &SecretCopierReconciler{Client: c, Recorder: record.NewFakeRecorder(10), ReportOnly: true}
+
+		Expect(reconciler.pruneTargetSecrets(ctx, c, newSecretCopier(secretsv1beta1.PruneDelete), namespaces)).To(Equal(0))
+
+		Expect(exists(c, "prune-dropped")).To(BeTrue())
+	})
+})
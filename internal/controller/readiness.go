@@ -0,0 +1,134 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Determine whether a target namespace satisfies the readiness signal required
+// by a rule. Service accounts and resource quotas are only read as metadata so
+// that the cache holds as little as possible for them.
+func (r *SecretCopierReconciler) targetNamespaceReady(ctx context.Context, rule *secretsv1beta1.SecretCopierRule, namespace *corev1.Namespace) (bool, error) {
+	readiness := rule.TargetNamespaceReadiness
+
+	if readiness == nil {
+		return true, nil
+	}
+
+	for key, value := range readiness.MatchLabels {
+		if current, ok := namespace.Labels[key]; !ok || current != value {
+			return false, nil
+		}
+	}
+
+	for key, value := range readiness.MatchAnnotations {
+		if current, ok := namespace.Annotations[key]; !ok || current != value {
+			return false, nil
+		}
+	}
+
+	if readiness.ServiceAccount != "" {
+		if exists, err := r.namespacedObjectExists(ctx, "ServiceAccount", namespace.Name, readiness.ServiceAccount); !exists {
+			return false, err
+		}
+	}
+
+	if readiness.ResourceQuota != "" {
+		if exists, err := r.namespacedObjectExists(ctx, "ResourceQuota", namespace.Name, readiness.ResourceQuota); !exists {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// Determine whether a core API object of the given kind exists.
+func (r *SecretCopierReconciler) namespacedObjectExists(ctx context.Context, kind string, namespace string, name string) (bool, error) {
+	object := &metav1.PartialObjectMetadata{}
+
+	object.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind(kind))
+
+	err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, object)
+
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// Handler function returning a function to find SecretCopier objects with a
+// rule whose readiness signal refers to a service account or resource quota.
+// This is used to trigger a reconciliation of the SecretCopier when the object
+// a target namespace is waiting on is created or deleted.
+func (r *SecretCopierReconciler) findSecretCopiersAwaitingReadiness(kind string) func(context.Context, client.Object) []reconcile.Request {
+	return func(ctx context.Context, object client.Object) []reconcile.Request {
+		log := log.FromContext(ctx)
+
+		var secretCopiers secretsv1beta1.SecretCopierList
+
+		if err := r.List(ctx, &secretCopiers); err != nil {
+			log.Error(err, "Unable to list SecretCopier objects")
+			return nil
+		}
+
+		var requests []reconcile.Request
+
+		for _, secretCopier := range secretCopiers.Items {
+			for _, rule := range secretCopier.Spec.Rules {
+				readiness := rule.TargetNamespaceReadiness
+
+				if readiness == nil {
+					continue
+				}
+
+				if (kind == "ServiceAccount" && readiness.ServiceAccount == object.GetName()) ||
+					(kind == "ResourceQuota" && readiness.ResourceQuota == object.GetName()) {
+					log.V(1).Info("Queue reconcile for readiness object against SecretCopier", "name", secretCopier.Name, "kind", kind, "object", object.GetName(), "namespace", object.GetNamespace())
+
+					requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&secretCopier)})
+
+					break
+				}
+			}
+		}
+
+		return requests
+	}
+}
+
+// Predicate which only lets through creation and deletion of objects, as only
+// the existence of a readiness object matters.
+func objectExistenceChanged() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return false
+		},
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+var _ = Describe("SecretCopier Target Namespace Readiness", func() {
+	ctx := context.Background()
+
+	// Test that a secret is only copied to a target namespace once it has
+	// the readiness annotation and service account required by the rule.
+
+	Context("Copy secret to ready target namespace #1", func() {
+		It("should wait for the target namespace to be ready", func() {
+			sourceNamespaceName := "readiness-source-namespace-1"
+			targetNamespaceName := "readiness-target-namespace-1"
+			sourceSecretName := "readiness-source-secret-1"
+
+			for _, name := range []string{sourceNamespaceName, targetNamespaceName} {
+				namespace := &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: name,
+					},
+				}
+				Expect(k8sClient.Create(ctx, namespace)).To(Succeed())
+			}
+
+			sourceSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      sourceSecretName,
+					Namespace: sourceNamespaceName,
+				},
+				Type: corev1.SecretTypeOpaque,
+				StringData: map[string]string{
+					"key1": "value1",
+				},
+			}
+			Expect(k8sClient.Create(ctx, sourceSecret)).To(Succeed())
+
+			secretCopier := &secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "readiness-secret-copier-1",
+				},
+				Spec: secretsv1beta1.SecretCopierSpec{
+					Rules: []secretsv1beta1.SecretCopierRule{
+						{
+							SourceSecret: secretsv1beta1.SourceSecret{
+								Namespace: sourceNamespaceName,
+								Name:      sourceSecretName,
+							},
+							TargetNamespaces: selectors.TargetNamespaces{
+								NameSelector: selectors.NameSelector{
+									MatchNames: []string{targetNamespaceName},
+								},
+							},
+							TargetNamespaceReadiness: &secretsv1beta1.NamespaceReadiness{
+								MatchAnnotations: map[string]string{
+									"example.com/provisioned": "true",
+								},
+								ServiceAccount: "app",
+							},
+							ReclaimPolicy: secretsv1beta1.ReclaimDelete,
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, secretCopier)).To(Succeed())
+
+			// Verify the target namespace is reported as not ready and the
+			// secret is not copied.
+
+			Eventually(func() int32 {
+				Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(secretCopier), secretCopier)).To(Succeed())
+				if len(secretCopier.Status.Rules) == 0 {
+					return 0
+				}
+				return secretCopier.Status.Rules[0].NotReadyNamespaces
+			}).Should(Equal(int32(1)))
+
+			targetSecretKey := client.ObjectKey{Namespace: targetNamespaceName, Name: sourceSecretName}
+
+			Consistently(func() error {
+				return k8sClient.Get(ctx, targetSecretKey, &corev1.Secret{})
+			}, 2*time.Second).ShouldNot(Succeed())
+
+			// Annotate the target namespace, which is not enough on its own.
+
+			var targetNamespace corev1.Namespace
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Name: targetNamespaceName}, &targetNamespace)).To(Succeed())
+			targetNamespace.Annotations = map[string]string{"example.com/provisioned": "true"}
+			Expect(k8sClient.Update(ctx, &targetNamespace)).To(Succeed())
+
+			Consistently(func() error {
+				return k8sClient.Get(ctx, targetSecretKey, &corev1.Secret{})
+			}, 2*time.Second).ShouldNot(Succeed())
+
+			// Create the service account, after which the secret is copied.
+
+			serviceAccount := &corev1.ServiceAccount{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "app",
+					Namespace: targetNamespaceName,
+				},
+			}
+			Expect(k8sClient.Create(ctx, serviceAccount)).To(Succeed())
+
+			Eventually(func() error {
+				return k8sClient.Get(ctx, targetSecretKey, &corev1.Secret{})
+			}).Should(Succeed())
+		})
+	})
+})
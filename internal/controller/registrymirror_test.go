@@ -0,0 +1,74 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Registry Mirrors", func() {
+	const dockerConfig = `{"auths":{"https://index.docker.io/v1/":{"auth":"dXNlcjpwYXNz"}}}`
+
+	newSourceSecret := func(config string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "pull-secret", Namespace: "registry"},
+			Type:       corev1.SecretTypeDockerConfigJson,
+			Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(config)},
+		}
+	}
+
+	rule := &secretsv1beta1.SecretCopierRule{
+		SourceSecret: secretsv1beta1.SourceSecret{Name: "pull-secret", Namespace: "registry"},
+		TargetSecret: secretsv1beta1.TargetSecret{
+			RegistryMirrors: []secretsv1beta1.RegistryMirror{
+				{Registry: "docker.io", Mirror: "mirror.example.com/dockerhub"},
+			},
+		},
+	}
+
+	It("should rewrite credentials for a registry to be for its mirror", func() {
+		sourceSecret := newSourceSecret(dockerConfig)
+
+		content, err := targetSecretContent(rule, sourceSecret)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content.Data[corev1.DockerConfigJsonKey])).To(Equal(`{"auths":{"mirror.example.com/dockerhub":{"auth":"dXNlcjpwYXNz"}}}`))
+		Expect(string(sourceSecret.Data[corev1.DockerConfigJsonKey])).To(Equal(dockerConfig))
+	})
+
+	It("should return the source secret unchanged when no mirror applies", func() {
+		sourceSecret := newSourceSecret(`{"auths":{"quay.io":{"auth":"YQ=="}}}`)
+
+		content, err := targetSecretContent(rule, sourceSecret)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(content).To(BeIdenticalTo(sourceSecret))
+	})
+
+	It("should fail when the docker config can't be parsed", func() {
+		_, err := targetSecretContent(rule, newSourceSecret(`{"auths":`))
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring(corev1.DockerConfigJsonKey))
+	})
+})
@@ -0,0 +1,87 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Handler function to find the SecretCopier which manages a target secret.
+// This is used to trigger a reconciliation of the SecretCopier when a target
+// secret is deleted, so that it is recreated straight away rather than only
+// when the SecretCopier is next resynced. The SecretCopier is identified by
+// the marker annotation on the target secret, or failing that by the owner
+// reference added when the reclaim policy is Delete.
+func (r *SecretCopierReconciler) findSecretCopierManagingTargetSecret(ctx context.Context, secret client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	name := managingSecretCopierName(secret)
+
+	if name == "" {
+		return nil
+	}
+
+	log.V(1).Info("Queue reconcile for deleted target Secret against SecretCopier", "name", name, "secret", secret.GetName(), "namespace", secret.GetNamespace())
+
+	return []reconcile.Request{{NamespacedName: client.ObjectKey{Name: name}}}
+}
+
+// Return the name of the SecretCopier which manages a target secret, or an
+// empty string if the secret is not a target secret.
+func managingSecretCopierName(secret client.Object) string {
+	if name := secret.GetAnnotations()[secretCopierMarkerAnnotation]; name != "" {
+		return name
+	}
+
+	for _, ownerReference := range secret.GetOwnerReferences() {
+		groupVersion, err := schema.ParseGroupVersion(ownerReference.APIVersion)
+
+		if err != nil {
+			continue
+		}
+
+		if ownerReference.Kind == "SecretCopier" && groupVersion.Group == secretsv1beta1.GroupVersion.Group {
+			return ownerReference.Name
+		}
+	}
+
+	return ""
+}
+
+// Predicate which only lets through the deletion of objects.
+func objectDeleted() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return false
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return false
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Deleted Target Secret Repair", func() {
+	ctx := context.Background()
+
+	reconciler := &SecretCopierReconciler{}
+
+	It("should enqueue the SecretCopier named by the marker annotation", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "registry",
+				Namespace: "tenant-a",
+				Annotations: map[string]string{
+					"secrets-manager.advok8s.io/secret-copier": "registry-copier",
+					"secrets-manager.advok8s.io/secret-name":   "source/registry",
+				},
+			},
+		}
+
+		Expect(reconciler.findSecretCopierManagingTargetSecret(ctx, secret)).To(ConsistOf(
+			reconcile.Request{NamespacedName: client.ObjectKey{Name: "registry-copier"}}))
+	})
+
+	It("should enqueue the SecretCopier owning a target secret without markers", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "registry",
+				Namespace: "tenant-a",
+				OwnerReferences: []metav1.OwnerReference{
+					{APIVersion: "v1", Kind: "ConfigMap", Name: "other"},
+					{APIVersion: "secrets-manager.advok8s.io/v1beta1", Kind: "SecretCopier", Name: "owning-copier"},
+				},
+			},
+		}
+
+		Expect(reconciler.findSecretCopierManagingTargetSecret(ctx, secret)).To(ConsistOf(
+			reconcile.Request{NamespacedName: client.ObjectKey{Name: "owning-copier"}}))
+	})
+
+	It("should ignore secrets which are not target secrets", func() {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "tenant-a"}}
+
+		Expect(reconciler.findSecretCopierManagingTargetSecret(ctx, secret)).To(BeEmpty())
+	})
+
+	It("should only let through deletions", func() {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "tenant-a"}}
+
+		predicate := objectDeleted()
+
+		Expect(predicate.Create(event.CreateEvent{Object: secret})).To(BeFalse())
+		Expect(predicate.Update(event.UpdateEvent{ObjectOld: secret, ObjectNew: secret})).To(BeFalse())
+		Expect(predicate.Generic(event.GenericEvent{Object: secret})).To(BeFalse())
+		Expect(predicate.Delete(event.DeleteEvent{Object: secret})).To(BeTrue())
+	})
+})
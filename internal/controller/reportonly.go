@@ -0,0 +1,60 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/internal/redact"
+)
+
+// Record that a write to a target secret was skipped because the manager is
+// running in report-only mode, or because writes to secrets are frozen. An
+// event is recorded against the SecretCopier describing the write which would
+// have been made, including the names of the keys and labels which would
+// change.
+func (r *SecretCopierReconciler) recordReportOnlyWrite(ctx context.Context, secretCopier *secretsv1beta1.SecretCopier, verb string, targetSecret *corev1.Secret, changes redact.ContentDiff) {
+	log := log.FromContext(ctx)
+
+	reason := "ReportOnly"
+
+	if !r.ReportOnly {
+		reason = "WriteFrozen"
+	}
+
+	log.Info("Writes suspended, skipping write of target secret", "reason", reason, "verb", verb, "targetSecret", targetSecret.Name, "targetNamespace", targetSecret.Namespace, "changes", changes.String())
+
+	r.Recorder.Eventf(secretCopier, corev1.EventTypeNormal, reason,
+		"Would %s secret %s in namespace %s%s", verb, targetSecret.Name, targetSecret.Namespace, describeContentDiff(changes))
+
+	countSkippedWrite("secretcopier", verb, r.ReportOnly)
+}
+
+// Describe the keys and labels which differ between the current and new
+// target secret, for appending to an event message. Only the names of keys
+// and labels are ever included.
+func describeContentDiff(changes redact.ContentDiff) string {
+	if changes.Empty() {
+		return ""
+	}
+
+	return " (" + changes.String() + ")"
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Report Only Writes", func() {
+	ctx := context.Background()
+
+	secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "masking-copier"}}
+
+	rule := &secretsv1beta1.SecretCopierRule{
+		SourceSecret:  secretsv1beta1.SourceSecret{Name: "registry", Namespace: "source"},
+		ReclaimPolicy: secretsv1beta1.ReclaimRetain,
+	}
+
+	newClient := func() client.WithWatch {
+		return fake.NewClientBuilder().WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "source"},
+				Data: map[string][]byte{
+					"password": []byte("rotated-password"),
+					"username": []byte("admin-user"),
+				},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "registry",
+					Namespace: "tenant-a",
+					Annotations: map[string]string{
+						"secrets-manager.advok8s.io/secret-copier": "masking-copier",
+						"secrets-manager.advok8s.io/secret-name":   "source/registry",
+					},
+				},
+				Data: map[string][]byte{
+					"password": []byte("original-password"),
+					"token":    []byte("stale-token"),
+				},
+			},
+		).Build()
+	}
+
+	It("should describe the keys which would change by name only", func() {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &SecretCopierReconciler{Client: newClient(), Recorder: recorder, ReportOnly: true}
+
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant-a")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyReportOnly))
+
+		var event string
+		Expect(recorder.Events).To(Receive(&event))
+
+		Expect(event).To(Equal("Normal ReportOnly Would update secret registry in namespace tenant-a (added keys username; removed keys token; changed keys password)"))
+		Expect(event).NotTo(ContainSubstring("rotated-password"))
+		Expect(event).NotTo(ContainSubstring("original-password"))
+	})
+
+	It("should describe the label keys which would change by name only", func() {
+		c := newClient()
+
+		sourceSecret := &corev1.Secret{}
+		Expect(c.Get(ctx, client.ObjectKey{Namespace: "source", Name: "registry"}, sourceSecret)).To(Succeed())
+
+		sourceSecret.Labels = map[string]string{"team": "platform-team"}
+		Expect(c.Update(ctx, sourceSecret)).To(Succeed())
+
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &SecretCopierReconciler{Client: c, Recorder: recorder, ReportOnly: true}
+
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant-a")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyReportOnly))
+
+		var event string
+		Expect(recorder.Events).To(Receive(&event))
+
+		Expect(event).To(HaveSuffix("(added keys username; removed keys token; changed keys password; added label keys team)"))
+		Expect(event).NotTo(ContainSubstring("platform-team"))
+	})
+
+	It("should mask values of the source secret in errors recorded in status", func() {
+		// Simulate an API server error which quotes the secret being written.
+
+		failing := interceptor.NewClient(newClient(), interceptor.Funcs{
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				secret := obj.(*corev1.Secret)
+
+				data, _ := json.Marshal(secret.Data)
+
+				return apierrors.NewBadRequest(fmt.Sprintf("invalid secret %s with username %s and data %s",
+					secret.Name, secret.Data["username"], data))
+			},
+		})
+
+		reconciler := &SecretCopierReconciler{Client: failing, Recorder: record.NewFakeRecorder(10)}
+
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant-a")
+
+		Expect(result).To(Equal(copyFailed))
+		Expect(apierrors.IsBadRequest(err)).To(BeTrue())
+
+		failedTarget := newFailedTarget(0, "tenant-a", result, err)
+
+		Expect(failedTarget.Message).To(ContainSubstring("invalid secret registry"))
+		Expect(failedTarget.Message).To(ContainSubstring("[REDACTED]"))
+		Expect(failedTarget.Message).NotTo(ContainSubstring("rotated-password"))
+		Expect(failedTarget.Message).NotTo(ContainSubstring("admin-user"))
+
+		var outcome ruleOutcome
+
+		outcome.copied("tenant-a", result, err)
+
+		Expect(outcome.lastError).NotTo(ContainSubstring("rotated-password"))
+	})
+})
@@ -0,0 +1,223 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/internal/redact"
+)
+
+// Watches added for the kinds of resource rules require to be present in
+// target namespaces. As the kinds are only known from the rules, watches are
+// added as rules requiring them are first seen. The zero value is ready to
+// use, with no watches added until the controller is set.
+type requiredResourceWatches struct {
+	mutex      sync.Mutex
+	controller controller.Controller
+	cache      cache.Cache
+	watched    map[schema.GroupVersionKind]bool
+}
+
+// Determine the kind of resource required by a rule.
+func requiredResourceKind(required *secretsv1beta1.RequiredResource) schema.GroupVersionKind {
+	return schema.FromAPIVersionAndKind(required.APIVersion, required.Kind)
+}
+
+// Determine whether a target namespace holds the resource required by a rule.
+// Resources are only read as metadata so that the cache holds as little as
+// possible for them.
+func (r *SecretCopierReconciler) requiredResourcePresent(ctx context.Context, rule *secretsv1beta1.SecretCopierRule, namespace string) (bool, error) {
+	required := rule.RequiredResource
+
+	if required == nil {
+		return true, nil
+	}
+
+	gvk := requiredResourceKind(required)
+
+	if err := r.watchRequiredResource(gvk); err != nil {
+		return false, err
+	}
+
+	selector := labels.Everything()
+
+	if required.Selector != nil {
+		var err error
+
+		if selector, err = metav1.LabelSelectorAsSelector(required.Selector); err != nil {
+			return false, err
+		}
+	}
+
+	if required.Name != "" {
+		object := &metav1.PartialObjectMetadata{}
+
+		object.SetGroupVersionKind(gvk)
+
+		err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: required.Name}, object)
+
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		return selector.Matches(labels.Set(object.Labels)), nil
+	}
+
+	objects := &metav1.PartialObjectMetadataList{}
+
+	objects.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+
+	if err := r.List(ctx, objects, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return false, err
+	}
+
+	return len(objects.Items) != 0, nil
+}
+
+// Add a watch for a kind of resource required by a rule if one hasn't been
+// added already, so the SecretCopier is reconciled when resources of the kind
+// are created or deleted, or their labels change.
+func (r *SecretCopierReconciler) watchRequiredResource(gvk schema.GroupVersionKind) error {
+	watches := &r.requiredResourceWatches
+
+	watches.mutex.Lock()
+	defer watches.mutex.Unlock()
+
+	if watches.controller == nil || watches.watched[gvk] {
+		return nil
+	}
+
+	object := &metav1.PartialObjectMetadata{}
+
+	object.SetGroupVersionKind(gvk)
+
+	err := watches.controller.Watch(source.Kind[client.Object](watches.cache, object,
+		enqueueCoalescedRequestsFromMapFunc(r.findSecretCopiersRequiringResource(gvk), r.CoalesceWindow, r.warmUp),
+		predicate.Or[client.Object](objectExistenceChanged(), predicate.LabelChangedPredicate{})))
+
+	if err != nil {
+		return err
+	}
+
+	if watches.watched == nil {
+		watches.watched = make(map[schema.GroupVersionKind]bool)
+	}
+
+	watches.watched[gvk] = true
+
+	return nil
+}
+
+// Handler function returning a function to find SecretCopier objects with a
+// rule requiring a kind of resource to be present in target namespaces. This
+// is used to trigger a reconciliation of the SecretCopier when a resource of
+// the kind is created or deleted.
+func (r *SecretCopierReconciler) findSecretCopiersRequiringResource(gvk schema.GroupVersionKind) func(context.Context, client.Object) []reconcile.Request {
+	return func(ctx context.Context, object client.Object) []reconcile.Request {
+		log := log.FromContext(ctx)
+
+		var secretCopiers secretsv1beta1.SecretCopierList
+
+		if err := r.List(ctx, &secretCopiers); err != nil {
+			log.Error(err, "Unable to list SecretCopier objects")
+			return nil
+		}
+
+		var requests []reconcile.Request
+
+		for _, secretCopier := range secretCopiers.Items {
+			for _, rule := range secretCopier.Spec.Rules {
+				if rule.RequiredResource == nil || requiredResourceKind(rule.RequiredResource) != gvk {
+					continue
+				}
+
+				if rule.RequiredResource.Name != "" && rule.RequiredResource.Name != object.GetName() {
+					continue
+				}
+
+				log.V(1).Info("Queue reconcile for required resource against SecretCopier", "name", secretCopier.Name, "kind", gvk.Kind, "object", object.GetName(), "namespace", object.GetNamespace())
+
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&secretCopier)})
+
+				break
+			}
+		}
+
+		return requests
+	}
+}
+
+// Remove the target secret of a rule from a target namespace which no longer
+// holds the resource the rule requires. Only a target secret managed by the
+// SecretCopier for the rule is removed, and not if it is labelled to be
+// ignored. Returns whether the target secret was removed.
+func (r *SecretCopierReconciler) removeTargetSecretWithoutRequiredResource(ctx context.Context, secretsClient client.Client, secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, targetNamespace string, targetSecretName string) (bool, error) {
+	log := log.FromContext(ctx)
+
+	var targetSecret corev1.Secret
+
+	if err := r.Get(ctx, client.ObjectKey{Namespace: targetNamespace, Name: targetSecretName}, &targetSecret); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+
+	if !r.targetSecretManagedBySecretCopier(secretCopier, rule, &targetSecret) || targetSecretIgnored(&targetSecret) {
+		return false, nil
+	}
+
+	if r.writesSuspended() {
+		r.recordReportOnlyWrite(ctx, secretCopier, "delete", &targetSecret, redact.ContentDiff{})
+		return false, nil
+	}
+
+	// Only delete the secret if it hasn't changed since it was checked.
+
+	preconditions := client.Preconditions{
+		UID:             &targetSecret.UID,
+		ResourceVersion: &targetSecret.ResourceVersion,
+	}
+
+	if err := secretsClient.Delete(ctx, &targetSecret, preconditions); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+
+	log.Info("Removed target secret as required resource no longer exists", "secretCopier", secretCopier.Name, "secret", targetSecretName, "namespace", targetNamespace)
+
+	r.Recorder.Eventf(secretCopier, corev1.EventTypeNormal, "SecretRemoved",
+		"Deleted secret %s in namespace %s as no %s required by the rule exists", targetSecretName, targetNamespace, rule.RequiredResource.Kind)
+
+	return true, nil
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Required Resources", func() {
+	ctx := context.Background()
+
+	secretCopier := &secretsv1beta1.SecretCopier{
+		ObjectMeta: metav1.ObjectMeta{Name: "required-copier"},
+	}
+
+	newRule := func(required *secretsv1beta1.RequiredResource) *secretsv1beta1.SecretCopierRule {
+		return &secretsv1beta1.SecretCopierRule{
+			SourceSecret:     secretsv1beta1.SourceSecret{Namespace: "required-source", Name: "registry"},
+			RequiredResource: required,
+		}
+	}
+
+	deployment := func(namespace string, name string, labels map[string]string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels},
+		}
+	}
+
+	targetSecret := func(namespace string, name string, secretCopierName string, labels map[string]string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      name,
+				Labels:    labels,
+				Annotations: map[string]string{
+					secretCopierMarkerAnnotation: secretCopierName,
+					sourceSecretMarkerAnnotation: "required-source/registry",
+				},
+			},
+		}
+	}
+
+	exists := func(c client.Client, namespace string, name string) bool {
+		return c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &corev1.Secret{}) == nil
+	}
+
+	It("should always treat a rule without a required resource as satisfied", func() {
+		reconciler := &SecretCopierReconciler{Client: fake.NewClientBuilder().Build()}
+
+		present, err := reconciler.requiredResourcePresent(ctx, newRule(nil), "required-target")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(present).To(BeTrue())
+	})
+
+	It("should check for a required resource by name", func() {
+		reconciler := &SecretCopierReconciler{Client: fake.NewClientBuilder().WithObjects(
+			deployment("required-target", "api", map[string]string{"app": "api"}),
+		).Build()}
+
+		rule := newRule(&secretsv1beta1.RequiredResource{APIVersion: "apps/v1", Kind: "Deployment", Name: "api"})
+
+		present, err := reconciler.requiredResourcePresent(ctx, rule, "required-target")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(present).To(BeTrue())
+
+		present, err = reconciler.requiredResourcePresent(ctx, rule, "required-other")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(present).To(BeFalse())
+
+		rule.RequiredResource.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+
+		present, err = reconciler.requiredResourcePresent(ctx, rule, "required-target")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(present).To(BeFalse())
+	})
+
+	It("should check for a required resource by label selector", func() {
+		reconciler := &SecretCopierReconciler{Client: fake.NewClientBuilder().WithObjects(
+			deployment("required-target", "api", map[string]string{"app": "api"}),
+			deployment("required-other", "web", map[string]string{"app": "web"}),
+		).Build()}
+
+		rule := newRule(&secretsv1beta1.RequiredResource{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+			Selector:   &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+		})
+
+		present, err := reconciler.requiredResourcePresent(ctx, rule, "required-target")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(present).To(BeTrue())
+
+		present, err = reconciler.requiredResourcePresent(ctx, rule, "required-other")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(present).To(BeFalse())
+	})
+
+	It("should only remove target secrets managed by the SecretCopier", func() {
+		c := fake.NewClientBuilder().WithObjects(
+			targetSecret("required-target", "managed", "required-copier", nil),
+			targetSecret("required-target", "unmanaged", "other-copier", nil),
+			targetSecret("required-target", "ignored", "required-copier", map[string]string{ignoreLabel: "true"}),
+		).Build()
+
+		reconciler := &SecretCopierReconciler{Client: c, Recorder: record.NewFakeRecorder(10)}
+
+		rule := newRule(&secretsv1beta1.RequiredResource{APIVersion: "apps/v1", Kind: "Deployment"})
+
+		removed := func(name string) bool {
+			removed, err := reconciler.removeTargetSecretWithoutRequiredResource(ctx, c, secretCopier, rule, "required-target", name)
+			Expect(err).NotTo(HaveOccurred())
+			return removed
+		}
+
+		Expect(removed("managed")).To(BeTrue())
+		Expect(removed("unmanaged")).To(BeFalse())
+		Expect(removed("ignored")).To(BeFalse())
+		Expect(removed("missing")).To(BeFalse())
+
+		Expect(exists(c, "required-target", "managed")).To(BeFalse())
+		Expect(exists(c, "required-target", "unmanaged")).To(BeTrue())
+		Expect(exists(c, "required-target", "ignored")).To(BeTrue())
+	})
+})
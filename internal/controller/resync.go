@@ -0,0 +1,63 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Annotation on a SecretCopier used to request an immediate resync. Any
+// change to the value, such as setting it to the current time, triggers a
+// resync, with the last value handled recorded in the status.
+const resyncAnnotation = "secrets-manager.advok8s.io/resync"
+
+// Return the value of the resync annotation if a resync has been requested
+// which has not yet been handled.
+func resyncRequested(secretCopier *secretsv1beta1.SecretCopier) (string, bool) {
+	value := secretCopier.Annotations[resyncAnnotation]
+
+	if value == "" || value == secretCopier.Status.LastHandledResync {
+		return "", false
+	}
+
+	return value, true
+}
+
+// Start a resync of the SecretCopier. State held by the controller between
+// reconciliations which could cause targets to be skipped is discarded, so
+// the following pass re-evaluates the selectors of every rule and retries
+// every target namespace from the first, including those backing off after
+// being denied permission.
+func (r *SecretCopierReconciler) startResync(ctx context.Context, secretCopier *secretsv1beta1.SecretCopier, value string) {
+	log := log.FromContext(ctx)
+
+	log.Info("Resync requested for SecretCopier", "name", secretCopier.Name, "value", value)
+
+	r.matchers.forget(secretCopier.Name)
+	r.ruleCursors.forget(secretCopier.Name)
+	r.deniedBackoff.forget(func(key string) bool {
+		return strings.HasPrefix(key, secretCopier.Name+"/")
+	})
+
+	r.Recorder.Eventf(secretCopier, corev1.EventTypeNormal, "ResyncRequested", "Resync requested with value %s", value)
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Resync Annotation", func() {
+	newSecretCopier := func(value string, handled string) *secretsv1beta1.SecretCopier {
+		secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "resync-copier"}}
+
+		if value != "" {
+			secretCopier.Annotations = map[string]string{resyncAnnotation: value}
+		}
+
+		secretCopier.Status.LastHandledResync = handled
+
+		return secretCopier
+	}
+
+	It("should only request a resync for a new annotation value", func() {
+		_, requested := resyncRequested(newSecretCopier("", ""))
+		Expect(requested).To(BeFalse())
+
+		_, requested = resyncRequested(newSecretCopier("2024-01-01T00:00:00Z", "2024-01-01T00:00:00Z"))
+		Expect(requested).To(BeFalse())
+
+		value, requested := resyncRequested(newSecretCopier("2024-01-02T00:00:00Z", "2024-01-01T00:00:00Z"))
+		Expect(requested).To(BeTrue())
+		Expect(value).To(Equal("2024-01-02T00:00:00Z"))
+	})
+
+	It("should clear the permission backoff of only the resynced SecretCopier", func() {
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &SecretCopierReconciler{Recorder: recorder}
+
+		now := time.Now()
+		denied := errors.New("forbidden")
+
+		reconciler.deniedBackoff.failed("resync-copier/tenant-a/registry", now, time.Minute, time.Hour, denied)
+		reconciler.deniedBackoff.failed("other-copier/tenant-a/registry", now, time.Minute, time.Hour, denied)
+
+		reconciler.startResync(context.Background(), newSecretCopier("now", ""), "now")
+
+		ready, _ := reconciler.deniedBackoff.ready("resync-copier/tenant-a/registry", now)
+		Expect(ready).To(BeTrue())
+
+		ready, _ = reconciler.deniedBackoff.ready("other-copier/tenant-a/registry", now)
+		Expect(ready).To(BeFalse())
+
+		Expect(recorder.Events).To(Receive(Equal("Normal ResyncRequested Resync requested with value now")))
+	})
+})
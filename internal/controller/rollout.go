@@ -0,0 +1,274 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// HTTP client used to call rollout verification hooks.
+var rolloutVerificationClient = &http.Client{Timeout: 30 * time.Second}
+
+// Payload posted to a rollout verification hook.
+type rolloutVerificationRequest struct {
+	SecretCopier     string                      `json:"secretCopier"`
+	SourceSecret     secretsv1beta1.SourceSecret `json:"sourceSecret"`
+	Revision         string                      `json:"revision"`
+	CanaryNamespaces []string                    `json:"canaryNamespaces"`
+}
+
+// Progress of the canary rollout of the source secret of a rule for a single
+// reconciliation of the SecretCopier.
+type ruleRollout struct {
+	status *secretsv1beta1.SecretCopierRolloutStatus
+	canary map[string]bool
+
+	// Time after which the SecretCopier should be reconciled again to
+	// advance the rollout. Zero if no requeue is needed.
+	requeueAfter time.Duration
+}
+
+// Whether the target namespace should be held back from being updated because
+// the rollout has not progressed past the canary namespaces.
+func (ro *ruleRollout) holds(targetNamespace string) bool {
+	if ro == nil || ro.canary[targetNamespace] {
+		return false
+	}
+
+	return ro.status.Phase != secretsv1beta1.RolloutComplete
+}
+
+// Calculate the revision of the source secret being rolled out from the type
+// and data of the secret.
+func sourceSecretRevision(secret *corev1.Secret) string {
+	hash := sha256.New()
+
+	hash.Write([]byte(secret.Type))
+
+	keys := make([]string, 0, len(secret.Data))
+
+	for key := range secret.Data {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Fprintf(hash, "\x00%s\x00%d\x00", key, len(secret.Data[key]))
+		hash.Write(secret.Data[key])
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// Determine the state of the canary rollout for a rule before target secrets
+// are copied. Returns nil if the rule has no rollout configured or there is
+// nothing to roll out. The previous progress of the rollout is taken from the
+// status of the SecretCopier so the rollout survives restarts of the manager.
+func (r *SecretCopierReconciler) startRollout(ctx context.Context, secretCopier *secretsv1beta1.SecretCopier, ruleIndex int, rule *secretsv1beta1.SecretCopierRule, canaryNamespaces []string) (*ruleRollout, error) {
+	log := log.FromContext(ctx)
+
+	if rule.Rollout == nil {
+		return nil, nil
+	}
+
+	secretsClient, err := r.secretsClient(secretCopier)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var sourceSecret corev1.Secret
+
+	if err := secretsClient.Get(ctx, client.ObjectKey{Namespace: rule.SourceSecret.Namespace, Name: rule.SourceSecret.Name}, &sourceSecret); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+
+	revision := sourceSecretRevision(&sourceSecret)
+
+	rollout := &ruleRollout{
+		canary: make(map[string]bool, len(canaryNamespaces)),
+	}
+
+	for _, namespace := range canaryNamespaces {
+		rollout.canary[namespace] = true
+	}
+
+	// Look up the progress of any prior rollout for the same source secret.
+	// If there is none, the source secret is being copied for the first time
+	// and there is no earlier version to protect, so copy it everywhere.
+
+	var previous *secretsv1beta1.SecretCopierRolloutStatus
+
+	if ruleStatus := previousRuleStatus(secretCopier.Status.Rules, ruleIndex, rule); ruleStatus != nil {
+		previous = ruleStatus.Rollout
+	}
+
+	switch {
+	case previous == nil:
+		rollout.status = &secretsv1beta1.SecretCopierRolloutStatus{
+			Revision:         revision,
+			Phase:            secretsv1beta1.RolloutComplete,
+			CanaryNamespaces: int32(len(canaryNamespaces)),
+			Message:          "Initial copy of source secret",
+		}
+
+		return rollout, nil
+
+	case previous.Revision != revision:
+		log.Info("Starting canary rollout of source secret", "sourceSecret", rule.SourceSecret, "revision", revision, "canaryNamespaces", canaryNamespaces)
+
+		r.Recorder.Eventf(secretCopier, corev1.EventTypeNormal, "RolloutStarted",
+			"Started canary rollout of secret %s/%s revision %s to %d canary namespaces",
+			rule.SourceSecret.Namespace, rule.SourceSecret.Name, revision, len(canaryNamespaces))
+
+		rollout.status = &secretsv1beta1.SecretCopierRolloutStatus{
+			Revision:         revision,
+			Phase:            secretsv1beta1.RolloutCanary,
+			CanaryNamespaces: int32(len(canaryNamespaces)),
+			Message:          "Updating canary namespaces",
+		}
+
+		return rollout, nil
+	}
+
+	rollout.status = previous.DeepCopy()
+	rollout.status.CanaryNamespaces = int32(len(canaryNamespaces))
+
+	if rollout.status.Phase != secretsv1beta1.RolloutVerifying {
+		return rollout, nil
+	}
+
+	// The canary namespaces were updated previously. Wait out the remainder of
+	// the verification delay, then run the verification hook if there is one
+	// to decide whether to proceed with the remaining target namespaces.
+
+	if rollout.status.CanaryCompletedTime != nil {
+		verifyAt := rollout.status.CanaryCompletedTime.Add(rule.Rollout.VerificationDelay.Duration)
+
+		if remaining := time.Until(verifyAt); remaining > 0 {
+			rollout.requeueAfter = remaining
+			return rollout, nil
+		}
+	}
+
+	if err := verifyRollout(ctx, secretCopier, rule, revision, canaryNamespaces); err != nil {
+		log.Info("Canary rollout verification failed", "sourceSecret", rule.SourceSecret, "revision", revision, "error", err.Error())
+
+		r.Recorder.Eventf(secretCopier, corev1.EventTypeWarning, "RolloutFailed",
+			"Verification of canary rollout of secret %s/%s revision %s failed: %v",
+			rule.SourceSecret.Namespace, rule.SourceSecret.Name, revision, err)
+
+		rollout.status.Phase = secretsv1beta1.RolloutFailed
+		rollout.status.Message = fmt.Sprintf("Verification failed: %v", err)
+
+		return rollout, nil
+	}
+
+	log.Info("Canary rollout verified, updating remaining namespaces", "sourceSecret", rule.SourceSecret, "revision", revision)
+
+	r.Recorder.Eventf(secretCopier, corev1.EventTypeNormal, "RolloutVerified",
+		"Verified canary rollout of secret %s/%s revision %s, updating remaining namespaces",
+		rule.SourceSecret.Namespace, rule.SourceSecret.Name, revision)
+
+	rollout.status.Phase = secretsv1beta1.RolloutComplete
+	rollout.status.Message = "Updating all target namespaces"
+
+	return rollout, nil
+}
+
+// Advance the canary rollout for a rule after target secrets have been copied.
+// Once all canary namespaces have been updated the rollout waits for the
+// verification delay before the remaining namespaces are updated.
+func (ro *ruleRollout) finish(rule *secretsv1beta1.SecretCopierRule, canarySynced int) {
+	if ro == nil || ro.status.Phase != secretsv1beta1.RolloutCanary {
+		return
+	}
+
+	if canarySynced < len(ro.canary) {
+		ro.status.Message = fmt.Sprintf("Updated %d of %d canary namespaces", canarySynced, len(ro.canary))
+		return
+	}
+
+	now := metav1.Now()
+
+	ro.status.Phase = secretsv1beta1.RolloutVerifying
+	ro.status.CanaryCompletedTime = &now
+	ro.status.Message = "Waiting for verification of canary namespaces"
+
+	ro.requeueAfter = rule.Rollout.VerificationDelay.Duration
+
+	if ro.requeueAfter <= 0 {
+		ro.requeueAfter = time.Second
+	}
+}
+
+// Call the verification hook for the rollout if one is configured. Returns an
+// error if the hook could not be called or did not respond with a 2xx status.
+func verifyRollout(ctx context.Context, secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, revision string, canaryNamespaces []string) error {
+	if rule.Rollout.VerificationURL == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(rolloutVerificationRequest{
+		SecretCopier:     secretCopier.Name,
+		SourceSecret:     rule.SourceSecret,
+		Revision:         revision,
+		CanaryNamespaces: canaryNamespaces,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, rule.Rollout.VerificationURL, bytes.NewReader(body))
+
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := rolloutVerificationClient.Do(request)
+
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("verification hook returned status %d", response.StatusCode)
+	}
+
+	return nil
+}
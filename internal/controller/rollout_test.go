@@ -0,0 +1,113 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("SecretCopier Rollout", func() {
+	ctx := context.Background()
+
+	It("should only change revision when the secret content changes", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "b"},
+			Type:       corev1.SecretTypeOpaque,
+			Data:       map[string][]byte{"key1": []byte("value1")},
+		}
+
+		revision := sourceSecretRevision(secret)
+
+		secret.Labels = map[string]string{"label": "value"}
+		Expect(sourceSecretRevision(secret)).To(Equal(revision))
+
+		secret.Data["key1"] = []byte("value2")
+		Expect(sourceSecretRevision(secret)).NotTo(Equal(revision))
+	})
+
+	It("should hold non canary namespaces until the rollout completes", func() {
+		rule := &secretsv1beta1.SecretCopierRule{
+			Rollout: &secretsv1beta1.SecretCopierRollout{
+				VerificationDelay: metav1.Duration{Duration: time.Minute},
+			},
+		}
+
+		rollout := &ruleRollout{
+			status: &secretsv1beta1.SecretCopierRolloutStatus{
+				Phase: secretsv1beta1.RolloutCanary,
+			},
+			canary: map[string]bool{"canary-1": true, "canary-2": true},
+		}
+
+		Expect(rollout.holds("canary-1")).To(BeFalse())
+		Expect(rollout.holds("other")).To(BeTrue())
+
+		rollout.finish(rule, 1)
+
+		Expect(rollout.status.Phase).To(Equal(secretsv1beta1.RolloutCanary))
+		Expect(rollout.requeueAfter).To(BeZero())
+
+		rollout.finish(rule, 2)
+
+		Expect(rollout.status.Phase).To(Equal(secretsv1beta1.RolloutVerifying))
+		Expect(rollout.status.CanaryCompletedTime).NotTo(BeNil())
+		Expect(rollout.requeueAfter).To(Equal(time.Minute))
+		Expect(rollout.holds("other")).To(BeTrue())
+
+		rollout.status.Phase = secretsv1beta1.RolloutComplete
+		Expect(rollout.holds("other")).To(BeFalse())
+
+		var noRollout *ruleRollout
+		Expect(noRollout.holds("other")).To(BeFalse())
+	})
+
+	It("should gate the rollout on the verification hook", func() {
+		status := http.StatusOK
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		defer server.Close()
+
+		secretCopier := &secretsv1beta1.SecretCopier{
+			ObjectMeta: metav1.ObjectMeta{Name: "rollout-secret-copier"},
+		}
+
+		rule := &secretsv1beta1.SecretCopierRule{
+			Rollout: &secretsv1beta1.SecretCopierRollout{
+				VerificationURL: server.URL,
+			},
+		}
+
+		Expect(verifyRollout(ctx, secretCopier, rule, "revision", []string{"canary-1"})).To(Succeed())
+
+		status = http.StatusServiceUnavailable
+
+		Expect(verifyRollout(ctx, secretCopier, rule, "revision", []string{"canary-1"})).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,204 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Outcome of copying the secret of a rule to its target namespaces in a
+// reconciliation, used to update the counts, last error and conditions in the
+// status of the rule.
+type ruleOutcome struct {
+	created int64
+	updated int64
+	failed  int32
+
+	// Number of failed target namespaces for each reason of failure.
+	failureReasons map[secretsv1beta1.CopyFailureReason]int32
+
+	lastError string
+}
+
+// Record the result of copying the secret of the rule to a target namespace.
+func (o *ruleOutcome) copied(targetNamespace string, result copyResult, err error) {
+	switch result {
+	case copyCreated:
+		o.created++
+	case copyUpdated:
+		o.updated++
+	case copyFailed, copyForbidden:
+		o.failed++
+
+		if o.failureReasons == nil {
+			o.failureReasons = make(map[secretsv1beta1.CopyFailureReason]int32)
+		}
+
+		o.failureReasons[classifyCopyFailure(result, err)]++
+
+		if err != nil {
+			o.lastError = fmt.Sprintf("namespace %s: %v", targetNamespace, err)
+		}
+	}
+}
+
+// Describe the number of failed target namespaces for each reason of failure,
+// ordered by reason so the description is stable.
+func (o *ruleOutcome) describeFailureReasons() string {
+	reasons := make([]string, 0, len(o.failureReasons))
+
+	for reason := range o.failureReasons {
+		reasons = append(reasons, string(reason))
+	}
+
+	sort.Strings(reasons)
+
+	for i, reason := range reasons {
+		reasons[i] = fmt.Sprintf("%s: %d", reason, o.failureReasons[secretsv1beta1.CopyFailureReason(reason)])
+	}
+
+	return strings.Join(reasons, ", ")
+}
+
+// Update the status of a rule with the outcome of the reconciliation. Counts
+// and the last error are carried over from the previous status of the rule,
+// if it was for the same source secret. The time of the last error is only
+// updated when the error changes, and conditions only transition when their
+// status changes, so the status settles when nothing changes.
+func (o *ruleOutcome) apply(ruleStatus *secretsv1beta1.SecretCopierRuleStatus, previous *secretsv1beta1.SecretCopierRuleStatus, generation int64, now metav1.Time) {
+	if previous != nil {
+		ruleStatus.CreatedSecrets = previous.CreatedSecrets
+		ruleStatus.UpdatedSecrets = previous.UpdatedSecrets
+		ruleStatus.LastError = previous.LastError
+		ruleStatus.LastErrorTime = previous.LastErrorTime
+		ruleStatus.Conditions = previous.Conditions
+	}
+
+	ruleStatus.CreatedSecrets += o.created
+	ruleStatus.UpdatedSecrets += o.updated
+
+	if o.lastError != "" && o.lastError != ruleStatus.LastError {
+		ruleStatus.LastError = o.lastError
+		ruleStatus.LastErrorTime = &now
+	}
+
+	condition := metav1.Condition{
+		Type:               secretsv1beta1.ConditionSynced,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+	}
+
+	switch {
+	case ruleStatus.Disabled:
+		condition.Reason = "Disabled"
+		condition.Message = "Rule is disabled"
+	case ruleStatus.DependencyError != "":
+		condition.Reason = "DependencyError"
+		condition.Message = ruleStatus.DependencyError
+	case ruleStatus.FanOutBlocked:
+		condition.Reason = "FanOutBlocked"
+		condition.Message = fmt.Sprintf("Rule matches %d target namespaces without setting allowLargeFanOut", ruleStatus.MatchedNamespaces)
+	case ruleStatus.Message != "":
+		condition.Reason = "NotCopying"
+		condition.Message = ruleStatus.Message
+	case o.failed > 0:
+		condition.Reason = "CopyFailed"
+		condition.Message = fmt.Sprintf("Copying failed for %d of %d target namespaces (%s)", o.failed, ruleStatus.MatchedNamespaces, o.describeFailureReasons())
+	case ruleStatus.SyncedNamespaces+ruleStatus.IgnoredNamespaces < ruleStatus.MatchedNamespaces:
+		condition.Reason = "Pending"
+		condition.Message = fmt.Sprintf("Target secret in sync in %d of %d target namespaces", ruleStatus.SyncedNamespaces, ruleStatus.MatchedNamespaces)
+	default:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "Synced"
+		condition.Message = fmt.Sprintf("Target secret in sync in all %d target namespaces", ruleStatus.MatchedNamespaces)
+
+		if ruleStatus.IgnoredNamespaces > 0 {
+			condition.Message = fmt.Sprintf("Target secret in sync in all %d target namespaces not ignoring it, ignored in %d",
+				ruleStatus.MatchedNamespaces-ruleStatus.IgnoredNamespaces, ruleStatus.IgnoredNamespaces)
+		}
+	}
+
+	// Copy the conditions before changing them, as they are shared with the
+	// previous status.
+
+	ruleStatus.Conditions = append([]metav1.Condition(nil), ruleStatus.Conditions...)
+
+	meta.SetStatusCondition(&ruleStatus.Conditions, condition)
+}
+
+// Find the status the rule had after the previous reconciliation. Rules expanded
+// from a source secret name pattern shift position as secrets matching the
+// pattern are added or removed, so where the status at the same position is
+// for a different source secret, the status is looked up by source secret.
+func previousRuleStatus(statuses []secretsv1beta1.SecretCopierRuleStatus, ruleIndex int, rule *secretsv1beta1.SecretCopierRule) *secretsv1beta1.SecretCopierRuleStatus {
+	if ruleIndex < len(statuses) && statuses[ruleIndex].SourceSecret == rule.SourceSecret {
+		return &statuses[ruleIndex]
+	}
+
+	for i := range statuses {
+		if statuses[i].SourceSecret == rule.SourceSecret && statuses[i].Name == rule.Name {
+			return &statuses[i]
+		}
+	}
+
+	return nil
+}
+
+// Determine the Ready condition of a SecretCopier from the Synced conditions
+// of its rules. Disabled rules are ignored.
+func readyCondition(ruleStatuses []secretsv1beta1.SecretCopierRuleStatus, generation int64) metav1.Condition {
+	notSynced := make([]string, 0)
+
+	for ruleIndex := range ruleStatuses {
+		ruleStatus := &ruleStatuses[ruleIndex]
+
+		if ruleStatus.Disabled || meta.IsStatusConditionTrue(ruleStatus.Conditions, secretsv1beta1.ConditionSynced) {
+			continue
+		}
+
+		if ruleStatus.Name != "" {
+			notSynced = append(notSynced, ruleStatus.Name)
+		} else {
+			notSynced = append(notSynced, fmt.Sprintf("rule %d", ruleIndex))
+		}
+	}
+
+	if len(notSynced) != 0 {
+		return metav1.Condition{
+			Type:               secretsv1beta1.ConditionReady,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: generation,
+			Reason:             "RulesNotSynced",
+			Message:            "Rules not in sync: " + strings.Join(notSynced, ", "),
+		}
+	}
+
+	return metav1.Condition{
+		Type:               secretsv1beta1.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "Synced",
+		Message:            "Target secrets of all rules are in sync",
+	}
+}
@@ -0,0 +1,270 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+var _ = Describe("Rule Status", func() {
+	earlier := metav1.NewTime(time.Date(2024, 9, 1, 10, 0, 0, 0, time.UTC))
+	now := metav1.NewTime(time.Date(2024, 9, 1, 11, 0, 0, 0, time.UTC))
+
+	It("should count secrets written and report the rule as synced", func() {
+		var outcome ruleOutcome
+
+		outcome.copied("tenant-a", copyCreated, nil)
+		outcome.copied("tenant-b", copyUpdated, nil)
+		outcome.copied("tenant-c", copyUnchanged, nil)
+
+		ruleStatus := secretsv1beta1.SecretCopierRuleStatus{MatchedNamespaces: 3, SyncedNamespaces: 3}
+
+		outcome.apply(&ruleStatus, &secretsv1beta1.SecretCopierRuleStatus{CreatedSecrets: 5, UpdatedSecrets: 2}, 1, now)
+
+		Expect(ruleStatus.CreatedSecrets).To(Equal(int64(6)))
+		Expect(ruleStatus.UpdatedSecrets).To(Equal(int64(3)))
+		Expect(ruleStatus.LastError).To(BeEmpty())
+
+		condition := meta.FindStatusCondition(ruleStatus.Conditions, secretsv1beta1.ConditionSynced)
+
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.ObservedGeneration).To(Equal(int64(1)))
+	})
+
+	It("should record the last error and only update its time when it changes", func() {
+		var outcome ruleOutcome
+
+		outcome.copied("tenant-a", copyFailed, errors.New("boom"))
+
+		previous := &secretsv1beta1.SecretCopierRuleStatus{
+			LastError:     "namespace tenant-a: boom",
+			LastErrorTime: &earlier,
+		}
+
+		ruleStatus := secretsv1beta1.SecretCopierRuleStatus{MatchedNamespaces: 1}
+
+		outcome.apply(&ruleStatus, previous, 1, now)
+
+		Expect(ruleStatus.LastError).To(Equal("namespace tenant-a: boom"))
+		Expect(ruleStatus.LastErrorTime).To(Equal(&earlier))
+		Expect(meta.FindStatusCondition(ruleStatus.Conditions, secretsv1beta1.ConditionSynced).Reason).To(Equal("CopyFailed"))
+		Expect(meta.FindStatusCondition(ruleStatus.Conditions, secretsv1beta1.ConditionSynced).Message).To(Equal("Copying failed for 1 of 1 target namespaces (Unknown: 1)"))
+
+		outcome = ruleOutcome{}
+		outcome.copied("tenant-b", copyForbidden, errors.New("denied"))
+
+		ruleStatus = secretsv1beta1.SecretCopierRuleStatus{MatchedNamespaces: 1}
+
+		outcome.apply(&ruleStatus, previous, 1, now)
+
+		Expect(ruleStatus.LastError).To(Equal("namespace tenant-b: denied"))
+		Expect(ruleStatus.LastErrorTime).To(Equal(&now))
+	})
+
+	It("should keep the transition time of an unchanged condition", func() {
+		previous := &secretsv1beta1.SecretCopierRuleStatus{
+			Conditions: []metav1.Condition{{
+				Type:               secretsv1beta1.ConditionSynced,
+				Status:             metav1.ConditionFalse,
+				Reason:             "Pending",
+				LastTransitionTime: earlier,
+			}},
+		}
+
+		ruleStatus := secretsv1beta1.SecretCopierRuleStatus{MatchedNamespaces: 2, SyncedNamespaces: 1}
+
+		var outcome ruleOutcome
+
+		outcome.apply(&ruleStatus, previous, 2, now)
+
+		condition := meta.FindStatusCondition(ruleStatus.Conditions, secretsv1beta1.ConditionSynced)
+
+		Expect(condition.Reason).To(Equal("Pending"))
+		Expect(condition.LastTransitionTime).To(Equal(earlier))
+		Expect(condition.ObservedGeneration).To(Equal(int64(2)))
+		Expect(previous.Conditions[0].ObservedGeneration).To(Equal(int64(0)))
+	})
+
+	It("should explain why a rule is not copying", func() {
+		var outcome ruleOutcome
+
+		ruleStatus := secretsv1beta1.SecretCopierRuleStatus{Message: "Source secret secrets/registry not found"}
+
+		outcome.apply(&ruleStatus, nil, 1, now)
+
+		condition := meta.FindStatusCondition(ruleStatus.Conditions, secretsv1beta1.ConditionSynced)
+
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal("NotCopying"))
+		Expect(condition.Message).To(Equal("Source secret secrets/registry not found"))
+	})
+
+	It("should report the SecretCopier ready only when all enabled rules are synced", func() {
+		synced := []metav1.Condition{{Type: secretsv1beta1.ConditionSynced, Status: metav1.ConditionTrue}}
+		pending := []metav1.Condition{{Type: secretsv1beta1.ConditionSynced, Status: metav1.ConditionFalse}}
+
+		ruleStatuses := []secretsv1beta1.SecretCopierRuleStatus{
+			{Conditions: synced},
+			{Disabled: true, Conditions: pending},
+		}
+
+		Expect(readyCondition(ruleStatuses, 1).Status).To(Equal(metav1.ConditionTrue))
+
+		ruleStatuses = append(ruleStatuses, secretsv1beta1.SecretCopierRuleStatus{Name: "database", Conditions: pending})
+
+		condition := readyCondition(ruleStatuses, 1)
+
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Message).To(Equal("Rules not in sync: database"))
+	})
+	It("should find the previous status of a rule expanded from a pattern after it moves", func() {
+		source := func(name string) secretsv1beta1.SourceSecret {
+			return secretsv1beta1.SourceSecret{Name: name, Namespace: "source"}
+		}
+
+		statuses := []secretsv1beta1.SecretCopierRuleStatus{
+			{SourceSecret: source("registry-a"), CreatedSecrets: 1},
+			{SourceSecret: source("registry-c"), CreatedSecrets: 3},
+		}
+
+		// A secret matching the pattern was added, so the rule for the last
+		// secret moved along one position.
+
+		for _, example := range []struct {
+			ruleIndex int
+			name      string
+			created   int64
+		}{
+			{ruleIndex: 0, name: "registry-a", created: 1},
+			{ruleIndex: 1, name: "registry-b", created: 0},
+			{ruleIndex: 2, name: "registry-c", created: 3},
+		} {
+			rule := &secretsv1beta1.SecretCopierRule{SourceSecret: source(example.name)}
+
+			previous := previousRuleStatus(statuses, example.ruleIndex, rule)
+
+			if example.created == 0 {
+				Expect(previous).To(BeNil(), example.name)
+				continue
+			}
+
+			Expect(previous).NotTo(BeNil(), example.name)
+			Expect(previous.CreatedSecrets).To(Equal(example.created), example.name)
+		}
+	})
+
+	Context("When target secret writes are rate limited", func() {
+		ctx := context.Background()
+
+		It("should always permit writes without a limiter", func() {
+			reconciler := &SecretCopierReconciler{}
+
+			Expect(reconciler.allowSecretWrite()).To(BeTrue())
+			Expect(reconciler.secretWriteDelay()).To(BeZero())
+		})
+
+		It("should permit writes up to the burst and report when the next is allowed", func() {
+			reconciler := &SecretCopierReconciler{SecretWriteLimiter: rate.NewLimiter(rate.Every(time.Minute), 2)}
+
+			Expect(reconciler.secretWriteDelay()).To(BeZero())
+
+			Expect(reconciler.allowSecretWrite()).To(BeTrue())
+			Expect(reconciler.allowSecretWrite()).To(BeTrue())
+
+			delay := reconciler.secretWriteDelay()
+
+			Expect(delay).To(BeNumerically(">", 50*time.Second))
+			Expect(delay).To(BeNumerically("<=", time.Minute))
+
+			// Working out the delay doesn't use up a token.
+
+			Expect(reconciler.secretWriteDelay()).To(BeNumerically("~", delay, time.Second))
+
+			Expect(reconciler.allowSecretWrite()).To(BeFalse())
+		})
+
+		It("should report target namespaces not yet written as pending", func() {
+			secretCopier := &secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{Name: "limited-copier", Generation: 1},
+				Spec: secretsv1beta1.SecretCopierSpec{
+					Rules: []secretsv1beta1.SecretCopierRule{
+						{
+							SourceSecret: secretsv1beta1.SourceSecret{Name: "registry", Namespace: "registries"},
+							TargetNamespaces: selectors.TargetNamespaces{
+								NameSelector: selectors.NameSelector{MatchNames: []string{"tenant-*"}},
+							},
+						},
+					},
+				},
+			}
+
+			k8sClient := fake.NewClientBuilder().WithObjects(
+				secretCopier,
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "registries"}},
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-a"}},
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-b"}},
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-c"}},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "registries"},
+					Data:       map[string][]byte{"token": []byte("registry-token")},
+				},
+			).WithStatusSubresource(secretCopier).Build()
+
+			reconciler := &SecretCopierReconciler{
+				Client:             k8sClient,
+				Recorder:           record.NewFakeRecorder(100),
+				SecretWriteLimiter: rate.NewLimiter(rate.Every(time.Hour), 1),
+			}
+
+			result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "limited-copier"}})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 59*time.Minute))
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(secretCopier), secretCopier)).To(Succeed())
+
+			Expect(secretCopier.Status.Rules).To(HaveLen(1))
+			Expect(secretCopier.Status.Rules[0].MatchedNamespaces).To(Equal(int32(3)))
+			Expect(secretCopier.Status.Rules[0].SyncedNamespaces).To(Equal(int32(1)))
+			Expect(secretCopier.Status.Rules[0].PendingNamespaces).To(Equal(int32(2)))
+
+			var secrets corev1.SecretList
+
+			Expect(k8sClient.List(ctx, &secrets)).To(Succeed())
+			Expect(secrets.Items).To(HaveLen(2))
+		})
+	})
+})
@@ -0,0 +1,553 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// SecretClaimReconciler reconciles a SecretClaim object
+type SecretClaimReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Recorder for events about SecretClaim objects.
+	Recorder record.EventRecorder
+
+	// If set, a SubjectAccessReview is performed for each claim to check that
+	// the namespace of the claim has been granted access to the catalog entry
+	// through RBAC, in addition to the namespace being allowed by the entry.
+	AuthorizeClaims bool
+
+	// Controller wide settings which can be changed while running. If nil,
+	// the built in defaults are used.
+	Config *ManagerConfig
+
+	// If set, secrets for claims are never written. Claims which would have
+	// resulted in a write are left pending.
+	ReportOnly bool
+}
+
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=secretclaims,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=secretclaims/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=secretclaims/finalizers,verbs=update
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=secretcatalogs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// Reconcile a SecretClaim by looking up the entry it claims in the SecretCatalog,
+// verifying that the entry can be claimed from the namespace of the claim, and
+// copying the secret for the entry into the namespace of the claim.
+func (r *SecretClaimReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	// Fetch the named SecretClaim object.
+
+	var secretClaim secretsv1beta1.SecretClaim
+
+	if err := r.Get(ctx, req.NamespacedName, &secretClaim); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			// Custom resource has been deleted. The secret created for the
+			// claim will be deleted by the garbage collector as the claim is
+			// set as its owner.
+
+			log.V(1).Info("SecretClaim has been deleted", "name", req.NamespacedName)
+
+			return ctrl.Result{}, nil
+		}
+
+		log.Error(err, "Unable to fetch SecretClaim", "name", req.NamespacedName)
+
+		return ctrl.Result{}, err
+	}
+
+	secretName := secretClaim.Spec.SecretName
+
+	if secretName == "" {
+		secretName = secretClaim.Name
+	}
+
+	// Determine the state of the lease on the claim if it has one. The claim
+	// is only bound while the lease has not expired.
+
+	now := time.Now()
+
+	lease := evaluateClaimLease(&secretClaim, now)
+
+	var phase secretsv1beta1.SecretClaimPhase
+	var message string
+
+	if lease != nil && lease.expired {
+		phase = secretsv1beta1.SecretClaimExpired
+		message = fmt.Sprintf("Lease on claim expired at %s", lease.expiryTime.UTC().Format(time.RFC3339))
+	} else {
+		var err error
+
+		phase, message, err = r.bindSecretClaim(ctx, &secretClaim, secretName)
+
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// If the claim is no longer bound, remove any secret previously created
+	// for it.
+
+	if phase != secretsv1beta1.SecretClaimBound && phase != secretsv1beta1.SecretClaimPending {
+		if err := r.deleteClaimedSecret(ctx, &secretClaim, secretName); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// If the claim was bound and the entry has since gone away, the claim has
+	// expired rather than been denied.
+
+	if phase == secretsv1beta1.SecretClaimDenied && secretClaim.Status.Phase == secretsv1beta1.SecretClaimBound {
+		phase = secretsv1beta1.SecretClaimExpired
+	}
+
+	if lease != nil && lease.renewed {
+		r.Recorder.Eventf(&secretClaim, corev1.EventTypeNormal, "LeaseRenewed",
+			"Lease on claim renewed until %s", lease.expiryTime.UTC().Format(time.RFC3339))
+	}
+
+	if phase != secretClaim.Status.Phase {
+		eventType := corev1.EventTypeNormal
+
+		if phase == secretsv1beta1.SecretClaimDenied || phase == secretsv1beta1.SecretClaimExpired {
+			eventType = corev1.EventTypeWarning
+		}
+
+		r.Recorder.Event(&secretClaim, eventType, string(phase), message)
+	}
+
+	status := secretsv1beta1.SecretClaimStatus{
+		ObservedGeneration: secretClaim.Generation,
+		Phase:              phase,
+		Message:            message,
+	}
+
+	if phase == secretsv1beta1.SecretClaimBound {
+		status.SecretName = secretName
+	}
+
+	if lease != nil {
+		status.LeaseRenewTime = &metav1.Time{Time: lease.renewTime}
+		status.LeaseExpiryTime = &metav1.Time{Time: lease.expiryTime}
+		status.ObservedRenewal = lease.renewal
+	}
+
+	if err := r.updateSecretClaimStatus(ctx, &secretClaim, status); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Requeue so the secret is removed when the lease expires, or written
+	// when a freeze on writes to secrets expires, whichever is sooner.
+
+	var requeue time.Duration
+
+	if lease != nil && !lease.expired {
+		requeue = lease.expiryTime.Sub(now)
+	}
+
+	if freezeRequeue := r.Config.Settings().WriteFreezeRemaining(now); freezeRequeue > 0 && (requeue <= 0 || freezeRequeue < requeue) {
+		requeue = freezeRequeue
+	}
+
+	return ctrl.Result{RequeueAfter: requeue}, nil
+}
+
+// State of the lease on a SecretClaim.
+type claimLease struct {
+	renewTime  time.Time
+	expiryTime time.Time
+	renewal    string
+	renewed    bool
+	expired    bool
+}
+
+// Evaluate the lease on a SecretClaim, returning nil if the claim doesn't have
+// a lease. The lease starts when first evaluated and is renewed whenever the
+// value of the renew lease annotation differs from that last observed.
+func evaluateClaimLease(secretClaim *secretsv1beta1.SecretClaim, now time.Time) *claimLease {
+	if secretClaim.Spec.LeaseDuration == nil {
+		return nil
+	}
+
+	lease := &claimLease{
+		renewal: secretClaim.Annotations[secretsv1beta1.SecretClaimRenewLeaseAnnotation],
+	}
+
+	switch {
+	case secretClaim.Status.LeaseRenewTime == nil:
+		lease.renewTime = now
+	case lease.renewal != secretClaim.Status.ObservedRenewal:
+		lease.renewTime = now
+		lease.renewed = true
+	default:
+		lease.renewTime = secretClaim.Status.LeaseRenewTime.Time
+	}
+
+	// Status times are serialized to second precision so use the same
+	// precision for a new lease to avoid needless status updates.
+
+	lease.renewTime = lease.renewTime.Truncate(time.Second)
+	lease.expiryTime = lease.renewTime.Add(secretClaim.Spec.LeaseDuration.Duration)
+	lease.expired = !now.Before(lease.expiryTime)
+
+	return lease
+}
+
+// Attempt to bind the claim, returning the resulting phase and an explanation
+// of it. An error is only returned where the operation should be retried.
+func (r *SecretClaimReconciler) bindSecretClaim(ctx context.Context, secretClaim *secretsv1beta1.SecretClaim, secretName string) (secretsv1beta1.SecretClaimPhase, string, error) {
+	log := log.FromContext(ctx)
+
+	// Look up the entry being claimed in the SecretCatalog.
+
+	var secretCatalog secretsv1beta1.SecretCatalog
+
+	if err := r.Get(ctx, client.ObjectKey{Name: secretClaim.Spec.Catalog}, &secretCatalog); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return secretsv1beta1.SecretClaimDenied, fmt.Sprintf("SecretCatalog %s not found", secretClaim.Spec.Catalog), nil
+		}
+
+		log.Error(err, "Unable to fetch SecretCatalog", "name", secretClaim.Spec.Catalog)
+
+		return "", "", err
+	}
+
+	entry, found := secretCatalog.FindEntry(secretClaim.Spec.Key)
+
+	if !found {
+		return secretsv1beta1.SecretClaimDenied, fmt.Sprintf("Key %s not found in SecretCatalog %s", secretClaim.Spec.Key, secretClaim.Spec.Catalog), nil
+	}
+
+	// Verify that the entry can be claimed from the namespace of the claim.
+
+	var namespace corev1.Namespace
+
+	if err := r.Get(ctx, client.ObjectKey{Name: secretClaim.Namespace}, &namespace); err != nil {
+		log.Error(err, "Unable to fetch Namespace", "name", secretClaim.Namespace)
+
+		return "", "", err
+	}
+
+	if !entry.AllowedNamespaces.Matches(&namespace) || r.Config.Settings().NamespaceDenied(namespace.Name) {
+		return secretsv1beta1.SecretClaimDenied, fmt.Sprintf("Key %s of SecretCatalog %s cannot be claimed from namespace %s", secretClaim.Spec.Key, secretClaim.Spec.Catalog, secretClaim.Namespace), nil
+	}
+
+	// Verify that the namespace has been granted access to the entry through
+	// RBAC if authorization of claims is enabled.
+
+	if r.AuthorizeClaims {
+		allowed, reason, err := r.claimAllowed(ctx, secretClaim)
+
+		if err != nil {
+			return "", "", err
+		}
+
+		if !allowed {
+			message := fmt.Sprintf("Namespace %s is not authorized to claim key %s of SecretCatalog %s", secretClaim.Namespace, secretClaim.Spec.Key, secretClaim.Spec.Catalog)
+
+			if reason != "" {
+				message += ": " + reason
+			}
+
+			return secretsv1beta1.SecretClaimDenied, message, nil
+		}
+	}
+
+	// Fetch the source secret for the entry.
+
+	var sourceSecret corev1.Secret
+
+	if err := r.Get(ctx, client.ObjectKey{Namespace: entry.SourceSecret.Namespace, Name: entry.SourceSecret.Name}, &sourceSecret); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return secretsv1beta1.SecretClaimPending, fmt.Sprintf("Source secret %s/%s not found", entry.SourceSecret.Namespace, entry.SourceSecret.Name), nil
+		}
+
+		log.Error(err, "Unable to fetch source secret", "sourceSecret", entry.SourceSecret)
+
+		return "", "", err
+	}
+
+	if r.Config.Settings().SecretTypeDenied(sourceSecret.Type) {
+		return secretsv1beta1.SecretClaimDenied, fmt.Sprintf("Source secret %s/%s is of type %s which is never copied", entry.SourceSecret.Namespace, entry.SourceSecret.Name, sourceSecret.Type), nil
+	}
+
+	// Create or update the secret in the namespace of the claim. An existing
+	// secret which isn't managed by this claim is never overwritten.
+
+	var targetSecret corev1.Secret
+
+	err := r.Get(ctx, client.ObjectKey{Namespace: secretClaim.Namespace, Name: secretName}, &targetSecret)
+
+	if err != nil && client.IgnoreNotFound(err) != nil {
+		log.Error(err, "Unable to fetch target secret", "targetSecret", secretName, "targetNamespace", secretClaim.Namespace)
+
+		return "", "", err
+	}
+
+	exists := err == nil
+
+	if exists && !metav1.IsControlledBy(&targetSecret, secretClaim) {
+		return secretsv1beta1.SecretClaimDenied, fmt.Sprintf("Secret %s already exists and is not managed by the claim", secretName), nil
+	}
+
+	source := entry.SourceSecret.Namespace + "/" + entry.SourceSecret.Name
+
+	if exists {
+		if targetSecret.Type == sourceSecret.Type && equality.Semantic.DeepEqual(targetSecret.Data, sourceSecret.Data) &&
+			targetSecret.Annotations["secrets-manager.advok8s.io/secret-name"] == source {
+			return secretsv1beta1.SecretClaimBound, fmt.Sprintf("Secret %s created from key %s of SecretCatalog %s", secretName, secretClaim.Spec.Key, secretClaim.Spec.Catalog), nil
+		}
+	}
+
+	// When running in report-only mode, or writes to secrets are frozen,
+	// the secret is not written and the claim is left pending.
+
+	if r.ReportOnly || r.Config.WritesFrozen() {
+		verb := "create"
+
+		if exists {
+			verb = "update"
+		}
+
+		log.Info("Writes suspended, skipping write of secret for SecretClaim", "verb", verb, "name", secretClaim.Name, "namespace", secretClaim.Namespace, "targetSecret", secretName)
+
+		countSkippedWrite("secretclaim", verb, r.ReportOnly)
+
+		return secretsv1beta1.SecretClaimPending, fmt.Sprintf("%s, secret %s would be %sd", skippedWriteReason(r.ReportOnly, r.Config), secretName, verb), nil
+	}
+
+	if exists {
+		// The type of a secret is immutable so if it differs the secret
+		// needs to be recreated.
+
+		if targetSecret.Type != sourceSecret.Type {
+			if err := r.Delete(ctx, &targetSecret); client.IgnoreNotFound(err) != nil {
+				log.Error(err, "Unable to delete target secret", "targetSecret", secretName, "targetNamespace", secretClaim.Namespace)
+
+				return "", "", err
+			}
+
+			exists = false
+		}
+	}
+
+	if !exists {
+		targetSecret = corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretName,
+				Namespace: secretClaim.Namespace,
+			},
+		}
+
+		if err := controllerutil.SetControllerReference(secretClaim, &targetSecret, r.Scheme); err != nil {
+			return "", "", err
+		}
+	}
+
+	if targetSecret.Annotations == nil {
+		targetSecret.Annotations = make(map[string]string)
+	}
+
+	targetSecret.Annotations["secrets-manager.advok8s.io/secret-claim"] = secretClaim.Name
+	targetSecret.Annotations["secrets-manager.advok8s.io/secret-name"] = source
+
+	targetSecret.Type = sourceSecret.Type
+	targetSecret.Data = sourceSecret.Data
+
+	if exists {
+		err = r.Update(ctx, &targetSecret)
+	} else {
+		err = r.Create(ctx, &targetSecret)
+	}
+
+	if err != nil {
+		log.Error(err, "Unable to write target secret", "targetSecret", secretName, "targetNamespace", secretClaim.Namespace)
+
+		return "", "", err
+	}
+
+	log.V(1).Info("Wrote secret for SecretClaim", "name", secretClaim.Name, "namespace", secretClaim.Namespace, "targetSecret", secretName)
+
+	return secretsv1beta1.SecretClaimBound, fmt.Sprintf("Secret %s created from key %s of SecretCatalog %s", secretName, secretClaim.Spec.Key, secretClaim.Spec.Catalog), nil
+}
+
+// Delete the secret created for a claim, if it exists and is managed by the
+// claim.
+func (r *SecretClaimReconciler) deleteClaimedSecret(ctx context.Context, secretClaim *secretsv1beta1.SecretClaim, secretName string) error {
+	log := log.FromContext(ctx)
+
+	var targetSecret corev1.Secret
+
+	if err := r.Get(ctx, client.ObjectKey{Namespace: secretClaim.Namespace, Name: secretName}, &targetSecret); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	if !metav1.IsControlledBy(&targetSecret, secretClaim) {
+		return nil
+	}
+
+	if r.ReportOnly || r.Config.WritesFrozen() {
+		log.Info("Writes suspended, skipping delete of secret for SecretClaim", "name", secretClaim.Name, "namespace", secretClaim.Namespace, "targetSecret", secretName)
+
+		countSkippedWrite("secretclaim", "delete", r.ReportOnly)
+
+		return nil
+	}
+
+	if err := r.Delete(ctx, &targetSecret); client.IgnoreNotFound(err) != nil {
+		log.Error(err, "Unable to delete secret for SecretClaim", "name", secretClaim.Name, "namespace", secretClaim.Namespace, "targetSecret", secretName)
+		return err
+	}
+
+	log.V(1).Info("Deleted secret for SecretClaim", "name", secretClaim.Name, "namespace", secretClaim.Namespace, "targetSecret", secretName)
+
+	return nil
+}
+
+// Update the status of the SecretClaim if it has changed.
+func (r *SecretClaimReconciler) updateSecretClaimStatus(ctx context.Context, secretClaim *secretsv1beta1.SecretClaim, status secretsv1beta1.SecretClaimStatus) error {
+	log := log.FromContext(ctx)
+
+	if equality.Semantic.DeepEqual(secretClaim.Status, status) {
+		return nil
+	}
+
+	secretClaim.Status = status
+
+	if err := r.Status().Update(ctx, secretClaim); err != nil {
+		log.Error(err, "Unable to update SecretClaim status", "name", secretClaim.Name, "namespace", secretClaim.Namespace)
+		return err
+	}
+
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SecretClaimReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&secretsv1beta1.SecretClaim{}).
+		Owns(&corev1.Secret{}).
+		Watches(
+			&secretsv1beta1.SecretCatalog{},
+			handler.EnqueueRequestsFromMapFunc(r.findSecretClaimsForSecretCatalog),
+		).
+		Watches(
+			&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.findSecretClaimsForSourceSecret),
+			builder.WithPredicates(secretContentChanged()),
+		).
+		Complete(r)
+}
+
+// Handler function to find SecretClaim objects which claim an entry from a
+// SecretCatalog. This is used to trigger a reconciliation of the claims when
+// the entries in the catalog change.
+func (r *SecretClaimReconciler) findSecretClaimsForSecretCatalog(ctx context.Context, object client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	var secretClaims secretsv1beta1.SecretClaimList
+
+	if err := r.List(ctx, &secretClaims); err != nil {
+		log.Error(err, "Unable to list SecretClaim objects")
+		return nil
+	}
+
+	var requests []reconcile.Request
+
+	for _, secretClaim := range secretClaims.Items {
+		if secretClaim.Spec.Catalog == object.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&secretClaim)})
+		}
+	}
+
+	return requests
+}
+
+// Handler function to find SecretClaim objects which claim an entry whose
+// source is the given secret. This is used to trigger a reconciliation of the
+// claims when the source secret is created or changes.
+func (r *SecretClaimReconciler) findSecretClaimsForSourceSecret(ctx context.Context, secret client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	var secretCatalogs secretsv1beta1.SecretCatalogList
+
+	if err := r.List(ctx, &secretCatalogs); err != nil {
+		log.Error(err, "Unable to list SecretCatalog objects")
+		return nil
+	}
+
+	// Determine the catalog entries which use the secret as their source.
+
+	type catalogKey struct {
+		catalog string
+		key     string
+	}
+
+	entries := make(map[catalogKey]struct{})
+
+	for _, secretCatalog := range secretCatalogs.Items {
+		for _, entry := range secretCatalog.Spec.Entries {
+			if entry.SourceSecret.Name == secret.GetName() && entry.SourceSecret.Namespace == secret.GetNamespace() {
+				entries[catalogKey{secretCatalog.Name, entry.Key}] = struct{}{}
+			}
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var secretClaims secretsv1beta1.SecretClaimList
+
+	if err := r.List(ctx, &secretClaims); err != nil {
+		log.Error(err, "Unable to list SecretClaim objects")
+		return nil
+	}
+
+	var requests []reconcile.Request
+
+	for _, secretClaim := range secretClaims.Items {
+		if _, ok := entries[catalogKey{secretClaim.Spec.Catalog, secretClaim.Spec.Key}]; ok {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&secretClaim)})
+		}
+	}
+
+	return requests
+}
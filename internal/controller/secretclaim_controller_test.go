@@ -0,0 +1,270 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+var _ = Describe("SecretClaim Controller", func() {
+	ctx := context.Background()
+
+	// Test claiming an entry from a secret catalog where the namespace of the
+	// claim is permitted to claim the entry, and where it is not.
+
+	Context("Claim secret from catalog #1", func() {
+		It("should bind claim from allowed namespace and deny others", func() {
+			sourceNamespaceName := "claim-source-namespace-1"
+			sourceSecretName := "claim-source-secret-1"
+			allowedNamespaceName := "claim-tenant-namespace-1"
+			deniedNamespaceName := "claim-other-namespace-1"
+			secretCatalogName := "secret-catalog-1"
+			secretClaimName := "secret-claim-1"
+
+			// Create source, allowed and denied namespaces.
+
+			for _, name := range []string{sourceNamespaceName, allowedNamespaceName, deniedNamespaceName} {
+				namespace := &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: name,
+					},
+				}
+				Expect(k8sClient.Create(ctx, namespace)).To(Succeed())
+			}
+
+			// Create source secret in source namespace.
+
+			sourceSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      sourceSecretName,
+					Namespace: sourceNamespaceName,
+				},
+				Type: corev1.SecretTypeOpaque,
+				StringData: map[string]string{
+					"key1": "value1",
+				},
+			}
+			Expect(k8sClient.Create(ctx, sourceSecret)).To(Succeed())
+
+			// Create the secret catalog custom resource.
+
+			secretCatalog := &secretsv1beta1.SecretCatalog{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: secretCatalogName,
+				},
+				Spec: secretsv1beta1.SecretCatalogSpec{
+					Entries: []secretsv1beta1.SecretCatalogEntry{
+						{
+							Key: "credentials",
+							SourceSecret: secretsv1beta1.SourceSecret{
+								Namespace: sourceNamespaceName,
+								Name:      sourceSecretName,
+							},
+							AllowedNamespaces: selectors.TargetNamespaces{
+								NameSelector: selectors.NameSelector{
+									MatchNames: []string{"claim-tenant-*"},
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, secretCatalog)).To(Succeed())
+
+			// Create secret claims in the allowed and denied namespaces.
+
+			for _, name := range []string{allowedNamespaceName, deniedNamespaceName} {
+				secretClaim := &secretsv1beta1.SecretClaim{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      secretClaimName,
+						Namespace: name,
+					},
+					Spec: secretsv1beta1.SecretClaimSpec{
+						Catalog: secretCatalogName,
+						Key:     "credentials",
+					},
+				}
+				Expect(k8sClient.Create(ctx, secretClaim)).To(Succeed())
+			}
+
+			// Wait for the claim in the allowed namespace to be bound and the
+			// secret to be created.
+
+			Eventually(func() secretsv1beta1.SecretClaimPhase {
+				secretClaim := &secretsv1beta1.SecretClaim{}
+				err := k8sClient.Get(ctx, client.ObjectKey{
+					Namespace: allowedNamespaceName,
+					Name:      secretClaimName,
+				}, secretClaim)
+				if err != nil {
+					return ""
+				}
+				return secretClaim.Status.Phase
+			}, 5*time.Second).Should(Equal(secretsv1beta1.SecretClaimBound))
+
+			Eventually(func() bool {
+				targetSecret := &corev1.Secret{}
+				err := k8sClient.Get(ctx, client.ObjectKey{
+					Namespace: allowedNamespaceName,
+					Name:      secretClaimName,
+				}, targetSecret)
+				return err == nil
+			}, 5*time.Second).Should(BeTrue())
+
+			// Wait for the claim in the denied namespace to be denied.
+
+			Eventually(func() secretsv1beta1.SecretClaimPhase {
+				secretClaim := &secretsv1beta1.SecretClaim{}
+				err := k8sClient.Get(ctx, client.ObjectKey{
+					Namespace: deniedNamespaceName,
+					Name:      secretClaimName,
+				}, secretClaim)
+				if err != nil {
+					return ""
+				}
+				return secretClaim.Status.Phase
+			}, 5*time.Second).Should(Equal(secretsv1beta1.SecretClaimDenied))
+		})
+	})
+
+	// Test that the secret for a claim with a lease is removed when the lease
+	// expires, and is restored when the lease is renewed.
+
+	Context("Claim secret with lease #1", func() {
+		It("should expire claim when lease runs out and restore it on renewal", func() {
+			sourceNamespaceName := "lease-source-namespace-1"
+			sourceSecretName := "lease-source-secret-1"
+			tenantNamespaceName := "lease-tenant-namespace-1"
+			secretCatalogName := "lease-secret-catalog-1"
+			secretClaimName := "lease-secret-claim-1"
+
+			// Create source and tenant namespaces.
+
+			for _, name := range []string{sourceNamespaceName, tenantNamespaceName} {
+				namespace := &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: name,
+					},
+				}
+				Expect(k8sClient.Create(ctx, namespace)).To(Succeed())
+			}
+
+			// Create source secret in source namespace.
+
+			sourceSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      sourceSecretName,
+					Namespace: sourceNamespaceName,
+				},
+				Type: corev1.SecretTypeOpaque,
+				StringData: map[string]string{
+					"key1": "value1",
+				},
+			}
+			Expect(k8sClient.Create(ctx, sourceSecret)).To(Succeed())
+
+			// Create the secret catalog custom resource.
+
+			secretCatalog := &secretsv1beta1.SecretCatalog{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: secretCatalogName,
+				},
+				Spec: secretsv1beta1.SecretCatalogSpec{
+					Entries: []secretsv1beta1.SecretCatalogEntry{
+						{
+							Key: "credentials",
+							SourceSecret: secretsv1beta1.SourceSecret{
+								Namespace: sourceNamespaceName,
+								Name:      sourceSecretName,
+							},
+							AllowedNamespaces: selectors.TargetNamespaces{
+								NameSelector: selectors.NameSelector{
+									MatchNames: []string{tenantNamespaceName},
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, secretCatalog)).To(Succeed())
+
+			// Create the secret claim with a short lease.
+
+			secretClaim := &secretsv1beta1.SecretClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      secretClaimName,
+					Namespace: tenantNamespaceName,
+				},
+				Spec: secretsv1beta1.SecretClaimSpec{
+					Catalog:       secretCatalogName,
+					Key:           "credentials",
+					LeaseDuration: &metav1.Duration{Duration: 3 * time.Second},
+				},
+			}
+			Expect(k8sClient.Create(ctx, secretClaim)).To(Succeed())
+
+			getPhase := func() secretsv1beta1.SecretClaimPhase {
+				secretClaim := &secretsv1beta1.SecretClaim{}
+				err := k8sClient.Get(ctx, client.ObjectKey{
+					Namespace: tenantNamespaceName,
+					Name:      secretClaimName,
+				}, secretClaim)
+				if err != nil {
+					return ""
+				}
+				return secretClaim.Status.Phase
+			}
+
+			secretExists := func() bool {
+				targetSecret := &corev1.Secret{}
+				err := k8sClient.Get(ctx, client.ObjectKey{
+					Namespace: tenantNamespaceName,
+					Name:      secretClaimName,
+				}, targetSecret)
+				return err == nil
+			}
+
+			// Wait for the claim to be bound and then for the lease to expire.
+
+			Eventually(getPhase, 5*time.Second).Should(Equal(secretsv1beta1.SecretClaimBound))
+			Eventually(getPhase, 10*time.Second).Should(Equal(secretsv1beta1.SecretClaimExpired))
+			Eventually(secretExists, 5*time.Second).Should(BeFalse())
+
+			// Renew the lease and wait for the claim to be bound again.
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(secretClaim), secretClaim)).To(Succeed())
+			secretClaim.Annotations = map[string]string{
+				secretsv1beta1.SecretClaimRenewLeaseAnnotation: "1",
+			}
+			Expect(k8sClient.Update(ctx, secretClaim)).To(Succeed())
+
+			Eventually(getPhase, 5*time.Second).Should(Equal(secretsv1beta1.SecretClaimBound))
+			Eventually(secretExists, 5*time.Second).Should(BeTrue())
+		})
+	})
+})
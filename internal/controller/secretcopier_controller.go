@@ -19,29 +19,220 @@ package controller
 import (
 	"bytes"
 	"context"
-
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/internal/redact"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/mutation"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
 )
 
 // SecretCopierReconciler reconciles a SecretCopier object
 type SecretCopierReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// Limiter applied to creates and updates of target secrets across all
+	// SecretCopier objects. When nil, writes are not throttled.
+	SecretWriteLimiter *rate.Limiter
+
+	// Window over which reconcile requests triggered by changes to secrets
+	// and namespaces are coalesced. When zero, requests are queued
+	// immediately.
+	CoalesceWindow time.Duration
+
+	// Recorder for events about SecretCopier objects.
+	Recorder record.EventRecorder
+
+	// Whether to record events against target secrets when they are created
+	// or updated, so they are visible in the target namespace.
+	TargetNamespaceEvents bool
+
+	// Whether to record events against the source secret of a rule when it
+	// is copied to target namespaces or copying fails, so the owner of the
+	// source namespace can see where the secret is being distributed.
+	SourceSecretEvents bool
+
+	// Whether to record events against the SecretCopier for the outcome of
+	// copying to each target namespace, so that copies can be followed
+	// without raising the log verbosity of the manager.
+	CopyEvents bool
+
+	// Name of a ConfigMap maintained in each target namespace which records
+	// the secrets copied into the namespace. When empty, no ConfigMap is
+	// maintained.
+	TargetNamespaceStatusConfigMap string
+
+	// Reader which bypasses the informer cache, used to read the target
+	// secret again when a write fails because the cached copy was stale. If
+	// nil, failed writes are not retried.
+	APIReader client.Reader
+
+	// Function returning a client which impersonates the given user with
+	// the extra information, used for SecretCopiers which name a service
+	// account. If nil, SecretCopiers which name a service account fail to
+	// reconcile.
+	ImpersonatingClient func(username string, extra map[string][]string) (client.Client, error)
+
+	// Mutator applied to target secrets before they are written, so site
+	// specific conventions can be enforced. If nil, target secrets are
+	// written as computed from the source secret and rule.
+	Mutator mutation.Mutator
+
+	// Controller wide settings which can be changed while running. If nil,
+	// the built in defaults are used.
+	Config *ManagerConfig
+
+	// Whether to delete and recreate target secrets when the type of the
+	// source secret changes, as the type of a secret can't be updated.
+	RecreateOnTypeChange bool
+
+	// Window over which the reconciliations made when the controller starts
+	// are spread, to avoid every SecretCopier being reconciled at once. When
+	// zero, they are made immediately.
+	WarmUpWindow time.Duration
+
+	// If set, target secrets are never written. The writes which would have
+	// been made are instead reported through status, events and metrics.
+	ReportOnly bool
+
+	// Maximum time spent copying the secret of a single rule in a
+	// reconciliation before the remaining target namespaces are left for a
+	// following reconciliation. When zero, rules are always fully processed.
+	RuleProcessingBudget time.Duration
+
+	// Delay before a SecretCopier with a rule whose source secret doesn't
+	// exist is reconciled again to check for it, doubling each time the
+	// source secret is still missing up to SourceWaitMaxRequeue. When zero,
+	// the SecretCopier is only reconciled again at the end of its sync period
+	// or when the source secret is created.
+	SourceWaitRequeue time.Duration
+
+	// Maximum delay between checks for a missing source secret. When zero, a
+	// default of two minutes is used.
+	SourceWaitMaxRequeue time.Duration
+
+	// Interval between checks of whether the remote clusters rules copy
+	// secrets to can be reached. When zero, target clusters are only checked
+	// when a SecretCopier copying to them is reconciled.
+	TargetClusterHealthInterval time.Duration
+
+	// Number of SecretCopier objects which can be reconciled concurrently.
+	// When zero, the default of the manager is used.
+	MaxConcurrentReconciles int
+
+	// Number of target namespaces of a rule the source secret is copied to
+	// concurrently. When zero or one, target namespaces are copied to one at
+	// a time.
+	CopyWorkers int
+
+	// Rate at which each SecretCopier can be reconciled when given its own
+	// rate limited queue budget, with the time spent reconciling it charged
+	// against the same budget. When zero, all SecretCopier objects share the
+	// default queue of the controller.
+	CopierQueueRate rate.Limit
+
+	// Number of reconciliations of a SecretCopier which can be made in a
+	// burst before its queue budget limits them.
+	CopierQueueBurst int
+
+	// Number of newly created namespaces secrets can be copied into
+	// concurrently ahead of the full reconciliation of the SecretCopiers
+	// matching them, using a work queue separate from that of SecretCopiers.
+	// When zero, new namespaces are only handled by the full reconciliation.
+	NewNamespaceWorkers int
+
+	// Compiled target namespace matchers for the rules of each SecretCopier.
+	matchers matcherCache
+
+	// Backoff for target secrets the controller was denied permission to
+	// manage.
+	deniedBackoff targetBackoff
+
+	// Progress of propagating changes to source secrets to target secrets.
+	propagation propagationTracker
+
+	// Schedule for spreading reconciliations when the controller starts.
+	warmUp *warmUp
+
+	// Position reached by rules whose processing was cut short.
+	ruleCursors ruleCursors
+
+	// Backoff for rules waiting for their source secret to be created.
+	sourceWaits targetBackoff
+
+	// Bytes of secret data held in the target secrets of each SecretCopier.
+	copyBudget copyBudget
+
+	// Cooldown windows of rules limiting how often changes are propagated.
+	updateCooldowns updateCooldowns
+
+	// Queue budgets of each SecretCopier, if they are isolated.
+	copierQueues *copierQueues
+
+	// Watches for the kinds of resource rules require in target namespaces.
+	requiredResourceWatches requiredResourceWatches
+
+	// Clients for remote clusters which rules copy secrets to.
+	targetClusters targetClusterClients
+
+	// Kubeconfig secrets of target clusters which have become unreachable or
+	// reachable again, so the SecretCopiers copying to them are reconciled.
+	targetClusterChanges chan event.GenericEvent
 }
 
+// Outcome of copying the source secret to a single target namespace.
+type copyResult int
+
+const (
+	copyFailed copyResult = iota
+	copySkipped
+	copyCreated
+	copyUpdated
+	copyUnchanged
+	copyThrottled
+	copyForbidden
+	copyReportOnly
+	copyNotReady
+	copyWaiting
+	copyIgnored
+)
+
 // +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=secretcopiers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=secretcopiers/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=secretcopiers/finalizers,verbs=update
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=secretcopierdelegations,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts;resourcequotas,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=impersonate
+// +kubebuilder:rbac:groups=authentication.k8s.io,resources=userextras/secrets-manager.advok8s.io/secret-copier,verbs=impersonate
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -69,6 +260,18 @@ func (r *SecretCopierReconciler) Reconcile(ctx context.Context, req ctrl.Request
 
 			log.V(1).Info("SecretCopier has been deleted", "name", req.NamespacedName)
 
+			deleteSecretCopierMetrics(req.Name)
+
+			r.pruneTargetNamespaceStatus(ctx, req.Name, nil, true)
+
+			r.matchers.forget(req.Name)
+			r.propagation.forget(req.Name)
+			r.ruleCursors.forget(req.Name)
+			r.forgetSourceWaits(req.Name)
+			r.copyBudget.forget(req.Name)
+			r.updateCooldowns.forget(req.Name)
+			r.copierQueues.forget(req.Name)
+
 			return ctrl.Result{}, nil
 		}
 
@@ -82,17 +285,46 @@ func (r *SecretCopierReconciler) Reconcile(ctx context.Context, req ctrl.Request
 
 	log.V(1).Info("Fetched SecretCopier", "secretCopier", &secretCopier)
 
+	// If a resync has been requested, discard any state which could cause
+	// targets to be skipped. The value is recorded in the status once the
+	// rules have been processed.
+
+	resyncValue, resync := resyncRequested(&secretCopier)
+
+	if resync {
+		r.startResync(ctx, &secretCopier, resyncValue)
+	}
+
 	// If there are no rules defined, there is nothing to do.
 
 	if len(secretCopier.Spec.Rules) == 0 {
 		log.V(1).Info("No rules to process for SecretCopier", "name", req.NamespacedName)
-		return ctrl.Result{}, nil
+
+		secretCopier.Status.ObservedGeneration = secretCopier.Generation
+		secretCopier.Status.Rules = nil
+
+		meta.SetStatusCondition(&secretCopier.Status.Conditions, readyCondition(nil, secretCopier.Generation))
+
+		if resync {
+			secretCopier.Status.LastHandledResync = resyncValue
+		}
+
+		r.copyBudget.set(secretCopier.Name, 0)
+
+		secretCopier.Status.SyncPeriod = nil
+		secretCopier.Status.NextSyncTime = nil
+
+		normalizeSecretCopierStatus(&secretCopier.Status, secretCopier.Spec.StatusDetail)
+
+		return ctrl.Result{}, r.updateStatus(ctx, &secretCopier)
 	}
 
 	// Query the set of namespaces in the Kubernetes cluster and filter out
-	// those in the terminating state. We still need to deal with errors if we
-	// can't later create a secret in a namespace that is terminating, but skip
-	// what we can for now to avoid noise in the logs.
+	// those which the manager has been configured to never copy to. Whether
+	// namespaces which are terminating or being decommissioned are skipped is
+	// decided by each rule.
+
+	settings := r.Config.Settings()
 
 	namespaces := &corev1.NamespaceList{}
 
@@ -106,86 +338,1059 @@ func (r *SecretCopierReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	activeNamespaces := make([]corev1.Namespace, 0)
 
 	for _, namespace := range namespaces.Items {
-		if namespace.Status.Phase != corev1.NamespaceTerminating {
+		if !settings.NamespaceDenied(namespace.Name) {
 			activeNamespaces = append(activeNamespaces, namespace)
 		}
 	}
 
+	// Drop anything still recorded against target namespaces which have
+	// been deleted, so they don't linger in the status or keep being retried.
+
+	r.forgetDeletedTargetNamespaces(ctx, &secretCopier, namespaces.Items)
+
+	// Process namespaces in order of name, so that the order in which target
+	// namespaces are processed is deterministic.
+
+	slices.SortFunc(activeNamespaces, func(a, b corev1.Namespace) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
 	// Generate a list of just the names of the active namespaces so we can log
 	// them for debugging.
 
 	activeNamespaceNames := make([]string, 0)
 
-	for _, namespace := range activeNamespaces {
-		activeNamespaceNames = append(activeNamespaceNames, namespace.Name)
-	}
+	for _, namespace := range activeNamespaces {
+		activeNamespaceNames = append(activeNamespaceNames, namespace.Name)
+	}
+
+	log.V(1).Info("Active namespaces", "namespaces", activeNamespaceNames)
+
+	// Source secrets are read using the client used for copying secrets, so
+	// that if the SecretCopier names a service account to impersonate, the
+	// status reflects what the service account can access.
+
+	secretsClient, err := r.secretsClient(&secretCopier)
+
+	if err != nil {
+		log.Error(err, "Unable to create client impersonating service account for SecretCopier", "name", req.NamespacedName, "serviceAccount", secretCopier.Spec.ServiceAccount)
+
+		r.Recorder.Eventf(&secretCopier, corev1.EventTypeWarning, "ImpersonationFailed",
+			"Unable to impersonate service account %s/%s: %v", secretCopier.Spec.ServiceAccount.Namespace, secretCopier.Spec.ServiceAccount.Name, err)
+
+		return ctrl.Result{}, err
+	}
+
+	// Expand rules with a source secret name which is a glob pattern into a
+	// rule for each matching source secret. The expanded rules are only used
+	// for this reconciliation and are never saved back to the SecretCopier.
+
+	rules, ruleOrigins, err := expandSourceSecretPatterns(ctx, secretsClient, secretCopier.Spec.Rules)
+
+	if err != nil {
+		log.Error(err, "Unable to list source secrets matching rules of SecretCopier", "name", req.NamespacedName)
+		return ctrl.Result{}, err
+	}
+
+	expandedSpec := secretsv1beta1.SecretCopierSpec{Rules: rules}
+
+	// Load the target namespace scopes a cluster admin has delegated for
+	// source namespaces, as rules copying from a source namespace with a
+	// delegation are restricted to copying within the delegated scope.
+
+	delegations, err := loadDelegationScopes(ctx, r.Client)
+
+	if err != nil {
+		log.Error(err, "Unable to list SecretCopierDelegation objects")
+		return ctrl.Result{}, err
+	}
+
+	// Iterate over the set of rules defined for the SecretCopier object and
+	// determine which target namespaces match the rule. The outcome for each
+	// rule is tracked so it can be reported in the status of the SecretCopier.
+	// Rules are processed so that each comes after the rules it depends on.
+	// Rules with unresolvable dependencies are processed last and never copy
+	// to any target namespace.
+
+	ruleOrder, unresolvedRules := expandedSpec.RuleOrder()
+
+	for ruleIndex := range rules {
+		if _, found := unresolvedRules[ruleIndex]; found {
+			ruleOrder = append(ruleOrder, ruleIndex)
+		}
+	}
+
+	ruleStatuses := make([]secretsv1beta1.SecretCopierRuleStatus, len(rules))
+
+	ruleOutcomes := make([]ruleOutcome, len(rules))
+
+	ruleSchedules := make([]ruleSchedule, len(rules))
+
+	dependencies := newRuleDependencies()
+
+	matchers := r.matchers.get(&secretCopier)
+
+	throttled := false
+
+	deniedTargets := 0
+
+	var failedTargets []secretsv1beta1.SecretCopierFailedTarget
+
+	tooLargeTargets := 0
+	largestTargetSize := 0
+
+	reportOnlyWrites := 0
+
+	ignoredTargets := make([]string, 0)
+
+	fanOutBlocked := make([]string, 0)
+
+	var rolloutRequeue time.Duration
+
+	var sourceWaitRequeue time.Duration
+
+	var cooldownRequeue time.Duration
+
+	var targetClusterRequeue time.Duration
+
+	var targetClusters targetClusterStates
+
+	waitingForSource := make([]int, 0)
+
+	deferred := false
+
+	targetSecretBytes := 0
+
+	// Target secrets the rules want to exist in target namespaces, so stale
+	// entries can be removed from the status ConfigMaps of target namespaces.
+	// This is only complete if no rule was skipped while leaving its target
+	// secrets as they are.
+
+	wantedTargetSecrets := make(map[client.ObjectKey]bool)
+
+	wantedTargetSecretsComplete := true
+
+	for _, ruleIndex := range ruleOrder {
+		rule := rules[ruleIndex]
+
+		// Rules expanded from a source secret name pattern are reported, and
+		// tracked between reconciliations, by the index of the rule in the
+		// spec they came from, rather than their position after expansion.
+
+		specIndex := ruleOrigins[ruleIndex]
+
+		matcher := matchers[specIndex]
+
+		ruleStatus := secretsv1beta1.SecretCopierRuleStatus{
+			Name:            rule.Name,
+			SourceSecret:    rule.SourceSecret,
+			DependencyError: unresolvedRules[ruleIndex],
+		}
+
+		// Skip over rules which have been disabled, leaving any target
+		// secrets they previously copied as they are.
+
+		if !rule.IsEnabled() {
+			log.V(1).Info("Skipping disabled rule for SecretCopier", "name", req.NamespacedName, "rule", rule)
+
+			ruleStatus.Disabled = true
+			ruleStatuses[ruleIndex] = ruleStatus
+
+			wantedTargetSecretsComplete = false
+
+			continue
+		}
+
+		// Rules copying to a remote cluster are processed separately, as the
+		// target namespaces are those of the remote cluster.
+
+		if rule.TargetCluster != nil {
+			// A delegated scope only covers namespaces of this cluster, so
+			// copying from a delegated source namespace to a remote
+			// cluster is refused.
+
+			if delegations.delegated(rule.SourceSecret.Namespace) {
+				log.Info("Not copying delegated source secret to remote cluster", "name", req.NamespacedName, "rule", specIndex, "sourceNamespace", rule.SourceSecret.Namespace)
+
+				ruleStatus.Message = fmt.Sprintf("Copying from source namespace %s is delegated and cannot target a remote cluster", rule.SourceSecret.Namespace)
+				ruleStatuses[ruleIndex] = ruleStatus
+
+				continue
+			}
+
+			ruleFailedTargets, ruleThrottled := r.copyRuleToTargetCluster(ctx, secretsClient, &secretCopier, specIndex, &rule, &ruleStatus, &ruleOutcomes[ruleIndex], &targetClusters)
+
+			// Retry a target cluster which couldn't be reached when its
+			// backoff expires.
+
+			if delay := targetClusters.retryDelay(targetClusterKey(rule.TargetCluster), time.Now()); delay > 0 {
+				ruleSchedules[ruleIndex].sooner(delay, syncReasonTargetClusterRetry)
+
+				if targetClusterRequeue == 0 || delay < targetClusterRequeue {
+					targetClusterRequeue = delay
+				}
+			}
+
+			failedTargets = append(failedTargets, ruleFailedTargets...)
+
+			throttled = throttled || ruleThrottled
+
+			ruleStatuses[ruleIndex] = ruleStatus
+
+			continue
+		}
+
+		// Namespaces created by vcluster, where the rule copies into the
+		// virtual clusters they hold, are processed separately, as the
+		// secret is copied through the API server of the virtual cluster.
+
+		virtualFailedTargets, virtualThrottled := r.copyRuleToVirtualClusters(ctx, secretsClient, &secretCopier, specIndex, &rule, &ruleStatus, activeNamespaces, matcher, delegations, &ruleOutcomes[ruleIndex])
+
+		failedTargets = append(failedTargets, virtualFailedTargets...)
+
+		throttled = throttled || virtualThrottled
+
+		// Time how long it takes to evaluate the selectors of the rule so
+		// that expensive selector patterns can be identified from metrics.
+
+		ruleLabel := strconv.Itoa(specIndex)
+
+		evaluationStart := time.Now()
+
+		selection := r.selectTargetNamespaces(ctx, settings, delegations, &rule, matcher, activeNamespaces)
+
+		targetNamespaces := make([]string, 0)
+
+		absentNamespaces := make([]string, 0)
+
+		notReadyNamespaces := selection.notReady
+
+		skippedNamespaces := selection.skipped
+
+		undelegatedNamespaces := selection.undelegated
+
+		unconsentedNamespaces := selection.unconsented
+
+		canaryNamespaces := make([]string, 0)
+
+		var canaryMatcher *selectors.TargetNamespacesMatcher
+
+		if rule.Rollout != nil {
+			canaryMatcher = rule.Rollout.CanaryNamespaces.Compile()
+		}
+
+		for _, target := range selection.matched {
+			dependencies.matchedTarget(&rule, target.namespace.Name)
+
+			// Remember namespaces which don't hold the resource the rule
+			// requires, so any copy already made there is removed.
+
+			if target.check == targetNamespaceLacksRequiredResource {
+				absentNamespaces = append(absentNamespaces, target.namespace.Name)
+			}
+
+			if !target.ready() {
+				continue
+			}
+
+			targetNamespaces = append(targetNamespaces, target.namespace.Name)
+
+			if canaryMatcher != nil && canaryMatcher.Matches(target.namespace) {
+				canaryNamespaces = append(canaryNamespaces, target.namespace.Name)
+			}
+		}
+
+		selectorEvaluationDuration.WithLabelValues(secretCopier.Name, ruleLabel).Observe(time.Since(evaluationStart).Seconds())
+		selectorEvaluationNamespaces.WithLabelValues(secretCopier.Name, ruleLabel).Set(float64(len(activeNamespaces)))
+
+		// Remove the target secret from target namespaces which no longer
+		// hold the resource the rule requires.
+
+		for _, targetNamespace := range absentNamespaces {
+			if _, err := r.removeTargetSecretWithoutRequiredResource(ctx, secretsClient, &secretCopier, &rule, targetNamespace, targetSecretName(&rule)); err != nil {
+				log.Error(err, "Unable to remove target secret from namespace lacking required resource", "name", req.NamespacedName, "namespace", targetNamespace)
+			}
+		}
+
+		ruleStatus.MatchedNamespaces = int32(len(targetNamespaces) + notReadyNamespaces)
+		ruleStatus.NotReadyNamespaces = int32(notReadyNamespaces)
+		ruleStatus.SkippedNamespaces += int32(skippedNamespaces)
+		ruleStatus.UndelegatedNamespaces += int32(undelegatedNamespaces)
+		ruleStatus.UnconsentedNamespaces += int32(unconsentedNamespaces)
+
+		// If the rule matches more target namespaces than the fan-out guard
+		// allows without the rule acknowledging it, don't process it, as the
+		// selectors may have been written incorrectly.
+
+		if selection.fanOutBlocked {
+			log.Info("Not processing rule matching too many target namespaces", "name", req.NamespacedName, "rule", specIndex, "matched", ruleStatus.MatchedNamespaces, "threshold", settings.FanOutGuardThreshold)
+
+			fanOutBlocked = append(fanOutBlocked, fmt.Sprintf("rule %d matches %d", specIndex, ruleStatus.MatchedNamespaces))
+
+			ruleStatus.FanOutBlocked = true
+			ruleStatuses[ruleIndex] = ruleStatus
+
+			wantedTargetSecretsComplete = false
+
+			continue
+		}
+
+		// Explain in the status if the rule can't copy anything because the
+		// source secret doesn't exist.
+
+		var sourceSecret corev1.Secret
+
+		if err := secretsClient.Get(ctx, client.ObjectKey{Namespace: rule.SourceSecret.Namespace, Name: rule.SourceSecret.Name}, &sourceSecret); err != nil {
+			switch {
+			case apierrors.IsForbidden(err) && secretCopier.Spec.ServiceAccount != nil:
+				ruleStatus.Message = fmt.Sprintf("Service account %s/%s is not permitted to read source secret %s/%s",
+					secretCopier.Spec.ServiceAccount.Namespace, secretCopier.Spec.ServiceAccount.Name, rule.SourceSecret.Namespace, rule.SourceSecret.Name)
+
+			case client.IgnoreNotFound(err) != nil:
+				log.Error(err, "Unable to fetch source secret", "sourceSecret", rule.SourceSecret)
+				return ctrl.Result{}, err
+
+			default:
+				ruleStatus.Message = fmt.Sprintf("Source secret %s/%s not found", rule.SourceSecret.Namespace, rule.SourceSecret.Name)
+
+				// Check again for the source secret sooner than the sync
+				// period, so problems with the order in which resources are
+				// created resolve quickly.
+
+				waitingForSource = append(waitingForSource, ruleIndex)
+
+				delay := r.waitForSourceSecret(&secretCopier, specIndex, &rule, time.Now())
+
+				if delay > 0 && (sourceWaitRequeue == 0 || delay < sourceWaitRequeue) {
+					sourceWaitRequeue = delay
+				}
+
+				ruleSchedules[ruleIndex].sooner(delay, syncReasonWaitingForSource)
+			}
+		} else {
+			r.sourceSecretFound(&secretCopier, specIndex, &rule)
+		}
+
+		// If there are no target namespaces that match the rule, there is
+		// nothing to do, but explain why in the status.
+
+		if len(targetNamespaces) == 0 {
+			log.V(1).Info("No target namespaces to process for SecretCopier", "name", req.NamespacedName, "rule", rule)
+
+			if ruleStatus.Message == "" && ruleStatus.VirtualClusterNamespaces == 0 {
+				ruleStatus.Message = r.explainNoTargetNamespaces(&rule, matcher, activeNamespaces, notReadyNamespaces, skippedNamespaces, undelegatedNamespaces, unconsentedNamespaces)
+			}
+
+			ruleStatuses[ruleIndex] = ruleStatus
+			continue
+		}
+
+		log.V(1).Info("Target namespaces to process for SecretCopier", "name", req.NamespacedName, "rule", rule, "targetNamespaces", targetNamespaces)
+
+		// If the rule has a canary rollout configured, work out which phase
+		// the rollout is in so that changes to the source secret are held
+		// back from target namespaces which are not canaries until the
+		// rollout has been verified.
+
+		rollout, err := r.startRollout(ctx, &secretCopier, ruleIndex, &rule, canaryNamespaces)
+
+		if err != nil {
+			log.Error(err, "Unable to determine rollout of source secret", "sourceSecret", rule.SourceSecret)
+			return ctrl.Result{}, err
+		}
+
+		// If the rule limits how often changes to the source secret are
+		// propagated, hold back a change made too soon after the last one
+		// until the interval has elapsed.
+
+		cooldown := r.ruleCooldown(&secretCopier, specIndex, &rule, &sourceSecret, time.Now())
+
+		if cooldown > 0 && (cooldownRequeue == 0 || cooldown < cooldownRequeue) {
+			cooldownRequeue = cooldown
+		}
+
+		ruleSchedules[ruleIndex].sooner(cooldown, syncReasonCooldown)
+
+		canarySynced := 0
+
+		targetSecretSize := ruleTargetSecretSize(&rule, &sourceSecret)
+
+		copiedNamespaces := 0
+		failedNamespaces := 0
+
+		currentTargetSecretName := r.currentTargetSecretName(ctx, &rule)
+
+		if rule.TargetSecret.HashSuffix != nil {
+			ruleStatus.TargetSecretName = currentTargetSecretName
+		}
+
+		for _, target := range selection.matched {
+			if target.check != targetNamespaceLacksRequiredResource && target.namespace.Name != rule.SourceSecret.Namespace {
+				wantedTargetSecrets[client.ObjectKey{Namespace: target.namespace.Name, Name: currentTargetSecretName}] = true
+			}
+		}
+
+		// Resume from where the rule got to if its processing was previously
+		// cut short. Once the rule has used up its processing budget, the
+		// remaining target namespaces are left for a following reconciliation,
+		// so a rule with many target namespaces can't hold up the other rules
+		// of the SecretCopier.
+
+		cursorKey := ruleCursorKey(secretCopier.Name, specIndex, &rule)
+
+		targetNamespaces = r.ruleCursors.rotate(cursorKey, targetNamespaces)
+
+		copyStart := time.Now()
+
+		ruleDeferred := false
+
+		// Copy the source secret to each of the target namespaces that match
+		// the rule. The copy operation will check itself if the source secret
+		// exists and copy it if the target secret does not exist, or update it
+		// if it does and the source secret has changed. Target namespaces are
+		// processed in batches the size of the number of copy workers, with
+		// the copies for a batch made concurrently, and the outcomes then
+		// recorded in order of target namespace.
+
+		batchSize := max(r.CopyWorkers, 1)
+
+		for start := 0; start < len(targetNamespaces); start += batchSize {
+			if r.RuleProcessingBudget > 0 && start > 0 && time.Since(copyStart) > r.RuleProcessingBudget {
+				log.V(1).Info("Deferring remaining target namespaces of rule as processing budget used", "name", req.NamespacedName, "rule", specIndex, "remaining", len(targetNamespaces)-start)
+
+				ruleStatus.PendingNamespaces += int32(len(targetNamespaces) - start)
+
+				r.ruleCursors.set(cursorKey, targetNamespaces[start-1])
+
+				ruleDeferred = true
+				deferred = true
+
+				break
+			}
+
+			batch := targetNamespaces[start:min(start+batchSize, len(targetNamespaces))]
+
+			copyNamespaces := make([]string, 0, len(batch))
+
+			for _, targetNamespace := range batch {
+				if targetNamespace == rule.SourceSecret.Namespace {
+					continue
+				}
+
+				// Wait for any rules this rule depends on to sync their
+				// target secret to the namespace first.
+
+				if ruleStatus.DependencyError != "" || dependencies.waiting(&rule, targetNamespace) {
+					ruleStatus.WaitingOnDependencies++
+					continue
+				}
+
+				if rollout.holds(targetNamespace) || cooldown > 0 {
+					ruleStatus.PendingNamespaces++
+					continue
+				}
+
+				copyNamespaces = append(copyNamespaces, targetNamespace)
+			}
+
+			for _, namespaceCopy := range r.copySecretToNamespaces(ctx, &secretCopier, &rule, copyNamespaces) {
+				targetNamespace, result, err := namespaceCopy.namespace, namespaceCopy.result, namespaceCopy.err
+
+				ruleOutcomes[ruleIndex].copied(targetNamespace, result, err)
+
+				r.recordCopyEvent(&secretCopier, &rule, targetNamespace, currentTargetSecretName, result, err)
+
+				switch result {
+				case copyCreated, copyUpdated, copyUnchanged:
+					ruleStatus.SyncedNamespaces++
+
+					if result != copyUnchanged {
+						copiedNamespaces++
+
+						secretDataWrittenBytesTotal.WithLabelValues(secretCopier.Name).Add(float64(targetSecretSize))
+					}
+
+					dependencies.syncedTarget(&rule, targetNamespace)
+
+					if rollout != nil && rollout.canary[targetNamespace] {
+						canarySynced++
+					}
+
+					r.updateTargetNamespaceStatus(ctx, &secretCopier, &rule, targetNamespace, currentTargetSecretName, result != copyUnchanged)
+
+					r.runFirstCopyHook(ctx, &secretCopier, &rule, targetNamespace, currentTargetSecretName)
+				case copyThrottled:
+					ruleStatus.PendingNamespaces++
+					throttled = true
+				case copyReportOnly:
+					ruleStatus.PendingNamespaces++
+					reportOnlyWrites++
+				case copyIgnored:
+					ruleStatus.IgnoredNamespaces++
+					ignoredTargets = append(ignoredTargets, targetNamespace+"/"+currentTargetSecretName)
+				case copyForbidden:
+					var denied *permissionDeniedError
+
+					if errors.As(err, &denied) {
+						ruleStatus.DeniedNamespaces = append(ruleStatus.DeniedNamespaces, secretsv1beta1.SecretCopierDeniedTarget{
+							Namespace: denied.Namespace,
+							Verb:      denied.Verb,
+							Resource:  denied.Resource,
+						})
+					}
+
+					deniedTargets++
+					failedNamespaces++
+
+					failedTarget := newFailedTarget(specIndex, targetNamespace, result, err)
+
+					countCopyFailure(&secretCopier, failedTarget.Reason)
+
+					failedTargets = append(failedTargets, failedTarget)
+				case copyFailed:
+					failedNamespaces++
+
+					var tooLarge *secretTooLargeError
+
+					if errors.As(err, &tooLarge) {
+						tooLargeTargets++
+						largestTargetSize = max(largestTargetSize, tooLarge.Size)
+					}
+
+					failedTarget := newFailedTarget(specIndex, targetNamespace, result, err)
+
+					countCopyFailure(&secretCopier, failedTarget.Reason)
+
+					failedTargets = append(failedTargets, failedTarget)
+				}
+			}
+		}
+
+		if !ruleDeferred {
+			r.ruleCursors.reset(cursorKey)
+		}
+
+		targetSecretBytes += int(ruleStatus.SyncedNamespaces) * targetSecretSize
+
+		if ruleStatus.Message == "" {
+			r.recordSourceSecretEvent(&secretCopier, &sourceSecret, specIndex, copiedNamespaces, failedNamespaces, int(ruleStatus.SyncedNamespaces))
+
+			r.recordPropagation(&secretCopier, specIndex, &rule, &sourceSecret, copiedNamespaces,
+				failedNamespaces == 0 && ruleStatus.PendingNamespaces == 0 && ruleStatus.WaitingOnDependencies == 0)
+		}
+
+		if rollout != nil {
+			rollout.finish(&rule, canarySynced)
+
+			ruleStatus.Rollout = rollout.status
+
+			if rollout.requeueAfter > 0 && (rolloutRequeue == 0 || rollout.requeueAfter < rolloutRequeue) {
+				rolloutRequeue = rollout.requeueAfter
+			}
+
+			ruleSchedules[ruleIndex].sooner(rollout.requeueAfter, syncReasonRollout)
+		}
+
+		// The time taken is only reported as a metric, as reporting it in
+		// the status would change the status, and so trigger another
+		// reconciliation, every time.
+
+		ruleProcessingDuration.WithLabelValues(secretCopier.Name, ruleLabel).Observe(time.Since(evaluationStart).Seconds())
+
+		ruleStatuses[ruleIndex] = ruleStatus
+	}
+
+	// Record the outcome of processing the rules in the status of the
+	// SecretCopier.
+
+	now := metav1.Now()
+
+	for ruleIndex := range ruleStatuses {
+		previous := previousRuleStatus(secretCopier.Status.Rules, ruleIndex, &rules[ruleIndex])
+
+		ruleOutcomes[ruleIndex].apply(&ruleStatuses[ruleIndex], previous, secretCopier.Generation, now)
+	}
+
+	secretCopier.Status.ObservedGeneration = secretCopier.Generation
+	secretCopier.Status.Rules = ruleStatuses
+
+	// Only record the bytes held in target secrets when all rules were fully
+	// processed, as otherwise target secrets left for a following
+	// reconciliation would be missing from the count.
+
+	if !deferred {
+		r.copyBudget.set(secretCopier.Name, targetSecretBytes)
+	}
+
+	// Prune target secrets no longer wanted by any rule if the SecretCopier
+	// asks for it. This is only done when all rules were fully processed, so
+	// the state of target secrets is known to be current.
+
+	if !deferred {
+		if _, err := r.pruneTargetSecrets(ctx, secretsClient, &secretCopier, namespaces.Items); err != nil {
+			log.Error(err, "Unable to prune target secrets of SecretCopier", "name", req.NamespacedName)
+		}
+
+		r.pruneTargetNamespaceStatus(ctx, secretCopier.Name, wantedTargetSecrets, wantedTargetSecretsComplete)
+	}
+
+	meta.SetStatusCondition(&secretCopier.Status.Conditions, readyCondition(ruleStatuses, secretCopier.Generation))
+
+	if resync {
+		secretCopier.Status.LastHandledResync = resyncValue
+	}
+
+	secretCopier.Status.FailedTargets, secretCopier.Status.FailedTargetsOverflow = mergeFailedTargets(
+		secretCopier.Status.FailedTargets, failedTargets, now)
+
+	if deniedTargets > 0 {
+		meta.SetStatusCondition(&secretCopier.Status.Conditions, metav1.Condition{
+			Type:               secretsv1beta1.ConditionPermissionDenied,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: secretCopier.Generation,
+			Reason:             "Forbidden",
+			Message:            fmt.Sprintf("Permission denied to manage target secret in %d target namespaces", deniedTargets),
+		})
+	} else {
+		meta.SetStatusCondition(&secretCopier.Status.Conditions, metav1.Condition{
+			Type:               secretsv1beta1.ConditionPermissionDenied,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: secretCopier.Generation,
+			Reason:             "Permitted",
+			Message:            "Permitted to manage target secrets in all target namespaces",
+		})
+	}
+
+	permissionDeniedTargets.WithLabelValues(secretCopier.Name).Set(float64(deniedTargets))
+
+	if tooLargeTargets > 0 {
+		meta.SetStatusCondition(&secretCopier.Status.Conditions, metav1.Condition{
+			Type:               secretsv1beta1.ConditionSecretTooLarge,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: secretCopier.Generation,
+			Reason:             "SizeLimitExceeded",
+			Message: fmt.Sprintf("Target secret exceeds the size limit of %d bytes in %d target namespaces, largest is %d bytes",
+				maxSecretSize, tooLargeTargets, largestTargetSize),
+		})
+	} else {
+		meta.RemoveStatusCondition(&secretCopier.Status.Conditions, secretsv1beta1.ConditionSecretTooLarge)
+	}
+
+	if len(fanOutBlocked) > 0 {
+		meta.SetStatusCondition(&secretCopier.Status.Conditions, metav1.Condition{
+			Type:               secretsv1beta1.ConditionLargeFanOut,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: secretCopier.Generation,
+			Reason:             "FanOutNotAcknowledged",
+			Message: fmt.Sprintf("Rules match more than %d target namespaces without setting allowLargeFanOut: %s",
+				settings.FanOutGuardThreshold, strings.Join(fanOutBlocked, ", ")),
+		})
+	} else {
+		meta.RemoveStatusCondition(&secretCopier.Status.Conditions, secretsv1beta1.ConditionLargeFanOut)
+	}
+
+	if len(ignoredTargets) > 0 {
+		meta.SetStatusCondition(&secretCopier.Status.Conditions, ignoredCondition(ignoredTargets, secretCopier.Generation))
+	} else {
+		meta.RemoveStatusCondition(&secretCopier.Status.Conditions, secretsv1beta1.ConditionIgnored)
+	}
+
+	if len(waitingForSource) > 0 {
+		meta.SetStatusCondition(&secretCopier.Status.Conditions, waitingForSourceCondition(ruleStatuses, waitingForSource, secretCopier.Generation))
+	} else {
+		meta.RemoveStatusCondition(&secretCopier.Status.Conditions, secretsv1beta1.ConditionWaitingForSource)
+	}
+
+	targetClusters.recordMetrics(secretCopier.Name, secretCopier.Status.TargetClusters, now.Time)
+
+	secretCopier.Status.TargetClusters = targetClusters.statuses(secretCopier.Status.TargetClusters, secretCopier.Generation)
+
+	if unreachable := targetClusters.unreachableClusters(); len(unreachable) > 0 {
+		meta.SetStatusCondition(&secretCopier.Status.Conditions, targetClusterUnreachableCondition(unreachable, secretCopier.Generation))
+	} else {
+		meta.RemoveStatusCondition(&secretCopier.Status.Conditions, secretsv1beta1.ConditionTargetClusterUnreachable)
+	}
+
+	if r.ReportOnly {
+		meta.SetStatusCondition(&secretCopier.Status.Conditions, metav1.Condition{
+			Type:               secretsv1beta1.ConditionReportOnly,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: secretCopier.Generation,
+			Reason:             "ReportOnly",
+			Message:            fmt.Sprintf("Manager is running in report-only mode, %d target secrets would be written", reportOnlyWrites),
+		})
+	} else {
+		meta.RemoveStatusCondition(&secretCopier.Status.Conditions, secretsv1beta1.ConditionReportOnly)
+	}
+
+	if !r.ReportOnly && settings.WritesFrozen(now.Time) {
+		condition := writesFrozenCondition(settings, secretCopier.Generation)
+
+		condition.Message += fmt.Sprintf(", %d target secrets would be written", reportOnlyWrites)
+
+		meta.SetStatusCondition(&secretCopier.Status.Conditions, condition)
+	} else {
+		meta.RemoveStatusCondition(&secretCopier.Status.Conditions, secretsv1beta1.ConditionWritesFrozen)
+	}
+
+	// Work out when to requeue the request based on the synchronizaion period
+	// defined for the SecretCopier. This is to ensure that we periodically check that target
+	// secrets are still in sync, even if a change was missed by the watches.
+
+	// If a canary rollout is waiting on its verification delay, a rule is
+	// waiting for its source secret to be created, or a change to a source
+	// secret is being held back by the minimum interval of a rule, requeue
+	// the request for when the delay expires if that is sooner.
+
+	syncPeriod := settings.SyncPeriod(&secretCopier)
+
+	defaultSyncPeriod := syncPeriod
+
+	if rolloutRequeue > 0 && (syncPeriod <= 0 || rolloutRequeue < syncPeriod) {
+		syncPeriod = rolloutRequeue
+	}
+
+	if sourceWaitRequeue > 0 && (syncPeriod <= 0 || sourceWaitRequeue < syncPeriod) {
+		log.V(1).Info("Waiting for source secrets of SecretCopier", "name", req.NamespacedName, "delay", sourceWaitRequeue)
+
+		syncPeriod = sourceWaitRequeue
+	}
+
+	if cooldownRequeue > 0 && (syncPeriod <= 0 || cooldownRequeue < syncPeriod) {
+		log.V(1).Info("Holding back changes to source secrets of SecretCopier", "name", req.NamespacedName, "delay", cooldownRequeue)
+
+		syncPeriod = cooldownRequeue
+	}
+
+	if targetClusterRequeue > 0 && (syncPeriod <= 0 || targetClusterRequeue < syncPeriod) {
+		log.V(1).Info("Retrying unreachable target clusters of SecretCopier", "name", req.NamespacedName, "delay", targetClusterRequeue)
+
+		syncPeriod = targetClusterRequeue
+	}
+
+	// If writes to secrets are frozen, requeue the request for when the
+	// freeze expires so the writes held back are then made.
+
+	if freezeRequeue := settings.WriteFreezeRemaining(now.Time); freezeRequeue > 0 && (syncPeriod <= 0 || freezeRequeue < syncPeriod) {
+		log.V(1).Info("Writes to secrets are frozen", "name", req.NamespacedName, "delay", freezeRequeue)
+
+		syncPeriod = freezeRequeue
+	}
+
+	// Report when each rule and the SecretCopier as a whole are next due to
+	// be synced, so the scheduling can be confirmed from the status.
+
+	for ruleIndex := range ruleStatuses {
+		schedule := ruleSchedule{after: defaultSyncPeriod, reason: syncReasonSyncPeriod}
+
+		schedule.sooner(ruleSchedules[ruleIndex].after, ruleSchedules[ruleIndex].reason)
+
+		var previousInterval *metav1.Duration
+
+		if ruleIndex < len(secretCopier.Status.Rules) {
+			previousInterval = secretCopier.Status.Rules[ruleIndex].SyncInterval
+		}
+
+		ruleStatuses[ruleIndex].SyncInterval = ruleSyncInterval(previousInterval, schedule.after)
+
+		if ruleStatuses[ruleIndex].SyncInterval != nil {
+			ruleStatuses[ruleIndex].SyncReason = schedule.reason
+		}
+	}
+
+	secretCopier.Status.SyncPeriod = nil
+
+	if defaultSyncPeriod > 0 {
+		secretCopier.Status.SyncPeriod = &metav1.Duration{Duration: defaultSyncPeriod}
+	}
+
+	nextSync := syncPeriod
+
+	if throttled {
+		nextSync = r.secretWriteDelay()
+	} else if deferred {
+		nextSync = deferredRuleRequeueDelay
+	}
+
+	secretCopier.Status.NextSyncTime = nextSyncTime(secretCopier.Status.NextSyncTime, now.Time, nextSync)
+
+	normalizeSecretCopierStatus(&secretCopier.Status, secretCopier.Spec.StatusDetail)
+
+	if err := r.updateStatus(ctx, &secretCopier); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// If any writes were deferred because the secret write rate limit was
+	// reached, requeue the request for when the limiter expects to be able to
+	// accept writes again so the rollout continues where it left off.
+
+	if throttled {
+		delay := r.secretWriteDelay()
+
+		log.V(1).Info("Secret writes throttled for SecretCopier", "name", req.NamespacedName, "delay", delay)
+
+		return ctrl.Result{Requeue: true, RequeueAfter: delay}, nil
+	}
+
+	// If processing of any rule was cut short by its processing budget,
+	// requeue the request so the rule continues where it left off.
+
+	if deferred {
+		log.V(1).Info("Processing of rules deferred for SecretCopier", "name", req.NamespacedName, "delay", deferredRuleRequeueDelay)
+
+		return ctrl.Result{RequeueAfter: deferredRuleRequeueDelay}, nil
+	}
+
+	// Otherwise requeue the request for the sync period worked out above.
+
+	if syncPeriod > 0 {
+		return ctrl.Result{RequeueAfter: syncPeriod}, nil
+	}
+
+	// No need to requeue the request.
+
+	return ctrl.Result{}, nil
+}
+
+// Explain why a rule matched no target namespaces which could be copied to.
+func (r *SecretCopierReconciler) explainNoTargetNamespaces(rule *secretsv1beta1.SecretCopierRule, matcher *selectors.TargetNamespacesMatcher, activeNamespaces []corev1.Namespace, notReadyNamespaces int, skippedNamespaces int, undelegatedNamespaces int, unconsentedNamespaces int) string {
+	if notReadyNamespaces > 0 {
+		return fmt.Sprintf("All %d matched namespaces are not ready", notReadyNamespaces)
+	}
+
+	if skippedNamespaces > 0 {
+		if rule.VirtualClusterNamespaces != nil {
+			return fmt.Sprintf("All %d matched namespaces are terminating, being decommissioned or created by vcluster", skippedNamespaces)
+		}
+
+		return fmt.Sprintf("All %d matched namespaces are terminating or being decommissioned", skippedNamespaces)
+	}
+
+	if undelegatedNamespaces > 0 {
+		return fmt.Sprintf("All %d matched namespaces are outside the scope delegated for source namespace %s", undelegatedNamespaces, rule.SourceSecret.Namespace)
+	}
+
+	if unconsentedNamespaces > 0 {
+		return fmt.Sprintf("All %d matched namespaces have not consented to copies by setting annotation %s to \"true\"", unconsentedNamespaces, secretsv1beta1.NamespaceAcceptCopiesAnnotation)
+	}
+
+	candidates := make([]*corev1.Namespace, 0, len(activeNamespaces))
+
+	for i := range activeNamespaces {
+		if activeNamespaces[i].Name != rule.SourceSecret.Namespace {
+			candidates = append(candidates, &activeNamespaces[i])
+		}
+	}
+
+	return "No target namespaces matched, " + matcher.Explain(candidates)
+}
+
+// Update the status of the SecretCopier object if it differs from what is
+// currently stored in the cluster. Skipping unchanged updates avoids triggering
+// further reconciliations of the SecretCopier for no reason.
+func (r *SecretCopierReconciler) updateStatus(ctx context.Context, secretCopier *secretsv1beta1.SecretCopier) error {
+	log := log.FromContext(ctx)
+
+	var current secretsv1beta1.SecretCopier
+
+	if err := r.Get(ctx, client.ObjectKeyFromObject(secretCopier), &current); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	if equality.Semantic.DeepEqual(current.Status, secretCopier.Status) {
+		return nil
+	}
+
+	if err := r.Status().Update(ctx, secretCopier); err != nil {
+		log.Error(err, "Unable to update SecretCopier status", "name", secretCopier.Name)
+		return err
+	}
+
+	return nil
+}
+
+// Determine if a write to a target secret is permitted by the secret write
+// rate limit. A token is consumed from the limiter if it is.
+func (r *SecretCopierReconciler) allowSecretWrite() bool {
+	if r.SecretWriteLimiter == nil {
+		return true
+	}
+
+	return r.SecretWriteLimiter.Allow()
+}
+
+// Calculate how long until the secret write rate limit will next permit a
+// write to a target secret. This is worked out from the tokens available
+// rather than by reserving one, as cancelling a reservation which can be
+// acted on immediately doesn't return its token to the limiter.
+func (r *SecretCopierReconciler) secretWriteDelay() time.Duration {
+	if r.SecretWriteLimiter == nil || r.SecretWriteLimiter.Limit() == rate.Inf {
+		return 0
+	}
+
+	tokens := r.SecretWriteLimiter.Tokens()
+
+	if tokens >= 1 {
+		return 0
+	}
+
+	if r.SecretWriteLimiter.Limit() <= 0 {
+		return rate.InfDuration
+	}
+
+	return time.Duration((1 - tokens) / float64(r.SecretWriteLimiter.Limit()) * float64(time.Second))
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SecretCopierReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Spread the reconciliations made when the controller starts over the
+	// warm-up window, if one is set.
+
+	start := time.Now()
+
+	r.warmUp = newWarmUp(r.WarmUpWindow, start)
 
-	log.V(1).Info("Active namespaces", "namespaces", activeNamespaceNames)
+	// Index SecretCopier objects by the secrets and bundled config maps they
+	// depend on, so changes to these only need to look up the SecretCopier
+	// objects affected.
 
-	// Iterate over the set of rules defined for the SecretCopier object and
-	// determine which target namespaces match the rule.
+	if err := indexSecretCopierSourceSecrets(context.Background(), mgr); err != nil {
+		return err
+	}
 
-	for _, rule := range secretCopier.Spec.Rules {
-		targetNamespaces := make([]string, 0)
+	if err := indexSecretCopierBundledConfigMaps(context.Background(), mgr); err != nil {
+		return err
+	}
 
-		for _, namespace := range activeNamespaces {
-			if namespace.Name != rule.SourceSecret.Namespace && rule.TargetNamespaces.Matches(&namespace) {
-				log.V(1).Info("Matched target Namespace against SecretCopier", "name", req.NamespacedName, "rule", rule, "namespace", namespace.Name)
+	controllerBuilder := ctrl.NewControllerManagedBy(mgr)
 
-				targetNamespaces = append(targetNamespaces, namespace.Name)
-			}
-		}
+	// When the controller wide settings change, all SecretCopier objects need
+	// to be reconciled so the new settings are applied.
 
-		// If there are no target namespaces that match the rule, there is
-		// nothing to do.
+	if r.Config != nil {
+		controllerBuilder = controllerBuilder.WatchesRawSource(
+			source.Channel(r.Config.Changes(), enqueueRequestsFromMapFuncWithWarmUp(r.findAllSecretCopiers, r.warmUp)),
+		)
+	}
 
-		if len(targetNamespaces) == 0 {
-			log.V(1).Info("No target namespaces to process for SecretCopier", "name", req.NamespacedName, "rule", rule)
-			continue
-		}
+	// When a target cluster becomes unreachable or reachable again, the
+	// SecretCopiers copying to it need to be reconciled, which are found in
+	// the same way as for a change to its kubeconfig secret.
 
-		log.V(1).Info("Target namespaces to process for SecretCopier", "name", req.NamespacedName, "rule", rule, "targetNamespaces", targetNamespaces)
+	if r.TargetClusterHealthInterval > 0 {
+		r.targetClusterChanges = make(chan event.GenericEvent)
 
-		// Copy the source secret to each of the target namespaces that match
-		// the rule. The copy operation will check itself if the source secret
-		// exists and copy it if the target secret does not exist, or update it
-		// if it does and the source secret has changed.
+		controllerBuilder = controllerBuilder.WatchesRawSource(
+			source.Channel(r.targetClusterChanges, enqueueRequestsFromMapFuncWithWarmUp(r.findSecretCopiersMatchingSourceSecret, r.warmUp)),
+		)
 
-		for _, targetNamespace := range targetNamespaces {
-			if targetNamespace != rule.SourceSecret.Namespace {
-				r.copySecretToNamespace(ctx, &secretCopier, &rule, targetNamespace)
-			}
+		if err := mgr.Add(manager.RunnableFunc(r.checkTargetClusterHealth)); err != nil {
+			return err
 		}
 	}
 
-	// Requeue the request based on the synchronizaion period defined for the
-	// SecretCopier. This is to ensure that we periodically check for case where
-	// the target secret has been deleted and we need to recreate it. We do this
-	// on an interval rather than detecting the deletion of the target secret
-	// and recreating it immediately to avoid thrashing the system.
-
-	if secretCopier.Spec.SyncPeriod.Duration > 0 {
-		return ctrl.Result{RequeueAfter: secretCopier.Spec.SyncPeriod.Duration}, nil
+	options := controller.Options{
+		MaxConcurrentReconciles: r.MaxConcurrentReconciles,
 	}
 
-	// No need to requeue the request.
+	// When SecretCopier objects are given their own queue budgets, the
+	// queue of the controller is wrapped so requests are admitted against
+	// the budget of the SecretCopier they are for.
 
-	return ctrl.Result{}, nil
-}
+	if r.CopierQueueRate > 0 {
+		r.copierQueues = newCopierQueues(r.CopierQueueRate, r.CopierQueueBurst)
 
-// SetupWithManager sets up the controller with the Manager.
-func (r *SecretCopierReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&secretsv1beta1.SecretCopier{}).
+		options.NewQueue = func(controllerName string, rateLimiter workqueue.TypedRateLimiter[reconcile.Request]) workqueue.TypedRateLimitingInterface[reconcile.Request] {
+			return r.copierQueues.wrap(workqueue.NewTypedRateLimitingQueueWithConfig(rateLimiter, workqueue.TypedRateLimitingQueueConfig[reconcile.Request]{
+				Name: controllerName,
+			}), rateLimiter)
+		}
+	}
+
+	secretCopierController, err := controllerBuilder.
+		Named("secretcopier").
+		WithOptions(options).
+		Watches(&secretsv1beta1.SecretCopier{}, enqueueSecretCopierWithWarmUp(r.warmUp)).
+		Watches(
+			&corev1.Secret{},
+			enqueueCoalescedRequestsFromMapFunc(r.findSecretCopiersMatchingSourceSecret, r.CoalesceWindow, r.warmUp),
+			builder.WithPredicates(secretContentChanged()),
+		).
 		Watches(
 			&corev1.Secret{},
-			handler.EnqueueRequestsFromMapFunc(r.findSecretCopiersMatchingSourceSecret),
+			enqueueCoalescedRequestsFromMapFunc(r.findSecretCopierManagingTargetSecret, r.CoalesceWindow, r.warmUp),
+			builder.WithPredicates(objectDeleted()),
+		).
+		Watches(
+			&corev1.ConfigMap{},
+			enqueueCoalescedRequestsFromMapFunc(r.findSecretCopiersBundlingConfigMap, r.CoalesceWindow, r.warmUp),
+			builder.OnlyMetadata,
 		).
 		Watches(
 			&corev1.Namespace{},
-			handler.EnqueueRequestsFromMapFunc(r.findSecretCopiersMatchingTargetNamespace),
+			enqueueCoalescedRequestsFromMapFunc(r.findSecretCopiersMatchingTargetNamespace, r.CoalesceWindow, r.warmUp),
+			builder.WithPredicates(r.namespaceSelectorFieldsChanged()),
+		).
+		Watches(
+			&corev1.ServiceAccount{},
+			enqueueCoalescedRequestsFromMapFunc(r.findSecretCopiersAwaitingReadiness("ServiceAccount"), r.CoalesceWindow, r.warmUp),
+			builder.OnlyMetadata,
+			builder.WithPredicates(objectExistenceChanged()),
+		).
+		Watches(
+			&corev1.ResourceQuota{},
+			enqueueCoalescedRequestsFromMapFunc(r.findSecretCopiersAwaitingReadiness("ResourceQuota"), r.CoalesceWindow, r.warmUp),
+			builder.OnlyMetadata,
+			builder.WithPredicates(objectExistenceChanged()),
+		).
+		Watches(
+			&secretsv1beta1.SecretCopierDelegation{},
+			enqueueCoalescedRequestsFromMapFunc(r.findSecretCopiersForDelegation, r.CoalesceWindow, r.warmUp),
 		).
-		Complete(r)
+		Build(r)
+
+	if err != nil {
+		return err
+	}
+
+	// Watches for the kinds of resource rules require to be present in
+	// target namespaces are added to the controller as they are first seen.
+
+	r.requiredResourceWatches.mutex.Lock()
+	r.requiredResourceWatches.controller = secretCopierController
+	r.requiredResourceWatches.cache = mgr.GetCache()
+	r.requiredResourceWatches.mutex.Unlock()
+
+	// Copy secrets into newly created namespaces without waiting behind the
+	// reconciliation of other SecretCopiers, if enabled.
+
+	if r.NewNamespaceWorkers > 0 {
+		return r.setupNewNamespaceFastPath(mgr, start)
+	}
+
+	return nil
+}
+
+// Handler function returning requests for all SecretCopier objects. This is
+// used to trigger a reconciliation of every SecretCopier when the controller
+// wide settings change.
+func (r *SecretCopierReconciler) findAllSecretCopiers(ctx context.Context, object client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	var secretCopiers secretsv1beta1.SecretCopierList
+
+	if err := r.List(ctx, &secretCopiers); err != nil {
+		log.Error(err, "Unable to list SecretCopier objects")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(secretCopiers.Items))
+
+	for _, secretCopier := range secretCopiers.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&secretCopier)})
+	}
+
+	return requests
 }
 
 // Handler function to find SecretCopier objects that match a source secret.
@@ -196,11 +1401,10 @@ func (r *SecretCopierReconciler) SetupWithManager(mgr ctrl.Manager) error {
 func (r *SecretCopierReconciler) findSecretCopiersMatchingSourceSecret(ctx context.Context, secret client.Object) []reconcile.Request {
 	log := log.FromContext(ctx)
 
-	// Fetch the list of SecretCopier objects.
-
-	var secretCopiers secretsv1beta1.SecretCopierList
+	// Fetch the list of SecretCopier objects which may depend on the secret,
+	// using the field index on source secrets.
 
-	err := r.List(ctx, &secretCopiers, &client.ListOptions{})
+	secretCopiers, err := r.listSecretCopiersForSourceSecret(ctx, client.ObjectKeyFromObject(secret))
 
 	if err != nil {
 		log.Error(err, "Unable to list SecretCopier objects")
@@ -212,9 +1416,9 @@ func (r *SecretCopierReconciler) findSecretCopiersMatchingSourceSecret(ctx conte
 
 	var requests []reconcile.Request
 
-	for _, secretCopier := range secretCopiers.Items {
+	for _, secretCopier := range secretCopiers {
 		for _, rule := range secretCopier.Spec.Rules {
-			if rule.SourceSecret.Name == secret.GetName() && rule.SourceSecret.Namespace == secret.GetNamespace() {
+			if sourceSecretMatches(&rule, client.ObjectKeyFromObject(secret)) || kubeconfigSecretMatches(&rule, client.ObjectKeyFromObject(secret)) {
 				log.V(1).Info("Queue reconcile for source Secret against SecretCopier", "name", secretCopier.Name, "rule", rule, "secret", secret.GetName(), "namespace", secret.GetNamespace())
 
 				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&secretCopier)})
@@ -266,8 +1470,10 @@ func (r *SecretCopierReconciler) findSecretCopiersMatchingTargetNamespace(ctx co
 	var requests []reconcile.Request
 
 	for _, secretCopier := range secretCopiers.Items {
-		for _, rule := range secretCopier.Spec.Rules {
-			if rule.SourceSecret.Namespace != namespace.Name && rule.TargetNamespaces.Matches(namespace) {
+		matchers := r.matchers.get(&secretCopier)
+
+		for ruleIndex, rule := range secretCopier.Spec.Rules {
+			if rule.TargetCluster == nil && rule.SourceSecret.Namespace != namespace.Name && matchers[ruleIndex].Matches(namespace) {
 				log.V(1).Info("Queue reconcile for target Namespace against SecretCopier", "name", secretCopier.Name, "rule", rule, "namespace", namespace.GetName())
 
 				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&secretCopier)})
@@ -283,12 +1489,63 @@ func (r *SecretCopierReconciler) findSecretCopiersMatchingTargetNamespace(ctx co
 	return requests
 }
 
-// Copy the source secret to the target namespace. The copy operation will check
-// itself if the source secret exists and copy it if the target secret does not
-// exist, or update it if it does and the source secret has changed. Also check
-// again that we are not trying to copy the secret to the same namespace it is
-// in.
-func (r *SecretCopierReconciler) copySecretToNamespace(ctx context.Context, secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, targetNamespace string) {
+// Name of the target secret for a rule. This defaults to the name of the
+// source secret if no name is given for the target secret.
+func targetSecretName(rule *secretsv1beta1.SecretCopierRule) string {
+	if rule.TargetSecret.Name != "" {
+		return rule.TargetSecret.Name
+	}
+
+	return rule.SourceSecret.Name
+}
+
+// Copy the source secret to the target namespace, together with the config map
+// bundled with it if the rule has one.
+func (r *SecretCopierReconciler) copySecretToNamespace(ctx context.Context, secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, targetNamespace string) (copyResult, error) {
+	if rule.BundledConfigMap != nil {
+		return r.copyBundleToNamespace(ctx, secretCopier, rule, targetNamespace)
+	}
+
+	return r.copyTargetSecretToNamespace(ctx, secretCopier, rule, targetNamespace)
+}
+
+// Copy the source secret alone to the target namespace. The target secret is
+// read from the informer cache, so if the write fails because the cached copy
+// was stale, the copy is retried once with the target secret read directly
+// from the API server. When the SecretCopier names a service account, the
+// target secret is always read directly from the API server as the service
+// account.
+func (r *SecretCopierReconciler) copyTargetSecretToNamespace(ctx context.Context, secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, targetNamespace string) (copyResult, error) {
+	if secretCopier.Spec.ServiceAccount != nil {
+		secretsClient, err := r.secretsClient(secretCopier)
+
+		if err != nil {
+			return copyFailed, err
+		}
+
+		return r.copySecretToNamespaceReading(ctx, secretsClient, secretCopier, rule, targetNamespace)
+	}
+
+	result, err := r.copySecretToNamespaceReading(ctx, r.Client, secretCopier, rule, targetNamespace)
+
+	if r.APIReader == nil || !staleCacheError(err) {
+		return result, err
+	}
+
+	log.FromContext(ctx).V(1).Info("Retrying copy of secret as cached target secret was stale", "targetNamespace", targetNamespace, "error", err.Error())
+
+	staleCacheRetriesTotal.WithLabelValues(secretCopier.Name).Inc()
+
+	return r.copySecretToNamespaceReading(ctx, r.APIReader, secretCopier, rule, targetNamespace)
+}
+
+// Copy the source secret to the target namespace, reading the target secret
+// using the given reader. The copy operation will check itself if the source
+// secret exists and copy it if the target secret does not exist, or update it
+// if it does and the source secret has changed. Also check again that we are
+// not trying to copy the secret to the same namespace it is in. Any values of
+// the source secret are masked in the returned error.
+func (r *SecretCopierReconciler) copySecretToNamespaceReading(ctx context.Context, reader client.Reader, secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, targetNamespace string) (result copyResult, err error) {
 	log := log.FromContext(ctx)
 
 	// Check that we are not trying to copy the secret to the same namespace it
@@ -298,49 +1555,125 @@ func (r *SecretCopierReconciler) copySecretToNamespace(ctx context.Context, secr
 
 	if sourceSecret.Namespace == targetNamespace {
 		log.V(1).Info("Skipping copy of secret to same namespace", "sourceSecret", sourceSecret, "targetNamespace", targetNamespace)
-		return
+		return copySkipped, nil
+	}
+
+	// If the reclaim policy of the rule is overridden for some target
+	// namespaces, resolve the policy which applies to this target namespace
+	// so the rest of the copy can use the rule as is.
+
+	if len(rule.ReclaimPolicyOverrides) != 0 {
+		resolved, err := r.resolveReclaimPolicy(ctx, rule, targetNamespace)
+
+		if err != nil {
+			log.Error(err, "Unable to resolve reclaim policy for target namespace", "targetNamespace", targetNamespace)
+			return copyFailed, err
+		}
+
+		rule = resolved
 	}
 
 	// Fetch the source secret.
 
-	targetSecretName := rule.TargetSecret.Name
+	targetSecretName := targetSecretName(rule)
+
+	// If the controller was recently denied permission to manage the target
+	// secret, don't try again until the backoff for the target has expired.
+
+	backoffKey := targetBackoffKey(secretCopier, targetNamespace, targetSecretName)
+
+	if ready, err := r.deniedBackoff.ready(backoffKey, time.Now()); !ready {
+		log.V(1).Info("Skipping copy of secret as permission was denied", "targetSecret", targetSecretName, "targetNamespace", targetNamespace)
+		return copyForbidden, err
+	}
+
+	secretsClient, err := r.secretsClient(secretCopier)
 
-	if targetSecretName == "" {
-		targetSecretName = sourceSecret.Name
+	if err != nil {
+		return copyFailed, err
 	}
 
 	var secret corev1.Secret
 
-	err := r.Get(ctx, client.ObjectKey{Namespace: sourceSecret.Namespace, Name: sourceSecret.Name}, &secret)
+	err = secretsClient.Get(ctx, client.ObjectKey{Namespace: sourceSecret.Namespace, Name: sourceSecret.Name}, &secret)
 
 	if err != nil {
 		if client.IgnoreNotFound(err) == nil {
 			// Source secret does not exist, so there is nothing to do.
 
 			log.V(1).Info("Source secret does not exist", "sourceSecret", sourceSecret)
-			return
+			return copySkipped, nil
 		}
 
 		// Error reading the source secret. Log the error and return.
 
 		log.Error(err, "Unable to fetch source secret", "sourceSecret", sourceSecret)
-		return
+		return copyFailed, err
 	}
 
 	log.V(1).Info("Fetched source secret", "sourceSecret", sourceSecret)
 
+	// Errors from here on can quote the target secret being written, so mask
+	// any values of the source secret before the error is recorded in events
+	// or the status of the SecretCopier.
+
+	sourceData := secret.Data
+
+	defer func() {
+		err = redact.Error(err, sourceData)
+	}()
+
+	// Secrets of some types must never be copied, whatever the rule says.
+
+	if r.Config.Settings().SecretTypeDenied(secret.Type) {
+		log.Info("Skipping copy of secret as its type is never copied", "sourceSecret", sourceSecret, "type", secret.Type)
+
+		r.Recorder.Eventf(secretCopier, corev1.EventTypeWarning, "SecretTypeDenied",
+			"Secret %s/%s of type %s is never copied", sourceSecret.Namespace, sourceSecret.Name, secret.Type)
+
+		return copySkipped, nil
+	}
+
+	// Only the data keys selected by the rule are copied, so drop any others
+	// and render any templates of the rule before the source secret is
+	// compared with the target secret.
+
+	content, err := targetSecretContent(rule, &secret)
+
+	if err != nil {
+		log.Error(err, "Unable to render target secret from templates", "sourceSecret", sourceSecret)
+		return copyFailed, err
+	}
+
+	secret = *content
+
+	// If the target secret is named with a content hash suffix, each change
+	// to the source secret results in a new immutable target secret rather
+	// than an update of the existing one.
+
+	if rule.TargetSecret.HashSuffix != nil {
+		return r.copyHashedSecretToNamespace(ctx, reader, secretCopier, rule, &secret, targetNamespace, backoffKey)
+	}
+
 	// Fetch the target secret.
 
 	var targetSecret corev1.Secret
 
-	err = r.Get(ctx, client.ObjectKey{Namespace: targetNamespace, Name: targetSecretName}, &targetSecret)
+	err = reader.Get(ctx, client.ObjectKey{Namespace: targetNamespace, Name: targetSecretName}, &targetSecret)
 
 	if err != nil {
 		if client.IgnoreNotFound(err) != nil {
 			// Error reading the target secret. Log the error and return.
 
 			log.Error(err, "Unable to fetch target secret", "targetSecret", targetSecretName, "targetNamespace", targetNamespace)
-			return
+
+			if apierrors.IsForbidden(err) {
+				err = newPermissionDeniedError("get", targetNamespace, err)
+				r.recordPermissionDenied(secretCopier, targetSecretName, err)
+				return copyForbidden, err
+			}
+
+			return copyFailed, err
 		}
 	}
 
@@ -361,56 +1694,64 @@ func (r *SecretCopierReconciler) copySecretToNamespace(ctx context.Context, secr
 
 		log.V(1).Info("Creating target secret", "targetSecret", targetSecret, "targetNamespace", targetNamespace)
 
-		targetSecretLabels := make(map[string]string)
+		targetSecret = r.newTargetSecret(secretCopier, rule, &secret, targetSecretName, targetNamespace)
 
-		for key, value := range secret.Labels {
-			targetSecretLabels[key] = value
+		if err := r.mutateTargetSecret(ctx, secretCopier, rule, &secret, &targetSecret); err != nil {
+			log.Error(err, "Unable to mutate target secret", "targetSecret", targetSecretName, "targetNamespace", targetNamespace)
+			return copyFailed, err
 		}
 
-		for key, value := range rule.TargetSecret.Labels {
-			targetSecretLabels[key] = value
+		if err := r.checkTargetSecretSize(secretCopier, &targetSecret); err != nil {
+			log.Info("Unable to create target secret as it is too large", "targetSecret", targetSecretName, "targetNamespace", targetNamespace, "size", secretDataSize(&targetSecret))
+			return copyFailed, err
 		}
 
-		ownerReferences := []metav1.OwnerReference{}
-
-		if rule.ReclaimPolicy == secretsv1beta1.ReclaimDelete {
-			ownerReferences = append(ownerReferences, metav1.OwnerReference{
-				APIVersion:         secretCopier.APIVersion,
-				Kind:               secretCopier.Kind,
-				Name:               secretCopier.Name,
-				UID:                secretCopier.UID,
-				Controller:         ptr.To(true),
-				BlockOwnerDeletion: ptr.To(true),
-			})
+		if r.writesSuspended() {
+			r.recordReportOnlyWrite(ctx, secretCopier, "create", &targetSecret, redact.DiffSecrets(nil, &targetSecret))
+			return copyReportOnly, nil
 		}
 
-		targetSecret = corev1.Secret{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      targetSecretName,
-				Namespace: targetNamespace,
-				Labels:    targetSecretLabels,
-				Annotations: map[string]string{
-					"secrets-manager.advok8s.io/secret-copier": secretCopier.Name,
-					"secrets-manager.advok8s.io/secret-name":   sourceSecret.Namespace + "/" + sourceSecret.Name,
-				},
-				OwnerReferences: ownerReferences,
-			},
-			Type: secret.Type,
-			Data: secret.Data,
+		if !r.allowSecretWrite() {
+			log.V(1).Info("Deferring create of target secret as write rate limit reached", "targetSecret", targetSecretName, "targetNamespace", targetNamespace)
+			return copyThrottled, nil
 		}
 
-		targetSecret.Namespace = targetNamespace
-
-		err = r.Create(ctx, &targetSecret)
+		err = secretsClient.Create(ctx, &targetSecret, targetSecretWriteOwner(secretCopier, rule))
 
 		if err != nil {
 			log.Error(err, "Unable to create target secret", "targetSecret", targetSecretName, "targetNamespace", targetNamespace)
-			return
+
+			if apierrors.IsForbidden(err) {
+				err = newPermissionDeniedError("create", targetNamespace, err)
+				r.recordPermissionDenied(secretCopier, targetSecretName, err)
+				return copyForbidden, err
+			}
+
+			return copyFailed, err
 		}
 
-		log.V(1).Info("Created target secret", "targetSecret", targetSecretName, "targetNamespace", targetNamespace)
+		log.V(1).Info("Created target secret", "targetSecret", targetSecretName, "targetNamespace", targetNamespace, "changes", redact.DiffSecrets(nil, &targetSecret).String())
 
-		return
+		r.deniedBackoff.succeeded(backoffKey)
+
+		r.recordTargetNamespaceEvent(secretCopier, rule, &targetSecret, "Created", "created", redact.DiffSecrets(nil, &targetSecret))
+
+		return copyCreated, nil
+	}
+
+	// A target secret labelled to be ignored is left as is, so the copy in a
+	// single namespace can be taken over without fighting the controller.
+
+	if r.targetSecretIgnoredBySecretCopier(secretCopier, rule, &targetSecret) {
+		log.V(1).Info("Skipping update of target secret as labelled to be ignored", "targetSecret", targetSecretName, "targetNamespace", targetNamespace)
+		return copyIgnored, nil
+	}
+
+	// If the target secret is managed by another SecretCopier which the rule
+	// takes over from, adopt it instead of updating it.
+
+	if r.targetSecretAdoptable(secretCopier, rule, &targetSecret) {
+		return r.adoptTargetSecret(ctx, secretCopier, rule, &secret, &targetSecret, backoffKey)
 	}
 
 	// Check that the target secret is managed by the SecretCopier object and
@@ -419,40 +1760,211 @@ func (r *SecretCopierReconciler) copySecretToNamespace(ctx context.Context, secr
 
 	if !r.targetSecretManagedBySecretCopier(secretCopier, rule, &targetSecret) {
 		log.V(1).Info("Skipping update of target secret as not managed by SecretCopier", "targetSecret", targetSecretName, "targetNamespace", targetNamespace)
-		return
+		return copySkipped, nil
 	}
 
 	// If the target secret exists, check if it is different to the source
 	// secret and if it is, update it. Labels need to be a copy of those from
 	// the source secret, overlaid with any additional labels specified in the
-	// rule for the target secret.
+	// rule for the target secret. When a mutator is configured, the target
+	// secret is expected to differ from the source secret, so it is instead
+	// compared once the mutator has been applied.
+
+	if r.Mutator == nil && !r.sourceSecretHasBeenUpdated(rule, &secret, &targetSecret) {
+		return copyUnchanged, nil
+	}
+
+	// The content of an immutable target secret can't be changed, so if it
+	// no longer matches it must be replaced instead.
+
+	if secretImmutable(&targetSecret) {
+		if result, replaced, err := r.replaceImmutableTargetSecret(ctx, secretCopier, rule, &secret, &targetSecret, backoffKey); replaced {
+			return result, err
+		}
+	}
+
+	// The type of a secret can't be changed, so if the type of the source
+	// secret has changed the target secret must be replaced instead.
+
+	if targetSecret.Type != secret.Type {
+		return r.recreateTargetSecret(ctx, secretCopier, rule, &secret, &targetSecret, backoffKey)
+	}
 
-	if r.sourceSecretHasBeenUpdated(rule, &secret, &targetSecret) {
-		log.V(1).Info("Updating target secret", "targetSecret", targetSecretName, "targetNamespace", targetNamespace)
+	log.V(1).Info("Updating target secret", "targetSecret", targetSecretName, "targetNamespace", targetNamespace)
 
-		targetSecretLabels := make(map[string]string)
+	currentSecret := targetSecret.DeepCopy()
 
-		for key, value := range secret.Labels {
-			targetSecretLabels[key] = value
+	targetSecret.ObjectMeta.Labels = r.targetSecretLabels(rule, &secret)
+
+	applyTargetSecretAnnotations(rule, &targetSecret)
+
+	setTargetSecretMarkers(&targetSecret, secretCopier.Name, rule.SourceSecret)
+
+	targetSecret.Data = secret.Data
+	targetSecret.Type = secret.Type
+
+	if rule.TargetSecret.Immutable {
+		targetSecret.Immutable = ptr.To(true)
+	}
+
+	if r.Mutator != nil {
+		if err := r.mutateTargetSecret(ctx, secretCopier, rule, &secret, &targetSecret); err != nil {
+			log.Error(err, "Unable to mutate target secret", "targetSecret", targetSecretName, "targetNamespace", targetNamespace)
+			return copyFailed, err
 		}
 
-		for key, value := range rule.TargetSecret.Labels {
-			targetSecretLabels[key] = value
+		if targetSecretContentEqual(currentSecret, &targetSecret) {
+			return copyUnchanged, nil
 		}
+	}
+
+	if err := r.checkTargetSecretSize(secretCopier, &targetSecret); err != nil {
+		log.Info("Unable to update target secret as it is too large", "targetSecret", targetSecretName, "targetNamespace", targetNamespace, "size", secretDataSize(&targetSecret))
+		return copyFailed, err
+	}
+
+	if r.writesSuspended() {
+		r.recordReportOnlyWrite(ctx, secretCopier, "update", &targetSecret, redact.DiffSecrets(currentSecret, &targetSecret))
+		return copyReportOnly, nil
+	}
 
-		targetSecret.ObjectMeta.Labels = targetSecretLabels
+	// Only make the real write if the API server reports that the update
+	// would change the stored target secret.
 
-		targetSecret.Data = secret.Data
-		targetSecret.Type = secret.Type
+	if !r.updateChangesSecret(ctx, secretsClient, currentSecret, &targetSecret) {
+		log.V(1).Info("Skipping update of target secret as dry run reported no change", "targetSecret", targetSecretName, "targetNamespace", targetNamespace)
 
-		err = r.Update(ctx, &targetSecret)
+		dryRunSkippedUpdatesTotal.WithLabelValues(secretCopier.Name).Inc()
 
-		if err != nil {
-			log.Error(err, "Unable to update target secret", "targetSecret", targetSecretName, "targetNamespace", targetNamespace)
-			return
+		return copyUnchanged, nil
+	}
+
+	if !r.allowSecretWrite() {
+		log.V(1).Info("Deferring update of target secret as write rate limit reached", "targetSecret", targetSecretName, "targetNamespace", targetNamespace)
+		return copyThrottled, nil
+	}
+
+	err = secretsClient.Update(ctx, &targetSecret, targetSecretWriteOwner(secretCopier, rule))
+
+	if err != nil {
+		log.Error(err, "Unable to update target secret", "targetSecret", targetSecretName, "targetNamespace", targetNamespace)
+
+		if apierrors.IsForbidden(err) {
+			err = newPermissionDeniedError("update", targetNamespace, err)
+			r.recordPermissionDenied(secretCopier, targetSecretName, err)
+			return copyForbidden, err
 		}
 
-		log.V(1).Info("Updated target secret", "targetSecret", targetSecretName, "targetNamespace", targetNamespace)
+		return copyFailed, err
+	}
+
+	log.V(1).Info("Updated target secret", "targetSecret", targetSecretName, "targetNamespace", targetNamespace, "changes", redact.DiffSecrets(currentSecret, &targetSecret).String())
+
+	r.deniedBackoff.succeeded(backoffKey)
+
+	r.recordTargetNamespaceEvent(secretCopier, rule, &targetSecret, "Updated", "updated", redact.DiffSecrets(currentSecret, &targetSecret))
+
+	return copyUpdated, nil
+}
+
+// Construct a new target secret. The metadata for the target secret must use
+// the calculated target secret name and namespace. Annotations record the
+// SecretCopier and source secret it was created from, and the SecretCopier is
+// set as the controlling owner if the reclaim policy is Delete.
+func (r *SecretCopierReconciler) newTargetSecret(secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, sourceSecret *corev1.Secret, name string, namespace string) corev1.Secret {
+	ownerReferences := []metav1.OwnerReference{}
+
+	if rule.ReclaimPolicy == secretsv1beta1.ReclaimDelete {
+		ownerReferences = append(ownerReferences, secretCopierOwnerReference(secretCopier))
+	}
+
+	targetSecret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       namespace,
+			Labels:          r.targetSecretLabels(rule, sourceSecret),
+			OwnerReferences: ownerReferences,
+		},
+		Type: sourceSecret.Type,
+		Data: sourceSecret.Data,
+	}
+
+	if rule.TargetSecret.Immutable {
+		targetSecret.Immutable = ptr.To(true)
+	}
+
+	applyTargetSecretAnnotations(rule, &targetSecret)
+
+	setTargetSecretMarkers(&targetSecret, secretCopier.Name, rule.SourceSecret)
+
+	return targetSecret
+}
+
+// Record an event against the target secret, if enabled, so that owners of the
+// target namespace can see that a secret was copied into their namespace
+// without needing access to the cluster scoped SecretCopier. The keys and
+// labels which differ are described by name only, never by value.
+func (r *SecretCopierReconciler) recordTargetNamespaceEvent(secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, targetSecret *corev1.Secret, reason string, action string, changes redact.ContentDiff) {
+	if !r.TargetNamespaceEvents {
+		return
+	}
+
+	r.Recorder.Eventf(targetSecret, corev1.EventTypeNormal, reason,
+		"Secret %s %s by SecretCopier %s from %s/%s%s", targetSecret.Name, action, secretCopier.Name,
+		rule.SourceSecret.Namespace, rule.SourceSecret.Name, describeContentDiff(changes))
+}
+
+// Record an event against the SecretCopier, if enabled, for the outcome of
+// copying the source secret of a rule to a target namespace. Nothing is
+// recorded when the target secret was already up to date, or when the copy
+// was held back or denied permission, as these are reported elsewhere.
+func (r *SecretCopierReconciler) recordCopyEvent(secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, targetNamespace string, targetSecretName string, result copyResult, err error) {
+	if !r.CopyEvents {
+		return
+	}
+
+	source := rule.SourceSecret.Namespace + "/" + rule.SourceSecret.Name
+	target := targetNamespace + "/" + targetSecretName
+
+	switch result {
+	case copyCreated:
+		r.Recorder.Eventf(secretCopier, corev1.EventTypeNormal, "SecretCreated",
+			"Secret %s created from %s", target, source)
+	case copyUpdated:
+		r.Recorder.Eventf(secretCopier, corev1.EventTypeNormal, "SecretUpdated",
+			"Secret %s updated from %s", target, source)
+	case copySkipped:
+		r.Recorder.Eventf(secretCopier, corev1.EventTypeNormal, "SecretSkipped",
+			"Secret %s not copied from %s as it is not managed by the SecretCopier or the source can't be copied", target, source)
+	case copyIgnored:
+		r.Recorder.Eventf(secretCopier, corev1.EventTypeNormal, "SecretIgnored",
+			"Secret %s not updated from %s as it is labelled to be ignored", target, source)
+	case copyFailed:
+		r.Recorder.Eventf(secretCopier, corev1.EventTypeWarning, "SecretCopyFailed",
+			"Unable to copy secret %s to %s (reason %s): %v", source, target, classifyCopyFailure(result, err), err)
+	}
+}
+
+// Record events against the source secret of a rule, if enabled, when it was
+// copied to target namespaces or copying to target namespaces failed, so that
+// owners of the source namespace can see where the secret is distributed
+// without needing access to the cluster scoped SecretCopier. Nothing is
+// recorded when all target secrets were already up to date.
+func (r *SecretCopierReconciler) recordSourceSecretEvent(secretCopier *secretsv1beta1.SecretCopier, sourceSecret *corev1.Secret, ruleIndex int, copied int, failed int, synced int) {
+	if !r.SourceSecretEvents {
+		return
+	}
+
+	if copied > 0 {
+		r.Recorder.Eventf(sourceSecret, corev1.EventTypeNormal, "SecretDistributed",
+			"Secret copied to %d namespaces by rule %d of SecretCopier %s, now synced to %d namespaces",
+			copied, ruleIndex, secretCopier.Name, synced)
+	}
+
+	if failed > 0 {
+		r.Recorder.Eventf(sourceSecret, corev1.EventTypeWarning, "SecretDistributionFailed",
+			"Secret could not be copied to %d namespaces by rule %d of SecretCopier %s",
+			failed, ruleIndex, secretCopier.Name)
 	}
 }
 
@@ -460,21 +1972,76 @@ func (r *SecretCopierReconciler) copySecretToNamespace(ctx context.Context, secr
 // secret and by the same SecretCopier object. This is done by checking the
 // annotations on the target secret.
 func (r *SecretCopierReconciler) targetSecretManagedBySecretCopier(secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, targetSecret *corev1.Secret) bool {
-	if targetSecret.Annotations["secrets-manager.advok8s.io/secret-copier"] != secretCopier.Name {
+	if targetSecret.Annotations[secretCopierMarkerAnnotation] != secretCopier.Name {
 		return false
 	}
 
-	if targetSecret.Annotations["secrets-manager.advok8s.io/secret-name"] != rule.SourceSecret.Namespace+"/"+rule.SourceSecret.Name {
-		return false
+	return targetSecretSourceMatches(targetSecret, rule.SourceSecret)
+}
+
+// Return a copy of the rule with the reclaim policy set to that which applies
+// to the target namespace, and the overrides removed.
+func (r *SecretCopierReconciler) resolveReclaimPolicy(ctx context.Context, rule *secretsv1beta1.SecretCopierRule, targetNamespace string) (*secretsv1beta1.SecretCopierRule, error) {
+	var namespace corev1.Namespace
+
+	if err := r.Get(ctx, client.ObjectKey{Name: targetNamespace}, &namespace); err != nil {
+		return nil, err
 	}
 
-	return true
+	resolved := *rule
+
+	resolved.ReclaimPolicy = rule.ReclaimPolicyFor(&namespace)
+	resolved.ReclaimPolicyOverrides = nil
+
+	return &resolved, nil
+}
+
+// Construct the owner reference making the SecretCopier the controlling owner
+// of a target secret, so the target secret is deleted with it.
+func secretCopierOwnerReference(secretCopier *secretsv1beta1.SecretCopier) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion:         secretCopier.APIVersion,
+		Kind:               secretCopier.Kind,
+		Name:               secretCopier.Name,
+		UID:                secretCopier.UID,
+		Controller:         ptr.To(true),
+		BlockOwnerDeletion: ptr.To(true),
+	}
+}
+
+// Calculate the labels for a target secret. These are a copy of the labels of
+// the source secret, overlaid with the labels the manager is configured to add
+// to all target secrets, and then any labels specified in the rule for the
+// target secret.
+func (r *SecretCopierReconciler) targetSecretLabels(rule *secretsv1beta1.SecretCopierRule, sourceSecret *corev1.Secret) map[string]string {
+	targetSecretLabels := make(map[string]string)
+
+	for key, value := range sourceSecret.Labels {
+		targetSecretLabels[key] = value
+	}
+
+	for key, value := range r.Config.Settings().TargetSecretLabels {
+		targetSecretLabels[key] = value
+	}
+
+	for key, value := range rule.TargetSecret.Labels {
+		targetSecretLabels[key] = value
+	}
+
+	// The ignore label is only ever set on a target secret by hand, so it
+	// must not be copied from the source secret or the copies would all be
+	// ignored.
+
+	delete(targetSecretLabels, ignoreLabel)
+
+	return targetSecretLabels
 }
 
 // Determine if the source secret has been updated by comparing the type, data
-// and labels of the source and target secrets.
+// and labels of the source and target secrets, and whether the annotations
+// from the rule are applied to the target secret.
 func (r *SecretCopierReconciler) sourceSecretHasBeenUpdated(rule *secretsv1beta1.SecretCopierRule, sourceSecret, targetSecret *corev1.Secret) bool {
-	if sourceSecret.Type != targetSecret.Type {
+	if sourceSecret.Type != targetSecret.Type || rule.TargetSecret.Immutable != secretImmutable(targetSecret) {
 		return true
 	}
 
@@ -500,37 +2067,23 @@ func (r *SecretCopierReconciler) sourceSecretHasBeenUpdated(rule *secretsv1beta1
 		return true
 	}
 
-	targetSecretLabels := make(map[string]string)
+	return !r.targetSecretLabelsMatch(rule, sourceSecret, targetSecret) || !targetSecretAnnotationsMatch(rule, targetSecret)
+}
 
-	for key, value := range sourceSecret.Labels {
-		targetSecretLabels[key] = value
-	}
+// Determine if the labels of the target secret match those calculated from the
+// source secret and the rule.
+func (r *SecretCopierReconciler) targetSecretLabelsMatch(rule *secretsv1beta1.SecretCopierRule, sourceSecret, targetSecret *corev1.Secret) bool {
+	targetSecretLabels := r.targetSecretLabels(rule, sourceSecret)
 
-	for key, value := range rule.TargetSecret.Labels {
-		targetSecretLabels[key] = value
+	if len(targetSecret.Labels) != len(targetSecretLabels) {
+		return false
 	}
 
-	mapStringStringEqual := func(a map[string]string, b map[string]string) bool {
-		if a == nil && b == nil {
-			return true
-		}
-		if a == nil || b == nil {
+	for key, value := range targetSecretLabels {
+		if current, ok := targetSecret.Labels[key]; !ok || current != value {
 			return false
 		}
-		if len(a) != len(b) {
-			return false
-		}
-		for key, valueA := range a {
-			if valueB, ok := b[key]; !ok || valueA != valueB {
-				return false
-			}
-		}
-		return true
 	}
 
-	if !mapStringStringEqual(sourceSecret.Labels, targetSecretLabels) {
-		return true
-	}
-
-	return false
+	return true
 }
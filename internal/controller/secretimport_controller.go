@@ -0,0 +1,421 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// SecretImportReconciler reconciles a SecretImport object
+type SecretImportReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Recorder for events about SecretImport objects.
+	Recorder record.EventRecorder
+
+	// Controller wide settings which can be changed while running. If nil,
+	// the built in defaults are used.
+	Config *ManagerConfig
+
+	// If set, secrets for imports are never written. Imports which would
+	// have resulted in a write are left pending.
+	ReportOnly bool
+}
+
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=secretimports,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=secretimports/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=secretimports/finalizers,verbs=update
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=secretexports,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+
+// Reconcile a SecretImport by looking up the SecretExport it names, verifying
+// that the export permits the secret to be imported into the namespace of the
+// import, and copying the exported secret into the namespace of the import.
+// As both sides are namespaced, namespace owners can share secrets with each
+// other without needing cluster wide access.
+func (r *SecretImportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	// Fetch the named SecretImport object.
+
+	var secretImport secretsv1beta1.SecretImport
+
+	if err := r.Get(ctx, req.NamespacedName, &secretImport); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			// Custom resource has been deleted. The secret created for the
+			// import will be deleted by the garbage collector as the import
+			// is set as its owner.
+
+			log.V(1).Info("SecretImport has been deleted", "name", req.NamespacedName)
+
+			return ctrl.Result{}, nil
+		}
+
+		log.Error(err, "Unable to fetch SecretImport", "name", req.NamespacedName)
+
+		return ctrl.Result{}, err
+	}
+
+	secretName := secretImport.ImportedSecretName()
+
+	phase, message, err := r.importSecret(ctx, &secretImport, secretName)
+
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// If the import is no longer permitted, remove any secret previously
+	// created for it.
+
+	if phase == secretsv1beta1.SecretImportDenied {
+		if err := r.deleteImportedSecret(ctx, &secretImport, secretName); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		// If the secret was imported and the export has since gone away or
+		// no longer permits the import, the import has been revoked.
+
+		if secretImport.Status.Phase == secretsv1beta1.SecretImportImported || secretImport.Status.Phase == secretsv1beta1.SecretImportRevoked {
+			phase = secretsv1beta1.SecretImportRevoked
+		}
+	}
+
+	if phase != secretImport.Status.Phase {
+		eventType := corev1.EventTypeNormal
+
+		if phase == secretsv1beta1.SecretImportDenied || phase == secretsv1beta1.SecretImportRevoked {
+			eventType = corev1.EventTypeWarning
+		}
+
+		r.Recorder.Event(&secretImport, eventType, string(phase), message)
+	}
+
+	status := secretsv1beta1.SecretImportStatus{
+		ObservedGeneration: secretImport.Generation,
+		Phase:              phase,
+		Message:            message,
+	}
+
+	if phase == secretsv1beta1.SecretImportImported {
+		status.SecretName = secretName
+	}
+
+	if err := r.updateSecretImportStatus(ctx, &secretImport, status); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Requeue for when a freeze on writes to secrets expires, so any write
+	// held back by it is then made.
+
+	return ctrl.Result{RequeueAfter: r.Config.Settings().WriteFreezeRemaining(time.Now())}, nil
+}
+
+// Attempt to import the secret, returning the resulting phase and an
+// explanation of it. An error is only returned where the operation should be
+// retried.
+func (r *SecretImportReconciler) importSecret(ctx context.Context, secretImport *secretsv1beta1.SecretImport, secretName string) (secretsv1beta1.SecretImportPhase, string, error) {
+	log := log.FromContext(ctx)
+
+	exportName := secretImport.ImportedExportName()
+
+	exportReference := secretImport.Spec.FromNamespace + "/" + exportName
+
+	// Look up the SecretExport being imported.
+
+	var secretExport secretsv1beta1.SecretExport
+
+	if err := r.Get(ctx, client.ObjectKey{Namespace: secretImport.Spec.FromNamespace, Name: exportName}, &secretExport); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return secretsv1beta1.SecretImportDenied, fmt.Sprintf("SecretExport %s not found", exportReference), nil
+		}
+
+		log.Error(err, "Unable to fetch SecretExport", "name", exportReference)
+
+		return "", "", err
+	}
+
+	// Verify that the export permits the secret to be imported into the
+	// namespace of the import.
+
+	var namespace corev1.Namespace
+
+	if err := r.Get(ctx, client.ObjectKey{Name: secretImport.Namespace}, &namespace); err != nil {
+		log.Error(err, "Unable to fetch Namespace", "name", secretImport.Namespace)
+
+		return "", "", err
+	}
+
+	if secretImport.Namespace == secretImport.Spec.FromNamespace || !secretExport.Spec.ToNamespaces.Matches(&namespace) || r.Config.Settings().NamespaceDenied(namespace.Name) {
+		return secretsv1beta1.SecretImportDenied, fmt.Sprintf("SecretExport %s cannot be imported into namespace %s", exportReference, secretImport.Namespace), nil
+	}
+
+	// Fetch the exported secret. It is always in the namespace of the export
+	// so an export can only offer secrets belonging to its own namespace.
+
+	sourceName := secretExport.ExportedSecretName()
+
+	source := secretExport.Namespace + "/" + sourceName
+
+	var sourceSecret corev1.Secret
+
+	if err := r.Get(ctx, client.ObjectKey{Namespace: secretExport.Namespace, Name: sourceName}, &sourceSecret); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return secretsv1beta1.SecretImportPending, fmt.Sprintf("Exported secret %s not found", source), nil
+		}
+
+		log.Error(err, "Unable to fetch exported secret", "sourceSecret", source)
+
+		return "", "", err
+	}
+
+	if r.Config.Settings().SecretTypeDenied(sourceSecret.Type) {
+		return secretsv1beta1.SecretImportDenied, fmt.Sprintf("Exported secret %s is of type %s which is never copied", source, sourceSecret.Type), nil
+	}
+
+	// Create or update the secret in the namespace of the import. An
+	// existing secret which isn't managed by this import is never
+	// overwritten.
+
+	var targetSecret corev1.Secret
+
+	err := r.Get(ctx, client.ObjectKey{Namespace: secretImport.Namespace, Name: secretName}, &targetSecret)
+
+	if err != nil && client.IgnoreNotFound(err) != nil {
+		log.Error(err, "Unable to fetch target secret", "targetSecret", secretName, "targetNamespace", secretImport.Namespace)
+
+		return "", "", err
+	}
+
+	exists := err == nil
+
+	if exists && !metav1.IsControlledBy(&targetSecret, secretImport) {
+		return secretsv1beta1.SecretImportDenied, fmt.Sprintf("Secret %s already exists and is not managed by the import", secretName), nil
+	}
+
+	imported := fmt.Sprintf("Secret %s imported from SecretExport %s", secretName, exportReference)
+
+	if exists {
+		if targetSecret.Type == sourceSecret.Type && equality.Semantic.DeepEqual(targetSecret.Data, sourceSecret.Data) &&
+			targetSecret.Annotations["secrets-manager.advok8s.io/secret-name"] == source {
+			return secretsv1beta1.SecretImportImported, imported, nil
+		}
+	}
+
+	// When running in report-only mode, or writes to secrets are frozen,
+	// the secret is not written and the import is left pending.
+
+	if r.ReportOnly || r.Config.WritesFrozen() {
+		verb := "create"
+
+		if exists {
+			verb = "update"
+		}
+
+		log.Info("Writes suspended, skipping write of secret for SecretImport", "verb", verb, "name", secretImport.Name, "namespace", secretImport.Namespace, "targetSecret", secretName)
+
+		countSkippedWrite("secretimport", verb, r.ReportOnly)
+
+		return secretsv1beta1.SecretImportPending, fmt.Sprintf("%s, secret %s would be %sd", skippedWriteReason(r.ReportOnly, r.Config), secretName, verb), nil
+	}
+
+	// The type of a secret is immutable so if it differs the secret needs to
+	// be recreated.
+
+	if exists && targetSecret.Type != sourceSecret.Type {
+		if err := r.Delete(ctx, &targetSecret); client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Unable to delete target secret", "targetSecret", secretName, "targetNamespace", secretImport.Namespace)
+
+			return "", "", err
+		}
+
+		exists = false
+	}
+
+	if !exists {
+		targetSecret = corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretName,
+				Namespace: secretImport.Namespace,
+			},
+		}
+
+		if err := controllerutil.SetControllerReference(secretImport, &targetSecret, r.Scheme); err != nil {
+			return "", "", err
+		}
+	}
+
+	if targetSecret.Annotations == nil {
+		targetSecret.Annotations = make(map[string]string)
+	}
+
+	targetSecret.Annotations["secrets-manager.advok8s.io/secret-import"] = secretImport.Name
+	targetSecret.Annotations["secrets-manager.advok8s.io/secret-name"] = source
+
+	targetSecret.Type = sourceSecret.Type
+	targetSecret.Data = sourceSecret.Data
+
+	if exists {
+		err = r.Update(ctx, &targetSecret)
+	} else {
+		err = r.Create(ctx, &targetSecret)
+	}
+
+	if err != nil {
+		log.Error(err, "Unable to write target secret", "targetSecret", secretName, "targetNamespace", secretImport.Namespace)
+
+		return "", "", err
+	}
+
+	log.V(1).Info("Wrote secret for SecretImport", "name", secretImport.Name, "namespace", secretImport.Namespace, "targetSecret", secretName)
+
+	return secretsv1beta1.SecretImportImported, imported, nil
+}
+
+// Delete the secret created for an import, if it exists and is managed by the
+// import.
+func (r *SecretImportReconciler) deleteImportedSecret(ctx context.Context, secretImport *secretsv1beta1.SecretImport, secretName string) error {
+	log := log.FromContext(ctx)
+
+	var targetSecret corev1.Secret
+
+	if err := r.Get(ctx, client.ObjectKey{Namespace: secretImport.Namespace, Name: secretName}, &targetSecret); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	if !metav1.IsControlledBy(&targetSecret, secretImport) {
+		return nil
+	}
+
+	if r.ReportOnly || r.Config.WritesFrozen() {
+		log.Info("Writes suspended, skipping delete of secret for SecretImport", "name", secretImport.Name, "namespace", secretImport.Namespace, "targetSecret", secretName)
+
+		countSkippedWrite("secretimport", "delete", r.ReportOnly)
+
+		return nil
+	}
+
+	if err := r.Delete(ctx, &targetSecret); client.IgnoreNotFound(err) != nil {
+		log.Error(err, "Unable to delete secret for SecretImport", "name", secretImport.Name, "namespace", secretImport.Namespace, "targetSecret", secretName)
+		return err
+	}
+
+	log.V(1).Info("Deleted secret for SecretImport", "name", secretImport.Name, "namespace", secretImport.Namespace, "targetSecret", secretName)
+
+	return nil
+}
+
+// Update the status of the SecretImport if it has changed.
+func (r *SecretImportReconciler) updateSecretImportStatus(ctx context.Context, secretImport *secretsv1beta1.SecretImport, status secretsv1beta1.SecretImportStatus) error {
+	log := log.FromContext(ctx)
+
+	if equality.Semantic.DeepEqual(secretImport.Status, status) {
+		return nil
+	}
+
+	secretImport.Status = status
+
+	if err := r.Status().Update(ctx, secretImport); err != nil {
+		log.Error(err, "Unable to update SecretImport status", "name", secretImport.Name, "namespace", secretImport.Namespace)
+		return err
+	}
+
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SecretImportReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&secretsv1beta1.SecretImport{}).
+		Owns(&corev1.Secret{}).
+		Watches(
+			&secretsv1beta1.SecretExport{},
+			handler.EnqueueRequestsFromMapFunc(r.findSecretImportsForSecretExport),
+		).
+		Watches(
+			&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.findSecretImportsForExportedSecret),
+			builder.WithPredicates(secretContentChanged()),
+		).
+		Complete(r)
+}
+
+// Handler function to find SecretImport objects which import a SecretExport.
+// This is used to trigger a reconciliation of the imports when the export is
+// created, changed or deleted.
+func (r *SecretImportReconciler) findSecretImportsForSecretExport(ctx context.Context, object client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	var secretImports secretsv1beta1.SecretImportList
+
+	if err := r.List(ctx, &secretImports); err != nil {
+		log.Error(err, "Unable to list SecretImport objects")
+		return nil
+	}
+
+	var requests []reconcile.Request
+
+	for _, secretImport := range secretImports.Items {
+		if secretImport.Spec.FromNamespace == object.GetNamespace() && secretImport.ImportedExportName() == object.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&secretImport)})
+		}
+	}
+
+	return requests
+}
+
+// Handler function to find SecretImport objects which import a SecretExport
+// offering the given secret. This is used to trigger a reconciliation of the
+// imports when the exported secret is created or changes.
+func (r *SecretImportReconciler) findSecretImportsForExportedSecret(ctx context.Context, secret client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	var secretExports secretsv1beta1.SecretExportList
+
+	if err := r.List(ctx, &secretExports, client.InNamespace(secret.GetNamespace())); err != nil {
+		log.Error(err, "Unable to list SecretExport objects")
+		return nil
+	}
+
+	var requests []reconcile.Request
+
+	for _, secretExport := range secretExports.Items {
+		if secretExport.ExportedSecretName() == secret.GetName() {
+			requests = append(requests, r.findSecretImportsForSecretExport(ctx, &secretExport)...)
+		}
+	}
+
+	return requests
+}
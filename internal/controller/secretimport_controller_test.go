@@ -0,0 +1,157 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+var _ = Describe("SecretImport Controller", func() {
+	ctx := context.Background()
+
+	var k8sClient client.Client
+
+	var reconciler *SecretImportReconciler
+
+	BeforeEach(func() {
+		k8sClient = fake.NewClientBuilder().
+			WithStatusSubresource(&secretsv1beta1.SecretImport{}).
+			WithObjects(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}},
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-a"}},
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other-a"}},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "team-a"},
+					Data:       map[string][]byte{"password": []byte("value")},
+				},
+				&secretsv1beta1.SecretExport{
+					ObjectMeta: metav1.ObjectMeta{Name: "registry-export", Namespace: "team-a"},
+					Spec: secretsv1beta1.SecretExportSpec{
+						SecretName: "registry",
+						ToNamespaces: selectors.TargetNamespaces{
+							NameSelector: selectors.NameSelector{MatchNames: []string{"tenant-*"}},
+						},
+					},
+				},
+			).Build()
+
+		reconciler = &SecretImportReconciler{Client: k8sClient, Scheme: scheme.Scheme, Recorder: record.NewFakeRecorder(10)}
+	})
+
+	importInto := func(namespace string) *secretsv1beta1.SecretImport {
+		secretImport := &secretsv1beta1.SecretImport{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: namespace},
+			Spec: secretsv1beta1.SecretImportSpec{
+				FromNamespace: "team-a",
+				ExportName:    "registry-export",
+			},
+		}
+
+		Expect(k8sClient.Create(ctx, secretImport)).To(Succeed())
+
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(secretImport)})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(secretImport), secretImport)).To(Succeed())
+
+		return secretImport
+	}
+
+	It("should import the secret into a namespace permitted by the export", func() {
+		secretImport := importInto("tenant-a")
+
+		Expect(secretImport.Status.Phase).To(Equal(secretsv1beta1.SecretImportImported))
+		Expect(secretImport.Status.SecretName).To(Equal("registry"))
+
+		targetSecret := &corev1.Secret{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "tenant-a", Name: "registry"}, targetSecret)).To(Succeed())
+
+		Expect(targetSecret.Data).To(HaveKeyWithValue("password", []byte("value")))
+		Expect(targetSecret.Annotations).To(HaveKeyWithValue("secrets-manager.advok8s.io/secret-name", "team-a/registry"))
+		Expect(metav1.IsControlledBy(targetSecret, secretImport)).To(BeTrue())
+	})
+
+	It("should deny an import into a namespace not permitted by the export", func() {
+		secretImport := importInto("other-a")
+
+		Expect(secretImport.Status.Phase).To(Equal(secretsv1beta1.SecretImportDenied))
+
+		err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "other-a", Name: "registry"}, &corev1.Secret{})
+		Expect(client.IgnoreNotFound(err)).To(Succeed())
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should not overwrite a secret not managed by the import", func() {
+		Expect(k8sClient.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "tenant-a"},
+			Data:       map[string][]byte{"password": []byte("local")},
+		})).To(Succeed())
+
+		secretImport := importInto("tenant-a")
+
+		Expect(secretImport.Status.Phase).To(Equal(secretsv1beta1.SecretImportDenied))
+
+		targetSecret := &corev1.Secret{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "tenant-a", Name: "registry"}, targetSecret)).To(Succeed())
+		Expect(targetSecret.Data).To(HaveKeyWithValue("password", []byte("local")))
+	})
+
+	It("should revoke the import when the export is deleted", func() {
+		secretImport := importInto("tenant-a")
+
+		Expect(secretImport.Status.Phase).To(Equal(secretsv1beta1.SecretImportImported))
+
+		Expect(k8sClient.Delete(ctx, &secretsv1beta1.SecretExport{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry-export", Namespace: "team-a"},
+		})).To(Succeed())
+
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(secretImport)})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(secretImport), secretImport)).To(Succeed())
+		Expect(secretImport.Status.Phase).To(Equal(secretsv1beta1.SecretImportRevoked))
+
+		err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "tenant-a", Name: "registry"}, &corev1.Secret{})
+		Expect(client.IgnoreNotFound(err)).To(Succeed())
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should find imports of an exported secret", func() {
+		secretImport := importInto("tenant-a")
+
+		requests := reconciler.findSecretImportsForExportedSecret(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "team-a"},
+		})
+
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].NamespacedName).To(Equal(client.ObjectKeyFromObject(secretImport)))
+	})
+})
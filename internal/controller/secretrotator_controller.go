@@ -0,0 +1,438 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+const (
+	// Annotation on a Deployment or StatefulSet listing the names of the
+	// secrets in its namespace which it depends on, separated by commas.
+	restartOnSecretChangeAnnotation = "secrets-manager.advok8s.io/restart-on-secret-change"
+
+	// Annotation on a Deployment or StatefulSet recording a hash of the
+	// copied secrets it depends on, as of when it was last restarted.
+	dependentSecretsHashAnnotation = "secrets-manager.advok8s.io/dependent-secrets-hash"
+
+	// Annotation on the pod template of a Deployment or StatefulSet set to
+	// the time it was restarted, which triggers a rolling restart.
+	restartedAtAnnotation = "secrets-manager.advok8s.io/restarted-at"
+)
+
+// SecretRotatorReconciler reconciles a SecretRotator object
+type SecretRotatorReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Recorder for events about SecretRotator objects.
+	Recorder record.EventRecorder
+
+	// Controller wide settings which can be changed while running. If nil,
+	// the built in defaults are used.
+	Config *ManagerConfig
+
+	// If set, workloads are never restarted. Restarts which would have been
+	// made are reported as events instead.
+	ReportOnly bool
+}
+
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=secretrotators,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=secretrotators/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=secretrotators/finalizers,verbs=update
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+
+// Reconcile a SecretRotator by checking the Deployments and StatefulSets in
+// its target namespaces which are annotated as depending on secrets. When the
+// data of a copied secret a workload depends on has changed since it was last
+// seen, a rolling restart of the workload is triggered by updating an
+// annotation of its pod template, so its pods pick up the rotated secret.
+func (r *SecretRotatorReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	// Fetch the named SecretRotator object.
+
+	var secretRotator secretsv1beta1.SecretRotator
+
+	if err := r.Get(ctx, req.NamespacedName, &secretRotator); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			// Custom resource has been deleted, so drop any metrics which
+			// were recorded against it.
+
+			log.V(1).Info("SecretRotator has been deleted", "name", req.NamespacedName)
+
+			deleteSecretRotatorMetrics(req.Name)
+
+			return ctrl.Result{}, nil
+		}
+
+		log.Error(err, "Unable to fetch SecretRotator", "name", req.NamespacedName)
+		return ctrl.Result{}, err
+	}
+
+	// Work out which namespaces workloads may be restarted in, excluding
+	// those which the manager has been configured to never copy to and any
+	// which are being deleted.
+
+	var namespaces corev1.NamespaceList
+
+	if err := r.List(ctx, &namespaces); err != nil {
+		log.Error(err, "Unable to list namespaces")
+		return ctrl.Result{}, err
+	}
+
+	settings := r.Config.Settings()
+
+	matcher := secretRotator.Spec.TargetNamespaces.Compile()
+
+	targetNamespaces := make(map[string]bool)
+
+	for i := range namespaces.Items {
+		namespace := &namespaces.Items[i]
+
+		if namespace.Status.Phase == corev1.NamespaceTerminating || settings.NamespaceDenied(namespace.Name) {
+			continue
+		}
+
+		if matcher.Matches(namespace) {
+			targetNamespaces[namespace.Name] = true
+		}
+	}
+
+	workloads, err := r.listDependentWorkloads(ctx)
+
+	if err != nil {
+		log.Error(err, "Unable to list workloads depending on secrets")
+		return ctrl.Result{}, err
+	}
+
+	status := *secretRotator.Status.DeepCopy()
+
+	status.ObservedGeneration = secretRotator.Generation
+	status.Workloads = 0
+
+	for _, workload := range workloads {
+		if !targetNamespaces[workload.GetNamespace()] {
+			continue
+		}
+
+		status.Workloads++
+
+		restarted, err := r.restartWorkloadIfSecretsChanged(ctx, &secretRotator, workload)
+
+		if err != nil {
+			log.Error(err, "Unable to restart workload for SecretRotator", "name", req.NamespacedName, "kind", workloadKind(workload), "namespace", workload.GetNamespace(), "workload", workload.GetName())
+			return ctrl.Result{}, err
+		}
+
+		if restarted {
+			now := metav1.Now()
+
+			status.Restarts++
+			status.LastRestartTime = &now
+		}
+	}
+
+	if equality.Semantic.DeepEqual(secretRotator.Status, status) {
+		return ctrl.Result{}, nil
+	}
+
+	secretRotator.Status = status
+
+	if err := r.Status().Update(ctx, &secretRotator); err != nil {
+		log.Error(err, "Unable to update SecretRotator status", "name", req.NamespacedName)
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// List the Deployments and StatefulSets in the cluster which are annotated as
+// depending on secrets and aren't being deleted.
+func (r *SecretRotatorReconciler) listDependentWorkloads(ctx context.Context) ([]client.Object, error) {
+	var deployments appsv1.DeploymentList
+
+	if err := r.List(ctx, &deployments); err != nil {
+		return nil, err
+	}
+
+	var statefulSets appsv1.StatefulSetList
+
+	if err := r.List(ctx, &statefulSets); err != nil {
+		return nil, err
+	}
+
+	var workloads []client.Object
+
+	for i := range deployments.Items {
+		workloads = append(workloads, &deployments.Items[i])
+	}
+
+	for i := range statefulSets.Items {
+		workloads = append(workloads, &statefulSets.Items[i])
+	}
+
+	dependent := workloads[:0]
+
+	for _, workload := range workloads {
+		if len(dependentSecretNames(workload)) != 0 && workload.GetDeletionTimestamp() == nil {
+			dependent = append(dependent, workload)
+		}
+	}
+
+	return dependent, nil
+}
+
+// Check whether the copied secrets a workload depends on have changed since
+// it was last seen, and if they have, trigger a rolling restart of it. The
+// first time a workload is seen, the hash of its secrets is only recorded so
+// that adding the annotation doesn't itself cause a restart. Returns whether
+// the workload was restarted.
+func (r *SecretRotatorReconciler) restartWorkloadIfSecretsChanged(ctx context.Context, secretRotator *secretsv1beta1.SecretRotator, workload client.Object) (bool, error) {
+	log := log.FromContext(ctx)
+
+	hash, err := r.dependentSecretsHash(ctx, workload)
+
+	if err != nil {
+		return false, err
+	}
+
+	recorded, seen := workload.GetAnnotations()[dependentSecretsHashAnnotation]
+
+	if seen && recorded == hash {
+		return false, nil
+	}
+
+	kind := workloadKind(workload)
+
+	if r.ReportOnly {
+		if seen {
+			log.Info("Report-only mode, skipping restart of workload", "kind", kind, "namespace", workload.GetNamespace(), "workload", workload.GetName())
+
+			countSkippedWrite("secretrotator", "restart", true)
+
+			r.Recorder.Eventf(secretRotator, corev1.EventTypeNormal, "ReportOnly", "Would restart %s %s in namespace %s as secrets it depends on changed",
+				kind, workload.GetName(), workload.GetNamespace())
+		}
+
+		return false, nil
+	}
+
+	patch := client.MergeFrom(workload.DeepCopyObject().(client.Object))
+
+	annotations := workload.GetAnnotations()
+
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+
+	annotations[dependentSecretsHashAnnotation] = hash
+
+	workload.SetAnnotations(annotations)
+
+	if seen {
+		template := workloadPodTemplate(workload)
+
+		if template.Annotations == nil {
+			template.Annotations = make(map[string]string)
+		}
+
+		template.Annotations[restartedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	if err := r.Patch(ctx, workload, patch); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+
+	if !seen {
+		log.V(1).Info("Recorded hash of secrets workload depends on", "kind", kind, "namespace", workload.GetNamespace(), "workload", workload.GetName(), "hash", hash)
+
+		return false, nil
+	}
+
+	log.Info("Restarted workload as secrets it depends on changed", "secretRotator", secretRotator.Name, "kind", kind, "namespace", workload.GetNamespace(), "workload", workload.GetName())
+
+	r.Recorder.Eventf(secretRotator, corev1.EventTypeNormal, "WorkloadRestarted", "Restarted %s %s in namespace %s as secrets it depends on changed",
+		kind, workload.GetName(), workload.GetNamespace())
+
+	workloadRestartsTotal.WithLabelValues(secretRotator.Name, kind).Inc()
+
+	return true, nil
+}
+
+// Calculate a hash of the copied secrets a workload depends on. Secrets which
+// don't exist, or which weren't copied by a SecretCopier, are left out.
+func (r *SecretRotatorReconciler) dependentSecretsHash(ctx context.Context, workload client.Object) (string, error) {
+	hash := sha256.New()
+
+	for _, name := range dependentSecretNames(workload) {
+		var secret corev1.Secret
+
+		if err := r.Get(ctx, client.ObjectKey{Namespace: workload.GetNamespace(), Name: name}, &secret); err != nil {
+			if client.IgnoreNotFound(err) != nil {
+				return "", err
+			}
+
+			continue
+		}
+
+		if _, copied := secret.Annotations[secretCopierMarkerAnnotation]; !copied {
+			continue
+		}
+
+		fmt.Fprintf(hash, "%s\x00%s\x00", name, sourceSecretRevision(&secret))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))[:16], nil
+}
+
+// Return the sorted names of the secrets a workload is annotated as depending
+// on, with duplicates and empty entries removed.
+func dependentSecretNames(workload client.Object) []string {
+	value := workload.GetAnnotations()[restartOnSecretChangeAnnotation]
+
+	var names []string
+
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	slices.Sort(names)
+
+	return slices.Compact(names)
+}
+
+// Return the kind of a workload which can be restarted.
+func workloadKind(workload client.Object) string {
+	switch workload.(type) {
+	case *appsv1.Deployment:
+		return "Deployment"
+	case *appsv1.StatefulSet:
+		return "StatefulSet"
+	}
+
+	return ""
+}
+
+// Return the pod template of a workload which can be restarted.
+func workloadPodTemplate(workload client.Object) *corev1.PodTemplateSpec {
+	switch workload := workload.(type) {
+	case *appsv1.Deployment:
+		return &workload.Spec.Template
+	case *appsv1.StatefulSet:
+		return &workload.Spec.Template
+	}
+
+	return nil
+}
+
+// Predicate which passes workloads when they are created, or the secrets they
+// are annotated as depending on change. Changes made to workloads when they
+// are restarted, and to their status, are ignored.
+func workloadDependenciesChanged() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return len(dependentSecretNames(e.Object)) != 0
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return e.ObjectOld.GetAnnotations()[restartOnSecretChangeAnnotation] != e.ObjectNew.GetAnnotations()[restartOnSecretChangeAnnotation]
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return len(dependentSecretNames(e.Object)) != 0
+		},
+	}
+}
+
+// Handler function to find all SecretRotator objects. This is used to trigger
+// a reconciliation of them when a copied secret changes, or a workload
+// depending on secrets is added or changed, as the few SecretRotators in a
+// cluster each cover many namespaces.
+func (r *SecretRotatorReconciler) findSecretRotators(ctx context.Context, object client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	if _, ok := object.(*corev1.Secret); ok {
+		if _, copied := object.GetAnnotations()[secretCopierMarkerAnnotation]; !copied {
+			return nil
+		}
+	}
+
+	var secretRotators secretsv1beta1.SecretRotatorList
+
+	if err := r.List(ctx, &secretRotators); err != nil {
+		log.Error(err, "Unable to list SecretRotator objects")
+		return nil
+	}
+
+	var requests []reconcile.Request
+
+	for _, secretRotator := range secretRotators.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&secretRotator)})
+	}
+
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SecretRotatorReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&secretsv1beta1.SecretRotator{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(
+			&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.findSecretRotators),
+			builder.WithPredicates(secretContentChanged()),
+		).
+		Watches(
+			&appsv1.Deployment{},
+			handler.EnqueueRequestsFromMapFunc(r.findSecretRotators),
+			builder.WithPredicates(workloadDependenciesChanged()),
+		).
+		Watches(
+			&appsv1.StatefulSet{},
+			handler.EnqueueRequestsFromMapFunc(r.findSecretRotators),
+			builder.WithPredicates(workloadDependenciesChanged()),
+		).
+		Complete(r)
+}
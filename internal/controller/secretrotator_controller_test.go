@@ -0,0 +1,241 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+var _ = Describe("SecretRotator Controller", func() {
+	ctx := context.Background()
+
+	request := ctrl.Request{NamespacedName: client.ObjectKey{Name: "rotator"}}
+
+	newSecretRotator := func() *secretsv1beta1.SecretRotator {
+		return &secretsv1beta1.SecretRotator{
+			ObjectMeta: metav1.ObjectMeta{Name: "rotator"},
+			Spec: secretsv1beta1.SecretRotatorSpec{
+				TargetNamespaces: selectors.TargetNamespaces{
+					NameSelector: selectors.NameSelector{MatchNames: []string{"rotator-*"}},
+				},
+			},
+		}
+	}
+
+	newNamespace := func(name string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+
+	newSecret := func(namespace string, name string, copied bool, password string) *corev1.Secret {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Data:       map[string][]byte{"password": []byte(password)},
+		}
+
+		if copied {
+			secret.Annotations = map[string]string{secretCopierMarkerAnnotation: "copier"}
+		}
+
+		return secret
+	}
+
+	newDeployment := func(namespace string, name string, secrets string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   namespace,
+				Name:        name,
+				Annotations: map[string]string{restartOnSecretChangeAnnotation: secrets},
+			},
+		}
+	}
+
+	newStatefulSet := func(namespace string, name string, secrets string) *appsv1.StatefulSet {
+		return &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   namespace,
+				Name:        name,
+				Annotations: map[string]string{restartOnSecretChangeAnnotation: secrets},
+			},
+		}
+	}
+
+	newReconciler := func(objects ...client.Object) (*SecretRotatorReconciler, *record.FakeRecorder) {
+		secretRotator := newSecretRotator()
+
+		recorder := record.NewFakeRecorder(10)
+
+		c := fake.NewClientBuilder().
+			WithObjects(append(objects, secretRotator)...).
+			WithStatusSubresource(secretRotator).
+			Build()
+
+		return &SecretRotatorReconciler{Client: c, Recorder: recorder}, recorder
+	}
+
+	rotateSecret := func(reconciler *SecretRotatorReconciler, namespace string, name string, password string) {
+		var secret corev1.Secret
+
+		Expect(reconciler.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &secret)).To(Succeed())
+
+		secret.Data["password"] = []byte(password)
+
+		Expect(reconciler.Update(ctx, &secret)).To(Succeed())
+	}
+
+	restartedAt := func(reconciler *SecretRotatorReconciler, workload client.Object) string {
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(workload), workload)).To(Succeed())
+
+		return workloadPodTemplate(workload).Annotations[restartedAtAnnotation]
+	}
+
+	It("should parse the secrets a workload depends on", func() {
+		Expect(dependentSecretNames(newDeployment("rotator-a", "web", " tls, registry,,tls "))).To(Equal([]string{"registry", "tls"}))
+		Expect(dependentSecretNames(&appsv1.Deployment{})).To(BeEmpty())
+	})
+
+	It("should only record the hash of secrets the first time a workload is seen", func() {
+		deployment := newDeployment("rotator-a", "web", "registry")
+
+		reconciler, recorder := newReconciler(
+			newNamespace("rotator-a"),
+			newSecret("rotator-a", "registry", true, "one"),
+			deployment,
+		)
+
+		_, err := reconciler.Reconcile(ctx, request)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restartedAt(reconciler, deployment)).To(BeEmpty())
+		Expect(deployment.Annotations).To(HaveKey(dependentSecretsHashAnnotation))
+		Expect(recorder.Events).To(BeEmpty())
+
+		var secretRotator secretsv1beta1.SecretRotator
+		Expect(reconciler.Get(ctx, request.NamespacedName, &secretRotator)).To(Succeed())
+		Expect(secretRotator.Status.Workloads).To(Equal(int32(1)))
+		Expect(secretRotator.Status.Restarts).To(BeZero())
+	})
+
+	It("should restart workloads when a copied secret they depend on changes", func() {
+		deployment := newDeployment("rotator-a", "web", "registry")
+		statefulSet := newStatefulSet("rotator-a", "db", "registry")
+		unrelated := newDeployment("rotator-a", "worker", "other")
+
+		reconciler, recorder := newReconciler(
+			newNamespace("rotator-a"),
+			newSecret("rotator-a", "registry", true, "one"),
+			newSecret("rotator-a", "other", true, "one"),
+			deployment,
+			statefulSet,
+			unrelated,
+		)
+
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		rotateSecret(reconciler, "rotator-a", "registry", "two")
+
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(restartedAt(reconciler, deployment)).NotTo(BeEmpty())
+		Expect(restartedAt(reconciler, statefulSet)).NotTo(BeEmpty())
+		Expect(restartedAt(reconciler, unrelated)).To(BeEmpty())
+
+		Expect(recorder.Events).To(HaveLen(2))
+		Expect(<-recorder.Events).To(Equal("Normal WorkloadRestarted Restarted Deployment web in namespace rotator-a as secrets it depends on changed"))
+
+		var secretRotator secretsv1beta1.SecretRotator
+		Expect(reconciler.Get(ctx, request.NamespacedName, &secretRotator)).To(Succeed())
+		Expect(secretRotator.Status.Workloads).To(Equal(int32(3)))
+		Expect(secretRotator.Status.Restarts).To(Equal(int64(2)))
+		Expect(secretRotator.Status.LastRestartTime).NotTo(BeNil())
+
+		// Reconciling again without further changes doesn't restart the
+		// workloads a second time.
+
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).To(HaveLen(1))
+	})
+
+	It("should ignore secrets which weren't copied and workloads outside target namespaces", func() {
+		deployment := newDeployment("rotator-a", "web", "local")
+		outside := newDeployment("other", "web", "registry")
+
+		reconciler, recorder := newReconciler(
+			newNamespace("rotator-a"),
+			newNamespace("other"),
+			newSecret("rotator-a", "local", false, "one"),
+			newSecret("other", "registry", true, "one"),
+			deployment,
+			outside,
+		)
+
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		rotateSecret(reconciler, "rotator-a", "local", "two")
+		rotateSecret(reconciler, "other", "registry", "two")
+
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(restartedAt(reconciler, deployment)).To(BeEmpty())
+		Expect(restartedAt(reconciler, outside)).To(BeEmpty())
+		Expect(outside.Annotations).NotTo(HaveKey(dependentSecretsHashAnnotation))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("should only report restarts when running in report-only mode", func() {
+		deployment := newDeployment("rotator-a", "web", "registry")
+
+		reconciler, recorder := newReconciler(
+			newNamespace("rotator-a"),
+			newSecret("rotator-a", "registry", true, "one"),
+			deployment,
+		)
+
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		rotateSecret(reconciler, "rotator-a", "registry", "two")
+
+		reconciler.ReportOnly = true
+
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(restartedAt(reconciler, deployment)).To(BeEmpty())
+
+		var event string
+		Expect(recorder.Events).To(Receive(&event))
+		Expect(event).To(Equal("Normal ReportOnly Would restart Deployment web in namespace rotator-a as secrets it depends on changed"))
+	})
+})
@@ -0,0 +1,294 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// SecretsReadyReconciler maintains the secrets-ready annotation on namespaces
+// matched by SecretCopier rules. The annotation is "true" once the target
+// secret of every enabled rule matching the namespace exists and is in sync
+// with its source secret, and "false" otherwise, so admission or CI tooling
+// can hold back workloads until the secrets they need, such as image pull
+// secrets, have been copied. Readiness is worked out from all SecretCopiers
+// together, as a namespace is often the target of more than one.
+type SecretsReadyReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Controller wide settings which can be changed while running. If nil,
+	// the built in defaults are used.
+	Config *ManagerConfig
+
+	// Compiled target namespace matchers for the rules of each SecretCopier.
+	matchers matcherCache
+}
+
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch;update;patch
+
+// Reconcile sets the secrets-ready annotation on the namespace to reflect
+// whether all secrets to be copied to it have been copied.
+func (r *SecretsReadyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	var namespace corev1.Namespace
+
+	if err := r.Get(ctx, req.NamespacedName, &namespace); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if namespace.Status.Phase == corev1.NamespaceTerminating {
+		return ctrl.Result{}, nil
+	}
+
+	required, ready, err := r.secretsReady(ctx, &namespace)
+
+	if err != nil {
+		log.Error(err, "Unable to determine if secrets are ready", "namespace", namespace.Name)
+		return ctrl.Result{}, err
+	}
+
+	// The annotation is only present on namespaces which at least one rule
+	// copies a secret to.
+
+	current, annotated := namespace.Annotations["secrets-manager.advok8s.io/secrets-ready"]
+
+	desired := "false"
+
+	if ready {
+		desired = "true"
+	}
+
+	if (!required && !annotated) || (required && annotated && current == desired) {
+		return ctrl.Result{}, nil
+	}
+
+	patch := client.MergeFrom(namespace.DeepCopy())
+
+	if required {
+		if namespace.Annotations == nil {
+			namespace.Annotations = make(map[string]string)
+		}
+
+		namespace.Annotations["secrets-manager.advok8s.io/secrets-ready"] = desired
+	} else {
+		delete(namespace.Annotations, "secrets-manager.advok8s.io/secrets-ready")
+	}
+
+	if err := r.Patch(ctx, &namespace, patch); err != nil {
+		log.Error(err, "Unable to update secrets-ready annotation of namespace", "namespace", namespace.Name)
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	log.V(1).Info("Updated secrets-ready annotation of namespace", "namespace", namespace.Name, "required", required, "ready", ready)
+
+	return ctrl.Result{}, nil
+}
+
+// Determine whether any enabled rule of a SecretCopier copies a secret to the
+// namespace, and if so, whether all such target secrets exist and are in sync
+// with their source secret. Rules whose source secret is of a type which is
+// never copied are ignored, as their target secret will never exist.
+func (r *SecretsReadyReconciler) secretsReady(ctx context.Context, namespace *corev1.Namespace) (bool, bool, error) {
+	var secretCopiers secretsv1beta1.SecretCopierList
+
+	if err := r.List(ctx, &secretCopiers); err != nil {
+		return false, false, err
+	}
+
+	// The helpers used when copying secrets are reused to check the target
+	// secrets, so that readiness is judged the same way.
+
+	copier := &SecretCopierReconciler{Client: r.Client, Config: r.Config}
+
+	required := false
+
+	for i := range secretCopiers.Items {
+		secretCopier := &secretCopiers.Items[i]
+
+		matchers := r.matchers.get(secretCopier)
+
+		rules, ruleOrigins, err := expandSourceSecretPatterns(ctx, r.Client, secretCopier.Spec.Rules)
+
+		if err != nil {
+			return false, false, err
+		}
+
+		for ruleIndex := range rules {
+			rule := &rules[ruleIndex]
+
+			if !rule.IsEnabled() || rule.TargetCluster != nil || rule.SourceSecret.Namespace == namespace.Name || !matchers[ruleOrigins[ruleIndex]].Matches(namespace) {
+				continue
+			}
+
+			var sourceSecret corev1.Secret
+
+			if err := r.Get(ctx, client.ObjectKey{Namespace: rule.SourceSecret.Namespace, Name: rule.SourceSecret.Name}, &sourceSecret); err != nil {
+				if client.IgnoreNotFound(err) != nil {
+					return false, false, err
+				}
+
+				return true, false, nil
+			}
+
+			if r.Config.Settings().SecretTypeDenied(sourceSecret.Type) {
+				continue
+			}
+
+			required = true
+
+			// The target secret holds only the data selected or rendered by
+			// the rule, so compare against that rather than the source secret.
+
+			content, err := targetSecretContent(rule, &sourceSecret)
+
+			if err != nil {
+				return true, false, nil
+			}
+
+			if rule.TargetNamespaceReadiness != nil {
+				namespaceReady, err := copier.targetNamespaceReady(ctx, rule, namespace)
+
+				if err != nil {
+					return false, false, err
+				}
+
+				if !namespaceReady {
+					return true, false, nil
+				}
+			}
+
+			name := targetSecretName(rule)
+
+			if rule.TargetSecret.HashSuffix != nil {
+				name = contentHashedTargetSecretName(rule, content)
+			}
+
+			var targetSecret corev1.Secret
+
+			if err := r.Get(ctx, client.ObjectKey{Namespace: namespace.Name, Name: name}, &targetSecret); err != nil {
+				if client.IgnoreNotFound(err) != nil {
+					return false, false, err
+				}
+
+				return true, false, nil
+			}
+
+			if targetSecretIgnored(&targetSecret) {
+				continue
+			}
+
+			if !copier.targetSecretManagedBySecretCopier(secretCopier, rule, &targetSecret) || copier.sourceSecretHasBeenUpdated(rule, content, &targetSecret) {
+				return true, false, nil
+			}
+		}
+	}
+
+	return required, true, nil
+}
+
+// Map a secret to the namespaces whose readiness it may affect. For a target
+// secret this is its own namespace, while for a source secret it is all
+// namespaces matched by rules copying it, as they are stale until updated.
+func (r *SecretsReadyReconciler) findNamespacesForSecret(ctx context.Context, object client.Object) []reconcile.Request {
+	if _, ok := object.GetAnnotations()["secrets-manager.advok8s.io/secret-copier"]; ok {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: object.GetNamespace()}}}
+	}
+
+	var secretCopiers secretsv1beta1.SecretCopierList
+
+	if err := r.List(ctx, &secretCopiers); err != nil {
+		return nil
+	}
+
+	var sourcing []*secretsv1beta1.SecretCopier
+
+	for i := range secretCopiers.Items {
+		for _, rule := range secretCopiers.Items[i].Spec.Rules {
+			if sourceSecretMatches(&rule, client.ObjectKeyFromObject(object)) {
+				sourcing = append(sourcing, &secretCopiers.Items[i])
+				break
+			}
+		}
+	}
+
+	return r.matchedNamespaces(ctx, sourcing...)
+}
+
+// Map a SecretCopier to the namespaces matched by its rules.
+func (r *SecretsReadyReconciler) findNamespacesForSecretCopier(ctx context.Context, object client.Object) []reconcile.Request {
+	secretCopier, ok := object.(*secretsv1beta1.SecretCopier)
+
+	if !ok {
+		return nil
+	}
+
+	return r.matchedNamespaces(ctx, secretCopier)
+}
+
+// Return requests for all namespaces matched by a rule of the SecretCopiers.
+func (r *SecretsReadyReconciler) matchedNamespaces(ctx context.Context, secretCopiers ...*secretsv1beta1.SecretCopier) []reconcile.Request {
+	if len(secretCopiers) == 0 {
+		return nil
+	}
+
+	var namespaces corev1.NamespaceList
+
+	if err := r.List(ctx, &namespaces); err != nil {
+		return nil
+	}
+
+	var requests []reconcile.Request
+
+	for i := range namespaces.Items {
+		namespace := &namespaces.Items[i]
+
+	copiers:
+		for _, secretCopier := range secretCopiers {
+			for _, matcher := range r.matchers.get(secretCopier) {
+				if matcher.Matches(namespace) {
+					requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: namespace.Name}})
+					break copiers
+				}
+			}
+		}
+	}
+
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SecretsReadyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("secretsready").
+		For(&corev1.Namespace{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.findNamespacesForSecret)).
+		Watches(&secretsv1beta1.SecretCopier{}, handler.EnqueueRequestsFromMapFunc(r.findNamespacesForSecretCopier)).
+		Complete(r)
+}
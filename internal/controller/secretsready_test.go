@@ -0,0 +1,133 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+var _ = Describe("Secrets Ready Annotation", func() {
+	ctx := context.Background()
+
+	// Test that a namespace is only marked as having its secrets ready once
+	// every rule targeting it has copied its secret.
+
+	Context("Secrets ready annotation #1", func() {
+		It("should mark the namespace ready once all rules have synced", func() {
+			sourceNamespaceName := "ready-source-namespace-1"
+			targetNamespaceName := "ready-target-namespace-1"
+			otherNamespaceName := "ready-other-namespace-1"
+
+			for _, name := range []string{sourceNamespaceName, targetNamespaceName, otherNamespaceName} {
+				namespace := &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: name,
+					},
+				}
+				Expect(k8sClient.Create(ctx, namespace)).To(Succeed())
+			}
+
+			pullSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ready-pull-secret-1",
+					Namespace: sourceNamespaceName,
+				},
+				Type: corev1.SecretTypeOpaque,
+				Data: map[string][]byte{
+					"key1": []byte("value1"),
+				},
+			}
+			Expect(k8sClient.Create(ctx, pullSecret)).To(Succeed())
+
+			targetNamespaces := selectors.TargetNamespaces{
+				NameSelector: selectors.NameSelector{
+					MatchNames: []string{targetNamespaceName},
+				},
+			}
+
+			secretCopier := &secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "ready-secret-copier-1",
+				},
+				Spec: secretsv1beta1.SecretCopierSpec{
+					Rules: []secretsv1beta1.SecretCopierRule{
+						{
+							SourceSecret: secretsv1beta1.SourceSecret{
+								Namespace: sourceNamespaceName,
+								Name:      "ready-pull-secret-1",
+							},
+							TargetNamespaces: targetNamespaces,
+						},
+						{
+							SourceSecret: secretsv1beta1.SourceSecret{
+								Namespace: sourceNamespaceName,
+								Name:      "ready-database-secret-1",
+							},
+							TargetNamespaces: targetNamespaces,
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, secretCopier)).To(Succeed())
+
+			readyAnnotation := func(name string) func() string {
+				return func() string {
+					var namespace corev1.Namespace
+					Expect(k8sClient.Get(ctx, client.ObjectKey{Name: name}, &namespace)).To(Succeed())
+					value, ok := namespace.Annotations["secrets-manager.advok8s.io/secrets-ready"]
+					if !ok {
+						return "<unset>"
+					}
+					return value
+				}
+			}
+
+			// The second rule can't be synced as its source secret doesn't
+			// exist yet, so the namespace isn't ready.
+
+			Eventually(readyAnnotation(targetNamespaceName)).Should(Equal("false"))
+
+			databaseSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ready-database-secret-1",
+					Namespace: sourceNamespaceName,
+				},
+				Type: corev1.SecretTypeOpaque,
+				Data: map[string][]byte{
+					"password": []byte("secret"),
+				},
+			}
+			Expect(k8sClient.Create(ctx, databaseSecret)).To(Succeed())
+
+			Eventually(readyAnnotation(targetNamespaceName)).Should(Equal("true"))
+
+			// Namespaces no rule copies to are not annotated.
+
+			Consistently(readyAnnotation(otherNamespaceName)).Should(Equal("<unset>"))
+		})
+	})
+})
@@ -0,0 +1,121 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Name of the field index on SecretCopier objects holding the secrets which,
+// when changed, require the SecretCopier to be reconciled. These are the
+// source secrets of the rules and the kubeconfig secrets for target clusters
+// and virtual clusters.
+const sourceSecretIndexField = "spec.rules.sourceSecret"
+
+// Name used in place of the secret name in the index key for rules whose
+// source secret name is a glob pattern. As an asterisk is not valid in the
+// name of a secret this cannot clash with the key for an actual secret.
+const sourceSecretPatternIndexName = "*"
+
+// Construct the index key for a secret.
+func sourceSecretIndexKey(namespace string, name string) string {
+	return namespace + "/" + name
+}
+
+// Extract the index keys for the secrets a SecretCopier depends on. Rules
+// with a source secret pattern are indexed against the source namespace only,
+// with the pattern being checked against the name of the secret on lookup.
+// Rules copying into virtual clusters are likewise indexed against the
+// namespace holding the kubeconfig secrets vcluster creates, as the names of
+// the secrets depend on the virtual clusters present.
+func sourceSecretIndexValues(object client.Object) []string {
+	secretCopier, ok := object.(*secretsv1beta1.SecretCopier)
+
+	if !ok {
+		return nil
+	}
+
+	var values []string
+
+	seen := make(map[string]bool)
+
+	add := func(value string) {
+		if !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+
+	for _, rule := range secretCopier.Spec.Rules {
+		if rule.CopiesSourceSecretPattern() {
+			add(sourceSecretIndexKey(rule.SourceSecret.Namespace, sourceSecretPatternIndexName))
+		} else {
+			add(sourceSecretIndexKey(rule.SourceSecret.Namespace, rule.SourceSecret.Name))
+		}
+
+		if rule.TargetCluster != nil {
+			reference := rule.TargetCluster.KubeconfigSecret
+
+			add(sourceSecretIndexKey(reference.Namespace, reference.Name))
+		}
+
+		if rule.VirtualClusterNamespaces != nil && rule.VirtualClusterNamespaces.Policy == secretsv1beta1.VirtualClusterNamespaceCopyInto {
+			add(sourceSecretIndexKey(rule.VirtualClusterNamespaces.KubeconfigSecretNamespace, sourceSecretPatternIndexName))
+		}
+	}
+
+	return values
+}
+
+// Register the field index used to look up the SecretCopier objects which
+// depend on a secret, so that secret events don't require every SecretCopier
+// to be listed.
+func indexSecretCopierSourceSecrets(ctx context.Context, mgr ctrl.Manager) error {
+	return mgr.GetFieldIndexer().IndexField(ctx, &secretsv1beta1.SecretCopier{}, sourceSecretIndexField, sourceSecretIndexValues)
+}
+
+// List the SecretCopier objects which may depend on a secret, using the field
+// index. The result includes SecretCopier objects with a source secret
+// pattern for the namespace of the secret, so the caller still needs to check
+// the rules of each against the secret.
+func (r *SecretCopierReconciler) listSecretCopiersForSourceSecret(ctx context.Context, key client.ObjectKey) ([]secretsv1beta1.SecretCopier, error) {
+	var secretCopiers []secretsv1beta1.SecretCopier
+
+	seen := make(map[string]bool)
+
+	for _, name := range []string{key.Name, sourceSecretPatternIndexName} {
+		var matched secretsv1beta1.SecretCopierList
+
+		if err := r.List(ctx, &matched, client.MatchingFields{sourceSecretIndexField: sourceSecretIndexKey(key.Namespace, name)}); err != nil {
+			return nil, err
+		}
+
+		for _, secretCopier := range matched.Items {
+			if !seen[secretCopier.Name] {
+				seen[secretCopier.Name] = true
+				secretCopiers = append(secretCopiers, secretCopier)
+			}
+		}
+	}
+
+	return secretCopiers, nil
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Source Secret Index", func() {
+	ctx := context.Background()
+
+	newSecretCopier := func(name string, rules ...secretsv1beta1.SecretCopierRule) *secretsv1beta1.SecretCopier {
+		return &secretsv1beta1.SecretCopier{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       secretsv1beta1.SecretCopierSpec{Rules: rules},
+		}
+	}
+
+	newRule := func(namespace string, name string) secretsv1beta1.SecretCopierRule {
+		return secretsv1beta1.SecretCopierRule{
+			SourceSecret: secretsv1beta1.SourceSecret{Namespace: namespace, Name: name},
+		}
+	}
+
+	newReconciler := func() *SecretCopierReconciler {
+		remote := newRule("index-source", "app")
+		remote.TargetCluster = &secretsv1beta1.TargetCluster{
+			KubeconfigSecret: secretsv1beta1.KubeconfigSecretReference{Namespace: "index-clusters", Name: "remote"},
+		}
+
+		virtual := newRule("index-source", "app")
+		virtual.VirtualClusterNamespaces = &secretsv1beta1.VirtualClusterNamespaces{
+			Policy:                    secretsv1beta1.VirtualClusterNamespaceCopyInto,
+			KubeconfigSecretNamespace: "index-vclusters",
+		}
+
+		return &SecretCopierReconciler{Client: fake.NewClientBuilder().
+			WithIndex(&secretsv1beta1.SecretCopier{}, sourceSecretIndexField, sourceSecretIndexValues).
+			WithObjects(
+				newSecretCopier("exact", newRule("index-source", "registry")),
+				newSecretCopier("pattern", newRule("index-source", "registry-*")),
+				newSecretCopier("remote", remote),
+				newSecretCopier("virtual", virtual),
+				newSecretCopier("unrelated", newRule("index-other", "registry")),
+			).Build()}
+	}
+
+	requested := func(reconciler *SecretCopierReconciler, namespace string, name string) []string {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+
+		var names []string
+
+		for _, request := range reconciler.findSecretCopiersMatchingSourceSecret(ctx, secret) {
+			names = append(names, request.Name)
+		}
+
+		return names
+	}
+
+	It("should index each secret a SecretCopier depends on once", func() {
+		secretCopier := newSecretCopier("indexed",
+			newRule("index-source", "registry"),
+			newRule("index-source", "registry"),
+			newRule("index-source", "tls-*"),
+		)
+
+		Expect(sourceSecretIndexValues(secretCopier)).To(Equal([]string{"index-source/registry", "index-source/*"}))
+		Expect(sourceSecretIndexValues(&corev1.Secret{})).To(BeEmpty())
+
+		virtual := newRule("index-source", "registry")
+		virtual.VirtualClusterNamespaces = &secretsv1beta1.VirtualClusterNamespaces{
+			Policy:                    secretsv1beta1.VirtualClusterNamespaceCopyInto,
+			KubeconfigSecretNamespace: "index-vclusters",
+		}
+
+		Expect(sourceSecretIndexValues(newSecretCopier("virtual", virtual))).To(Equal([]string{"index-source/registry", "index-vclusters/*"}))
+	})
+
+	It("should only queue SecretCopier objects depending on the secret", func() {
+		reconciler := newReconciler()
+
+		Expect(requested(reconciler, "index-source", "registry")).To(ConsistOf("exact"))
+		Expect(requested(reconciler, "index-source", "registry-mirror")).To(ConsistOf("pattern"))
+		Expect(requested(reconciler, "index-source", "other")).To(BeEmpty())
+		Expect(requested(reconciler, "index-clusters", "remote")).To(ConsistOf("remote"))
+		Expect(requested(reconciler, "index-vclusters", "vc-team-a")).To(ConsistOf("virtual"))
+		Expect(requested(reconciler, "index-vclusters", "other")).To(BeEmpty())
+		Expect(requested(reconciler, "index-other", "registry")).To(ConsistOf("unrelated"))
+	})
+
+	It("should return each SecretCopier only once", func() {
+		reconciler := newReconciler()
+
+		Expect(reconciler.Create(ctx, newSecretCopier("both",
+			newRule("index-source", "registry"),
+			newRule("index-source", "*"),
+		))).To(Succeed())
+
+		secretCopiers, err := reconciler.listSecretCopiersForSourceSecret(ctx, client.ObjectKey{Namespace: "index-source", Name: "registry"})
+
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+
+		for _, secretCopier := range secretCopiers {
+			names = append(names, secretCopier.Name)
+		}
+
+		Expect(names).To(ConsistOf("exact", "pattern", "both"))
+	})
+})
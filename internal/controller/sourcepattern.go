@@ -0,0 +1,186 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+// Key identifying a rule of the spec of a SecretCopier, together with its
+// source secret, so that the rules expanded from a source secret name pattern
+// are told apart but keep the same key as other secrets are added or removed.
+func ruleSourceKey(ruleIndex int, rule *secretsv1beta1.SecretCopierRule) string {
+	return strconv.Itoa(ruleIndex) + "/" + rule.SourceSecret.Namespace + "/" + rule.SourceSecret.Name
+}
+
+// Expand the rules whose source secret name is a glob pattern into a rule for
+// each secret in the source namespace with a matching name. Rules otherwise
+// keep the order given, with the rules expanded from a pattern ordered by the
+// name of the source secret. A rule whose pattern matches no secrets is kept
+// as is, so that its status reports the source secret as not found. The index
+// of the rule each returned rule came from is also returned.
+func expandSourceSecretPatterns(ctx context.Context, reader client.Reader, rules []secretsv1beta1.SecretCopierRule) ([]secretsv1beta1.SecretCopierRule, []int, error) {
+	expanded := make([]secretsv1beta1.SecretCopierRule, 0, len(rules))
+	origins := make([]int, 0, len(rules))
+
+	expandedNames := make(map[string][]string)
+
+	sourceNames := make(map[string][]string)
+
+	for i := range rules {
+		rule := &rules[i]
+
+		if !rule.CopiesSourceSecretPattern() {
+			expanded = append(expanded, *rule)
+			origins = append(origins, i)
+
+			continue
+		}
+
+		// Secrets in a source namespace are listed once, no matter how many
+		// rules have a pattern for it.
+
+		names, found := sourceNames[rule.SourceSecret.Namespace]
+
+		if !found {
+			var secrets corev1.SecretList
+
+			if err := reader.List(ctx, &secrets, client.InNamespace(rule.SourceSecret.Namespace)); err != nil {
+				return nil, nil, err
+			}
+
+			names = make([]string, 0, len(secrets.Items))
+
+			for _, secret := range secrets.Items {
+				names = append(names, secret.Name)
+			}
+
+			slices.Sort(names)
+
+			sourceNames[rule.SourceSecret.Namespace] = names
+		}
+
+		matched := 0
+
+		for _, name := range names {
+			if !selectors.MatchesAnyPattern(name, []string{rule.SourceSecret.Name}) {
+				continue
+			}
+
+			expandedRule := ruleForSourceSecret(rule, name)
+
+			if rule.Name != "" {
+				expandedNames[rule.Name] = append(expandedNames[rule.Name], expandedRule.Name)
+			}
+
+			expanded = append(expanded, expandedRule)
+			origins = append(origins, i)
+
+			matched++
+		}
+
+		if matched == 0 {
+			expanded = append(expanded, *rule)
+			origins = append(origins, i)
+		}
+	}
+
+	// A rule depending on a named rule with a pattern depends on all the
+	// rules which the pattern was expanded to.
+
+	if len(expandedNames) != 0 {
+		for i := range expanded {
+			rule := &expanded[i]
+
+			if len(rule.DependsOn) == 0 {
+				continue
+			}
+
+			dependsOn := make([]string, 0, len(rule.DependsOn))
+
+			for _, name := range rule.DependsOn {
+				if names, found := expandedNames[name]; found {
+					dependsOn = append(dependsOn, names...)
+				} else {
+					dependsOn = append(dependsOn, name)
+				}
+			}
+
+			rule.DependsOn = dependsOn
+		}
+	}
+
+	return expanded, origins, nil
+}
+
+// Return a rule with a source secret pattern narrowed to copy just the named
+// source secret. The target secret takes the name of the source secret, and a
+// named rule is given the name of the source secret as a suffix so that the
+// names of rules remain unique.
+func ruleForSourceSecret(rule *secretsv1beta1.SecretCopierRule, name string) secretsv1beta1.SecretCopierRule {
+	expanded := *rule.DeepCopy()
+
+	expanded.SourceSecret.Name = name
+	expanded.TargetSecret.Name = ""
+
+	if rule.Name != "" {
+		expanded.Name = rule.Name + "/" + name
+	}
+
+	return expanded
+}
+
+// Determine if a secret is a source secret of a rule, either because it is
+// the named source secret or its name matches the source secret pattern.
+func sourceSecretMatches(rule *secretsv1beta1.SecretCopierRule, key client.ObjectKey) bool {
+	if rule.SourceSecret.Namespace != key.Namespace {
+		return false
+	}
+
+	if !rule.CopiesSourceSecretPattern() {
+		return rule.SourceSecret.Name == key.Name
+	}
+
+	return selectors.MatchesAnyPattern(key.Name, []string{rule.SourceSecret.Name})
+}
+
+// Return the rule to check a target secret against. For a rule with a source
+// secret pattern matching the source secret recorded on the target secret,
+// this is the rule narrowed to that source secret, otherwise the rule itself.
+func ruleForTargetSecret(rule *secretsv1beta1.SecretCopierRule, secret *corev1.Secret) *secretsv1beta1.SecretCopierRule {
+	if !rule.CopiesSourceSecretPattern() {
+		return rule
+	}
+
+	source, ok := targetSecretSource(secret)
+
+	if !ok || !sourceSecretMatches(rule, source) {
+		return rule
+	}
+
+	expanded := ruleForSourceSecret(rule, source.Name)
+
+	return &expanded
+}
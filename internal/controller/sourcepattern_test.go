@@ -0,0 +1,147 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Source Secret Patterns", func() {
+	newSecret := func(namespace string, name string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	}
+
+	reader := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		newSecret("source", "tls-b"),
+		newSecret("source", "tls-a"),
+		newSecret("source", "token"),
+		newSecret("other", "tls-c"),
+	).Build()
+
+	It("should expand a pattern into a rule for each matching source secret", func() {
+		rules := []secretsv1beta1.SecretCopierRule{
+			{
+				SourceSecret: secretsv1beta1.SourceSecret{Name: "token", Namespace: "source"},
+				TargetSecret: secretsv1beta1.TargetSecret{Name: "api-token"},
+			},
+			{
+				SourceSecret: secretsv1beta1.SourceSecret{Name: "tls-*", Namespace: "source"},
+			},
+		}
+
+		expanded, origins, err := expandSourceSecretPatterns(context.Background(), reader, rules)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(origins).To(Equal([]int{0, 1, 1}))
+
+		Expect(expanded).To(HaveLen(3))
+		Expect(expanded[0]).To(Equal(rules[0]))
+		Expect(expanded[1].SourceSecret).To(Equal(secretsv1beta1.SourceSecret{Name: "tls-a", Namespace: "source"}))
+		Expect(expanded[2].SourceSecret).To(Equal(secretsv1beta1.SourceSecret{Name: "tls-b", Namespace: "source"}))
+		Expect(targetSecretName(&expanded[2])).To(Equal("tls-b"))
+
+		// The rules given are left unchanged.
+
+		Expect(rules[1].SourceSecret.Name).To(Equal("tls-*"))
+	})
+
+	It("should keep a pattern which matches no source secrets", func() {
+		rules := []secretsv1beta1.SecretCopierRule{
+			{
+				SourceSecret: secretsv1beta1.SourceSecret{Name: "ca-*", Namespace: "source"},
+			},
+		}
+
+		expanded, origins, err := expandSourceSecretPatterns(context.Background(), reader, rules)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(origins).To(Equal([]int{0}))
+		Expect(expanded).To(Equal(rules))
+	})
+
+	It("should make rules depend on every rule a named pattern expands to", func() {
+		rules := []secretsv1beta1.SecretCopierRule{
+			{
+				Name:         "certificates",
+				SourceSecret: secretsv1beta1.SourceSecret{Name: "tls-*", Namespace: "source"},
+			},
+			{
+				Name:         "token",
+				DependsOn:    []string{"certificates"},
+				SourceSecret: secretsv1beta1.SourceSecret{Name: "token", Namespace: "source"},
+			},
+		}
+
+		expanded, _, err := expandSourceSecretPatterns(context.Background(), reader, rules)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(expanded).To(HaveLen(3))
+		Expect(expanded[0].Name).To(Equal("certificates/tls-a"))
+		Expect(expanded[1].Name).To(Equal("certificates/tls-b"))
+		Expect(expanded[2].DependsOn).To(Equal([]string{"certificates/tls-a", "certificates/tls-b"}))
+
+		spec := secretsv1beta1.SecretCopierSpec{Rules: expanded}
+
+		order, unresolved := spec.RuleOrder()
+
+		Expect(unresolved).To(BeEmpty())
+		Expect(order).To(Equal([]int{0, 1, 2}))
+	})
+
+	It("should match source secrets against a pattern", func() {
+		rule := &secretsv1beta1.SecretCopierRule{
+			SourceSecret: secretsv1beta1.SourceSecret{Name: "tls-*", Namespace: "source"},
+		}
+
+		Expect(sourceSecretMatches(rule, client.ObjectKey{Namespace: "source", Name: "tls-a"})).To(BeTrue())
+		Expect(sourceSecretMatches(rule, client.ObjectKey{Namespace: "source", Name: "token"})).To(BeFalse())
+		Expect(sourceSecretMatches(rule, client.ObjectKey{Namespace: "other", Name: "tls-c"})).To(BeFalse())
+	})
+
+	It("should narrow a pattern to the source secret of a target secret", func() {
+		rule := &secretsv1beta1.SecretCopierRule{
+			SourceSecret: secretsv1beta1.SourceSecret{Name: "tls-*", Namespace: "source"},
+		}
+
+		target := newSecret("target", "tls-a")
+
+		setTargetSecretMarkers(target, "secret-copier", secretsv1beta1.SourceSecret{Namespace: "source", Name: "tls-a"})
+
+		narrowed := ruleForTargetSecret(rule, target)
+
+		Expect(narrowed.SourceSecret.Name).To(Equal("tls-a"))
+		Expect(targetSecretSourceMatches(target, narrowed.SourceSecret)).To(BeTrue())
+		Expect(targetSecretNameMatches(narrowed, target)).To(BeTrue())
+
+		// A target secret copied from a source secret not matching the
+		// pattern is checked against the rule as given.
+
+		setTargetSecretMarkers(target, "secret-copier", secretsv1beta1.SourceSecret{Namespace: "source", Name: "token"})
+
+		Expect(ruleForTargetSecret(rule, target)).To(BeIdenticalTo(rule))
+	})
+})
@@ -0,0 +1,97 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Maximum delay between checks for the source secret of a rule waiting for
+// it, when no other maximum has been configured.
+const defaultSourceWaitMaxRequeue = 2 * time.Minute
+
+// Key under which the backoff for a rule waiting for its source secret is
+// tracked. The source secret is included so that changing the source secret
+// of a rule starts the backoff again.
+func sourceWaitKey(secretCopier *secretsv1beta1.SecretCopier, ruleIndex int, rule *secretsv1beta1.SecretCopierRule) string {
+	return secretCopier.Name + "/" + ruleSourceKey(ruleIndex, rule)
+}
+
+// Record that a rule is waiting for its source secret to be created, returning
+// how long until the SecretCopier should be reconciled again to check for it.
+// The delay starts at the configured requeue delay and doubles each time the
+// source secret is still missing, up to the configured maximum. Zero is
+// returned if requeuing while waiting for the source secret is disabled.
+func (r *SecretCopierReconciler) waitForSourceSecret(secretCopier *secretsv1beta1.SecretCopier, ruleIndex int, rule *secretsv1beta1.SecretCopierRule, now time.Time) time.Duration {
+	if r.SourceWaitRequeue <= 0 {
+		return 0
+	}
+
+	maxDelay := r.SourceWaitMaxRequeue
+
+	if maxDelay <= 0 {
+		maxDelay = defaultSourceWaitMaxRequeue
+	}
+
+	maxDelay = max(maxDelay, r.SourceWaitRequeue)
+
+	return r.sourceWaits.failed(sourceWaitKey(secretCopier, ruleIndex, rule), now, r.SourceWaitRequeue, maxDelay, nil)
+}
+
+// Record that the source secret of a rule exists, so that the backoff starts
+// again from the beginning if the source secret is later deleted.
+func (r *SecretCopierReconciler) sourceSecretFound(secretCopier *secretsv1beta1.SecretCopier, ruleIndex int, rule *secretsv1beta1.SecretCopierRule) {
+	r.sourceWaits.succeeded(sourceWaitKey(secretCopier, ruleIndex, rule))
+}
+
+// Discard the backoff for all rules of a SecretCopier.
+func (r *SecretCopierReconciler) forgetSourceWaits(secretCopierName string) {
+	r.sourceWaits.forget(func(key string) bool {
+		return strings.HasPrefix(key, secretCopierName+"/")
+	})
+}
+
+// Construct the WaitingForSource condition listing the rules whose source
+// secret doesn't exist. The message only changes when the set of rules
+// waiting changes, so the condition settles while they are waiting.
+func waitingForSourceCondition(ruleStatuses []secretsv1beta1.SecretCopierRuleStatus, waiting []int, generation int64) metav1.Condition {
+	sources := make([]string, 0, len(waiting))
+
+	for _, ruleIndex := range waiting {
+		source := ruleStatuses[ruleIndex].SourceSecret
+
+		if name := ruleStatuses[ruleIndex].Name; name != "" {
+			sources = append(sources, fmt.Sprintf("%s (%s/%s)", name, source.Namespace, source.Name))
+		} else {
+			sources = append(sources, fmt.Sprintf("rule %d (%s/%s)", ruleIndex, source.Namespace, source.Name))
+		}
+	}
+
+	return metav1.Condition{
+		Type:               secretsv1beta1.ConditionWaitingForSource,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "SourceSecretNotFound",
+		Message:            "Waiting for source secrets to be created: " + strings.Join(sources, ", "),
+	}
+}
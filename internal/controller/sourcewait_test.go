@@ -0,0 +1,83 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Waiting For Source Secrets", func() {
+	secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "waiting-copier"}}
+
+	rule := &secretsv1beta1.SecretCopierRule{
+		SourceSecret: secretsv1beta1.SourceSecret{Name: "registry", Namespace: "source"},
+	}
+
+	now := time.Date(2024, 9, 1, 10, 0, 0, 0, time.UTC)
+
+	It("should not requeue when disabled", func() {
+		reconciler := &SecretCopierReconciler{}
+
+		Expect(reconciler.waitForSourceSecret(secretCopier, 0, rule, now)).To(BeZero())
+	})
+
+	It("should back off while the source secret is missing", func() {
+		reconciler := &SecretCopierReconciler{SourceWaitRequeue: 5 * time.Second, SourceWaitMaxRequeue: 15 * time.Second}
+
+		Expect(reconciler.waitForSourceSecret(secretCopier, 0, rule, now)).To(Equal(5 * time.Second))
+		Expect(reconciler.waitForSourceSecret(secretCopier, 0, rule, now)).To(Equal(10 * time.Second))
+		Expect(reconciler.waitForSourceSecret(secretCopier, 0, rule, now)).To(Equal(15 * time.Second))
+		Expect(reconciler.waitForSourceSecret(secretCopier, 0, rule, now)).To(Equal(15 * time.Second))
+
+		// Rules are backed off independently.
+
+		Expect(reconciler.waitForSourceSecret(secretCopier, 1, rule, now)).To(Equal(5 * time.Second))
+
+		// Once the source secret exists the backoff starts again.
+
+		reconciler.sourceSecretFound(secretCopier, 0, rule)
+
+		Expect(reconciler.waitForSourceSecret(secretCopier, 0, rule, now)).To(Equal(5 * time.Second))
+
+		reconciler.forgetSourceWaits(secretCopier.Name)
+
+		Expect(reconciler.waitForSourceSecret(secretCopier, 1, rule, now)).To(Equal(5 * time.Second))
+	})
+
+	It("should list the rules waiting in the condition", func() {
+		ruleStatuses := []secretsv1beta1.SecretCopierRuleStatus{
+			{Name: "registry", SourceSecret: secretsv1beta1.SourceSecret{Name: "registry", Namespace: "source"}},
+			{SourceSecret: secretsv1beta1.SourceSecret{Name: "tls", Namespace: "source"}},
+			{SourceSecret: secretsv1beta1.SourceSecret{Name: "ca", Namespace: "source"}},
+		}
+
+		condition := waitingForSourceCondition(ruleStatuses, []int{0, 2}, 3)
+
+		Expect(condition.Type).To(Equal(secretsv1beta1.ConditionWaitingForSource))
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.ObservedGeneration).To(Equal(int64(3)))
+		Expect(condition.Reason).To(Equal("SourceSecretNotFound"))
+		Expect(condition.Message).To(Equal("Waiting for source secrets to be created: registry (source/registry), rule 2 (source/ca)"))
+	})
+})
@@ -0,0 +1,29 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Determine if a write of a target secret failed because it was based on a
+// stale copy of the target secret from the informer cache. A create fails if
+// the target secret was created since the cache was updated, and an update or
+// delete fails if the target secret was changed since.
+func staleCacheError(err error) bool {
+	return apierrors.IsAlreadyExists(err) || apierrors.IsConflict(err)
+}
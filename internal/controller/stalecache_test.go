@@ -0,0 +1,103 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Stale Cache Reads", func() {
+	ctx := context.Background()
+
+	secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "stale-copier"}}
+
+	rule := &secretsv1beta1.SecretCopierRule{
+		SourceSecret:  secretsv1beta1.SourceSecret{Name: "registry", Namespace: "source"},
+		ReclaimPolicy: secretsv1beta1.ReclaimRetain,
+	}
+
+	var live client.WithWatch
+	var cached client.Client
+
+	BeforeEach(func() {
+		live = fake.NewClientBuilder().WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "source"},
+				Data:       map[string][]byte{"password": []byte("rotated")},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "registry",
+					Namespace: "tenant-a",
+					Annotations: map[string]string{
+						"secrets-manager.advok8s.io/secret-copier": "stale-copier",
+						"secrets-manager.advok8s.io/secret-name":   "source/registry",
+					},
+				},
+				Data: map[string][]byte{"password": []byte("secret")},
+			},
+		).Build()
+
+		// Simulate an informer cache which hasn't yet seen the target secret.
+
+		cached = interceptor.NewClient(live, interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				if key.Namespace == "tenant-a" {
+					return apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, key.Name)
+				}
+
+				return c.Get(ctx, key, obj, opts...)
+			},
+		})
+	})
+
+	It("should fail the copy when there is no reader bypassing the cache", func() {
+		reconciler := &SecretCopierReconciler{Client: cached, Recorder: record.NewFakeRecorder(10)}
+
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant-a")
+
+		Expect(result).To(Equal(copyFailed))
+		Expect(staleCacheError(err)).To(BeTrue())
+	})
+
+	It("should retry the copy reading the target secret from the API server", func() {
+		reconciler := &SecretCopierReconciler{Client: cached, APIReader: live, Recorder: record.NewFakeRecorder(10)}
+
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant-a")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyUpdated))
+
+		targetSecret := &corev1.Secret{}
+		Expect(live.Get(ctx, client.ObjectKey{Namespace: "tenant-a", Name: "registry"}, targetSecret)).To(Succeed())
+		Expect(targetSecret.Data).To(HaveKeyWithValue("password", []byte("rotated")))
+	})
+})
@@ -0,0 +1,91 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Maximum number of denied target namespaces listed in the status of a rule,
+// so the status doesn't grow beyond the size limit for an object when
+// permission is denied in every target namespace.
+const maxDeniedNamespaces = 50
+
+// Put the status of a SecretCopier into a canonical form before it is saved.
+// Lists are sorted and capped so that the status only changes when the state
+// it reports does, rather than with the order in which target namespaces
+// happened to be processed, which would otherwise show up as churn when the
+// object is diffed by GitOps tools. Details the SecretCopier has asked not to
+// be reported are dropped, leaving only counts.
+func normalizeSecretCopierStatus(status *secretsv1beta1.SecretCopierStatus, detail secretsv1beta1.StatusDetail) {
+	for i := range status.Rules {
+		ruleStatus := &status.Rules[i]
+
+		sortConditions(ruleStatus.Conditions)
+
+		ruleStatus.DeniedNamespaces, ruleStatus.DeniedNamespacesOverflow = capDeniedNamespaces(ruleStatus.DeniedNamespaces)
+
+		if detail == secretsv1beta1.StatusDetailSummary {
+			ruleStatus.DeniedNamespacesOverflow += int32(len(ruleStatus.DeniedNamespaces))
+			ruleStatus.DeniedNamespaces = nil
+		}
+	}
+
+	if detail == secretsv1beta1.StatusDetailSummary {
+		status.FailedTargetsOverflow += int32(len(status.FailedTargets))
+		status.FailedTargets = nil
+	}
+
+	for i := range status.TargetClusters {
+		sortConditions(status.TargetClusters[i].Conditions)
+	}
+
+	sortConditions(status.Conditions)
+}
+
+// Sort conditions by type. Conditions are otherwise kept in the order they
+// were first set, which changes whenever a condition is removed and later set
+// again.
+func sortConditions(conditions []metav1.Condition) {
+	slices.SortStableFunc(conditions, func(a, b metav1.Condition) int {
+		return strings.Compare(a.Type, b.Type)
+	})
+}
+
+// Sort the denied target namespaces of a rule by namespace, returning the
+// capped list and the number of denied target namespaces which were left out.
+func capDeniedNamespaces(denied []secretsv1beta1.SecretCopierDeniedTarget) ([]secretsv1beta1.SecretCopierDeniedTarget, int32) {
+	slices.SortStableFunc(denied, func(a, b secretsv1beta1.SecretCopierDeniedTarget) int {
+		return cmp.Or(
+			strings.Compare(a.Namespace, b.Namespace),
+			strings.Compare(a.Verb, b.Verb),
+			strings.Compare(a.Resource, b.Resource),
+		)
+	})
+
+	if len(denied) > maxDeniedNamespaces {
+		return denied[:maxDeniedNamespaces], int32(len(denied) - maxDeniedNamespaces)
+	}
+
+	return denied, 0
+}
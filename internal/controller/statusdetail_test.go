@@ -0,0 +1,114 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Status Detail", func() {
+	newStatus := func() *secretsv1beta1.SecretCopierStatus {
+		return &secretsv1beta1.SecretCopierStatus{
+			Rules: []secretsv1beta1.SecretCopierRuleStatus{
+				{
+					DeniedNamespaces: []secretsv1beta1.SecretCopierDeniedTarget{
+						{Namespace: "tenant-b", Verb: "update", Resource: "secrets"},
+						{Namespace: "tenant-a", Verb: "update", Resource: "secrets"},
+					},
+					Conditions: []metav1.Condition{
+						{Type: secretsv1beta1.ConditionSynced},
+						{Type: secretsv1beta1.ConditionIgnored},
+					},
+				},
+			},
+			FailedTargets: []secretsv1beta1.SecretCopierFailedTarget{
+				{Namespace: "tenant-a"},
+				{Namespace: "tenant-b"},
+			},
+			FailedTargetsOverflow: 3,
+			Conditions: []metav1.Condition{
+				{Type: secretsv1beta1.ConditionReady},
+				{Type: secretsv1beta1.ConditionPermissionDenied},
+				{Type: secretsv1beta1.ConditionIgnored},
+			},
+		}
+	}
+
+	conditionTypes := func(conditions []metav1.Condition) []string {
+		types := make([]string, 0, len(conditions))
+
+		for _, condition := range conditions {
+			types = append(types, condition.Type)
+		}
+
+		return types
+	}
+
+	It("should sort lists reported in the full status", func() {
+		status := newStatus()
+
+		normalizeSecretCopierStatus(status, secretsv1beta1.StatusDetailFull)
+
+		Expect(conditionTypes(status.Conditions)).To(Equal([]string{"Ignored", "PermissionDenied", "Ready"}))
+		Expect(conditionTypes(status.Rules[0].Conditions)).To(Equal([]string{"Ignored", "Synced"}))
+
+		Expect(status.Rules[0].DeniedNamespaces[0].Namespace).To(Equal("tenant-a"))
+		Expect(status.Rules[0].DeniedNamespaces[1].Namespace).To(Equal("tenant-b"))
+		Expect(status.Rules[0].DeniedNamespacesOverflow).To(BeZero())
+
+		Expect(status.FailedTargets).To(HaveLen(2))
+		Expect(status.FailedTargetsOverflow).To(Equal(int32(3)))
+	})
+
+	It("should cap the denied target namespaces of a rule", func() {
+		status := newStatus()
+
+		status.Rules[0].DeniedNamespaces = nil
+
+		for i := maxDeniedNamespaces + 5; i > 0; i-- {
+			status.Rules[0].DeniedNamespaces = append(status.Rules[0].DeniedNamespaces, secretsv1beta1.SecretCopierDeniedTarget{
+				Namespace: fmt.Sprintf("tenant-%03d", i),
+			})
+		}
+
+		normalizeSecretCopierStatus(status, secretsv1beta1.StatusDetailFull)
+
+		Expect(status.Rules[0].DeniedNamespaces).To(HaveLen(maxDeniedNamespaces))
+		Expect(status.Rules[0].DeniedNamespaces[0].Namespace).To(Equal("tenant-001"))
+		Expect(status.Rules[0].DeniedNamespacesOverflow).To(Equal(int32(5)))
+	})
+
+	It("should only report counts in the summary status", func() {
+		status := newStatus()
+
+		normalizeSecretCopierStatus(status, secretsv1beta1.StatusDetailSummary)
+
+		Expect(status.Rules[0].DeniedNamespaces).To(BeNil())
+		Expect(status.Rules[0].DeniedNamespacesOverflow).To(Equal(int32(2)))
+
+		Expect(status.FailedTargets).To(BeNil())
+		Expect(status.FailedTargetsOverflow).To(Equal(int32(5)))
+
+		Expect(conditionTypes(status.Conditions)).To(Equal([]string{"Ignored", "PermissionDenied", "Ready"}))
+	})
+})
@@ -0,0 +1,93 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Reasons reported for when a rule is next due to be synced.
+const (
+	syncReasonSyncPeriod         = "SyncPeriod"
+	syncReasonWaitingForSource   = "WaitingForSource"
+	syncReasonCooldown           = "Cooldown"
+	syncReasonRollout            = "Rollout"
+	syncReasonTargetClusterRetry = "TargetClusterRetry"
+)
+
+// When a rule is next due to be synced, and why.
+type ruleSchedule struct {
+	after  time.Duration
+	reason string
+}
+
+// Bring the sync of the rule forward if the given delay is sooner than the
+// one already scheduled.
+func (s *ruleSchedule) sooner(after time.Duration, reason string) {
+	if after > 0 && (s.after <= 0 || after < s.after) {
+		s.after = after
+		s.reason = reason
+	}
+}
+
+// Determine the sync interval to report in the status of a rule. As the delay
+// until a rule is next synced counts down between reconciliations, and
+// updating the status triggers another reconciliation, the previous value is
+// kept unless the new interval differs from it by more than a quarter, and
+// intervals are reported to second precision, so that the status settles.
+func ruleSyncInterval(previous *metav1.Duration, current time.Duration) *metav1.Duration {
+	if current <= 0 {
+		return nil
+	}
+
+	current = max(current.Round(time.Second), time.Second)
+
+	if previous != nil {
+		difference := current - previous.Duration
+
+		if difference < 0 {
+			difference = -difference
+		}
+
+		if difference*4 <= previous.Duration {
+			return previous
+		}
+	}
+
+	return &metav1.Duration{Duration: current}
+}
+
+// Determine the time to report at which a SecretCopier is next scheduled to
+// be reconciled. When a reconcile is already scheduled sooner, as is the case
+// when the reconciliation was triggered by an update of the status, the
+// queue keeps the earlier time, so the previous value is kept. Times are
+// reported to second precision as that is how they are stored.
+func nextSyncTime(previous *metav1.Time, now time.Time, after time.Duration) *metav1.Time {
+	if after <= 0 {
+		return nil
+	}
+
+	next := now.Add(after).Truncate(time.Second)
+
+	if previous != nil && previous.Time.After(now) && !previous.Time.After(next) {
+		return previous
+	}
+
+	return &metav1.Time{Time: next}
+}
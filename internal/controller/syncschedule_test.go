@@ -0,0 +1,70 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Sync Schedule", func() {
+	It("should bring the sync of a rule forward to the soonest delay", func() {
+		schedule := ruleSchedule{after: 10 * time.Hour, reason: syncReasonSyncPeriod}
+
+		schedule.sooner(0, syncReasonCooldown)
+		Expect(schedule).To(Equal(ruleSchedule{after: 10 * time.Hour, reason: syncReasonSyncPeriod}))
+
+		schedule.sooner(5*time.Second, syncReasonWaitingForSource)
+		schedule.sooner(time.Minute, syncReasonRollout)
+		Expect(schedule).To(Equal(ruleSchedule{after: 5 * time.Second, reason: syncReasonWaitingForSource}))
+	})
+
+	It("should settle the reported sync interval of a rule", func() {
+		Expect(ruleSyncInterval(nil, 0)).To(BeNil())
+
+		interval := ruleSyncInterval(nil, 59900*time.Millisecond)
+		Expect(interval.Duration).To(Equal(time.Minute))
+
+		// A delay which has counted down a little is reported unchanged.
+
+		Expect(ruleSyncInterval(interval, 50*time.Second)).To(BeIdenticalTo(interval))
+
+		// A delay which differs by more than a quarter is reported.
+
+		Expect(ruleSyncInterval(interval, 10*time.Second).Duration).To(Equal(10 * time.Second))
+	})
+
+	It("should keep the next sync time when a sooner sync is already scheduled", func() {
+		now := time.Date(2024, 1, 1, 12, 0, 0, 500000000, time.UTC)
+
+		Expect(nextSyncTime(nil, now, 0)).To(BeNil())
+
+		next := nextSyncTime(nil, now, 10*time.Hour)
+		Expect(next.Time).To(Equal(time.Date(2024, 1, 1, 22, 0, 0, 0, time.UTC)))
+
+		Expect(nextSyncTime(next, now.Add(time.Second), 10*time.Hour)).To(BeIdenticalTo(next))
+
+		sooner := nextSyncTime(next, now, 5*time.Second)
+		Expect(sooner.Time).To(Equal(time.Date(2024, 1, 1, 12, 0, 5, 0, time.UTC)))
+
+		Expect(nextSyncTime(&metav1.Time{Time: now.Add(-time.Minute)}, now, time.Minute).Time).To(Equal(time.Date(2024, 1, 1, 12, 1, 0, 0, time.UTC)))
+	})
+})
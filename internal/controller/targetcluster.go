@@ -0,0 +1,435 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/internal/redact"
+)
+
+// Data key of the secret holding the kubeconfig for a target cluster, if the
+// reference to the secret doesn't give one.
+const defaultKubeconfigKey = "kubeconfig"
+
+// Timeout for requests made to a target cluster, so that a cluster which is
+// unreachable can't hold up the processing of other rules indefinitely.
+const targetClusterTimeout = 30 * time.Second
+
+// Clients for the remote clusters secrets are copied to, keyed by the secret
+// holding the kubeconfig for the cluster. A new client is created whenever the
+// secret changes.
+type targetClusterClients struct {
+	mutex   sync.Mutex
+	entries map[client.ObjectKey]targetClusterClient
+
+	// Backoff for target clusters found to be unreachable, keyed by the
+	// secret holding the kubeconfig for the cluster. It is cleared when a
+	// new client is created, so a corrected kubeconfig is tried straight
+	// away.
+	health targetBackoff
+
+	// Function creating a client from a REST config. If not set, a client
+	// which reads directly from the API server of the cluster is created.
+	newClient func(config *rest.Config, scheme *runtime.Scheme) (client.Client, error)
+}
+
+type targetClusterClient struct {
+	resourceVersion string
+	client          client.Client
+}
+
+// Return a client for the target cluster, reading the secret holding the
+// kubeconfig for the cluster using the given reader. Errors never include the
+// contents of the kubeconfig.
+func (c *targetClusterClients) get(ctx context.Context, reader client.Reader, scheme *runtime.Scheme, targetCluster *secretsv1beta1.TargetCluster) (targetClient client.Client, err error) {
+	reference := targetCluster.KubeconfigSecret
+
+	key := client.ObjectKey{Namespace: reference.Namespace, Name: reference.Name}
+
+	var secret corev1.Secret
+
+	if err := reader.Get(ctx, key, &secret); err != nil {
+		return nil, fmt.Errorf("unable to read kubeconfig secret %s: %w", key, err)
+	}
+
+	dataKey := reference.Key
+
+	if dataKey == "" {
+		dataKey = defaultKubeconfigKey
+	}
+
+	kubeconfig, found := secret.Data[dataKey]
+
+	if !found {
+		return nil, fmt.Errorf("kubeconfig secret %s has no key %s", key, dataKey)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if entry, found := c.entries[key]; found && entry.resourceVersion == secret.ResourceVersion {
+		return entry.client, nil
+	}
+
+	defer func() {
+		err = redact.Error(err, secret.Data)
+	}()
+
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig in secret %s: %w", key, err)
+	}
+
+	if config.Timeout == 0 {
+		config.Timeout = targetClusterTimeout
+	}
+
+	newClient := c.newClient
+
+	if newClient == nil {
+		newClient = func(config *rest.Config, scheme *runtime.Scheme) (client.Client, error) {
+			return client.New(config, client.Options{Scheme: scheme})
+		}
+	}
+
+	targetClient, err = newClient(config, scheme)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to create client for kubeconfig in secret %s: %w", key, err)
+	}
+
+	if c.entries == nil {
+		c.entries = make(map[client.ObjectKey]targetClusterClient)
+	}
+
+	c.entries[key] = targetClusterClient{
+		resourceVersion: secret.ResourceVersion,
+		client:          targetClient,
+	}
+
+	c.health.succeeded(key.String())
+
+	return targetClient, nil
+}
+
+// Return the clients currently held, keyed by the secret holding the
+// kubeconfig for the cluster.
+func (c *targetClusterClients) clients() map[client.ObjectKey]client.Client {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	clients := make(map[client.ObjectKey]client.Client, len(c.entries))
+
+	for key, entry := range c.entries {
+		clients[key] = entry.client
+	}
+
+	return clients
+}
+
+// Determine if a secret holds the kubeconfig for the target cluster of a
+// rule, or for a virtual cluster the rule copies into.
+func kubeconfigSecretMatches(rule *secretsv1beta1.SecretCopierRule, key client.ObjectKey) bool {
+	if isVirtualClusterKubeconfigSecret(rule, key) {
+		return true
+	}
+
+	if rule.TargetCluster == nil {
+		return false
+	}
+
+	reference := rule.TargetCluster.KubeconfigSecret
+
+	return reference.Namespace == key.Namespace && reference.Name == key.Name
+}
+
+// Copy the source secret of a rule to the matching namespaces of the remote
+// cluster the rule targets, recording the outcome in the status of the rule
+// and whether the remote cluster could be reached in the target cluster
+// states. Returns the target namespaces where copying failed, and whether any
+// writes were deferred by the secret write rate limit.
+func (r *SecretCopierReconciler) copyRuleToTargetCluster(ctx context.Context, secretsClient client.Client, secretCopier *secretsv1beta1.SecretCopier, ruleIndex int, rule *secretsv1beta1.SecretCopierRule, ruleStatus *secretsv1beta1.SecretCopierRuleStatus, outcome *ruleOutcome, clusters *targetClusterStates) ([]secretsv1beta1.SecretCopierFailedTarget, bool) {
+	log := log.FromContext(ctx)
+
+	targetClient, err := r.targetClusters.get(ctx, secretsClient, r.Scheme, rule.TargetCluster)
+
+	if err != nil {
+		log.Error(err, "Unable to create client for target cluster", "kubeconfigSecret", rule.TargetCluster.KubeconfigSecret)
+
+		ruleStatus.Message = fmt.Sprintf("Unable to access target cluster: %v", err)
+
+		return nil, false
+	}
+
+	// Don't make requests to a target cluster which was recently found to
+	// be unreachable until its backoff expires.
+
+	clusterKey := targetClusterKey(rule.TargetCluster)
+
+	if backingOff, err := r.targetClusterBackingOff(clusterKey, clusters, time.Now()); backingOff {
+		log.V(1).Info("Skipping target cluster which is unreachable", "kubeconfigSecret", rule.TargetCluster.KubeconfigSecret)
+
+		ruleStatus.Message = fmt.Sprintf("Target cluster is unreachable: %v", err)
+
+		return nil, false
+	}
+
+	var sourceSecret corev1.Secret
+
+	if err := secretsClient.Get(ctx, client.ObjectKey{Namespace: rule.SourceSecret.Namespace, Name: rule.SourceSecret.Name}, &sourceSecret); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Unable to fetch source secret", "sourceSecret", rule.SourceSecret)
+
+			ruleStatus.Message = fmt.Sprintf("Unable to read source secret %s/%s: %v", rule.SourceSecret.Namespace, rule.SourceSecret.Name, err)
+		} else {
+			ruleStatus.Message = fmt.Sprintf("Source secret %s/%s not found", rule.SourceSecret.Namespace, rule.SourceSecret.Name)
+		}
+
+		return nil, false
+	}
+
+	if r.Config.Settings().SecretTypeDenied(sourceSecret.Type) {
+		ruleStatus.Message = fmt.Sprintf("Source secret %s/%s is of type %s which is never copied", rule.SourceSecret.Namespace, rule.SourceSecret.Name, sourceSecret.Type)
+
+		return nil, false
+	}
+
+	// Target namespaces are matched against the namespaces of the target
+	// cluster. As the source namespace is in a different cluster, a target
+	// namespace of the same name is not excluded.
+
+	namespaces := &corev1.NamespaceList{}
+
+	err = targetClient.List(ctx, namespaces)
+
+	if !r.recordTargetClusterHealth(clusterKey, clusters, time.Now(), err) {
+		log.Error(err, "Target cluster is unreachable", "kubeconfigSecret", rule.TargetCluster.KubeconfigSecret)
+
+		ruleStatus.Message = fmt.Sprintf("Target cluster is unreachable: %v", err)
+
+		return nil, false
+	}
+
+	if err != nil {
+		log.Error(err, "Unable to list namespaces of target cluster", "kubeconfigSecret", rule.TargetCluster.KubeconfigSecret)
+
+		ruleStatus.Message = fmt.Sprintf("Unable to list namespaces of target cluster: %v", err)
+
+		return nil, false
+	}
+
+	matcher := rule.TargetNamespaces.Compile()
+
+	targetNamespaces := make([]string, 0)
+
+	for i := range namespaces.Items {
+		namespace := &namespaces.Items[i]
+
+		if !matcher.Matches(namespace) {
+			continue
+		}
+
+		if rule.SkipsNamespace(namespace) {
+			ruleStatus.SkippedNamespaces++
+			continue
+		}
+
+		if r.Config.Settings().NamespaceUnconsented(rule, namespace) {
+			ruleStatus.UnconsentedNamespaces++
+			continue
+		}
+
+		targetNamespaces = append(targetNamespaces, namespace.Name)
+	}
+
+	slices.Sort(targetNamespaces)
+
+	ruleStatus.MatchedNamespaces = int32(len(targetNamespaces))
+
+	if len(targetNamespaces) == 0 {
+		ruleStatus.Message = "No target namespaces matched in target cluster"
+
+		return nil, false
+	}
+
+	content, err := targetSecretContent(rule, &sourceSecret)
+
+	if err != nil {
+		log.Error(err, "Unable to render target secret from templates", "sourceSecret", rule.SourceSecret)
+
+		ruleStatus.Message = fmt.Sprintf("Unable to render target secret: %v", err)
+
+		return nil, false
+	}
+
+	var failedTargets []secretsv1beta1.SecretCopierFailedTarget
+
+	throttled := false
+
+	syncedNamespaces := ruleStatus.SyncedNamespaces
+
+	for _, targetNamespace := range targetNamespaces {
+		result, err := r.copySecretToTargetClusterNamespace(ctx, targetClient, secretCopier, rule, content, targetNamespace)
+
+		if failedTarget := r.recordTargetClusterCopy(secretCopier, ruleIndex, rule, ruleStatus, outcome, targetNamespace, result, err); failedTarget != nil {
+			failedTargets = append(failedTargets, *failedTarget)
+		}
+
+		throttled = throttled || result == copyThrottled
+	}
+
+	clusters.copied(clusterKey, int(ruleStatus.SyncedNamespaces-syncedNamespaces), len(failedTargets))
+
+	return failedTargets, throttled
+}
+
+// Record the outcome of copying the target secret of a rule to a namespace of
+// another cluster, counting it in the status of the rule. The namespace is
+// the one reported in the status and events. Returns the failed target if the
+// copy failed.
+func (r *SecretCopierReconciler) recordTargetClusterCopy(secretCopier *secretsv1beta1.SecretCopier, ruleIndex int, rule *secretsv1beta1.SecretCopierRule, ruleStatus *secretsv1beta1.SecretCopierRuleStatus, outcome *ruleOutcome, targetNamespace string, result copyResult, err error) *secretsv1beta1.SecretCopierFailedTarget {
+	outcome.copied(targetNamespace, result, err)
+
+	r.recordCopyEvent(secretCopier, rule, targetNamespace, targetSecretName(rule), result, err)
+
+	switch result {
+	case copyCreated, copyUpdated, copyUnchanged:
+		ruleStatus.SyncedNamespaces++
+	case copyThrottled, copyReportOnly:
+		ruleStatus.PendingNamespaces++
+	case copyIgnored:
+		ruleStatus.IgnoredNamespaces++
+	case copyFailed:
+		failedTarget := newFailedTarget(ruleIndex, targetNamespace, result, err)
+
+		countCopyFailure(secretCopier, failedTarget.Reason)
+
+		return &failedTarget
+	}
+
+	return nil
+}
+
+// Copy the target secret content of a rule to a namespace of the remote
+// cluster the rule targets. The target secret is marked in the same way as in
+// the local cluster, but as owner references can't refer to objects in
+// another cluster, it is never deleted when the SecretCopier is. Any values of
+// the source secret are masked in the returned error.
+func (r *SecretCopierReconciler) copySecretToTargetClusterNamespace(ctx context.Context, targetClient client.Client, secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, content *corev1.Secret, targetNamespace string) (result copyResult, err error) {
+	log := log.FromContext(ctx)
+
+	defer func() {
+		err = redact.Error(err, content.Data)
+	}()
+
+	name := targetSecretName(rule)
+
+	var targetSecret corev1.Secret
+
+	err = targetClient.Get(ctx, client.ObjectKey{Namespace: targetNamespace, Name: name}, &targetSecret)
+
+	if client.IgnoreNotFound(err) != nil {
+		log.Error(err, "Unable to fetch target secret in target cluster", "targetSecret", name, "targetNamespace", targetNamespace)
+		return copyFailed, err
+	}
+
+	// If the target secret does not exist, or has to be replaced because the
+	// type of the source secret has changed, create it.
+
+	exists := err == nil
+
+	if exists {
+		if r.targetSecretIgnoredBySecretCopier(secretCopier, rule, &targetSecret) {
+			return copyIgnored, nil
+		}
+
+		if !r.targetSecretManagedBySecretCopier(secretCopier, rule, &targetSecret) {
+			log.V(1).Info("Skipping update of target secret in target cluster as not managed by SecretCopier", "targetSecret", name, "targetNamespace", targetNamespace)
+			return copySkipped, nil
+		}
+
+		if !r.sourceSecretHasBeenUpdated(rule, content, &targetSecret) {
+			return copyUnchanged, nil
+		}
+	}
+
+	desired := r.newTargetSecret(secretCopier, rule, content, name, targetNamespace)
+
+	desired.OwnerReferences = nil
+
+	if err := r.checkTargetSecretSize(secretCopier, &desired); err != nil {
+		return copyFailed, err
+	}
+
+	if r.writesSuspended() {
+		return copyReportOnly, nil
+	}
+
+	if !r.allowSecretWrite() {
+		return copyThrottled, nil
+	}
+
+	switch {
+	case !exists:
+		err = targetClient.Create(ctx, &desired)
+
+		result = copyCreated
+
+	case targetSecret.Type != desired.Type:
+		if err = targetClient.Delete(ctx, &targetSecret); client.IgnoreNotFound(err) == nil {
+			err = targetClient.Create(ctx, &desired)
+		}
+
+		result = copyUpdated
+
+	default:
+		targetSecret.Labels = desired.Labels
+
+		applyTargetSecretAnnotations(rule, &targetSecret)
+
+		setTargetSecretMarkers(&targetSecret, secretCopier.Name, rule.SourceSecret)
+
+		targetSecret.Data = desired.Data
+
+		err = targetClient.Update(ctx, &targetSecret)
+
+		result = copyUpdated
+	}
+
+	if err != nil {
+		log.Error(err, "Unable to write target secret in target cluster", "targetSecret", name, "targetNamespace", targetNamespace)
+		return copyFailed, err
+	}
+
+	log.V(1).Info("Wrote target secret in target cluster", "targetSecret", name, "targetNamespace", targetNamespace)
+
+	return result, nil
+}
@@ -0,0 +1,385 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+var _ = Describe("Target Clusters", func() {
+	ctx := context.Background()
+
+	const kubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: spoke
+  cluster:
+    server: https://spoke.example.com:6443
+contexts:
+- name: spoke
+  context:
+    cluster: spoke
+    user: spoke
+current-context: spoke
+users:
+- name: spoke
+  user:
+    token: spoke-token
+`
+
+	secretCopier := &secretsv1beta1.SecretCopier{
+		ObjectMeta: metav1.ObjectMeta{Name: "fleet-copier", UID: "fleet-uid"},
+	}
+
+	rule := &secretsv1beta1.SecretCopierRule{
+		SourceSecret:  secretsv1beta1.SourceSecret{Name: "wildcard-tls", Namespace: "certificates"},
+		ReclaimPolicy: secretsv1beta1.ReclaimDelete,
+		TargetCluster: &secretsv1beta1.TargetCluster{
+			KubeconfigSecret: secretsv1beta1.KubeconfigSecretReference{Name: "spoke", Namespace: "fleet"},
+		},
+		TargetNamespaces: selectors.TargetNamespaces{
+			NameSelector: selectors.NameSelector{MatchNames: []string{"ingress-*"}},
+		},
+	}
+
+	newLocalClient := func() client.Client {
+		return fake.NewClientBuilder().WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "spoke", Namespace: "fleet", ResourceVersion: "1"},
+				Data:       map[string][]byte{"kubeconfig": []byte(kubeconfig)},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "wildcard-tls", Namespace: "certificates"},
+				Type:       corev1.SecretTypeOpaque,
+				Data:       map[string][]byte{"tls.crt": []byte("certificate")},
+			},
+		).Build()
+	}
+
+	newRemoteClient := func(objects ...client.Object) client.Client {
+		objects = append(objects,
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ingress-a"}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ingress-b"}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		)
+
+		return fake.NewClientBuilder().WithObjects(objects...).Build()
+	}
+
+	newReconciler := func(localClient client.Client, remoteClient client.Client) *SecretCopierReconciler {
+		reconciler := &SecretCopierReconciler{Client: localClient, Recorder: record.NewFakeRecorder(10)}
+
+		reconciler.targetClusters.newClient = func(config *rest.Config, scheme *runtime.Scheme) (client.Client, error) {
+			Expect(config.Host).To(Equal("https://spoke.example.com:6443"))
+			Expect(config.BearerToken).To(Equal("spoke-token"))
+
+			return remoteClient, nil
+		}
+
+		return reconciler
+	}
+
+	It("should copy the source secret to matching namespaces of the remote cluster", func() {
+		localClient := newLocalClient()
+		remoteClient := newRemoteClient()
+
+		reconciler := newReconciler(localClient, remoteClient)
+
+		ruleStatus := secretsv1beta1.SecretCopierRuleStatus{}
+		outcome := ruleOutcome{}
+
+		failedTargets, throttled := reconciler.copyRuleToTargetCluster(ctx, localClient, secretCopier, 0, rule, &ruleStatus, &outcome, &targetClusterStates{})
+
+		Expect(failedTargets).To(BeEmpty())
+		Expect(throttled).To(BeFalse())
+
+		Expect(ruleStatus.MatchedNamespaces).To(Equal(int32(2)))
+		Expect(ruleStatus.SyncedNamespaces).To(Equal(int32(2)))
+		Expect(outcome.created).To(Equal(int64(2)))
+
+		var targetSecret corev1.Secret
+
+		Expect(remoteClient.Get(ctx, client.ObjectKey{Namespace: "ingress-a", Name: "wildcard-tls"}, &targetSecret)).To(Succeed())
+
+		Expect(targetSecret.Data).To(HaveKeyWithValue("tls.crt", []byte("certificate")))
+		Expect(targetSecret.OwnerReferences).To(BeEmpty())
+		Expect(targetSecret.Annotations).To(HaveKeyWithValue("secrets-manager.advok8s.io/secret-copier", "fleet-copier"))
+
+		// A second pass finds the target secrets in sync.
+
+		outcome = ruleOutcome{}
+
+		_, _ = reconciler.copyRuleToTargetCluster(ctx, localClient, secretCopier, 0, rule, &ruleStatus, &outcome, &targetClusterStates{})
+
+		Expect(outcome.created).To(BeZero())
+		Expect(outcome.updated).To(BeZero())
+	})
+
+	It("should not update secrets in the remote cluster which it doesn't manage", func() {
+		localClient := newLocalClient()
+		remoteClient := newRemoteClient(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "wildcard-tls", Namespace: "ingress-a"},
+			Data:       map[string][]byte{"tls.crt": []byte("local")},
+		})
+
+		reconciler := newReconciler(localClient, remoteClient)
+
+		result, err := reconciler.copySecretToTargetClusterNamespace(ctx, remoteClient, secretCopier, rule, &corev1.Secret{
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{"tls.crt": []byte("certificate")},
+		}, "ingress-a")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copySkipped))
+	})
+
+	It("should report a kubeconfig secret which can't be used", func() {
+		localClient := fake.NewClientBuilder().WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "spoke", Namespace: "fleet"},
+			Data:       map[string][]byte{"config": []byte(kubeconfig)},
+		}).Build()
+
+		reconciler := newReconciler(localClient, newRemoteClient())
+
+		ruleStatus := secretsv1beta1.SecretCopierRuleStatus{}
+
+		_, _ = reconciler.copyRuleToTargetCluster(ctx, localClient, secretCopier, 0, rule, &ruleStatus, &ruleOutcome{}, &targetClusterStates{})
+
+		Expect(ruleStatus.Message).To(Equal("Unable to access target cluster: kubeconfig secret fleet/spoke has no key kubeconfig"))
+	})
+
+	It("should back off from a remote cluster which can't be reached", func() {
+		localClient := newLocalClient()
+
+		lists := 0
+
+		remoteClient := interceptor.NewClient(newRemoteClient().(client.WithWatch), interceptor.Funcs{
+			List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+				lists++
+				return errors.New("dial tcp 192.0.2.1:6443: connect: connection refused")
+			},
+		})
+
+		reconciler := newReconciler(localClient, remoteClient)
+
+		ruleStatus := secretsv1beta1.SecretCopierRuleStatus{}
+		clusters := targetClusterStates{}
+
+		_, _ = reconciler.copyRuleToTargetCluster(ctx, localClient, secretCopier, 0, rule, &ruleStatus, &ruleOutcome{}, &clusters)
+
+		Expect(ruleStatus.Message).To(Equal("Target cluster is unreachable: dial tcp 192.0.2.1:6443: connect: connection refused"))
+		Expect(lists).To(Equal(1))
+
+		Expect(clusters.unreachableClusters()).To(Equal([]string{"fleet/spoke"}))
+		Expect(clusters.retryDelay(client.ObjectKey{Namespace: "fleet", Name: "spoke"}, time.Now())).To(BeNumerically("~", targetClusterInitialBackoff, time.Second))
+
+		statuses := clusters.statuses(nil, 1)
+
+		Expect(statuses).To(HaveLen(1))
+		Expect(statuses[0].KubeconfigSecret).To(Equal("fleet/spoke"))
+		Expect(statuses[0].NextRetryTime).NotTo(BeNil())
+		Expect(statuses[0].Conditions).To(ConsistOf(HaveField("Reason", "Unreachable")))
+
+		// While backing off, no request is made to the remote cluster.
+
+		ruleStatus = secretsv1beta1.SecretCopierRuleStatus{}
+		clusters = targetClusterStates{}
+
+		_, _ = reconciler.copyRuleToTargetCluster(ctx, localClient, secretCopier, 0, rule, &ruleStatus, &ruleOutcome{}, &clusters)
+
+		Expect(ruleStatus.Message).To(Equal("Target cluster is unreachable: dial tcp 192.0.2.1:6443: connect: connection refused"))
+		Expect(lists).To(Equal(1))
+		Expect(clusters.unreachableClusters()).To(Equal([]string{"fleet/spoke"}))
+
+		// The status of a reachable remote cluster keeps the conditions
+		// previously reported for it.
+
+		reachable := targetClusterStates{}
+		reachable.reachable(client.ObjectKey{Namespace: "fleet", Name: "spoke"})
+
+		statuses = reachable.statuses(statuses, 1)
+
+		Expect(statuses[0].NextRetryTime).To(BeNil())
+		Expect(statuses[0].Conditions).To(ConsistOf(And(HaveField("Reason", "Reachable"), HaveField("Status", metav1.ConditionTrue))))
+		Expect(reachable.unreachableClusters()).To(BeEmpty())
+	})
+
+	It("should export metrics for each remote cluster", func() {
+		localClient := newLocalClient()
+
+		unreachable := false
+
+		remoteClient := interceptor.NewClient(newRemoteClient().(client.WithWatch), interceptor.Funcs{
+			List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+				if unreachable {
+					return errors.New("dial tcp 192.0.2.1:6443: connect: connection refused")
+				}
+				return c.List(ctx, list, opts...)
+			},
+		})
+
+		reconciler := newReconciler(localClient, remoteClient)
+
+		metricsCopier := &secretsv1beta1.SecretCopier{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-metrics-copier", UID: "cluster-metrics-uid"},
+		}
+
+		now := time.Unix(1700000000, 0)
+
+		clusters := targetClusterStates{}
+
+		_, _ = reconciler.copyRuleToTargetCluster(ctx, localClient, metricsCopier, 0, rule, &secretsv1beta1.SecretCopierRuleStatus{}, &ruleOutcome{}, &clusters)
+
+		clusters.recordMetrics(metricsCopier.Name, nil, now)
+
+		Expect(testutil.ToFloat64(targetClusterSecretsSynced.WithLabelValues("cluster-metrics-copier", "fleet/spoke"))).To(Equal(2.0))
+		Expect(testutil.ToFloat64(targetClusterLastSyncTimestamp.WithLabelValues("cluster-metrics-copier", "fleet/spoke"))).To(Equal(1700000000.0))
+		Expect(testutil.ToFloat64(targetClusterSyncFailuresTotal.WithLabelValues("cluster-metrics-copier", "fleet/spoke"))).To(BeZero())
+
+		// When the remote cluster can't be reached, the failure is counted
+		// while the time of the last sync is left as it was.
+
+		unreachable = true
+
+		clusters = targetClusterStates{}
+
+		_, _ = reconciler.copyRuleToTargetCluster(ctx, localClient, metricsCopier, 0, rule, &secretsv1beta1.SecretCopierRuleStatus{}, &ruleOutcome{}, &clusters)
+
+		clusters.recordMetrics(metricsCopier.Name, nil, now.Add(time.Minute))
+
+		Expect(testutil.ToFloat64(targetClusterSyncFailuresTotal.WithLabelValues("cluster-metrics-copier", "fleet/spoke"))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(targetClusterLastSyncTimestamp.WithLabelValues("cluster-metrics-copier", "fleet/spoke"))).To(Equal(1700000000.0))
+
+		// A skipped attempt while backing off isn't counted as a failure.
+
+		clusters = targetClusterStates{}
+
+		_, _ = reconciler.copyRuleToTargetCluster(ctx, localClient, metricsCopier, 0, rule, &secretsv1beta1.SecretCopierRuleStatus{}, &ruleOutcome{}, &clusters)
+
+		statuses := clusters.statuses(nil, 1)
+
+		clusters.recordMetrics(metricsCopier.Name, nil, now.Add(2*time.Minute))
+
+		Expect(testutil.ToFloat64(targetClusterSyncFailuresTotal.WithLabelValues("cluster-metrics-copier", "fleet/spoke"))).To(Equal(1.0))
+
+		// Once no rule copies to the remote cluster, its metrics are removed.
+
+		clusters = targetClusterStates{}
+
+		clusters.recordMetrics(metricsCopier.Name, statuses, now.Add(3*time.Minute))
+
+		Expect(targetClusterSecretsSynced.Delete(prometheus.Labels{"secretcopier": "cluster-metrics-copier", "cluster": "fleet/spoke"})).To(BeFalse())
+		Expect(targetClusterSyncFailuresTotal.Delete(prometheus.Labels{"secretcopier": "cluster-metrics-copier", "cluster": "fleet/spoke"})).To(BeFalse())
+	})
+
+	It("should not back off from a remote cluster which refuses a request", func() {
+		localClient := newLocalClient()
+
+		remoteClient := interceptor.NewClient(newRemoteClient().(client.WithWatch), interceptor.Funcs{
+			List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+				return apierrors.NewForbidden(corev1.Resource("namespaces"), "", errors.New("not permitted"))
+			},
+		})
+
+		reconciler := newReconciler(localClient, remoteClient)
+
+		ruleStatus := secretsv1beta1.SecretCopierRuleStatus{}
+		clusters := targetClusterStates{}
+
+		_, _ = reconciler.copyRuleToTargetCluster(ctx, localClient, secretCopier, 0, rule, &ruleStatus, &ruleOutcome{}, &clusters)
+
+		Expect(ruleStatus.Message).To(HavePrefix("Unable to list namespaces of target cluster: "))
+		Expect(clusters.unreachableClusters()).To(BeEmpty())
+
+		ready, _ := reconciler.targetClusters.health.ready("fleet/spoke", time.Now())
+
+		Expect(ready).To(BeTrue())
+	})
+
+	It("should reconcile SecretCopiers when the health of a remote cluster changes", func() {
+		localClient := newLocalClient()
+
+		unreachable := true
+
+		remoteClient := interceptor.NewClient(newRemoteClient().(client.WithWatch), interceptor.Funcs{
+			List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+				if unreachable {
+					return errors.New("dial tcp 192.0.2.1:6443: i/o timeout")
+				}
+				return c.List(ctx, list, opts...)
+			},
+		})
+
+		reconciler := newReconciler(localClient, remoteClient)
+		reconciler.targetClusterChanges = make(chan event.GenericEvent, 10)
+
+		_, err := reconciler.targetClusters.get(ctx, localClient, reconciler.Scheme, rule.TargetCluster)
+		Expect(err).NotTo(HaveOccurred())
+
+		reconciler.probeTargetClusters(ctx)
+
+		Expect(reconciler.targetClusterChanges).To(Receive(HaveField("Object.GetName()", "spoke")))
+
+		// While still unreachable, only the first failure is reported, and
+		// a probe isn't made until the backoff expires.
+
+		reconciler.probeTargetClusters(ctx)
+
+		Expect(reconciler.targetClusterChanges).NotTo(Receive())
+
+		reconciler.targetClusters.health.failed("fleet/spoke", time.Now().Add(-time.Hour), time.Minute, time.Minute, nil)
+
+		reconciler.probeTargetClusters(ctx)
+
+		Expect(reconciler.targetClusterChanges).NotTo(Receive())
+
+		// Once the remote cluster can be reached again, the change is
+		// reported and the backoff cleared.
+
+		reconciler.targetClusters.health.failed("fleet/spoke", time.Now().Add(-time.Hour), time.Minute, time.Minute, nil)
+
+		unreachable = false
+
+		reconciler.probeTargetClusters(ctx)
+
+		Expect(reconciler.targetClusterChanges).To(Receive(HaveField("Object.GetNamespace()", "fleet")))
+
+		_, backingOff := reconciler.targetClusters.health.retryTime("fleet/spoke")
+
+		Expect(backingOff).To(BeFalse())
+	})
+})
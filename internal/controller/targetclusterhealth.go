@@ -0,0 +1,357 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Delay before a target cluster found to be unreachable is tried again. The
+// delay doubles each time the target cluster is still unreachable, up to the
+// maximum delay.
+const (
+	targetClusterInitialBackoff = 5 * time.Second
+	targetClusterMaxBackoff     = 5 * time.Minute
+)
+
+// Timeout for the request made to a target cluster when checking whether it
+// can be reached.
+const targetClusterProbeTimeout = 10 * time.Second
+
+// Key of the secret holding the kubeconfig for a target cluster, under which
+// the client and backoff for the target cluster are tracked.
+func targetClusterKey(targetCluster *secretsv1beta1.TargetCluster) client.ObjectKey {
+	return client.ObjectKey{Namespace: targetCluster.KubeconfigSecret.Namespace, Name: targetCluster.KubeconfigSecret.Name}
+}
+
+// Determine if an error from a request to a target cluster means the target
+// cluster couldn't be reached, rather than that the request was refused.
+// Errors which aren't a status returned by the API server, such as a failure
+// to connect, are treated as the target cluster being unreachable, as are
+// timeouts and the API server being unavailable.
+func targetClusterUnreachable(err error) bool {
+	var status apierrors.APIStatus
+
+	if !errors.As(err, &status) {
+		return true
+	}
+
+	return apierrors.IsTimeout(err) || apierrors.IsServerTimeout(err) || apierrors.IsServiceUnavailable(err)
+}
+
+// Whether the target clusters of the rules of a SecretCopier could be reached
+// in a reconciliation, keyed by the secret holding the kubeconfig for the
+// target cluster. The zero value is ready to use.
+type targetClusterStates struct {
+	entries map[client.ObjectKey]*targetClusterState
+}
+
+type targetClusterState struct {
+	err     error
+	retryAt time.Time
+
+	// Whether a request was made to the target cluster, rather than it
+	// being skipped while backing off.
+	attempted bool
+
+	// Number of target namespaces where the target secret is in sync, and
+	// number of failures copying to the target cluster.
+	synced   int
+	failures int
+}
+
+// Return the state of the target cluster, adding it if not already present.
+func (s *targetClusterStates) state(key client.ObjectKey) *targetClusterState {
+	if s.entries == nil {
+		s.entries = make(map[client.ObjectKey]*targetClusterState)
+	}
+
+	state, found := s.entries[key]
+
+	if !found {
+		state = &targetClusterState{}
+		s.entries[key] = state
+	}
+
+	return state
+}
+
+// Record that the target cluster could be reached. A target cluster found to
+// be unreachable by another rule remains unreachable.
+func (s *targetClusterStates) reachable(key client.ObjectKey) {
+	s.state(key).attempted = true
+}
+
+// Record that the target cluster couldn't be reached, and when it will next
+// be tried. The failure is counted if a request was made to the target
+// cluster.
+func (s *targetClusterStates) unreachable(key client.ObjectKey, err error, retryAt time.Time, attempted bool) {
+	state := s.state(key)
+
+	state.err = err
+	state.retryAt = retryAt
+
+	if attempted {
+		state.attempted = true
+		state.failures++
+	}
+}
+
+// Record the number of target namespaces of the target cluster where the
+// target secret of a rule is in sync, and where copying failed.
+func (s *targetClusterStates) copied(key client.ObjectKey, synced int, failed int) {
+	state := s.state(key)
+
+	state.synced += synced
+	state.failures += failed
+}
+
+// Update the metrics for each target cluster of a SecretCopier. The number of
+// target secrets in sync is only updated when the target cluster was reached,
+// and the time of the last sync only when there were no failures. Metrics for
+// target clusters in the previous status which no rule copies to any longer
+// are removed.
+func (s *targetClusterStates) recordMetrics(secretCopierName string, previous []secretsv1beta1.SecretCopierTargetClusterStatus, now time.Time) {
+	for key, state := range s.entries {
+		cluster := key.String()
+
+		if state.failures > 0 {
+			targetClusterSyncFailuresTotal.WithLabelValues(secretCopierName, cluster).Add(float64(state.failures))
+		}
+
+		if state.err != nil || !state.attempted {
+			continue
+		}
+
+		targetClusterSecretsSynced.WithLabelValues(secretCopierName, cluster).Set(float64(state.synced))
+
+		if state.failures == 0 {
+			targetClusterLastSyncTimestamp.WithLabelValues(secretCopierName, cluster).Set(float64(now.Unix()))
+		}
+	}
+
+	current := make(map[string]bool, len(s.entries))
+
+	for key := range s.entries {
+		current[key.String()] = true
+	}
+
+	for _, previousStatus := range previous {
+		if !current[previousStatus.KubeconfigSecret] {
+			deleteTargetClusterMetrics(secretCopierName, previousStatus.KubeconfigSecret)
+		}
+	}
+}
+
+// Return how long until the target cluster will next be tried, or zero if it
+// could be reached.
+func (s *targetClusterStates) retryDelay(key client.ObjectKey, now time.Time) time.Duration {
+	state, found := s.entries[key]
+
+	if !found || state.err == nil {
+		return 0
+	}
+
+	return max(state.retryAt.Sub(now), time.Second)
+}
+
+// Construct the status of each target cluster, ordered by kubeconfig secret.
+// The conditions of the previous status of a target cluster are carried
+// over, so the transition time of the Ready condition is only updated when
+// the target cluster becomes reachable or unreachable.
+func (s *targetClusterStates) statuses(previous []secretsv1beta1.SecretCopierTargetClusterStatus, generation int64) []secretsv1beta1.SecretCopierTargetClusterStatus {
+	if len(s.entries) == 0 {
+		return nil
+	}
+
+	statuses := make([]secretsv1beta1.SecretCopierTargetClusterStatus, 0, len(s.entries))
+
+	for key, state := range s.entries {
+		status := secretsv1beta1.SecretCopierTargetClusterStatus{KubeconfigSecret: key.String()}
+
+		for _, previousStatus := range previous {
+			if previousStatus.KubeconfigSecret == status.KubeconfigSecret {
+				status.Conditions = slices.Clone(previousStatus.Conditions)
+			}
+		}
+
+		if state.err == nil {
+			meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+				Type:               secretsv1beta1.ConditionReady,
+				Status:             metav1.ConditionTrue,
+				ObservedGeneration: generation,
+				Reason:             "Reachable",
+				Message:            "Target cluster is reachable",
+			})
+		} else {
+			status.NextRetryTime = &metav1.Time{Time: state.retryAt.Truncate(time.Second)}
+
+			meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+				Type:               secretsv1beta1.ConditionReady,
+				Status:             metav1.ConditionFalse,
+				ObservedGeneration: generation,
+				Reason:             "Unreachable",
+				Message:            fmt.Sprintf("Target cluster is unreachable: %v", state.err),
+			})
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	slices.SortFunc(statuses, func(a, b secretsv1beta1.SecretCopierTargetClusterStatus) int {
+		return strings.Compare(a.KubeconfigSecret, b.KubeconfigSecret)
+	})
+
+	return statuses
+}
+
+// Return the kubeconfig secrets of the target clusters which couldn't be
+// reached, ordered by name.
+func (s *targetClusterStates) unreachableClusters() []string {
+	var unreachable []string
+
+	for key, state := range s.entries {
+		if state.err != nil {
+			unreachable = append(unreachable, key.String())
+		}
+	}
+
+	slices.Sort(unreachable)
+
+	return unreachable
+}
+
+// Construct the TargetClusterUnreachable condition listing the target
+// clusters which couldn't be reached.
+func targetClusterUnreachableCondition(unreachable []string, generation int64) metav1.Condition {
+	return metav1.Condition{
+		Type:               secretsv1beta1.ConditionTargetClusterUnreachable,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "Unreachable",
+		Message:            "Target clusters with kubeconfig secrets are unreachable: " + strings.Join(unreachable, ", "),
+	}
+}
+
+// Check whether a target cluster is backing off after being found to be
+// unreachable. If it is, the copy is not attempted, with the error from the
+// last attempt and the time of the next attempt being recorded instead, so
+// that an unreachable target cluster doesn't hold up every reconciliation
+// until the request to it times out.
+func (r *SecretCopierReconciler) targetClusterBackingOff(key client.ObjectKey, clusters *targetClusterStates, now time.Time) (bool, error) {
+	ready, err := r.targetClusters.health.ready(key.String(), now)
+
+	if ready {
+		return false, nil
+	}
+
+	retryAt, _ := r.targetClusters.health.retryTime(key.String())
+
+	clusters.unreachable(key, err, retryAt, false)
+
+	return true, err
+}
+
+// Record the outcome of a request to a target cluster. If the target cluster
+// couldn't be reached, it is backed off so following reconciliations don't
+// try it again until the delay expires. Returns whether the target cluster
+// was reachable.
+func (r *SecretCopierReconciler) recordTargetClusterHealth(key client.ObjectKey, clusters *targetClusterStates, now time.Time, err error) bool {
+	if err != nil && targetClusterUnreachable(err) {
+		delay := r.targetClusters.health.failed(key.String(), now, targetClusterInitialBackoff, targetClusterMaxBackoff, err)
+
+		clusters.unreachable(key, err, now.Add(delay), true)
+
+		return false
+	}
+
+	r.targetClusters.health.succeeded(key.String())
+
+	clusters.reachable(key)
+
+	return true
+}
+
+// Periodically check whether the target clusters for which clients are held
+// can be reached. Implements manager.Runnable through manager.RunnableFunc,
+// so only runs when elected leader.
+func (r *SecretCopierReconciler) checkTargetClusterHealth(ctx context.Context) error {
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithName("target-cluster-health"))
+
+	wait.UntilWithContext(ctx, r.probeTargetClusters, r.TargetClusterHealthInterval)
+
+	return nil
+}
+
+// Probe each target cluster for which a client is held, skipping those still
+// backing off. When a target cluster becomes unreachable or reachable again,
+// the SecretCopiers which copy to it are reconciled so their status reflects
+// the change, and so copying resumes as soon as the target cluster is back.
+func (r *SecretCopierReconciler) probeTargetClusters(ctx context.Context) {
+	log := log.FromContext(ctx)
+
+	for key, targetClient := range r.targetClusters.clients() {
+		now := time.Now()
+
+		if ready, _ := r.targetClusters.health.ready(key.String(), now); !ready {
+			continue
+		}
+
+		_, wasUnreachable := r.targetClusters.health.retryTime(key.String())
+
+		probeCtx, cancel := context.WithTimeout(ctx, targetClusterProbeTimeout)
+
+		err := targetClient.List(probeCtx, &corev1.NamespaceList{}, client.Limit(1))
+
+		cancel()
+
+		var clusters targetClusterStates
+
+		reachable := r.recordTargetClusterHealth(key, &clusters, now, err)
+
+		switch {
+		case reachable && wasUnreachable:
+			log.Info("Target cluster is reachable again", "kubeconfigSecret", key)
+		case !reachable && !wasUnreachable:
+			log.Error(err, "Target cluster is unreachable", "kubeconfigSecret", key)
+		default:
+			continue
+		}
+
+		select {
+		case r.targetClusterChanges <- event.GenericEvent{Object: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
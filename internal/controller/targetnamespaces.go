@@ -0,0 +1,222 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+// Outcome of checking whether a namespace is a target namespace the source
+// secret of a rule should be copied to. The outcomes after
+// targetNamespaceUnconsented are for namespaces which count as matched by
+// the rule, even if the secret can't yet be copied to them.
+type targetNamespaceCheck int
+
+const (
+	// The namespace is the source namespace or isn't matched by the
+	// selectors of the rule.
+	targetNamespaceUnmatched targetNamespaceCheck = iota
+
+	// The namespace was created by vcluster and the rule copies into the
+	// virtual cluster it holds instead.
+	targetNamespaceVirtualCluster
+
+	// The manager is configured to never copy to the namespace.
+	targetNamespaceDenied
+
+	// The namespace is terminating, being decommissioned, or was created by
+	// vcluster and the rule doesn't target such namespaces.
+	targetNamespaceSkipped
+
+	// The namespace is outside of the scope delegated for the source
+	// namespace.
+	targetNamespaceUndelegated
+
+	// Consent is required and the namespace hasn't accepted copies.
+	targetNamespaceUnconsented
+
+	// The namespace doesn't have the readiness signal required by the rule.
+	targetNamespaceNotReady
+
+	// The namespace doesn't hold the resource required by the rule, so any
+	// copy already made there should be removed.
+	targetNamespaceLacksRequiredResource
+
+	// The source secret should be copied to the namespace.
+	targetNamespaceEligible
+)
+
+// Determine whether a namespace is a target namespace of a rule. This is the
+// single place deciding which namespaces a rule copies to, used when
+// reconciling a SecretCopier, applying it once, working out a copy plan and
+// copying into newly created namespaces, so they all agree. An error is
+// returned along with targetNamespaceNotReady if the readiness of the
+// namespace or the presence of the required resource couldn't be determined.
+func (r *SecretCopierReconciler) checkTargetNamespace(ctx context.Context, settings *ManagerSettings, delegations delegationScopes, rule *secretsv1beta1.SecretCopierRule, matcher *selectors.TargetNamespacesMatcher, namespace *corev1.Namespace) (targetNamespaceCheck, error) {
+	if namespace.Name == rule.SourceSecret.Namespace || !matcher.Matches(namespace) {
+		return targetNamespaceUnmatched, nil
+	}
+
+	if rule.CopiesIntoVirtualCluster(namespace) {
+		return targetNamespaceVirtualCluster, nil
+	}
+
+	if settings.NamespaceDenied(namespace.Name) {
+		return targetNamespaceDenied, nil
+	}
+
+	if rule.SkipsNamespace(namespace) {
+		return targetNamespaceSkipped, nil
+	}
+
+	// Never copy outside of the scope delegated for the source namespace,
+	// whatever the selectors of the rule match.
+
+	if !delegations.permits(rule.SourceSecret.Namespace, namespace) {
+		return targetNamespaceUndelegated, nil
+	}
+
+	// Where consent is required, only copy to namespaces whose owners have
+	// annotated them as accepting copies.
+
+	if settings.NamespaceUnconsented(rule, namespace) {
+		return targetNamespaceUnconsented, nil
+	}
+
+	// Hold off copying to the namespace if the rule requires a readiness
+	// signal the namespace doesn't yet have, or a resource it doesn't hold.
+
+	ready, err := r.targetNamespaceReady(ctx, rule, namespace)
+
+	if err != nil || !ready {
+		return targetNamespaceNotReady, err
+	}
+
+	present, err := r.requiredResourcePresent(ctx, rule, namespace.Name)
+
+	if err != nil {
+		return targetNamespaceNotReady, err
+	}
+
+	if !present {
+		return targetNamespaceLacksRequiredResource, nil
+	}
+
+	return targetNamespaceEligible, nil
+}
+
+// A namespace matched as a target namespace by a rule.
+type matchedTargetNamespace struct {
+	// The namespace.
+	namespace *corev1.Namespace
+
+	// One of targetNamespaceNotReady, targetNamespaceLacksRequiredResource
+	// or targetNamespaceEligible.
+	check targetNamespaceCheck
+
+	// Error determining whether the namespace is ready.
+	err error
+}
+
+// Determine whether the source secret should be copied to the namespace.
+func (t *matchedTargetNamespace) ready() bool {
+	return t.check == targetNamespaceEligible
+}
+
+// Target namespaces of a rule as selected from a list of namespaces.
+type targetNamespaceSelection struct {
+	// Namespaces matched by the rule, in the order of the list, including
+	// those the source secret can't yet be copied to.
+	matched []matchedTargetNamespace
+
+	// Number of namespaces matched which aren't ready to be copied to.
+	notReady int
+
+	// Number of namespaces matched by the selectors of the rule which were
+	// skipped, are outside of the delegated scope, or haven't consented.
+	skipped     int
+	undelegated int
+	unconsented int
+
+	// Whether the rule matches more namespaces than the fan-out guard allows
+	// without the rule acknowledging it.
+	fanOutBlocked bool
+}
+
+// Select the target namespaces of a rule from a list of namespaces, applying
+// the fan-out guard to the number matched.
+func (r *SecretCopierReconciler) selectTargetNamespaces(ctx context.Context, settings *ManagerSettings, delegations delegationScopes, rule *secretsv1beta1.SecretCopierRule, matcher *selectors.TargetNamespacesMatcher, namespaces []corev1.Namespace) targetNamespaceSelection {
+	log := log.FromContext(ctx)
+
+	var selection targetNamespaceSelection
+
+	for i := range namespaces {
+		namespace := &namespaces[i]
+
+		check, err := r.checkTargetNamespace(ctx, settings, delegations, rule, matcher, namespace)
+
+		switch check {
+		case targetNamespaceUnmatched, targetNamespaceVirtualCluster, targetNamespaceDenied:
+			continue
+
+		case targetNamespaceSkipped:
+			log.V(1).Info("Skipping terminating or decommissioning target namespace", "namespace", namespace.Name)
+
+			selection.skipped++
+			continue
+
+		case targetNamespaceUndelegated:
+			log.V(1).Info("Skipping target namespace outside of delegated scope", "namespace", namespace.Name, "sourceNamespace", rule.SourceSecret.Namespace)
+
+			selection.undelegated++
+			continue
+
+		case targetNamespaceUnconsented:
+			log.V(1).Info("Skipping target namespace which hasn't consented to copies", "namespace", namespace.Name)
+
+			selection.unconsented++
+			continue
+
+		case targetNamespaceNotReady, targetNamespaceLacksRequiredResource:
+			if err != nil {
+				log.Error(err, "Unable to determine readiness of target namespace", "namespace", namespace.Name)
+			}
+
+			log.V(1).Info("Target namespace not ready", "namespace", namespace.Name)
+
+			selection.notReady++
+		}
+
+		log.V(1).Info("Matched target namespace", "namespace", namespace.Name)
+
+		selection.matched = append(selection.matched, matchedTargetNamespace{
+			namespace: namespace,
+			check:     check,
+			err:       err,
+		})
+	}
+
+	selection.fanOutBlocked = settings.FanOutBlocked(rule, len(selection.matched))
+
+	return selection
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+var _ = Describe("Target Namespace Selection", func() {
+	ctx := context.Background()
+
+	newNamespace := func(name string, labels map[string]string, annotations map[string]string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels, Annotations: annotations}}
+	}
+
+	newRule := func() *secretsv1beta1.SecretCopierRule {
+		return &secretsv1beta1.SecretCopierRule{
+			SourceSecret: secretsv1beta1.SourceSecret{Namespace: "registries", Name: "registry"},
+			TargetNamespaces: selectors.TargetNamespaces{
+				NameSelector: selectors.NameSelector{MatchNames: []string{"registries", "tenant-*"}},
+			},
+		}
+	}
+
+	delegation := &secretsv1beta1.SecretCopierDelegation{
+		ObjectMeta: metav1.ObjectMeta{Name: "registries"},
+		Spec: secretsv1beta1.SecretCopierDelegationSpec{
+			SourceNamespace: "registries",
+			TargetNamespaces: selectors.TargetNamespaces{
+				NameSelector: selectors.NameSelector{MatchNames: []string{"tenant-a", "tenant-consent"}},
+			},
+		},
+	}
+
+	It("should check each reason a namespace isn't a target namespace", func() {
+		requiredDeployment := func(rule *secretsv1beta1.SecretCopierRule) {
+			rule.RequiredResource = &secretsv1beta1.RequiredResource{APIVersion: "apps/v1", Kind: "Deployment", Name: "api"}
+		}
+
+		tests := []struct {
+			name      string
+			namespace *corev1.Namespace
+			objects   []client.Object
+			configure func(rule *secretsv1beta1.SecretCopierRule)
+			settings  ManagerSettings
+			check     targetNamespaceCheck
+		}{
+			{name: "eligible", namespace: newNamespace("tenant-a", nil, nil), check: targetNamespaceEligible},
+			{name: "source namespace", namespace: newNamespace("registries", nil, nil), check: targetNamespaceUnmatched},
+			{name: "not matched", namespace: newNamespace("other", nil, nil), check: targetNamespaceUnmatched},
+			{
+				name:      "denied",
+				namespace: newNamespace("tenant-a", nil, nil),
+				settings:  ManagerSettings{DeniedNamespaces: []string{"tenant-a"}},
+				check:     targetNamespaceDenied,
+			},
+			{
+				name:      "decommissioning",
+				namespace: newNamespace("tenant-a", map[string]string{secretsv1beta1.NamespaceDecommissioningLabel: "true"}, nil),
+				check:     targetNamespaceSkipped,
+			},
+			{name: "outside delegated scope", namespace: newNamespace("tenant-b", nil, nil), check: targetNamespaceUndelegated},
+			{
+				name:      "unconsented",
+				namespace: newNamespace("tenant-a", nil, nil),
+				configure: func(rule *secretsv1beta1.SecretCopierRule) { rule.RequireNamespaceConsent = true },
+				check:     targetNamespaceUnconsented,
+			},
+			{
+				name:      "consented",
+				namespace: newNamespace("tenant-consent", nil, map[string]string{secretsv1beta1.NamespaceAcceptCopiesAnnotation: "true"}),
+				configure: func(rule *secretsv1beta1.SecretCopierRule) { rule.RequireNamespaceConsent = true },
+				check:     targetNamespaceEligible,
+			},
+			{
+				name:      "not ready",
+				namespace: newNamespace("tenant-a", nil, nil),
+				configure: func(rule *secretsv1beta1.SecretCopierRule) {
+					rule.TargetNamespaceReadiness = &secretsv1beta1.NamespaceReadiness{MatchLabels: map[string]string{"ready": "true"}}
+				},
+				check: targetNamespaceNotReady,
+			},
+			{
+				name:      "lacks required resource",
+				namespace: newNamespace("tenant-a", nil, nil),
+				configure: requiredDeployment,
+				check:     targetNamespaceLacksRequiredResource,
+			},
+			{
+				name:      "holds required resource",
+				namespace: newNamespace("tenant-a", nil, nil),
+				objects:   []client.Object{&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-a", Name: "api"}}},
+				configure: requiredDeployment,
+				check:     targetNamespaceEligible,
+			},
+		}
+
+		delegations := delegationScopes{"registries": {delegation.Spec.TargetNamespaces.Compile()}}
+
+		for _, test := range tests {
+			reconciler := &SecretCopierReconciler{Client: fake.NewClientBuilder().WithObjects(test.namespace).WithObjects(test.objects...).Build()}
+
+			rule := newRule()
+
+			if test.configure != nil {
+				test.configure(rule)
+			}
+
+			check, err := reconciler.checkTargetNamespace(ctx, newManagerSettings(test.settings), delegations, rule, rule.TargetNamespaces.Compile(), test.namespace)
+
+			Expect(err).NotTo(HaveOccurred(), test.name)
+			Expect(check).To(Equal(test.check), test.name)
+		}
+	})
+
+	It("should only copy within the delegated scope when applying once", func() {
+		secretCopier := &secretsv1beta1.SecretCopier{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry-copier"},
+			Spec: secretsv1beta1.SecretCopierSpec{
+				Rules: []secretsv1beta1.SecretCopierRule{*newRule()},
+			},
+		}
+
+		k8sClient := fake.NewClientBuilder().WithObjects(
+			delegation,
+			newNamespace("registries", nil, nil),
+			newNamespace("tenant-a", nil, nil),
+			newNamespace("tenant-b", nil, nil),
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "registries"},
+				Data:       map[string][]byte{"token": []byte("registry-token")},
+			},
+		).Build()
+
+		reconciler := &SecretCopierReconciler{Client: k8sClient, Recorder: record.NewFakeRecorder(100)}
+
+		summary, err := reconciler.ApplyOnce(ctx, secretCopier)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(summary.Rules[0].Targets).To(ConsistOf(ApplyTargetResult{
+			Namespace: "tenant-a",
+			Secret:    "registry",
+			Result:    "created",
+		}))
+	})
+
+	It("should only copy to namespaces holding the required resource when applying once", func() {
+		rule := newRule()
+
+		rule.TargetNamespaces.NameSelector.MatchNames = []string{"tenant-*"}
+		rule.RequiredResource = &secretsv1beta1.RequiredResource{APIVersion: "apps/v1", Kind: "Deployment", Name: "api"}
+
+		secretCopier := &secretsv1beta1.SecretCopier{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry-copier"},
+			Spec: secretsv1beta1.SecretCopierSpec{
+				Rules: []secretsv1beta1.SecretCopierRule{*rule},
+			},
+		}
+
+		k8sClient := fake.NewClientBuilder().WithObjects(
+			newNamespace("registries", nil, nil),
+			newNamespace("tenant-a", nil, nil),
+			newNamespace("tenant-b", nil, nil),
+			&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "tenant-a", Name: "api"}},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "registries"},
+				Data:       map[string][]byte{"token": []byte("registry-token")},
+			},
+		).Build()
+
+		reconciler := &SecretCopierReconciler{Client: k8sClient, Recorder: record.NewFakeRecorder(100)}
+
+		summary, err := reconciler.ApplyOnce(ctx, secretCopier)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(summary.Rules[0].Targets).To(ConsistOf(
+			ApplyTargetResult{Namespace: "tenant-a", Secret: "registry", Result: "created"},
+			ApplyTargetResult{Namespace: "tenant-b", Secret: "registry", Result: "not-ready"},
+		))
+
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "tenant-b", Name: "registry"}, &corev1.Secret{})).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,140 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/datatemplate"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/registrymirror"
+)
+
+// Return the content of the target secret for a rule, being the source secret
+// with only the data keys the rule copies, with the data rendered from the
+// templates of the rule if it has any, and with registry credentials rewritten
+// for the mirrors of the rule. The source secret is returned unchanged if the
+// rule doesn't change it. An error is returned if a template fails to render
+// or registry credentials can't be rewritten, in which case the target secret
+// can't be written.
+func targetSecretContent(rule *secretsv1beta1.SecretCopierRule, sourceSecret *corev1.Secret) (*corev1.Secret, error) {
+	content, err := renderTargetSecretContent(rule, sourceSecret)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return mirrorRegistries(rule, content, sourceSecret)
+}
+
+// Return the source secret with only the data keys the rule copies, and with
+// the data rendered from the templates of the rule if it has any.
+func renderTargetSecretContent(rule *secretsv1beta1.SecretCopierRule, sourceSecret *corev1.Secret) (*corev1.Secret, error) {
+	content := filterSourceSecretKeys(rule, sourceSecret)
+
+	template := rule.TargetSecret.Template
+
+	if template == nil {
+		return content, nil
+	}
+
+	values := datatemplate.Values{
+		Data:      make(map[string]string, len(content.Data)),
+		Name:      sourceSecret.Name,
+		Namespace: sourceSecret.Namespace,
+	}
+
+	for key, value := range content.Data {
+		values.Data[key] = string(value)
+	}
+
+	rendered, err := datatemplate.Render(template.Data, values)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if content == sourceSecret {
+		content = sourceSecret.DeepCopy()
+	}
+
+	if !template.IncludeSourceData {
+		content.Data = rendered
+
+		return content, nil
+	}
+
+	if content.Data == nil {
+		content.Data = make(map[string][]byte, len(rendered))
+	}
+
+	for key, value := range rendered {
+		content.Data[key] = value
+	}
+
+	return content, nil
+}
+
+// Rewrite the registries of the credentials held in the Docker config data
+// keys of the target secret content to the mirrors given by the rule. The
+// content is returned unchanged if the rule has no mirrors or none apply. An
+// error is returned if the Docker config data can't be parsed.
+func mirrorRegistries(rule *secretsv1beta1.SecretCopierRule, content *corev1.Secret, sourceSecret *corev1.Secret) (*corev1.Secret, error) {
+	if len(rule.TargetSecret.RegistryMirrors) == 0 {
+		return content, nil
+	}
+
+	mirrors := make([]registrymirror.Mirror, 0, len(rule.TargetSecret.RegistryMirrors))
+
+	for _, mirror := range rule.TargetSecret.RegistryMirrors {
+		mirrors = append(mirrors, registrymirror.Mirror{Registry: mirror.Registry, Mirror: mirror.Mirror})
+	}
+
+	rewriters := map[string]func([]byte, []registrymirror.Mirror) ([]byte, error){
+		corev1.DockerConfigJsonKey: registrymirror.RewriteDockerConfigJSON,
+		corev1.DockerConfigKey:     registrymirror.RewriteDockerConfig,
+	}
+
+	for key, rewrite := range rewriters {
+		value, found := content.Data[key]
+
+		if !found {
+			continue
+		}
+
+		rewritten, err := rewrite(value, mirrors)
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to rewrite registries in data key %s: %w", key, err)
+		}
+
+		if bytes.Equal(rewritten, value) {
+			continue
+		}
+
+		if content == sourceSecret {
+			content = sourceSecret.DeepCopy()
+		}
+
+		content.Data[key] = rewritten
+	}
+
+	return content, nil
+}
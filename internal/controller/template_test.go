@@ -0,0 +1,157 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Target Secret Templates", func() {
+	ctx := context.Background()
+
+	secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "templating-copier"}}
+
+	newSourceSecret := func() *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "source"},
+			Data: map[string][]byte{
+				"host":     []byte("db.example.com"),
+				"user":     []byte("app"),
+				"password": []byte("s3cr3t-password"),
+			},
+		}
+	}
+
+	newRule := func(includeSourceData bool) *secretsv1beta1.SecretCopierRule {
+		return &secretsv1beta1.SecretCopierRule{
+			SourceSecret: secretsv1beta1.SourceSecret{Name: "database", Namespace: "source"},
+			TargetSecret: secretsv1beta1.TargetSecret{
+				ExcludeKeys: []string{"host"},
+				Template: &secretsv1beta1.TargetSecretTemplate{
+					Data: map[string]string{
+						"dsn": "postgres://{{ .Data.user }}:{{ .Data.password }}@db/app",
+					},
+					IncludeSourceData: includeSourceData,
+				},
+			},
+		}
+	}
+
+	It("should render only the templated keys by default", func() {
+		sourceSecret := newSourceSecret()
+
+		content, err := targetSecretContent(newRule(false), sourceSecret)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(content.Data).To(Equal(map[string][]byte{"dsn": []byte("postgres://app:s3cr3t-password@db/app")}))
+		Expect(sourceSecret.Data).To(HaveLen(3))
+	})
+
+	It("should merge the selected source keys when requested", func() {
+		content, err := targetSecretContent(newRule(true), newSourceSecret())
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(content.Data).To(HaveKey("dsn"))
+		Expect(content.Data).To(HaveKey("user"))
+		Expect(content.Data).To(HaveKey("password"))
+		Expect(content.Data).NotTo(HaveKey("host"))
+	})
+
+	It("should fail to render a template referencing an excluded key", func() {
+		rule := newRule(false)
+		rule.TargetSecret.Template.Data["url"] = "https://{{ .Data.host }}"
+
+		_, err := targetSecretContent(rule, newSourceSecret())
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("key url"))
+	})
+
+	It("should copy the rendered data to the target namespace", func() {
+		k8sClient := fake.NewClientBuilder().WithObjects(newSourceSecret()).Build()
+
+		reconciler := &SecretCopierReconciler{Client: k8sClient, Recorder: record.NewFakeRecorder(10)}
+
+		rule := newRule(false)
+
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant-a")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyCreated))
+
+		targetSecret := &corev1.Secret{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "tenant-a", Name: "database"}, targetSecret)).To(Succeed())
+
+		Expect(targetSecret.Data).To(Equal(map[string][]byte{"dsn": []byte("postgres://app:s3cr3t-password@db/app")}))
+
+		// The rendered target secret is in sync so is not written again.
+
+		result, err = reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant-a")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyUnchanged))
+
+		// A change to the template is copied to the target secret.
+
+		rule.TargetSecret.Template.Data["dsn"] = "mysql://{{ .Data.user }}@db/app"
+
+		result, err = reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant-a")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyUpdated))
+	})
+
+	It("should fail the copy when a template fails to render", func() {
+		k8sClient := fake.NewClientBuilder().WithObjects(newSourceSecret()).Build()
+
+		reconciler := &SecretCopierReconciler{Client: k8sClient, Recorder: record.NewFakeRecorder(10)}
+
+		rule := newRule(false)
+		rule.TargetSecret.Template.Data["dsn"] = "{{ .Data.password | b64dec }}"
+
+		result, err := reconciler.copySecretToNamespace(ctx, secretCopier, rule, "tenant-a")
+
+		Expect(err).To(HaveOccurred())
+		Expect(result).To(Equal(copyFailed))
+		Expect(err.Error()).NotTo(ContainSubstring("s3cr3t-password"))
+	})
+
+	It("should name hashed target secrets from the rendered data", func() {
+		rule := newRule(false)
+		rule.TargetSecret.HashSuffix = &secretsv1beta1.TargetSecretHashSuffix{}
+
+		sourceSecret := newSourceSecret()
+		name := hashedTargetSecretName(rule, sourceSecret)
+
+		sourceSecret.Data["host"] = []byte("db2.example.com")
+		Expect(hashedTargetSecretName(rule, sourceSecret)).To(Equal(name))
+
+		rule.TargetSecret.Template.Data["dsn"] = "mysql://{{ .Data.user }}@db/app"
+		Expect(hashedTargetSecretName(rule, sourceSecret)).NotTo(Equal(name))
+	})
+})
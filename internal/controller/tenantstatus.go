@@ -0,0 +1,219 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Label marking the status ConfigMaps maintained in target namespaces, so they
+// can be found when removing entries.
+const (
+	targetNamespaceStatusManagedByLabel = "app.kubernetes.io/managed-by"
+	targetNamespaceStatusManagedBy      = "advok8s-secrets-manager"
+)
+
+// Entry in the status ConfigMap of a target namespace describing a secret which
+// was copied into the namespace. Only the reference to the source secret is
+// recorded, never any of the secret data.
+type targetNamespaceStatusEntry struct {
+	SecretCopier string `json:"secretCopier"`
+	Source       string `json:"source"`
+	LastSyncTime string `json:"lastSyncTime"`
+}
+
+// Record in the status ConfigMap of the target namespace, if enabled, that the
+// target secret is managed by the SecretCopier. The ConfigMap holds one key per
+// target secret. The last sync time of an entry is updated when the target
+// secret was written, otherwise the entry is only added if it is missing or no
+// longer matches the SecretCopier and source secret.
+func (r *SecretCopierReconciler) updateTargetNamespaceStatus(ctx context.Context, secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, targetNamespace string, targetSecretName string, written bool) {
+	if r.TargetNamespaceStatusConfigMap == "" || r.writesSuspended() {
+		return
+	}
+
+	log := log.FromContext(ctx)
+
+	source := rule.SourceSecret.Namespace + "/" + rule.SourceSecret.Name
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var configMap corev1.ConfigMap
+
+		err := r.Get(ctx, client.ObjectKey{Namespace: targetNamespace, Name: r.TargetNamespaceStatusConfigMap}, &configMap)
+
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+
+		exists := err == nil
+
+		if configMap.Data == nil {
+			configMap.Data = make(map[string]string)
+		}
+
+		// Check whether the existing entry, if any, needs to be updated.
+
+		var entry targetNamespaceStatusEntry
+
+		if value, ok := configMap.Data[targetSecretName]; ok && !written {
+			if json.Unmarshal([]byte(value), &entry) == nil && entry.SecretCopier == secretCopier.Name && entry.Source == source {
+				return nil
+			}
+		}
+
+		entry = targetNamespaceStatusEntry{
+			SecretCopier: secretCopier.Name,
+			Source:       source,
+			LastSyncTime: time.Now().UTC().Format(time.RFC3339),
+		}
+
+		value, err := json.Marshal(entry)
+
+		if err != nil {
+			return err
+		}
+
+		configMap.Data[targetSecretName] = string(value)
+
+		if exists {
+			return r.Update(ctx, &configMap)
+		}
+
+		configMap.ObjectMeta = metav1.ObjectMeta{
+			Name:      r.TargetNamespaceStatusConfigMap,
+			Namespace: targetNamespace,
+			Labels: map[string]string{
+				targetNamespaceStatusManagedByLabel: targetNamespaceStatusManagedBy,
+			},
+		}
+
+		return r.Create(ctx, &configMap)
+	})
+
+	if err != nil {
+		log.Error(err, "Unable to update status ConfigMap for target namespace", "configMap", r.TargetNamespaceStatusConfigMap, "targetNamespace", targetNamespace)
+	}
+}
+
+// Remove entries recorded by the SecretCopier from the status ConfigMaps of
+// target namespaces, if enabled, where the target secret no longer exists or
+// is no longer managed by the SecretCopier. When all rules of the SecretCopier
+// were processed, so the target secrets it wants are known, entries for any
+// other target secret are removed as well, covering target secrets of removed
+// rules, of namespaces which are no longer matched, and of hash suffixed names
+// which have since changed. Passing no wanted target secrets with complete set
+// removes all entries of the SecretCopier, as is done when it is deleted. A
+// ConfigMap left without any entries is deleted.
+func (r *SecretCopierReconciler) pruneTargetNamespaceStatus(ctx context.Context, secretCopierName string, wanted map[client.ObjectKey]bool, complete bool) {
+	if r.TargetNamespaceStatusConfigMap == "" || r.writesSuspended() {
+		return
+	}
+
+	log := log.FromContext(ctx)
+
+	var configMaps corev1.ConfigMapList
+
+	if err := r.List(ctx, &configMaps, client.MatchingLabels{targetNamespaceStatusManagedByLabel: targetNamespaceStatusManagedBy}); err != nil {
+		log.Error(err, "Unable to list status ConfigMaps of target namespaces", "configMap", r.TargetNamespaceStatusConfigMap)
+		return
+	}
+
+	stale := func(namespace string, key string, value string) bool {
+		var entry targetNamespaceStatusEntry
+
+		if json.Unmarshal([]byte(value), &entry) != nil || entry.SecretCopier != secretCopierName {
+			return false
+		}
+
+		targetSecret := client.ObjectKey{Namespace: namespace, Name: key}
+
+		if complete && !wanted[targetSecret] {
+			return true
+		}
+
+		var secret corev1.Secret
+
+		if err := r.Get(ctx, targetSecret, &secret); err != nil {
+			return apierrors.IsNotFound(err)
+		}
+
+		return secret.Annotations[secretCopierMarkerAnnotation] != secretCopierName
+	}
+
+	for i := range configMaps.Items {
+		configMap := &configMaps.Items[i]
+
+		if configMap.Name != r.TargetNamespaceStatusConfigMap {
+			continue
+		}
+
+		found := false
+
+		for key, value := range configMap.Data {
+			if stale(configMap.Namespace, key, value) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			continue
+		}
+
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			var current corev1.ConfigMap
+
+			if err := r.Get(ctx, client.ObjectKeyFromObject(configMap), &current); err != nil {
+				return client.IgnoreNotFound(err)
+			}
+
+			data := maps.Clone(current.Data)
+
+			maps.DeleteFunc(data, func(key string, value string) bool {
+				return stale(current.Namespace, key, value)
+			})
+
+			if len(data) == len(current.Data) {
+				return nil
+			}
+
+			if len(data) == 0 {
+				return client.IgnoreNotFound(r.Delete(ctx, &current, client.Preconditions{UID: &current.UID, ResourceVersion: &current.ResourceVersion}))
+			}
+
+			current.Data = data
+
+			return r.Update(ctx, &current)
+		})
+
+		if err != nil {
+			log.Error(err, "Unable to remove entries from status ConfigMap for target namespace", "configMap", r.TargetNamespaceStatusConfigMap, "targetNamespace", configMap.Namespace)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Target Namespace Status", func() {
+	ctx := context.Background()
+
+	secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "tenant-copier"}}
+
+	rule := &secretsv1beta1.SecretCopierRule{
+		SourceSecret: secretsv1beta1.SourceSecret{Name: "registry", Namespace: "source"},
+	}
+
+	entry := func(secretCopierName string) string {
+		value, err := json.Marshal(targetNamespaceStatusEntry{SecretCopier: secretCopierName, Source: "source/registry"})
+
+		Expect(err).NotTo(HaveOccurred())
+
+		return string(value)
+	}
+
+	statusConfigMap := func(namespace string, data map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "secrets-status",
+				Namespace: namespace,
+				Labels:    map[string]string{targetNamespaceStatusManagedByLabel: targetNamespaceStatusManagedBy},
+			},
+			Data: data,
+		}
+	}
+
+	targetSecret := func(namespace string, name string, secretCopierName string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   namespace,
+				Annotations: map[string]string{secretCopierMarkerAnnotation: secretCopierName},
+			},
+		}
+	}
+
+	statusData := func(k8sClient client.Client, namespace string) map[string]string {
+		var configMap corev1.ConfigMap
+
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "secrets-status"}, &configMap)).To(Succeed())
+
+		return configMap.Data
+	}
+
+	It("should record the target secret in the status ConfigMap", func() {
+		k8sClient := fake.NewClientBuilder().Build()
+
+		reconciler := &SecretCopierReconciler{Client: k8sClient, TargetNamespaceStatusConfigMap: "secrets-status"}
+
+		reconciler.updateTargetNamespaceStatus(ctx, secretCopier, rule, "tenant-a", "registry", true)
+
+		data := statusData(k8sClient, "tenant-a")
+
+		Expect(data).To(HaveKey("registry"))
+
+		var recorded targetNamespaceStatusEntry
+
+		Expect(json.Unmarshal([]byte(data["registry"]), &recorded)).To(Succeed())
+		Expect(recorded.SecretCopier).To(Equal("tenant-copier"))
+		Expect(recorded.Source).To(Equal("source/registry"))
+		Expect(recorded.LastSyncTime).NotTo(BeEmpty())
+	})
+
+	It("should remove entries for target secrets which are deleted or no longer managed", func() {
+		k8sClient := fake.NewClientBuilder().WithObjects(
+			statusConfigMap("tenant-a", map[string]string{
+				"registry": entry("tenant-copier"),
+				"deleted":  entry("tenant-copier"),
+				"adopted":  entry("tenant-copier"),
+				"other":    entry("other-copier"),
+			}),
+			targetSecret("tenant-a", "registry", "tenant-copier"),
+			targetSecret("tenant-a", "adopted", "other-copier"),
+		).Build()
+
+		reconciler := &SecretCopierReconciler{Client: k8sClient, TargetNamespaceStatusConfigMap: "secrets-status"}
+
+		reconciler.pruneTargetNamespaceStatus(ctx, "tenant-copier", nil, false)
+
+		Expect(statusData(k8sClient, "tenant-a")).To(HaveLen(2))
+		Expect(statusData(k8sClient, "tenant-a")).To(HaveKey("registry"))
+		Expect(statusData(k8sClient, "tenant-a")).To(HaveKey("other"))
+	})
+
+	It("should remove entries for target secrets no longer wanted once all rules are processed", func() {
+		k8sClient := fake.NewClientBuilder().WithObjects(
+			statusConfigMap("tenant-a", map[string]string{
+				"registry-abc": entry("tenant-copier"),
+				"registry-def": entry("tenant-copier"),
+			}),
+			statusConfigMap("tenant-b", map[string]string{
+				"registry-abc": entry("tenant-copier"),
+			}),
+			targetSecret("tenant-a", "registry-abc", "tenant-copier"),
+			targetSecret("tenant-a", "registry-def", "tenant-copier"),
+			targetSecret("tenant-b", "registry-abc", "tenant-copier"),
+		).Build()
+
+		reconciler := &SecretCopierReconciler{Client: k8sClient, TargetNamespaceStatusConfigMap: "secrets-status"}
+
+		wanted := map[client.ObjectKey]bool{{Namespace: "tenant-a", Name: "registry-def"}: true}
+
+		// Entries are only removed for existing target secrets when it is
+		// known which target secrets are wanted.
+
+		reconciler.pruneTargetNamespaceStatus(ctx, "tenant-copier", wanted, false)
+
+		Expect(statusData(k8sClient, "tenant-a")).To(HaveLen(2))
+		Expect(statusData(k8sClient, "tenant-b")).To(HaveLen(1))
+
+		reconciler.pruneTargetNamespaceStatus(ctx, "tenant-copier", wanted, true)
+
+		Expect(statusData(k8sClient, "tenant-a")).To(Equal(map[string]string{"registry-def": entry("tenant-copier")}))
+
+		err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "tenant-b", Name: "secrets-status"}, &corev1.ConfigMap{})
+
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("should remove all entries of a deleted SecretCopier", func() {
+		k8sClient := fake.NewClientBuilder().WithObjects(
+			statusConfigMap("tenant-a", map[string]string{
+				"registry": entry("tenant-copier"),
+				"other":    entry("other-copier"),
+			}),
+			targetSecret("tenant-a", "registry", "tenant-copier"),
+		).Build()
+
+		reconciler := &SecretCopierReconciler{Client: k8sClient, TargetNamespaceStatusConfigMap: "secrets-status"}
+
+		reconciler.pruneTargetNamespaceStatus(ctx, "tenant-copier", nil, true)
+
+		Expect(statusData(k8sClient, "tenant-a")).To(Equal(map[string]string{"other": entry("other-copier")}))
+	})
+
+	It("should leave status ConfigMaps alone when not enabled", func() {
+		k8sClient := fake.NewClientBuilder().WithObjects(
+			statusConfigMap("tenant-a", map[string]string{"registry": entry("tenant-copier")}),
+		).Build()
+
+		reconciler := &SecretCopierReconciler{Client: k8sClient}
+
+		reconciler.pruneTargetNamespaceStatus(ctx, "tenant-copier", nil, true)
+
+		Expect(statusData(k8sClient, "tenant-a")).To(HaveKey("registry"))
+	})
+})
@@ -0,0 +1,152 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/internal/redact"
+)
+
+// Error returned when the type of the source secret no longer matches that
+// of the target secret. As the type of a secret is immutable, the target
+// secret can't be updated to match.
+type secretTypeChangedError struct {
+	Namespace string
+	Name      string
+	From      corev1.SecretType
+	To        corev1.SecretType
+}
+
+func (e *secretTypeChangedError) Error() string {
+	return fmt.Sprintf("type of target secret %s in namespace %s is %s but the source secret is now %s, and the type of a secret can't be changed",
+		e.Name, e.Namespace, e.From, e.To)
+}
+
+// Replace a target secret whose type no longer matches that of the source
+// secret. Updating the target secret would be rejected by the API server, so
+// if recreating target secrets is enabled the target secret is deleted and
+// created again with the new type. Otherwise a warning event is recorded
+// against the SecretCopier and an error returned, so the failure is reported
+// clearly rather than the update being rejected on every reconciliation.
+func (r *SecretCopierReconciler) recreateTargetSecret(ctx context.Context, secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, sourceSecret *corev1.Secret, targetSecret *corev1.Secret, backoffKey string) (copyResult, error) {
+	log := log.FromContext(ctx)
+
+	typeChanged := &secretTypeChangedError{
+		Namespace: targetSecret.Namespace,
+		Name:      targetSecret.Name,
+		From:      targetSecret.Type,
+		To:        sourceSecret.Type,
+	}
+
+	if !r.RecreateOnTypeChange {
+		log.Info("Unable to update target secret as its type has changed", "targetSecret", targetSecret.Name, "targetNamespace", targetSecret.Namespace, "from", typeChanged.From, "to", typeChanged.To)
+
+		r.Recorder.Eventf(secretCopier, corev1.EventTypeWarning, "SecretTypeChanged", "Unable to update %s", typeChanged.Error())
+
+		return copyFailed, typeChanged
+	}
+
+	replacement := r.newTargetSecret(secretCopier, rule, sourceSecret, targetSecret.Name, targetSecret.Namespace)
+
+	if err := r.mutateTargetSecret(ctx, secretCopier, rule, sourceSecret, &replacement); err != nil {
+		log.Error(err, "Unable to mutate target secret", "targetSecret", targetSecret.Name, "targetNamespace", targetSecret.Namespace)
+		return copyFailed, err
+	}
+
+	return r.replaceTargetSecret(ctx, secretCopier, rule, targetSecret, &replacement, backoffKey,
+		fmt.Sprintf("its type changed from %s to %s", typeChanged.From, typeChanged.To))
+}
+
+// Replace a target secret which can't be updated in place by deleting it and
+// creating the replacement. An event is recorded against the SecretCopier
+// giving the reason the target secret had to be recreated.
+func (r *SecretCopierReconciler) replaceTargetSecret(ctx context.Context, secretCopier *secretsv1beta1.SecretCopier, rule *secretsv1beta1.SecretCopierRule, targetSecret *corev1.Secret, replacement *corev1.Secret, backoffKey string, reason string) (copyResult, error) {
+	log := log.FromContext(ctx)
+
+	if err := r.checkTargetSecretSize(secretCopier, replacement); err != nil {
+		log.Info("Unable to recreate target secret as it is too large", "targetSecret", targetSecret.Name, "targetNamespace", targetSecret.Namespace, "size", secretDataSize(replacement))
+		return copyFailed, err
+	}
+
+	if r.writesSuspended() {
+		r.recordReportOnlyWrite(ctx, secretCopier, "recreate", replacement, redact.DiffSecrets(targetSecret, replacement))
+		return copyReportOnly, nil
+	}
+
+	if !r.allowSecretWrite() {
+		log.V(1).Info("Deferring recreate of target secret as write rate limit reached", "targetSecret", targetSecret.Name, "targetNamespace", targetSecret.Namespace)
+		return copyThrottled, nil
+	}
+
+	log.Info("Recreating target secret", "targetSecret", targetSecret.Name, "targetNamespace", targetSecret.Namespace, "reason", reason)
+
+	// Only delete the target secret as it was when checked, so a concurrent
+	// change to it is picked up by the next reconciliation instead. If the
+	// create then fails, the target secret no longer exists and will be
+	// created by the next reconciliation.
+
+	secretsClient, err := r.secretsClient(secretCopier)
+
+	if err != nil {
+		return copyFailed, err
+	}
+
+	err = secretsClient.Delete(ctx, targetSecret, client.Preconditions{UID: &targetSecret.UID, ResourceVersion: &targetSecret.ResourceVersion})
+
+	if client.IgnoreNotFound(err) != nil {
+		log.Error(err, "Unable to delete target secret", "targetSecret", targetSecret.Name, "targetNamespace", targetSecret.Namespace)
+
+		if apierrors.IsForbidden(err) {
+			err = newPermissionDeniedError("delete", targetSecret.Namespace, err)
+			r.recordPermissionDenied(secretCopier, targetSecret.Name, err)
+			return copyForbidden, err
+		}
+
+		return copyFailed, err
+	}
+
+	if err := secretsClient.Create(ctx, replacement, targetSecretWriteOwner(secretCopier, rule)); err != nil {
+		log.Error(err, "Unable to create target secret", "targetSecret", targetSecret.Name, "targetNamespace", targetSecret.Namespace)
+
+		if apierrors.IsForbidden(err) {
+			err = newPermissionDeniedError("create", targetSecret.Namespace, err)
+			r.recordPermissionDenied(secretCopier, targetSecret.Name, err)
+			return copyForbidden, err
+		}
+
+		return copyFailed, err
+	}
+
+	log.V(1).Info("Recreated target secret", "targetSecret", targetSecret.Name, "targetNamespace", targetSecret.Namespace, "changes", redact.DiffSecrets(targetSecret, replacement).String())
+
+	r.deniedBackoff.succeeded(backoffKey)
+
+	r.Recorder.Eventf(secretCopier, corev1.EventTypeNormal, "SecretRecreated",
+		"Recreated secret %s in namespace %s as %s", targetSecret.Name, targetSecret.Namespace, reason)
+
+	r.recordTargetNamespaceEvent(secretCopier, rule, replacement, "Recreated", "recreated", redact.DiffSecrets(targetSecret, replacement))
+
+	return copyUpdated, nil
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Source Secret Type Changes", func() {
+	ctx := context.Background()
+
+	secretCopier := &secretsv1beta1.SecretCopier{ObjectMeta: metav1.ObjectMeta{Name: "type-change-copier"}}
+
+	rule := &secretsv1beta1.SecretCopierRule{
+		SourceSecret: secretsv1beta1.SourceSecret{Name: "registry", Namespace: "source"},
+	}
+
+	sourceSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "source"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte("{}")},
+	}
+
+	var fakeClient client.Client
+	var recorder *record.FakeRecorder
+	var targetSecret *corev1.Secret
+
+	BeforeEach(func() {
+		fakeClient = fake.NewClientBuilder().WithObjects(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "tenant-a"},
+			Type:       corev1.SecretTypeOpaque,
+			Data:       map[string][]byte{"password": []byte("secret")},
+		}).Build()
+
+		recorder = record.NewFakeRecorder(10)
+
+		targetSecret = &corev1.Secret{}
+		Expect(fakeClient.Get(ctx, client.ObjectKey{Namespace: "tenant-a", Name: "registry"}, targetSecret)).To(Succeed())
+	})
+
+	It("should fail with a clear error when recreating is not enabled", func() {
+		reconciler := &SecretCopierReconciler{Client: fakeClient, Recorder: recorder}
+
+		result, err := reconciler.recreateTargetSecret(ctx, secretCopier, rule, sourceSecret, targetSecret, "key")
+
+		Expect(result).To(Equal(copyFailed))
+
+		var typeChanged *secretTypeChangedError
+
+		Expect(errors.As(err, &typeChanged)).To(BeTrue())
+		Expect(newFailedTarget(0, "tenant-a", result, err).Reason).To(Equal("Conflict"))
+
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning SecretTypeChanged")))
+
+		Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(targetSecret), targetSecret)).To(Succeed())
+		Expect(targetSecret.Type).To(Equal(corev1.SecretTypeOpaque))
+	})
+
+	It("should delete and recreate the target secret when enabled", func() {
+		reconciler := &SecretCopierReconciler{Client: fakeClient, Recorder: recorder, RecreateOnTypeChange: true}
+
+		result, err := reconciler.recreateTargetSecret(ctx, secretCopier, rule, sourceSecret, targetSecret, "key")
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(copyUpdated))
+
+		Expect(recorder.Events).To(Receive(Equal(
+			"Normal SecretRecreated Recreated secret registry in namespace tenant-a as its type changed from Opaque to kubernetes.io/dockerconfigjson")))
+
+		recreated := &corev1.Secret{}
+		Expect(fakeClient.Get(ctx, client.ObjectKeyFromObject(targetSecret), recreated)).To(Succeed())
+		Expect(recreated.Type).To(Equal(corev1.SecretTypeDockerConfigJson))
+		Expect(recreated.Data).To(Equal(sourceSecret.Data))
+	})
+
+	It("should not delete a target secret which changed since it was checked", func() {
+		reconciler := &SecretCopierReconciler{Client: fakeClient, Recorder: recorder, RecreateOnTypeChange: true}
+
+		stale := targetSecret.DeepCopy()
+		stale.ResourceVersion = "1"
+
+		targetSecret.Data = map[string][]byte{"password": []byte("changed")}
+		Expect(fakeClient.Update(ctx, targetSecret)).To(Succeed())
+
+		result, err := reconciler.recreateTargetSecret(ctx, secretCopier, rule, sourceSecret, stale, "key")
+
+		Expect(result).To(Equal(copyFailed))
+		Expect(apierrors.IsConflict(err)).To(BeTrue())
+	})
+})
@@ -0,0 +1,302 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/vault"
+)
+
+const (
+	// Annotation on a secret naming the VaultSecret it was created for.
+	vaultSecretAnnotation = "secrets-manager.advok8s.io/vault-secret"
+
+	// Annotation on a secret recording the Vault path it was read from.
+	vaultPathAnnotation = "secrets-manager.advok8s.io/vault-path"
+
+	// Annotation on a secret recording the version of the Vault secret it
+	// holds. Only set for version 2 of the KV secrets engine.
+	vaultVersionAnnotation = "secrets-manager.advok8s.io/vault-version"
+)
+
+// VaultSecretReconciler reconciles a VaultSecret object
+type VaultSecretReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Recorder for events about VaultSecret objects.
+	Recorder record.EventRecorder
+
+	// Controller wide settings which can be changed while running. If nil,
+	// the built in defaults are used.
+	Config *ManagerConfig
+
+	// If set, secrets for VaultSecret objects are never written.
+	ReportOnly bool
+
+	// HTTP client used for requests against Vault where no CA certificates
+	// are given for a VaultSecret. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+}
+
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=vaultsecrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=vaultsecrets/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=vaultsecrets/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts/token,verbs=create
+
+// Reconcile a VaultSecret by reading the secret from the KV secrets engine of
+// the Vault server and writing its data to a secret in the namespace of the
+// VaultSecret. The secret is read again from Vault each refresh interval, so
+// changes made in Vault are picked up, and from there propagated by any
+// SecretCopier using the secret as a source.
+func (r *VaultSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	// Fetch the named VaultSecret object.
+
+	var vaultSecret secretsv1beta1.VaultSecret
+
+	if err := r.Get(ctx, req.NamespacedName, &vaultSecret); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			// Custom resource has been deleted. The secret created for it
+			// will be deleted by the garbage collector as the VaultSecret is
+			// set as its owner.
+
+			log.V(1).Info("VaultSecret has been deleted", "name", req.NamespacedName)
+
+			return ctrl.Result{}, nil
+		}
+
+		log.Error(err, "Unable to fetch VaultSecret", "name", req.NamespacedName)
+
+		return ctrl.Result{}, err
+	}
+
+	refreshInterval := defaultExternalRefreshInterval
+
+	if vaultSecret.Spec.RefreshInterval != nil && vaultSecret.Spec.RefreshInterval.Duration > 0 {
+		refreshInterval = vaultSecret.Spec.RefreshInterval.Duration
+	}
+
+	secretName := vaultSecret.TargetSecretName()
+
+	status := *vaultSecret.Status.DeepCopy()
+
+	status.ObservedGeneration = vaultSecret.Generation
+
+	condition := metav1.Condition{ObservedGeneration: vaultSecret.Generation}
+
+	secret, err := r.readVaultSecret(ctx, &vaultSecret)
+
+	if err != nil {
+		condition.Reason = "ReadFailed"
+		condition.Message = err.Error()
+
+		var authErr *externalAuthError
+
+		if errors.As(err, &authErr) {
+			condition.Reason = "AuthenticationFailed"
+		}
+	} else {
+		version := ""
+
+		synced := fmt.Sprintf("Secret %s synced from %s", secretName, vaultSecret.Spec.Path)
+
+		if secret.Version != 0 {
+			version = strconv.FormatInt(secret.Version, 10)
+			synced = fmt.Sprintf("%s at version %s", synced, version)
+		}
+
+		annotations := map[string]string{
+			vaultSecretAnnotation:  vaultSecret.Name,
+			vaultPathAnnotation:    vaultSecret.Spec.Path,
+			vaultVersionAnnotation: version,
+		}
+
+		writer := &externalSecretWriter{Client: r.Client, Scheme: r.Scheme, Config: r.Config, ReportOnly: r.ReportOnly, Controller: "vaultsecret"}
+
+		condition.Reason, condition.Message, err = writer.write(ctx, &vaultSecret, "VaultSecret", secretName, secret.Data, annotations, synced)
+
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if condition.Reason == "Synced" {
+			now := metav1.Now()
+
+			status.SecretName = secretName
+			status.SecretVersion = secret.Version
+			status.LastRefreshTime = &now
+		}
+	}
+
+	requeueAfter := setExternalSecretReadyCondition(r.Recorder, &vaultSecret, &status.Conditions, condition, refreshInterval, r.Config)
+
+	if err := r.updateVaultSecretStatus(ctx, &vaultSecret, status); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// Read the secret for the VaultSecret from Vault, authenticating as
+// configured. Errors are reported in the status of the VaultSecret rather
+// than retried immediately, as they are usually due to configuration of the
+// VaultSecret or Vault itself.
+func (r *VaultSecretReconciler) readVaultSecret(ctx context.Context, vaultSecret *secretsv1beta1.VaultSecret) (*vault.Secret, error) {
+	spec := &vaultSecret.Spec
+
+	vaultClient := &vault.Client{
+		Address:    spec.Address,
+		Namespace:  spec.Namespace,
+		HTTPClient: r.HTTPClient,
+	}
+
+	if spec.CACertificates != nil {
+		caCertificates, err := readSecretKey(ctx, r, vaultSecret.Namespace, spec.CACertificates.Name, spec.CACertificates.Key)
+
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+
+		if !pool.AppendCertsFromPEM(caCertificates) {
+			return nil, fmt.Errorf("no valid CA certificates in key %s of secret %s", spec.CACertificates.Key, spec.CACertificates.Name)
+		}
+
+		vaultClient.HTTPClient = &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+			},
+		}
+	}
+
+	switch {
+	case spec.Auth.Token != nil:
+		token, err := readSecretKey(ctx, r, vaultSecret.Namespace, spec.Auth.Token.Name, spec.Auth.Token.Key)
+
+		if err != nil {
+			return nil, &externalAuthError{"Vault", err}
+		}
+
+		vaultClient.Token = string(token)
+
+	case spec.Auth.Kubernetes != nil:
+		auth := spec.Auth.Kubernetes
+
+		serviceAccountName := auth.ServiceAccountName
+
+		if serviceAccountName == "" {
+			serviceAccountName = "default"
+		}
+
+		mount := auth.Mount
+
+		if mount == "" {
+			mount = "kubernetes"
+		}
+
+		jwt, err := requestServiceAccountToken(ctx, r.Client, vaultSecret.Namespace, serviceAccountName, auth.Audiences)
+
+		if err != nil {
+			return nil, &externalAuthError{"Vault", err}
+		}
+
+		token, err := vaultClient.LoginKubernetes(ctx, mount, auth.Role, jwt)
+
+		if err != nil {
+			return nil, &externalAuthError{"Vault", err}
+		}
+
+		vaultClient.Token = token
+
+	default:
+		return nil, &externalAuthError{"Vault", errors.New("no authentication method configured")}
+	}
+
+	mount := spec.Mount
+
+	if mount == "" {
+		mount = "secret"
+	}
+
+	kvVersion := int(spec.KVVersion)
+
+	if kvVersion == 0 {
+		kvVersion = 2
+	}
+
+	secret, err := vaultClient.ReadKV(ctx, mount, spec.Path, kvVersion)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s/%s: %w", mount, spec.Path, err)
+	}
+
+	return secret, nil
+}
+
+// Update the status of the VaultSecret if it has changed.
+func (r *VaultSecretReconciler) updateVaultSecretStatus(ctx context.Context, vaultSecret *secretsv1beta1.VaultSecret, status secretsv1beta1.VaultSecretStatus) error {
+	log := log.FromContext(ctx)
+
+	if equality.Semantic.DeepEqual(vaultSecret.Status, status) {
+		return nil
+	}
+
+	vaultSecret.Status = status
+
+	if err := r.Status().Update(ctx, vaultSecret); err != nil {
+		log.Error(err, "Unable to update VaultSecret status", "name", vaultSecret.Name, "namespace", vaultSecret.Namespace)
+		return err
+	}
+
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager. Only changes to
+// the generation of a VaultSecret trigger a reconciliation, so the status
+// updated on each refresh doesn't cause Vault to be read again immediately.
+func (r *VaultSecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&secretsv1beta1.VaultSecret{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Owns(&corev1.Secret{}).
+		Complete(r)
+}
@@ -0,0 +1,281 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("VaultSecret Controller", func() {
+	ctx := context.Background()
+
+	request := ctrl.Request{NamespacedName: client.ObjectKey{Namespace: "team-1", Name: "database"}}
+
+	// Fake Vault server holding a single secret in a version 2 KV secrets
+	// engine, accepting either the root token or a token obtained by logging
+	// in with the Kubernetes auth method.
+
+	var mutex sync.Mutex
+	var password string
+	var version int64
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		password = "secret-1"
+		version = 1
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			switch r.URL.Path {
+			case "/v1/auth/kubernetes/login":
+				var body map[string]string
+
+				_ = json.NewDecoder(r.Body).Decode(&body)
+
+				if body["role"] != "team-1" || body["jwt"] != "service-account-token" {
+					w.WriteHeader(http.StatusForbidden)
+					_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+					return
+				}
+
+				_, _ = w.Write([]byte(`{"auth":{"client_token":"login-token"}}`))
+
+			case "/v1/secret/data/team-1/database":
+				if token := r.Header.Get("X-Vault-Token"); token != "root" && token != "login-token" {
+					w.WriteHeader(http.StatusForbidden)
+					_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+					return
+				}
+
+				_, _ = w.Write([]byte(`{"data":{"data":{"password":"` + password + `"},"metadata":{"version":` + strconv.FormatInt(version, 10) + `}}}`))
+
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		DeferCleanup(server.Close)
+	})
+
+	newVaultSecret := func(auth secretsv1beta1.VaultAuth) *secretsv1beta1.VaultSecret {
+		return &secretsv1beta1.VaultSecret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-1", Name: "database", Generation: 1},
+			Spec: secretsv1beta1.VaultSecretSpec{
+				Address: server.URL,
+				Path:    "team-1/database",
+				Auth:    auth,
+			},
+		}
+	}
+
+	tokenAuth := func() secretsv1beta1.VaultAuth {
+		return secretsv1beta1.VaultAuth{Token: &secretsv1beta1.VaultSecretKeyReference{Name: "vault-token", Key: "token"}}
+	}
+
+	newTokenSecret := func(token string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-1", Name: "vault-token"},
+			Data:       map[string][]byte{"token": []byte(token)},
+		}
+	}
+
+	newReconciler := func(vaultSecret *secretsv1beta1.VaultSecret, objects ...client.Object) (*VaultSecretReconciler, *record.FakeRecorder) {
+		recorder := record.NewFakeRecorder(10)
+
+		c := fake.NewClientBuilder().
+			WithObjects(append(objects, vaultSecret)...).
+			WithStatusSubresource(vaultSecret).
+			WithInterceptorFuncs(interceptor.Funcs{
+				SubResourceCreate: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, subResource client.Object, opts ...client.SubResourceCreateOption) error {
+					tokenRequest := subResource.(*authenticationv1.TokenRequest)
+					tokenRequest.Status.Token = obj.GetName() + "-token"
+					return nil
+				},
+			}).
+			Build()
+
+		return &VaultSecretReconciler{Client: c, Scheme: scheme.Scheme, Recorder: recorder}, recorder
+	}
+
+	fetch := func(reconciler *VaultSecretReconciler) (*secretsv1beta1.VaultSecret, *corev1.Secret) {
+		var vaultSecret secretsv1beta1.VaultSecret
+
+		Expect(reconciler.Get(ctx, request.NamespacedName, &vaultSecret)).To(Succeed())
+
+		var secret corev1.Secret
+
+		if err := reconciler.Get(ctx, request.NamespacedName, &secret); err != nil {
+			Expect(client.IgnoreNotFound(err)).To(Succeed())
+			return &vaultSecret, nil
+		}
+
+		return &vaultSecret, &secret
+	}
+
+	It("should sync the secret from Vault using a token", func() {
+		reconciler, recorder := newReconciler(newVaultSecret(tokenAuth()), newTokenSecret("root"))
+
+		result, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(5 * time.Minute))
+
+		vaultSecret, secret := fetch(reconciler)
+		Expect(secret).NotTo(BeNil())
+		Expect(secret.Type).To(Equal(corev1.SecretTypeOpaque))
+		Expect(secret.Data).To(Equal(map[string][]byte{"password": []byte("secret-1")}))
+		Expect(secret.Annotations).To(HaveKeyWithValue(vaultSecretAnnotation, "database"))
+		Expect(secret.Annotations).To(HaveKeyWithValue(vaultPathAnnotation, "team-1/database"))
+		Expect(secret.Annotations).To(HaveKeyWithValue(vaultVersionAnnotation, "1"))
+		Expect(metav1.IsControlledBy(secret, vaultSecret)).To(BeTrue())
+
+		Expect(vaultSecret.Status.SecretName).To(Equal("database"))
+		Expect(vaultSecret.Status.SecretVersion).To(Equal(int64(1)))
+		Expect(vaultSecret.Status.LastRefreshTime).NotTo(BeNil())
+		Expect(meta.IsStatusConditionTrue(vaultSecret.Status.Conditions, secretsv1beta1.ConditionReady)).To(BeTrue())
+		Expect(recorder.Events).To(Receive(ContainSubstring("Synced Secret database synced from team-1/database at version 1")))
+
+		// A new version of the secret in Vault is picked up on refresh.
+
+		mutex.Lock()
+		password = "secret-2"
+		version = 2
+		mutex.Unlock()
+
+		_, err = reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		vaultSecret, secret = fetch(reconciler)
+		Expect(secret.Data).To(Equal(map[string][]byte{"password": []byte("secret-2")}))
+		Expect(secret.Annotations).To(HaveKeyWithValue(vaultVersionAnnotation, "2"))
+		Expect(vaultSecret.Status.SecretVersion).To(Equal(int64(2)))
+	})
+
+	It("should log in using the Kubernetes auth method", func() {
+		auth := secretsv1beta1.VaultAuth{Kubernetes: &secretsv1beta1.VaultKubernetesAuth{Role: "team-1", ServiceAccountName: "service-account"}}
+
+		reconciler, _ := newReconciler(newVaultSecret(auth))
+
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, secret := fetch(reconciler)
+		Expect(secret).NotTo(BeNil())
+		Expect(secret.Data).To(Equal(map[string][]byte{"password": []byte("secret-1")}))
+	})
+
+	It("should report failures to read from Vault and retry sooner", func() {
+		tests := []struct {
+			name    string
+			auth    secretsv1beta1.VaultAuth
+			objects []client.Object
+			reason  string
+		}{
+			{
+				name:    "rejected token",
+				auth:    tokenAuth(),
+				objects: []client.Object{newTokenSecret("wrong")},
+				reason:  "ReadFailed",
+			},
+			{
+				name:   "missing token secret",
+				auth:   tokenAuth(),
+				reason: "AuthenticationFailed",
+			},
+			{
+				name:   "rejected login",
+				auth:   secretsv1beta1.VaultAuth{Kubernetes: &secretsv1beta1.VaultKubernetesAuth{Role: "other"}},
+				reason: "AuthenticationFailed",
+			},
+		}
+
+		for _, test := range tests {
+			By(test.name)
+
+			reconciler, recorder := newReconciler(newVaultSecret(test.auth), test.objects...)
+
+			result, err := reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Minute))
+
+			vaultSecret, secret := fetch(reconciler)
+			Expect(secret).To(BeNil())
+
+			condition := meta.FindStatusCondition(vaultSecret.Status.Conditions, secretsv1beta1.ConditionReady)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(test.reason))
+			Expect(recorder.Events).To(Receive(HavePrefix("Warning " + test.reason)))
+
+			// The same failure is not reported again.
+
+			_, err = reconciler.Reconcile(ctx, request)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorder.Events).NotTo(Receive())
+		}
+	})
+
+	It("should not overwrite a secret it doesn't manage", func() {
+		existing := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-1", Name: "database"},
+			Data:       map[string][]byte{"password": []byte("original")},
+		}
+
+		reconciler, _ := newReconciler(newVaultSecret(tokenAuth()), newTokenSecret("root"), existing)
+
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		vaultSecret, secret := fetch(reconciler)
+		Expect(secret.Data).To(Equal(map[string][]byte{"password": []byte("original")}))
+		Expect(meta.FindStatusCondition(vaultSecret.Status.Conditions, secretsv1beta1.ConditionReady).Reason).To(Equal("SecretConflict"))
+	})
+
+	It("should not write the secret in report-only mode", func() {
+		reconciler, _ := newReconciler(newVaultSecret(tokenAuth()), newTokenSecret("root"))
+		reconciler.ReportOnly = true
+
+		_, err := reconciler.Reconcile(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+
+		vaultSecret, secret := fetch(reconciler)
+		Expect(secret).To(BeNil())
+		Expect(meta.FindStatusCondition(vaultSecret.Status.Conditions, secretsv1beta1.ConditionReady).Reason).To(Equal("WritesSuspended"))
+	})
+})
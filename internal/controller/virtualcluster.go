@@ -0,0 +1,179 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+const (
+	// Prefix of the name of the kubeconfig secret vcluster creates for a
+	// virtual cluster, followed by the name of the virtual cluster.
+	virtualClusterKubeconfigPrefix = "vc-"
+
+	// Data key of the kubeconfig secret vcluster creates for a virtual
+	// cluster.
+	virtualClusterKubeconfigKey = "config"
+)
+
+// Target cluster for the virtual cluster of the given name, accessed using the
+// kubeconfig secret vcluster created for it.
+func virtualTargetCluster(rule *secretsv1beta1.SecretCopierRule, name string) *secretsv1beta1.TargetCluster {
+	return &secretsv1beta1.TargetCluster{
+		KubeconfigSecret: secretsv1beta1.KubeconfigSecretReference{
+			Namespace: rule.VirtualClusterNamespaces.KubeconfigSecretNamespace,
+			Name:      virtualClusterKubeconfigPrefix + name,
+			Key:       virtualClusterKubeconfigKey,
+		},
+	}
+}
+
+// Determine if a secret could hold the kubeconfig for a virtual cluster the
+// rule copies into.
+func isVirtualClusterKubeconfigSecret(rule *secretsv1beta1.SecretCopierRule, key client.ObjectKey) bool {
+	if rule.VirtualClusterNamespaces == nil || rule.VirtualClusterNamespaces.Policy != secretsv1beta1.VirtualClusterNamespaceCopyInto {
+		return false
+	}
+
+	return key.Namespace == rule.VirtualClusterNamespaces.KubeconfigSecretNamespace && strings.HasPrefix(key.Name, virtualClusterKubeconfigPrefix)
+}
+
+// Copy the source secret of a rule into the virtual clusters held by matching
+// namespaces vcluster created, where the rule copies into virtual clusters
+// rather than to the namespaces themselves. Each namespace is copied to the
+// namespace of the virtual cluster it holds, with the outcome recorded in the
+// status of the rule against the namespace of this cluster. Returns the
+// target namespaces where copying failed, and whether any writes were
+// deferred by the secret write rate limit.
+func (r *SecretCopierReconciler) copyRuleToVirtualClusters(ctx context.Context, secretsClient client.Client, secretCopier *secretsv1beta1.SecretCopier, ruleIndex int, rule *secretsv1beta1.SecretCopierRule, ruleStatus *secretsv1beta1.SecretCopierRuleStatus, namespaces []corev1.Namespace, matcher *selectors.TargetNamespacesMatcher, delegations delegationScopes, outcome *ruleOutcome) ([]secretsv1beta1.SecretCopierFailedTarget, bool) {
+	log := log.FromContext(ctx)
+
+	// Group the matching namespaces by the virtual cluster they hold. The
+	// same checks are applied as for any other target namespace.
+
+	virtualClusters := make(map[string][]*corev1.Namespace)
+
+	for i := range namespaces {
+		namespace := &namespaces[i]
+
+		if namespace.Name == rule.SourceSecret.Namespace || !rule.CopiesIntoVirtualCluster(namespace) || !matcher.Matches(namespace) {
+			continue
+		}
+
+		if rule.SkipsInactiveNamespace(namespace) {
+			ruleStatus.SkippedNamespaces++
+			continue
+		}
+
+		if !delegations.permits(rule.SourceSecret.Namespace, namespace) {
+			ruleStatus.UndelegatedNamespaces++
+			continue
+		}
+
+		if r.Config.Settings().NamespaceUnconsented(rule, namespace) {
+			ruleStatus.UnconsentedNamespaces++
+			continue
+		}
+
+		name := secretsv1beta1.VirtualClusterOf(namespace)
+
+		virtualClusters[name] = append(virtualClusters[name], namespace)
+
+		ruleStatus.VirtualClusterNamespaces++
+	}
+
+	if len(virtualClusters) == 0 {
+		return nil, false
+	}
+
+	// Problems with the source secret are reported in the status of the
+	// rule when processing the namespaces of this cluster, so aren't
+	// reported again here.
+
+	var sourceSecret corev1.Secret
+
+	if err := secretsClient.Get(ctx, client.ObjectKey{Namespace: rule.SourceSecret.Namespace, Name: rule.SourceSecret.Name}, &sourceSecret); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Unable to fetch source secret", "sourceSecret", rule.SourceSecret)
+		}
+
+		return nil, false
+	}
+
+	if r.Config.Settings().SecretTypeDenied(sourceSecret.Type) {
+		return nil, false
+	}
+
+	content, contentErr := targetSecretContent(rule, &sourceSecret)
+
+	if contentErr != nil {
+		log.Error(contentErr, "Unable to render target secret from templates", "sourceSecret", rule.SourceSecret)
+	}
+
+	names := make([]string, 0, len(virtualClusters))
+
+	for name := range virtualClusters {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	var failedTargets []secretsv1beta1.SecretCopierFailedTarget
+
+	throttled := false
+
+	for _, name := range names {
+		targetClient, err := r.targetClusters.get(ctx, secretsClient, r.Scheme, virtualTargetCluster(rule, name))
+
+		if err != nil {
+			log.Error(err, "Unable to create client for virtual cluster", "virtualCluster", name)
+
+			err = fmt.Errorf("unable to access virtual cluster %s: %w", name, err)
+		} else if contentErr != nil {
+			err = contentErr
+		}
+
+		// If the virtual cluster can't be accessed, or the target secret
+		// can't be rendered, copying to each of its namespaces fails.
+
+		for _, namespace := range virtualClusters[name] {
+			result, copyErr := copyFailed, err
+
+			if err == nil {
+				result, copyErr = r.copySecretToTargetClusterNamespace(ctx, targetClient, secretCopier, rule, content, secretsv1beta1.VirtualNamespaceOf(namespace))
+			}
+
+			if failedTarget := r.recordTargetClusterCopy(secretCopier, ruleIndex, rule, ruleStatus, outcome, namespace.Name, result, copyErr); failedTarget != nil {
+				failedTargets = append(failedTargets, *failedTarget)
+			}
+
+			throttled = throttled || result == copyThrottled
+		}
+	}
+
+	return failedTargets, throttled
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+var _ = Describe("Virtual Clusters", func() {
+	ctx := context.Background()
+
+	const kubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: team-a
+  cluster:
+    server: https://team-a.vclusters.svc:443
+contexts:
+- name: team-a
+  context:
+    cluster: team-a
+    user: team-a
+current-context: team-a
+users:
+- name: team-a
+  user:
+    token: team-a-token
+`
+
+	secretCopier := &secretsv1beta1.SecretCopier{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry-copier", UID: "registry-uid"},
+	}
+
+	newRule := func(policy secretsv1beta1.VirtualClusterNamespacePolicy) *secretsv1beta1.SecretCopierRule {
+		return &secretsv1beta1.SecretCopierRule{
+			SourceSecret:  secretsv1beta1.SourceSecret{Name: "registry", Namespace: "registries"},
+			ReclaimPolicy: secretsv1beta1.ReclaimDelete,
+			TargetNamespaces: selectors.TargetNamespaces{
+				NameSelector: selectors.NameSelector{MatchNames: []string{"*"}},
+			},
+			VirtualClusterNamespaces: &secretsv1beta1.VirtualClusterNamespaces{
+				Policy:                    policy,
+				KubeconfigSecretNamespace: "vclusters",
+			},
+		}
+	}
+
+	newNamespace := func(name string, virtualCluster string, virtualNamespace string) corev1.Namespace {
+		namespace := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+
+		if virtualCluster != "" {
+			namespace.Labels = map[string]string{secretsv1beta1.VirtualClusterManagedByLabel: virtualCluster}
+		}
+
+		if virtualNamespace != "" {
+			namespace.Annotations = map[string]string{secretsv1beta1.VirtualClusterObjectNameAnnotation: virtualNamespace}
+		}
+
+		return namespace
+	}
+
+	It("should decide which namespaces the rule skips", func() {
+		plain := newNamespace("apps", "", "")
+		synced := newNamespace("apps-x-team-a", "team-a", "apps")
+
+		tests := []struct {
+			policy       secretsv1beta1.VirtualClusterNamespacePolicy
+			skipsSynced  bool
+			copiesSynced bool
+		}{
+			{policy: secretsv1beta1.VirtualClusterNamespaceTarget, skipsSynced: false, copiesSynced: false},
+			{policy: secretsv1beta1.VirtualClusterNamespaceSkip, skipsSynced: true, copiesSynced: false},
+			{policy: secretsv1beta1.VirtualClusterNamespaceCopyInto, skipsSynced: true, copiesSynced: true},
+		}
+
+		for _, test := range tests {
+			rule := newRule(test.policy)
+
+			Expect(rule.SkipsNamespace(&plain)).To(BeFalse(), string(test.policy))
+			Expect(rule.CopiesIntoVirtualCluster(&plain)).To(BeFalse(), string(test.policy))
+			Expect(rule.SkipsNamespace(&synced)).To(Equal(test.skipsSynced), string(test.policy))
+			Expect(rule.CopiesIntoVirtualCluster(&synced)).To(Equal(test.copiesSynced), string(test.policy))
+		}
+
+		Expect(secretsv1beta1.VirtualNamespaceOf(&synced)).To(Equal("apps"))
+		Expect(secretsv1beta1.VirtualNamespaceOf(&plain)).To(Equal("apps"))
+
+		Expect(kubeconfigSecretMatches(newRule(secretsv1beta1.VirtualClusterNamespaceCopyInto), client.ObjectKey{Namespace: "vclusters", Name: "vc-team-a"})).To(BeTrue())
+		Expect(kubeconfigSecretMatches(newRule(secretsv1beta1.VirtualClusterNamespaceSkip), client.ObjectKey{Namespace: "vclusters", Name: "vc-team-a"})).To(BeFalse())
+	})
+
+	It("should copy into the namespaces of virtual clusters", func() {
+		localClient := fake.NewClientBuilder().WithObjects(
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "vc-team-a", Namespace: "vclusters", ResourceVersion: "1"},
+				Data:       map[string][]byte{"config": []byte(kubeconfig)},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "registries"},
+				Type:       corev1.SecretTypeOpaque,
+				Data:       map[string][]byte{"token": []byte("registry-token")},
+			},
+		).Build()
+
+		remoteClient := fake.NewClientBuilder().WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}},
+		).Build()
+
+		reconciler := &SecretCopierReconciler{Client: localClient, Recorder: record.NewFakeRecorder(10)}
+
+		reconciler.targetClusters.newClient = func(config *rest.Config, scheme *runtime.Scheme) (client.Client, error) {
+			Expect(config.Host).To(Equal("https://team-a.vclusters.svc:443"))
+
+			return remoteClient, nil
+		}
+
+		terminating := newNamespace("web-x-team-a", "team-a", "web")
+		terminating.Status.Phase = corev1.NamespaceTerminating
+
+		namespaces := []corev1.Namespace{
+			newNamespace("apps", "", ""),
+			newNamespace("apps-x-team-a", "team-a", "apps"),
+			newNamespace("apps-x-team-b", "team-b", "apps"),
+			terminating,
+		}
+
+		rule := newRule(secretsv1beta1.VirtualClusterNamespaceCopyInto)
+
+		ruleStatus := secretsv1beta1.SecretCopierRuleStatus{}
+		outcome := ruleOutcome{}
+
+		failedTargets, throttled := reconciler.copyRuleToVirtualClusters(ctx, localClient, secretCopier, 0, rule, &ruleStatus, namespaces, rule.TargetNamespaces.Compile(), delegationScopes{}, &outcome)
+
+		Expect(throttled).To(BeFalse())
+
+		Expect(ruleStatus.VirtualClusterNamespaces).To(Equal(int32(2)))
+		Expect(ruleStatus.SkippedNamespaces).To(Equal(int32(1)))
+		Expect(ruleStatus.SyncedNamespaces).To(Equal(int32(1)))
+
+		// The copy is made in the namespace of the virtual cluster.
+
+		var targetSecret corev1.Secret
+
+		Expect(remoteClient.Get(ctx, client.ObjectKey{Namespace: "apps", Name: "registry"}, &targetSecret)).To(Succeed())
+
+		Expect(targetSecret.Data).To(HaveKeyWithValue("token", []byte("registry-token")))
+		Expect(targetSecret.OwnerReferences).To(BeEmpty())
+
+		// The virtual cluster without a kubeconfig secret fails, recorded
+		// against the namespace of this cluster.
+
+		Expect(failedTargets).To(HaveLen(1))
+		Expect(failedTargets[0].Namespace).To(Equal("apps-x-team-b"))
+		Expect(failedTargets[0].Message).To(ContainSubstring("unable to access virtual cluster team-b"))
+
+		// Nothing is copied where the rule doesn't copy into virtual
+		// clusters.
+
+		ruleStatus = secretsv1beta1.SecretCopierRuleStatus{}
+
+		rule = newRule(secretsv1beta1.VirtualClusterNamespaceSkip)
+
+		failedTargets, _ = reconciler.copyRuleToVirtualClusters(ctx, localClient, secretCopier, 0, rule, &ruleStatus, namespaces, rule.TargetNamespaces.Compile(), delegationScopes{}, &outcome)
+
+		Expect(failedTargets).To(BeEmpty())
+		Expect(ruleStatus.VirtualClusterNamespaces).To(BeZero())
+	})
+
+	It("should not treat namespaces of virtual clusters as targets when applying once or planning", func() {
+		rule := newRule(secretsv1beta1.VirtualClusterNamespaceCopyInto)
+
+		plain := newNamespace("apps", "", "")
+		synced := newNamespace("apps-x-team-a", "team-a", "apps")
+		source := newNamespace("registries", "", "")
+
+		secretCopier := &secretsv1beta1.SecretCopier{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry-copier"},
+			Spec:       secretsv1beta1.SecretCopierSpec{Rules: []secretsv1beta1.SecretCopierRule{*rule}},
+		}
+
+		localClient := fake.NewClientBuilder().WithObjects(&plain, &synced, &source, secretCopier,
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "registries"},
+				Data:       map[string][]byte{"token": []byte("registry-token")},
+			},
+		).Build()
+
+		reconciler := &SecretCopierReconciler{Client: localClient, Recorder: record.NewFakeRecorder(10)}
+
+		check, err := reconciler.checkTargetNamespace(ctx, defaultManagerSettings, nil, rule, rule.TargetNamespaces.Compile(), &synced)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(check).To(Equal(targetNamespaceVirtualCluster))
+
+		summary, err := reconciler.ApplyOnce(ctx, secretCopier)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(summary.Rules[0].Targets).To(HaveLen(1))
+		Expect(summary.Rules[0].Targets[0].Namespace).To(Equal("apps"))
+
+		plan, err := CollectCopyPlan(ctx, localClient, defaultManagerSettings)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.Targets).To(HaveLen(1))
+		Expect(plan.Targets[0].TargetNamespace).To(Equal("apps"))
+	})
+
+	It("should reconcile when a namespace is labelled as belonging to a virtual cluster", func() {
+		reconciler := &SecretCopierReconciler{Client: fake.NewClientBuilder().Build()}
+
+		predicate := reconciler.namespaceSelectorFieldsChanged()
+
+		plain := newNamespace("apps-x-team-a", "", "")
+		synced := newNamespace("apps-x-team-a", "team-a", "")
+
+		Expect(predicate.Update(event.UpdateEvent{ObjectOld: &plain, ObjectNew: &synced})).To(BeTrue())
+		Expect(predicate.Update(event.UpdateEvent{ObjectOld: &synced, ObjectNew: &plain})).To(BeTrue())
+		Expect(predicate.Update(event.UpdateEvent{ObjectOld: &synced, ObjectNew: &synced})).To(BeFalse())
+	})
+})
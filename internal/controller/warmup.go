@@ -0,0 +1,138 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"hash/fnv"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Schedule for spreading the reconciliations made when the controller starts
+// over a window. When the informer caches are first populated, a create event
+// is delivered for every existing SecretCopier, secret and namespace, which
+// would otherwise result in every SecretCopier being reconciled at once. Each
+// SecretCopier is instead given a slot within the window, determined from its
+// name, and requests resulting from the initial population of the caches are
+// delayed until that slot. A nil schedule disables the warm-up.
+type warmUp struct {
+	start  time.Time
+	window time.Duration
+}
+
+// Create a warm-up schedule starting now. Returns nil if the window is zero.
+func newWarmUp(window time.Duration, start time.Time) *warmUp {
+	if window <= 0 {
+		return nil
+	}
+
+	return &warmUp{start: start, window: window}
+}
+
+// Determine if a create event is for an object which existed before the
+// controller started, as is the case when the informer caches are first
+// populated.
+func (w *warmUp) initial(object client.Object) bool {
+	return w != nil && object.GetCreationTimestamp().Time.Before(w.start)
+}
+
+// Calculate how long the request should be delayed so it is processed in its
+// slot. Once the slot has passed no delay is required.
+func (w *warmUp) delay(request reconcile.Request, now time.Time) time.Duration {
+	if w == nil {
+		return 0
+	}
+
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(request.String()))
+
+	slot := time.Duration(hash.Sum64() % uint64(w.window))
+
+	return max(w.start.Add(slot).Sub(now), 0)
+}
+
+// Add the request to the work queue, delayed until its slot if the warm-up
+// is still in progress.
+func (w *warmUp) enqueue(q workqueue.TypedRateLimitingInterface[reconcile.Request], request reconcile.Request) {
+	if delay := w.delay(request, time.Now()); delay > 0 {
+		q.AddAfter(request, delay)
+	} else {
+		q.Add(request)
+	}
+}
+
+// Create an event handler for SecretCopier objects, the same as
+// handler.EnqueueRequestForObject except that the create events for existing
+// SecretCopier objects when the controller starts are spread over the warm-up
+// window.
+func enqueueSecretCopierWithWarmUp(w *warmUp) handler.EventHandler {
+	requestFor := func(object client.Object) reconcile.Request {
+		return reconcile.Request{NamespacedName: client.ObjectKeyFromObject(object)}
+	}
+
+	return handler.Funcs{
+		CreateFunc: func(ctx context.Context, e event.CreateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			if w.initial(e.Object) {
+				w.enqueue(q, requestFor(e.Object))
+				return
+			}
+
+			q.Add(requestFor(e.Object))
+		},
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			q.Add(requestFor(e.ObjectNew))
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			q.Add(requestFor(e.Object))
+		},
+		GenericFunc: func(ctx context.Context, e event.GenericEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			q.Add(requestFor(e.Object))
+		},
+	}
+}
+
+// Create an event handler which maps events to reconcile requests using the
+// supplied map function, the same as handler.EnqueueRequestsFromMapFunc, but
+// with requests delayed until their slot while the warm-up is in progress.
+func enqueueRequestsFromMapFuncWithWarmUp(fn handler.MapFunc, w *warmUp) handler.EventHandler {
+	enqueue := func(ctx context.Context, q workqueue.TypedRateLimitingInterface[reconcile.Request], object client.Object) {
+		for _, request := range fn(ctx, object) {
+			w.enqueue(q, request)
+		}
+	}
+
+	return handler.Funcs{
+		CreateFunc: func(ctx context.Context, e event.CreateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(ctx, q, e.Object)
+		},
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(ctx, q, e.ObjectNew)
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(ctx, q, e.Object)
+		},
+		GenericFunc: func(ctx context.Context, e event.GenericEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(ctx, q, e.Object)
+		},
+	}
+}
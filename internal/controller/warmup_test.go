@@ -0,0 +1,132 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+var _ = Describe("Warm-up Scheduling", func() {
+	start := time.Now()
+
+	request := func(name string) reconcile.Request {
+		return reconcile.Request{NamespacedName: types.NamespacedName{Name: name}}
+	}
+
+	secretCopier := func(name string, created time.Time) *secretsv1beta1.SecretCopier {
+		return &secretsv1beta1.SecretCopier{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created)},
+		}
+	}
+
+	newQueue := func() workqueue.TypedRateLimitingInterface[reconcile.Request] {
+		return workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+	}
+
+	It("should be disabled without a window", func() {
+		Expect(newWarmUp(0, start)).To(BeNil())
+
+		var w *warmUp
+
+		Expect(w.initial(secretCopier("copier", start.Add(-time.Hour)))).To(BeFalse())
+		Expect(w.delay(request("copier"), start)).To(BeZero())
+	})
+
+	It("should spread requests over the window and not delay them once their slot has passed", func() {
+		w := newWarmUp(time.Minute, start)
+
+		slots := make(map[time.Duration]bool)
+
+		for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+			delay := w.delay(request(name), start)
+
+			Expect(delay).To(BeNumerically(">=", 0))
+			Expect(delay).To(BeNumerically("<", time.Minute))
+			Expect(w.delay(request(name), start)).To(Equal(delay))
+			Expect(w.delay(request(name), start.Add(time.Minute))).To(BeZero())
+
+			slots[delay] = true
+		}
+
+		Expect(len(slots)).To(BeNumerically(">", 1))
+	})
+
+	It("should only treat objects created before the start as initial", func() {
+		w := newWarmUp(time.Minute, start)
+
+		Expect(w.initial(secretCopier("existing", start.Add(-time.Hour)))).To(BeTrue())
+		Expect(w.initial(secretCopier("new", start.Add(time.Hour)))).To(BeFalse())
+	})
+
+	It("should delay the initial create of existing SecretCopiers only", func() {
+		w := newWarmUp(time.Hour, time.Now())
+		handler := enqueueSecretCopierWithWarmUp(w)
+		ctx := context.Background()
+
+		existing := secretCopier("existing", start.Add(-time.Hour))
+
+		// Choose a name whose slot is not at the very start of the window.
+
+		for w.delay(request(existing.Name), time.Now()) < time.Second {
+			existing.Name += "x"
+		}
+
+		queue := newQueue()
+		defer queue.ShutDown()
+
+		handler.Create(ctx, event.CreateEvent{Object: existing}, queue)
+		Expect(queue.Len()).To(BeZero())
+
+		handler.Update(ctx, event.UpdateEvent{ObjectOld: existing, ObjectNew: existing}, queue)
+		Expect(queue.Len()).To(Equal(1))
+
+		queue = newQueue()
+		defer queue.ShutDown()
+
+		handler.Create(ctx, event.CreateEvent{Object: secretCopier("new", time.Now().Add(time.Hour))}, queue)
+		Expect(queue.Len()).To(Equal(1))
+
+		coalesced := enqueueCoalescedRequestsFromMapFunc(func(context.Context, client.Object) []reconcile.Request {
+			return []reconcile.Request{request(existing.Name)}
+		}, 0, w)
+
+		queue = newQueue()
+		defer queue.ShutDown()
+
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "secret", CreationTimestamp: metav1.NewTime(start.Add(-time.Hour))}}
+
+		coalesced.Create(ctx, event.CreateEvent{Object: secret}, queue)
+		Expect(queue.Len()).To(BeZero())
+
+		coalesced.Update(ctx, event.UpdateEvent{ObjectOld: secret, ObjectNew: secret}, queue)
+		Expect(queue.Len()).To(Equal(1))
+	})
+})
@@ -0,0 +1,401 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csiprovider
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The messages of the v1alpha1 provider API of the Secrets Store CSI driver.
+// They are encoded by hand rather than generated from the protobuf definition
+// of the API, to avoid depending on the driver module for a handful of simple
+// messages. Field numbers must match those of the API.
+
+// VersionRequest is the request for the Version RPC.
+type VersionRequest struct {
+	Version string
+}
+
+// VersionResponse is the response for the Version RPC.
+type VersionResponse struct {
+	Version        string
+	RuntimeName    string
+	RuntimeVersion string
+}
+
+// MountRequest is the request for the Mount RPC.
+type MountRequest struct {
+	// JSON encoded attributes of the volume, including the parameters of the
+	// SecretProviderClass and details of the pod.
+	Attributes string
+
+	// JSON encoded node publish secrets. Unused by this provider.
+	Secrets string
+
+	// Path the volume is mounted at.
+	TargetPath string
+
+	// JSON encoded file permission.
+	Permission string
+
+	// Versions of the objects currently mounted.
+	CurrentObjectVersion []*ObjectVersion
+}
+
+// MountResponse is the response for the Mount RPC.
+type MountResponse struct {
+	ObjectVersion []*ObjectVersion
+	Error         *Error
+	Files         []*File
+}
+
+// ObjectVersion identifies the version of an object mounted into a pod.
+type ObjectVersion struct {
+	ID      string
+	Version string
+}
+
+// Error is an error code returned by the Mount RPC.
+type Error struct {
+	Code string
+}
+
+// File is the content of a file to write into the volume.
+type File struct {
+	Path     string
+	Mode     int32
+	Contents []byte
+}
+
+// A message which can be encoded to and decoded from the protobuf wire format.
+type wireMessage interface {
+	marshal(b []byte) []byte
+	unmarshal(b []byte) error
+}
+
+func (m *VersionRequest) marshal(b []byte) []byte {
+	return appendString(b, 1, m.Version)
+}
+
+func (m *VersionRequest) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 {
+			return consumeString(typ, b, &m.Version)
+		}
+
+		return skipField(num, typ, b)
+	})
+}
+
+func (m *VersionResponse) marshal(b []byte) []byte {
+	b = appendString(b, 1, m.Version)
+	b = appendString(b, 2, m.RuntimeName)
+	b = appendString(b, 3, m.RuntimeVersion)
+
+	return b
+}
+
+func (m *VersionResponse) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeString(typ, b, &m.Version)
+		case 2:
+			return consumeString(typ, b, &m.RuntimeName)
+		case 3:
+			return consumeString(typ, b, &m.RuntimeVersion)
+		}
+
+		return skipField(num, typ, b)
+	})
+}
+
+func (m *MountRequest) marshal(b []byte) []byte {
+	b = appendString(b, 1, m.Attributes)
+	b = appendString(b, 2, m.Secrets)
+	b = appendString(b, 3, m.TargetPath)
+	b = appendString(b, 4, m.Permission)
+
+	for _, version := range m.CurrentObjectVersion {
+		b = appendMessage(b, 5, version)
+	}
+
+	return b
+}
+
+func (m *MountRequest) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeString(typ, b, &m.Attributes)
+		case 2:
+			return consumeString(typ, b, &m.Secrets)
+		case 3:
+			return consumeString(typ, b, &m.TargetPath)
+		case 4:
+			return consumeString(typ, b, &m.Permission)
+		case 5:
+			version := &ObjectVersion{}
+			m.CurrentObjectVersion = append(m.CurrentObjectVersion, version)
+
+			return consumeMessage(typ, b, version)
+		}
+
+		return skipField(num, typ, b)
+	})
+}
+
+func (m *MountResponse) marshal(b []byte) []byte {
+	for _, version := range m.ObjectVersion {
+		b = appendMessage(b, 1, version)
+	}
+
+	if m.Error != nil {
+		b = appendMessage(b, 2, m.Error)
+	}
+
+	for _, file := range m.Files {
+		b = appendMessage(b, 3, file)
+	}
+
+	return b
+}
+
+func (m *MountResponse) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			version := &ObjectVersion{}
+			m.ObjectVersion = append(m.ObjectVersion, version)
+
+			return consumeMessage(typ, b, version)
+		case 2:
+			m.Error = &Error{}
+
+			return consumeMessage(typ, b, m.Error)
+		case 3:
+			file := &File{}
+			m.Files = append(m.Files, file)
+
+			return consumeMessage(typ, b, file)
+		}
+
+		return skipField(num, typ, b)
+	})
+}
+
+func (m *ObjectVersion) marshal(b []byte) []byte {
+	b = appendString(b, 1, m.ID)
+	b = appendString(b, 2, m.Version)
+
+	return b
+}
+
+func (m *ObjectVersion) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeString(typ, b, &m.ID)
+		case 2:
+			return consumeString(typ, b, &m.Version)
+		}
+
+		return skipField(num, typ, b)
+	})
+}
+
+func (m *Error) marshal(b []byte) []byte {
+	return appendString(b, 1, m.Code)
+}
+
+func (m *Error) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 {
+			return consumeString(typ, b, &m.Code)
+		}
+
+		return skipField(num, typ, b)
+	})
+}
+
+func (m *File) marshal(b []byte) []byte {
+	b = appendString(b, 1, m.Path)
+
+	if m.Mode != 0 {
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(m.Mode))
+	}
+
+	if len(m.Contents) != 0 {
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, m.Contents)
+	}
+
+	return b
+}
+
+func (m *File) unmarshal(b []byte) error {
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch num {
+		case 1:
+			return consumeString(typ, b, &m.Path)
+		case 2:
+			if typ != protowire.VarintType {
+				return 0, fmt.Errorf("unexpected wire type %d for field %d", typ, num)
+			}
+
+			value, n := protowire.ConsumeVarint(b)
+
+			if n < 0 {
+				return 0, protowire.ParseError(n)
+			}
+
+			m.Mode = int32(value)
+
+			return n, nil
+		case 3:
+			if typ != protowire.BytesType {
+				return 0, fmt.Errorf("unexpected wire type %d for field %d", typ, num)
+			}
+
+			value, n := protowire.ConsumeBytes(b)
+
+			if n < 0 {
+				return 0, protowire.ParseError(n)
+			}
+
+			m.Contents = append([]byte(nil), value...)
+
+			return n, nil
+		}
+
+		return skipField(num, typ, b)
+	})
+}
+
+// Append a string field, omitting it if empty as proto3 does.
+func appendString(b []byte, num protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+
+	return protowire.AppendString(b, value)
+}
+
+// Append an embedded message field.
+func appendMessage(b []byte, num protowire.Number, message wireMessage) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+
+	return protowire.AppendBytes(b, message.marshal(nil))
+}
+
+// Iterate over the fields of an encoded message, calling the handler for each
+// with the bytes following the tag. The handler returns the number of bytes
+// it consumed.
+func consumeFields(b []byte, handler func(num protowire.Number, typ protowire.Type, b []byte) (int, error)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+
+		b = b[n:]
+
+		n, err := handler(num, typ, b)
+
+		if err != nil {
+			return err
+		}
+
+		b = b[n:]
+	}
+
+	return nil
+}
+
+func consumeString(typ protowire.Type, b []byte, value *string) (int, error) {
+	if typ != protowire.BytesType {
+		return 0, fmt.Errorf("unexpected wire type %d for string field", typ)
+	}
+
+	s, n := protowire.ConsumeString(b)
+
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+
+	*value = s
+
+	return n, nil
+}
+
+func consumeMessage(typ protowire.Type, b []byte, message wireMessage) (int, error) {
+	if typ != protowire.BytesType {
+		return 0, fmt.Errorf("unexpected wire type %d for message field", typ)
+	}
+
+	value, n := protowire.ConsumeBytes(b)
+
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+
+	return n, message.unmarshal(value)
+}
+
+func skipField(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+	n := protowire.ConsumeFieldValue(num, typ, b)
+
+	if n < 0 {
+		return 0, protowire.ParseError(n)
+	}
+
+	return n, nil
+}
+
+// Codec encoding the provider API messages for gRPC. It is registered under
+// the name of the standard protobuf codec as that is what the driver uses.
+type codec struct{}
+
+func (codec) Marshal(v any) ([]byte, error) {
+	message, ok := v.(wireMessage)
+
+	if !ok {
+		return nil, fmt.Errorf("cannot marshal %T", v)
+	}
+
+	return message.marshal(nil), nil
+}
+
+func (codec) Unmarshal(data []byte, v any) error {
+	message, ok := v.(wireMessage)
+
+	if !ok {
+		return fmt.Errorf("cannot unmarshal into %T", v)
+	}
+
+	return message.unmarshal(data)
+}
+
+func (codec) Name() string {
+	return "proto"
+}
@@ -0,0 +1,365 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package csiprovider implements a provider for the Secrets Store CSI driver,
+// allowing pods to mount entries of a SecretCatalog as files without the
+// secret being copied into the namespace of the pod.
+package csiprovider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Name of the provider as given in the provider field of a
+// SecretProviderClass.
+const ProviderName = "advok8s-secrets-manager"
+
+// Default path of the socket the provider listens on. The driver looks for
+// providers in this directory.
+const DefaultSocketPath = "/etc/kubernetes/secrets-store-csi-providers/" + ProviderName + ".sock"
+
+// Version of the provider API implemented.
+const apiVersion = "v1alpha1"
+
+// Attributes of the volume supplied by the driver describing the pod.
+const (
+	podNamespaceAttribute      = "csi.storage.k8s.io/pod.namespace"
+	serviceAccountAttribute    = "csi.storage.k8s.io/serviceAccount.name"
+	objectsParameterAttribute  = "objects"
+	defaultFilePermissionValue = 0o644
+)
+
+// Object to mount, as listed in the objects parameter of a
+// SecretProviderClass.
+type mountObject struct {
+	// Name of the SecretCatalog.
+	Catalog string `json:"catalog"`
+
+	// Key of the entry in the SecretCatalog.
+	Key string `json:"key"`
+
+	// Directory within the volume to write the files for the data of the
+	// secret to. Defaults to the key of the entry.
+	Path string `json:"path,omitempty"`
+}
+
+// Server implements the provider API of the Secrets Store CSI driver. Each
+// object listed in the SecretProviderClass names an entry of a SecretCatalog,
+// which is subject to the same checks as a SecretClaim for the entry made from
+// the namespace of the pod.
+type Server struct {
+	Client client.Client
+
+	// If set, a SubjectAccessReview is performed for each object mounted to
+	// check that the service account of the pod has been granted access to
+	// the catalog entry through RBAC.
+	AuthorizeMounts bool
+
+	// Types of secret which are never mounted.
+	NeverMountSecretTypes []corev1.SecretType
+
+	// Version of the provider reported to the driver.
+	RuntimeVersion string
+}
+
+// Methods of the provider API.
+type providerServer interface {
+	Version(ctx context.Context, request *VersionRequest) (*VersionResponse, error)
+	Mount(ctx context.Context, request *MountRequest) (*MountResponse, error)
+}
+
+// Description of the provider API service used to register the server.
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: "v1alpha1.CSIDriverProvider",
+	HandlerType: (*providerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Version",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				request := &VersionRequest{}
+
+				if err := dec(request); err != nil {
+					return nil, err
+				}
+
+				return srv.(providerServer).Version(ctx, request)
+			},
+		},
+		{
+			MethodName: "Mount",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				request := &MountRequest{}
+
+				if err := dec(request); err != nil {
+					return nil, err
+				}
+
+				return srv.(providerServer).Mount(ctx, request)
+			},
+		},
+	},
+	Metadata: "service.proto",
+}
+
+// Serve the provider API on a unix socket until the context is cancelled.
+func (s *Server) Serve(ctx context.Context, socketPath string) error {
+	log := log.FromContext(ctx)
+
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to remove stale socket %s: %w", socketPath, err)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+
+	if err != nil {
+		return fmt.Errorf("unable to listen on socket %s: %w", socketPath, err)
+	}
+
+	server := grpc.NewServer(grpc.ForceServerCodec(codec{}))
+
+	server.RegisterService(&serviceDesc, s)
+
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+
+	log.Info("Serving Secrets Store CSI provider", "socket", socketPath)
+
+	return server.Serve(listener)
+}
+
+// Version reports the version of the provider API implemented.
+func (s *Server) Version(ctx context.Context, request *VersionRequest) (*VersionResponse, error) {
+	runtimeVersion := s.RuntimeVersion
+
+	if runtimeVersion == "" {
+		runtimeVersion = "unknown"
+	}
+
+	return &VersionResponse{
+		Version:        apiVersion,
+		RuntimeName:    ProviderName,
+		RuntimeVersion: runtimeVersion,
+	}, nil
+}
+
+// Mount returns the files for the objects listed in the SecretProviderClass.
+// The version of each object is the resource version of the source secret, so
+// the driver rewrites the files when the source secret changes if rotation is
+// enabled.
+func (s *Server) Mount(ctx context.Context, request *MountRequest) (*MountResponse, error) {
+	log := log.FromContext(ctx)
+
+	var attributes map[string]string
+
+	if err := json.Unmarshal([]byte(request.Attributes), &attributes); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unable to parse attributes: %v", err)
+	}
+
+	namespaceName := attributes[podNamespaceAttribute]
+
+	if namespaceName == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "missing attribute %s", podNamespaceAttribute)
+	}
+
+	var objects []mountObject
+
+	if err := yaml.Unmarshal([]byte(attributes[objectsParameterAttribute]), &objects); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unable to parse objects parameter: %v", err)
+	}
+
+	if len(objects) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "no objects listed in the %s parameter", objectsParameterAttribute)
+	}
+
+	mode := int32(defaultFilePermissionValue)
+
+	if request.Permission != "" {
+		permission, err := strconv.ParseInt(request.Permission, 10, 32)
+
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "unable to parse permission: %v", err)
+		}
+
+		mode = int32(permission)
+	}
+
+	var namespace corev1.Namespace
+
+	if err := s.Client.Get(ctx, client.ObjectKey{Name: namespaceName}, &namespace); err != nil {
+		return nil, status.Errorf(codes.Internal, "unable to fetch namespace %s: %v", namespaceName, err)
+	}
+
+	response := &MountResponse{}
+
+	paths := make(map[string]bool)
+
+	for _, object := range objects {
+		secret, err := s.fetchObject(ctx, &namespace, attributes[serviceAccountAttribute], object)
+
+		if err != nil {
+			log.Info("Unable to mount object", "catalog", object.Catalog, "key", object.Key, "namespace", namespaceName, "error", err.Error())
+			return nil, err
+		}
+
+		directory := object.Path
+
+		if directory == "" {
+			directory = object.Key
+		}
+
+		keys := make([]string, 0, len(secret.Data))
+
+		for key := range secret.Data {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			filePath := path.Join(directory, key)
+
+			if paths[filePath] {
+				return nil, status.Errorf(codes.InvalidArgument, "more than one object writes the file %s", filePath)
+			}
+
+			paths[filePath] = true
+
+			response.Files = append(response.Files, &File{
+				Path:     filePath,
+				Mode:     mode,
+				Contents: secret.Data[key],
+			})
+		}
+
+		response.ObjectVersion = append(response.ObjectVersion, &ObjectVersion{
+			ID:      object.Catalog + "/" + object.Key,
+			Version: secret.ResourceVersion,
+		})
+	}
+
+	log.V(1).Info("Mounted objects", "namespace", namespaceName, "objects", len(objects), "files", len(response.Files))
+
+	return response, nil
+}
+
+// Fetch the source secret of a catalog entry after checking it may be mounted
+// by a pod in the namespace.
+func (s *Server) fetchObject(ctx context.Context, namespace *corev1.Namespace, serviceAccount string, object mountObject) (*corev1.Secret, error) {
+	var catalog secretsv1beta1.SecretCatalog
+
+	if err := s.Client.Get(ctx, client.ObjectKey{Name: object.Catalog}, &catalog); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return nil, status.Errorf(codes.NotFound, "SecretCatalog %s not found", object.Catalog)
+		}
+
+		return nil, status.Errorf(codes.Internal, "unable to fetch SecretCatalog %s: %v", object.Catalog, err)
+	}
+
+	entry, found := catalog.FindEntry(object.Key)
+
+	if !found {
+		return nil, status.Errorf(codes.NotFound, "key %s not found in SecretCatalog %s", object.Key, object.Catalog)
+	}
+
+	if !entry.AllowedNamespaces.Matches(namespace) {
+		return nil, status.Errorf(codes.PermissionDenied, "key %s of SecretCatalog %s cannot be mounted from namespace %s", object.Key, object.Catalog, namespace.Name)
+	}
+
+	if s.AuthorizeMounts {
+		allowed, err := s.mountAllowed(ctx, namespace.Name, serviceAccount, object)
+
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "unable to authorize mount: %v", err)
+		}
+
+		if !allowed {
+			return nil, status.Errorf(codes.PermissionDenied, "service account %s of namespace %s is not authorized to mount key %s of SecretCatalog %s",
+				serviceAccount, namespace.Name, object.Key, object.Catalog)
+		}
+	}
+
+	var secret corev1.Secret
+
+	if err := s.Client.Get(ctx, client.ObjectKey{Namespace: entry.SourceSecret.Namespace, Name: entry.SourceSecret.Name}, &secret); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return nil, status.Errorf(codes.NotFound, "source secret %s/%s not found", entry.SourceSecret.Namespace, entry.SourceSecret.Name)
+		}
+
+		return nil, status.Errorf(codes.Internal, "unable to fetch source secret: %v", err)
+	}
+
+	for _, secretType := range s.NeverMountSecretTypes {
+		if secret.Type == secretType {
+			return nil, status.Errorf(codes.PermissionDenied, "source secret %s/%s is of type %s which is never mounted", secret.Namespace, secret.Name, secret.Type)
+		}
+	}
+
+	return &secret, nil
+}
+
+// Check using a SubjectAccessReview whether the service account of the pod is
+// permitted to access the catalog entry. The check is the same as that made
+// for a SecretClaim, but with the service account of the pod as the subject.
+func (s *Server) mountAllowed(ctx context.Context, namespace string, serviceAccount string, object mountObject) (bool, error) {
+	if serviceAccount == "" {
+		serviceAccount = "default"
+	}
+
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User: "system:serviceaccount:" + namespace + ":" + serviceAccount,
+			Groups: []string{
+				"system:serviceaccounts",
+				"system:serviceaccounts:" + namespace,
+				"system:authenticated",
+			},
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   namespace,
+				Verb:        "create",
+				Group:       secretsv1beta1.GroupVersion.Group,
+				Resource:    "secretcatalogentries",
+				Subresource: "claim",
+				Name:        object.Catalog + ":" + object.Key,
+			},
+		},
+	}
+
+	if err := s.Client.Create(ctx, review); err != nil {
+		return false, err
+	}
+
+	return review.Status.Allowed, nil
+}
@@ -0,0 +1,261 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csiprovider
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	messages := []wireMessage{
+		&VersionRequest{Version: "v1alpha1"},
+		&VersionResponse{Version: "v1alpha1", RuntimeName: ProviderName, RuntimeVersion: "1.0.0"},
+		&MountRequest{
+			Attributes: `{"objects":"- catalog: shared\n  key: registry"}`,
+			Secrets:    "{}",
+			TargetPath: "/var/lib/kubelet/pods/x/volumes/y",
+			Permission: "420",
+			CurrentObjectVersion: []*ObjectVersion{
+				{ID: "shared/registry", Version: "10"},
+			},
+		},
+		&MountResponse{
+			ObjectVersion: []*ObjectVersion{{ID: "shared/registry", Version: "11"}},
+			Error:         &Error{Code: "Failed"},
+			Files: []*File{
+				{Path: "registry/password", Mode: 0o600, Contents: []byte("secret")},
+			},
+		},
+	}
+
+	for _, message := range messages {
+		data, err := codec{}.Marshal(message)
+
+		if err != nil {
+			t.Fatalf("marshal %T: %v", message, err)
+		}
+
+		decoded := reflect.New(reflect.TypeOf(message).Elem()).Interface()
+
+		if err := (codec{}).Unmarshal(data, decoded); err != nil {
+			t.Fatalf("unmarshal %T: %v", message, err)
+		}
+
+		if !reflect.DeepEqual(message, decoded) {
+			t.Errorf("round trip of %T = %+v, expected %+v", message, decoded, message)
+		}
+	}
+}
+
+func TestServerMount(t *testing.T) {
+	scheme := runtime.NewScheme()
+
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := secretsv1beta1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	catalog := &secretsv1beta1.SecretCatalog{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared"},
+		Spec: secretsv1beta1.SecretCatalogSpec{
+			Entries: []secretsv1beta1.SecretCatalogEntry{
+				{
+					Key:          "registry",
+					SourceSecret: secretsv1beta1.SourceSecret{Name: "registry", Namespace: "secrets"},
+					AllowedNamespaces: selectors.TargetNamespaces{
+						NameSelector: selectors.NameSelector{MatchNames: []string{"team-*"}},
+					},
+				},
+				{
+					Key:          "token",
+					SourceSecret: secretsv1beta1.SourceSecret{Name: "token", Namespace: "secrets"},
+				},
+			},
+		},
+	}
+
+	objects := []client.Object{
+		catalog,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "secrets"},
+			Type:       corev1.SecretTypeOpaque,
+			Data:       map[string][]byte{"username": []byte("user"), "password": []byte("pass")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "token", Namespace: "secrets"},
+			Type:       corev1.SecretTypeServiceAccountToken,
+			Data:       map[string][]byte{"token": []byte("abc")},
+		},
+	}
+
+	attributes := func(namespace string, objects string) string {
+		data, _ := json.Marshal(map[string]string{
+			podNamespaceAttribute:     namespace,
+			serviceAccountAttribute:   "app",
+			objectsParameterAttribute: objects,
+		})
+
+		return string(data)
+	}
+
+	tests := []struct {
+		name      string
+		authorize bool
+		allowed   bool
+		request   *MountRequest
+		files     []string
+		code      codes.Code
+	}{
+		{
+			name:    "mounts entry allowed for namespace",
+			request: &MountRequest{Attributes: attributes("team-a", "- catalog: shared\n  key: registry\n"), Permission: "420"},
+			files:   []string{"registry/password", "registry/username"},
+		},
+		{
+			name:    "mounts entry under custom path",
+			request: &MountRequest{Attributes: attributes("team-a", "- catalog: shared\n  key: registry\n  path: creds\n")},
+			files:   []string{"creds/password", "creds/username"},
+		},
+		{
+			name:    "rejects namespace not allowed by entry",
+			request: &MountRequest{Attributes: attributes("other", "- catalog: shared\n  key: registry\n")},
+			code:    codes.PermissionDenied,
+		},
+		{
+			name:    "rejects unknown key",
+			request: &MountRequest{Attributes: attributes("team-a", "- catalog: shared\n  key: missing\n")},
+			code:    codes.NotFound,
+		},
+		{
+			name:    "rejects unknown catalog",
+			request: &MountRequest{Attributes: attributes("team-a", "- catalog: missing\n  key: registry\n")},
+			code:    codes.NotFound,
+		},
+		{
+			name:    "rejects secret type never mounted",
+			request: &MountRequest{Attributes: attributes("team-a", "- catalog: shared\n  key: token\n")},
+			code:    codes.PermissionDenied,
+		},
+		{
+			name:    "rejects missing objects",
+			request: &MountRequest{Attributes: attributes("team-a", "")},
+			code:    codes.InvalidArgument,
+		},
+		{
+			name:    "rejects files written twice",
+			request: &MountRequest{Attributes: attributes("team-a", "- catalog: shared\n  key: registry\n- catalog: shared\n  key: registry\n")},
+			code:    codes.InvalidArgument,
+		},
+		{
+			name:      "mounts entry authorized for service account",
+			authorize: true,
+			allowed:   true,
+			request:   &MountRequest{Attributes: attributes("team-a", "- catalog: shared\n  key: registry\n")},
+			files:     []string{"registry/password", "registry/username"},
+		},
+		{
+			name:      "rejects entry not authorized for service account",
+			authorize: true,
+			request:   &MountRequest{Attributes: attributes("team-a", "- catalog: shared\n  key: registry\n")},
+			code:      codes.PermissionDenied,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var reviews []*authorizationv1.SubjectAccessReview
+
+			fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).WithInterceptorFuncs(interceptor.Funcs{
+				Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+					if review, ok := obj.(*authorizationv1.SubjectAccessReview); ok {
+						review.Status.Allowed = test.allowed
+						reviews = append(reviews, review)
+						return nil
+					}
+
+					return c.Create(ctx, obj, opts...)
+				},
+			}).Build()
+
+			server := &Server{
+				Client:                fakeClient,
+				AuthorizeMounts:       test.authorize,
+				NeverMountSecretTypes: []corev1.SecretType{corev1.SecretTypeServiceAccountToken},
+			}
+
+			response, err := server.Mount(context.Background(), test.request)
+
+			if test.code != codes.OK {
+				if status.Code(err) != test.code {
+					t.Fatalf("Mount() error = %v, expected code %v", err, test.code)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Mount() error = %v", err)
+			}
+
+			var files []string
+
+			for _, file := range response.Files {
+				files = append(files, file.Path)
+
+				if test.request.Permission == "420" && file.Mode != 0o644 {
+					t.Errorf("file %s mode = %o, expected 644", file.Path, file.Mode)
+				}
+			}
+
+			if !reflect.DeepEqual(files, test.files) {
+				t.Errorf("files = %v, expected %v", files, test.files)
+			}
+
+			if len(response.ObjectVersion) != 1 || response.ObjectVersion[0].ID != "shared/registry" {
+				t.Errorf("object versions = %+v", response.ObjectVersion)
+			}
+
+			if test.authorize {
+				if len(reviews) != 1 || reviews[0].Spec.User != "system:serviceaccount:team-a:app" {
+					t.Errorf("reviews = %+v", reviews)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,469 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package doctor implements self checks which verify that the secrets manager
+// has been deployed correctly, printing remediation advice for any failures.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// Options describe the deployment being checked. The feature options should
+// match the flags the manager was started with, so the permissions required
+// by each enabled feature are checked.
+type Options struct {
+	// Namespace the manager is deployed in.
+	Namespace string
+
+	// Service account the manager runs as, in the form "namespace:name". If
+	// empty, the permissions of the caller are checked instead.
+	ServiceAccount string
+
+	// Name of the lease used for leader election. If empty, leader election
+	// is not checked.
+	LeaderElectionID string
+
+	// Whether the validating webhook is expected to be enabled.
+	Webhooks bool
+
+	// Whether the manager runs in report-only mode, in which case it doesn't
+	// need permission to write secrets.
+	ReportOnly bool
+
+	// Features enabled in the manager which require additional permissions.
+	TargetNamespaceStatusConfigMap string
+	AuthorizeSecretClaims          bool
+	ManagedSecretsReport           string
+	ManagerConfig                  string
+	SecretsReadyAnnotation         bool
+}
+
+// Result of a single check.
+type Result struct {
+	// Short description of what was checked.
+	Name string
+
+	// Whether the check passed.
+	Passed bool
+
+	// Explanation of why the check failed.
+	Message string
+
+	// Action which should be taken to fix a failed check.
+	Remediation string
+}
+
+// Names of the custom resource definitions which must be installed.
+var requiredCRDs = []string{
+	"secretcopiers",
+	"secretcatalogs",
+	"secretclaims",
+	"managedsecretsreports",
+	"secretsmanagerconfigs",
+}
+
+// Name of the validating webhook for SecretCopier objects.
+const secretCopierWebhookName = "vsecretcopier-v1beta1.kb.io"
+
+// Run all checks against the cluster, writing the results to out. Returns
+// whether all the checks passed.
+func Run(ctx context.Context, c client.Client, options Options, out io.Writer) bool {
+	var results []Result
+
+	results = append(results, checkCRDs(ctx, c)...)
+	results = append(results, checkPermissions(ctx, c, options)...)
+
+	if options.Webhooks {
+		results = append(results, checkWebhook(ctx, c)...)
+	}
+
+	if options.LeaderElectionID != "" {
+		results = append(results, checkLeaderElection(ctx, c, options, time.Now()))
+	}
+
+	passed := true
+
+	for _, result := range results {
+		if result.Passed {
+			fmt.Fprintf(out, "[PASS] %s\n", result.Name)
+			continue
+		}
+
+		passed = false
+
+		fmt.Fprintf(out, "[FAIL] %s: %s\n", result.Name, result.Message)
+
+		if result.Remediation != "" {
+			fmt.Fprintf(out, "       Remediation: %s\n", result.Remediation)
+		}
+	}
+
+	return passed
+}
+
+// Check that the custom resource definitions are installed and serve the
+// version of the API used by the manager.
+func checkCRDs(ctx context.Context, c client.Client) []Result {
+	var results []Result
+
+	group := secretsv1beta1.GroupVersion.Group
+	version := secretsv1beta1.GroupVersion.Version
+
+	remediation := "Install the CRDs from this release of the secrets manager using \"make install\" or by applying the installer manifest."
+
+	for _, plural := range requiredCRDs {
+		name := plural + "." + group
+
+		result := Result{Name: "CRD " + name, Remediation: remediation}
+
+		var crd apiextensionsv1.CustomResourceDefinition
+
+		if err := c.Get(ctx, client.ObjectKey{Name: name}, &crd); err != nil {
+			result.Message = fmt.Sprintf("unable to fetch CRD: %v", err)
+			results = append(results, result)
+
+			continue
+		}
+
+		served := false
+		storage := ""
+
+		for _, crdVersion := range crd.Spec.Versions {
+			if crdVersion.Name == version && crdVersion.Served {
+				served = true
+			}
+
+			if crdVersion.Storage {
+				storage = crdVersion.Name
+			}
+		}
+
+		switch {
+		case !served:
+			result.Message = fmt.Sprintf("version %s is not served", version)
+		case storage != version:
+			result.Message = fmt.Sprintf("storage version is %s but expected %s", storage, version)
+		default:
+			result.Passed = true
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// Permission required by the manager.
+type permission struct {
+	verb        string
+	group       string
+	resource    string
+	subresource string
+	namespace   string
+}
+
+func (p permission) String() string {
+	resource := p.resource
+
+	if p.subresource != "" {
+		resource += "/" + p.subresource
+	}
+
+	if p.group != "" {
+		resource += "." + p.group
+	}
+
+	if p.namespace != "" {
+		return fmt.Sprintf("%s %s in namespace %s", p.verb, resource, p.namespace)
+	}
+
+	return fmt.Sprintf("%s %s", p.verb, resource)
+}
+
+// Determine the permissions required by the manager for the enabled features.
+func requiredPermissions(options Options) []permission {
+	group := secretsv1beta1.GroupVersion.Group
+
+	var permissions []permission
+
+	add := func(group string, resource string, subresource string, verbs ...string) {
+		for _, verb := range verbs {
+			permissions = append(permissions, permission{verb: verb, group: group, resource: resource, subresource: subresource})
+		}
+	}
+
+	add(group, "secretcopiers", "", "get", "list", "watch")
+	add(group, "secretcopiers", "status", "update")
+	add(group, "secretcatalogs", "", "get", "list", "watch")
+	add(group, "secretclaims", "", "get", "list", "watch")
+	add(group, "secretclaims", "status", "update")
+	add("", "secrets", "", "get", "list", "watch")
+
+	if !options.ReportOnly {
+		add("", "secrets", "", "create", "update", "delete")
+	}
+
+	add("", "namespaces", "", "get", "list", "watch")
+	add("", "serviceaccounts", "", "get", "list", "watch")
+	add("", "resourcequotas", "", "get", "list", "watch")
+	add("", "events", "", "create", "patch")
+
+	if options.ManagerConfig != "" {
+		add(group, "secretsmanagerconfigs", "", "get", "list", "watch")
+		add(group, "secretsmanagerconfigs", "status", "update")
+	}
+
+	if options.TargetNamespaceStatusConfigMap != "" && !options.ReportOnly {
+		add("", "configmaps", "", "get", "list", "watch", "create", "update")
+	}
+
+	if options.SecretsReadyAnnotation && !options.ReportOnly {
+		add("", "namespaces", "", "patch")
+	}
+
+	if options.AuthorizeSecretClaims {
+		add("authorization.k8s.io", "subjectaccessreviews", "", "create")
+	}
+
+	if options.ManagedSecretsReport != "" {
+		add(group, "managedsecretsreports", "", "get", "list", "watch", "create")
+		add(group, "managedsecretsreports", "status", "update")
+	}
+
+	if options.LeaderElectionID != "" {
+		for _, verb := range []string{"get", "create", "update"} {
+			permissions = append(permissions, permission{verb: verb, group: "coordination.k8s.io", resource: "leases", namespace: options.Namespace})
+		}
+	}
+
+	return permissions
+}
+
+// Check that the manager has the permissions required for the enabled
+// features. When a service account is given a SubjectAccessReview is used,
+// otherwise the permissions of the caller are checked.
+func checkPermissions(ctx context.Context, c client.Client, options Options) []Result {
+	var results []Result
+
+	subject := "caller"
+
+	if options.ServiceAccount != "" {
+		subject = "system:serviceaccount:" + options.ServiceAccount
+	}
+
+	for _, required := range requiredPermissions(options) {
+		result := Result{
+			Name:        "RBAC " + required.String(),
+			Remediation: fmt.Sprintf("Grant %s the permission to %s, for example by applying the manager ClusterRole from config/rbac/role.yaml.", subject, required),
+		}
+
+		attributes := &authorizationv1.ResourceAttributes{
+			Namespace:   required.namespace,
+			Verb:        required.verb,
+			Group:       required.group,
+			Resource:    required.resource,
+			Subresource: required.subresource,
+		}
+
+		var status authorizationv1.SubjectAccessReviewStatus
+
+		if options.ServiceAccount != "" {
+			namespace, _, _ := strings.Cut(options.ServiceAccount, ":")
+
+			review := &authorizationv1.SubjectAccessReview{
+				Spec: authorizationv1.SubjectAccessReviewSpec{
+					User:               subject,
+					Groups:             []string{"system:serviceaccounts", "system:serviceaccounts:" + namespace, "system:authenticated"},
+					ResourceAttributes: attributes,
+				},
+			}
+
+			if err := c.Create(ctx, review); err != nil {
+				result.Message = fmt.Sprintf("unable to perform SubjectAccessReview: %v", err)
+				results = append(results, result)
+
+				continue
+			}
+
+			status = review.Status
+		} else {
+			review := &authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: attributes,
+				},
+			}
+
+			if err := c.Create(ctx, review); err != nil {
+				result.Message = fmt.Sprintf("unable to perform SelfSubjectAccessReview: %v", err)
+				results = append(results, result)
+
+				continue
+			}
+
+			status = review.Status
+		}
+
+		if status.Allowed {
+			result.Passed = true
+		} else {
+			result.Message = "permission denied"
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// Check that the validating webhook is registered, that its service has ready
+// endpoints, and that the API server can call it by making a dry run request
+// to create a SecretCopier.
+func checkWebhook(ctx context.Context, c client.Client) []Result {
+	var results []Result
+
+	registered := Result{
+		Name:        "Webhook " + secretCopierWebhookName + " registered",
+		Remediation: "Deploy the manager with the webhook enabled in config/default/kustomization.yaml, or start the manager with ENABLE_WEBHOOKS=false if the webhook is not wanted.",
+	}
+
+	var configurations admissionregistrationv1.ValidatingWebhookConfigurationList
+
+	if err := c.List(ctx, &configurations); err != nil {
+		registered.Message = fmt.Sprintf("unable to list ValidatingWebhookConfigurations: %v", err)
+		return append(results, registered)
+	}
+
+	var webhook *admissionregistrationv1.ValidatingWebhook
+
+	for i := range configurations.Items {
+		for j := range configurations.Items[i].Webhooks {
+			if configurations.Items[i].Webhooks[j].Name == secretCopierWebhookName {
+				webhook = &configurations.Items[i].Webhooks[j]
+			}
+		}
+	}
+
+	if webhook == nil {
+		registered.Message = "no ValidatingWebhookConfiguration contains the webhook"
+		return append(results, registered)
+	}
+
+	registered.Passed = true
+
+	results = append(results, registered)
+
+	// Check the service used by the webhook has ready endpoints.
+
+	if service := webhook.ClientConfig.Service; service != nil {
+		endpoints := Result{
+			Name:        fmt.Sprintf("Webhook service %s/%s has ready endpoints", service.Namespace, service.Name),
+			Remediation: "Check that the manager pod is running and ready, and that the webhook service selects it.",
+		}
+
+		var slices discoveryv1.EndpointSliceList
+
+		if err := c.List(ctx, &slices, client.InNamespace(service.Namespace), client.MatchingLabels{discoveryv1.LabelServiceName: service.Name}); err != nil {
+			endpoints.Message = fmt.Sprintf("unable to list EndpointSlices: %v", err)
+		} else {
+			for _, slice := range slices.Items {
+				for _, endpoint := range slice.Endpoints {
+					if endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready {
+						endpoints.Passed = true
+					}
+				}
+			}
+
+			if !endpoints.Passed {
+				endpoints.Message = "no ready endpoints found"
+			}
+		}
+
+		results = append(results, endpoints)
+	}
+
+	// Make a dry run request which will be sent to the webhook by the API
+	// server, verifying it is reachable and responding.
+
+	reachable := Result{
+		Name:        "Webhook " + secretCopierWebhookName + " reachable",
+		Remediation: "Check the manager logs, that the webhook serving certificate is valid and trusted by the caBundle of the webhook configuration, and that network policies allow the API server to reach the manager.",
+	}
+
+	secretCopier := &secretsv1beta1.SecretCopier{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "doctor-",
+		},
+	}
+
+	if err := c.Create(ctx, secretCopier, client.DryRunAll); err != nil {
+		reachable.Message = fmt.Sprintf("dry run create of SecretCopier failed: %v", err)
+	} else {
+		reachable.Passed = true
+	}
+
+	return append(results, reachable)
+}
+
+// Check that a leader has been elected and is renewing its lease.
+func checkLeaderElection(ctx context.Context, c client.Client, options Options, now time.Time) Result {
+	result := Result{
+		Name:        fmt.Sprintf("Leader election lease %s/%s held", options.Namespace, options.LeaderElectionID),
+		Remediation: "Check that the manager is running with --leader-elect and that its logs do not show errors acquiring or renewing the lease.",
+	}
+
+	var lease coordinationv1.Lease
+
+	if err := c.Get(ctx, client.ObjectKey{Namespace: options.Namespace, Name: options.LeaderElectionID}, &lease); err != nil {
+		result.Message = fmt.Sprintf("unable to fetch lease: %v", err)
+		return result
+	}
+
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity == "" {
+		result.Message = "lease is not held by any manager"
+		return result
+	}
+
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		result.Message = fmt.Sprintf("lease held by %s has never been renewed", *lease.Spec.HolderIdentity)
+		return result
+	}
+
+	expiry := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+
+	if now.After(expiry) {
+		result.Message = fmt.Sprintf("lease held by %s expired at %s", *lease.Spec.HolderIdentity, expiry.UTC().Format(time.RFC3339))
+		return result
+	}
+
+	result.Passed = true
+
+	return result
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCheckCRDs(t *testing.T) {
+	scheme := runtime.NewScheme()
+
+	if err := apiextensionsv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	crd := func(plural string, versions ...apiextensionsv1.CustomResourceDefinitionVersion) client.Object {
+		return &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: plural + ".secrets-manager.advok8s.io"},
+			Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+				Versions: versions,
+			},
+		}
+	}
+
+	current := apiextensionsv1.CustomResourceDefinitionVersion{Name: "v1beta1", Served: true, Storage: true}
+	old := apiextensionsv1.CustomResourceDefinitionVersion{Name: "v1alpha1", Served: true, Storage: true}
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		crd("secretcopiers", current),
+		crd("secretcatalogs", current),
+		crd("secretclaims", old),
+	).Build()
+
+	results := checkCRDs(context.Background(), fakeClient)
+
+	expected := map[string]bool{
+		"CRD secretcopiers.secrets-manager.advok8s.io":         true,
+		"CRD secretcatalogs.secrets-manager.advok8s.io":        true,
+		"CRD secretclaims.secrets-manager.advok8s.io":          false,
+		"CRD managedsecretsreports.secrets-manager.advok8s.io": false,
+		"CRD secretsmanagerconfigs.secrets-manager.advok8s.io": false,
+	}
+
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(results))
+	}
+
+	for _, result := range results {
+		if result.Passed != expected[result.Name] {
+			t.Errorf("%s: expected passed=%v, got %v (%s)", result.Name, expected[result.Name], result.Passed, result.Message)
+		}
+
+		if !result.Passed && result.Remediation == "" {
+			t.Errorf("%s: expected remediation for failed check", result.Name)
+		}
+	}
+}
+
+func TestCheckLeaderElection(t *testing.T) {
+	now := time.Now()
+
+	lease := func(holder string, renewed time.Time) *coordinationv1.Lease {
+		return &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "system", Name: "leader"},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       ptr.To(holder),
+				LeaseDurationSeconds: ptr.To[int32](15),
+				RenewTime:            &metav1.MicroTime{Time: renewed},
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		lease  *coordinationv1.Lease
+		passed bool
+	}{
+		{"missing lease", nil, false},
+		{"held lease", lease("manager-1", now.Add(-5*time.Second)), true},
+		{"expired lease", lease("manager-1", now.Add(-time.Minute)), false},
+		{"unheld lease", lease("", now), false},
+	}
+
+	options := Options{Namespace: "system", LeaderElectionID: "leader"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := fake.NewClientBuilder()
+
+			if tt.lease != nil {
+				builder = builder.WithObjects(tt.lease)
+			}
+
+			result := checkLeaderElection(context.Background(), builder.Build(), options, now)
+
+			if result.Passed != tt.passed {
+				t.Errorf("expected passed=%v, got %v (%s)", tt.passed, result.Passed, result.Message)
+			}
+		})
+	}
+}
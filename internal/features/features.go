@@ -0,0 +1,182 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package features implements feature gates, allowing new capabilities of the
+// secrets manager to be staged through alpha and beta before becoming
+// generally available, in the same way as upstream Kubernetes components.
+package features
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Feature is the name of a feature which can be enabled or disabled.
+type Feature string
+
+// Stage is the maturity of a feature.
+type Stage string
+
+const (
+	// Alpha features are disabled by default and may change or be removed.
+	Alpha Stage = "ALPHA"
+
+	// Beta features are usually enabled by default and are well tested.
+	Beta Stage = "BETA"
+
+	// GA features are always enabled and cannot be disabled.
+	GA Stage = ""
+
+	// Deprecated features will be removed in a future release.
+	Deprecated Stage = "DEPRECATED"
+)
+
+// Spec describes the default state and maturity of a feature.
+type Spec struct {
+	// Whether the feature is enabled when not explicitly set.
+	Default bool
+
+	// Maturity of the feature.
+	Stage Stage
+
+	// Whether the feature is locked to its default and cannot be changed.
+	LockToDefault bool
+}
+
+// Gate records which features are enabled. It is safe for concurrent use.
+type Gate struct {
+	mutex   sync.RWMutex
+	known   map[Feature]Spec
+	enabled map[Feature]bool
+}
+
+// NewGate creates a Gate for the given known features.
+func NewGate(known map[Feature]Spec) *Gate {
+	return &Gate{
+		known:   known,
+		enabled: make(map[Feature]bool),
+	}
+}
+
+// Set the state of features from a comma separated list of name=value pairs,
+// the format accepted by the --feature-gates flag. Implements flag.Value.
+func (g *Gate) Set(value string) error {
+	settings := make(map[string]bool)
+
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+
+		if pair == "" {
+			continue
+		}
+
+		name, enabled, found := strings.Cut(pair, "=")
+
+		if !found {
+			return fmt.Errorf("missing bool value for feature %s", name)
+		}
+
+		parsed, err := strconv.ParseBool(strings.TrimSpace(enabled))
+
+		if err != nil {
+			return fmt.Errorf("invalid value %q for feature %s: %w", enabled, name, err)
+		}
+
+		settings[strings.TrimSpace(name)] = parsed
+	}
+
+	return g.SetFromMap(settings)
+}
+
+// SetFromMap sets the state of features from a map of feature names. Unknown
+// features, and attempts to change features locked to their default, are
+// rejected without changing the state of any feature.
+func (g *Gate) SetFromMap(settings map[string]bool) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for name, enabled := range settings {
+		spec, found := g.known[Feature(name)]
+
+		if !found {
+			return fmt.Errorf("unrecognized feature gate %s", name)
+		}
+
+		if spec.LockToDefault && spec.Default != enabled {
+			return fmt.Errorf("cannot set feature gate %s to %v, feature is locked to %v", name, enabled, spec.Default)
+		}
+	}
+
+	for name, enabled := range settings {
+		g.enabled[Feature(name)] = enabled
+	}
+
+	return nil
+}
+
+// String returns the features which have been explicitly set, in the format
+// accepted by Set. Implements flag.Value.
+func (g *Gate) String() string {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	pairs := make([]string, 0, len(g.enabled))
+
+	for name, enabled := range g.enabled {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", name, enabled))
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+// Enabled reports whether a feature is enabled. Unknown features are treated
+// as disabled.
+func (g *Gate) Enabled(feature Feature) bool {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	if enabled, found := g.enabled[feature]; found {
+		return enabled
+	}
+
+	return g.known[feature].Default
+}
+
+// KnownFeatures returns a description of each feature which isn't GA, for use
+// in the help for the --feature-gates flag.
+func (g *Gate) KnownFeatures() []string {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	descriptions := make([]string, 0, len(g.known))
+
+	for name, spec := range g.known {
+		if spec.Stage == GA {
+			continue
+		}
+
+		descriptions = append(descriptions, fmt.Sprintf("%s=true|false (%s - default=%v)", name, spec.Stage, spec.Default))
+	}
+
+	sort.Strings(descriptions)
+
+	return descriptions
+}
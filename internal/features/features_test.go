@@ -0,0 +1,109 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+import (
+	"testing"
+)
+
+func TestGate(t *testing.T) {
+	const (
+		alphaFeature  Feature = "AlphaFeature"
+		betaFeature   Feature = "BetaFeature"
+		lockedFeature Feature = "LockedFeature"
+	)
+
+	known := map[Feature]Spec{
+		alphaFeature:  {Default: false, Stage: Alpha},
+		betaFeature:   {Default: true, Stage: Beta},
+		lockedFeature: {Default: true, Stage: GA, LockToDefault: true},
+	}
+
+	tests := []struct {
+		name     string
+		value    string
+		wantErr  bool
+		expected map[Feature]bool
+	}{
+		{
+			name:     "defaults",
+			value:    "",
+			expected: map[Feature]bool{alphaFeature: false, betaFeature: true, lockedFeature: true},
+		},
+		{
+			name:     "enable alpha and disable beta",
+			value:    "AlphaFeature=true, BetaFeature=false",
+			expected: map[Feature]bool{alphaFeature: true, betaFeature: false, lockedFeature: true},
+		},
+		{
+			name:     "locked feature set to default",
+			value:    "LockedFeature=true",
+			expected: map[Feature]bool{alphaFeature: false, betaFeature: true, lockedFeature: true},
+		},
+		{
+			name:    "locked feature changed",
+			value:   "LockedFeature=false",
+			wantErr: true,
+		},
+		{
+			name:    "unknown feature",
+			value:   "UnknownFeature=true",
+			wantErr: true,
+		},
+		{
+			name:    "missing value",
+			value:   "AlphaFeature",
+			wantErr: true,
+		},
+		{
+			name:    "invalid value",
+			value:   "AlphaFeature=maybe",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gate := NewGate(known)
+
+			err := gate.Set(tt.value)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+
+			for feature, expected := range tt.expected {
+				if got := gate.Enabled(feature); got != expected {
+					t.Errorf("Enabled(%s) = %v, want %v", feature, got, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestGateKnownFeatures(t *testing.T) {
+	gate := NewGate(map[Feature]Spec{
+		"AlphaFeature": {Default: false, Stage: Alpha},
+		"GAFeature":    {Default: true, Stage: GA, LockToDefault: true},
+	})
+
+	descriptions := gate.KnownFeatures()
+
+	if len(descriptions) != 1 || descriptions[0] != "AlphaFeature=true|false (ALPHA - default=false)" {
+		t.Errorf("KnownFeatures() = %v", descriptions)
+	}
+}
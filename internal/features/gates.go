@@ -0,0 +1,69 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package features
+
+// Features of the secrets manager which can be enabled or disabled using the
+// --feature-gates flag. New features which carry risk should be added here as
+// Alpha and disabled by default, then promoted as they mature.
+const (
+	// Allow tenants to claim secrets from a SecretCatalog using SecretClaims.
+	SecretClaims Feature = "SecretClaims"
+
+	// Maintain a ManagedSecretsReport summarizing all managed secrets.
+	ManagedSecretsReport Feature = "ManagedSecretsReport"
+
+	// Validate SecretCopier objects using an admission webhook.
+	AdmissionWebhooks Feature = "AdmissionWebhooks"
+
+	// Inject secrets into pods matched by a SecretInjector using a mutating
+	// admission webhook.
+	SecretInjection Feature = "SecretInjection"
+
+	// Copy config maps between namespaces using ConfigMapCopiers.
+	ConfigMapCopiers Feature = "ConfigMapCopiers"
+
+	// Allow namespace owners to share secrets with other namespaces using
+	// SecretExports and SecretImports.
+	SecretExports Feature = "SecretExports"
+
+	// Restart workloads depending on copied secrets when they change using
+	// SecretRotators.
+	SecretRotators Feature = "SecretRotators"
+
+	// Sync secrets from the KV secrets engine of HashiCorp Vault using
+	// VaultSecrets.
+	VaultSecrets Feature = "VaultSecrets"
+
+	// Sync secrets from AWS Secrets Manager using AWSSecrets.
+	AWSSecrets Feature = "AWSSecrets"
+)
+
+// Default state and maturity of each feature.
+var defaultFeatures = map[Feature]Spec{
+	SecretClaims:         {Default: true, Stage: Beta},
+	ManagedSecretsReport: {Default: true, Stage: Beta},
+	AdmissionWebhooks:    {Default: true, Stage: Beta},
+	SecretInjection:      {Default: false, Stage: Alpha},
+	ConfigMapCopiers:     {Default: false, Stage: Alpha},
+	SecretExports:        {Default: false, Stage: Alpha},
+	SecretRotators:       {Default: false, Stage: Alpha},
+	VaultSecrets:         {Default: false, Stage: Alpha},
+	AWSSecrets:           {Default: false, Stage: Alpha},
+}
+
+// DefaultGate holds the state of the features of the secrets manager.
+var DefaultGate = NewGate(defaultFeatures)
@@ -0,0 +1,208 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package redact provides helpers for describing secret data in messages
+// which end up stored in events and status, such that key names can be
+// reported but secret values are never included.
+package redact
+
+import (
+	"bytes"
+	"encoding/base64"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Placeholder is substituted in messages for any secret value.
+const Placeholder = "[REDACTED]"
+
+// Mask replaces any occurrence in the message of a value from the given secret
+// data, either as is or base64 encoded, with the placeholder. Messages built
+// from errors returned by the API server can quote the object being written,
+// so any message derived from such an error should be masked before it is
+// recorded in an event or the status of a resource. Every non-empty value is
+// masked, however short, so a very short value may also mask matching text
+// elsewhere in the message.
+func Mask(message string, data ...map[string][]byte) string {
+	var values []string
+
+	for _, secretData := range data {
+		for _, value := range secretData {
+			if len(value) == 0 {
+				continue
+			}
+
+			values = append(values, string(value), base64.StdEncoding.EncodeToString(value))
+		}
+	}
+
+	// Replace longer values first so a value which contains another value is
+	// not left partially in place.
+
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+
+	for _, value := range values {
+		message = strings.ReplaceAll(message, value, Placeholder)
+	}
+
+	return message
+}
+
+// Error returns an error with the same message as the given error, but with
+// any secret values masked. The original error is still returned when the
+// error is unwrapped, so checks on the type of the error continue to work.
+func Error(err error, data ...map[string][]byte) error {
+	if err == nil {
+		return nil
+	}
+
+	message := Mask(err.Error(), data...)
+
+	if message == err.Error() {
+		return err
+	}
+
+	return &maskedError{err: err, message: message}
+}
+
+type maskedError struct {
+	err     error
+	message string
+}
+
+func (e *maskedError) Error() string {
+	return e.message
+}
+
+func (e *maskedError) Unwrap() error {
+	return e.err
+}
+
+// KeyDiff describes the differences between two sets of secret data by the
+// names of the keys alone.
+type KeyDiff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// DiffKeys compares two sets of secret data and returns the sorted names of
+// keys which were added, removed or whose values changed.
+func DiffKeys(old, new map[string][]byte) KeyDiff {
+	return diffMapKeys(old, new, bytes.Equal)
+}
+
+// DiffLabels compares two sets of labels and returns the sorted names of
+// label keys which were added, removed or whose values changed.
+func DiffLabels(old, new map[string]string) KeyDiff {
+	return diffMapKeys(old, new, func(a, b string) bool { return a == b })
+}
+
+// Compare the keys of two maps, using the given function to decide whether
+// the values of a key present in both are equal.
+func diffMapKeys[V any](old, new map[string]V, equal func(a, b V) bool) KeyDiff {
+	var diff KeyDiff
+
+	for key, value := range new {
+		previous, found := old[key]
+
+		if !found {
+			diff.Added = append(diff.Added, key)
+		} else if !equal(previous, value) {
+			diff.Changed = append(diff.Changed, key)
+		}
+	}
+
+	for key := range old {
+		if _, found := new[key]; !found {
+			diff.Removed = append(diff.Removed, key)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+
+	return diff
+}
+
+// Empty reports whether no keys differ.
+func (d KeyDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// String describes the differences by key name, for example "added keys a,
+// b; changed keys c". An empty string is returned if no keys differ.
+func (d KeyDiff) String() string {
+	return strings.Join(d.describe("keys"), "; ")
+}
+
+// Describe each kind of difference as a separate phrase naming the keys,
+// using the given noun for the keys.
+func (d KeyDiff) describe(noun string) []string {
+	var parts []string
+
+	if len(d.Added) != 0 {
+		parts = append(parts, "added "+noun+" "+strings.Join(d.Added, ", "))
+	}
+
+	if len(d.Removed) != 0 {
+		parts = append(parts, "removed "+noun+" "+strings.Join(d.Removed, ", "))
+	}
+
+	if len(d.Changed) != 0 {
+		parts = append(parts, "changed "+noun+" "+strings.Join(d.Changed, ", "))
+	}
+
+	return parts
+}
+
+// ContentDiff describes the differences between two versions of a secret by
+// the names of the keys of its data and of its labels alone.
+type ContentDiff struct {
+	Keys   KeyDiff
+	Labels KeyDiff
+}
+
+// DiffSecrets compares two versions of a secret. The old secret may be nil
+// when the secret is being created, in which case all keys and labels of the
+// new secret are reported as added.
+func DiffSecrets(old, new *corev1.Secret) ContentDiff {
+	if old == nil {
+		old = &corev1.Secret{}
+	}
+
+	return ContentDiff{
+		Keys:   DiffKeys(old.Data, new.Data),
+		Labels: DiffLabels(old.Labels, new.Labels),
+	}
+}
+
+// Empty reports whether neither keys nor labels differ.
+func (d ContentDiff) Empty() bool {
+	return d.Keys.Empty() && d.Labels.Empty()
+}
+
+// String describes the differences by the names of keys and labels, for
+// example "changed keys a; added label keys team". An empty string is
+// returned if nothing differs.
+func (d ContentDiff) String() string {
+	return strings.Join(append(d.Keys.describe("keys"), d.Labels.describe("label keys")...), "; ")
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redact
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type testError struct{}
+
+func (testError) Error() string {
+	return "invalid value \"s3cr3t-password\""
+}
+
+func TestMask(t *testing.T) {
+	data := map[string][]byte{
+		"password": []byte("s3cr3t-password"),
+		"token":    []byte("s3cr3t"),
+		"pin":      []byte("7"),
+		"code":     []byte("k3"),
+		"otp":      []byte("x9z"),
+		"empty":    []byte(""),
+	}
+
+	tests := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{
+			name:    "no values",
+			message: "secret foo not found",
+			want:    "secret foo not found",
+		},
+		{
+			name:    "raw value",
+			message: `value "s3cr3t-password" is invalid`,
+			want:    `value "[REDACTED]" is invalid`,
+		},
+		{
+			name:    "longer value masked first",
+			message: "s3cr3t-password and s3cr3t",
+			want:    "[REDACTED] and [REDACTED]",
+		},
+		{
+			name:    "base64 value",
+			message: `data: {"password":"czNjcjN0LXBhc3N3b3Jk"}`,
+			want:    `data: {"password":"[REDACTED]"}`,
+		},
+		{
+			name:    "one byte value",
+			message: `value "7" is invalid`,
+			want:    `value "[REDACTED]" is invalid`,
+		},
+		{
+			name:    "two byte value",
+			message: `value "k3" is invalid`,
+			want:    `value "[REDACTED]" is invalid`,
+		},
+		{
+			name:    "three byte value",
+			message: `value "x9z" is invalid`,
+			want:    `value "[REDACTED]" is invalid`,
+		},
+		{
+			name:    "short base64 value",
+			message: `data: {"otp":"eDl6"}`,
+			want:    `data: {"otp":"[REDACTED]"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Mask(tt.message, data); got != tt.want {
+				t.Errorf("Mask() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	data := map[string][]byte{"password": []byte("s3cr3t-password")}
+
+	if Error(nil, data) != nil {
+		t.Errorf("Error(nil) should be nil")
+	}
+
+	unchanged := errors.New("not found")
+
+	if got := Error(unchanged, data); got != unchanged {
+		t.Errorf("Error() = %v, want original error when nothing is masked", got)
+	}
+
+	err := Error(fmt.Errorf("update failed: %w", testError{}), data)
+
+	if strings.Contains(err.Error(), "s3cr3t-password") {
+		t.Errorf("Error() = %q, contains secret value", err.Error())
+	}
+
+	if !errors.As(err, &testError{}) {
+		t.Errorf("Error() should wrap the original error")
+	}
+}
+
+func TestDiffKeys(t *testing.T) {
+	tests := []struct {
+		name       string
+		old        map[string][]byte
+		new        map[string][]byte
+		want       KeyDiff
+		wantString string
+	}{
+		{
+			name:       "unchanged",
+			old:        map[string][]byte{"a": []byte("1")},
+			new:        map[string][]byte{"a": []byte("1")},
+			want:       KeyDiff{},
+			wantString: "",
+		},
+		{
+			name:       "created",
+			new:        map[string][]byte{"b": []byte("2"), "a": []byte("1")},
+			want:       KeyDiff{Added: []string{"a", "b"}},
+			wantString: "added keys a, b",
+		},
+		{
+			name: "added removed and changed",
+			old:  map[string][]byte{"a": []byte("1"), "b": []byte("2"), "c": []byte("3")},
+			new:  map[string][]byte{"a": []byte("one"), "c": []byte("3"), "d": []byte("4")},
+			want: KeyDiff{
+				Added:   []string{"d"},
+				Removed: []string{"b"},
+				Changed: []string{"a"},
+			},
+			wantString: "added keys d; removed keys b; changed keys a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffKeys(tt.old, tt.new)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffKeys() = %+v, want %+v", got, tt.want)
+			}
+
+			if got.Empty() != (tt.wantString == "") {
+				t.Errorf("Empty() = %v, want %v", got.Empty(), tt.wantString == "")
+			}
+
+			if got.String() != tt.wantString {
+				t.Errorf("String() = %q, want %q", got.String(), tt.wantString)
+			}
+
+			for _, value := range tt.new {
+				if len(value) > 1 && strings.Contains(got.String(), string(value)) {
+					t.Errorf("String() = %q, contains value %q", got.String(), value)
+				}
+			}
+		})
+	}
+}
+
+func TestDiffSecrets(t *testing.T) {
+	newSecret := func(labels map[string]string, data map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Labels: labels}, Data: data}
+	}
+
+	tests := []struct {
+		name       string
+		old        *corev1.Secret
+		new        *corev1.Secret
+		wantString string
+	}{
+		{
+			name:       "unchanged",
+			old:        newSecret(map[string]string{"team": "a"}, map[string][]byte{"a": []byte("1")}),
+			new:        newSecret(map[string]string{"team": "a"}, map[string][]byte{"a": []byte("1")}),
+			wantString: "",
+		},
+		{
+			name:       "created",
+			new:        newSecret(map[string]string{"team": "a"}, map[string][]byte{"a": []byte("1")}),
+			wantString: "added keys a; added label keys team",
+		},
+		{
+			name:       "labels only",
+			old:        newSecret(map[string]string{"team": "a", "tier": "web"}, map[string][]byte{"a": []byte("1")}),
+			new:        newSecret(map[string]string{"team": "b", "env": "prod"}, map[string][]byte{"a": []byte("1")}),
+			wantString: "added label keys env; removed label keys tier; changed label keys team",
+		},
+		{
+			name:       "keys and labels",
+			old:        newSecret(nil, map[string][]byte{"a": []byte("1")}),
+			new:        newSecret(map[string]string{"team": "a"}, map[string][]byte{"a": []byte("2")}),
+			wantString: "changed keys a; added label keys team",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffSecrets(tt.old, tt.new)
+
+			if got.Empty() != (tt.wantString == "") {
+				t.Errorf("Empty() = %v, want %v", got.Empty(), tt.wantString == "")
+			}
+
+			if got.String() != tt.wantString {
+				t.Errorf("String() = %q, want %q", got.String(), tt.wantString)
+			}
+		})
+	}
+}
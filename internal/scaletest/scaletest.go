@@ -0,0 +1,337 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package scaletest generates synthetic namespaces, secrets and SecretCopier
+// resources at a configurable scale and measures how long the manager takes
+// to copy the secrets, so the throughput of a deployment can be estimated.
+package scaletest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+// Label added to all generated resources, with the prefix as its value. As
+// labels of source secrets are copied, it is also present on target secrets.
+const fixtureLabel = "secrets-manager.advok8s.io/scale-test"
+
+// Label distinguishing the source namespace from the target namespaces.
+const fixtureRoleLabel = "secrets-manager.advok8s.io/scale-test-role"
+
+// Options describe the fixtures to generate.
+type Options struct {
+	// Prefix for the names of all generated resources.
+	Prefix string
+
+	// Number of target namespaces to create.
+	Namespaces int
+
+	// Number of source secrets to create. Each secret is copied to every
+	// target namespace.
+	Secrets int
+
+	// Number of SecretCopier resources the secrets are spread across.
+	SecretCopiers int
+
+	// Size in bytes of the data of each source secret.
+	SecretSize int
+
+	// How often to check how many secrets have been copied.
+	PollInterval time.Duration
+}
+
+// Validate checks the options describe a usable set of fixtures.
+func (o Options) Validate() error {
+	if o.Prefix == "" {
+		return errors.New("prefix must not be empty")
+	}
+
+	if o.Namespaces < 1 || o.Secrets < 1 || o.SecretCopiers < 1 {
+		return errors.New("number of namespaces, secrets and secret copiers must be at least 1")
+	}
+
+	if o.SecretCopiers > o.Secrets {
+		return errors.New("number of secret copiers must not exceed number of secrets")
+	}
+
+	if o.SecretSize < 0 {
+		return errors.New("secret size must not be negative")
+	}
+
+	return nil
+}
+
+// Report describes the outcome of a scale test.
+type Report struct {
+	Namespaces    int `json:"namespaces"`
+	Secrets       int `json:"secrets"`
+	SecretCopiers int `json:"secretCopiers"`
+
+	// Number of target secrets which should be created.
+	ExpectedTargets int `json:"expectedTargets"`
+
+	// Number of target secrets which had been created when the test ended.
+	CopiedTargets int `json:"copiedTargets"`
+
+	// Whether all target secrets were created before the test timed out.
+	Complete bool `json:"complete"`
+
+	// Time taken to create the fixtures.
+	CreateDuration metav1.Duration `json:"createDuration"`
+
+	// Time from the creation of the first SecretCopier until all target
+	// secrets were created, or the test timed out.
+	CopyDuration metav1.Duration `json:"copyDuration"`
+
+	// Number of target secrets created per second.
+	TargetsPerSecond float64 `json:"targetsPerSecond"`
+}
+
+// Fixtures are the resources generated for a scale test.
+type Fixtures struct {
+	Namespaces    []*corev1.Namespace
+	Secrets       []*corev1.Secret
+	SecretCopiers []*secretsv1beta1.SecretCopier
+}
+
+// Name of the namespace holding the source secrets.
+func sourceNamespace(prefix string) string {
+	return prefix + "-source"
+}
+
+// Generate the resources for a scale test. The first namespace is the source
+// namespace. Secrets are assigned to the SecretCopier resources round robin,
+// with each rule copying one secret to all the target namespaces.
+func Generate(options Options) Fixtures {
+	var fixtures Fixtures
+
+	labels := func(role string) map[string]string {
+		labels := map[string]string{fixtureLabel: options.Prefix}
+
+		if role != "" {
+			labels[fixtureRoleLabel] = role
+		}
+
+		return labels
+	}
+
+	fixtures.Namespaces = append(fixtures.Namespaces, &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   sourceNamespace(options.Prefix),
+			Labels: labels("source"),
+		},
+	})
+
+	for i := 0; i < options.Namespaces; i++ {
+		fixtures.Namespaces = append(fixtures.Namespaces, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   fmt.Sprintf("%s-target-%d", options.Prefix, i),
+				Labels: labels("target"),
+			},
+		})
+	}
+
+	for i := 0; i < options.SecretCopiers; i++ {
+		fixtures.SecretCopiers = append(fixtures.SecretCopiers, &secretsv1beta1.SecretCopier{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   fmt.Sprintf("%s-%d", options.Prefix, i),
+				Labels: labels(""),
+			},
+		})
+	}
+
+	value := make([]byte, options.SecretSize)
+
+	for i := range value {
+		value[i] = 'x'
+	}
+
+	for i := 0; i < options.Secrets; i++ {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("%s-secret-%d", options.Prefix, i),
+				Namespace: sourceNamespace(options.Prefix),
+				Labels:    labels(""),
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{"value": value},
+		}
+
+		fixtures.Secrets = append(fixtures.Secrets, secret)
+
+		secretCopier := fixtures.SecretCopiers[i%options.SecretCopiers]
+
+		secretCopier.Spec.Rules = append(secretCopier.Spec.Rules, secretsv1beta1.SecretCopierRule{
+			SourceSecret: secretsv1beta1.SourceSecret{
+				Name:      secret.Name,
+				Namespace: secret.Namespace,
+			},
+			TargetNamespaces: selectors.TargetNamespaces{
+				LabelSelector: selectors.LabelSelector{
+					MatchLabels: labels("target"),
+				},
+			},
+		})
+	}
+
+	return fixtures
+}
+
+// Run creates the fixtures and waits until all target secrets have been
+// created or the context is done, writing progress to out. The SecretCopier
+// resources are created last so the time taken to copy the secrets can be
+// measured from that point.
+func Run(ctx context.Context, c client.Client, options Options, out io.Writer) (Report, error) {
+	fixtures := Generate(options)
+
+	report := Report{
+		Namespaces:      options.Namespaces,
+		Secrets:         options.Secrets,
+		SecretCopiers:   options.SecretCopiers,
+		ExpectedTargets: options.Namespaces * options.Secrets,
+	}
+
+	start := time.Now()
+
+	var objects []client.Object
+
+	for _, namespace := range fixtures.Namespaces {
+		objects = append(objects, namespace)
+	}
+
+	for _, secret := range fixtures.Secrets {
+		objects = append(objects, secret)
+	}
+
+	for _, object := range objects {
+		if err := c.Create(ctx, object); err != nil {
+			return report, fmt.Errorf("unable to create %s: %w", object.GetName(), err)
+		}
+	}
+
+	fmt.Fprintf(out, "Created %d namespaces and %d secrets\n", len(fixtures.Namespaces), len(fixtures.Secrets))
+
+	copyStart := time.Now()
+
+	for _, secretCopier := range fixtures.SecretCopiers {
+		if err := c.Create(ctx, secretCopier); err != nil {
+			return report, fmt.Errorf("unable to create SecretCopier %s: %w", secretCopier.Name, err)
+		}
+	}
+
+	report.CreateDuration = metav1.Duration{Duration: time.Since(start)}
+
+	fmt.Fprintf(out, "Created %d SecretCopiers\n", len(fixtures.SecretCopiers))
+
+	interval := options.PollInterval
+
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		copied, err := countTargets(ctx, c, options.Prefix)
+
+		if err == nil {
+			report.CopiedTargets = copied
+		} else if ctx.Err() == nil {
+			return report, fmt.Errorf("unable to count target secrets: %w", err)
+		}
+
+		report.CopyDuration = metav1.Duration{Duration: time.Since(copyStart)}
+
+		if seconds := report.CopyDuration.Seconds(); seconds > 0 {
+			report.TargetsPerSecond = float64(report.CopiedTargets) / seconds
+		}
+
+		if report.CopiedTargets >= report.ExpectedTargets {
+			report.Complete = true
+			return report, nil
+		}
+
+		fmt.Fprintf(out, "Copied %d of %d target secrets\n", report.CopiedTargets, report.ExpectedTargets)
+
+		select {
+		case <-ctx.Done():
+			return report, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Count the target secrets created for the fixtures with the given prefix.
+func countTargets(ctx context.Context, c client.Client, prefix string) (int, error) {
+	var secrets corev1.SecretList
+
+	if err := c.List(ctx, &secrets, client.MatchingLabels{fixtureLabel: prefix}); err != nil {
+		return 0, err
+	}
+
+	count := 0
+
+	for _, secret := range secrets.Items {
+		if secret.Namespace != sourceNamespace(prefix) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// Cleanup deletes the resources generated for the fixtures with the given
+// prefix. Target secrets are removed along with their namespaces.
+func Cleanup(ctx context.Context, c client.Client, prefix string) error {
+	selector := client.MatchingLabels{fixtureLabel: prefix}
+
+	var secretCopiers secretsv1beta1.SecretCopierList
+
+	if err := c.List(ctx, &secretCopiers, selector); err != nil {
+		return err
+	}
+
+	for i := range secretCopiers.Items {
+		if err := c.Delete(ctx, &secretCopiers.Items[i]); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+
+	var namespaces corev1.NamespaceList
+
+	if err := c.List(ctx, &namespaces, selector); err != nil {
+		return err
+	}
+
+	for i := range namespaces.Items {
+		if err := c.Delete(ctx, &namespaces.Items[i]); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scaletest
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+func newScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := secretsv1beta1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	return scheme
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		valid   bool
+	}{
+		{"valid", Options{Prefix: "scale", Namespaces: 10, Secrets: 5, SecretCopiers: 2}, true},
+		{"empty prefix", Options{Namespaces: 10, Secrets: 5, SecretCopiers: 2}, false},
+		{"no namespaces", Options{Prefix: "scale", Secrets: 5, SecretCopiers: 2}, false},
+		{"no secrets", Options{Prefix: "scale", Namespaces: 10, SecretCopiers: 2}, false},
+		{"more copiers than secrets", Options{Prefix: "scale", Namespaces: 10, Secrets: 2, SecretCopiers: 5}, false},
+		{"negative size", Options{Prefix: "scale", Namespaces: 1, Secrets: 1, SecretCopiers: 1, SecretSize: -1}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.options.Validate(); (err == nil) != test.valid {
+				t.Errorf("expected valid=%v, got error %v", test.valid, err)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		rules   []int
+	}{
+		{"single copier", Options{Prefix: "scale", Namespaces: 3, Secrets: 4, SecretCopiers: 1, SecretSize: 16}, []int{4}},
+		{"even spread", Options{Prefix: "scale", Namespaces: 2, Secrets: 4, SecretCopiers: 2}, []int{2, 2}},
+		{"uneven spread", Options{Prefix: "scale", Namespaces: 1, Secrets: 5, SecretCopiers: 3}, []int{2, 2, 1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fixtures := Generate(test.options)
+
+			if len(fixtures.Namespaces) != test.options.Namespaces+1 {
+				t.Errorf("expected %d namespaces, got %d", test.options.Namespaces+1, len(fixtures.Namespaces))
+			}
+
+			if fixtures.Namespaces[0].Name != "scale-source" {
+				t.Errorf("expected source namespace first, got %s", fixtures.Namespaces[0].Name)
+			}
+
+			if len(fixtures.Secrets) != test.options.Secrets {
+				t.Errorf("expected %d secrets, got %d", test.options.Secrets, len(fixtures.Secrets))
+			}
+
+			for _, secret := range fixtures.Secrets {
+				if len(secret.Data["value"]) != test.options.SecretSize {
+					t.Errorf("expected secret %s of size %d, got %d", secret.Name, test.options.SecretSize, len(secret.Data["value"]))
+				}
+			}
+
+			if len(fixtures.SecretCopiers) != len(test.rules) {
+				t.Fatalf("expected %d secret copiers, got %d", len(test.rules), len(fixtures.SecretCopiers))
+			}
+
+			for i, secretCopier := range fixtures.SecretCopiers {
+				if len(secretCopier.Spec.Rules) != test.rules[i] {
+					t.Errorf("expected %d rules in %s, got %d", test.rules[i], secretCopier.Name, len(secretCopier.Spec.Rules))
+				}
+
+				for _, rule := range secretCopier.Spec.Rules {
+					for _, namespace := range fixtures.Namespaces {
+						matches := rule.TargetNamespaces.Matches(namespace)
+
+						if matches != (namespace.Name != "scale-source") {
+							t.Errorf("unexpected match=%v of rule in %s for namespace %s", matches, secretCopier.Name, namespace.Name)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRunTimesOut(t *testing.T) {
+	fakeClient := fake.NewClientBuilder().WithScheme(newScheme(t)).Build()
+
+	options := Options{Prefix: "scale", Namespaces: 2, Secrets: 2, SecretCopiers: 1, PollInterval: 10 * time.Millisecond}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// Without a manager running nothing is copied, so the test times out
+	// with no target secrets.
+
+	report, err := Run(ctx, fakeClient, options, io.Discard)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Complete || report.CopiedTargets != 0 || report.ExpectedTargets != 4 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	if err := Cleanup(context.Background(), fakeClient, "scale"); err != nil {
+		t.Fatal(err)
+	}
+
+	var namespaces corev1.NamespaceList
+
+	if err := fakeClient.List(context.Background(), &namespaces, client.MatchingLabels{fixtureLabel: "scale"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var secretCopiers secretsv1beta1.SecretCopierList
+
+	if err := fakeClient.List(context.Background(), &secretCopiers); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(namespaces.Items) != 0 || len(secretCopiers.Items) != 0 {
+		t.Errorf("expected fixtures to be deleted, got %d namespaces and %d secret copiers", len(namespaces.Items), len(secretCopiers.Items))
+	}
+}
+
+func TestRunCompletes(t *testing.T) {
+	// Target secrets are created up front as the manager would create them,
+	// so the test completes on the first check.
+
+	var objects []client.Object
+
+	// Secrets from fixtures with another prefix are not counted.
+
+	prefixes := map[string]string{"scale-target-0": "scale", "scale-target-1": "scale", "other-target-0": "other"}
+
+	for namespace, prefix := range prefixes {
+		for _, name := range []string{"scale-secret-0", "scale-secret-1"} {
+			objects = append(objects, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespace,
+					Labels:    map[string]string{fixtureLabel: prefix},
+				},
+			})
+		}
+	}
+
+	fakeClient := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(objects...).Build()
+
+	options := Options{Prefix: "scale", Namespaces: 2, Secrets: 2, SecretCopiers: 2}
+
+	report, err := Run(context.Background(), fakeClient, options, io.Discard)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.Complete || report.CopiedTargets != 4 || report.ExpectedTargets != 4 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
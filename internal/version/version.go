@@ -0,0 +1,112 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package version reports the version of the secrets manager binary. The
+// version, git commit and build date are set at build time using linker
+// flags, falling back to the version control details recorded by the Go
+// toolchain when not set.
+package version
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time using -ldflags "-X <package>.Version=...".
+var (
+	Version   = ""
+	GitCommit = ""
+	BuildDate = ""
+)
+
+// Info describes the build of the secrets manager binary.
+type Info struct {
+	// Release version, or "devel" for an unreleased build.
+	Version string `json:"version"`
+
+	// Git commit the binary was built from.
+	GitCommit string `json:"gitCommit"`
+
+	// Time at which the binary was built, or of the commit if not known.
+	BuildDate string `json:"buildDate"`
+
+	// Version of Go the binary was built with.
+	GoVersion string `json:"goVersion"`
+}
+
+// Get returns the build information for the running binary.
+func Get() Info {
+	buildInfo, _ := debug.ReadBuildInfo()
+
+	return get(buildInfo)
+}
+
+func get(buildInfo *debug.BuildInfo) Info {
+	info := Info{
+		Version:   Version,
+		GitCommit: GitCommit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+
+	// Fall back to the details recorded by the Go toolchain for any value
+	// not set using linker flags.
+
+	if buildInfo != nil {
+		if info.Version == "" && buildInfo.Main.Version != "(devel)" {
+			info.Version = buildInfo.Main.Version
+		}
+
+		for _, setting := range buildInfo.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.GitCommit == "" {
+					info.GitCommit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			}
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = "devel"
+	}
+
+	if info.GitCommit == "" {
+		info.GitCommit = "unknown"
+	}
+
+	if info.BuildDate == "" {
+		info.BuildDate = "unknown"
+	}
+
+	return info
+}
+
+// Handler returns an HTTP handler which responds with the build information
+// as JSON.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		_ = json.NewEncoder(w).Encode(Get())
+	})
+}
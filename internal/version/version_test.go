@@ -0,0 +1,103 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		name      string
+		version   string
+		gitCommit string
+		buildDate string
+		buildInfo *debug.BuildInfo
+		expected  Info
+	}{
+		{
+			name: "no build information",
+			expected: Info{
+				Version:   "devel",
+				GitCommit: "unknown",
+				BuildDate: "unknown",
+			},
+		},
+		{
+			name: "version control details recorded by toolchain",
+			buildInfo: &debug.BuildInfo{
+				Main: debug.Module{Version: "(devel)"},
+				Settings: []debug.BuildSetting{
+					{Key: "vcs.revision", Value: "0123456789abcdef"},
+					{Key: "vcs.time", Value: "2024-09-01T10:00:00Z"},
+				},
+			},
+			expected: Info{
+				Version:   "devel",
+				GitCommit: "0123456789abcdef",
+				BuildDate: "2024-09-01T10:00:00Z",
+			},
+		},
+		{
+			name: "module version recorded by toolchain",
+			buildInfo: &debug.BuildInfo{
+				Main: debug.Module{Version: "v0.3.0"},
+			},
+			expected: Info{
+				Version:   "v0.3.0",
+				GitCommit: "unknown",
+				BuildDate: "unknown",
+			},
+		},
+		{
+			name:      "linker flags take precedence",
+			version:   "v0.4.0",
+			gitCommit: "fedcba9876543210",
+			buildDate: "2024-10-01T12:00:00Z",
+			buildInfo: &debug.BuildInfo{
+				Main: debug.Module{Version: "v0.3.0"},
+				Settings: []debug.BuildSetting{
+					{Key: "vcs.revision", Value: "0123456789abcdef"},
+					{Key: "vcs.time", Value: "2024-09-01T10:00:00Z"},
+				},
+			},
+			expected: Info{
+				Version:   "v0.4.0",
+				GitCommit: "fedcba9876543210",
+				BuildDate: "2024-10-01T12:00:00Z",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			Version, GitCommit, BuildDate = test.version, test.gitCommit, test.buildDate
+
+			defer func() {
+				Version, GitCommit, BuildDate = "", "", ""
+			}()
+
+			test.expected.GoVersion = runtime.Version()
+
+			if info := get(test.buildInfo); info != test.expected {
+				t.Errorf("get() = %+v, expected %+v", info, test.expected)
+			}
+		})
+	}
+}
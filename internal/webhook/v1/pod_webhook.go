@@ -0,0 +1,273 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+// log is for logging in this package.
+var podlog = logf.Log.WithName("pod-resource")
+
+// SetupPodWebhookWithManager registers the webhook for Pod in the manager.
+func SetupPodWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&corev1.Pod{}).
+		WithDefaulter(&PodCustomDefaulter{
+			Client: mgr.GetClient(),
+		}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate--v1-pod,mutating=true,failurePolicy=ignore,sideEffects=None,groups="",resources=pods,verbs=create,versions=v1,name=mpod-v1.kb.io,admissionReviewVersions=v1
+// +kubebuilder:rbac:groups=secrets-manager.advok8s.io,resources=secretinjectors,verbs=get;list;watch
+
+// PodCustomDefaulter struct is responsible for injecting secrets into pods
+// matched by a SecretInjector when they are created.
+type PodCustomDefaulter struct {
+	// Client used to list the SecretInjectors in the namespace of a pod.
+	Client client.Reader
+}
+
+var _ webhook.CustomDefaulter = &PodCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the type Pod.
+func (d *PodCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return fmt.Errorf("expected a Pod object but got %T", obj)
+	}
+
+	// The namespace is not always set on a pod when it is created, in which
+	// case it is taken from the admission request.
+
+	namespace := pod.Namespace
+
+	if namespace == "" {
+		if request, err := admission.RequestFromContext(ctx); err == nil {
+			namespace = request.Namespace
+		}
+	}
+
+	var injectors secretsv1beta1.SecretInjectorList
+
+	if err := d.Client.List(ctx, &injectors, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+
+	// Apply the SecretInjectors in order of name, so the result doesn't
+	// depend on the order they are listed in.
+
+	sort.Slice(injectors.Items, func(i, j int) bool {
+		return injectors.Items[i].Name < injectors.Items[j].Name
+	})
+
+	injectedBy := make([]string, 0)
+
+	for i := range injectors.Items {
+		injector := &injectors.Items[i]
+
+		selector, err := metav1.LabelSelectorAsSelector(&injector.Spec.Selector)
+
+		if err != nil {
+			podlog.Error(err, "Invalid selector in SecretInjector", "name", injector.Name, "namespace", injector.Namespace)
+			continue
+		}
+
+		if !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+
+		// Skip the SecretInjector if none of the containers it names exist
+		// in the pod, so a volume isn't added which nothing mounts.
+
+		containers := selectedContainers(pod, injector.Spec.Containers)
+
+		if len(containers) == 0 {
+			continue
+		}
+
+		podlog.Info("Injecting secrets into pod", "injector", injector.Name, "namespace", namespace, "pod", podName(pod))
+
+		injectSecrets(pod, containers, injector)
+
+		injectedBy = append(injectedBy, injector.Name)
+	}
+
+	if len(injectedBy) == 0 {
+		return nil
+	}
+
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+
+	pod.Annotations[secretsv1beta1.SecretInjectorInjectedByAnnotation] = strings.Join(injectedBy, ",")
+
+	return nil
+}
+
+// Name of a pod for logging. Pods created by controllers often only have a
+// generated name prefix at the time they are admitted.
+func podName(pod *corev1.Pod) string {
+	if pod.Name != "" {
+		return pod.Name
+	}
+
+	return pod.GenerateName
+}
+
+// Inject the secrets of the SecretInjector into the selected containers of the
+// pod. Environment variables and volume mounts already present in a container
+// are left as they are, so a pod can override what would be injected.
+func injectSecrets(pod *corev1.Pod, containers []*corev1.Container, injector *secretsv1beta1.SecretInjector) {
+	for index, secret := range injector.Spec.Secrets {
+		optional := secret.Optional
+
+		if secret.Env != nil {
+			for _, container := range containers {
+				injectEnv(container, &secret, &optional)
+			}
+		}
+
+		if secret.MountPath != "" {
+			volumeName := injectedVolumeName(injector.Name, index)
+
+			if !slices.ContainsFunc(pod.Spec.Volumes, func(volume corev1.Volume) bool { return volume.Name == volumeName }) {
+				volume := corev1.Volume{
+					Name: volumeName,
+					VolumeSource: corev1.VolumeSource{
+						Secret: &corev1.SecretVolumeSource{
+							SecretName: secret.SecretName,
+							Optional:   &optional,
+						},
+					},
+				}
+
+				for _, key := range secret.Keys {
+					volume.Secret.Items = append(volume.Secret.Items, corev1.KeyToPath{Key: key, Path: key})
+				}
+
+				pod.Spec.Volumes = append(pod.Spec.Volumes, volume)
+			}
+
+			for _, container := range containers {
+				if slices.ContainsFunc(container.VolumeMounts, func(mount corev1.VolumeMount) bool { return mount.MountPath == secret.MountPath }) {
+					continue
+				}
+
+				container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+					Name:      volumeName,
+					MountPath: secret.MountPath,
+					ReadOnly:  true,
+				})
+			}
+		}
+	}
+}
+
+// Inject the keys of a secret into a container as environment variables.
+// Where no keys are listed, all keys of the secret are injected using an
+// envFrom source.
+func injectEnv(container *corev1.Container, secret *secretsv1beta1.InjectedSecret, optional *bool) {
+	if len(secret.Keys) == 0 {
+		source := corev1.EnvFromSource{
+			Prefix: secret.Env.Prefix,
+			SecretRef: &corev1.SecretEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secret.SecretName},
+				Optional:             optional,
+			},
+		}
+
+		if !slices.ContainsFunc(container.EnvFrom, func(existing corev1.EnvFromSource) bool {
+			return existing.Prefix == source.Prefix && existing.SecretRef != nil && existing.SecretRef.Name == secret.SecretName
+		}) {
+			container.EnvFrom = append(container.EnvFrom, source)
+		}
+
+		return
+	}
+
+	for _, key := range secret.Keys {
+		name := secret.Env.Prefix + key
+
+		if slices.ContainsFunc(container.Env, func(existing corev1.EnvVar) bool { return existing.Name == name }) {
+			continue
+		}
+
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name: name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: secret.SecretName},
+					Key:                  key,
+					Optional:             optional,
+				},
+			},
+		})
+	}
+}
+
+// Return the containers of the pod which secrets are injected into. If no
+// names are given, all containers other than init containers are selected.
+func selectedContainers(pod *corev1.Pod, names []string) []*corev1.Container {
+	containers := make([]*corev1.Container, 0)
+
+	for i := range pod.Spec.Containers {
+		if len(names) == 0 || slices.Contains(names, pod.Spec.Containers[i].Name) {
+			containers = append(containers, &pod.Spec.Containers[i])
+		}
+	}
+
+	if len(names) == 0 {
+		return containers
+	}
+
+	for i := range pod.Spec.InitContainers {
+		if slices.Contains(names, pod.Spec.InitContainers[i].Name) {
+			containers = append(containers, &pod.Spec.InitContainers[i])
+		}
+	}
+
+	return containers
+}
+
+// Name of the volume for a secret mounted by a SecretInjector. The name of the
+// SecretInjector is hashed so the volume name stays within the length allowed.
+func injectedVolumeName(injectorName string, index int) string {
+	hash := fnv.New32a()
+
+	hash.Write([]byte(injectorName))
+
+	return fmt.Sprintf("injected-secret-%08x-%d", hash.Sum32(), index)
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+func TestPodCustomDefaulter_Default(t *testing.T) {
+	scheme := runtime.NewScheme()
+
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = secretsv1beta1.AddToScheme(scheme)
+
+	injectors := []secretsv1beta1.SecretInjector{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "tenant-1"},
+			Spec: secretsv1beta1.SecretInjectorSpec{
+				Selector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				Secrets: []secretsv1beta1.InjectedSecret{
+					{
+						SecretName: "database-credentials",
+						Keys:       []string{"username", "password"},
+						Env:        &secretsv1beta1.InjectedSecretEnv{Prefix: "DB_"},
+					},
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "tenant-1"},
+			Spec: secretsv1beta1.SecretInjectorSpec{
+				Containers: []string{"sidecar"},
+				Secrets: []secretsv1beta1.InjectedSecret{
+					{
+						SecretName: "registry-credentials",
+						MountPath:  "/var/run/secrets/registry",
+						Optional:   true,
+					},
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "tenant-2"},
+			Spec: secretsv1beta1.SecretInjectorSpec{
+				Secrets: []secretsv1beta1.InjectedSecret{
+					{
+						SecretName: "other-credentials",
+						Env:        &secretsv1beta1.InjectedSecretEnv{},
+					},
+				},
+			},
+		},
+	}
+
+	builder := fake.NewClientBuilder().WithScheme(scheme)
+
+	for i := range injectors {
+		builder = builder.WithObjects(&injectors[i])
+	}
+
+	defaulter := &PodCustomDefaulter{Client: builder.Build()}
+
+	tests := []struct {
+		name       string
+		pod        *corev1.Pod
+		injectedBy string
+		env        map[string][]string
+		envFrom    map[string]int
+		mounts     map[string]int
+		volumes    int
+	}{
+		{
+			name: "selector matches pod",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "tenant-1", Labels: map[string]string{"app": "web"}},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "main"}, {Name: "sidecar"}},
+				},
+			},
+			injectedBy: "database,registry",
+			env:        map[string][]string{"main": {"DB_username", "DB_password"}, "sidecar": {"DB_username", "DB_password"}},
+			mounts:     map[string]int{"main": 0, "sidecar": 1},
+			volumes:    1,
+		},
+		{
+			name: "selector does not match pod",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "tenant-1", Labels: map[string]string{"app": "api"}},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "main"}},
+				},
+			},
+			env:    map[string][]string{"main": nil},
+			mounts: map[string]int{"main": 0},
+		},
+		{
+			name: "existing environment variable is kept",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "tenant-1", Labels: map[string]string{"app": "web"}},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "main", Env: []corev1.EnvVar{{Name: "DB_username", Value: "admin"}}}},
+				},
+			},
+			injectedBy: "database",
+			env:        map[string][]string{"main": {"DB_username", "DB_password"}},
+		},
+		{
+			name: "all keys injected using envFrom",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "job", Namespace: "tenant-2"},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "main"}},
+				},
+			},
+			injectedBy: "other",
+			env:        map[string][]string{"main": nil},
+			envFrom:    map[string]int{"main": 1},
+		},
+		{
+			name: "no secret injectors in namespace",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "tenant-3", Labels: map[string]string{"app": "web"}},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "main"}},
+				},
+			},
+			env: map[string][]string{"main": nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := defaulter.Default(context.Background(), tt.pod); err != nil {
+				t.Fatalf("Default() error = %v", err)
+			}
+
+			// Applying the defaulter a second time should not change the pod.
+
+			if err := defaulter.Default(context.Background(), tt.pod); err != nil {
+				t.Fatalf("Default() error = %v", err)
+			}
+
+			if got := tt.pod.Annotations[secretsv1beta1.SecretInjectorInjectedByAnnotation]; got != tt.injectedBy {
+				t.Errorf("injected by = %q, want %q", got, tt.injectedBy)
+			}
+
+			if got := len(tt.pod.Spec.Volumes); got != tt.volumes {
+				t.Errorf("volumes = %d, want %d", got, tt.volumes)
+			}
+
+			for _, container := range tt.pod.Spec.Containers {
+				names := make([]string, 0)
+
+				for _, env := range container.Env {
+					names = append(names, env.Name)
+				}
+
+				if want := tt.env[container.Name]; len(names) != len(want) {
+					t.Errorf("container %q env = %v, want %v", container.Name, names, want)
+				} else {
+					for i := range want {
+						if names[i] != want[i] {
+							t.Errorf("container %q env = %v, want %v", container.Name, names, want)
+							break
+						}
+					}
+				}
+
+				if got := len(container.EnvFrom); got != tt.envFrom[container.Name] {
+					t.Errorf("container %q envFrom = %d, want %d", container.Name, got, tt.envFrom[container.Name])
+				}
+
+				if got := len(container.VolumeMounts); got != tt.mounts[container.Name] {
+					t.Errorf("container %q volume mounts = %d, want %d", container.Name, got, tt.mounts[container.Name])
+				}
+			}
+		})
+	}
+}
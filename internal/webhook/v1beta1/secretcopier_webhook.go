@@ -0,0 +1,548 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimachineryvalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/datatemplate"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/registrymirror"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+// log is for logging in this package.
+var secretcopierlog = logf.Log.WithName("secretcopier-resource")
+
+// Annotation which when set to "true" on a SecretCopier disables enforcement of
+// the admission limits for that SecretCopier.
+const BypassLimitsAnnotation = "secrets-manager.advok8s.io/bypass-limits"
+
+// Prefix of annotations reserved for use by the manager, which can't be given
+// as annotations to apply to a target secret.
+const reservedAnnotationPrefix = "secrets-manager.advok8s.io/"
+
+// SecretCopierLimits are the maximums enforced on a SecretCopier at admission.
+// A value of zero means no limit is enforced.
+type SecretCopierLimits struct {
+	// Maximum number of rules in a SecretCopier.
+	MaxRules int
+
+	// Maximum number of target namespaces matched by a single rule.
+	MaxTargetNamespacesPerRule int
+
+	// Maximum number of target secrets managed by a SecretCopier across all
+	// of its rules.
+	MaxTargetsPerCopier int
+}
+
+// SetupSecretCopierWebhookWithManager registers the webhook for SecretCopier in the manager.
+func SetupSecretCopierWebhookWithManager(mgr ctrl.Manager, limits SecretCopierLimits) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&secretsv1beta1.SecretCopier{}).
+		WithValidator(&SecretCopierCustomValidator{
+			Client:         mgr.GetClient(),
+			AccessReviewer: mgr.GetClient(),
+			Limits:         limits,
+		}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-secrets-manager-advok8s-io-v1beta1-secretcopier,mutating=false,failurePolicy=fail,sideEffects=None,groups=secrets-manager.advok8s.io,resources=secretcopiers,verbs=create;update,versions=v1beta1,name=vsecretcopier-v1beta1.kb.io,admissionReviewVersions=v1
+
+// SecretCopierCustomValidator struct is responsible for validating the SecretCopier resource
+// when it is created or updated.
+type SecretCopierCustomValidator struct {
+	// Client used to list namespaces when estimating the fan-out of rules.
+	Client client.Reader
+
+	// Client used to make SubjectAccessReviews checking that the user making
+	// a request may impersonate the service account named by a SecretCopier.
+	// If nil, SecretCopiers which name a service account are rejected.
+	AccessReviewer client.Writer
+
+	// Limits to enforce.
+	Limits SecretCopierLimits
+}
+
+var _ webhook.CustomValidator = &SecretCopierCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type SecretCopier.
+func (v *SecretCopierCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	secretcopier, ok := obj.(*secretsv1beta1.SecretCopier)
+	if !ok {
+		return nil, fmt.Errorf("expected a SecretCopier object but got %T", obj)
+	}
+	secretcopierlog.Info("Validation for SecretCopier upon creation", "name", secretcopier.GetName())
+
+	return nil, v.validateSecretCopier(ctx, secretcopier, nil)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type SecretCopier.
+func (v *SecretCopierCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	secretcopier, ok := newObj.(*secretsv1beta1.SecretCopier)
+	if !ok {
+		return nil, fmt.Errorf("expected a SecretCopier object for the newObj but got %T", newObj)
+	}
+	oldSecretcopier, ok := oldObj.(*secretsv1beta1.SecretCopier)
+	if !ok {
+		return nil, fmt.Errorf("expected a SecretCopier object for the oldObj but got %T", oldObj)
+	}
+	secretcopierlog.Info("Validation for SecretCopier upon update", "name", secretcopier.GetName())
+
+	return nil, v.validateSecretCopier(ctx, secretcopier, oldSecretcopier)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type SecretCopier.
+func (v *SecretCopierCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// Validate the SecretCopier against the configured limits. The number of
+// target namespaces for each rule is estimated by matching the rule against
+// the namespaces which currently exist, in the same way as the controller
+// would when the SecretCopier is reconciled. Dependencies between rules and
+// the SecretCopiers target secrets are adopted from are always validated,
+// even when the limits are bypassed, as is the service account to impersonate.
+// When updating, the previous version of the SecretCopier is supplied.
+func (v *SecretCopierCustomValidator) validateSecretCopier(ctx context.Context, secretcopier *secretsv1beta1.SecretCopier, oldSecretcopier *secretsv1beta1.SecretCopier) error {
+	var allErrs field.ErrorList
+
+	serviceAccountErrs, err := v.validateServiceAccount(ctx, secretcopier, oldSecretcopier)
+
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+
+	allErrs = append(allErrs, serviceAccountErrs...)
+
+	rulesPath := field.NewPath("spec").Child("rules")
+
+	_, unresolvedRules := secretcopier.Spec.RuleOrder()
+
+	for ruleIndex, rule := range secretcopier.Spec.Rules {
+		if reason, found := unresolvedRules[ruleIndex]; found {
+			allErrs = append(allErrs, field.Invalid(rulesPath.Index(ruleIndex).Child("dependsOn"), rule.DependsOn, reason))
+		}
+
+		targetSecretPath := rulesPath.Index(ruleIndex).Child("targetSecret")
+
+		if rule.BundledConfigMap != nil && rule.TargetSecret.HashSuffix != nil {
+			allErrs = append(allErrs, field.Forbidden(rulesPath.Index(ruleIndex).Child("bundledConfigMap"),
+				"a config map can't be bundled with a target secret named with a hash suffix"))
+		}
+
+		allErrs = append(allErrs, validateSourceSecretPattern(rulesPath.Index(ruleIndex), &rule)...)
+		allErrs = append(allErrs, validateTargetCluster(rulesPath.Index(ruleIndex), &rule)...)
+		allErrs = append(allErrs, validateRequiredResource(rulesPath.Index(ruleIndex), &rule)...)
+		allErrs = append(allErrs, validateKeyPatterns(targetSecretPath.Child("includeKeys"), rule.TargetSecret.IncludeKeys)...)
+		allErrs = append(allErrs, validateKeyPatterns(targetSecretPath.Child("excludeKeys"), rule.TargetSecret.ExcludeKeys)...)
+		allErrs = append(allErrs, validateTargetAnnotations(targetSecretPath.Child("annotations"), rule.TargetSecret.Annotations)...)
+		allErrs = append(allErrs, validateTargetTemplate(targetSecretPath.Child("template"), rule.TargetSecret.Template)...)
+		allErrs = append(allErrs, validateRegistryMirrors(targetSecretPath.Child("registryMirrors"), rule.TargetSecret.RegistryMirrors)...)
+		allErrs = append(allErrs, validateFirstCopyHook(rulesPath.Index(ruleIndex).Child("onFirstCopy"), rule.OnFirstCopy)...)
+
+		if slices.Contains(rule.AdoptFrom, secretcopier.Name) {
+			allErrs = append(allErrs, field.Invalid(rulesPath.Index(ruleIndex).Child("adoptFrom"), rule.AdoptFrom,
+				"a SecretCopier can't adopt target secrets from itself"))
+		}
+	}
+
+	if secretcopier.Annotations[BypassLimitsAnnotation] == "true" {
+		return v.invalid(secretcopier, allErrs)
+	}
+
+	if v.Limits.MaxRules > 0 && len(secretcopier.Spec.Rules) > v.Limits.MaxRules {
+		allErrs = append(allErrs, field.TooMany(rulesPath, len(secretcopier.Spec.Rules), v.Limits.MaxRules))
+	}
+
+	if v.Limits.MaxTargetNamespacesPerRule > 0 || v.Limits.MaxTargetsPerCopier > 0 {
+		namespaces := &corev1.NamespaceList{}
+
+		if err := v.Client.List(ctx, namespaces); err != nil {
+			return apierrors.NewInternalError(err)
+		}
+
+		totalTargets := 0
+
+		for ruleIndex, rule := range secretcopier.Spec.Rules {
+			// Namespaces of remote clusters can't be counted here.
+
+			if rule.TargetCluster != nil {
+				continue
+			}
+
+			matcher := rule.TargetNamespaces.Compile()
+
+			ruleTargets := 0
+
+			for _, namespace := range namespaces.Items {
+				if rule.SkipsNamespace(&namespace) {
+					continue
+				}
+
+				if namespace.Name != rule.SourceSecret.Namespace && matcher.Matches(&namespace) {
+					ruleTargets++
+				}
+			}
+
+			if v.Limits.MaxTargetNamespacesPerRule > 0 && ruleTargets > v.Limits.MaxTargetNamespacesPerRule {
+				allErrs = append(allErrs, field.Invalid(rulesPath.Index(ruleIndex).Child("targetNamespaces"), ruleTargets,
+					fmt.Sprintf("rule matches %d target namespaces, which exceeds the limit of %d", ruleTargets, v.Limits.MaxTargetNamespacesPerRule)))
+			}
+
+			totalTargets += ruleTargets
+		}
+
+		if v.Limits.MaxTargetsPerCopier > 0 && totalTargets > v.Limits.MaxTargetsPerCopier {
+			allErrs = append(allErrs, field.Invalid(rulesPath, totalTargets,
+				fmt.Sprintf("rules match %d target secrets in total, which exceeds the limit of %d", totalTargets, v.Limits.MaxTargetsPerCopier)))
+		}
+	}
+
+	return v.invalid(secretcopier, allErrs)
+}
+
+// Validate the glob patterns selecting the data keys of the source secret to
+// copy.
+func validateKeyPatterns(path *field.Path, patterns []string) field.ErrorList {
+	var allErrs field.ErrorList
+
+	for i, pattern := range patterns {
+		if err := selectors.ValidatePattern(pattern); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Index(i), pattern, err.Error()))
+		}
+	}
+
+	return allErrs
+}
+
+// Validate a source secret name which is a glob pattern. As each matching
+// source secret is copied to a target secret of the same name, the rule can't
+// name the target secret, nor bundle a config map which every target secret
+// would share.
+func validateSourceSecretPattern(path *field.Path, rule *secretsv1beta1.SecretCopierRule) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if !rule.CopiesSourceSecretPattern() {
+		return allErrs
+	}
+
+	if err := selectors.ValidatePattern(rule.SourceSecret.Name); err != nil {
+		allErrs = append(allErrs, field.Invalid(path.Child("sourceSecret", "name"), rule.SourceSecret.Name, err.Error()))
+	}
+
+	if rule.TargetSecret.Name != "" {
+		allErrs = append(allErrs, field.Forbidden(path.Child("targetSecret", "name"),
+			"the target secret can't be named when the source secret name is a pattern"))
+	}
+
+	if rule.BundledConfigMap != nil {
+		allErrs = append(allErrs, field.Forbidden(path.Child("bundledConfigMap"),
+			"a config map can't be bundled when the source secret name is a pattern"))
+	}
+
+	return allErrs
+}
+
+// Validate a rule which copies to a remote cluster. Options which rely on
+// state held in the cluster the manager runs in can't be used with it.
+func validateTargetCluster(path *field.Path, rule *secretsv1beta1.SecretCopierRule) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if rule.TargetCluster == nil {
+		return allErrs
+	}
+
+	forbidden := func(child *field.Path) {
+		allErrs = append(allErrs, field.Forbidden(child, "can't be used when copying to a remote cluster"))
+	}
+
+	if rule.TargetSecret.HashSuffix != nil {
+		forbidden(path.Child("targetSecret", "hashSuffix"))
+	}
+
+	if rule.TargetSecret.Immutable {
+		forbidden(path.Child("targetSecret", "immutable"))
+	}
+
+	if rule.BundledConfigMap != nil {
+		forbidden(path.Child("bundledConfigMap"))
+	}
+
+	if rule.Rollout != nil {
+		forbidden(path.Child("rollout"))
+	}
+
+	if rule.TargetNamespaceReadiness != nil {
+		forbidden(path.Child("targetNamespaceReadiness"))
+	}
+
+	if rule.OnFirstCopy != nil {
+		forbidden(path.Child("onFirstCopy"))
+	}
+
+	if len(rule.AdoptFrom) != 0 {
+		forbidden(path.Child("adoptFrom"))
+	}
+
+	if rule.RequiredResource != nil {
+		forbidden(path.Child("requiredResource"))
+	}
+
+	if rule.VirtualClusterNamespaces != nil && rule.VirtualClusterNamespaces.Policy == secretsv1beta1.VirtualClusterNamespaceCopyInto {
+		forbidden(path.Child("virtualClusterNamespaces", "policy"))
+	}
+
+	return allErrs
+}
+
+// Validate the resource a rule requires to be present in target namespaces.
+// The API version must parse and the selector must be valid. As the target
+// secret is removed by name from target namespaces without the resource, it
+// can't be named with a hash suffix.
+func validateRequiredResource(path *field.Path, rule *secretsv1beta1.SecretCopierRule) field.ErrorList {
+	required := rule.RequiredResource
+
+	if required == nil {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+
+	if _, err := schema.ParseGroupVersion(required.APIVersion); err != nil {
+		allErrs = append(allErrs, field.Invalid(path.Child("requiredResource", "apiVersion"), required.APIVersion, err.Error()))
+	}
+
+	allErrs = append(allErrs, metav1validation.ValidateLabelSelector(required.Selector,
+		metav1validation.LabelSelectorValidationOptions{}, path.Child("requiredResource", "selector"))...)
+
+	if rule.TargetSecret.HashSuffix != nil {
+		allErrs = append(allErrs, field.Forbidden(path.Child("targetSecret", "hashSuffix"),
+			"a target secret named with a hash suffix can't be used with a required resource"))
+	}
+
+	return allErrs
+}
+
+// Validate the annotations to apply to the target secret. Annotations with the
+// prefix reserved for the manager are rejected, as the manager would ignore
+// them rather than clobber the markers it uses to track target secrets.
+func validateTargetAnnotations(path *field.Path, annotations map[string]string) field.ErrorList {
+	allErrs := apimachineryvalidation.ValidateAnnotations(annotations, path)
+
+	keys := make([]string, 0, len(annotations))
+
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		if strings.HasPrefix(key, reservedAnnotationPrefix) {
+			allErrs = append(allErrs, field.Forbidden(path.Key(key),
+				fmt.Sprintf("annotations with the prefix %s are reserved for use by the manager", reservedAnnotationPrefix)))
+		}
+	}
+
+	return allErrs
+}
+
+// Validate the templates rendering the data of the target secret. Each key
+// must be a valid data key of a secret and each template must parse.
+func validateTargetTemplate(path *field.Path, template *secretsv1beta1.TargetSecretTemplate) field.ErrorList {
+	if template == nil {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+
+	keys := make([]string, 0, len(template.Data))
+
+	for key := range template.Data {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		for _, msg := range validation.IsConfigMapKey(key) {
+			allErrs = append(allErrs, field.Invalid(path.Child("data").Key(key), key, msg))
+		}
+
+		if err := datatemplate.Validate(template.Data[key]); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("data").Key(key), template.Data[key], err.Error()))
+		}
+	}
+
+	return allErrs
+}
+
+// Validate the registry mirrors credentials in the target secret are rewritten
+// for. Registries and mirrors must be hostnames without a scheme, and a
+// registry can't be mirrored more than once.
+func validateRegistryMirrors(path *field.Path, mirrors []secretsv1beta1.RegistryMirror) field.ErrorList {
+	var allErrs field.ErrorList
+
+	registries := make(map[string]bool, len(mirrors))
+
+	for i, mirror := range mirrors {
+		if err := registrymirror.ValidateRegistry(mirror.Registry); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Index(i).Child("registry"), mirror.Registry, err.Error()))
+		}
+
+		if err := registrymirror.ValidateRegistry(mirror.Mirror); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Index(i).Child("mirror"), mirror.Mirror, err.Error()))
+		}
+
+		if registries[mirror.Registry] {
+			allErrs = append(allErrs, field.Duplicate(path.Index(i).Child("registry"), mirror.Registry))
+		}
+
+		registries[mirror.Registry] = true
+	}
+
+	return allErrs
+}
+
+// Validate the first copy hook of a rule. The labels and annotations must be
+// valid for the target namespace, with the annotation prefix reserved for the
+// manager rejected as for target secrets, and the webhook URL must be an
+// absolute HTTP or HTTPS URL.
+func validateFirstCopyHook(path *field.Path, hook *secretsv1beta1.FirstCopyHook) field.ErrorList {
+	if hook == nil {
+		return nil
+	}
+
+	allErrs := metav1validation.ValidateLabels(hook.NamespaceLabels, path.Child("namespaceLabels"))
+
+	allErrs = append(allErrs, validateTargetAnnotations(path.Child("namespaceAnnotations"), hook.NamespaceAnnotations)...)
+
+	if hook.WebhookURL != "" {
+		parsed, err := url.Parse(hook.WebhookURL)
+
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			allErrs = append(allErrs, field.Invalid(path.Child("webhookURL"), hook.WebhookURL, "must be an absolute http or https URL"))
+		}
+	}
+
+	return allErrs
+}
+
+// Validate the service account named by the SecretCopier to impersonate. As
+// the SecretCopier would allow the user making the request to act with the
+// permissions of the service account, the user must themselves be permitted to
+// impersonate it. This is only checked when the service account is first set
+// or changed, so other users can still update the SecretCopier.
+func (v *SecretCopierCustomValidator) validateServiceAccount(ctx context.Context, secretcopier *secretsv1beta1.SecretCopier, oldSecretcopier *secretsv1beta1.SecretCopier) (field.ErrorList, error) {
+	serviceAccount := secretcopier.Spec.ServiceAccount
+
+	if serviceAccount == nil {
+		return nil, nil
+	}
+
+	serviceAccountPath := field.NewPath("spec").Child("serviceAccount")
+
+	var allErrs field.ErrorList
+
+	for _, msg := range validation.IsDNS1123Subdomain(serviceAccount.Name) {
+		allErrs = append(allErrs, field.Invalid(serviceAccountPath.Child("name"), serviceAccount.Name, msg))
+	}
+
+	for _, msg := range validation.IsDNS1123Label(serviceAccount.Namespace) {
+		allErrs = append(allErrs, field.Invalid(serviceAccountPath.Child("namespace"), serviceAccount.Namespace, msg))
+	}
+
+	if len(allErrs) != 0 {
+		return allErrs, nil
+	}
+
+	if oldSecretcopier != nil && oldSecretcopier.Spec.ServiceAccount != nil && *oldSecretcopier.Spec.ServiceAccount == *serviceAccount {
+		return nil, nil
+	}
+
+	if v.AccessReviewer == nil {
+		return field.ErrorList{field.Forbidden(serviceAccountPath, "impersonation of service accounts is not enabled")}, nil
+	}
+
+	request, err := admission.RequestFromContext(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(request.UserInfo.Extra))
+
+	for key, value := range request.UserInfo.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   request.UserInfo.Username,
+			Groups: request.UserInfo.Groups,
+			UID:    request.UserInfo.UID,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: serviceAccount.Namespace,
+				Verb:      "impersonate",
+				Resource:  "serviceaccounts",
+				Name:      serviceAccount.Name,
+			},
+		},
+	}
+
+	if err := v.AccessReviewer.Create(ctx, review); err != nil {
+		return nil, err
+	}
+
+	if !review.Status.Allowed {
+		return field.ErrorList{field.Forbidden(serviceAccountPath,
+			fmt.Sprintf("user %s is not permitted to impersonate service account %s/%s", request.UserInfo.Username, serviceAccount.Namespace, serviceAccount.Name))}, nil
+	}
+
+	return nil, nil
+}
+
+// Return an error rejecting the SecretCopier if there are any validation
+// errors.
+func (v *SecretCopierCustomValidator) invalid(secretcopier *secretsv1beta1.SecretCopier, allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(secretsv1beta1.GroupVersion.WithKind("SecretCopier").GroupKind(), secretcopier.Name, allErrs)
+}
@@ -0,0 +1,1082 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+)
+
+func TestSecretCopierCustomValidator_Limits(t *testing.T) {
+	namespaces := []corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "source-namespace"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "target-namespace-1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "target-namespace-2"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "target-namespace-3"}},
+		{ObjectMeta: metav1.ObjectMeta{
+			Name:   "target-namespace-4",
+			Labels: map[string]string{secretsv1beta1.NamespaceDecommissioningLabel: "true"},
+		}},
+	}
+
+	builder := fake.NewClientBuilder()
+
+	for i := range namespaces {
+		builder = builder.WithObjects(&namespaces[i])
+	}
+
+	fakeClient := builder.Build()
+
+	rule := secretsv1beta1.SecretCopierRule{
+		SourceSecret: secretsv1beta1.SourceSecret{
+			Namespace: "source-namespace",
+			Name:      "source-secret",
+		},
+		TargetNamespaces: selectors.TargetNamespaces{
+			NameSelector: selectors.NameSelector{
+				MatchNames: []string{"*-namespace*"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name                   string
+		limits                 SecretCopierLimits
+		rules                  int
+		annotations            map[string]string
+		includeDecommissioning bool
+		wantErr                bool
+	}{
+		{
+			name:    "no limits",
+			limits:  SecretCopierLimits{},
+			rules:   3,
+			wantErr: false,
+		},
+		{
+			name:    "too many rules",
+			limits:  SecretCopierLimits{MaxRules: 2},
+			rules:   3,
+			wantErr: true,
+		},
+		{
+			name:    "within target namespaces per rule",
+			limits:  SecretCopierLimits{MaxTargetNamespacesPerRule: 3},
+			rules:   1,
+			wantErr: false,
+		},
+		{
+			name:    "too many target namespaces per rule",
+			limits:  SecretCopierLimits{MaxTargetNamespacesPerRule: 2},
+			rules:   1,
+			wantErr: true,
+		},
+		{
+			name:                   "decommissioning namespaces included",
+			limits:                 SecretCopierLimits{MaxTargetNamespacesPerRule: 3},
+			rules:                  1,
+			includeDecommissioning: true,
+			wantErr:                true,
+		},
+		{
+			name:    "too many targets per copier",
+			limits:  SecretCopierLimits{MaxTargetsPerCopier: 5},
+			rules:   2,
+			wantErr: true,
+		},
+		{
+			name:        "limits bypassed by annotation",
+			limits:      SecretCopierLimits{MaxRules: 1},
+			rules:       2,
+			annotations: map[string]string{BypassLimitsAnnotation: "true"},
+			wantErr:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &SecretCopierCustomValidator{
+				Client: fakeClient,
+				Limits: tt.limits,
+			}
+
+			secretCopier := &secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "secret-copier",
+					Annotations: tt.annotations,
+				},
+			}
+
+			for i := 0; i < tt.rules; i++ {
+				secretCopier.Spec.Rules = append(secretCopier.Spec.Rules, rule)
+				secretCopier.Spec.Rules[i].IncludeDecommissioningNamespaces = tt.includeDecommissioning
+			}
+
+			_, err := validator.ValidateCreate(context.Background(), secretCopier)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SecretCopierCustomValidator.ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecretCopierCustomValidator_Dependencies(t *testing.T) {
+	rule := func(name string, dependsOn ...string) secretsv1beta1.SecretCopierRule {
+		return secretsv1beta1.SecretCopierRule{
+			Name:      name,
+			DependsOn: dependsOn,
+			SourceSecret: secretsv1beta1.SourceSecret{
+				Namespace: "source-namespace",
+				Name:      "source-secret",
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		rules     []secretsv1beta1.SecretCopierRule
+		wantOrder []int
+		wantErr   bool
+	}{
+		{
+			name:      "no dependencies",
+			rules:     []secretsv1beta1.SecretCopierRule{rule("a"), rule(""), rule("b")},
+			wantOrder: []int{0, 1, 2},
+			wantErr:   false,
+		},
+		{
+			name:      "dependency declared later",
+			rules:     []secretsv1beta1.SecretCopierRule{rule("client-certs", "ca-bundle"), rule("ca-bundle")},
+			wantOrder: []int{1, 0},
+			wantErr:   false,
+		},
+		{
+			name:      "unknown dependency",
+			rules:     []secretsv1beta1.SecretCopierRule{rule("a", "missing"), rule("b")},
+			wantOrder: []int{1},
+			wantErr:   true,
+		},
+		{
+			name:      "duplicate name",
+			rules:     []secretsv1beta1.SecretCopierRule{rule("a"), rule("a")},
+			wantOrder: []int{0},
+			wantErr:   true,
+		},
+		{
+			name:      "cycle",
+			rules:     []secretsv1beta1.SecretCopierRule{rule("a", "b"), rule("b", "a"), rule("c")},
+			wantOrder: []int{2},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &SecretCopierCustomValidator{
+				Client: fake.NewClientBuilder().Build(),
+			}
+
+			secretCopier := &secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "secret-copier",
+					Annotations: map[string]string{BypassLimitsAnnotation: "true"},
+				},
+				Spec: secretsv1beta1.SecretCopierSpec{
+					Rules: tt.rules,
+				},
+			}
+
+			order, _ := secretCopier.Spec.RuleOrder()
+
+			if !reflect.DeepEqual(order, tt.wantOrder) {
+				t.Errorf("SecretCopierSpec.RuleOrder() = %v, want %v", order, tt.wantOrder)
+			}
+
+			_, err := validator.ValidateCreate(context.Background(), secretCopier)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SecretCopierCustomValidator.ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecretCopierCustomValidator_AdoptFrom(t *testing.T) {
+	tests := []struct {
+		name      string
+		adoptFrom []string
+		wantErr   bool
+	}{
+		{
+			name:      "no adoption",
+			adoptFrom: nil,
+			wantErr:   false,
+		},
+		{
+			name:      "other secret copiers",
+			adoptFrom: []string{"team-a-copier", "team-b-copier"},
+			wantErr:   false,
+		},
+		{
+			name:      "itself",
+			adoptFrom: []string{"team-a-copier", "secret-copier"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &SecretCopierCustomValidator{
+				Client: fake.NewClientBuilder().Build(),
+			}
+
+			secretCopier := &secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "secret-copier",
+					Annotations: map[string]string{BypassLimitsAnnotation: "true"},
+				},
+				Spec: secretsv1beta1.SecretCopierSpec{
+					Rules: []secretsv1beta1.SecretCopierRule{
+						{
+							SourceSecret: secretsv1beta1.SourceSecret{
+								Namespace: "source-namespace",
+								Name:      "source-secret",
+							},
+							AdoptFrom: tt.adoptFrom,
+						},
+					},
+				},
+			}
+
+			_, err := validator.ValidateCreate(context.Background(), secretCopier)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SecretCopierCustomValidator.ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecretCopierCustomValidator_KeyPatterns(t *testing.T) {
+	tests := []struct {
+		name        string
+		includeKeys []string
+		excludeKeys []string
+		wantErr     bool
+	}{
+		{
+			name:    "no patterns",
+			wantErr: false,
+		},
+		{
+			name:        "valid patterns",
+			includeKeys: []string{"ca.crt", "tls.*"},
+			excludeKeys: []string{"*.key"},
+			wantErr:     false,
+		},
+		{
+			name:        "invalid include pattern",
+			includeKeys: []string{"ca.[crt"},
+			wantErr:     true,
+		},
+		{
+			name:        "invalid exclude pattern",
+			excludeKeys: []string{"tls.\\"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &SecretCopierCustomValidator{
+				Client: fake.NewClientBuilder().Build(),
+			}
+
+			secretCopier := &secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "secret-copier",
+					Annotations: map[string]string{BypassLimitsAnnotation: "true"},
+				},
+				Spec: secretsv1beta1.SecretCopierSpec{
+					Rules: []secretsv1beta1.SecretCopierRule{
+						{
+							SourceSecret: secretsv1beta1.SourceSecret{
+								Namespace: "source-namespace",
+								Name:      "source-secret",
+							},
+							TargetSecret: secretsv1beta1.TargetSecret{
+								IncludeKeys: tt.includeKeys,
+								ExcludeKeys: tt.excludeKeys,
+							},
+						},
+					},
+				},
+			}
+
+			_, err := validator.ValidateCreate(context.Background(), secretCopier)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SecretCopierCustomValidator.ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecretCopierCustomValidator_BundledConfigMap(t *testing.T) {
+	tests := []struct {
+		name       string
+		hashSuffix *secretsv1beta1.TargetSecretHashSuffix
+		wantErr    bool
+	}{
+		{
+			name:    "target secret updated in place",
+			wantErr: false,
+		},
+		{
+			name:       "target secret named with hash suffix",
+			hashSuffix: &secretsv1beta1.TargetSecretHashSuffix{RetainedGenerations: 3},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &SecretCopierCustomValidator{
+				Client: fake.NewClientBuilder().Build(),
+			}
+
+			secretCopier := &secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "secret-copier",
+					Annotations: map[string]string{BypassLimitsAnnotation: "true"},
+				},
+				Spec: secretsv1beta1.SecretCopierSpec{
+					Rules: []secretsv1beta1.SecretCopierRule{
+						{
+							SourceSecret: secretsv1beta1.SourceSecret{
+								Namespace: "source-namespace",
+								Name:      "tls-secret",
+							},
+							TargetSecret: secretsv1beta1.TargetSecret{
+								HashSuffix: tt.hashSuffix,
+							},
+							BundledConfigMap: &secretsv1beta1.BundledConfigMap{
+								Name: "ca-bundle",
+							},
+						},
+					},
+				},
+			}
+
+			_, err := validator.ValidateCreate(context.Background(), secretCopier)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SecretCopierCustomValidator.ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecretCopierCustomValidator_ServiceAccount(t *testing.T) {
+	reviewer := interceptor.NewClient(fake.NewClientBuilder().Build(), interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			review := obj.(*authorizationv1.SubjectAccessReview)
+			review.Status.Allowed = review.Spec.User == "team-a-admin" &&
+				review.Spec.ResourceAttributes.Verb == "impersonate" &&
+				review.Spec.ResourceAttributes.Resource == "serviceaccounts" &&
+				review.Spec.ResourceAttributes.Namespace == "team-a"
+			return nil
+		},
+	})
+
+	teamA := &secretsv1beta1.SecretCopierServiceAccount{Namespace: "team-a", Name: "secret-copier"}
+	teamB := &secretsv1beta1.SecretCopierServiceAccount{Namespace: "team-b", Name: "secret-copier"}
+
+	tests := []struct {
+		name              string
+		noReviewer        bool
+		user              string
+		serviceAccount    *secretsv1beta1.SecretCopierServiceAccount
+		oldServiceAccount *secretsv1beta1.SecretCopierServiceAccount
+		update            bool
+		wantErr           bool
+	}{
+		{
+			name:           "no service account",
+			noReviewer:     true,
+			user:           "team-b-admin",
+			serviceAccount: nil,
+			wantErr:        false,
+		},
+		{
+			name:           "invalid service account name",
+			user:           "team-a-admin",
+			serviceAccount: &secretsv1beta1.SecretCopierServiceAccount{Namespace: "team-a", Name: "Secret_Copier"},
+			wantErr:        true,
+		},
+		{
+			name:           "permitted to impersonate",
+			user:           "team-a-admin",
+			serviceAccount: teamA,
+			wantErr:        false,
+		},
+		{
+			name:           "not permitted to impersonate",
+			user:           "team-a-admin",
+			serviceAccount: teamB,
+			wantErr:        true,
+		},
+		{
+			name:           "impersonation not enabled",
+			noReviewer:     true,
+			user:           "team-a-admin",
+			serviceAccount: teamA,
+			wantErr:        true,
+		},
+		{
+			name:              "update leaving service account unchanged",
+			user:              "team-b-admin",
+			serviceAccount:    teamA,
+			oldServiceAccount: teamA,
+			update:            true,
+			wantErr:           false,
+		},
+		{
+			name:              "update changing service account",
+			user:              "team-a-admin",
+			serviceAccount:    teamB,
+			oldServiceAccount: teamA,
+			update:            true,
+			wantErr:           true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &SecretCopierCustomValidator{
+				Client: fake.NewClientBuilder().Build(),
+			}
+
+			if !tt.noReviewer {
+				validator.AccessReviewer = reviewer
+			}
+
+			secretCopier := &secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{Name: "secret-copier"},
+				Spec: secretsv1beta1.SecretCopierSpec{
+					ServiceAccount: tt.serviceAccount,
+				},
+			}
+
+			ctx := admission.NewContextWithRequest(context.Background(), admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					UserInfo: authenticationv1.UserInfo{Username: tt.user},
+				},
+			})
+
+			var err error
+
+			if tt.update {
+				oldSecretCopier := secretCopier.DeepCopy()
+				oldSecretCopier.Spec.ServiceAccount = tt.oldServiceAccount
+
+				_, err = validator.ValidateUpdate(ctx, oldSecretCopier, secretCopier)
+			} else {
+				_, err = validator.ValidateCreate(ctx, secretCopier)
+			}
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SecretCopierCustomValidator.ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecretCopierCustomValidator_TargetAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantErr     bool
+	}{
+		{
+			name:    "no annotations",
+			wantErr: false,
+		},
+		{
+			name:        "valid annotations",
+			annotations: map[string]string{"reloader.stakater.com/match": "true"},
+			wantErr:     false,
+		},
+		{
+			name:        "invalid annotation key",
+			annotations: map[string]string{"not a key": "true"},
+			wantErr:     true,
+		},
+		{
+			name:        "reserved annotation key",
+			annotations: map[string]string{"secrets-manager.advok8s.io/secret-copier": "other"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &SecretCopierCustomValidator{
+				Client: fake.NewClientBuilder().Build(),
+			}
+
+			secretCopier := &secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "secret-copier",
+					Annotations: map[string]string{BypassLimitsAnnotation: "true"},
+				},
+				Spec: secretsv1beta1.SecretCopierSpec{
+					Rules: []secretsv1beta1.SecretCopierRule{
+						{
+							SourceSecret: secretsv1beta1.SourceSecret{
+								Namespace: "source-namespace",
+								Name:      "source-secret",
+							},
+							TargetSecret: secretsv1beta1.TargetSecret{
+								Annotations: tt.annotations,
+							},
+						},
+					},
+				},
+			}
+
+			_, err := validator.ValidateCreate(context.Background(), secretCopier)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SecretCopierCustomValidator.ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecretCopierCustomValidator_FirstCopyHook(t *testing.T) {
+	tests := []struct {
+		name    string
+		hook    *secretsv1beta1.FirstCopyHook
+		wantErr bool
+	}{
+		{
+			name:    "no hook",
+			wantErr: false,
+		},
+		{
+			name: "valid hook",
+			hook: &secretsv1beta1.FirstCopyHook{
+				NamespaceLabels:      map[string]string{"example.com/secrets-seeded": "true"},
+				NamespaceAnnotations: map[string]string{"example.com/seeded-by": "secrets-manager"},
+				WebhookURL:           "https://provisioner.example.com/seeded",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid label value",
+			hook: &secretsv1beta1.FirstCopyHook{
+				NamespaceLabels: map[string]string{"example.com/secrets-seeded": "not a value"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "reserved annotation key",
+			hook: &secretsv1beta1.FirstCopyHook{
+				NamespaceAnnotations: map[string]string{"secrets-manager.advok8s.io/secrets-ready": "true"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "relative webhook URL",
+			hook: &secretsv1beta1.FirstCopyHook{
+				WebhookURL: "/seeded",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported webhook URL scheme",
+			hook: &secretsv1beta1.FirstCopyHook{
+				WebhookURL: "ftp://provisioner.example.com/seeded",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &SecretCopierCustomValidator{
+				Client: fake.NewClientBuilder().Build(),
+			}
+
+			secretCopier := &secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "secret-copier",
+					Annotations: map[string]string{BypassLimitsAnnotation: "true"},
+				},
+				Spec: secretsv1beta1.SecretCopierSpec{
+					Rules: []secretsv1beta1.SecretCopierRule{
+						{
+							SourceSecret: secretsv1beta1.SourceSecret{
+								Namespace: "source-namespace",
+								Name:      "source-secret",
+							},
+							OnFirstCopy: tt.hook,
+						},
+					},
+				},
+			}
+
+			_, err := validator.ValidateCreate(context.Background(), secretCopier)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SecretCopierCustomValidator.ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecretCopierCustomValidator_TargetTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template *secretsv1beta1.TargetSecretTemplate
+		wantErr  bool
+	}{
+		{
+			name:    "no template",
+			wantErr: false,
+		},
+		{
+			name: "valid template",
+			template: &secretsv1beta1.TargetSecretTemplate{
+				Data: map[string]string{"dsn": "postgres://{{ .Data.user }}@{{ .Data.host }}/app"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid data key",
+			template: &secretsv1beta1.TargetSecretTemplate{
+				Data: map[string]string{"not a key": "{{ .Data.user }}"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "template which doesn't parse",
+			template: &secretsv1beta1.TargetSecretTemplate{
+				Data: map[string]string{"dsn": "{{ .Data.user "},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &SecretCopierCustomValidator{
+				Client: fake.NewClientBuilder().Build(),
+			}
+
+			secretCopier := &secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "secret-copier",
+					Annotations: map[string]string{BypassLimitsAnnotation: "true"},
+				},
+				Spec: secretsv1beta1.SecretCopierSpec{
+					Rules: []secretsv1beta1.SecretCopierRule{
+						{
+							SourceSecret: secretsv1beta1.SourceSecret{
+								Namespace: "source-namespace",
+								Name:      "source-secret",
+							},
+							TargetSecret: secretsv1beta1.TargetSecret{
+								Template: tt.template,
+							},
+						},
+					},
+				},
+			}
+
+			_, err := validator.ValidateCreate(context.Background(), secretCopier)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SecretCopierCustomValidator.ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecretCopierCustomValidator_RegistryMirrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		mirrors []secretsv1beta1.RegistryMirror
+		wantErr bool
+	}{
+		{
+			name:    "no mirrors",
+			wantErr: false,
+		},
+		{
+			name: "valid mirrors",
+			mirrors: []secretsv1beta1.RegistryMirror{
+				{Registry: "docker.io", Mirror: "mirror.example.com/dockerhub"},
+				{Registry: "ghcr.io", Mirror: "mirror.example.com:5000"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "mirror with scheme",
+			mirrors: []secretsv1beta1.RegistryMirror{
+				{Registry: "docker.io", Mirror: "https://mirror.example.com"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "registry mirrored twice",
+			mirrors: []secretsv1beta1.RegistryMirror{
+				{Registry: "docker.io", Mirror: "mirror-a.example.com"},
+				{Registry: "docker.io", Mirror: "mirror-b.example.com"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &SecretCopierCustomValidator{
+				Client: fake.NewClientBuilder().Build(),
+			}
+
+			secretCopier := &secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "secret-copier",
+					Annotations: map[string]string{BypassLimitsAnnotation: "true"},
+				},
+				Spec: secretsv1beta1.SecretCopierSpec{
+					Rules: []secretsv1beta1.SecretCopierRule{
+						{
+							SourceSecret: secretsv1beta1.SourceSecret{
+								Namespace: "source-namespace",
+								Name:      "source-secret",
+							},
+							TargetSecret: secretsv1beta1.TargetSecret{
+								RegistryMirrors: tt.mirrors,
+							},
+						},
+					},
+				},
+			}
+
+			_, err := validator.ValidateCreate(context.Background(), secretCopier)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SecretCopierCustomValidator.ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecretCopierCustomValidator_RequiredResource(t *testing.T) {
+	tests := []struct {
+		name       string
+		required   *secretsv1beta1.RequiredResource
+		hashSuffix *secretsv1beta1.TargetSecretHashSuffix
+		wantErr    bool
+	}{
+		{
+			name:    "no required resource",
+			wantErr: false,
+		},
+		{
+			name: "valid required resource",
+			required: &secretsv1beta1.RequiredResource{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Selector:   &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid api version",
+			required: &secretsv1beta1.RequiredResource{
+				APIVersion: "apps/v1/extra",
+				Kind:       "Deployment",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid selector",
+			required: &secretsv1beta1.RequiredResource{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "app", Operator: "Near"},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "hash suffixed target secret",
+			required: &secretsv1beta1.RequiredResource{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+			},
+			hashSuffix: &secretsv1beta1.TargetSecretHashSuffix{RetainedGenerations: 3},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &SecretCopierCustomValidator{
+				Client: fake.NewClientBuilder().Build(),
+			}
+
+			secretCopier := &secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "secret-copier",
+					Annotations: map[string]string{BypassLimitsAnnotation: "true"},
+				},
+				Spec: secretsv1beta1.SecretCopierSpec{
+					Rules: []secretsv1beta1.SecretCopierRule{
+						{
+							SourceSecret: secretsv1beta1.SourceSecret{
+								Namespace: "source-namespace",
+								Name:      "source-secret",
+							},
+							TargetSecret: secretsv1beta1.TargetSecret{
+								HashSuffix: tt.hashSuffix,
+							},
+							RequiredResource: tt.required,
+						},
+					},
+				},
+			}
+
+			_, err := validator.ValidateCreate(context.Background(), secretCopier)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SecretCopierCustomValidator.ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecretCopierCustomValidator_SourceSecretPattern(t *testing.T) {
+	tests := []struct {
+		name          string
+		sourceName    string
+		targetName    string
+		bundledConfig *secretsv1beta1.BundledConfigMap
+		wantErr       bool
+	}{
+		{
+			name:       "named source secret",
+			sourceName: "source-secret",
+			targetName: "target-secret",
+			wantErr:    false,
+		},
+		{
+			name:       "source secret pattern",
+			sourceName: "tls-*",
+			wantErr:    false,
+		},
+		{
+			name:       "malformed source secret pattern",
+			sourceName: "tls-[",
+			wantErr:    true,
+		},
+		{
+			name:       "source secret pattern with target secret name",
+			sourceName: "tls-*",
+			targetName: "target-secret",
+			wantErr:    true,
+		},
+		{
+			name:          "source secret pattern with bundled config map",
+			sourceName:    "tls-*",
+			bundledConfig: &secretsv1beta1.BundledConfigMap{Name: "ca-bundle"},
+			wantErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &SecretCopierCustomValidator{
+				Client: fake.NewClientBuilder().Build(),
+			}
+
+			secretCopier := &secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "secret-copier",
+					Annotations: map[string]string{BypassLimitsAnnotation: "true"},
+				},
+				Spec: secretsv1beta1.SecretCopierSpec{
+					Rules: []secretsv1beta1.SecretCopierRule{
+						{
+							SourceSecret: secretsv1beta1.SourceSecret{
+								Namespace: "source-namespace",
+								Name:      tt.sourceName,
+							},
+							TargetSecret: secretsv1beta1.TargetSecret{
+								Name: tt.targetName,
+							},
+							BundledConfigMap: tt.bundledConfig,
+						},
+					},
+				},
+			}
+
+			_, err := validator.ValidateCreate(context.Background(), secretCopier)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SecretCopierCustomValidator.ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecretCopierCustomValidator_TargetCluster(t *testing.T) {
+	targetCluster := &secretsv1beta1.TargetCluster{
+		KubeconfigSecret: secretsv1beta1.KubeconfigSecretReference{
+			Name:      "spoke-1",
+			Namespace: "fleet",
+		},
+	}
+
+	tests := []struct {
+		name    string
+		rule    secretsv1beta1.SecretCopierRule
+		wantErr bool
+	}{
+		{
+			name:    "local cluster",
+			rule:    secretsv1beta1.SecretCopierRule{},
+			wantErr: false,
+		},
+		{
+			name: "remote cluster",
+			rule: secretsv1beta1.SecretCopierRule{
+				TargetCluster: targetCluster,
+			},
+			wantErr: false,
+		},
+		{
+			name: "remote cluster with hash suffix",
+			rule: secretsv1beta1.SecretCopierRule{
+				TargetCluster: targetCluster,
+				TargetSecret: secretsv1beta1.TargetSecret{
+					HashSuffix: &secretsv1beta1.TargetSecretHashSuffix{},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "remote cluster with immutable target secret",
+			rule: secretsv1beta1.SecretCopierRule{
+				TargetCluster: targetCluster,
+				TargetSecret: secretsv1beta1.TargetSecret{
+					Immutable: true,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "remote cluster with first copy hook",
+			rule: secretsv1beta1.SecretCopierRule{
+				TargetCluster: targetCluster,
+				OnFirstCopy: &secretsv1beta1.FirstCopyHook{
+					NamespaceLabels: map[string]string{"tls": "ready"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "remote cluster adopting target secrets",
+			rule: secretsv1beta1.SecretCopierRule{
+				TargetCluster: targetCluster,
+				AdoptFrom:     []string{"previous-copier"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "remote cluster skipping virtual cluster namespaces",
+			rule: secretsv1beta1.SecretCopierRule{
+				TargetCluster: targetCluster,
+				VirtualClusterNamespaces: &secretsv1beta1.VirtualClusterNamespaces{
+					Policy: secretsv1beta1.VirtualClusterNamespaceSkip,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "remote cluster copying into virtual clusters",
+			rule: secretsv1beta1.SecretCopierRule{
+				TargetCluster: targetCluster,
+				VirtualClusterNamespaces: &secretsv1beta1.VirtualClusterNamespaces{
+					Policy:                    secretsv1beta1.VirtualClusterNamespaceCopyInto,
+					KubeconfigSecretNamespace: "vclusters",
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := &SecretCopierCustomValidator{
+				Client: fake.NewClientBuilder().Build(),
+			}
+
+			rule := tt.rule
+
+			rule.SourceSecret = secretsv1beta1.SourceSecret{
+				Namespace: "source-namespace",
+				Name:      "source-secret",
+			}
+
+			secretCopier := &secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "secret-copier",
+					Annotations: map[string]string{BypassLimitsAnnotation: "true"},
+				},
+				Spec: secretsv1beta1.SecretCopierSpec{
+					Rules: []secretsv1beta1.SecretCopierRule{rule},
+				},
+			}
+
+			_, err := validator.ValidateCreate(context.Background(), secretCopier)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SecretCopierCustomValidator.ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
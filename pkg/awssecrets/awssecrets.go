@@ -0,0 +1,327 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package awssecrets implements the small part of the AWS API needed to read
+// secrets from AWS Secrets Manager, authenticating with either an access key
+// or a web identity token for an IAM role, as used by IAM roles for service
+// accounts (IRSA). It avoids depending on the full AWS SDK for what is a
+// couple of requests.
+package awssecrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ValueKey is the key holding the value of a secret which isn't a JSON
+// object.
+const ValueKey = "value"
+
+// Credentials used to sign requests.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// Session token for temporary credentials, such as those returned by
+	// assuming a role.
+	SessionToken string
+}
+
+// Client makes requests against AWS Secrets Manager.
+type Client struct {
+	// AWS region of the secrets, such as us-east-1.
+	Region string
+
+	// URL of the Secrets Manager endpoint. If empty, the regional endpoint
+	// is used.
+	Endpoint string
+
+	// URL of the STS endpoint used to assume roles. If empty, the regional
+	// endpoint is used.
+	STSEndpoint string
+
+	// Credentials used to sign requests, as given directly or returned by
+	// assuming a role.
+	Credentials Credentials
+
+	// HTTP client used for requests. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+}
+
+// Error is returned when AWS responds with an error status.
+type Error struct {
+	// HTTP status code of the response.
+	StatusCode int
+
+	// Error code reported by AWS, such as ResourceNotFoundException.
+	Code string
+
+	// Message describing the error.
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("aws responded with status %d", e.StatusCode)
+	}
+
+	if e.Message == "" {
+		return fmt.Sprintf("aws responded with status %d: %s", e.StatusCode, e.Code)
+	}
+
+	return fmt.Sprintf("aws responded with status %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Secret is the value read from Secrets Manager.
+type Secret struct {
+	// Data of the secret. Where the value is a JSON object each field is a
+	// key, with values which are not strings given as JSON. Otherwise the
+	// value is given under ValueKey.
+	Data map[string][]byte
+
+	// Identifier of the version of the secret read.
+	VersionID string
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+
+	return http.DefaultClient
+}
+
+// Send a request, returning the body of the response if it succeeded. The
+// decode function is used to extract the error from a failed response.
+func (c *Client) send(request *http.Request, decode func([]byte) *Error) ([]byte, error) {
+	response, err := c.httpClient().Do(request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1024*1024))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		failure := decode(body)
+		failure.StatusCode = response.StatusCode
+
+		return nil, failure
+	}
+
+	return body, nil
+}
+
+// AssumeRoleWithWebIdentity assumes the IAM role by presenting a web identity
+// token, such as a service account token issued for the sts.amazonaws.com
+// audience. On success the temporary credentials returned by STS are used for
+// following requests and also returned.
+func (c *Client) AssumeRoleWithWebIdentity(ctx context.Context, roleARN string, sessionName string, token string) (Credentials, error) {
+	endpoint := c.STSEndpoint
+
+	if endpoint == "" {
+		endpoint = "https://sts." + c.Region + ".amazonaws.com"
+	}
+
+	form := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {roleARN},
+		"RoleSessionName":  {sessionName},
+		"WebIdentityToken": {token},
+		"DurationSeconds":  {"900"},
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(endpoint, "/")+"/", strings.NewReader(form.Encode()))
+
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	// The request is not signed, as the web identity token is what
+	// authenticates it.
+
+	body, err := c.send(request, func(body []byte) *Error {
+		var failure struct {
+			Error struct {
+				Code    string `xml:"Code"`
+				Message string `xml:"Message"`
+			} `xml:"Error"`
+		}
+
+		_ = xml.Unmarshal(body, &failure)
+
+		return &Error{Code: failure.Error.Code, Message: failure.Error.Message}
+	})
+
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	var response struct {
+		Credentials struct {
+			AccessKeyID     string `xml:"AccessKeyId"`
+			SecretAccessKey string `xml:"SecretAccessKey"`
+			SessionToken    string `xml:"SessionToken"`
+		} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	}
+
+	if err := xml.Unmarshal(body, &response); err != nil {
+		return Credentials{}, fmt.Errorf("unable to decode response from STS: %w", err)
+	}
+
+	if response.Credentials.AccessKeyID == "" {
+		return Credentials{}, fmt.Errorf("assuming role %s returned no credentials", roleARN)
+	}
+
+	c.Credentials = Credentials{
+		AccessKeyID:     response.Credentials.AccessKeyID,
+		SecretAccessKey: response.Credentials.SecretAccessKey,
+		SessionToken:    response.Credentials.SessionToken,
+	}
+
+	return c.Credentials, nil
+}
+
+// GetSecretValue reads the secret with the given name or ARN. The version
+// stage selects the version read, with the current version read if empty.
+func (c *Client) GetSecretValue(ctx context.Context, secretID string, versionStage string) (*Secret, error) {
+	endpoint := c.Endpoint
+
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + c.Region + ".amazonaws.com"
+	}
+
+	input := map[string]string{"SecretId": secretID}
+
+	if versionStage != "" {
+		input["VersionStage"] = versionStage
+	}
+
+	payload, err := json.Marshal(input)
+
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(endpoint, "/")+"/", bytes.NewReader(payload))
+
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	request.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+
+	signRequest(request, payload, c.Credentials, c.Region, "secretsmanager", time.Now())
+
+	body, err := c.send(request, func(body []byte) *Error {
+		var failure struct {
+			Type         string `json:"__type"`
+			Message      string `json:"message"`
+			MessageUpper string `json:"Message"`
+		}
+
+		_ = json.Unmarshal(body, &failure)
+
+		// The type may be qualified by a namespace, such as
+		// com.amazonaws.secretsmanager#ResourceNotFoundException.
+
+		code := failure.Type[strings.LastIndex(failure.Type, "#")+1:]
+
+		message := failure.Message
+
+		if message == "" {
+			message = failure.MessageUpper
+		}
+
+		return &Error{Code: code, Message: message}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The binary value of a secret is base64 encoded, which is decoded when
+	// unmarshalled into a byte slice.
+
+	var response struct {
+		VersionID    string  `json:"VersionId"`
+		SecretString *string `json:"SecretString"`
+		SecretBinary []byte  `json:"SecretBinary"`
+	}
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("unable to decode response from Secrets Manager: %w", err)
+	}
+
+	secret := &Secret{VersionID: response.VersionID}
+
+	if response.SecretString == nil {
+		secret.Data = map[string][]byte{ValueKey: response.SecretBinary}
+
+		return secret, nil
+	}
+
+	// A secret string holding a JSON object, as created by the AWS console
+	// for key/value pairs, is expanded into a key per field.
+
+	var fields map[string]any
+
+	decoder := json.NewDecoder(strings.NewReader(*response.SecretString))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&fields); err != nil || fields == nil || decoder.More() {
+		secret.Data = map[string][]byte{ValueKey: []byte(*response.SecretString)}
+
+		return secret, nil
+	}
+
+	secret.Data = make(map[string][]byte, len(fields))
+
+	for key, value := range fields {
+		if text, ok := value.(string); ok {
+			secret.Data[key] = []byte(text)
+			continue
+		}
+
+		encoded, err := json.Marshal(value)
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to encode value of key %q: %w", key, err)
+		}
+
+		secret.Data[key] = encoded
+	}
+
+	return secret, nil
+}
@@ -0,0 +1,286 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awssecrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignRequest(t *testing.T) {
+	// Requests and signatures are from the AWS Signature Version 4 test
+	// suite.
+
+	credentials := Credentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		method        string
+		url           string
+		authorization string
+	}{
+		{
+			name:          "get vanilla",
+			method:        http.MethodGet,
+			url:           "https://example.amazonaws.com/",
+			authorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:          "post vanilla",
+			method:        http.MethodPost,
+			url:           "https://example.amazonaws.com/",
+			authorization: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := http.NewRequest(tt.method, tt.url, nil)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			signRequest(request, nil, credentials, "us-east-1", "service", now)
+
+			if got := request.Header.Get("Authorization"); got != tt.authorization {
+				t.Errorf("Authorization = %q, want %q", got, tt.authorization)
+			}
+
+			if got := request.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Errorf("X-Amz-Date = %q, want %q", got, "20150830T123600Z")
+			}
+		})
+	}
+}
+
+// Start a fake AWS server serving both STS and Secrets Manager. Web identity
+// tokens for the app role are accepted, and Secrets Manager requests must be
+// signed with either the static access key or the credentials issued by
+// assuming the role.
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	secrets := map[string]string{
+		"database": `{"username":"admin","password":"secret","port":5432}`,
+		"api-key":  `"not an object"`,
+		"token":    `plain-text`,
+		"binary":   `{"VersionId":"v1","SecretBinary":"AAEC"}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") == "" {
+			_ = r.ParseForm()
+
+			if r.Form.Get("Action") != "AssumeRoleWithWebIdentity" || r.Form.Get("RoleArn") != "arn:aws:iam::123456789012:role/app" || r.Form.Get("WebIdentityToken") != "service-account-token" {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`<ErrorResponse><Error><Type>Sender</Type><Code>AccessDenied</Code><Message>Not authorized</Message></Error></ErrorResponse>`))
+				return
+			}
+
+			_, _ = w.Write([]byte(`<AssumeRoleWithWebIdentityResponse><AssumeRoleWithWebIdentityResult><Credentials>` +
+				`<AccessKeyId>ASIATEMPORARY</AccessKeyId><SecretAccessKey>temporary</SecretAccessKey><SessionToken>session</SessionToken>` +
+				`</Credentials></AssumeRoleWithWebIdentityResult></AssumeRoleWithWebIdentityResponse>`))
+			return
+		}
+
+		authorization := r.Header.Get("Authorization")
+
+		staticKey := strings.Contains(authorization, "Credential=AKIDEXAMPLE/")
+		assumedRole := strings.Contains(authorization, "Credential=ASIATEMPORARY/") && r.Header.Get("X-Amz-Security-Token") == "session"
+
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" || !strings.Contains(authorization, "/us-east-1/secretsmanager/aws4_request") || (!staticKey && !assumedRole) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"UnrecognizedClientException","message":"The security token included in the request is invalid."}`))
+			return
+		}
+
+		var input map[string]string
+
+		_ = json.NewDecoder(r.Body).Decode(&input)
+
+		value, ok := secrets[input["SecretId"]]
+
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"com.amazonaws.secretsmanager#ResourceNotFoundException","Message":"Secrets Manager can't find the specified secret."}`))
+			return
+		}
+
+		if input["SecretId"] == "binary" {
+			_, _ = w.Write([]byte(value))
+			return
+		}
+
+		encoded, _ := json.Marshal(map[string]string{"VersionId": "v1", "SecretString": value})
+
+		_, _ = w.Write(encoded)
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestGetSecretValue(t *testing.T) {
+	server := newServer(t)
+
+	tests := []struct {
+		name     string
+		secretID string
+		want     map[string]string
+		wantCode string
+	}{
+		{
+			name:     "json object",
+			secretID: "database",
+			want:     map[string]string{"username": "admin", "password": "secret", "port": "5432"},
+		},
+		{
+			name:     "json string",
+			secretID: "api-key",
+			want:     map[string]string{ValueKey: `"not an object"`},
+		},
+		{
+			name:     "plain text",
+			secretID: "token",
+			want:     map[string]string{ValueKey: "plain-text"},
+		},
+		{
+			name:     "binary",
+			secretID: "binary",
+			want:     map[string]string{ValueKey: "\x00\x01\x02"},
+		},
+		{
+			name:     "missing secret",
+			secretID: "missing",
+			wantCode: "ResourceNotFoundException",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{
+				Region:      "us-east-1",
+				Endpoint:    server.URL,
+				Credentials: Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "example"},
+			}
+
+			secret, err := client.GetSecretValue(context.Background(), tt.secretID, "")
+
+			if tt.wantCode != "" {
+				var awsErr *Error
+
+				if !errors.As(err, &awsErr) || awsErr.Code != tt.wantCode {
+					t.Fatalf("GetSecretValue() error = %v, want code %s", err, tt.wantCode)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("GetSecretValue() error = %v", err)
+			}
+
+			if secret.VersionID != "v1" {
+				t.Errorf("VersionID = %q, want %q", secret.VersionID, "v1")
+			}
+
+			if len(secret.Data) != len(tt.want) {
+				t.Fatalf("Data = %q, want %q", secret.Data, tt.want)
+			}
+
+			for key, value := range tt.want {
+				if string(secret.Data[key]) != value {
+					t.Errorf("Data[%q] = %q, want %q", key, secret.Data[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestAssumeRoleWithWebIdentity(t *testing.T) {
+	server := newServer(t)
+
+	tests := []struct {
+		name     string
+		roleARN  string
+		token    string
+		wantCode string
+	}{
+		{
+			name:    "accepted token",
+			roleARN: "arn:aws:iam::123456789012:role/app",
+			token:   "service-account-token",
+		},
+		{
+			name:     "rejected token",
+			roleARN:  "arn:aws:iam::123456789012:role/app",
+			token:    "other-token",
+			wantCode: "AccessDenied",
+		},
+		{
+			name:     "other role",
+			roleARN:  "arn:aws:iam::123456789012:role/other",
+			token:    "service-account-token",
+			wantCode: "AccessDenied",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{Region: "us-east-1", Endpoint: server.URL, STSEndpoint: server.URL}
+
+			credentials, err := client.AssumeRoleWithWebIdentity(context.Background(), tt.roleARN, "session", tt.token)
+
+			if tt.wantCode != "" {
+				var awsErr *Error
+
+				if !errors.As(err, &awsErr) || awsErr.Code != tt.wantCode || awsErr.StatusCode != http.StatusForbidden {
+					t.Fatalf("AssumeRoleWithWebIdentity() error = %v, want code %s", err, tt.wantCode)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("AssumeRoleWithWebIdentity() error = %v", err)
+			}
+
+			if credentials.AccessKeyID != "ASIATEMPORARY" || credentials.SessionToken != "session" {
+				t.Errorf("AssumeRoleWithWebIdentity() = %+v", credentials)
+			}
+
+			// The assumed credentials are used to sign following requests.
+
+			if _, err := client.GetSecretValue(context.Background(), "database", "AWSCURRENT"); err != nil {
+				t.Errorf("GetSecretValue() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awssecrets
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Format of the timestamp used when signing requests.
+const amzDateFormat = "20060102T150405Z"
+
+// Sign a request using AWS Signature Version 4. All headers already set on
+// the request are signed along with the host. The body must be the exact
+// bytes sent with the request.
+func signRequest(request *http.Request, body []byte, credentials Credentials, region string, service string, now time.Time) {
+	amzDate := now.UTC().Format(amzDateFormat)
+	date := amzDate[:8]
+
+	request.Header.Set("X-Amz-Date", amzDate)
+
+	if credentials.SessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	// Build the canonical headers, which are the lower cased header names
+	// in sorted order, each with its trimmed values.
+
+	headers := map[string]string{"host": request.URL.Host}
+
+	for name, values := range request.Header {
+		trimmed := make([]string, len(values))
+
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+
+		headers[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+
+	for name := range headers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+
+	signedHeaders := strings.Join(names, ";")
+
+	payloadHash := sha256.Sum256(body)
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		canonicalPath(request.URL),
+		canonicalQuery(request.URL),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	// Sign the hash of the canonical request using a key derived from the
+	// secret access key for the date, region and service.
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+credentials.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// Canonical form of the path of a URL, with each segment URI encoded.
+func canonicalPath(u *url.URL) string {
+	path := u.EscapedPath()
+
+	if path == "" {
+		return "/"
+	}
+
+	segments := strings.Split(path, "/")
+
+	for i, segment := range segments {
+		if unescaped, err := url.PathUnescape(segment); err == nil {
+			segment = unescaped
+		}
+
+		segments[i] = uriEncode(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// Canonical form of the query string of a URL, with parameters sorted by
+// name and then value.
+func canonicalQuery(u *url.URL) string {
+	query := u.Query()
+
+	parameters := make([]string, 0, len(query))
+
+	for name, values := range query {
+		for _, value := range values {
+			parameters = append(parameters, uriEncode(name)+"="+uriEncode(value))
+		}
+	}
+
+	sort.Strings(parameters)
+
+	return strings.Join(parameters, "&")
+}
+
+// URI encode a string as required for signing, escaping every byte other
+// than the unreserved characters.
+func uriEncode(value string) string {
+	var encoded strings.Builder
+
+	for _, b := range []byte(value) {
+		if 'A' <= b && b <= 'Z' || 'a' <= b && b <= 'z' || '0' <= b && b <= '9' || b == '-' || b == '_' || b == '.' || b == '~' {
+			encoded.WriteByte(b)
+		} else {
+			encoded.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{b})))
+		}
+	}
+
+	return encoded.String()
+}
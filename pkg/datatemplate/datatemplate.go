@@ -0,0 +1,112 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package datatemplate renders Go templates over the data of a secret to
+// produce new data keys, such as a connection string built from separate host,
+// user and password keys.
+package datatemplate
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Values available to a template when it is rendered.
+type Values struct {
+	// Data of the secret, with values as strings.
+	Data map[string]string
+
+	// Name of the secret.
+	Name string
+
+	// Namespace of the secret.
+	Namespace string
+}
+
+// Functions available to templates in addition to the builtin functions of
+// text/template.
+var functions = template.FuncMap{
+	"b64enc": func(value string) string {
+		return base64.StdEncoding.EncodeToString([]byte(value))
+	},
+	"b64dec": func(value string) (string, error) {
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		return string(decoded), err
+	},
+	"pathEscape":  url.PathEscape,
+	"queryEscape": url.QueryEscape,
+	"lower":       strings.ToLower,
+	"upper":       strings.ToUpper,
+	"trim":        strings.TrimSpace,
+	"default": func(fallback string, value string) string {
+		if value == "" {
+			return fallback
+		}
+
+		return value
+	},
+}
+
+// Parse a template. Referencing a data key which doesn't exist is an error
+// when the template is rendered, so a missing key can't silently produce an
+// incomplete value.
+func parse(name string, text string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Funcs(functions).Parse(text)
+}
+
+// Validate checks that a template can be parsed.
+func Validate(text string) error {
+	_, err := parse("template", text)
+
+	return err
+}
+
+// Render each of the templates, keyed by the name of the data key they
+// produce, with the given values. Errors identify the key whose template
+// failed but never include the values of the data.
+func Render(templates map[string]string, values Values) (map[string][]byte, error) {
+	keys := make([]string, 0, len(templates))
+
+	for key := range templates {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	data := make(map[string][]byte, len(templates))
+
+	for _, key := range keys {
+		tmpl, err := parse(key, templates[key])
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse template for key %s: %w", key, err)
+		}
+
+		var output strings.Builder
+
+		if err := tmpl.Execute(&output, values); err != nil {
+			return nil, fmt.Errorf("unable to render template for key %s: %w", key, err)
+		}
+
+		data[key] = []byte(output.String())
+	}
+
+	return data, nil
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func init() {
+	Register("aesgcm", newAESGCMProvider)
+}
+
+// AESGCMProvider encrypts values using AES-GCM with a local key. It is
+// intended for environments without an external key management service, and
+// as a reference for implementing other providers.
+type AESGCMProvider struct {
+	name string
+	aead cipher.AEAD
+}
+
+// NewAESGCMProvider creates a provider using a 16, 24 or 32 byte key, selecting
+// AES-128, AES-192 or AES-256 respectively.
+func NewAESGCMProvider(name string, key []byte) (*AESGCMProvider, error) {
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &AESGCMProvider{name: name, aead: aead}, nil
+}
+
+// Create the provider from options. The key is read from the file given by
+// the "keyFile" option, which must hold the base64 encoded key. The "name"
+// option overrides the name recorded in sealed values, which allows a
+// replacement key to be introduced alongside the existing one.
+func newAESGCMProvider(options map[string]string) (Provider, error) {
+	keyFile := options["keyFile"]
+
+	if keyFile == "" {
+		return nil, fmt.Errorf("aesgcm encryption provider requires the keyFile option")
+	}
+
+	data, err := os.ReadFile(keyFile)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to read encryption key: %w", err)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+
+	if err != nil {
+		return nil, fmt.Errorf("encryption key in %s is not base64 encoded: %w", keyFile, err)
+	}
+
+	name := options["name"]
+
+	if name == "" {
+		name = "aesgcm"
+	}
+
+	return NewAESGCMProvider(name, key)
+}
+
+// Name of the provider.
+func (p *AESGCMProvider) Name() string {
+	return p.name
+}
+
+// Encrypt the plaintext. A random nonce is generated for each value and is
+// prepended to the ciphertext.
+func (p *AESGCMProvider) Encrypt(_ context.Context, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, p.aead.NonceSize())
+
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return p.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt ciphertext produced by Encrypt.
+func (p *AESGCMProvider) Decrypt(_ context.Context, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < p.aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+
+	nonce, ciphertext := ciphertext[:p.aead.NonceSize()], ciphertext[p.aead.NonceSize():]
+
+	return p.aead.Open(nil, nonce, ciphertext, nil)
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+func init() {
+	Register("exec", newExecProvider)
+}
+
+// ExecProvider delegates encryption to an external command, in the same way
+// as client-go credential plugins. This allows age, a cloud KMS or a PKCS#11
+// HSM to be used through its existing command line tooling, without the
+// provider needing to be compiled into the secrets manager. The command is
+// run with "encrypt" or "decrypt" as its final argument, is passed the value
+// on stdin, and must write the result to stdout.
+type ExecProvider struct {
+	name    string
+	command string
+	args    []string
+}
+
+// NewExecProvider creates a provider which runs the command with the given
+// arguments.
+func NewExecProvider(name string, command string, args ...string) *ExecProvider {
+	return &ExecProvider{name: name, command: command, args: args}
+}
+
+// Create the provider from options. The "command" option gives the path of
+// the command and "args" optional space separated arguments to pass before
+// the operation. The "name" option overrides the name recorded in sealed
+// values.
+func newExecProvider(options map[string]string) (Provider, error) {
+	command := options["command"]
+
+	if command == "" {
+		return nil, fmt.Errorf("exec encryption provider requires the command option")
+	}
+
+	name := options["name"]
+
+	if name == "" {
+		name = "exec"
+	}
+
+	return NewExecProvider(name, command, strings.Fields(options["args"])...), nil
+}
+
+// Name of the provider.
+func (p *ExecProvider) Name() string {
+	return p.name
+}
+
+// Encrypt the plaintext by running the command.
+func (p *ExecProvider) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	return p.run(ctx, "encrypt", plaintext)
+}
+
+// Decrypt the ciphertext by running the command.
+func (p *ExecProvider) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	return p.run(ctx, "decrypt", ciphertext)
+}
+
+// Run the command for the operation. Any output on stderr is included in the
+// error so failures of the command can be diagnosed, but never the value.
+func (p *ExecProvider) run(ctx context.Context, operation string, input []byte) ([]byte, error) {
+	args := append(append([]string{}, p.args...), operation)
+
+	cmd := exec.CommandContext(ctx, p.command, args...)
+
+	var stdout, stderr bytes.Buffer
+
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%s %s: %w: %s", p.command, operation, err, message)
+		}
+
+		return nil, fmt.Errorf("%s %s: %w", p.command, operation, err)
+	}
+
+	return stdout.Bytes(), nil
+}
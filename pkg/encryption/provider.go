@@ -0,0 +1,163 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package encryption defines the interface through which secret values are
+// encrypted and decrypted when they leave the cluster, for example when
+// secrets are backed up, exported or delivered in encrypted form. Providers
+// are registered by name, so organizations can plug in their own crypto, such
+// as age, a cloud KMS or a PKCS#11 HSM, without changing the secrets manager.
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Provider encrypts and decrypts secret values. Implementations must be safe
+// for concurrent use.
+type Provider interface {
+	// Name of the provider, as recorded in sealed values so the provider
+	// which can decrypt a value can be found again.
+	Name() string
+
+	// Encrypt the plaintext, returning the ciphertext.
+	Encrypt(ctx context.Context, plaintext []byte) ([]byte, error)
+
+	// Decrypt ciphertext previously returned by Encrypt.
+	Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+// Factory creates a provider from options given as key/value pairs. The
+// options understood depend on the provider.
+type Factory func(options map[string]string) (Provider, error)
+
+var (
+	factoriesMutex sync.RWMutex
+	factories      = map[string]Factory{}
+)
+
+// Register a factory for a provider type. Registering the same type twice
+// panics, as this indicates two providers are conflicting over a name.
+func Register(providerType string, factory Factory) {
+	factoriesMutex.Lock()
+	defer factoriesMutex.Unlock()
+
+	if _, exists := factories[providerType]; exists {
+		panic(fmt.Sprintf("encryption provider %q already registered", providerType))
+	}
+
+	factories[providerType] = factory
+}
+
+// Types returns the names of the registered provider types in sorted order.
+func Types() []string {
+	factoriesMutex.RLock()
+	defer factoriesMutex.RUnlock()
+
+	types := make([]string, 0, len(factories))
+
+	for providerType := range factories {
+		types = append(types, providerType)
+	}
+
+	sort.Strings(types)
+
+	return types
+}
+
+// New creates a provider of the registered type using the options.
+func New(providerType string, options map[string]string) (Provider, error) {
+	factoriesMutex.RLock()
+	factory, exists := factories[providerType]
+	factoriesMutex.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("unknown encryption provider %q, must be one of %s", providerType, strings.Join(Types(), ", "))
+	}
+
+	return factory(options)
+}
+
+// Prefix of a sealed value. The version allows the format to be changed later
+// while still being able to open values sealed by older releases.
+const sealedValuePrefix = "enc:v1:"
+
+// Seal encrypts the plaintext using the provider and wraps the ciphertext in
+// a text envelope recording the name of the provider. The envelope is safe to
+// include in JSON, YAML or CSV output.
+func Seal(ctx context.Context, provider Provider, plaintext []byte) ([]byte, error) {
+	ciphertext, err := provider.Encrypt(ctx, plaintext)
+
+	if err != nil {
+		return nil, fmt.Errorf("encryption provider %q failed to encrypt value: %w", provider.Name(), err)
+	}
+
+	var sealed bytes.Buffer
+
+	sealed.WriteString(sealedValuePrefix)
+	sealed.WriteString(provider.Name())
+	sealed.WriteString(":")
+	sealed.WriteString(base64.StdEncoding.EncodeToString(ciphertext))
+
+	return sealed.Bytes(), nil
+}
+
+// IsSealed tests whether a value was produced by Seal.
+func IsSealed(value []byte) bool {
+	return bytes.HasPrefix(value, []byte(sealedValuePrefix))
+}
+
+// Open decrypts a value produced by Seal. The provider named in the envelope
+// must be one of those given, which allows values sealed by a previous
+// provider to still be opened while moving to a new one.
+func Open(ctx context.Context, sealed []byte, providers ...Provider) ([]byte, error) {
+	if !IsSealed(sealed) {
+		return nil, fmt.Errorf("value is not sealed")
+	}
+
+	name, encoded, found := strings.Cut(string(sealed[len(sealedValuePrefix):]), ":")
+
+	if !found {
+		return nil, fmt.Errorf("sealed value is malformed")
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+
+	if err != nil {
+		return nil, fmt.Errorf("sealed value is malformed: %w", err)
+	}
+
+	for _, provider := range providers {
+		if provider.Name() != name {
+			continue
+		}
+
+		plaintext, err := provider.Decrypt(ctx, ciphertext)
+
+		if err != nil {
+			return nil, fmt.Errorf("encryption provider %q failed to decrypt value: %w", name, err)
+		}
+
+		return plaintext, nil
+	}
+
+	return nil, fmt.Errorf("no encryption provider %q available to open sealed value", name)
+}
@@ -0,0 +1,242 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSealAndOpen(t *testing.T) {
+	current, err := NewAESGCMProvider("current", bytes.Repeat([]byte{1}, 32))
+
+	if err != nil {
+		t.Fatalf("NewAESGCMProvider() error = %v", err)
+	}
+
+	previous, err := NewAESGCMProvider("previous", bytes.Repeat([]byte{2}, 16))
+
+	if err != nil {
+		t.Fatalf("NewAESGCMProvider() error = %v", err)
+	}
+
+	sealedByPrevious, err := Seal(context.Background(), previous, []byte("old-value"))
+
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		sealed    func() []byte
+		providers []Provider
+		want      string
+		wantErr   bool
+	}{
+		{
+			name: "round trip",
+			sealed: func() []byte {
+				sealed, _ := Seal(context.Background(), current, []byte("password"))
+				return sealed
+			},
+			providers: []Provider{current},
+			want:      "password",
+		},
+		{
+			name:      "opened by previous provider",
+			sealed:    func() []byte { return sealedByPrevious },
+			providers: []Provider{current, previous},
+			want:      "old-value",
+		},
+		{
+			name:      "provider not available",
+			sealed:    func() []byte { return sealedByPrevious },
+			providers: []Provider{current},
+			wantErr:   true,
+		},
+		{
+			name: "tampered ciphertext",
+			sealed: func() []byte {
+				sealed := append([]byte{}, sealedByPrevious...)
+				sealed[len(sealed)-3] ^= 1
+				return sealed
+			},
+			providers: []Provider{previous},
+			wantErr:   true,
+		},
+		{
+			name:      "value not sealed",
+			sealed:    func() []byte { return []byte("password") },
+			providers: []Provider{current},
+			wantErr:   true,
+		},
+		{
+			name:      "malformed envelope",
+			sealed:    func() []byte { return []byte("enc:v1:current") },
+			providers: []Provider{current},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Open(context.Background(), tt.sealed(), tt.providers...)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Open() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("Open() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeal_Envelope(t *testing.T) {
+	provider, err := NewAESGCMProvider("aesgcm", bytes.Repeat([]byte{1}, 32))
+
+	if err != nil {
+		t.Fatalf("NewAESGCMProvider() error = %v", err)
+	}
+
+	first, _ := Seal(context.Background(), provider, []byte("password"))
+	second, _ := Seal(context.Background(), provider, []byte("password"))
+
+	if !IsSealed(first) || !strings.HasPrefix(string(first), "enc:v1:aesgcm:") {
+		t.Errorf("Seal() = %q, want enc:v1:aesgcm: prefix", first)
+	}
+
+	if bytes.Equal(first, second) {
+		t.Errorf("Seal() returned the same ciphertext twice")
+	}
+
+	if bytes.Contains(first, []byte("password")) {
+		t.Errorf("Seal() leaked the plaintext")
+	}
+}
+
+func TestNew(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key")
+
+	if err := os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		providerType string
+		options      map[string]string
+		wantName     string
+		wantErr      bool
+	}{
+		{
+			name:         "aesgcm with key file",
+			providerType: "aesgcm",
+			options:      map[string]string{"keyFile": keyFile},
+			wantName:     "aesgcm",
+		},
+		{
+			name:         "aesgcm with name",
+			providerType: "aesgcm",
+			options:      map[string]string{"keyFile": keyFile, "name": "aesgcm-2024"},
+			wantName:     "aesgcm-2024",
+		},
+		{
+			name:         "aesgcm without key file",
+			providerType: "aesgcm",
+			options:      map[string]string{},
+			wantErr:      true,
+		},
+		{
+			name:         "exec with command",
+			providerType: "exec",
+			options:      map[string]string{"command": "/usr/local/bin/kms-plugin", "args": "--key-id alias/secrets"},
+			wantName:     "exec",
+		},
+		{
+			name:         "exec without command",
+			providerType: "exec",
+			options:      map[string]string{},
+			wantErr:      true,
+		},
+		{
+			name:         "unknown provider",
+			providerType: "rot13",
+			options:      map[string]string{},
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := New(tt.providerType, tt.options)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && provider.Name() != tt.wantName {
+				t.Errorf("Name() = %q, want %q", provider.Name(), tt.wantName)
+			}
+		})
+	}
+}
+
+func TestExecProvider(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("requires /bin/sh")
+	}
+
+	// The command applies rot13 to its input using tr, which stands in for an
+	// external tool such as age.
+
+	script := filepath.Join(t.TempDir(), "plugin.sh")
+
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ncase \"$1\" in\nencrypt) tr a-z n-za-m ;;\ndecrypt) tr n-za-m a-z ;;\n*) echo \"bad operation $1\" >&2; exit 1 ;;\nesac\n"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := NewExecProvider("rot13", script)
+
+	sealed, err := Seal(context.Background(), provider, []byte("password"))
+
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+
+	plaintext, err := Open(context.Background(), sealed, provider)
+
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	if string(plaintext) != "password" {
+		t.Errorf("Open() = %q, want %q", plaintext, "password")
+	}
+
+	failing := NewExecProvider("failing", script, "unexpected")
+
+	if _, err := failing.Encrypt(context.Background(), []byte("password")); err == nil || !strings.Contains(err.Error(), "bad operation unexpected") {
+		t.Errorf("Encrypt() error = %v, want stderr of command", err)
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func init() {
+	Register("exec", newExecMutator)
+}
+
+// ExecMutator delegates mutation to an external command, so that mutators can
+// be written in any language and deployed alongside the manager without being
+// compiled into it. The command is passed the request as JSON on stdin, and
+// must write the mutated target secret as JSON to stdout.
+type ExecMutator struct {
+	command string
+	args    []string
+}
+
+// NewExecMutator creates a mutator which runs the command with the given
+// arguments.
+func NewExecMutator(command string, args ...string) *ExecMutator {
+	return &ExecMutator{command: command, args: args}
+}
+
+// Create the mutator from options. The "command" option gives the path of the
+// command and "args" optional space separated arguments to pass to it.
+func newExecMutator(options map[string]string) (Mutator, error) {
+	command := options["command"]
+
+	if command == "" {
+		return nil, fmt.Errorf("exec mutator requires the command option")
+	}
+
+	return NewExecMutator(command, strings.Fields(options["args"])...), nil
+}
+
+// Name of the mutator.
+func (m *ExecMutator) Name() string {
+	return "exec"
+}
+
+// Mutate the target secret by running the command. Any output on stderr is
+// included in the error so failures of the command can be diagnosed.
+func (m *ExecMutator) Mutate(ctx context.Context, request *Request) error {
+	input, err := json.Marshal(request)
+
+	if err != nil {
+		return fmt.Errorf("unable to encode mutation request: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, m.command, m.args...)
+
+	var stdout, stderr bytes.Buffer
+
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return fmt.Errorf("%s: %w: %s", m.command, err, message)
+		}
+
+		return fmt.Errorf("%s: %w", m.command, err)
+	}
+
+	var target corev1.Secret
+
+	if err := json.Unmarshal(stdout.Bytes(), &target); err != nil {
+		return fmt.Errorf("%s: unable to decode mutated target secret: %w", m.command, err)
+	}
+
+	*request.Target = target
+
+	return nil
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package mutation defines the extension point through which target secrets
+// can be adjusted just before they are written, so site specific conventions,
+// such as required labels or annotations, or data keys expected by in-house
+// tooling, can be enforced without changing the secrets manager. Mutators are
+// registered by type, in the same way as encryption providers.
+package mutation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Request describes a target secret about to be written.
+type Request struct {
+	// Name of the SecretCopier writing the target secret.
+	SecretCopier string `json:"secretCopier"`
+
+	// Name of the rule of the SecretCopier, or the namespace and name of
+	// its source secret if the rule has no name.
+	Rule string `json:"rule"`
+
+	// The source secret, after any keys not copied by the rule have been
+	// removed. Must not be modified.
+	Source *corev1.Secret `json:"source"`
+
+	// The target secret computed from the source secret and the rule, which
+	// the mutator may modify in place.
+	Target *corev1.Secret `json:"target"`
+}
+
+// Mutator adjusts target secrets before they are written. The labels,
+// annotations and data of the target secret may be changed. Any change to its
+// name, namespace, type, owner references or the annotations the manager uses
+// to track target secrets is discarded. Mutators are invoked each time the
+// desired state of a target secret is calculated, not only when it is
+// written, so must be deterministic. Implementations must be safe for
+// concurrent use.
+type Mutator interface {
+	// Name of the mutator, as reported in errors and logs.
+	Name() string
+
+	// Mutate the target secret of the request in place.
+	Mutate(ctx context.Context, request *Request) error
+}
+
+// Func adapts a function to a Mutator.
+type Func func(ctx context.Context, request *Request) error
+
+// Name of the mutator.
+func (f Func) Name() string {
+	return "func"
+}
+
+// Mutate the target secret by calling the function.
+func (f Func) Mutate(ctx context.Context, request *Request) error {
+	return f(ctx, request)
+}
+
+// Factory creates a mutator from options given as key/value pairs. The
+// options understood depend on the mutator.
+type Factory func(options map[string]string) (Mutator, error)
+
+var (
+	factoriesMutex sync.RWMutex
+	factories      = map[string]Factory{}
+)
+
+// Register a factory for a mutator type. Registering the same type twice
+// panics, as this indicates two mutators are conflicting over a name.
+func Register(mutatorType string, factory Factory) {
+	factoriesMutex.Lock()
+	defer factoriesMutex.Unlock()
+
+	if _, exists := factories[mutatorType]; exists {
+		panic(fmt.Sprintf("mutator %q already registered", mutatorType))
+	}
+
+	factories[mutatorType] = factory
+}
+
+// Types returns the names of the registered mutator types in sorted order.
+func Types() []string {
+	factoriesMutex.RLock()
+	defer factoriesMutex.RUnlock()
+
+	types := make([]string, 0, len(factories))
+
+	for mutatorType := range factories {
+		types = append(types, mutatorType)
+	}
+
+	sort.Strings(types)
+
+	return types
+}
+
+// New creates a mutator of the registered type using the options.
+func New(mutatorType string, options map[string]string) (Mutator, error) {
+	factoriesMutex.RLock()
+	factory, exists := factories[mutatorType]
+	factoriesMutex.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("unknown mutator %q, must be one of %s", mutatorType, strings.Join(Types(), ", "))
+	}
+
+	return factory(options)
+}
+
+// ParseOptions parses options given as a comma separated list of key=value
+// pairs, as accepted on the command line.
+func ParseOptions(value string) (map[string]string, error) {
+	options := map[string]string{}
+
+	for _, option := range strings.Split(value, ",") {
+		if strings.TrimSpace(option) == "" {
+			continue
+		}
+
+		key, optionValue, found := strings.Cut(option, "=")
+
+		if !found || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid mutator option %q, must be key=value", option)
+		}
+
+		options[strings.TrimSpace(key)] = optionValue
+	}
+
+	return options, nil
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mutation
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:  "empty",
+			value: "",
+			want:  map[string]string{},
+		},
+		{
+			name:  "command and args",
+			value: "command=/usr/local/bin/mutate, args=--strict --team=platform",
+			want:  map[string]string{"command": "/usr/local/bin/mutate", "args": "--strict --team=platform"},
+		},
+		{
+			name:    "missing value",
+			value:   "command",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOptions(tt.value)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseOptions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		mutatorType string
+		options     map[string]string
+		wantErr     bool
+	}{
+		{
+			name:        "exec with command",
+			mutatorType: "exec",
+			options:     map[string]string{"command": "/usr/local/bin/mutate"},
+		},
+		{
+			name:        "exec without command",
+			mutatorType: "exec",
+			options:     map[string]string{},
+			wantErr:     true,
+		},
+		{
+			name:        "unknown mutator",
+			mutatorType: "webhook",
+			options:     map[string]string{},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mutator, err := New(tt.mutatorType, tt.options)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && mutator.Name() != tt.mutatorType {
+				t.Errorf("Name() = %q, want %q", mutator.Name(), tt.mutatorType)
+			}
+		})
+	}
+}
+
+func TestExecMutator(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("requires /bin/sh")
+	}
+
+	// The command replaces the target secret with a fixed secret, checking
+	// it was passed the request.
+
+	script := filepath.Join(t.TempDir(), "mutate.sh")
+
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n"+
+		"grep -q '\"secretCopier\":\"registry\"' || { echo \"bad request\" >&2; exit 1; }\n"+
+		"echo '{\"metadata\":{\"name\":\"registry\",\"labels\":{\"team\":\"platform\"}},\"data\":{\"token\":\"dG9rZW4=\"}}'\n"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	request := &Request{
+		SecretCopier: "registry",
+		Rule:         "secrets/registry",
+		Source:       &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "secrets"}},
+		Target:       &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "tenant-a"}},
+	}
+
+	if err := NewExecMutator(script).Mutate(context.Background(), request); err != nil {
+		t.Fatalf("Mutate() error = %v", err)
+	}
+
+	if request.Target.Labels["team"] != "platform" || string(request.Target.Data["token"]) != "token" {
+		t.Errorf("Mutate() target = %+v, want mutated target", request.Target)
+	}
+
+	request.SecretCopier = "other"
+
+	if err := NewExecMutator(script).Mutate(context.Background(), request); err == nil || !strings.Contains(err.Error(), "bad request") {
+		t.Errorf("Mutate() error = %v, want stderr of command", err)
+	}
+}
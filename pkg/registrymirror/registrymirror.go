@@ -0,0 +1,205 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package registrymirror rewrites the registry hostnames of the credentials in
+// Docker config data, such as held by image pull secrets, so that credentials
+// for a registry are instead given for a mirror of it.
+package registrymirror
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Hostname used for Docker Hub, which credentials may also be given for under
+// one of its aliases.
+const dockerHub = "docker.io"
+
+// Aliases under which credentials for Docker Hub may be given.
+var dockerHubAliases = map[string]bool{
+	"index.docker.io":      true,
+	"registry-1.docker.io": true,
+	"registry.docker.io":   true,
+}
+
+// Mirror maps a registry to the mirror which credentials for it should be
+// given for instead.
+type Mirror struct {
+	// Registry whose credentials are rewritten, such as docker.io.
+	Registry string
+
+	// Mirror the credentials are given for instead, such as
+	// mirror.example.com/dockerhub.
+	Mirror string
+}
+
+// Normalize the key of a credentials entry to the registry it is for,
+// stripping any scheme and API version path, and mapping Docker Hub aliases
+// to docker.io.
+func normalize(key string) string {
+	key = strings.TrimPrefix(key, "https://")
+	key = strings.TrimPrefix(key, "http://")
+
+	for _, suffix := range []string{"/v1/", "/v2/", "/v1", "/v2", "/"} {
+		key = strings.TrimSuffix(key, suffix)
+	}
+
+	host, path, _ := strings.Cut(key, "/")
+
+	host = strings.ToLower(host)
+
+	if dockerHubAliases[host] {
+		host = dockerHub
+	}
+
+	if path == "" {
+		return host
+	}
+
+	return host + "/" + path
+}
+
+// Determine the key a credentials entry should be given under, returning
+// false if no mirror applies to it.
+func mirrorKey(key string, mirrors []Mirror) (string, bool) {
+	registry := normalize(key)
+
+	for _, mirror := range mirrors {
+		from := normalize(mirror.Registry)
+
+		if registry == from {
+			return mirror.Mirror, true
+		}
+
+		if rest, found := strings.CutPrefix(registry, from+"/"); found {
+			return strings.TrimSuffix(mirror.Mirror, "/") + "/" + rest, true
+		}
+	}
+
+	return "", false
+}
+
+// Rewrite the keys of a map of credentials entries. Entries are kept as raw
+// JSON so any fields they hold are preserved. An entry already given for a
+// mirror is kept in preference to one rewritten to the same key.
+func rewriteEntries(entries map[string]json.RawMessage, mirrors []Mirror) (map[string]json.RawMessage, bool) {
+	rewritten := make(map[string]json.RawMessage, len(entries))
+
+	changed := false
+
+	for key, entry := range entries {
+		if _, found := mirrorKey(key, mirrors); !found {
+			rewritten[key] = entry
+		}
+	}
+
+	for key, entry := range entries {
+		target, found := mirrorKey(key, mirrors)
+
+		if !found {
+			continue
+		}
+
+		changed = true
+
+		if _, exists := rewritten[target]; !exists {
+			rewritten[target] = entry
+		}
+	}
+
+	return rewritten, changed
+}
+
+// RewriteDockerConfigJSON rewrites the registries of the credentials held in
+// data in the format of the .dockerconfigjson key of an image pull secret.
+// Data is returned unchanged if no mirror applies to any of the credentials.
+func RewriteDockerConfigJSON(data []byte, mirrors []Mirror) ([]byte, error) {
+	var config map[string]json.RawMessage
+
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid docker config: %w", err)
+	}
+
+	auths, found := config["auths"]
+
+	if !found {
+		return data, nil
+	}
+
+	var entries map[string]json.RawMessage
+
+	if err := json.Unmarshal(auths, &entries); err != nil {
+		return nil, fmt.Errorf("invalid auths in docker config: %w", err)
+	}
+
+	entries, changed := rewriteEntries(entries, mirrors)
+
+	if !changed {
+		return data, nil
+	}
+
+	auths, err := json.Marshal(entries)
+
+	if err != nil {
+		return nil, err
+	}
+
+	config["auths"] = auths
+
+	return json.Marshal(config)
+}
+
+// RewriteDockerConfig rewrites the registries of the credentials held in data
+// in the legacy format of the .dockercfg key of an image pull secret. Data is
+// returned unchanged if no mirror applies to any of the credentials.
+func RewriteDockerConfig(data []byte, mirrors []Mirror) ([]byte, error) {
+	var entries map[string]json.RawMessage
+
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("invalid docker config: %w", err)
+	}
+
+	entries, changed := rewriteEntries(entries, mirrors)
+
+	if !changed {
+		return data, nil
+	}
+
+	return json.Marshal(entries)
+}
+
+// ValidateRegistry checks that a registry or mirror is given as a hostname,
+// optionally with a port and path, without a scheme.
+func ValidateRegistry(value string) error {
+	if value == "" {
+		return fmt.Errorf("must not be empty")
+	}
+
+	if strings.Contains(value, "://") {
+		return fmt.Errorf("must not include a scheme")
+	}
+
+	if strings.ContainsAny(value, " \t\r\n") {
+		return fmt.Errorf("must not contain whitespace")
+	}
+
+	if host, _, _ := strings.Cut(value, "/"); host == "" {
+		return fmt.Errorf("must start with a hostname")
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registrymirror
+
+import (
+	"testing"
+)
+
+func TestRewriteDockerConfigJSON(t *testing.T) {
+	mirrors := []Mirror{
+		{Registry: "docker.io", Mirror: "mirror.example.com/dockerhub"},
+		{Registry: "ghcr.io", Mirror: "mirror.example.com:5000"},
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "docker hub alias rewritten",
+			data: `{"auths":{"https://index.docker.io/v1/":{"auth":"dXNlcjpwYXNz"}}}`,
+			want: `{"auths":{"mirror.example.com/dockerhub":{"auth":"dXNlcjpwYXNz"}}}`,
+		},
+		{
+			name: "registry with path and port rewritten",
+			data: `{"auths":{"ghcr.io/acme":{"auth":"YQ=="},"quay.io":{"auth":"Yg=="}}}`,
+			want: `{"auths":{"mirror.example.com:5000/acme":{"auth":"YQ=="},"quay.io":{"auth":"Yg=="}}}`,
+		},
+		{
+			name: "existing mirror entry kept",
+			data: `{"auths":{"docker.io":{"auth":"YQ=="},"mirror.example.com/dockerhub":{"auth":"Yg=="}}}`,
+			want: `{"auths":{"mirror.example.com/dockerhub":{"auth":"Yg=="}}}`,
+		},
+		{
+			name: "other fields preserved",
+			data: `{"auths":{"docker.io":{"auth":"YQ==","email":"a@example.com"}},"credsStore":"none"}`,
+			want: `{"auths":{"mirror.example.com/dockerhub":{"auth":"YQ==","email":"a@example.com"}},"credsStore":"none"}`,
+		},
+		{
+			name: "unchanged when no mirror applies",
+			data: `{ "auths": { "quay.io": { "auth": "YQ==" } } }`,
+			want: `{ "auths": { "quay.io": { "auth": "YQ==" } } }`,
+		},
+		{
+			name:    "invalid json",
+			data:    `{"auths":`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RewriteDockerConfigJSON([]byte(tt.data), mirrors)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RewriteDockerConfigJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("RewriteDockerConfigJSON() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewriteDockerConfig(t *testing.T) {
+	mirrors := []Mirror{{Registry: "docker.io", Mirror: "mirror.example.com"}}
+
+	got, err := RewriteDockerConfig([]byte(`{"registry-1.docker.io":{"auth":"YQ=="}}`), mirrors)
+
+	if err != nil {
+		t.Fatalf("RewriteDockerConfig() error = %v", err)
+	}
+
+	if want := `{"mirror.example.com":{"auth":"YQ=="}}`; string(got) != want {
+		t.Errorf("RewriteDockerConfig() = %s, want %s", got, want)
+	}
+}
+
+func TestValidateRegistry(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{value: "docker.io"},
+		{value: "mirror.example.com:5000/dockerhub"},
+		{value: "", wantErr: true},
+		{value: "https://docker.io", wantErr: true},
+		{value: "/dockerhub", wantErr: true},
+		{value: "mirror example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if err := ValidateRegistry(tt.value); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRegistry(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package selectors
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// globPattern is a precompiled glob expression. Patterns which contain no glob
+// meta characters are matched using a plain string comparison.
+type globPattern struct {
+	pattern string
+	literal bool
+}
+
+// Compile a glob expression.
+func compileGlob(pattern string) globPattern {
+	return globPattern{
+		pattern: pattern,
+		literal: !strings.ContainsAny(pattern, `*?[\`),
+	}
+}
+
+// Compile a list of glob expressions.
+func compileGlobs(patterns []string) []globPattern {
+	globs := make([]globPattern, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		globs = append(globs, compileGlob(pattern))
+	}
+
+	return globs
+}
+
+// Matches a value against the glob expression.
+func (g globPattern) matches(value string) bool {
+	if g.literal {
+		return g.pattern == value
+	}
+
+	match, _ := filepath.Match(g.pattern, value)
+
+	return match
+}
+
+// Matches a value against any of a list of glob expressions.
+func matchesAnyGlob(value string, globs []globPattern) bool {
+	for _, glob := range globs {
+		if glob.matches(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchesAnyPattern reports whether a value matches any of a list of glob
+// expressions.
+func MatchesAnyPattern(value string, patterns []string) bool {
+	return matchesAnyGlob(value, compileGlobs(patterns))
+}
+
+// IsPattern reports whether a value contains glob meta characters, and so would
+// not be matched using a plain string comparison.
+func IsPattern(value string) bool {
+	return !compileGlob(value).literal
+}
+
+// ValidatePattern checks that a glob expression is well formed.
+func ValidatePattern(pattern string) error {
+	_, err := filepath.Match(pattern, "")
+
+	return err
+}
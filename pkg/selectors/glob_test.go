@@ -0,0 +1,158 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package selectors
+
+import (
+	"testing"
+)
+
+func TestGlobPattern_Matches(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		value   string
+		literal bool
+		want    bool
+	}{
+		{
+			name:    "literal match",
+			pattern: "test-namespace",
+			value:   "test-namespace",
+			literal: true,
+			want:    true,
+		},
+		{
+			name:    "literal no match",
+			pattern: "test-namespace",
+			value:   "test-namespace-1",
+			literal: true,
+			want:    false,
+		},
+		{
+			name:    "wildcard match",
+			pattern: "test-*",
+			value:   "test-namespace",
+			literal: false,
+			want:    true,
+		},
+		{
+			name:    "character class match",
+			pattern: "test-[0-9]",
+			value:   "test-1",
+			literal: false,
+			want:    true,
+		},
+		{
+			name:    "single character no match",
+			pattern: "test-?",
+			value:   "test-10",
+			literal: false,
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			glob := compileGlob(tt.pattern)
+			if glob.literal != tt.literal {
+				t.Errorf("compileGlob().literal = %v, want %v", glob.literal, tt.literal)
+			}
+			if got := glob.matches(tt.value); got != tt.want {
+				t.Errorf("globPattern.matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesAnyPattern(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		value    string
+		want     bool
+	}{
+		{
+			name:     "no patterns",
+			patterns: nil,
+			value:    "ca.crt",
+			want:     false,
+		},
+		{
+			name:     "literal match",
+			patterns: []string{"tls.key", "ca.crt"},
+			value:    "ca.crt",
+			want:     true,
+		},
+		{
+			name:     "wildcard match",
+			patterns: []string{"*.crt"},
+			value:    "tls.crt",
+			want:     true,
+		},
+		{
+			name:     "no match",
+			patterns: []string{"*.crt"},
+			value:    "tls.key",
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchesAnyPattern(tt.value, tt.patterns); got != tt.want {
+				t.Errorf("MatchesAnyPattern() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsPattern(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{
+			name:  "literal",
+			value: "tls-secret",
+			want:  false,
+		},
+		{
+			name:  "wildcard",
+			value: "tls-*",
+			want:  true,
+		},
+		{
+			name:  "single character",
+			value: "tls-?",
+			want:  true,
+		},
+		{
+			name:  "character class",
+			value: "tls-[ab]",
+			want:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsPattern(tt.value); got != tt.want {
+				t.Errorf("IsPattern() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
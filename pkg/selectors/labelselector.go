@@ -17,7 +17,7 @@ limitations under the License.
 package selectors
 
 import (
-	"path/filepath"
+	"strconv"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -31,6 +31,9 @@ type LabelSelector struct {
 	MatchLabels map[string]string `json:"matchLabels,omitempty"`
 
 	// matchExpressions is a list of label selector requirements. The requirements are ANDed.
+	// In addition to the standard operators, the Gt and Lt operators are supported, which
+	// take a single integer value and match labels whose value is an integer greater than
+	// or less than it, as for node affinity.
 	MatchExpressions []metav1.LabelSelectorRequirement `json:"matchExpressions,omitempty"`
 }
 
@@ -39,28 +42,88 @@ func (s LabelSelector) IsEmpty() bool {
 	return len(s.MatchLabels) == 0 && len(s.MatchExpressions) == 0
 }
 
-// Matches against a set of labels.
-func (s LabelSelector) Matches(labels map[string]string) bool {
-	// Empty set will never be matched.
+// Keys of the labels referenced by the selector.
+func (s LabelSelector) Keys() []string {
+	keys := make([]string, 0, len(s.MatchLabels)+len(s.MatchExpressions))
 
-	if len(s.MatchLabels) == 0 && len(s.MatchExpressions) == 0 {
-		return false
+	for key := range s.MatchLabels {
+		keys = append(keys, key)
 	}
 
-	// Function to match label against list of labels using glob expression.
+	for _, matchExpression := range s.MatchExpressions {
+		keys = append(keys, matchExpression.Key)
+	}
 
-	globMatchLabel := func(label string, items []string) bool {
-		for _, item := range items {
-			if match, _ := filepath.Match(item, label); match {
-				return true
-			}
+	return keys
+}
+
+// Operators for numeric comparison of label values, as used by node affinity.
+const (
+	LabelSelectorOpGt metav1.LabelSelectorOperator = "Gt"
+	LabelSelectorOpLt metav1.LabelSelectorOperator = "Lt"
+)
+
+// labelRequirement is a precompiled form of a label selector requirement.
+type labelRequirement struct {
+	key      string
+	operator metav1.LabelSelectorOperator
+	values   []globPattern
+
+	// Value to compare against for the Gt and Lt operators. If the
+	// requirement doesn't have a single integer value it never matches.
+	number      int64
+	numberValid bool
+}
+
+// LabelMatcher is a precompiled form of LabelSelector.
+type LabelMatcher struct {
+	matchLabels  map[string]string
+	requirements []labelRequirement
+}
+
+// Compile the selector into a matcher which can be reused.
+func (s LabelSelector) Compile() *LabelMatcher {
+	requirements := make([]labelRequirement, 0, len(s.MatchExpressions))
+
+	for _, matchExpression := range s.MatchExpressions {
+		requirement := labelRequirement{
+			key:      matchExpression.Key,
+			operator: matchExpression.Operator,
+			values:   compileGlobs(matchExpression.Values),
+		}
+
+		if len(matchExpression.Values) == 1 {
+			number, err := strconv.ParseInt(matchExpression.Values[0], 10, 64)
+
+			requirement.number = number
+			requirement.numberValid = err == nil
 		}
+
+		requirements = append(requirements, requirement)
+	}
+
+	return &LabelMatcher{
+		matchLabels:  s.MatchLabels,
+		requirements: requirements,
+	}
+}
+
+// Matches against a set of labels.
+func (s LabelSelector) Matches(labels map[string]string) bool {
+	return s.Compile().Matches(labels)
+}
+
+// Matches against a set of labels.
+func (m *LabelMatcher) Matches(labels map[string]string) bool {
+	// Empty set will never be matched.
+
+	if len(m.matchLabels) == 0 && len(m.requirements) == 0 {
 		return false
 	}
 
 	// Match labels against matchLabels.
 
-	for key, value := range s.MatchLabels {
+	for key, value := range m.matchLabels {
 		if label, ok := labels[key]; !ok || label != value {
 			return false
 		}
@@ -68,24 +131,28 @@ func (s LabelSelector) Matches(labels map[string]string) bool {
 
 	// Match labels against matchExpressions.
 
-	for _, matchExpression := range s.MatchExpressions {
-		if label, ok := labels[matchExpression.Key]; ok {
-			switch matchExpression.Operator {
+	for _, requirement := range m.requirements {
+		if label, ok := labels[requirement.key]; ok {
+			switch requirement.operator {
 			case "In":
-				if !globMatchLabel(label, matchExpression.Values) {
+				if !matchesAnyGlob(label, requirement.values) {
 					return false
 				}
 			case "NotIn":
-				if globMatchLabel(label, matchExpression.Values) {
+				if matchesAnyGlob(label, requirement.values) {
 					return false
 				}
 			case "Exists":
 				// Do nothing.
 			case "DoesNotExist":
 				return false
+			case LabelSelectorOpGt, LabelSelectorOpLt:
+				if !requirement.compare(label) {
+					return false
+				}
 			}
 		} else {
-			switch matchExpression.Operator {
+			switch requirement.operator {
 			case "In":
 				return false
 			case "NotIn":
@@ -94,9 +161,31 @@ func (s LabelSelector) Matches(labels map[string]string) bool {
 				return false
 			case "DoesNotExist":
 				// Do nothing.
+			case LabelSelectorOpGt, LabelSelectorOpLt:
+				return false
 			}
 		}
 	}
 
 	return true
 }
+
+// Compare a label value against the value of a Gt or Lt requirement. Label
+// values which are not integers never match.
+func (r *labelRequirement) compare(label string) bool {
+	if !r.numberValid {
+		return false
+	}
+
+	value, err := strconv.ParseInt(label, 10, 64)
+
+	if err != nil {
+		return false
+	}
+
+	if r.operator == LabelSelectorOpGt {
+		return value > r.number
+	}
+
+	return value < r.number
+}
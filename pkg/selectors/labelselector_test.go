@@ -291,6 +291,116 @@ func TestLabelSelector_Matches(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "MatchExpressions: Gt match",
+			labels: map[string]string{
+				"tenant-tier": "3",
+			},
+			s: LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{
+						Key:      "tenant-tier",
+						Operator: "Gt",
+						Values:   []string{"2"},
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "MatchExpressions: Gt equal value no match",
+			labels: map[string]string{
+				"tenant-tier": "2",
+			},
+			s: LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{
+						Key:      "tenant-tier",
+						Operator: "Gt",
+						Values:   []string{"2"},
+					},
+				},
+			},
+			want: false,
+		},
+		{
+			name: "MatchExpressions: Lt match",
+			labels: map[string]string{
+				"tenant-tier": "1",
+			},
+			s: LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{
+						Key:      "tenant-tier",
+						Operator: "Lt",
+						Values:   []string{"2"},
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "MatchExpressions: Lt no match",
+			labels: map[string]string{
+				"tenant-tier": "5",
+			},
+			s: LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{
+						Key:      "tenant-tier",
+						Operator: "Lt",
+						Values:   []string{"2"},
+					},
+				},
+			},
+			want: false,
+		},
+		{
+			name: "MatchExpressions: Gt non integer label",
+			labels: map[string]string{
+				"tenant-tier": "gold",
+			},
+			s: LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{
+						Key:      "tenant-tier",
+						Operator: "Gt",
+						Values:   []string{"2"},
+					},
+				},
+			},
+			want: false,
+		},
+		{
+			name: "MatchExpressions: Gt non integer value",
+			labels: map[string]string{
+				"tenant-tier": "3",
+			},
+			s: LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{
+						Key:      "tenant-tier",
+						Operator: "Gt",
+						Values:   []string{"two"},
+					},
+				},
+			},
+			want: false,
+		},
+		{
+			name:   "MatchExpressions: Lt label not found",
+			labels: map[string]string{},
+			s: LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{
+						Key:      "tenant-tier",
+						Operator: "Lt",
+						Values:   []string{"2"},
+					},
+				},
+			},
+			want: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -300,3 +410,24 @@ func TestLabelSelector_Matches(t *testing.T) {
 		})
 	}
 }
+
+func TestLabelSelector_Keys(t *testing.T) {
+	s := LabelSelector{
+		MatchLabels: map[string]string{
+			"app": "myapp",
+		},
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{
+				Key:      "env",
+				Operator: "In",
+				Values:   []string{"prod"},
+			},
+		},
+	}
+
+	keys := s.Keys()
+
+	if len(keys) != 2 || keys[0] != "app" || keys[1] != "env" {
+		t.Errorf("LabelSelector.Keys() = %v, want %v", keys, []string{"app", "env"})
+	}
+}
@@ -17,7 +17,6 @@ limitations under the License.
 package selectors
 
 import (
-	"path/filepath"
 	"strings"
 )
 
@@ -33,19 +32,19 @@ func (s NameSelector) IsEmpty() bool {
 	return len(s.MatchNames) == 0
 }
 
-// Matches against a name.
-func (s NameSelector) Matches(name string) bool {
-	// Empty set will never be matched.
-
-	if len(s.MatchNames) == 0 {
-		return false
-	}
-
-	// Split names into include and exclude lists.
+// NameMatcher is a precompiled form of NameSelector.
+type NameMatcher struct {
+	includeNames []globPattern
+	excludeNames []globPattern
+}
 
+// Compile the selector into a matcher which can be reused.
+func (s NameSelector) Compile() *NameMatcher {
 	var matchExcludeNames []string
 	var matchIncludeNames []string
 
+	// Split names into include and exclude lists.
+
 	for _, name := range s.MatchNames {
 		if strings.HasPrefix(name, "!") {
 			matchExcludeNames = append(matchExcludeNames, name[1:])
@@ -54,26 +53,34 @@ func (s NameSelector) Matches(name string) bool {
 		}
 	}
 
-	// Function to match name against list of names using glob expression.
+	return &NameMatcher{
+		includeNames: compileGlobs(matchIncludeNames),
+		excludeNames: compileGlobs(matchExcludeNames),
+	}
+}
 
-	globMatchName := func(name string, items []string) bool {
-		for _, item := range items {
-			if ok, _ := filepath.Match(item, name); ok {
-				return true
-			}
-		}
+// Matches against a name.
+func (s NameSelector) Matches(name string) bool {
+	return s.Compile().Matches(name)
+}
+
+// Matches against a name.
+func (m *NameMatcher) Matches(name string) bool {
+	// Empty set will never be matched.
+
+	if len(m.includeNames) == 0 && len(m.excludeNames) == 0 {
 		return false
 	}
 
 	// If there are any include names, but don't match any then return false.
 
-	if len(matchIncludeNames) > 0 && !globMatchName(name, matchIncludeNames) {
+	if len(m.includeNames) > 0 && !matchesAnyGlob(name, m.includeNames) {
 		return false
 	}
 
 	// If there are any exclude names, and match any then return false.
 
-	if len(matchExcludeNames) > 0 && globMatchName(name, matchExcludeNames) {
+	if len(m.excludeNames) > 0 && matchesAnyGlob(name, m.excludeNames) {
 		return false
 	}
 
@@ -0,0 +1,89 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package selectors
+
+// Annotation set by OpenShift on a project to record the user who requested
+// it.
+const OpenShiftRequesterAnnotation = "openshift.io/requester"
+
+// Label set by Rancher on a namespace to record the project it belongs to.
+const RancherProjectIDLabel = "field.cattle.io/projectId"
+
+// RequesterSelector is a selector which matches on the user who requested a
+// namespace on OpenShift, or the project a namespace belongs to on Rancher.
+// +k8s:deepcopy-gen=true
+type RequesterSelector struct {
+	// List of requesters to match on, compared against the
+	// openshift.io/requester annotation. Entries may be glob patterns.
+	MatchRequesters []string `json:"matchRequesters,omitempty"`
+
+	// List of Rancher project IDs to match on, compared against the
+	// field.cattle.io/projectId label. Entries may be glob patterns.
+	MatchProjects []string `json:"matchProjects,omitempty"`
+}
+
+// Test whether selector is empty.
+func (s RequesterSelector) IsEmpty() bool {
+	return len(s.MatchRequesters) == 0 && len(s.MatchProjects) == 0
+}
+
+// RequesterMatcher is a precompiled form of RequesterSelector.
+type RequesterMatcher struct {
+	requesters []globPattern
+	projects   []globPattern
+}
+
+// Compile the selector into a matcher which can be reused.
+func (s RequesterSelector) Compile() *RequesterMatcher {
+	return &RequesterMatcher{
+		requesters: compileGlobs(s.MatchRequesters),
+		projects:   compileGlobs(s.MatchProjects),
+	}
+}
+
+// Matches against the annotations and labels of a namespace.
+func (s RequesterSelector) Matches(annotations map[string]string, labels map[string]string) bool {
+	return s.Compile().Matches(annotations, labels)
+}
+
+// Matches against the annotations and labels of a namespace. Where both
+// requesters and projects are given, both must match.
+func (m *RequesterMatcher) Matches(annotations map[string]string, labels map[string]string) bool {
+	// Empty set will never be matched.
+
+	if len(m.requesters) == 0 && len(m.projects) == 0 {
+		return false
+	}
+
+	if len(m.requesters) > 0 {
+		requester, ok := annotations[OpenShiftRequesterAnnotation]
+
+		if !ok || !matchesAnyGlob(requester, m.requesters) {
+			return false
+		}
+	}
+
+	if len(m.projects) > 0 {
+		project, ok := labels[RancherProjectIDLabel]
+
+		if !ok || !matchesAnyGlob(project, m.projects) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package selectors
+
+import (
+	"testing"
+)
+
+func TestRequesterSelector_Matches(t *testing.T) {
+	tests := []struct {
+		name        string
+		s           RequesterSelector
+		annotations map[string]string
+		labels      map[string]string
+		want        bool
+	}{
+		{
+			name:        "EmptySelector: nothing to match",
+			s:           RequesterSelector{},
+			annotations: map[string]string{OpenShiftRequesterAnnotation: "alice"},
+			want:        false,
+		},
+		{
+			name:        "MatchRequesters: requester match",
+			s:           RequesterSelector{MatchRequesters: []string{"alice", "bob"}},
+			annotations: map[string]string{OpenShiftRequesterAnnotation: "bob"},
+			want:        true,
+		},
+		{
+			name:        "MatchRequesters: glob match",
+			s:           RequesterSelector{MatchRequesters: []string{"system:serviceaccount:ci:*"}},
+			annotations: map[string]string{OpenShiftRequesterAnnotation: "system:serviceaccount:ci:pipeline"},
+			want:        true,
+		},
+		{
+			name:        "MatchRequesters: requester no match",
+			s:           RequesterSelector{MatchRequesters: []string{"alice"}},
+			annotations: map[string]string{OpenShiftRequesterAnnotation: "bob"},
+			want:        false,
+		},
+		{
+			name: "MatchRequesters: annotation missing",
+			s:    RequesterSelector{MatchRequesters: []string{"*"}},
+			want: false,
+		},
+		{
+			name:   "MatchProjects: project match",
+			s:      RequesterSelector{MatchProjects: []string{"p-abc12"}},
+			labels: map[string]string{RancherProjectIDLabel: "p-abc12"},
+			want:   true,
+		},
+		{
+			name:        "Both: project no match",
+			s:           RequesterSelector{MatchRequesters: []string{"alice"}, MatchProjects: []string{"p-abc12"}},
+			annotations: map[string]string{OpenShiftRequesterAnnotation: "alice"},
+			labels:      map[string]string{RancherProjectIDLabel: "p-xyz99"},
+			want:        false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.s.Matches(tt.annotations, tt.labels); got != tt.want {
+				t.Errorf("RequesterSelector.Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
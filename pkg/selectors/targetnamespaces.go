@@ -17,6 +17,8 @@ limitations under the License.
 package selectors
 
 import (
+	"fmt"
+
 	corev1 "k8s.io/api/core/v1"
 )
 
@@ -34,41 +36,109 @@ type TargetNamespaces struct {
 
 	// List of namespaces to match by label.
 	LabelSelector LabelSelector `json:"labelSelector,omitempty"`
+
+	// List of namespaces to match by OpenShift requester or Rancher project.
+	RequesterSelector RequesterSelector `json:"requesterSelector,omitempty"`
 }
 
 // Matches against a namespace. As soon as one of the matchers fails we
 // give up and return false.
 func (s TargetNamespaces) Matches(namespace *corev1.Namespace) bool {
-	// If there is no name selector, then match on all but Kubernetes
-	// system namespaces. Otherwise match on name selector.
+	return s.Compile().Matches(namespace)
+}
 
-	if s.NameSelector.IsEmpty() {
-		tmpNameSelector := NameSelector{[]string{"!kube-*"}}
+// Keys of the namespace labels referenced by the selectors. A change to the
+// value of any other label on a namespace cannot change whether it matches.
+func (s TargetNamespaces) LabelKeys() []string {
+	keys := s.LabelSelector.Keys()
 
-		if !tmpNameSelector.Matches(namespace.Name) {
-			return false
-		}
+	if len(s.RequesterSelector.MatchProjects) != 0 {
+		keys = append(keys, RancherProjectIDLabel)
+	}
+
+	return keys
+}
+
+// Keys of the namespace annotations referenced by the selectors.
+func (s TargetNamespaces) AnnotationKeys() []string {
+	if len(s.RequesterSelector.MatchRequesters) != 0 {
+		return []string{OpenShiftRequesterAnnotation}
+	}
+
+	return nil
+}
+
+// TargetNamespacesMatcher is a precompiled form of TargetNamespaces. Creating
+// a matcher once and reusing it avoids parsing the selectors again for every
+// namespace being matched.
+type TargetNamespacesMatcher struct {
+	nameMatcher      *NameMatcher
+	uidMatcher       *UIDMatcher
+	ownerSelector    OwnerSelector
+	labelMatcher     *LabelMatcher
+	requesterMatcher *RequesterMatcher
+}
+
+// Compile the selectors into a matcher which can be reused. If there is no
+// name selector, then the matcher will match on all but Kubernetes system
+// namespaces.
+func (s TargetNamespaces) Compile() *TargetNamespacesMatcher {
+	matcher := &TargetNamespacesMatcher{
+		ownerSelector: s.OwnerSelector,
+	}
+
+	if s.NameSelector.IsEmpty() {
+		matcher.nameMatcher = NameSelector{[]string{"!kube-*"}}.Compile()
 	} else {
-		if !s.NameSelector.Matches(namespace.Name) {
-			return false
-		}
+		matcher.nameMatcher = s.NameSelector.Compile()
+	}
+
+	if !s.UIDSelector.IsEmpty() {
+		matcher.uidMatcher = s.UIDSelector.Compile()
+	}
+
+	if !s.LabelSelector.IsEmpty() {
+		matcher.labelMatcher = s.LabelSelector.Compile()
+	}
+
+	if !s.RequesterSelector.IsEmpty() {
+		matcher.requesterMatcher = s.RequesterSelector.Compile()
+	}
+
+	return matcher
+}
+
+// Matches against a namespace. As soon as one of the matchers fails we
+// give up and return false.
+func (m *TargetNamespacesMatcher) Matches(namespace *corev1.Namespace) bool {
+	// Match on name selector, or the default of excluding Kubernetes system
+	// namespaces if there was no name selector.
+
+	if !m.nameMatcher.Matches(namespace.Name) {
+		return false
 	}
 
 	// If there are UIDs to match on, then match on them.
 
-	if !s.UIDSelector.IsEmpty() && !s.UIDSelector.Matches(string(namespace.GetUID())) {
+	if m.uidMatcher != nil && !m.uidMatcher.Matches(string(namespace.GetUID())) {
 		return false
 	}
 
 	// If there are owners to match on, then match on them.
 
-	if !s.OwnerSelector.IsEmpty() && !s.OwnerSelector.Matches(namespace.GetOwnerReferences()) {
+	if !m.ownerSelector.IsEmpty() && !m.ownerSelector.Matches(namespace.GetOwnerReferences()) {
 		return false
 	}
 
 	// If there are labels to match on, then match on them.
 
-	if !s.LabelSelector.IsEmpty() && !s.LabelSelector.Matches(namespace.GetLabels()) {
+	if m.labelMatcher != nil && !m.labelMatcher.Matches(namespace.GetLabels()) {
+		return false
+	}
+
+	// If there are requesters or projects to match on, then match on them.
+
+	if m.requesterMatcher != nil && !m.requesterMatcher.Matches(namespace.GetAnnotations(), namespace.GetLabels()) {
 		return false
 	}
 
@@ -76,3 +146,57 @@ func (s TargetNamespaces) Matches(namespace *corev1.Namespace) bool {
 
 	return true
 }
+
+// Explain why none of the namespaces are matched, naming the selector which
+// excluded the last of them. Selectors are applied in the same order as when
+// matching. Returns an empty string if any of the namespaces match.
+func (m *TargetNamespacesMatcher) Explain(namespaces []*corev1.Namespace) string {
+	if len(namespaces) == 0 {
+		return "there are no candidate namespaces"
+	}
+
+	stages := []struct {
+		selector string
+		matches  func(namespace *corev1.Namespace) bool
+	}{
+		{"name selector", func(namespace *corev1.Namespace) bool {
+			return m.nameMatcher.Matches(namespace.Name)
+		}},
+		{"UID selector", func(namespace *corev1.Namespace) bool {
+			return m.uidMatcher == nil || m.uidMatcher.Matches(string(namespace.GetUID()))
+		}},
+		{"owner selector", func(namespace *corev1.Namespace) bool {
+			return m.ownerSelector.IsEmpty() || m.ownerSelector.Matches(namespace.GetOwnerReferences())
+		}},
+		{"label selector", func(namespace *corev1.Namespace) bool {
+			return m.labelMatcher == nil || m.labelMatcher.Matches(namespace.GetLabels())
+		}},
+		{"requester selector", func(namespace *corev1.Namespace) bool {
+			return m.requesterMatcher == nil || m.requesterMatcher.Matches(namespace.GetAnnotations(), namespace.GetLabels())
+		}},
+	}
+
+	remaining := namespaces
+
+	for index, stage := range stages {
+		matched := make([]*corev1.Namespace, 0, len(remaining))
+
+		for _, namespace := range remaining {
+			if stage.matches(namespace) {
+				matched = append(matched, namespace)
+			}
+		}
+
+		if len(matched) == 0 {
+			if index == 0 {
+				return fmt.Sprintf("%s excluded all %d namespaces", stage.selector, len(remaining))
+			}
+
+			return fmt.Sprintf("%s excluded the remaining %d of %d namespaces", stage.selector, len(remaining), len(namespaces))
+		}
+
+		remaining = matched
+	}
+
+	return ""
+}
@@ -225,6 +225,64 @@ func TestTargetNamespaces_Matches(t *testing.T) {
 			if got := tt.selector.Matches(&tt.namespace); got != tt.want {
 				t.Errorf("TargetNamespaces.Matches() = %v, want %v", got, tt.want)
 			}
+			if got := tt.selector.Compile().Matches(&tt.namespace); got != tt.want {
+				t.Errorf("TargetNamespacesMatcher.Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTargetNamespacesMatcher_Explain(t *testing.T) {
+	namespaces := []*corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"tier": "1"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "team-b", Labels: map[string]string{"tier": "2"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+	}
+
+	tests := []struct {
+		name       string
+		selector   TargetNamespaces
+		namespaces []*corev1.Namespace
+		want       string
+	}{
+		{
+			name:       "no candidate namespaces",
+			selector:   TargetNamespaces{},
+			namespaces: nil,
+			want:       "there are no candidate namespaces",
+		},
+		{
+			name: "name selector excludes all",
+			selector: TargetNamespaces{
+				NameSelector: NameSelector{MatchNames: []string{"tema-*"}},
+			},
+			namespaces: namespaces,
+			want:       "name selector excluded all 3 namespaces",
+		},
+		{
+			name: "label selector excludes remaining",
+			selector: TargetNamespaces{
+				NameSelector:  NameSelector{MatchNames: []string{"team-*"}},
+				LabelSelector: LabelSelector{MatchLabels: map[string]string{"tier": "3"}},
+			},
+			namespaces: namespaces,
+			want:       "label selector excluded the remaining 2 of 3 namespaces",
+		},
+		{
+			name: "matches",
+			selector: TargetNamespaces{
+				NameSelector: NameSelector{MatchNames: []string{"team-*"}},
+			},
+			namespaces: namespaces,
+			want:       "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.selector.Compile().Explain(tt.namespaces); got != tt.want {
+				t.Errorf("TargetNamespacesMatcher.Explain() = %q, want %q", got, tt.want)
+			}
 		})
 	}
 }
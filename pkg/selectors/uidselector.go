@@ -19,7 +19,8 @@ package selectors
 // UIDSelector is a selector which matches on UID.
 // +k8s:deepcopy-gen=true
 type UIDSelector struct {
-	// List of UIDs to match on.
+	// List of UIDs to match on. Entries may be glob patterns, for example
+	// "3f2a*" to match on a UID prefix.
 	MatchUids []string `json:"matchUids"`
 }
 
@@ -28,13 +29,43 @@ func (s UIDSelector) IsEmpty() bool {
 	return len(s.MatchUids) == 0
 }
 
+// UIDMatcher is a precompiled form of UIDSelector. Exact UIDs are held in a
+// set so that long lists of UIDs remain cheap to match against, with only the
+// glob patterns being checked one by one.
+type UIDMatcher struct {
+	uids     map[string]struct{}
+	patterns []globPattern
+}
+
+// Compile the selector into a matcher which can be reused.
+func (s UIDSelector) Compile() *UIDMatcher {
+	uids := make(map[string]struct{}, len(s.MatchUids))
+
+	var patterns []globPattern
+
+	for _, uid := range s.MatchUids {
+		glob := compileGlob(uid)
+
+		if glob.literal {
+			uids[uid] = struct{}{}
+		} else {
+			patterns = append(patterns, glob)
+		}
+	}
+
+	return &UIDMatcher{uids: uids, patterns: patterns}
+}
+
 // Matches against a uid.
 func (s UIDSelector) Matches(uid string) bool {
-	for _, name := range s.MatchUids {
-		if name == uid {
-			return true
-		}
+	return s.Compile().Matches(uid)
+}
+
+// Matches against a uid.
+func (m *UIDMatcher) Matches(uid string) bool {
+	if _, ok := m.uids[uid]; ok {
+		return true
 	}
 
-	return false
+	return matchesAnyGlob(uid, m.patterns)
 }
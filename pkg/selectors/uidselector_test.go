@@ -34,3 +34,53 @@ func TestUIDSelector_Matches(t *testing.T) {
 		t.Errorf("Expected UID selector to not match uid4, but it did.")
 	}
 }
+
+func TestUIDSelector_MatchesGlob(t *testing.T) {
+	tests := []struct {
+		name      string
+		matchUids []string
+		uid       string
+		want      bool
+	}{
+		{
+			name:      "prefix match",
+			matchUids: []string{"3f2a*"},
+			uid:       "3f2a9c1e-7d41-4a6b-9f0e-1c2d3e4f5a6b",
+			want:      true,
+		},
+		{
+			name:      "prefix mismatch",
+			matchUids: []string{"3f2a*"},
+			uid:       "4f2a9c1e-7d41-4a6b-9f0e-1c2d3e4f5a6b",
+			want:      false,
+		},
+		{
+			name:      "character class",
+			matchUids: []string{"[0-3]*"},
+			uid:       "2b7e0c1e-7d41-4a6b-9f0e-1c2d3e4f5a6b",
+			want:      true,
+		},
+		{
+			name:      "exact alongside glob",
+			matchUids: []string{"uid1", "abc*"},
+			uid:       "uid1",
+			want:      true,
+		},
+		{
+			name:      "exact does not match prefix",
+			matchUids: []string{"uid1"},
+			uid:       "uid10",
+			want:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector := UIDSelector{MatchUids: tt.matchUids}
+
+			if got := selector.Matches(tt.uid); got != tt.want {
+				t.Errorf("UIDSelector.Matches(%q) = %v, want %v", tt.uid, got, tt.want)
+			}
+		})
+	}
+}
@@ -108,6 +108,31 @@ func (in *OwnerSelector) DeepCopy() *OwnerSelector {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RequesterSelector) DeepCopyInto(out *RequesterSelector) {
+	*out = *in
+	if in.MatchRequesters != nil {
+		in, out := &in.MatchRequesters, &out.MatchRequesters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MatchProjects != nil {
+		in, out := &in.MatchProjects, &out.MatchProjects
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RequesterSelector.
+func (in *RequesterSelector) DeepCopy() *RequesterSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(RequesterSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TargetNamespaces) DeepCopyInto(out *TargetNamespaces) {
 	*out = *in
@@ -115,6 +140,7 @@ func (in *TargetNamespaces) DeepCopyInto(out *TargetNamespaces) {
 	in.UIDSelector.DeepCopyInto(&out.UIDSelector)
 	in.OwnerSelector.DeepCopyInto(&out.OwnerSelector)
 	in.LabelSelector.DeepCopyInto(&out.LabelSelector)
+	in.RequesterSelector.DeepCopyInto(&out.RequesterSelector)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetNamespaces.
@@ -0,0 +1,361 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package testing provides a harness for integration tests of SecretCopier
+// configurations. It starts a local Kubernetes API server using envtest with
+// the custom resource definitions of the secrets manager installed and the
+// controllers of the secrets manager running against it, along with helpers to
+// create namespaces, secrets and SecretCopiers and to wait for secrets to be
+// copied.
+//
+// The envtest binaries must be installed, with their location given by
+// Options.BinaryAssetsDirectory or the KUBEBUILDER_ASSETS environment
+// variable. They can be installed using setup-envtest:
+//
+//	export KUBEBUILDER_ASSETS="$(setup-envtest use 1.31.0 -p path)"
+package testing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/config"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/internal/controller"
+)
+
+// Timeout applied by the wait helpers when the context has no deadline.
+const DefaultTimeout = 30 * time.Second
+
+// Interval at which the wait helpers check for the expected state.
+const pollInterval = 100 * time.Millisecond
+
+// Options for starting the test environment.
+type Options struct {
+	// Directory containing the etcd and kube-apiserver binaries. If empty,
+	// the KUBEBUILDER_ASSETS environment variable or the envtest default of
+	// /usr/local/kubebuilder/bin is used.
+	BinaryAssetsDirectory string
+
+	// Additional directories containing custom resource definitions to
+	// install, for resources used by the configuration under test.
+	CRDDirectoryPaths []string
+
+	// Whether the SecretClaim controller is run in addition to the
+	// SecretCopier controller.
+	SecretClaims bool
+}
+
+// Environment is a running test environment.
+type Environment struct {
+	// Configuration for connecting to the API server.
+	Config *rest.Config
+
+	// Client for the API server, not reading through a cache.
+	Client client.Client
+
+	// Scheme with the Kubernetes and secrets manager types registered.
+	Scheme *k8sruntime.Scheme
+
+	testEnv *envtest.Environment
+	cancel  context.CancelFunc
+	done    chan error
+}
+
+// Location of the custom resource definitions of the secrets manager,
+// relative to the source of this package.
+func crdDirectoryPath() string {
+	_, filename, _, _ := runtime.Caller(0)
+
+	return filepath.Join(filepath.Dir(filename), "..", "..", "config", "crd", "bases")
+}
+
+// Start the API server and the controllers of the secrets manager. Stop must
+// be called to shut down the environment when done.
+func Start(options Options) (*Environment, error) {
+	scheme := k8sruntime.NewScheme()
+
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+
+	if err := secretsv1beta1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+
+	testEnv := &envtest.Environment{
+		CRDDirectoryPaths:     append([]string{crdDirectoryPath()}, options.CRDDirectoryPaths...),
+		ErrorIfCRDPathMissing: true,
+		BinaryAssetsDirectory: options.BinaryAssetsDirectory,
+		Scheme:                scheme,
+	}
+
+	cfg, err := testEnv.Start()
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to start test environment: %w", err)
+	}
+
+	env := &Environment{
+		Config:  cfg,
+		Scheme:  scheme,
+		testEnv: testEnv,
+		done:    make(chan error, 1),
+	}
+
+	if err := env.startManager(options); err != nil {
+		_ = testEnv.Stop()
+		return nil, err
+	}
+
+	return env, nil
+}
+
+// Create the manager running the controllers and start it in the background.
+func (e *Environment) startManager(options Options) error {
+	var err error
+
+	e.Client, err = client.New(e.Config, client.Options{Scheme: e.Scheme})
+
+	if err != nil {
+		return fmt.Errorf("unable to create client: %w", err)
+	}
+
+	// Metrics are not served, and controller names are not required to be
+	// unique, so that more than one environment can run in the same process.
+
+	mgr, err := ctrl.NewManager(e.Config, ctrl.Options{
+		Scheme:     e.Scheme,
+		Metrics:    metricsserver.Options{BindAddress: "0"},
+		Controller: config.Controller{SkipNameValidation: ptr.To(true)},
+	})
+
+	if err != nil {
+		return fmt.Errorf("unable to create manager: %w", err)
+	}
+
+	if err := (&controller.SecretCopierReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("secretcopier-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create SecretCopier controller: %w", err)
+	}
+
+	if options.SecretClaims {
+		if err := (&controller.SecretClaimReconciler{
+			Client:   mgr.GetClient(),
+			Scheme:   mgr.GetScheme(),
+			Recorder: mgr.GetEventRecorderFor("secretclaim-controller"),
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to create SecretClaim controller: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	e.cancel = cancel
+
+	go func() {
+		e.done <- mgr.Start(ctx)
+	}()
+
+	return nil
+}
+
+// Stop the controllers and the API server.
+func (e *Environment) Stop() error {
+	e.cancel()
+
+	managerErr := <-e.done
+
+	return errors.Join(managerErr, e.testEnv.Stop())
+}
+
+// CreateNamespace creates a namespace with the given labels.
+func (e *Environment) CreateNamespace(ctx context.Context, name string, labels map[string]string) (*corev1.Namespace, error) {
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+	}
+
+	if err := e.Client.Create(ctx, namespace); err != nil {
+		return nil, err
+	}
+
+	return namespace, nil
+}
+
+// CreateSecret creates an opaque secret holding the given data.
+func (e *Environment) CreateSecret(ctx context.Context, namespace string, name string, data map[string]string) (*corev1.Secret, error) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Type:       corev1.SecretTypeOpaque,
+		StringData: data,
+	}
+
+	if err := e.Client.Create(ctx, secret); err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// CreateSecretCopier creates a SecretCopier.
+func (e *Environment) CreateSecretCopier(ctx context.Context, secretCopier *secretsv1beta1.SecretCopier) error {
+	return e.Client.Create(ctx, secretCopier)
+}
+
+// Apply the default timeout if the context has no deadline.
+func withDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, DefaultTimeout)
+}
+
+// WaitForSecret waits until the secret exists and, if a condition is given,
+// the condition holds for it. Returns the secret as last observed.
+func (e *Environment) WaitForSecret(ctx context.Context, namespace string, name string, condition func(*corev1.Secret) bool) (*corev1.Secret, error) {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	secret := &corev1.Secret{}
+
+	err := wait.PollUntilContextCancel(ctx, pollInterval, true, func(ctx context.Context) (bool, error) {
+		if err := e.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, secret); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+
+		return condition == nil || condition(secret), nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("secret %s/%s not as expected: %w", namespace, name, err)
+	}
+
+	return secret, nil
+}
+
+// WaitForSecretData waits until the secret exists and holds the given data.
+func (e *Environment) WaitForSecretData(ctx context.Context, namespace string, name string, data map[string]string) (*corev1.Secret, error) {
+	return e.WaitForSecret(ctx, namespace, name, func(secret *corev1.Secret) bool {
+		if len(secret.Data) != len(data) {
+			return false
+		}
+
+		for key, value := range data {
+			if current, ok := secret.Data[key]; !ok || string(current) != value {
+				return false
+			}
+		}
+
+		return true
+	})
+}
+
+// WaitForSecretDeleted waits until the secret no longer exists.
+func (e *Environment) WaitForSecretDeleted(ctx context.Context, namespace string, name string) error {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	err := wait.PollUntilContextCancel(ctx, pollInterval, true, func(ctx context.Context) (bool, error) {
+		err := e.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, &corev1.Secret{})
+
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+
+		return false, err
+	})
+
+	if err != nil {
+		return fmt.Errorf("secret %s/%s not deleted: %w", namespace, name, err)
+	}
+
+	return nil
+}
+
+// WaitForSecretCopierSynced waits until the controller has processed the
+// current generation of the SecretCopier and its status reports no target
+// namespaces pending, waiting on dependencies or failing. Returns the
+// SecretCopier as last observed.
+func (e *Environment) WaitForSecretCopierSynced(ctx context.Context, name string) (*secretsv1beta1.SecretCopier, error) {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	secretCopier := &secretsv1beta1.SecretCopier{}
+
+	err := wait.PollUntilContextCancel(ctx, pollInterval, true, func(ctx context.Context) (bool, error) {
+		if err := e.Client.Get(ctx, client.ObjectKey{Name: name}, secretCopier); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+
+		return SecretCopierSynced(secretCopier), nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("SecretCopier %s not synced: %w", name, err)
+	}
+
+	return secretCopier, nil
+}
+
+// SecretCopierSynced reports whether the status of the SecretCopier shows the
+// current generation was processed with no target namespaces pending, waiting
+// on dependencies or failing.
+func SecretCopierSynced(secretCopier *secretsv1beta1.SecretCopier) bool {
+	status := &secretCopier.Status
+
+	if status.ObservedGeneration != secretCopier.Generation {
+		return false
+	}
+
+	if len(status.FailedTargets) != 0 || status.FailedTargetsOverflow != 0 {
+		return false
+	}
+
+	for _, rule := range status.Rules {
+		if rule.PendingNamespaces != 0 || rule.WaitingOnDependencies != 0 || len(rule.DeniedNamespaces) != 0 {
+			return false
+		}
+	}
+
+	return true
+}
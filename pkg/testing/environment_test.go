@@ -0,0 +1,121 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+)
+
+func TestCRDDirectoryPath(t *testing.T) {
+	path := crdDirectoryPath()
+
+	matches, err := filepath.Glob(filepath.Join(path, "*.yaml"))
+
+	if err != nil || len(matches) == 0 {
+		t.Errorf("crdDirectoryPath() = %s, expected directory containing CRDs", path)
+	}
+
+	if _, err := os.Stat(filepath.Join(path, "secrets-manager.advok8s.io_secretcopiers.yaml")); err != nil {
+		t.Errorf("SecretCopier CRD not found: %v", err)
+	}
+}
+
+func TestSecretCopierSynced(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   secretsv1beta1.SecretCopierStatus
+		expected bool
+	}{
+		{
+			name:     "not yet processed",
+			status:   secretsv1beta1.SecretCopierStatus{},
+			expected: false,
+		},
+		{
+			name: "all targets synced",
+			status: secretsv1beta1.SecretCopierStatus{
+				ObservedGeneration: 2,
+				Rules: []secretsv1beta1.SecretCopierRuleStatus{
+					{MatchedNamespaces: 3, SyncedNamespaces: 3},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "earlier generation processed",
+			status: secretsv1beta1.SecretCopierStatus{
+				ObservedGeneration: 1,
+				Rules: []secretsv1beta1.SecretCopierRuleStatus{
+					{MatchedNamespaces: 3, SyncedNamespaces: 3},
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "targets pending",
+			status: secretsv1beta1.SecretCopierStatus{
+				ObservedGeneration: 2,
+				Rules: []secretsv1beta1.SecretCopierRuleStatus{
+					{MatchedNamespaces: 3, SyncedNamespaces: 2, PendingNamespaces: 1},
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "targets waiting on dependencies",
+			status: secretsv1beta1.SecretCopierStatus{
+				ObservedGeneration: 2,
+				Rules: []secretsv1beta1.SecretCopierRuleStatus{
+					{MatchedNamespaces: 3, SyncedNamespaces: 2, WaitingOnDependencies: 1},
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "targets failing",
+			status: secretsv1beta1.SecretCopierStatus{
+				ObservedGeneration: 2,
+				Rules: []secretsv1beta1.SecretCopierRuleStatus{
+					{MatchedNamespaces: 3, SyncedNamespaces: 2},
+				},
+				FailedTargets: []secretsv1beta1.SecretCopierFailedTarget{
+					{Namespace: "tenant-a", Reason: "Invalid"},
+				},
+			},
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			secretCopier := &secretsv1beta1.SecretCopier{
+				ObjectMeta: metav1.ObjectMeta{Name: "copier", Generation: 2},
+				Status:     test.status,
+			}
+
+			if synced := SecretCopierSynced(secretCopier); synced != test.expected {
+				t.Errorf("SecretCopierSynced() = %v, expected %v", synced, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	secretsv1beta1 "github.com/advok8s/advok8s-secrets-manager/api/v1beta1"
+	"github.com/advok8s/advok8s-secrets-manager/pkg/selectors"
+	secretstesting "github.com/advok8s/advok8s-secrets-manager/pkg/testing"
+)
+
+// Test a SecretCopier configuration end to end, as a downstream project
+// would. Skipped unless the envtest binaries are available.
+func TestEnvironment(t *testing.T) {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		t.Skip("KUBEBUILDER_ASSETS not set")
+	}
+
+	env, err := secretstesting.Start(secretstesting.Options{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if err := env.Stop(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	ctx := context.Background()
+
+	for _, name := range []string{"harness-source", "harness-target"} {
+		if _, err := env.CreateNamespace(ctx, name, map[string]string{"team": "harness"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := env.CreateSecret(ctx, "harness-source", "registry", map[string]string{"password": "secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err = env.CreateSecretCopier(ctx, &secretsv1beta1.SecretCopier{
+		ObjectMeta: metav1.ObjectMeta{Name: "harness-copier"},
+		Spec: secretsv1beta1.SecretCopierSpec{
+			Rules: []secretsv1beta1.SecretCopierRule{
+				{
+					SourceSecret: secretsv1beta1.SourceSecret{Name: "registry", Namespace: "harness-source"},
+					TargetNamespaces: selectors.TargetNamespaces{
+						NameSelector: selectors.NameSelector{MatchNames: []string{"harness-target"}},
+					},
+				},
+			},
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.WaitForSecretData(ctx, "harness-target", "registry", map[string]string{"password": "secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.WaitForSecretCopierSynced(ctx, "harness-copier"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package vault implements the small part of the HashiCorp Vault HTTP API
+// needed to read secrets from a KV secrets engine, authenticating with either
+// a token or the Kubernetes auth method. It avoids depending on the full Vault
+// client library for what is a handful of requests.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client makes requests against a Vault server.
+type Client struct {
+	// Address of the Vault server, such as https://vault.example.com:8200.
+	Address string
+
+	// Vault Enterprise namespace requests are made in. Empty for the root
+	// namespace.
+	Namespace string
+
+	// Token used to authenticate requests, as given directly or returned by
+	// logging in.
+	Token string
+
+	// HTTP client used for requests. If nil, http.DefaultClient is used.
+	HTTPClient *http.Client
+}
+
+// Error is returned when the Vault server responds with an error status.
+type Error struct {
+	// HTTP status code of the response.
+	StatusCode int
+
+	// Errors reported by the Vault server.
+	Errors []string
+}
+
+func (e *Error) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("vault responded with status %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("vault responded with status %d: %s", e.StatusCode, strings.Join(e.Errors, "; "))
+}
+
+// Secret is the data read from a KV secrets engine.
+type Secret struct {
+	// Data of the secret. Values which are not strings are given as JSON.
+	Data map[string][]byte
+
+	// Version of the secret. Only set for version 2 of the KV secrets
+	// engine.
+	Version int64
+}
+
+// Make a request against the Vault API, decoding the JSON response into out
+// if it isn't nil.
+func (c *Client) do(ctx context.Context, method string, path string, body any, out any) error {
+	var reader io.Reader
+
+	if body != nil {
+		encoded, err := json.Marshal(body)
+
+		if err != nil {
+			return err
+		}
+
+		reader = bytes.NewReader(encoded)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.Address, "/")+"/v1/"+path, reader)
+
+	if err != nil {
+		return err
+	}
+
+	if c.Token != "" {
+		request.Header.Set("X-Vault-Token", c.Token)
+	}
+
+	if c.Namespace != "" {
+		request.Header.Set("X-Vault-Namespace", c.Namespace)
+	}
+
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := c.HTTPClient
+
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	response, err := httpClient.Do(request)
+
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		var failure struct {
+			Errors []string `json:"errors"`
+		}
+
+		_ = json.NewDecoder(io.LimitReader(response.Body, 64*1024)).Decode(&failure)
+
+		return &Error{StatusCode: response.StatusCode, Errors: failure.Errors}
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(response.Body).Decode(out)
+}
+
+// Escape each segment of a path for use in a URL, leaving the separators.
+func escapePath(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// LoginKubernetes logs in using the Kubernetes auth method mounted at mount,
+// presenting a service account token for the role. On success the client
+// token returned by Vault is used for following requests and also returned.
+func (c *Client) LoginKubernetes(ctx context.Context, mount string, role string, jwt string) (string, error) {
+	var response struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+
+	body := map[string]string{"role": role, "jwt": jwt}
+
+	if err := c.do(ctx, http.MethodPost, "auth/"+escapePath(mount)+"/login", body, &response); err != nil {
+		return "", err
+	}
+
+	if response.Auth.ClientToken == "" {
+		return "", fmt.Errorf("vault kubernetes login for role %q returned no client token", role)
+	}
+
+	c.Token = response.Auth.ClientToken
+
+	return c.Token, nil
+}
+
+// ReadKV reads the secret at path from the KV secrets engine mounted at mount.
+// The kvVersion gives the version of the secrets engine, either 1 or 2. For
+// version 2 the latest version of the secret is read.
+func (c *Client) ReadKV(ctx context.Context, mount string, path string, kvVersion int) (*Secret, error) {
+	var fields map[string]any
+
+	secret := &Secret{}
+
+	switch kvVersion {
+	case 1:
+		var response struct {
+			Data map[string]any `json:"data"`
+		}
+
+		if err := c.do(ctx, http.MethodGet, escapePath(mount)+"/"+escapePath(path), nil, &response); err != nil {
+			return nil, err
+		}
+
+		fields = response.Data
+
+	case 2:
+		var response struct {
+			Data struct {
+				Data     map[string]any `json:"data"`
+				Metadata struct {
+					Version int64 `json:"version"`
+				} `json:"metadata"`
+			} `json:"data"`
+		}
+
+		if err := c.do(ctx, http.MethodGet, escapePath(mount)+"/data/"+escapePath(path), nil, &response); err != nil {
+			return nil, err
+		}
+
+		// A secret whose latest version was deleted is returned without any
+		// data, which is treated the same as it not existing.
+
+		if response.Data.Data == nil {
+			return nil, &Error{StatusCode: http.StatusNotFound, Errors: []string{"latest version of secret has been deleted"}}
+		}
+
+		fields = response.Data.Data
+		secret.Version = response.Data.Metadata.Version
+
+	default:
+		return nil, fmt.Errorf("unsupported KV secrets engine version %d, must be 1 or 2", kvVersion)
+	}
+
+	secret.Data = make(map[string][]byte, len(fields))
+
+	for key, value := range fields {
+		if text, ok := value.(string); ok {
+			secret.Data[key] = []byte(text)
+			continue
+		}
+
+		encoded, err := json.Marshal(value)
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to encode value of key %q: %w", key, err)
+		}
+
+		secret.Data[key] = encoded
+	}
+
+	return secret, nil
+}
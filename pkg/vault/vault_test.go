@@ -0,0 +1,235 @@
+/*
+Copyright 2024 Graham Dumpleton.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Start a fake Vault server serving a KV version 1 engine at kv1, a KV
+// version 2 engine at secret, and the Kubernetes auth method. Requests must
+// carry the token issued by logging in, or the root token.
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	respond := func(w http.ResponseWriter, status int, body any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(body)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/auth/kubernetes/login" {
+			var login map[string]string
+
+			_ = json.NewDecoder(r.Body).Decode(&login)
+
+			if login["role"] != "app" || login["jwt"] != "service-account-token" {
+				respond(w, http.StatusForbidden, map[string]any{"errors": []string{"permission denied"}})
+				return
+			}
+
+			respond(w, http.StatusOK, map[string]any{"auth": map[string]any{"client_token": "login-token"}})
+			return
+		}
+
+		if token := r.Header.Get("X-Vault-Token"); token != "root" && token != "login-token" {
+			respond(w, http.StatusForbidden, map[string]any{"errors": []string{"permission denied"}})
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/kv1/app/db":
+			respond(w, http.StatusOK, map[string]any{"data": map[string]any{"password": "one", "port": 5432}})
+		case "/v1/secret/data/app/db":
+			respond(w, http.StatusOK, map[string]any{"data": map[string]any{
+				"data":     map[string]any{"password": "two"},
+				"metadata": map[string]any{"version": 3},
+			}})
+		case "/v1/secret/data/app/deleted":
+			respond(w, http.StatusOK, map[string]any{"data": map[string]any{
+				"data":     nil,
+				"metadata": map[string]any{"version": 4},
+			}})
+		default:
+			respond(w, http.StatusNotFound, map[string]any{"errors": []string{}})
+		}
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestClientReadKV(t *testing.T) {
+	server := newServer(t)
+
+	tests := []struct {
+		name        string
+		token       string
+		mount       string
+		path        string
+		kvVersion   int
+		want        map[string]string
+		wantVersion int64
+		wantStatus  int
+		wantErr     bool
+	}{
+		{
+			name:      "version 1 engine with non string value",
+			token:     "root",
+			mount:     "kv1",
+			path:      "app/db",
+			kvVersion: 1,
+			want:      map[string]string{"password": "one", "port": "5432"},
+		},
+		{
+			name:        "version 2 engine",
+			token:       "root",
+			mount:       "secret",
+			path:        "/app/db/",
+			kvVersion:   2,
+			want:        map[string]string{"password": "two"},
+			wantVersion: 3,
+		},
+		{
+			name:       "deleted latest version",
+			token:      "root",
+			mount:      "secret",
+			path:       "app/deleted",
+			kvVersion:  2,
+			wantStatus: http.StatusNotFound,
+			wantErr:    true,
+		},
+		{
+			name:       "missing secret",
+			token:      "root",
+			mount:      "secret",
+			path:       "app/missing",
+			kvVersion:  2,
+			wantStatus: http.StatusNotFound,
+			wantErr:    true,
+		},
+		{
+			name:       "bad token",
+			token:      "wrong",
+			mount:      "secret",
+			path:       "app/db",
+			kvVersion:  2,
+			wantStatus: http.StatusForbidden,
+			wantErr:    true,
+		},
+		{
+			name:      "unsupported engine version",
+			token:     "root",
+			mount:     "secret",
+			path:      "app/db",
+			kvVersion: 3,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{Address: server.URL + "/", Token: tt.token}
+
+			secret, err := client.ReadKV(context.Background(), tt.mount, tt.path, tt.kvVersion)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadKV() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantStatus != 0 {
+				var vaultErr *Error
+
+				if !errors.As(err, &vaultErr) || vaultErr.StatusCode != tt.wantStatus {
+					t.Fatalf("ReadKV() error = %v, want status %d", err, tt.wantStatus)
+				}
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if len(secret.Data) != len(tt.want) {
+				t.Fatalf("ReadKV() data = %v, want %v", secret.Data, tt.want)
+			}
+
+			for key, value := range tt.want {
+				if string(secret.Data[key]) != value {
+					t.Errorf("ReadKV() data[%q] = %q, want %q", key, secret.Data[key], value)
+				}
+			}
+
+			if secret.Version != tt.wantVersion {
+				t.Errorf("ReadKV() version = %d, want %d", secret.Version, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestClientLoginKubernetes(t *testing.T) {
+	server := newServer(t)
+
+	tests := []struct {
+		name    string
+		role    string
+		jwt     string
+		wantErr bool
+	}{
+		{
+			name: "valid role and token",
+			role: "app",
+			jwt:  "service-account-token",
+		},
+		{
+			name:    "wrong role",
+			role:    "other",
+			jwt:     "service-account-token",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{Address: server.URL}
+
+			token, err := client.LoginKubernetes(context.Background(), "kubernetes", tt.role, tt.jwt)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoginKubernetes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if token != "login-token" || client.Token != "login-token" {
+				t.Fatalf("LoginKubernetes() token = %q, client token = %q, want login-token", token, client.Token)
+			}
+
+			if _, err := client.ReadKV(context.Background(), "secret", "app/db", 2); err != nil {
+				t.Errorf("ReadKV() after login error = %v", err)
+			}
+		})
+	}
+}